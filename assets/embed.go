@@ -26,6 +26,15 @@ var SKR04JSON []byte
 //go:embed buchungsregeln.json
 var BuchungsregelnJSON []byte
 
+// InterRegularTTF and InterBoldTTF are the fonts embedded into the invoice
+// PDFs (SIL Open Font License, see fonts/Inter-LICENSE.txt).
+//
+//go:embed fonts/Inter-Regular.ttf
+var InterRegularTTF []byte
+
+//go:embed fonts/Inter-Bold.ttf
+var InterBoldTTF []byte
+
 // GetTranslationFile returns the content of a translation file
 func GetTranslationFile(filename string) ([]byte, error) {
 	return EmbeddedFiles.ReadFile("i18n/" + filename)
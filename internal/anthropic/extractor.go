@@ -28,7 +28,8 @@ Ziel: Liefere ausschließlich ein strenges JSON-Objekt mit genau diesen Schlüss
   "monat": "MM oder null",
   "bezahldatum": "dd.MM.yyyy oder null",
   "bar_bezahlt": false,
-  "ausgangsrechnung": false
+  "ausgangsrechnung": false,
+  "unsichere_felder": []
 }
 
 Regeln:
@@ -46,6 +47,7 @@ Regeln:
 - jahr / monat: aus rechnungsdatum ableiten (YYYY, MM).
 - verwendungszweck: kurze menschliche Zusammenfassung (max. ~80 Zeichen), z. B. "Cloud-Abo Oktober 2025".
 - bezahldatum: Setze dieses Feld NUR, wenn der Beleg explizit eine erfolgte Zahlung ausweist — z. B. "Zahlung: bar", "bar bezahlt", "Betrag erhalten", "bereits bezahlt", "bezahlt am", Quittung, Kassenbon, EC-Zahlung, Kassenbewegung, oder ein ähnlicher Hinweis. Verwende dann das Zahlungs- bzw. Belegdatum (dd.MM.yyyy). Falls kein explizites Zahlungsdatum angegeben ist, aber der Beleg eindeutig ein bezahlter Kassenbon/Quittung ist, verwende das Belegdatum. In allen anderen Fällen (normale Rechnung ohne Zahlungsbestätigung): null.
+- unsichere_felder: Liste der Schlüssel (z. B. "rechnungsdatum", "steuerzeilen", "bruttobetrag"), deren Wert du nur schlecht lesen konntest oder erschließen musstest (unscharf, abgeschnitten, handschriftlich, mehrdeutig). Leere Liste, wenn alle Werte eindeutig auf dem Beleg stehen.
 - bar_bezahlt: true, wenn die Zahlung in BARGELD erfolgt ist (bar, Bargeld, Kassenbon, Quittung, Cash). false in allen anderen Fällen (Überweisung, EC, Kreditkarte, PayPal, unbekannt).
 
 vat_id (Umsatzsteuer-Identifikationsnummer des Rechnungsstellers):
@@ -124,9 +126,10 @@ func isOwnVATID(extracted string, ownVATIDs []string) bool {
 	return false
 }
 
-// Extractor extracts invoice metadata using Claude API.
+// Extractor extracts invoice metadata using a language model; the backend
+// is a Provider (Claude by default).
 type Extractor struct {
-	client       *Client
+	provider     Provider
 	logger       *logging.Logger
 	debug        bool
 	accountHints []core.SKRAccount
@@ -135,9 +138,9 @@ type Extractor struct {
 // NewExtractor creates a new Anthropic extractor.
 func NewExtractor(logger *logging.Logger, debug bool) *Extractor {
 	return &Extractor{
-		client: NewClient(),
-		logger: logger,
-		debug:  debug,
+		provider: NewClient(),
+		logger:   logger,
+		debug:    debug,
 	}
 }
 
@@ -218,7 +221,7 @@ func (e *Extractor) ExtractStatementFromImages(ctx context.Context, apiKey, mode
 		e.logger.Debug("Model: %s, media type: %s", model, mediaType)
 	}
 
-	response, err := e.client.SendWithImages(ctx, apiKey, model,
+	response, err := e.provider.SendWithImages(ctx, apiKey, model,
 		statementSystemPrompt, visionPrompt, imagesBase64, mediaType)
 	if err != nil {
 		return core.StatementMetadata{}, fmt.Errorf("Vision API request failed: %w", err)
@@ -295,7 +298,7 @@ func (e *Extractor) ExtractFromImage(ctx context.Context, apiKey, model, imageBa
 	}
 
 	// Send request with image
-	response, err := e.client.SendWithImage(ctx, apiKey, model, prompt, visionPrompt, imageBase64, mediaType)
+	response, err := e.provider.SendWithImages(ctx, apiKey, model, prompt, visionPrompt, []string{imageBase64}, mediaType)
 	if err != nil {
 		if e.debug && e.logger != nil {
 			e.logger.Debug("=== CLAUDE VISION API ERROR ===")
@@ -316,6 +319,7 @@ func (e *Extractor) ExtractFromImage(ctx context.Context, apiKey, model, imageBa
 	if err != nil {
 		return core.Meta{}, 0, err
 	}
+	core.AssessProvenance(&meta, "", true)
 	if e.debug && e.logger != nil && meta.VATID == "" && len(ownVATIDs) > 0 {
 		e.logger.Debug("VAT-ID either not detected or matched an own VAT-ID and was filtered out")
 	}
@@ -346,7 +350,7 @@ func (e *Extractor) Extract(ctx context.Context, apiKey, model, text string, own
 	}
 
 	// Send request
-	response, err := e.client.Send(ctx, apiKey, model, prompt, text)
+	response, err := e.provider.Send(ctx, apiKey, model, prompt, text)
 	if err != nil {
 		if e.debug && e.logger != nil {
 			e.logger.Debug("=== CLAUDE API ERROR ===")
@@ -367,6 +371,7 @@ func (e *Extractor) Extract(ctx context.Context, apiKey, model, text string, own
 	if err != nil {
 		return core.Meta{}, 0, err
 	}
+	core.AssessProvenance(&meta, text, false)
 	if e.debug && e.logger != nil && meta.VATID == "" && len(ownVATIDs) > 0 {
 		e.logger.Debug("VAT-ID either not detected or matched an own VAT-ID and was filtered out")
 	}
@@ -392,7 +397,7 @@ func (e *Extractor) ExtractMultimodal(ctx context.Context, apiKey, model, text s
 		e.logger.Debug("Model: %s, text: %d chars, images: %d", model, len(text), len(imagesBase64))
 	}
 
-	response, err := e.client.SendWithImages(ctx, apiKey, model, prompt, userMessage, imagesBase64, mediaType)
+	response, err := e.provider.SendWithImages(ctx, apiKey, model, prompt, userMessage, imagesBase64, mediaType)
 	if err != nil {
 		if e.debug && e.logger != nil {
 			e.logger.Debug("=== CLAUDE MULTIMODAL API ERROR ===\nError: %v", err)
@@ -408,6 +413,7 @@ func (e *Extractor) ExtractMultimodal(ctx context.Context, apiKey, model, text s
 	if err != nil {
 		return core.Meta{}, 0, err
 	}
+	core.AssessProvenance(&meta, text, len(imagesBase64) > 0)
 	return meta, 0.95, nil
 }
 
@@ -441,6 +447,7 @@ func parseExtractionJSON(response string, ownVATIDs []string) (core.Meta, error)
 		Bezahldatum             *string  `json:"bezahldatum"`
 		BarBezahlt              *bool    `json:"bar_bezahlt"`
 		Ausgangsrechnung        *bool    `json:"ausgangsrechnung"`
+		UnsichereFelder         []string `json:"unsichere_felder"`
 	}
 
 	if err := json.Unmarshal([]byte(response), &result); err != nil {
@@ -505,10 +512,33 @@ func parseExtractionJSON(response string, ownVATIDs []string) (core.Meta, error)
 	if result.Ausgangsrechnung != nil {
 		meta.Ausgangsrechnung = *result.Ausgangsrechnung
 	}
+	// Fields the model flagged as uncertain; AssessProvenance keeps their
+	// low confidence when it rates the rest.
+	for _, key := range result.UnsichereFelder {
+		for _, f := range uncertainKeyFields[strings.ToLower(strings.TrimSpace(key))] {
+			if meta.Provenance == nil {
+				meta.Provenance = core.Provenance{}
+			}
+			meta.Provenance[f] = core.FieldProvenance{Source: core.SourceModel, Confidence: core.ConfidenceUncertain}
+		}
+	}
 
 	return meta, nil
 }
 
+// uncertainKeyFields maps the JSON keys of "unsichere_felder" to the
+// provenance fields they feed.
+var uncertainKeyFields = map[string][]string{
+	"auftraggeber":    {core.FieldAuftraggeber},
+	"rechnungsnummer": {core.FieldRechnungsnummer},
+	"vat_id":          {core.FieldVATID},
+	"rechnungsdatum":  {core.FieldRechnungsdatum},
+	"bezahldatum":     {core.FieldBezahldatum},
+	"steuerzeilen":    {core.FieldBetragNetto, core.FieldSteuersatzBetrag},
+	"trinkgeld":       {core.FieldBruttobetrag},
+	"bruttobetrag":    {core.FieldBruttobetrag},
+}
+
 // parseExtractionResponse is an alias for parseExtractionJSON kept for
 // backward compatibility with existing tests that call it directly.
 func parseExtractionResponse(response string, ownVATIDs []string) (core.Meta, error) {
@@ -610,7 +640,7 @@ func (e *Extractor) RankStatementLine(ctx context.Context, apiKey, model, suppli
 		sb.WriteString(fmt.Sprintf("%d: %s\n", i, t))
 	}
 
-	reply, err := e.client.Send(ctx, apiKey, model, "Du hilfst beim Belegabgleich.", sb.String())
+	reply, err := e.provider.Send(ctx, apiKey, model, "Du hilfst beim Belegabgleich.", sb.String())
 	if err != nil {
 		return -1, fmt.Errorf("RankStatementLine API call failed: %w", err)
 	}
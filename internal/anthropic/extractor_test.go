@@ -195,3 +195,21 @@ func TestAccountHintSectionListsAccounts(t *testing.T) {
 		t.Error("no hints should yield empty section")
 	}
 }
+
+func TestParseUncertainFields(t *testing.T) {
+	js := `{"auftraggeber":"Bäckerei Ernst","steuerzeilen":[{"satz":7,"netto":10,"mwst":0.7}],"bruttobetrag":10.7,` +
+		`"rechnungsdatum":"01.02.2026","unsichere_felder":["rechnungsdatum","Steuerzeilen","unbekannt"]}`
+	meta, err := parseExtractionJSON(js, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	core.AssessProvenance(&meta, "Bäckerei Ernst  Summe 10,70", false)
+	u := meta.UncertainFields()
+	want := []string{core.FieldRechnungsdatum, core.FieldBetragNetto, core.FieldSteuersatzBetrag}
+	if strings.Join(u, ",") != strings.Join(want, ",") {
+		t.Errorf("UncertainFields = %v, want %v", u, want)
+	}
+	if p := meta.Provenance[core.FieldBruttobetrag]; p.Source != core.SourceTextLayer {
+		t.Errorf("gross on the text layer = %+v", p)
+	}
+}
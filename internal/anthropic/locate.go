@@ -41,7 +41,7 @@ func (e *Extractor) LocateValue(ctx context.Context, apiKey, model string, image
 		e.logger.Debug("=== CLAUDE LOCATE VALUE REQUEST (%d pages, value=%q) ===", len(imagesBase64), value)
 	}
 
-	response, err := e.client.SendWithImages(ctx, apiKey, model, locateSystemPrompt, userMessage, imagesBase64, mediaType)
+	response, err := e.provider.SendWithImages(ctx, apiKey, model, locateSystemPrompt, userMessage, imagesBase64, mediaType)
 	if err != nil {
 		return LocatedBox{}, fmt.Errorf("LocateValue API request failed: %w", err)
 	}
@@ -0,0 +1,34 @@
+package anthropic
+
+import "context"
+
+// Provider is the model backend an Extractor talks to: a system prompt and a
+// user message (optionally with page images) go in, the model's text reply
+// comes out. Prompts and JSON parsing stay in the Extractor, so every backend
+// yields the same core.Meta. *Client (Claude) is the default; the openai
+// package provides an OpenAI-compatible backend.
+type Provider interface {
+	// Name is the label shown in status messages and the Quelle badge.
+	Name() string
+	Send(ctx context.Context, apiKey, model, systemPrompt, userMessage string) (string, error)
+	SendWithImages(ctx context.Context, apiKey, model, systemPrompt, userMessage string, imagesBase64 []string, mediaType string) (string, error)
+}
+
+// Name implements Provider.
+func (c *Client) Name() string {
+	return "Claude"
+}
+
+// SetProvider switches the backend used for all extraction requests. A nil
+// provider restores the Claude client.
+func (e *Extractor) SetProvider(p Provider) {
+	if p == nil {
+		p = NewClient()
+	}
+	e.provider = p
+}
+
+// ProviderName returns the Name of the active backend.
+func (e *Extractor) ProviderName() string {
+	return e.provider.Name()
+}
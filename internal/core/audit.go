@@ -13,6 +13,9 @@ type AuditEntry struct {
 	Entitaet  string // "invoice"
 	Schluessel string // e.g. "2026-0001 2026-01-Firma-..."
 	Details   string // JSON diff for updates, empty for create/delete
+	ID        int64  // row id; the chain order
+	PrevHash  string // Hash of the previous entry ("" for the first)
+	Hash      string // AuditHash over PrevHash and this entry's content
 }
 
 // diffEntry holds the old and new value for a changed field.
@@ -0,0 +1,276 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audit actions besides the invoice/period ones.
+const (
+	AuditActionFingerprint = "fingerprint" // file hashes of an invoice anchored into the chain
+	AuditActionChain       = "chain"       // the hash chain was started (over the entries written before it)
+)
+
+// AuditHash chains an audit entry: the SHA-256 over the previous entry's hash
+// and this entry's timestamp, action, entity, key and details. Changing any
+// of them, or removing or inserting an entry, breaks every later link.
+func AuditHash(prev string, e AuditEntry) string {
+	h := sha256.New()
+	for _, part := range []string{prev, auditHashTS(e.TS), e.Aktion, e.Entitaet, e.Schluessel, e.Details} {
+		// Length-prefixed, so field boundaries cannot be shifted.
+		fmt.Fprintf(h, "%d:%s\n", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// auditHashTS brings a timestamp into the SQLite form it is stored in; the
+// driver reads DATETIME columns back as RFC 3339.
+func auditHashTS(ts string) string {
+	if t, err := time.Parse(time.RFC3339, ts); err == nil {
+		return t.UTC().Format("2006-01-02 15:04:05")
+	}
+	return ts
+}
+
+// AuditFingerprintDetails renders the file hashes of an invoice for an
+// AuditActionFingerprint entry: {"0":"<sha256>","1":"<sha256 of _Anhang1>"}.
+func AuditFingerprintDetails(fps []Fingerprint) string {
+	m := make(map[string]string, len(fps))
+	for _, fp := range fps {
+		m[fmt.Sprintf("%d", fp.Anhang)] = fp.SHA256
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// AuditChainBreak describes the first entry whose link does not hold.
+type AuditChainBreak struct {
+	Entry AuditEntry
+	Grund string // German reason for the report
+}
+
+// AuditAnker is the head of the chain at one point in time, kept outside the
+// database (the GoBD export carries it as AuditAnkerDatei). A later log must
+// still contain that entry with that hash; otherwise its newest entries were
+// removed or the whole chain was recomputed.
+type AuditAnker struct {
+	ID   int64
+	TS   string
+	Hash string
+}
+
+// AuditAnkerDatei is the name of the anchor file in the GoBD export.
+const AuditAnkerDatei = "audit_kettenkopf.txt"
+
+// Format renders the anchor as the text of AuditAnkerDatei.
+func (k AuditAnker) Format() string {
+	return fmt.Sprintf("Änderungsprotokoll – Kettenkopf\nEintrag: %d\nZeitpunkt: %s\nHash: %s\n", k.ID, k.TS, k.Hash)
+}
+
+// ParseAuditAnker reads an anchor written by Format.
+func ParseAuditAnker(data []byte) (AuditAnker, error) {
+	var k AuditAnker
+	for _, line := range strings.Split(string(data), "\n") {
+		key, val, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		switch key {
+		case "Eintrag":
+			id, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return AuditAnker{}, fmt.Errorf("Kettenkopf: Eintrag %q ungültig", val)
+			}
+			k.ID = id
+		case "Zeitpunkt":
+			k.TS = val
+		case "Hash":
+			k.Hash = val
+		}
+	}
+	if k.ID == 0 || len(k.Hash) != sha256.Size*2 {
+		return AuditAnker{}, fmt.Errorf("Kettenkopf: keine Angaben zu Eintrag und Hash gefunden")
+	}
+	return k, nil
+}
+
+// ReadAuditAnker reads an anchor from a GoBD export ZIP or from the anchor
+// file itself.
+func ReadAuditAnker(data []byte) (AuditAnker, error) {
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		return ParseAuditAnker(data)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return AuditAnker{}, fmt.Errorf("Kettenkopf: %w", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != AuditAnkerDatei {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return AuditAnker{}, fmt.Errorf("Kettenkopf: %w", err)
+		}
+		defer func() { _ = rc.Close() }()
+		b, err := io.ReadAll(rc)
+		if err != nil {
+			return AuditAnker{}, fmt.Errorf("Kettenkopf: %w", err)
+		}
+		return ParseAuditAnker(b)
+	}
+	return AuditAnker{}, fmt.Errorf("Kettenkopf: %s fehlt im Archiv", AuditAnkerDatei)
+}
+
+// AuditChainReport is the result of VerifyAuditChain.
+type AuditChainReport struct {
+	Eintraege    int              // entries checked
+	Fingerprints int              // AuditActionFingerprint entries (anchored receipts)
+	Kopf         string           // hash of the last entry: note it down to detect truncation later
+	Bruch        *AuditChainBreak // first broken link; nil = intact
+	Zeitraum     [2]string        // timestamps of the first and last entry
+	Anker        *AuditAnker      // anchor checked by VerifyAuditChainAnker; nil = none
+}
+
+// OK reports whether the chain is intact.
+func (r AuditChainReport) OK() bool {
+	return r.Bruch == nil
+}
+
+// VerifyAuditChain checks a complete audit log in id order: ids without
+// gaps, every entry pointing at its predecessor's hash and carrying the hash
+// of its own content, and the chain started only once (a second start means
+// the hashes were stripped and recomputed).
+func VerifyAuditChain(entries []AuditEntry) AuditChainReport {
+	r := AuditChainReport{Eintraege: len(entries)}
+	if len(entries) > 0 {
+		r.Zeitraum = [2]string{entries[0].TS, entries[len(entries)-1].TS}
+	}
+	prev := ""
+	starts := 0
+	for i, e := range entries {
+		fail := func(grund string) AuditChainReport {
+			r.Bruch = &AuditChainBreak{Entry: e, Grund: grund}
+			return r
+		}
+		switch e.Aktion {
+		case AuditActionFingerprint:
+			r.Fingerprints++
+		case AuditActionChain:
+			if starts++; starts > 1 {
+				return fail("Hash-Kette erneut begonnen (Hashes entfernt und neu berechnet?)")
+			}
+		}
+		switch {
+		case i > 0 && e.ID != entries[i-1].ID+1:
+			return fail(fmt.Sprintf("Lücke in der Nummerierung: Eintrag %d fehlt", entries[i-1].ID+1))
+		case e.Hash == "":
+			return fail("Eintrag ohne Hash")
+		case e.PrevHash != prev:
+			return fail("Verweis auf den Vorgänger stimmt nicht (Eintrag davor gelöscht, eingefügt oder geändert)")
+		case AuditHash(e.PrevHash, e) != e.Hash:
+			return fail("Inhalt nachträglich geändert")
+		}
+		prev = e.Hash
+		r.Kopf = e.Hash
+	}
+	return r
+}
+
+// VerifyAuditChainAnker verifies the log like VerifyAuditChain and then
+// checks it against an anchor taken earlier: the anchored entry must still
+// exist with the anchored hash. This catches what the chain alone cannot —
+// the newest entries removed, or every hash stripped and recomputed.
+func VerifyAuditChainAnker(entries []AuditEntry, anker AuditAnker) AuditChainReport {
+	r := VerifyAuditChain(entries)
+	r.Anker = &anker
+	if !r.OK() {
+		return r
+	}
+	for _, e := range entries {
+		if e.ID != anker.ID {
+			continue
+		}
+		if e.Hash != anker.Hash {
+			r.Bruch = &AuditChainBreak{Entry: e, Grund: "Hash weicht vom verankerten Kettenkopf ab (Kette neu berechnet?)"}
+		}
+		return r
+	}
+	r.Bruch = &AuditChainBreak{
+		Entry: AuditEntry{ID: anker.ID, TS: anker.TS, Hash: anker.Hash},
+		Grund: "Verankerter Kettenkopf fehlt (jüngste Einträge entfernt?)",
+	}
+	return r
+}
+
+// shortHash abbreviates a hash for reports ("3f9a…c21b").
+func shortHash(h string) string {
+	if len(h) <= 12 {
+		return h
+	}
+	return h[:6] + "…" + h[len(h)-6:]
+}
+
+// Describe renders the report as German text lines (dialog and PDF).
+func (r AuditChainReport) Describe() []string {
+	lines := []string{fmt.Sprintf("Geprüfte Einträge: %d", r.Eintraege)}
+	if r.Eintraege > 0 {
+		lines = append(lines, fmt.Sprintf("Zeitraum: %s – %s", r.Zeitraum[0], r.Zeitraum[1]))
+	}
+	lines = append(lines, fmt.Sprintf("Verankerte Beleg-Fingerprints: %d", r.Fingerprints))
+	if r.Anker != nil {
+		lines = append(lines, fmt.Sprintf("Abgleich mit Kettenkopf: Eintrag %d vom %s (%s)", r.Anker.ID, r.Anker.TS, shortHash(r.Anker.Hash)))
+	}
+	if r.OK() {
+		lines = append(lines, "Ergebnis: Hash-Kette intakt")
+		if r.Anker != nil {
+			lines = append(lines, "Verankerter Kettenkopf bestätigt")
+		}
+		if r.Kopf != "" {
+			lines = append(lines, "Kettenkopf: "+r.Kopf)
+		}
+		return lines
+	}
+	e := r.Bruch.Entry
+	return append(lines,
+		"Ergebnis: Hash-Kette GEBROCHEN",
+		fmt.Sprintf("Erster Bruch: Eintrag %d vom %s (%s %s)", e.ID, e.TS, e.Aktion, strings.TrimSpace(e.Schluessel)),
+		"Grund: "+r.Bruch.Grund,
+		"Gespeicherter Hash: "+shortHash(e.Hash),
+	)
+}
+
+// BuildAuditChainPDF renders the verification report of the audit log's
+// hash chain.
+func BuildAuditChainPDF(r AuditChainReport, title, company string) ([]byte, error) {
+	pdf, tr := newReportPDF(title, "P", company)
+	pdf.SetFont("Arial", "", 10)
+	for _, line := range r.Describe() {
+		pdf.MultiCell(0, 6, tr(line), "", "L", false)
+	}
+	pdf.Ln(4)
+	pdf.SetFont("Arial", "I", 8)
+	pdf.MultiCell(0, 4.5, tr("Jeder Eintrag des Änderungsprotokolls enthält den SHA-256-Hash über "+
+		"seinen Inhalt und den Hash des vorherigen Eintrags. Wird ein Eintrag nachträglich geändert, "+
+		"gelöscht oder eingefügt, stimmt die Kette ab dieser Stelle nicht mehr. Das Entfernen der "+
+		"jüngsten Einträge oder eine neu berechnete Kette lässt sich nur durch Abgleich mit einem "+
+		"außerhalb der Datenbank verwahrten Kettenkopf erkennen (Datei "+AuditAnkerDatei+" im GoBD-Export)."), "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// chain builds a valid audit chain over the given actions.
+func chain(actions ...string) []AuditEntry {
+	var out []AuditEntry
+	prev := ""
+	for i, a := range actions {
+		e := AuditEntry{ID: int64(i + 1), TS: "2026-03-01 10:00:00", Aktion: a, Entitaet: "invoice", Schluessel: "2026-0001 a.pdf"}
+		e.PrevHash = prev
+		e.Hash = AuditHash(prev, e)
+		prev = e.Hash
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	entries := chain(AuditActionChain, "create", AuditActionFingerprint, "update", "lock")
+	r := VerifyAuditChain(entries)
+	if !r.OK() || r.Eintraege != 5 || r.Fingerprints != 1 || r.Kopf != entries[4].Hash {
+		t.Fatalf("intact chain = %+v", r)
+	}
+
+	edited := chain(AuditActionChain, "create", "update", "lock")
+	edited[2].Details = `{"Bruttobetrag":{"alt":119,"neu":19}}`
+	if r := VerifyAuditChain(edited); r.OK() || r.Bruch.Entry.ID != 3 || !strings.Contains(r.Bruch.Grund, "geändert") {
+		t.Errorf("edited entry: %+v", r.Bruch)
+	}
+
+	// Deleting an entry leaves a gap; renumbering it away breaks the link.
+	deleted := chain(AuditActionChain, "create", "delete", "lock")
+	gap := append(append([]AuditEntry{}, deleted[:2]...), deleted[3])
+	if r := VerifyAuditChain(gap); r.OK() || r.Bruch.Entry.ID != 4 || !strings.Contains(r.Bruch.Grund, "Eintrag 3 fehlt") {
+		t.Errorf("deleted entry: %+v", r.Bruch)
+	}
+	gap[2].ID = 3
+	if r := VerifyAuditChain(gap); r.OK() || !strings.Contains(r.Bruch.Grund, "Vorgänger") {
+		t.Errorf("renumbered entry: %+v", r.Bruch)
+	}
+
+	// Recomputing every hash is caught by the second chain start.
+	restarted := chain(AuditActionChain, "create", AuditActionChain)
+	if r := VerifyAuditChain(restarted); r.OK() || r.Bruch.Entry.ID != 3 {
+		t.Errorf("restarted chain: %+v", r.Bruch)
+	}
+
+	stripped := chain(AuditActionChain, "create")
+	stripped[1].Hash = ""
+	if r := VerifyAuditChain(stripped); r.OK() || r.Bruch.Grund != "Eintrag ohne Hash" {
+		t.Errorf("stripped hash: %+v", r.Bruch)
+	}
+	if lines := VerifyAuditChain(edited).Describe(); !strings.Contains(strings.Join(lines, "\n"), "Erster Bruch: Eintrag 3") {
+		t.Errorf("report = %q", lines)
+	}
+}
+
+func TestVerifyAuditChainAnker(t *testing.T) {
+	entries := chain(AuditActionChain, "create", "update", "lock")
+	anker, err := ParseAuditAnker([]byte(AuditAnker{ID: 3, TS: entries[2].TS, Hash: entries[2].Hash}.Format()))
+	if err != nil || anker.ID != 3 || anker.Hash != entries[2].Hash {
+		t.Fatalf("ParseAuditAnker = %+v, %v", anker, err)
+	}
+
+	r := VerifyAuditChainAnker(entries, anker)
+	if !r.OK() || !strings.Contains(strings.Join(r.Describe(), "\n"), "Verankerter Kettenkopf bestätigt") {
+		t.Errorf("anchored chain = %+v", r)
+	}
+
+	// The newest entries removed: the chain itself is intact.
+	if r := VerifyAuditChainAnker(entries[:2], anker); r.OK() || r.Bruch.Entry.ID != 3 || !strings.Contains(r.Bruch.Grund, "fehlt") {
+		t.Errorf("truncated chain: %+v", r.Bruch)
+	}
+
+	// Every hash recomputed over edited content, without a second start.
+	rewritten := chain(AuditActionChain, "create", "delete", "lock")
+	if !VerifyAuditChain(rewritten).OK() {
+		t.Fatal("rewritten chain must be intact on its own")
+	}
+	if r := VerifyAuditChainAnker(rewritten, anker); r.OK() || r.Bruch.Entry.ID != 3 || !strings.Contains(r.Bruch.Grund, "neu berechnet") {
+		t.Errorf("rewritten chain: %+v", r.Bruch)
+	}
+
+	if _, err := ParseAuditAnker([]byte("Eintrag: 3\n")); err == nil {
+		t.Error("anchor without hash must be rejected")
+	}
+}
+
+func TestAuditHash_FieldBoundaries(t *testing.T) {
+	a := AuditEntry{Aktion: "create", Schluessel: "ab", Details: "c"}
+	b := AuditEntry{Aktion: "create", Schluessel: "a", Details: "bc"}
+	if AuditHash("", a) == AuditHash("", b) {
+		t.Error("moving text between fields must change the hash")
+	}
+	if got := AuditFingerprintDetails([]Fingerprint{{SHA256: "aa"}, {Anhang: 1, SHA256: "bb"}}); got != `{"0":"aa","1":"bb"}` {
+		t.Errorf("details = %s", got)
+	}
+}
@@ -9,6 +9,8 @@ import "math"
 //   - Bruttobetrag > 0
 //   - |Bruttobetrag − (SumNetto + SumMwSt + Trinkgeld)| ≤ 0.02
 //   - NOT (foreign currency with Wechselkurs ≤ 0)
+//   - no key field is uncertain (Meta.UncertainFields ∩ AutobookKeyFields);
+//     only checked when the extractor set a provenance
 func AutobookPlausible(m Meta) bool {
 	if len(m.TaxLines) == 0 {
 		return false
@@ -27,6 +29,11 @@ func AutobookPlausible(m Meta) bool {
 	if m.Waehrung != "" && m.Waehrung != "EUR" && m.Wechselkurs <= 0 {
 		return false
 	}
+	for _, f := range m.UncertainFields() {
+		if containsString(AutobookKeyFields, f) {
+			return false
+		}
+	}
 	return true
 }
 
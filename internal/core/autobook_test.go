@@ -33,6 +33,31 @@ func TestAutobookPlausible_WithTip(t *testing.T) {
 	}
 }
 
+func TestAutobookPlausible_Provenance(t *testing.T) {
+	m := okMeta()
+	m.Auftraggeber = "Hetzner"
+	m.Rechnungsdatum = "03.02.2026"
+	m.BetragNetto, m.SteuersatzBetrag = 100, 19
+	SetProvenance(&m, SourceEInvoice, 1)
+	if !AutobookPlausible(m) {
+		t.Fatal("expected true: all key fields certain")
+	}
+	m.Provenance[FieldRechnungsdatum] = FieldProvenance{Source: SourceVision, Confidence: ConfidenceUncertain}
+	if AutobookPlausible(m) {
+		t.Fatal("expected false: uncertain Rechnungsdatum")
+	}
+	m.Provenance[FieldRechnungsdatum] = FieldProvenance{Source: SourceVision, Confidence: 1}
+	m.Provenance[FieldRechnungsnummer] = FieldProvenance{Source: SourceModel, Confidence: ConfidenceModel}
+	if !AutobookPlausible(m) {
+		t.Fatal("expected true: Rechnungsnummer is not a key field")
+	}
+	m.Rechnungsdatum = ""
+	delete(m.Provenance, FieldRechnungsdatum)
+	if AutobookPlausible(m) {
+		t.Fatal("expected false: key field missing")
+	}
+}
+
 func TestAutobookPlausible_NoTaxLines(t *testing.T) {
 	m := okMeta()
 	m.TaxLines = nil
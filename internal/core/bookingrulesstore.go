@@ -76,6 +76,10 @@ func mergeBundledIntoSaved(saved, bundled *BookingRules) *BookingRules {
 	}
 	for _, r := range bundled.Regeln {
 		if !have[r.Kategorie] {
+			if rc, ok := saved.Rule("reverse_charge"); ok && r.Kategorie == "reverse_charge_eu" {
+				// Same accounts as the profile's other § 13b bookings.
+				r.KontoVStRC, r.KontoUStRC, r.RcSatz = rc.KontoVStRC, rc.KontoUStRC, rc.RcSatz
+			}
 			saved.Regeln = append(saved.Regeln, r)
 		}
 	}
@@ -16,6 +16,17 @@ type BookingEntry struct {
 	Steuerschluessel string  `json:"steuerschluessel,omitempty"`
 }
 
+// Unsigned returns the entry with a positive amount: a negative (Storno)
+// amount is posted as its absolute value on the opposite side, the form
+// export formats without signed amounts expect.
+func (e BookingEntry) Unsigned() BookingEntry {
+	if e.Betrag < 0 {
+		e.Betrag = -e.Betrag
+		e.Soll = !e.Soll
+	}
+	return e
+}
+
 // Booking is the set of entries that posts a single receipt, plus a free-text
 // rationale/notes ("Buchungswissen").
 type Booking struct {
@@ -177,13 +188,13 @@ func BuildBooking(rules *BookingRules, kategorie string, lines []TaxLine, trinkg
 	var entries []BookingEntry
 
 	switch kategorie {
-	case "reverse_charge":
+	case "reverse_charge", "reverse_charge_eu":
 		net := round2(SumNetto(lines) + trinkgeld)
 		vat := round2(net * rule.RcSatz / 100)
 		return Booking{Entries: []BookingEntry{
 			{Konto: expenseAccount, Betrag: net, Soll: true},
-			{Konto: rule.KontoVStRC, Betrag: vat, Soll: true},
-			{Konto: rule.KontoUStRC, Betrag: vat, Soll: false},
+			{Konto: rule.KontoVStRC, Betrag: vat, Soll: true, Steuerschluessel: rcSteuerschluessel(kategorie)},
+			{Konto: rule.KontoUStRC, Betrag: vat, Soll: false, Steuerschluessel: rcSteuerschluessel(kategorie)},
 			{Konto: paymentAccount, Betrag: net, Soll: false},
 		}}, nil
 	case "geschenke":
@@ -262,3 +273,13 @@ func BuildRevenueBooking(rules *BookingRules, lines []TaxLine, revenueAccount, p
 	entries = append([]BookingEntry{{Konto: sollKonto, Betrag: round2(habenSum), Soll: true}}, entries...)
 	return Booking{Entries: entries}, nil
 }
+
+// rcSteuerschluessel is the tax key of a § 13b booking's tax entries:
+// SteuerschluesselRCEU for a service from another EU country, none for the
+// other § 13b cases.
+func rcSteuerschluessel(kategorie string) string {
+	if kategorie == "reverse_charge_eu" {
+		return SteuerschluesselRCEU
+	}
+	return ""
+}
@@ -32,6 +32,13 @@ type BookingRules struct {
 	ForderungsKonto    int            `json:"forderungskonto,omitempty"`
 	KontoStichwoerter  map[string]int `json:"konto_stichwoerter,omitempty"`
 	Regeln             []BookingRule  `json:"regeln"`
+	// UStVAKonten maps accounts (optionally per DATEV tax key) to UStVA
+	// Kennzahlen, ahead of the defaults KennzahlKonten derives from the
+	// accounts above.
+	UStVAKonten []KennzahlKonto `json:"ustva_konten,omitempty"`
+	// AnlagenerloesKonten are the revenue accounts for sales of fixed
+	// assets, which do not count towards the Gesamtumsatz of § 19 UStG.
+	AnlagenerloesKonten []int `json:"anlagenerloes_konten,omitempty"`
 }
 
 // ParseBookingRules decodes the rules base JSON.
@@ -80,6 +87,16 @@ func (r *BookingRules) ErloesKonto(vatID string, mwst float64) (int, bool) {
 	return k, ok
 }
 
+// IsAnlagenerloesKonto reports whether konto is one of AnlagenerloesKonten.
+func (r *BookingRules) IsAnlagenerloesKonto(konto int) bool {
+	for _, k := range r.AnlagenerloesKonten {
+		if k == konto {
+			return true
+		}
+	}
+	return false
+}
+
 // SuggestKonto proposes a Gegenkonto for a new supplier by scanning text
 // (supplier name + Verwendungszweck) for configured keywords. Case-insensitive
 // substring match; the longest matching keyword wins (most specific). Returns
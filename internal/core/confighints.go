@@ -4,11 +4,16 @@ import "strings"
 
 // MissingConfigHints returns i18n keys for unmet setup preconditions, shown as
 // dismissible banners. hasAPIKey reports whether a Claude API key is stored.
+// The OpenAI-compatible backend needs no key (self-hosted servers run
+// without one) but a model name.
 func MissingConfigHints(s Settings, hasAPIKey bool) []string {
 	var hints []string
-	if s.ProcessingMode == "claude" && !hasAPIKey {
+	if s.ProcessingMode == ProcessingModeClaude && !hasAPIKey {
 		hints = append(hints, "hint.no_api_key")
 	}
+	if s.ProcessingMode == ProcessingModeOpenAI && strings.TrimSpace(s.OpenAIModel) == "" {
+		hints = append(hints, "hint.no_openai_model")
+	}
 	if strings.TrimSpace(s.StorageRoot) == "" {
 		hints = append(hints, "hint.no_storage")
 	}
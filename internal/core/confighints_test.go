@@ -23,6 +23,18 @@ func TestMissingConfigHints(t *testing.T) {
 	if has(MissingConfigHints(Settings{ProcessingMode: "local", StorageRoot: "/x"}, false), "hint.no_api_key") {
 		t.Error("local mode must not warn about API key")
 	}
+	// OpenAI-compatible backend: no key needed, but a model name.
+	o := Settings{ProcessingMode: ProcessingModeOpenAI, StorageRoot: "/x"}
+	if h := MissingConfigHints(o, false); len(h) != 1 || h[0] != "hint.no_openai_model" {
+		t.Errorf("openai without model: hints %v", h)
+	}
+	o.OpenAIModel = "qwen2.5vl"
+	if h := MissingConfigHints(o, false); len(h) != 0 {
+		t.Errorf("openai with model: hints %v", h)
+	}
+	if !o.UsesAI() || o.AIModel() != "qwen2.5vl" || (Settings{ProcessingMode: "local"}).UsesAI() {
+		t.Error("UsesAI/AIModel wrong for openai/local")
+	}
 	// Missing storage root → warn.
 	if !has(MissingConfigHints(Settings{ProcessingMode: "local"}, false), "hint.no_storage") {
 		t.Error("expected no_storage hint")
@@ -50,6 +50,7 @@ var DefaultCSVColumns = []string{
 	"Steuerzeilen",
 	"Buchung",
 	"Exportiert",
+	"StornoZu",
 	"Originalwaehrung",
 	"Originalbetrag_Brutto",
 }
@@ -92,6 +93,7 @@ var ColumnDisplayNames = map[string]string{
 	"Steuerzeilen":          "Steuerzeilen (Detail)",
 	"Buchung":               "Buchungssatz",
 	"Exportiert":            "Exportiert",
+	"StornoZu":              "Storno zu",
 	"Originalwaehrung":      "Originalwährung",
 	"Originalbetrag_Brutto": "Originalbetrag Brutto",
 }
@@ -134,6 +136,7 @@ var ColumnTranslationKeys = map[string]string{
 	"Steuerzeilen":          "table.col.taxlines",
 	"Buchung":               "table.col.buchung",
 	"Exportiert":            "table.col.exportiert",
+	"StornoZu":              "table.col.stornozu",
 	"Originalwaehrung":      "table.col.originalwaehrung",
 	"Originalbetrag_Brutto": "table.col.originalbetrag_brutto",
 }
@@ -337,6 +340,7 @@ func (r *CSVRepository) Load(path string) ([]CSVRow, error) {
 		}
 		row.Buchung = ParseBooking(valueForColumn(record, headerMap, "Buchung"))
 		row.Exportiert = strings.EqualFold(strings.TrimSpace(valueForColumn(record, headerMap, "Exportiert")), "true")
+		row.StornoZu = valueForColumn(record, headerMap, "StornoZu")
 		// Documentation columns (optional; empty/zero when absent in older CSVs).
 		row.Originalwaehrung = valueForColumn(record, headerMap, "Originalwaehrung")
 		row.Originalbetrag_Brutto = parseFloat(valueForColumn(record, headerMap, "Originalbetrag_Brutto"))
@@ -538,6 +542,7 @@ func (r *CSVRepository) rowToRecord(row CSVRow) []string {
 		"Steuerzeilen":          MarshalTaxLines(row.TaxLines),
 		"Buchung":               MarshalBooking(row.Buchung),
 		"Exportiert":            fmt.Sprintf("%t", row.Exportiert),
+		"StornoZu":              row.StornoZu,
 		"Originalwaehrung":      row.Originalwaehrung,
 		"Originalbetrag_Brutto": r.formatFloat(row.Originalbetrag_Brutto),
 	}
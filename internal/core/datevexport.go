@@ -51,7 +51,7 @@ func BuildDATEVStapel(h DATEVHeader, rows []CSVRow) ([]byte, int, int) {
 
 	exported, skipped := 0, 0
 	for _, r := range rows {
-		base, counters, ok := r.Buchung.PaymentAndCounters(r.Ausgangsrechnung)
+		base, counters, ok := r.ExportSplit()
 		if !r.Buchung.Balanced() || !ok {
 			skipped++
 			continue
@@ -68,6 +68,7 @@ func BuildDATEVStapel(h DATEVHeader, rows []CSVRow) ([]byte, int, int) {
 		belegfeld2 := datevClean(r.Rechnungsnummer, 36)
 		text := datevClean(strings.TrimSpace(r.Auftraggeber+" "+r.Verwendungszweck), 60)
 		for _, e := range counters {
+			e = e.Unsigned()
 			sh := "S"
 			if !e.Soll {
 				sh = "H"
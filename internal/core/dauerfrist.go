@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sondervorauszahlung returns the Sondervorauszahlung (Kz 38) a monthly filer
+// with Dauerfristverlängerung pays for a year: 1/11 of the previous year's
+// Vorauszahlungen, i.e. of its returns' Kz 83 before the deduction of that
+// year's own Sondervorauszahlung (Kz 39 added back). Never negative.
+func Sondervorauszahlung(vorjahr []UStVAOfficial) float64 {
+	var summe float64
+	for _, u := range vorjahr {
+		summe += u.Kz83 + u.Kz39
+	}
+	if summe <= 0 {
+		return 0
+	}
+	return round2(summe / 11)
+}
+
+// MitSondervorauszahlung returns the December return with the year's
+// Sondervorauszahlung deducted (Kz 39) from the remaining Vorauszahlung
+// (Kz 83).
+func (u UStVAOfficial) MitSondervorauszahlung(svz float64) UStVAOfficial {
+	u.Kz39 = round2(svz)
+	u.Kz83 = round2(u.Kz83 - u.Kz39)
+	return u
+}
+
+// UStVAFrist is one due date of the VAT calendar: the return for the months
+// VonMonat..BisMonat of Jahr, or (VonMonat == 0) the Anmeldung and payment of
+// the Sondervorauszahlung.
+type UStVAFrist struct {
+	Jahr     int
+	VonMonat int
+	BisMonat int
+	Faellig  time.Time
+}
+
+// Sondervorauszahlung reports whether the Frist is the Sondervorauszahlung's.
+func (f UStVAFrist) Sondervorauszahlung() bool {
+	return f.VonMonat == 0
+}
+
+// Zeitraum renders the period as the UStVA exports name it: "YYYY-MM",
+// "YYYY-QN", or "YYYY-SVZ" for the Sondervorauszahlung.
+func (f UStVAFrist) Zeitraum() string {
+	switch {
+	case f.Sondervorauszahlung():
+		return fmt.Sprintf("%04d-SVZ", f.Jahr)
+	case f.VonMonat == f.BisMonat:
+		return fmt.Sprintf("%04d-%02d", f.Jahr, f.VonMonat)
+	default:
+		return fmt.Sprintf("%04d-Q%d", f.Jahr, (f.VonMonat-1)/3+1)
+	}
+}
+
+// UStVAFristen returns the due dates of a year's returns (§ 18 UStG): the
+// 10th of the month after the period, one month later with
+// Dauerfristverlängerung (§§ 46–48 UStDV). A monthly filer with the extension
+// first owes the Sondervorauszahlung, due with its Anmeldung on 10 February.
+// Dates on a weekend move to the Monday (§ 108 Abs. 3 AO); public holidays
+// are not considered.
+func UStVAFristen(jahr int, quartal, dauerfrist bool) []UStVAFrist {
+	var out []UStVAFrist
+	if dauerfrist && !quartal {
+		out = append(out, UStVAFrist{Jahr: jahr, Faellig: werktag(time.Date(jahr, time.February, 10, 0, 0, 0, 0, time.UTC))})
+	}
+	schritt := 1
+	if quartal {
+		schritt = 3
+	}
+	for von := 1; von <= 12; von += schritt {
+		bis := von + schritt - 1
+		nach := 1
+		if dauerfrist {
+			nach = 2
+		}
+		faellig := time.Date(jahr, time.Month(bis+nach), 10, 0, 0, 0, 0, time.UTC)
+		out = append(out, UStVAFrist{Jahr: jahr, VonMonat: von, BisMonat: bis, Faellig: werktag(faellig)})
+	}
+	return out
+}
+
+// werktag moves a Saturday or Sunday to the following Monday.
+func werktag(t time.Time) time.Time {
+	switch t.Weekday() {
+	case time.Saturday:
+		return t.AddDate(0, 0, 2)
+	case time.Sunday:
+		return t.AddDate(0, 0, 1)
+	}
+	return t
+}
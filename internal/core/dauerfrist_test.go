@@ -0,0 +1,65 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSondervorauszahlung(t *testing.T) {
+	vorjahr := make([]UStVAOfficial, 12)
+	for i := range vorjahr {
+		vorjahr[i].Kz83 = 1000
+	}
+	vorjahr[3].Kz83 = -1000 // an Überschuss reduces the sum
+	// December of the previous year had its own Sondervorauszahlung deducted.
+	vorjahr[11] = vorjahr[11].MitSondervorauszahlung(900)
+	if vorjahr[11].Kz83 != 100 || vorjahr[11].Kz39 != 900 {
+		t.Fatalf("December = %+v", vorjahr[11])
+	}
+	if got := Sondervorauszahlung(vorjahr); got != 909.09 {
+		t.Errorf("Sondervorauszahlung = %v", got)
+	}
+	if got := Sondervorauszahlung([]UStVAOfficial{{Kz83: -500}}); got != 0 {
+		t.Errorf("Überschuss year = %v", got)
+	}
+}
+
+func TestUStVAFristen(t *testing.T) {
+	monat := UStVAFristen(2026, false, false)
+	if len(monat) != 12 || monat[0].Zeitraum() != "2026-01" || monat[0].Faellig.Format("02.01.2006") != "10.02.2026" {
+		t.Fatalf("monthly = %+v", monat[0])
+	}
+	// 10.01.2027 is a Sunday.
+	if d := monat[11].Faellig.Format("02.01.2006"); d != "11.01.2027" {
+		t.Errorf("December due %s", d)
+	}
+
+	dfv := UStVAFristen(2026, false, true)
+	if len(dfv) != 13 || !dfv[0].Sondervorauszahlung() || dfv[0].Zeitraum() != "2026-SVZ" ||
+		dfv[0].Faellig.Format("02.01.2006") != "10.02.2026" {
+		t.Fatalf("SVZ = %+v", dfv[0])
+	}
+	if d := dfv[1].Faellig.Format("02.01.2006"); d != "10.03.2026" {
+		t.Errorf("January with extension due %s", d)
+	}
+
+	quartal := UStVAFristen(2026, true, true)
+	if len(quartal) != 4 || quartal[0].Zeitraum() != "2026-Q1" || quartal[3].Zeitraum() != "2026-Q4" {
+		t.Fatalf("quarterly = %+v", quartal)
+	}
+	// Q1 with extension: 10.05.2026 is a Sunday.
+	if d := quartal[0].Faellig.Format("02.01.2006"); d != "11.05.2026" {
+		t.Errorf("Q1 due %s", d)
+	}
+}
+
+func TestUStVAKz39Exports(t *testing.T) {
+	u := UStVAOfficial{Kz81: 10000, USt81: 1900, Kz83: 1900}.MitSondervorauszahlung(500)
+	data, err := BuildUStVAXML(u, "2026-12", "")
+	if err != nil || !strings.Contains(string(data), `nr="39"`) || !strings.Contains(string(data), "<wert>1400</wert>") {
+		t.Errorf("XML:\n%s", data)
+	}
+	if _, err := BuildUStVAPDF(u, "USt-Voranmeldung", "Test"); err != nil {
+		t.Errorf("PDF: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"os"
@@ -14,12 +15,47 @@ import (
 type EInvoiceFormat string
 
 const (
-	FormatXRechnung EInvoiceFormat = "XRechnung"
-	FormatZUGFeRD   EInvoiceFormat = "ZUGFeRD"
-	FormatNone      EInvoiceFormat = ""
+	FormatXRechnung    EInvoiceFormat = "XRechnung"
+	FormatXRechnungUBL EInvoiceFormat = "XRechnung (UBL)"
+	FormatZUGFeRD      EInvoiceFormat = "ZUGFeRD"
+	FormatNone         EInvoiceFormat = ""
 )
 
-// EInvoiceExtractor extracts structured data from XRechnung and ZUGFeRD PDFs.
+// EInvoiceSyntax is the XML syntax an e-invoice is written in. EN 16931
+// allows two: UN/CEFACT Cross Industry Invoice (used by ZUGFeRD/Factur-X and
+// CII-XRechnung) and OASIS UBL 2.1 (common for XRechnung).
+type EInvoiceSyntax string
+
+const (
+	SyntaxCII  EInvoiceSyntax = "CII"
+	SyntaxUBL  EInvoiceSyntax = "UBL"
+	SyntaxNone EInvoiceSyntax = ""
+)
+
+// DetectSyntax reports the syntax of an e-invoice XML by its root element:
+// <CrossIndustryInvoice> is CII, <Invoice> or <CreditNote> is UBL. Returns
+// SyntaxNone for anything else (including malformed XML).
+func DetectSyntax(xmlData []byte) EInvoiceSyntax {
+	dec := xml.NewDecoder(bytes.NewReader(xmlData))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return SyntaxNone
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			switch se.Name.Local {
+			case "CrossIndustryInvoice":
+				return SyntaxCII
+			case "Invoice", "CreditNote":
+				return SyntaxUBL
+			}
+			return SyntaxNone
+		}
+	}
+}
+
+// EInvoiceExtractor extracts structured data from XRechnung and ZUGFeRD PDFs
+// and from standalone e-invoice XML files.
 type EInvoiceExtractor struct{}
 
 // NewEInvoiceExtractor creates a new e-invoice extractor.
@@ -27,14 +63,26 @@ func NewEInvoiceExtractor() *EInvoiceExtractor {
 	return &EInvoiceExtractor{}
 }
 
-// DetectFormat checks if a PDF contains XRechnung or ZUGFeRD data.
+// DetectFormat checks if a PDF contains XRechnung or ZUGFeRD data, or if a
+// standalone .xml file is such an e-invoice.
 // Returns the format type and true if detected, empty string and false otherwise.
-func (e *EInvoiceExtractor) DetectFormat(pdfPath string) (EInvoiceFormat, bool) {
-	xmlData, attachmentName, err := e.extractXMLAttachment(pdfPath)
+func (e *EInvoiceExtractor) DetectFormat(path string) (EInvoiceFormat, bool) {
+	xmlData, attachmentName, err := e.loadXML(path)
 	if err != nil || xmlData == nil {
 		return FormatNone, false
 	}
 
+	// UBL exists only as XRechnung (ZUGFeRD/Factur-X is always CII).
+	syntax := DetectSyntax(xmlData)
+	if syntax == SyntaxUBL {
+		return FormatXRechnungUBL, true
+	}
+	// A bare XML file must itself be an invoice; an arbitrary XML (e.g. a
+	// CAMT statement) is not an e-invoice.
+	if IsXML(path) && syntax == SyntaxNone {
+		return FormatNone, false
+	}
+
 	// Check filename to determine format
 	lowerName := strings.ToLower(attachmentName)
 	if strings.Contains(lowerName, "factur-x") || strings.Contains(lowerName, "zugferd") {
@@ -57,11 +105,12 @@ func (e *EInvoiceExtractor) DetectFormat(pdfPath string) (EInvoiceFormat, bool)
 	return FormatZUGFeRD, true
 }
 
-// Extract extracts invoice metadata from XRechnung/ZUGFeRD XML.
-// Returns Meta with confidence 1.0 (perfect accuracy for structured data).
-func (e *EInvoiceExtractor) Extract(pdfPath string) (Meta, float64, error) {
-	// Extract XML attachment
-	xmlData, _, err := e.extractXMLAttachment(pdfPath)
+// Extract extracts invoice metadata from XRechnung/ZUGFeRD XML in CII or UBL
+// syntax and validates it (Meta.Pruefbericht). Returns Meta with confidence
+// 1.0 (perfect accuracy for structured data).
+func (e *EInvoiceExtractor) Extract(path string) (Meta, float64, error) {
+	// Extract XML attachment (or read the standalone XML file)
+	xmlData, _, err := e.loadXML(path)
 	if err != nil {
 		return Meta{}, 0, fmt.Errorf("failed to extract XML: %w", err)
 	}
@@ -69,19 +118,54 @@ func (e *EInvoiceExtractor) Extract(pdfPath string) (Meta, float64, error) {
 		return Meta{}, 0, fmt.Errorf("no XML attachment found")
 	}
 
-	// Parse XML
-	invoice, err := e.parseXML(xmlData)
+	meta, err := e.extractFromXML(xmlData)
 	if err != nil {
-		return Meta{}, 0, fmt.Errorf("failed to parse XML: %w", err)
+		return Meta{}, 0, err
 	}
-
-	// Map to Meta structure
-	meta := e.mapToMeta(invoice)
+	// Check the business rules; findings are advisory and travel with the
+	// Meta to the confirmation dialog.
+	if v, err := ValidateEInvoiceXML(xmlData); err == nil {
+		meta.Pruefbericht = &v
+	}
+	SetProvenance(&meta, SourceEInvoice, 1.0)
 
 	// Confidence is always 1.0 for structured data
 	return meta, 1.0, nil
 }
 
+// extractFromXML parses e-invoice XML in either syntax and maps it to Meta.
+func (e *EInvoiceExtractor) extractFromXML(xmlData []byte) (Meta, error) {
+	switch DetectSyntax(xmlData) {
+	case SyntaxCII:
+		invoice, err := e.parseXML(xmlData)
+		if err != nil {
+			return Meta{}, fmt.Errorf("failed to parse XML: %w", err)
+		}
+		return e.mapToMeta(invoice), nil
+	case SyntaxUBL:
+		invoice, err := e.parseUBL(xmlData)
+		if err != nil {
+			return Meta{}, fmt.Errorf("failed to parse XML: %w", err)
+		}
+		return e.mapUBLToMeta(invoice), nil
+	}
+	return Meta{}, fmt.Errorf("unknown e-invoice syntax (neither CII nor UBL)")
+}
+
+// loadXML returns the e-invoice XML for path: the file itself for a
+// standalone .xml, otherwise the embedded attachment of the PDF. The returned
+// name is the file or attachment name, used for format detection.
+func (e *EInvoiceExtractor) loadXML(path string) ([]byte, string, error) {
+	if IsXML(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read XML file: %w", err)
+		}
+		return data, filepath.Base(path), nil
+	}
+	return e.extractXMLAttachment(path)
+}
+
 // extractXMLAttachment extracts the first XML attachment from a PDF.
 // Returns the XML data, attachment filename, and error.
 // Uses pdfcpu library to extract embedded files from PDF/A-3 documents.
@@ -157,6 +241,7 @@ func (e *EInvoiceExtractor) extractXMLAttachment(pdfPath string) ([]byte, string
 // CrossIndustryInvoice represents the root element of CII XML.
 type CrossIndustryInvoice struct {
 	XMLName                     xml.Name                    `xml:"CrossIndustryInvoice"`
+	GuidelineID                 string                      `xml:"ExchangedDocumentContext>GuidelineSpecifiedDocumentContextParameter>ID"` // BT-24
 	ExchangedDocument           ExchangedDocument           `xml:"ExchangedDocument"`
 	SupplyChainTradeTransaction SupplyChainTradeTransaction `xml:"SupplyChainTradeTransaction"`
 }
@@ -164,6 +249,7 @@ type CrossIndustryInvoice struct {
 // ExchangedDocument contains document-level information.
 type ExchangedDocument struct {
 	ID            string        `xml:"ID"`
+	TypeCode      string        `xml:"TypeCode"`
 	IssueDateTime IssueDateTime `xml:"IssueDateTime"`
 }
 
@@ -180,38 +266,132 @@ type DateTimeString struct {
 
 // SupplyChainTradeTransaction contains the main transaction details.
 type SupplyChainTradeTransaction struct {
-	ApplicableHeaderTradeAgreement  HeaderTradeAgreement  `xml:"ApplicableHeaderTradeAgreement"`
-	ApplicableHeaderTradeSettlement HeaderTradeSettlement `xml:"ApplicableHeaderTradeSettlement"`
+	IncludedSupplyChainTradeLineItem []TradeLineItem       `xml:"IncludedSupplyChainTradeLineItem"`
+	ApplicableHeaderTradeAgreement   HeaderTradeAgreement  `xml:"ApplicableHeaderTradeAgreement"`
+	ApplicableHeaderTradeSettlement  HeaderTradeSettlement `xml:"ApplicableHeaderTradeSettlement"`
+}
+
+// TradeLineItem is one invoice line (BG-25).
+type TradeLineItem struct {
+	LineID         string   `xml:"AssociatedDocumentLineDocument>LineID"`
+	ProductName    string   `xml:"SpecifiedTradeProduct>Name"`
+	NetPrice       Amount   `xml:"SpecifiedLineTradeAgreement>NetPriceProductTradePrice>ChargeAmount"`
+	BilledQuantity Quantity `xml:"SpecifiedLineTradeDelivery>BilledQuantity"`
+	Tax            TradeTax `xml:"SpecifiedLineTradeSettlement>ApplicableTradeTax"`
+	LineTotal      Amount   `xml:"SpecifiedLineTradeSettlement>SpecifiedTradeSettlementLineMonetarySummation>LineTotalAmount"`
+}
+
+// Quantity is an invoiced quantity with its UN/ECE unit code.
+type Quantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
 }
 
 // HeaderTradeAgreement contains party information.
 type HeaderTradeAgreement struct {
+	BuyerReference   string     `xml:"BuyerReference"` // BT-10, Leitweg-ID for XRechnung
 	SellerTradeParty TradeParty `xml:"SellerTradeParty"`
+	BuyerTradeParty  TradeParty `xml:"BuyerTradeParty"`
 }
 
 // TradeParty represents a party (seller or buyer).
 type TradeParty struct {
-	Name string `xml:"Name"`
+	Name                     string            `xml:"Name"`
+	DefinedTradeContact      TradeContact      `xml:"DefinedTradeContact"`
+	PostalTradeAddress       TradeAddress      `xml:"PostalTradeAddress"`
+	SpecifiedTaxRegistration []TaxRegistration `xml:"SpecifiedTaxRegistration"`
+}
+
+// TradeContact is a party's contact point (BG-6 for the seller).
+type TradeContact struct {
+	PersonName string `xml:"PersonName"`
+	Telephone  string `xml:"TelephoneUniversalCommunication>CompleteNumber"`
+	Email      string `xml:"EmailURIUniversalCommunication>URIID"`
+}
+
+// TradeAddress is a party's postal address.
+type TradeAddress struct {
+	PostcodeCode string `xml:"PostcodeCode"`
+	CityName     string `xml:"CityName"`
+	CountryID    string `xml:"CountryID"`
+}
+
+// TaxRegistration is a party's tax number; schemeID "VA" marks the USt-IdNr,
+// "FC" the national Steuernummer.
+type TaxRegistration struct {
+	ID TaxRegistrationID `xml:"ID"`
+}
+
+// TaxRegistrationID is the registration value with its scheme.
+type TaxRegistrationID struct {
+	SchemeID string `xml:"schemeID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// VATID returns the party's USt-IdNr (schemeID "VA"), or "" if it has none.
+func (p TradeParty) VATID() string {
+	return p.taxRegistration("VA")
+}
+
+// TaxNumber returns the party's national Steuernummer (schemeID "FC").
+func (p TradeParty) TaxNumber() string {
+	return p.taxRegistration("FC")
+}
+
+func (p TradeParty) taxRegistration(scheme string) string {
+	for _, r := range p.SpecifiedTaxRegistration {
+		if strings.EqualFold(r.ID.SchemeID, scheme) {
+			return strings.TrimSpace(r.ID.Value)
+		}
+	}
+	return ""
 }
 
 // HeaderTradeSettlement contains payment and amount information.
 type HeaderTradeSettlement struct {
 	InvoiceCurrencyCode                             string             `xml:"InvoiceCurrencyCode"`
 	ApplicableTradeTax                              []TradeTax         `xml:"ApplicableTradeTax"`
+	SpecifiedTradeSettlementPaymentMeans            []PaymentMeans     `xml:"SpecifiedTradeSettlementPaymentMeans"`
+	SpecifiedTradeAllowanceCharge                   []AllowanceCharge  `xml:"SpecifiedTradeAllowanceCharge"`
 	SpecifiedTradePaymentTerms                      *TradePaymentTerms `xml:"SpecifiedTradePaymentTerms"`
 	SpecifiedTradeSettlementHeaderMonetarySummation MonetarySummation  `xml:"SpecifiedTradeSettlementHeaderMonetarySummation"`
 }
 
-// TradeTax represents tax information.
+// TradeTax represents tax information: one VAT breakdown line (BG-23) on
+// the header, or the line's VAT category and rate on an item.
 type TradeTax struct {
 	CalculatedAmount      Amount `xml:"CalculatedAmount"`
 	BasisAmount           Amount `xml:"BasisAmount"`
+	CategoryCode          string `xml:"CategoryCode"`
+	ExemptionReason       string `xml:"ExemptionReason"`
+	ExemptionReasonCode   string `xml:"ExemptionReasonCode"`
 	RateApplicablePercent string `xml:"RateApplicablePercent"`
 }
 
-// Amount represents a monetary amount.
+// PaymentMeans is a payment instruction (BG-16); TypeCode is BT-81.
+type PaymentMeans struct {
+	TypeCode string `xml:"TypeCode"`
+}
+
+// AllowanceCharge is a document-level allowance (Nachlass, BG-20) or charge
+// (Zuschlag, BG-21) with the VAT rate it falls under.
+type AllowanceCharge struct {
+	ChargeIndicator string   `xml:"ChargeIndicator>Indicator"`
+	ActualAmount    Amount   `xml:"ActualAmount"`
+	Reason          string   `xml:"Reason"`
+	Tax             TradeTax `xml:"CategoryTradeTax"`
+}
+
+// IsCharge reports whether the entry is a charge rather than an allowance.
+func (ac AllowanceCharge) IsCharge() bool {
+	return strings.EqualFold(strings.TrimSpace(ac.ChargeIndicator), "true")
+}
+
+// Amount represents a monetary amount. CurrencyID is only set where the
+// syntax allows it (TaxTotalAmount).
 type Amount struct {
-	Value string `xml:",chardata"`
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
 }
 
 // TradePaymentTerms contains payment terms.
@@ -221,10 +401,30 @@ type TradePaymentTerms struct {
 
 // MonetarySummation contains total amounts.
 type MonetarySummation struct {
-	LineTotalAmount     Amount `xml:"LineTotalAmount"`
-	TaxBasisTotalAmount Amount `xml:"TaxBasisTotalAmount"`
-	TaxTotalAmount      Amount `xml:"TaxTotalAmount"`
-	GrandTotalAmount    Amount `xml:"GrandTotalAmount"`
+	LineTotalAmount      Amount   `xml:"LineTotalAmount"`
+	ChargeTotalAmount    Amount   `xml:"ChargeTotalAmount"`
+	AllowanceTotalAmount Amount   `xml:"AllowanceTotalAmount"`
+	TaxBasisTotalAmount  Amount   `xml:"TaxBasisTotalAmount"`
+	TaxTotalAmount       []Amount `xml:"TaxTotalAmount"` // BT-110, optionally BT-111 in accounting currency
+	RoundingAmount       Amount   `xml:"RoundingAmount"`
+	GrandTotalAmount     Amount   `xml:"GrandTotalAmount"`
+	TotalPrepaidAmount   Amount   `xml:"TotalPrepaidAmount"`
+	DuePayableAmount     Amount   `xml:"DuePayableAmount"`
+}
+
+// TaxTotal returns the VAT total in the invoice currency (BT-110): the
+// TaxTotalAmount whose currencyID matches currency, else the first one.
+// ok is false when the invoice states none.
+func (m MonetarySummation) TaxTotal(currency string) (Amount, bool) {
+	for _, a := range m.TaxTotalAmount {
+		if strings.EqualFold(strings.TrimSpace(a.CurrencyID), strings.TrimSpace(currency)) {
+			return a, true
+		}
+	}
+	if len(m.TaxTotalAmount) > 0 {
+		return m.TaxTotalAmount[0], true
+	}
+	return Amount{}, false
 }
 
 // parseXML parses the CII XML into a structured format.
@@ -254,38 +454,77 @@ func (e *EInvoiceExtractor) mapToMeta(invoice *CrossIndustryInvoice) Meta {
 		meta.Monat = dateStr[4:6] // MM
 	}
 
-	// Company name (seller)
-	meta.Auftraggeber = invoice.SupplyChainTradeTransaction.ApplicableHeaderTradeAgreement.SellerTradeParty.Name
+	// Company name and VAT-ID (seller)
+	seller := invoice.SupplyChainTradeTransaction.ApplicableHeaderTradeAgreement.SellerTradeParty
+	meta.Auftraggeber = seller.Name
+	meta.VATID = seller.VATID()
 
 	// Currency
 	meta.Waehrung = invoice.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement.InvoiceCurrencyCode
 
-	// Amounts
+	// Positions: the invoice lines plus document-level allowances/charges,
+	// so they add up to the tax basis and can split the expense.
 	settlement := invoice.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
-	meta.BetragNetto = parseXMLAmount(settlement.SpecifiedTradeSettlementHeaderMonetarySummation.TaxBasisTotalAmount.Value)
-	meta.Bruttobetrag = parseXMLAmount(settlement.SpecifiedTradeSettlementHeaderMonetarySummation.GrandTotalAmount.Value)
+	meta.Positionen = ciiPositions(invoice)
 
-	// Tax information (use first tax entry)
-	if len(settlement.ApplicableTradeTax) > 0 {
-		tax := settlement.ApplicableTradeTax[0]
-		meta.SteuersatzProzent = parseXMLAmount(tax.RateApplicablePercent)
-		meta.SteuersatzBetrag = parseXMLAmount(tax.CalculatedAmount.Value)
+	// Tax information: one TaxLine per VAT breakdown line
+	meta.TaxLines = ciiTaxLines(settlement, meta.Positionen)
+	meta.SteuersatzProzent = PrimarySatz(meta.TaxLines)
+
+	// Amounts. A missing total falls back to the sum of the breakdown.
+	sums := settlement.SpecifiedTradeSettlementHeaderMonetarySummation
+	meta.BetragNetto = parseXMLAmount(sums.TaxBasisTotalAmount.Value)
+	if strings.TrimSpace(sums.TaxBasisTotalAmount.Value) == "" {
+		meta.BetragNetto = round2(SumNetto(meta.TaxLines))
+	}
+	// The VAT total is the sum of the breakdown (BR-CO-14); the stated
+	// TaxTotalAmount is only the fallback.
+	meta.SteuersatzBetrag = round2(SumMwSt(meta.TaxLines))
+	if t, ok := sums.TaxTotal(meta.Waehrung); ok && len(meta.TaxLines) == 0 {
+		meta.SteuersatzBetrag = parseXMLAmount(t.Value)
 	}
+	meta.Bruttobetrag = parseXMLAmount(sums.GrandTotalAmount.Value)
 
 	// Payment due date (optional)
 	if settlement.SpecifiedTradePaymentTerms != nil &&
 		settlement.SpecifiedTradePaymentTerms.DueDateDateTime != nil {
 		dueDateStr := settlement.SpecifiedTradePaymentTerms.DueDateDateTime.DateTimeString.Value
-		meta.Bezahldatum = e.convertDateFormat(dueDateStr)
+		meta.Faelligkeit = e.convertDateFormat(dueDateStr)
 	}
 
 	// Verwendungszweck: Create a short description from the invoice number
 	// This can be customized based on needs
-	meta.Verwendungszweck = fmt.Sprintf("Rechnung %s", meta.Rechnungsnummer)
+	if strings.TrimSpace(invoice.ExchangedDocument.TypeCode) == ciiTypeGutschrift {
+		negateGutschrift(&meta)
+		meta.Verwendungszweck = fmt.Sprintf("Gutschrift %s", meta.Rechnungsnummer)
+	} else {
+		meta.Verwendungszweck = fmt.Sprintf("Rechnung %s", meta.Rechnungsnummer)
+	}
 
 	return meta
 }
 
+// ciiTypeGutschrift is the CII document type code (BT-3) of a credit note.
+const ciiTypeGutschrift = "381"
+
+// negateGutschrift turns the amounts of a credit note, which the XML states
+// as positive, into those of a reversal: net, VAT, gross, the tax lines and
+// the positions become negative, so it books against the expense and the
+// Vorsteuer instead of claiming them again.
+func negateGutschrift(meta *Meta) {
+	meta.BetragNetto = -meta.BetragNetto
+	meta.SteuersatzBetrag = -meta.SteuersatzBetrag
+	meta.Bruttobetrag = -meta.Bruttobetrag
+	for i := range meta.TaxLines {
+		meta.TaxLines[i].Netto = -meta.TaxLines[i].Netto
+		meta.TaxLines[i].MwStBetrag = -meta.TaxLines[i].MwStBetrag
+	}
+	for i := range meta.Positionen {
+		meta.Positionen[i].Netto = -meta.Positionen[i].Netto
+		meta.Positionen[i].Einzelpreis = -meta.Positionen[i].Einzelpreis
+	}
+}
+
 // convertDateFormat converts YYYYMMDD to DD.MM.YYYY.
 func (e *EInvoiceExtractor) convertDateFormat(dateStr string) string {
 	// Remove any spaces and validate length
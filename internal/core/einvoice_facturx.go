@@ -0,0 +1,419 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// FacturXFileName is the attachment name ZUGFeRD 2.x / Factur-X prescribe
+// for the embedded CII XML.
+const FacturXFileName = "factur-x.xml"
+
+// EInvoiceFileName returns the file name of a generated e-invoice for an
+// invoice file: "<base>_XRechnung.xml" for an XML, "<base>_ZUGFeRD.pdf" for
+// a hybrid PDF.
+func EInvoiceFileName(invoiceName string, pdf bool) string {
+	base := strings.TrimSuffix(invoiceName, filepath.Ext(invoiceName))
+	if pdf {
+		return base + "_ZUGFeRD.pdf"
+	}
+	return base + "_XRechnung.xml"
+}
+
+// EmbedFacturX turns a PDF into a ZUGFeRD/Factur-X hybrid invoice: ciiXML is
+// embedded as factur-x.xml (AFRelationship Alternative, MIME text/xml),
+// referenced from the catalog's /AF array, and the document gets XMP
+// metadata with the Factur-X extension schema and conformance level
+// EN 16931. title goes into the Info dictionary and dc:title, modTime is
+// the attachment's modification date.
+//
+// For PDF/A-3 the document gets an sRGB output intent unless it has one,
+// and title, author, creator, producer and dates of the Info dictionary
+// are mirrored into the XMP packet. The PDF/A-3B identification is only
+// written when every font is embedded (BuildRechnungPDF embeds its fonts;
+// an archived PDF or the Sichtbeleg with the standard fonts does not), so
+// the file never claims a conformance it cannot have. The e-invoice data
+// itself is complete either way.
+func EmbedFacturX(pdfData, ciiXML []byte, title string, modTime time.Time) ([]byte, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.ADDATTACHMENTS
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdfData), conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	xrt := ctx.XRefTable
+	if err := xrt.LocateNameTree("EmbeddedFiles", true); err != nil {
+		return nil, fmt.Errorf("failed to prepare attachments: %w", err)
+	}
+	existing, err := ctx.ListAttachments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	for _, a := range existing {
+		if strings.EqualFold(a.FileName, FacturXFileName) || strings.EqualFold(a.ID, FacturXFileName) {
+			return nil, fmt.Errorf("PDF enthält bereits eine %s", FacturXFileName)
+		}
+	}
+
+	// Embedded file stream with the MIME subtype PDF/A-3 requires.
+	sd, err := xrt.NewStreamDictForBuf(ciiXML)
+	if err != nil {
+		return nil, err
+	}
+	sd.InsertName("Type", "EmbeddedFile")
+	sd.InsertName("Subtype", "text/xml")
+	params := types.NewDict()
+	params.InsertInt("Size", len(ciiXML))
+	params.Insert("ModDate", types.StringLiteral(types.DateString(modTime)))
+	sd.Insert("Params", params)
+	if err := sd.Encode(); err != nil {
+		return nil, err
+	}
+	sdRef, err := xrt.IndRefForNewObject(*sd)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := xrt.NewFileSpecDict(FacturXFileName, FacturXFileName, "Factur-X/ZUGFeRD-Rechnung", *sdRef)
+	if err != nil {
+		return nil, err
+	}
+	fs.InsertName("AFRelationship", "Alternative")
+	fsRef, err := xrt.IndRefForNewObject(fs)
+	if err != nil {
+		return nil, err
+	}
+	m := model.NameMap{FacturXFileName: []types.Dict{fs}}
+	if err := xrt.Names["EmbeddedFiles"].Add(xrt, FacturXFileName, *fsRef, m, []string{"F", "UF"}); err != nil {
+		return nil, fmt.Errorf("failed to add %s: %w", FacturXFileName, err)
+	}
+
+	catalog, err := xrt.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	if _, found := catalog.Find("OutputIntents"); !found {
+		oi, err := srgbOutputIntent(xrt)
+		if err != nil {
+			return nil, err
+		}
+		catalog.Insert("OutputIntents", types.Array{oi})
+	}
+
+	// Info dictionary: PDF/A wants each entry repeated in the XMP packet,
+	// so Subject and Keywords (which BuchISY never sets) are dropped.
+	info, err := facturXInfo(xrt, title)
+	if err != nil {
+		return nil, err
+	}
+	author, _ := infoText(xrt, info, "Author")
+	creator, _ := infoText(xrt, info, "Creator")
+
+	// XMP metadata stream, uncompressed so validators can read it and the
+	// date placeholder can be filled in after writing.
+	xmp := []byte(facturXMP(title, author, creator, fontsEingebettet(xrt)))
+	md := types.StreamDict{Dict: types.NewDict(), Content: xmp}
+	md.InsertName("Type", "Metadata")
+	md.InsertName("Subtype", "XML")
+	if err := md.Encode(); err != nil {
+		return nil, err
+	}
+	mdRef, err := xrt.IndRefForNewObject(md)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog.Update("Metadata", *mdRef)
+	af := types.Array{*fsRef}
+	if o, found := catalog.Find("AF"); found {
+		if arr, err := xrt.DereferenceArray(o); err == nil {
+			af = append(arr, *fsRef)
+		}
+	}
+	catalog.Update("AF", af)
+	v := model.V17
+	xrt.HeaderVersion = &v
+	xrt.RootVersion = nil
+	catalog.Delete("Version")
+
+	var out bytes.Buffer
+	if err := api.Write(ctx, &out, conf); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	// pdfcpu stamps CreationDate and ModDate while writing; the XMP dates
+	// take the same instant. The placeholder has the length of the date,
+	// so the stream length and the xref offsets stay valid.
+	written := time.Now()
+	if o, found := info.Find("CreationDate"); found {
+		if sl, ok := o.(types.StringLiteral); ok {
+			if t, ok := types.DateTime(sl.Value(), true); ok {
+				written = t
+			}
+		}
+	}
+	return bytes.ReplaceAll(out.Bytes(), []byte(xmpDatePlaceholder), []byte(written.Format(xmpDateLayout))), nil
+}
+
+// xmpDateLayout is the XMP date format with a numeric zone, and
+// xmpDatePlaceholder a stand-in of the same length.
+const (
+	xmpDateLayout      = "2006-01-02T15:04:05-07:00"
+	xmpDatePlaceholder = "0000-00-00T00:00:00+00:00"
+)
+
+// facturXInfo returns the document's Info dictionary (creating one if the
+// PDF has none) with the title set and Subject and Keywords removed.
+func facturXInfo(xrt *model.XRefTable, title string) (types.Dict, error) {
+	if xrt.Info == nil {
+		ref, err := xrt.IndRefForNewObject(types.NewDict())
+		if err != nil {
+			return nil, err
+		}
+		xrt.Info = ref
+	}
+	info, err := xrt.DereferenceDict(*xrt.Info)
+	if err != nil || info == nil {
+		return nil, fmt.Errorf("failed to read document info: %w", err)
+	}
+	s, err := types.EscapedUTF16String(title)
+	if err != nil {
+		return nil, err
+	}
+	info.Update("Title", types.StringLiteral(*s))
+	info.Delete("Subject")
+	info.Delete("Keywords")
+	if creator, _ := infoText(xrt, info, "Creator"); creator == "" {
+		info.Update("Creator", types.StringLiteral("BuchISY"))
+	}
+	return info, nil
+}
+
+// infoText returns a text entry of the Info dictionary.
+func infoText(xrt *model.XRefTable, info types.Dict, key string) (string, error) {
+	o, found := info.Find(key)
+	if !found {
+		return "", nil
+	}
+	return xrt.DereferenceText(o)
+}
+
+// fontsEingebettet reports whether every font of the document carries its
+// font program. Type 3 fonts are defined in the PDF itself; Type 0 fonts
+// are checked through their descendant CID fonts.
+func fontsEingebettet(xrt *model.XRefTable) bool {
+	for _, e := range xrt.Table {
+		if e == nil || e.Free || e.Object == nil {
+			continue
+		}
+		d, ok := e.Object.(types.Dict)
+		if !ok || d.Type() == nil || *d.Type() != "Font" {
+			continue
+		}
+		if st := d.Subtype(); st != nil && (*st == "Type0" || *st == "Type3") {
+			continue
+		}
+		o, found := d.Find("FontDescriptor")
+		if !found {
+			return false
+		}
+		fd, err := xrt.DereferenceDict(o)
+		if err != nil || fd == nil {
+			return false
+		}
+		_, f1 := fd.Find("FontFile")
+		_, f2 := fd.Find("FontFile2")
+		_, f3 := fd.Find("FontFile3")
+		if !f1 && !f2 && !f3 {
+			return false
+		}
+	}
+	return true
+}
+
+// srgbOutputIntent adds the sRGB ICC profile to the document and returns
+// the PDF/A output intent dictionary referring to it.
+func srgbOutputIntent(xrt *model.XRefTable) (types.Dict, error) {
+	sd, err := xrt.NewStreamDictForBuf(srgbICCProfile())
+	if err != nil {
+		return nil, err
+	}
+	sd.InsertInt("N", 3)
+	if err := sd.Encode(); err != nil {
+		return nil, err
+	}
+	ref, err := xrt.IndRefForNewObject(*sd)
+	if err != nil {
+		return nil, err
+	}
+	oi := types.NewDict()
+	oi.InsertName("Type", "OutputIntent")
+	oi.InsertName("S", "GTS_PDFA1")
+	oi.Insert("OutputConditionIdentifier", types.StringLiteral("sRGB IEC61966-2.1"))
+	oi.Insert("RegistryName", types.StringLiteral("http://www.color.org"))
+	oi.Insert("Info", types.StringLiteral("sRGB IEC61966-2.1"))
+	oi.Insert("DestOutputProfile", *ref)
+	return oi, nil
+}
+
+// srgbICCProfile builds an ICC v2 display profile for sRGB: D50-adapted
+// primaries (Bradford), the D50 white point and the sRGB tone curve as a
+// 1024-entry table shared by the three channels.
+func srgbICCProfile() []byte {
+	be := binary.BigEndian
+	s15 := func(v float64) uint32 { return uint32(int32(math.Round(v * 65536))) }
+	xyz := func(x, y, z float64) []byte {
+		b := append([]byte("XYZ "), 0, 0, 0, 0)
+		b = be.AppendUint32(b, s15(x))
+		b = be.AppendUint32(b, s15(y))
+		return be.AppendUint32(b, s15(z))
+	}
+	const name = "sRGB IEC61966-2.1"
+	desc := append([]byte("desc"), 0, 0, 0, 0)
+	desc = be.AppendUint32(desc, uint32(len(name)+1))
+	desc = append(append(desc, name...), 0)
+	desc = append(desc, make([]byte, 4+4+2+1+67)...)
+	cprt := append(append([]byte("text"), 0, 0, 0, 0), "No copyright, use freely"...)
+	cprt = append(cprt, 0)
+	curv := append([]byte("curv"), 0, 0, 0, 0)
+	curv = be.AppendUint32(curv, 1024)
+	for i := 0; i < 1024; i++ {
+		v := float64(i) / 1023
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		curv = be.AppendUint16(curv, uint16(math.Round(v*65535)))
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc},
+		{"cprt", cprt},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4360747, 0.2225045, 0.0139322)},
+		{"gXYZ", xyz(0.3850649, 0.7168786, 0.0971045)},
+		{"bXYZ", xyz(0.1430804, 0.0606169, 0.7141733)},
+		{"rTRC", curv},
+		{"gTRC", nil},
+		{"bTRC", nil},
+	}
+	pad := func(b []byte) []byte {
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+		return b
+	}
+	offset := 128 + 4 + 12*len(tags)
+	var table, data []byte
+	table = be.AppendUint32(table, uint32(len(tags)))
+	var curvOffset int
+	for _, t := range tags {
+		if t.data == nil { // green and blue share the red tone curve
+			table = append(table, t.sig...)
+			table = be.AppendUint32(table, uint32(curvOffset))
+			table = be.AppendUint32(table, uint32(len(curv)))
+			continue
+		}
+		if t.sig == "rTRC" {
+			curvOffset = offset + len(data)
+		}
+		table = append(table, t.sig...)
+		table = be.AppendUint32(table, uint32(offset+len(data)))
+		table = be.AppendUint32(table, uint32(len(t.data)))
+		data = pad(append(data, t.data...))
+	}
+
+	header := make([]byte, 128)
+	be.PutUint32(header[0:], uint32(128+len(table)+len(data)))
+	be.PutUint32(header[8:], 0x02100000)
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	for i, v := range []uint16{1998, 2, 9, 6, 49, 0} {
+		be.PutUint16(header[24+2*i:], v)
+	}
+	copy(header[36:], "acsp")
+	be.PutUint32(header[68:], s15(0.9642))
+	be.PutUint32(header[72:], s15(1.0))
+	be.PutUint32(header[76:], s15(0.8249))
+	return append(append(header, table...), data...)
+}
+
+// facturXMP is the XMP packet of a Factur-X PDF: the PDF/A-3B
+// identification (only if pdfa), title, author, creator tool, producer and
+// the date placeholder mirroring the Info dictionary, the PDF/A extension
+// schema describing the fx namespace, and the fx properties.
+func facturXMP(title, author, creator string, pdfa bool) string {
+	const tmpl = `<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+%s  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:title>
+%s  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+   <xmp:CreatorTool>%s</xmp:CreatorTool>
+   <xmp:CreateDate>%s</xmp:CreateDate>
+   <xmp:ModifyDate>%[5]s</xmp:ModifyDate>
+   <xmp:MetadataDate>%[5]s</xmp:MetadataDate>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">
+   <pdf:Producer>%s</pdf:Producer>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:pdfaExtension="http://www.aiim.org/pdfa/ns/extension/" xmlns:pdfaSchema="http://www.aiim.org/pdfa/ns/schema#" xmlns:pdfaProperty="http://www.aiim.org/pdfa/ns/property#">
+   <pdfaExtension:schemas>
+    <rdf:Bag>
+     <rdf:li rdf:parseType="Resource">
+      <pdfaSchema:schema>Factur-X PDFA Extension Schema</pdfaSchema:schema>
+      <pdfaSchema:namespaceURI>urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#</pdfaSchema:namespaceURI>
+      <pdfaSchema:prefix>fx</pdfaSchema:prefix>
+      <pdfaSchema:property>
+       <rdf:Seq>
+        <rdf:li rdf:parseType="Resource"><pdfaProperty:name>DocumentFileName</pdfaProperty:name><pdfaProperty:valueType>Text</pdfaProperty:valueType><pdfaProperty:category>external</pdfaProperty:category><pdfaProperty:description>The name of the embedded XML document</pdfaProperty:description></rdf:li>
+        <rdf:li rdf:parseType="Resource"><pdfaProperty:name>DocumentType</pdfaProperty:name><pdfaProperty:valueType>Text</pdfaProperty:valueType><pdfaProperty:category>external</pdfaProperty:category><pdfaProperty:description>The type of the hybrid document in capital letters, e.g. INVOICE or ORDER</pdfaProperty:description></rdf:li>
+        <rdf:li rdf:parseType="Resource"><pdfaProperty:name>Version</pdfaProperty:name><pdfaProperty:valueType>Text</pdfaProperty:valueType><pdfaProperty:category>external</pdfaProperty:category><pdfaProperty:description>The actual version of the standard applying to the embedded XML document</pdfaProperty:description></rdf:li>
+        <rdf:li rdf:parseType="Resource"><pdfaProperty:name>ConformanceLevel</pdfaProperty:name><pdfaProperty:valueType>Text</pdfaProperty:valueType><pdfaProperty:category>external</pdfaProperty:category><pdfaProperty:description>The conformance level of the embedded XML document</pdfaProperty:description></rdf:li>
+       </rdf:Seq>
+      </pdfaSchema:property>
+     </rdf:li>
+    </rdf:Bag>
+   </pdfaExtension:schemas>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:fx="urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#">
+   <fx:DocumentType>INVOICE</fx:DocumentType>
+   <fx:DocumentFileName>%s</fx:DocumentFileName>
+   <fx:Version>1.0</fx:Version>
+   <fx:ConformanceLevel>EN 16931</fx:ConformanceLevel>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+	pdfaid, dcCreator := "", ""
+	if pdfa {
+		pdfaid = `  <rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">
+   <pdfaid:part>3</pdfaid:part>
+   <pdfaid:conformance>B</pdfaid:conformance>
+  </rdf:Description>
+`
+	}
+	if author != "" {
+		dcCreator = "   <dc:creator><rdf:Seq><rdf:li>" + html.EscapeString(author) + "</rdf:li></rdf:Seq></dc:creator>\n"
+	}
+	if creator == "" {
+		creator = "BuchISY"
+	}
+	return fmt.Sprintf(tmpl, pdfaid, html.EscapeString(title), dcCreator, html.EscapeString(creator),
+		xmpDatePlaceholder, html.EscapeString("pdfcpu "+model.VersionStr), FacturXFileName)
+}
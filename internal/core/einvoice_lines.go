@@ -0,0 +1,110 @@
+package core
+
+import (
+	"math"
+	"strings"
+)
+
+// InvoicePosition is one position of an e-invoice: an invoice line (BG-25)
+// or a document-level allowance/charge (BG-20/BG-21). Netto is the line's
+// net amount; allowances are negative, so the positions of a document add up
+// to its tax basis. The booking editor uses them to split the expense across
+// accounts.
+type InvoicePosition struct {
+	Nr          string  // line ID (BT-126); empty for allowances/charges
+	Bezeichnung string  // item name, or "Nachlass"/"Zuschlag" plus reason
+	Menge       float64 // invoiced quantity (0 for allowances/charges)
+	Einheit     string  // UN/ECE unit code, e.g. "H87", "HUR"
+	Einzelpreis float64 // net unit price
+	Netto       float64 // net line amount (negative for an allowance)
+	SatzProzent float64 // VAT rate of the line
+}
+
+// Label returns a short description for lists: "Nr Bezeichnung".
+func (p InvoicePosition) Label() string {
+	return strings.TrimSpace(p.Nr + " " + p.Bezeichnung)
+}
+
+// SumPositionen returns the total net of positions.
+func SumPositionen(positions []InvoicePosition) float64 {
+	var s float64
+	for _, p := range positions {
+		s += p.Netto
+	}
+	return round2(s)
+}
+
+// allowanceChargePosition turns a document-level allowance or charge into a
+// position; the allowance amount is negated.
+func allowanceChargePosition(isCharge bool, amount, satz float64, reason string) InvoicePosition {
+	label := "Nachlass"
+	if isCharge {
+		label = "Zuschlag"
+	} else {
+		amount = -amount
+	}
+	if r := strings.TrimSpace(reason); r != "" {
+		label += ": " + r
+	}
+	return InvoicePosition{Bezeichnung: label, Netto: amount, SatzProzent: satz}
+}
+
+// ciiPositions collects the item lines and document-level
+// allowances/charges of a CII invoice.
+func ciiPositions(invoice *CrossIndustryInvoice) []InvoicePosition {
+	var out []InvoicePosition
+	for _, li := range invoice.SupplyChainTradeTransaction.IncludedSupplyChainTradeLineItem {
+		out = append(out, InvoicePosition{
+			Nr:          strings.TrimSpace(li.LineID),
+			Bezeichnung: strings.TrimSpace(li.ProductName),
+			Menge:       parseXMLAmount(li.BilledQuantity.Value),
+			Einheit:     strings.TrimSpace(li.BilledQuantity.UnitCode),
+			Einzelpreis: parseXMLAmount(li.NetPrice.Value),
+			Netto:       parseXMLAmount(li.LineTotal.Value),
+			SatzProzent: parseXMLAmount(li.Tax.RateApplicablePercent),
+		})
+	}
+	settlement := invoice.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
+	for _, ac := range settlement.SpecifiedTradeAllowanceCharge {
+		out = append(out, allowanceChargePosition(ac.IsCharge(),
+			parseXMLAmount(ac.ActualAmount.Value),
+			parseXMLAmount(ac.Tax.RateApplicablePercent), ac.Reason))
+	}
+	return out
+}
+
+// ciiTaxLines turns every ApplicableTradeTax of the header into a TaxLine.
+// The breakdown's BasisAmount (BT-116) already is the sum of the line nets
+// minus allowances plus charges of its rate (BR-S-08); when a producer omits it,
+// the basis is rebuilt from the positions of that rate and, if the tax
+// amount is missing too, computed from the rate.
+func ciiTaxLines(settlement HeaderTradeSettlement, positions []InvoicePosition) []TaxLine {
+	var lines []TaxLine
+	for _, t := range settlement.ApplicableTradeTax {
+		satz := parseXMLAmount(t.RateApplicablePercent)
+		line := TaxLine{
+			Netto:       parseXMLAmount(t.BasisAmount.Value),
+			SatzProzent: satz,
+			MwStBetrag:  parseXMLAmount(t.CalculatedAmount.Value),
+		}
+		if strings.TrimSpace(t.BasisAmount.Value) == "" {
+			line.Netto = positionsNettoAt(positions, satz)
+		}
+		if strings.TrimSpace(t.CalculatedAmount.Value) == "" {
+			line.MwStBetrag = round2(line.Netto * satz / 100)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// positionsNettoAt sums the net of all positions taxed at satz.
+func positionsNettoAt(positions []InvoicePosition, satz float64) float64 {
+	var s float64
+	for _, p := range positions {
+		if math.Abs(p.SatzProzent-satz) < 0.005 {
+			s += p.Netto
+		}
+	}
+	return round2(s)
+}
@@ -0,0 +1,799 @@
+package core
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Specification identifiers (BT-24) of generated e-invoices. Factur-X and
+// ZUGFeRD use the plain EN 16931 identifier for their EN 16931 profile; the
+// XRechnung CIUS adds its compliance suffix.
+const (
+	GuidelineEN16931   = "urn:cen.eu:en16931:2017"
+	GuidelineXRechnung = "urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0"
+	// peppolBillingProcess is the business process (BT-23) XRechnung expects.
+	peppolBillingProcess = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+)
+
+// OutgoingEInvoice is everything needed to turn an Ausgangsrechnung into an
+// e-invoice: the recorded Meta (customer name and VAT-ID, number, date,
+// TaxLines, optional Positionen), the profile's own company data as the
+// seller, and the customer details BuchISY does not store per invoice.
+type OutgoingEInvoice struct {
+	Meta            Meta
+	Verkaeufer      Firmendaten
+	VerkaeuferUStID string      // seller VAT-ID (BT-31), the profile's first own VAT-ID
+	Kaeufer         Firmendaten // customer address; Name defaults to Meta.Auftraggeber
+	Kaeuferreferenz string      // BT-10, the Leitweg-ID for public-sector customers
+	Faelligkeit     string      // due date DD.MM.YYYY (BT-9), optional
+	Leistungsdatum  string      // delivery date DD.MM.YYYY (BT-72), defaults to the invoice date
+}
+
+// outDoc is the syntax-neutral invoice both generators render. Amounts are
+// final (rounded, sign-normalised) so CII and UBL carry identical numbers.
+type outDoc struct {
+	typeCode                         string // 380 invoice, 381 credit note
+	number, currency                 string
+	issue, delivery, due             time.Time
+	note, terms                      string
+	seller, buyer                    Firmendaten
+	sellerVATID, buyerVATID          string
+	buyerReference                   string
+	paymentMeans                     string // UNTDID 4461: 58 SEPA credit transfer, 1 not defined
+	lines                            []outLine
+	allowances                       []outAllowanceCharge
+	vat                              []outVAT
+	lineTotal, allowTotal            float64
+	chargeTotal, basis, tax, payable float64
+}
+
+type outLine struct {
+	id, name, unit  string
+	qty, price, net float64
+	category        string
+	rate            float64
+}
+
+type outAllowanceCharge struct {
+	charge   bool
+	reason   string
+	amount   float64
+	category string
+	rate     float64
+}
+
+type outVAT struct {
+	category            string
+	rate, basis, amount float64
+	reason, reasonCode  string
+}
+
+// vatCategory picks the UNTDID 5305 category for a rate: S for a positive
+// rate; for 0 % reverse charge (AE) when the customer has an EU VAT-ID of
+// another member state (the ZM case), not subject (O) for a customer abroad,
+// exempt (E) otherwise. Zero-rated categories carry their exemption reason.
+func (inv OutgoingEInvoice) vatCategory(rate float64) (cat, reason, code string) {
+	switch {
+	case rate > 0:
+		return "S", "", ""
+	case IsEUVatID(inv.Meta.VATID):
+		return "AE", "Steuerschuldnerschaft des Leistungsempfängers (Reverse Charge)", "VATEX-EU-AE"
+	case inv.buyerCountry() != "DE":
+		return "O", "Nicht im Inland steuerbare Leistung", "VATEX-EU-O"
+	}
+	return "E", "Steuerfreie Leistung", ""
+}
+
+// buyerCountry is the customer's country code: the address, else the
+// VAT-ID prefix (EL is Greece), else DE.
+func (inv OutgoingEInvoice) buyerCountry() string {
+	if c := strings.ToUpper(strings.TrimSpace(inv.Kaeufer.Land)); c != "" {
+		return c
+	}
+	if id := strings.ToUpper(strings.TrimSpace(inv.Meta.VATID)); IsEUVatID(id) {
+		if id[:2] == "EL" {
+			return "GR"
+		}
+		return id[:2]
+	}
+	return "DE"
+}
+
+// document resolves the invoice into final amounts. Lines come from the
+// Positionen when present (positions without a line number become
+// document-level allowances or charges), else one line per TaxLine. An
+// invoice with a negative total becomes a credit note (381) with positive
+// amounts.
+func (inv OutgoingEInvoice) document() (outDoc, error) {
+	m := inv.Meta
+	if strings.TrimSpace(m.Rechnungsnummer) == "" {
+		return outDoc{}, fmt.Errorf("Rechnungsnummer fehlt")
+	}
+	issue, err := time.Parse("02.01.2006", strings.TrimSpace(m.Rechnungsdatum))
+	if err != nil {
+		return outDoc{}, fmt.Errorf("ungültiges Rechnungsdatum %q", m.Rechnungsdatum)
+	}
+	d := outDoc{
+		typeCode:       "380",
+		number:         strings.TrimSpace(m.Rechnungsnummer),
+		currency:       strings.ToUpper(strings.TrimSpace(m.Waehrung)),
+		issue:          issue,
+		delivery:       issue,
+		note:           strings.TrimSpace(m.Verwendungszweck),
+		seller:         inv.Verkaeufer,
+		buyer:          inv.Kaeufer,
+		sellerVATID:    strings.ToUpper(strings.TrimSpace(inv.VerkaeuferUStID)),
+		buyerVATID:     strings.ToUpper(strings.TrimSpace(m.VATID)),
+		buyerReference: strings.TrimSpace(inv.Kaeuferreferenz),
+		paymentMeans:   "1",
+	}
+	if d.currency == "" {
+		d.currency = "EUR"
+	}
+	if d.note == "-" {
+		d.note = ""
+	}
+	if strings.TrimSpace(d.seller.Land) == "" {
+		d.seller.Land = "DE"
+	}
+	if strings.TrimSpace(d.buyer.Name) == "" {
+		d.buyer.Name = strings.TrimSpace(m.Auftraggeber)
+	}
+	d.buyer.Land = inv.buyerCountry()
+	if strings.TrimSpace(d.seller.IBAN) != "" {
+		d.paymentMeans = "58"
+	}
+	if s := strings.TrimSpace(inv.Leistungsdatum); s != "" {
+		if t, err := time.Parse("02.01.2006", s); err == nil {
+			d.delivery = t
+		}
+	}
+	if s := strings.TrimSpace(inv.Faelligkeit); s != "" {
+		t, err := time.Parse("02.01.2006", s)
+		if err != nil {
+			return outDoc{}, fmt.Errorf("ungültiges Fälligkeitsdatum %q", s)
+		}
+		d.due = t
+		d.terms = "Zahlbar bis " + s + " ohne Abzug"
+	} else {
+		d.terms = "Zahlbar sofort ohne Abzug"
+	}
+
+	taxLines := m.TaxLines
+	if len(taxLines) == 0 {
+		taxLines = ReconstructTaxLines(m.BetragNetto, m.SteuersatzProzent, m.SteuersatzBetrag, m.Bruttobetrag)
+	}
+	if len(taxLines) == 0 && len(m.Positionen) == 0 {
+		return outDoc{}, fmt.Errorf("Rechnung %s enthält keine Beträge", d.number)
+	}
+	sign := 1.0
+	total := SumNetto(taxLines)
+	if len(m.Positionen) > 0 {
+		total = SumPositionen(m.Positionen)
+	}
+	if total < 0 {
+		d.typeCode = "381"
+		sign = -1
+	}
+
+	name := d.note
+	if name == "" {
+		name = "Leistung"
+	}
+	if len(m.Positionen) > 0 {
+		for i, p := range m.Positionen {
+			net := round2(sign * p.Netto)
+			if strings.TrimSpace(p.Nr) == "" && p.Menge == 0 {
+				cat, _, _ := inv.vatCategory(p.SatzProzent)
+				d.allowances = append(d.allowances, outAllowanceCharge{
+					charge: net > 0, reason: allowanceReason(p.Bezeichnung), amount: math.Abs(net),
+					category: cat, rate: p.SatzProzent,
+				})
+				continue
+			}
+			l := outLine{
+				id: strings.TrimSpace(p.Nr), name: strings.TrimSpace(p.Bezeichnung),
+				unit: strings.TrimSpace(p.Einheit), qty: p.Menge, price: math.Abs(p.Einzelpreis),
+				net: net, rate: p.SatzProzent,
+			}
+			if l.id == "" {
+				l.id = strconv.Itoa(i + 1)
+			}
+			if l.name == "" {
+				l.name = name
+			}
+			if l.unit == "" {
+				l.unit = "C62"
+			}
+			if l.qty == 0 {
+				l.qty = 1
+			}
+			if l.price == 0 {
+				l.price = math.Abs(net / l.qty)
+			}
+			// A negative line keeps a positive price (BR-27) and carries
+			// the sign in the quantity.
+			if (net < 0) != (l.qty < 0) {
+				l.qty = -l.qty
+			}
+			l.category, _, _ = inv.vatCategory(l.rate)
+			d.lines = append(d.lines, l)
+		}
+	} else {
+		for i, tl := range taxLines {
+			net := round2(sign * tl.Netto)
+			l := outLine{
+				id: strconv.Itoa(i + 1), name: name, unit: "C62",
+				qty: 1, price: math.Abs(net), net: net, rate: tl.SatzProzent,
+			}
+			if net < 0 {
+				l.qty = -1
+			}
+			if len(taxLines) > 1 {
+				l.name = fmt.Sprintf("%s (%s %%)", name, pdfAmount(tl.SatzProzent))
+			}
+			l.category, _, _ = inv.vatCategory(l.rate)
+			d.lines = append(d.lines, l)
+		}
+	}
+
+	// VAT breakdown per category and rate, in order of first appearance.
+	// The booked VAT of a TaxLine with the same rate wins when it is
+	// within a cent of the computed amount, so the e-invoice reproduces
+	// the recorded gross.
+	idx := map[string]int{}
+	addBasis := func(cat string, rate, amount float64) {
+		k := cat + "|" + strconv.FormatFloat(rate, 'f', 2, 64)
+		i, ok := idx[k]
+		if !ok {
+			_, reason, code := inv.vatCategory(rate)
+			idx[k] = len(d.vat)
+			d.vat = append(d.vat, outVAT{category: cat, rate: rate, reason: reason, reasonCode: code})
+			i = len(d.vat) - 1
+		}
+		d.vat[i].basis += amount
+	}
+	for _, l := range d.lines {
+		d.lineTotal += l.net
+		addBasis(l.category, l.rate, l.net)
+	}
+	for _, ac := range d.allowances {
+		if ac.charge {
+			d.chargeTotal += ac.amount
+			addBasis(ac.category, ac.rate, ac.amount)
+		} else {
+			d.allowTotal += ac.amount
+			addBasis(ac.category, ac.rate, -ac.amount)
+		}
+	}
+	for i := range d.vat {
+		v := &d.vat[i]
+		v.basis = round2(v.basis)
+		v.amount = round2(v.basis * v.rate / 100)
+		for _, tl := range taxLines {
+			if tl.SatzProzent == v.rate && math.Abs(sign*tl.MwStBetrag-v.amount) <= 0.01 {
+				v.amount = round2(sign * tl.MwStBetrag)
+				break
+			}
+		}
+		d.tax += v.amount
+	}
+	d.lineTotal = round2(d.lineTotal)
+	d.allowTotal = round2(d.allowTotal)
+	d.chargeTotal = round2(d.chargeTotal)
+	d.basis = round2(d.lineTotal - d.allowTotal + d.chargeTotal)
+	d.tax = round2(d.tax)
+	d.payable = round2(d.basis + d.tax)
+	return d, nil
+}
+
+// allowanceReason strips the "Nachlass: "/"Zuschlag: " label that
+// allowanceChargePosition puts in front of the reason.
+func allowanceReason(label string) string {
+	for _, p := range []string{"Nachlass", "Zuschlag"} {
+		if strings.HasPrefix(label, p) {
+			if r := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(label, p), ":")); r != "" {
+				return r
+			}
+			return p
+		}
+	}
+	return strings.TrimSpace(label)
+}
+
+// xmlAmount formats an amount with two decimals and a dot.
+func xmlAmount(v float64) string {
+	return strconv.FormatFloat(round2(v), 'f', 2, 64)
+}
+
+// xmlDecimal formats a quantity or rate without trailing zeros.
+func xmlDecimal(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// xmlRate formats the rate of a VAT category; "not subject" (O) carries
+// none (BR-O-05).
+func xmlRate(category string, rate float64) string {
+	if category == "O" {
+		return ""
+	}
+	return xmlDecimal(rate)
+}
+
+// electronicAddress is a party's electronic address (BT-34/BT-49) with
+// scheme EM (e-mail), which XRechnung requires for seller and buyer.
+type electronicAddress struct {
+	SchemeID string `xml:"schemeID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+func newElectronicAddress(email string) *electronicAddress {
+	if email = strings.TrimSpace(email); email == "" {
+		return nil
+	}
+	return &electronicAddress{SchemeID: "EM", Value: email}
+}
+
+// ciiDate is a udt:DateTimeString in format 102 (YYYYMMDD).
+type ciiDate struct {
+	Format string `xml:"format,attr"`
+	Value  string `xml:",chardata"`
+}
+
+func newCIIDate(t time.Time) *ciiDate {
+	return &ciiDate{Format: "102", Value: t.Format("20060102")}
+}
+
+type ciiAmount struct {
+	CurrencyID string `xml:"currencyID,attr,omitempty"`
+	Value      string `xml:",chardata"`
+}
+
+type ciiTax struct {
+	CalculatedAmount    string `xml:"ram:CalculatedAmount,omitempty"`
+	TypeCode            string `xml:"ram:TypeCode"`
+	ExemptionReason     string `xml:"ram:ExemptionReason,omitempty"`
+	BasisAmount         string `xml:"ram:BasisAmount,omitempty"`
+	CategoryCode        string `xml:"ram:CategoryCode"`
+	ExemptionReasonCode string `xml:"ram:ExemptionReasonCode,omitempty"`
+	Rate                string `xml:"ram:RateApplicablePercent,omitempty"`
+}
+
+type ciiTaxRegistration struct {
+	ID struct {
+		SchemeID string `xml:"schemeID,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"ram:ID"`
+}
+
+type ciiContact struct {
+	PersonName string `xml:"ram:PersonName,omitempty"`
+	Telephone  string `xml:"ram:TelephoneUniversalCommunication>ram:CompleteNumber,omitempty"`
+	Email      string `xml:"ram:EmailURIUniversalCommunication>ram:URIID,omitempty"`
+}
+
+type ciiParty struct {
+	Name    string      `xml:"ram:Name"`
+	Contact *ciiContact `xml:"ram:DefinedTradeContact,omitempty"`
+	Address struct {
+		PostcodeCode string `xml:"ram:PostcodeCode,omitempty"`
+		LineOne      string `xml:"ram:LineOne,omitempty"`
+		CityName     string `xml:"ram:CityName,omitempty"`
+		CountryID    string `xml:"ram:CountryID"`
+	} `xml:"ram:PostalTradeAddress"`
+	URI             *electronicAddress   `xml:"ram:URIUniversalCommunication>ram:URIID"`
+	TaxRegistration []ciiTaxRegistration `xml:"ram:SpecifiedTaxRegistration"`
+}
+
+func newCIIParty(f Firmendaten, vatID string, withContact bool) ciiParty {
+	p := ciiParty{Name: strings.TrimSpace(f.Name)}
+	p.Address.PostcodeCode = strings.TrimSpace(f.PLZ)
+	p.Address.LineOne = strings.TrimSpace(f.Strasse)
+	p.Address.CityName = strings.TrimSpace(f.Ort)
+	p.Address.CountryID = strings.ToUpper(strings.TrimSpace(f.Land))
+	p.URI = newElectronicAddress(f.Email)
+	if withContact && (f.Ansprechpartner != "" || f.Telefon != "" || f.Email != "") {
+		p.Contact = &ciiContact{
+			PersonName: strings.TrimSpace(f.Ansprechpartner),
+			Telephone:  strings.TrimSpace(f.Telefon),
+			Email:      strings.TrimSpace(f.Email),
+		}
+	}
+	add := func(scheme, id string) {
+		if id = strings.TrimSpace(id); id != "" {
+			var r ciiTaxRegistration
+			r.ID.SchemeID, r.ID.Value = scheme, id
+			p.TaxRegistration = append(p.TaxRegistration, r)
+		}
+	}
+	if withContact {
+		add("FC", f.Steuernummer)
+	}
+	add("VA", vatID)
+	return p
+}
+
+type ciiLineItem struct {
+	LineID   string `xml:"ram:AssociatedDocumentLineDocument>ram:LineID"`
+	Name     string `xml:"ram:SpecifiedTradeProduct>ram:Name"`
+	NetPrice string `xml:"ram:SpecifiedLineTradeAgreement>ram:NetPriceProductTradePrice>ram:ChargeAmount"`
+	Quantity struct {
+		UnitCode string `xml:"unitCode,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"ram:SpecifiedLineTradeDelivery>ram:BilledQuantity"`
+	Tax       ciiTax `xml:"ram:SpecifiedLineTradeSettlement>ram:ApplicableTradeTax"`
+	LineTotal string `xml:"ram:SpecifiedLineTradeSettlement>ram:SpecifiedTradeSettlementLineMonetarySummation>ram:LineTotalAmount"`
+}
+
+type ciiAllowanceCharge struct {
+	Indicator string `xml:"ram:ChargeIndicator>udt:Indicator"`
+	Amount    string `xml:"ram:ActualAmount"`
+	Reason    string `xml:"ram:Reason,omitempty"`
+	Tax       ciiTax `xml:"ram:CategoryTradeTax"`
+}
+
+type ciiPaymentMeans struct {
+	TypeCode string `xml:"ram:TypeCode"`
+	IBAN     string `xml:"ram:PayeePartyCreditorFinancialAccount>ram:IBANID,omitempty"`
+	BIC      string `xml:"ram:PayeeSpecifiedCreditorFinancialInstitution>ram:BICID,omitempty"`
+}
+
+type ciiInvoiceOut struct {
+	XMLName           xml.Name `xml:"rsm:CrossIndustryInvoice"`
+	NSrsm             string   `xml:"xmlns:rsm,attr"`
+	NSram             string   `xml:"xmlns:ram,attr"`
+	NSqdt             string   `xml:"xmlns:qdt,attr"`
+	NSudt             string   `xml:"xmlns:udt,attr"`
+	BusinessProcessID string   `xml:"rsm:ExchangedDocumentContext>ram:BusinessProcessSpecifiedDocumentContextParameter>ram:ID,omitempty"`
+	GuidelineID       string   `xml:"rsm:ExchangedDocumentContext>ram:GuidelineSpecifiedDocumentContextParameter>ram:ID"`
+	Document          struct {
+		ID        string   `xml:"ram:ID"`
+		TypeCode  string   `xml:"ram:TypeCode"`
+		IssueDate *ciiDate `xml:"ram:IssueDateTime>udt:DateTimeString"`
+		Note      string   `xml:"ram:IncludedNote>ram:Content,omitempty"`
+	} `xml:"rsm:ExchangedDocument"`
+	Transaction struct {
+		Lines     []ciiLineItem `xml:"ram:IncludedSupplyChainTradeLineItem"`
+		Agreement struct {
+			BuyerReference string   `xml:"ram:BuyerReference,omitempty"`
+			Seller         ciiParty `xml:"ram:SellerTradeParty"`
+			Buyer          ciiParty `xml:"ram:BuyerTradeParty"`
+		} `xml:"ram:ApplicableHeaderTradeAgreement"`
+		Delivery   *ciiDate `xml:"ram:ApplicableHeaderTradeDelivery>ram:ActualDeliverySupplyChainEvent>ram:OccurrenceDateTime>udt:DateTimeString"`
+		Settlement struct {
+			Currency     string               `xml:"ram:InvoiceCurrencyCode"`
+			PaymentMeans ciiPaymentMeans      `xml:"ram:SpecifiedTradeSettlementPaymentMeans"`
+			Taxes        []ciiTax             `xml:"ram:ApplicableTradeTax"`
+			Allowances   []ciiAllowanceCharge `xml:"ram:SpecifiedTradeAllowanceCharge"`
+			Terms        struct {
+				Description string   `xml:"ram:Description,omitempty"`
+				DueDate     *ciiDate `xml:"ram:DueDateDateTime>udt:DateTimeString"`
+			} `xml:"ram:SpecifiedTradePaymentTerms"`
+			Sums struct {
+				LineTotal      string    `xml:"ram:LineTotalAmount"`
+				ChargeTotal    string    `xml:"ram:ChargeTotalAmount,omitempty"`
+				AllowanceTotal string    `xml:"ram:AllowanceTotalAmount,omitempty"`
+				TaxBasisTotal  string    `xml:"ram:TaxBasisTotalAmount"`
+				TaxTotal       ciiAmount `xml:"ram:TaxTotalAmount"`
+				GrandTotal     string    `xml:"ram:GrandTotalAmount"`
+				DuePayable     string    `xml:"ram:DuePayableAmount"`
+			} `xml:"ram:SpecifiedTradeSettlementHeaderMonetarySummation"`
+		} `xml:"ram:ApplicableHeaderTradeSettlement"`
+	} `xml:"rsm:SupplyChainTradeTransaction"`
+}
+
+// BuildCIIInvoiceXML renders an outgoing invoice as UN/CEFACT CII (D16B) with
+// the given specification identifier: GuidelineEN16931 for the XML embedded
+// in a ZUGFeRD/Factur-X PDF, GuidelineXRechnung for a CII XRechnung.
+func BuildCIIInvoiceXML(inv OutgoingEInvoice, guideline string) ([]byte, error) {
+	d, err := inv.document()
+	if err != nil {
+		return nil, err
+	}
+	var x ciiInvoiceOut
+	x.NSrsm = "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+	x.NSram = "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+	x.NSqdt = "urn:un:unece:uncefact:data:standard:QualifiedDataType:100"
+	x.NSudt = "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100"
+	if guideline == GuidelineXRechnung {
+		x.BusinessProcessID = peppolBillingProcess
+	}
+	x.GuidelineID = guideline
+	x.Document.ID = d.number
+	x.Document.TypeCode = d.typeCode
+	x.Document.IssueDate = newCIIDate(d.issue)
+	x.Document.Note = d.note
+
+	tx := &x.Transaction
+	for _, l := range d.lines {
+		li := ciiLineItem{LineID: l.id, Name: l.name, NetPrice: xmlAmount(l.price), LineTotal: xmlAmount(l.net)}
+		li.Quantity.UnitCode, li.Quantity.Value = l.unit, xmlDecimal(l.qty)
+		li.Tax = ciiTax{TypeCode: "VAT", CategoryCode: l.category, Rate: xmlRate(l.category, l.rate)}
+		tx.Lines = append(tx.Lines, li)
+	}
+	tx.Agreement.BuyerReference = d.buyerReference
+	tx.Agreement.Seller = newCIIParty(d.seller, d.sellerVATID, true)
+	tx.Agreement.Buyer = newCIIParty(d.buyer, d.buyerVATID, false)
+	tx.Delivery = newCIIDate(d.delivery)
+
+	st := &tx.Settlement
+	st.Currency = d.currency
+	st.PaymentMeans.TypeCode = d.paymentMeans
+	if d.paymentMeans == "58" {
+		st.PaymentMeans.IBAN = strings.ReplaceAll(strings.TrimSpace(d.seller.IBAN), " ", "")
+		st.PaymentMeans.BIC = strings.TrimSpace(d.seller.BIC)
+	}
+	for _, v := range d.vat {
+		st.Taxes = append(st.Taxes, ciiTax{
+			CalculatedAmount: xmlAmount(v.amount), TypeCode: "VAT", ExemptionReason: v.reason,
+			BasisAmount: xmlAmount(v.basis), CategoryCode: v.category,
+			ExemptionReasonCode: v.reasonCode, Rate: xmlRate(v.category, v.rate),
+		})
+	}
+	for _, ac := range d.allowances {
+		st.Allowances = append(st.Allowances, ciiAllowanceCharge{
+			Indicator: strconv.FormatBool(ac.charge), Amount: xmlAmount(ac.amount), Reason: ac.reason,
+			Tax: ciiTax{TypeCode: "VAT", CategoryCode: ac.category, Rate: xmlRate(ac.category, ac.rate)},
+		})
+	}
+	st.Terms.Description = d.terms
+	if !d.due.IsZero() {
+		st.Terms.DueDate = newCIIDate(d.due)
+	}
+	st.Sums.LineTotal = xmlAmount(d.lineTotal)
+	if len(d.allowances) > 0 {
+		st.Sums.ChargeTotal = xmlAmount(d.chargeTotal)
+		st.Sums.AllowanceTotal = xmlAmount(d.allowTotal)
+	}
+	st.Sums.TaxBasisTotal = xmlAmount(d.basis)
+	st.Sums.TaxTotal = ciiAmount{CurrencyID: d.currency, Value: xmlAmount(d.tax)}
+	st.Sums.GrandTotal = xmlAmount(d.basis + d.tax)
+	st.Sums.DuePayable = xmlAmount(d.payable)
+
+	out, err := xml.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+type ublAmountOut struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type ublTaxCategoryOut struct {
+	ID            string `xml:"cbc:ID"`
+	Percent       string `xml:"cbc:Percent,omitempty"`
+	ExemptionCode string `xml:"cbc:TaxExemptionReasonCode,omitempty"`
+	Exemption     string `xml:"cbc:TaxExemptionReason,omitempty"`
+	TaxScheme     string `xml:"cac:TaxScheme>cbc:ID"`
+}
+
+type ublPartyTaxSchemeOut struct {
+	CompanyID string `xml:"cbc:CompanyID"`
+	TaxScheme string `xml:"cac:TaxScheme>cbc:ID"`
+}
+
+type ublContactOut struct {
+	Name           string `xml:"cbc:Name,omitempty"`
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+type ublPartyOut struct {
+	EndpointID *electronicAddress `xml:"cbc:EndpointID"`
+	Name       string             `xml:"cac:PartyName>cbc:Name"`
+	Address    struct {
+		StreetName  string `xml:"cbc:StreetName,omitempty"`
+		CityName    string `xml:"cbc:CityName,omitempty"`
+		PostalZone  string `xml:"cbc:PostalZone,omitempty"`
+		CountryCode string `xml:"cac:Country>cbc:IdentificationCode"`
+	} `xml:"cac:PostalAddress"`
+	TaxSchemes       []ublPartyTaxSchemeOut `xml:"cac:PartyTaxScheme"`
+	RegistrationName string                 `xml:"cac:PartyLegalEntity>cbc:RegistrationName"`
+	Contact          *ublContactOut         `xml:"cac:Contact"`
+}
+
+func newUBLParty(f Firmendaten, vatID string, withContact bool) ublPartyOut {
+	var p ublPartyOut
+	p.EndpointID = newElectronicAddress(f.Email)
+	p.Name = strings.TrimSpace(f.Name)
+	p.RegistrationName = p.Name
+	p.Address.StreetName = strings.TrimSpace(f.Strasse)
+	p.Address.CityName = strings.TrimSpace(f.Ort)
+	p.Address.PostalZone = strings.TrimSpace(f.PLZ)
+	p.Address.CountryCode = strings.ToUpper(strings.TrimSpace(f.Land))
+	if id := strings.TrimSpace(vatID); id != "" {
+		p.TaxSchemes = append(p.TaxSchemes, ublPartyTaxSchemeOut{CompanyID: id, TaxScheme: "VAT"})
+	}
+	if !withContact {
+		return p
+	}
+	if st := strings.TrimSpace(f.Steuernummer); st != "" {
+		p.TaxSchemes = append(p.TaxSchemes, ublPartyTaxSchemeOut{CompanyID: st, TaxScheme: "FC"})
+	}
+	if f.Ansprechpartner != "" || f.Telefon != "" || f.Email != "" {
+		p.Contact = &ublContactOut{
+			Name:           strings.TrimSpace(f.Ansprechpartner),
+			Telephone:      strings.TrimSpace(f.Telefon),
+			ElectronicMail: strings.TrimSpace(f.Email),
+		}
+	}
+	return p
+}
+
+type ublQuantityOut struct {
+	XMLName  xml.Name
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// ublLineOut is an <InvoiceLine>; XMLName and the quantity's XMLName turn
+// it into a <CreditNoteLine> with a <CreditedQuantity>.
+type ublLineOut struct {
+	XMLName             xml.Name
+	ID                  string         `xml:"cbc:ID"`
+	Quantity            ublQuantityOut `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount ublAmountOut   `xml:"cbc:LineExtensionAmount"`
+	Item                struct {
+		Name        string            `xml:"cbc:Name"`
+		TaxCategory ublTaxCategoryOut `xml:"cac:ClassifiedTaxCategory"`
+	} `xml:"cac:Item"`
+	Price ublAmountOut `xml:"cac:Price>cbc:PriceAmount"`
+}
+
+type ublAllowanceChargeOut struct {
+	ChargeIndicator string            `xml:"cbc:ChargeIndicator"`
+	Reason          string            `xml:"cbc:AllowanceChargeReason,omitempty"`
+	Amount          ublAmountOut      `xml:"cbc:Amount"`
+	TaxCategory     ublTaxCategoryOut `xml:"cac:TaxCategory"`
+}
+
+type ublTaxSubtotalOut struct {
+	TaxableAmount ublAmountOut      `xml:"cbc:TaxableAmount"`
+	TaxAmount     ublAmountOut      `xml:"cbc:TaxAmount"`
+	TaxCategory   ublTaxCategoryOut `xml:"cac:TaxCategory"`
+}
+
+type ublAccountOut struct {
+	ID     string `xml:"cbc:ID"`
+	Branch string `xml:"cac:FinancialInstitutionBranch>cbc:ID,omitempty"`
+}
+
+type ublInvoiceOut struct {
+	XMLName         xml.Name    // Invoice or CreditNote
+	NS              string      `xml:"xmlns,attr"`
+	NScac           string      `xml:"xmlns:cac,attr"`
+	NScbc           string      `xml:"xmlns:cbc,attr"`
+	CustomizationID string      `xml:"cbc:CustomizationID"`
+	ProfileID       string      `xml:"cbc:ProfileID"`
+	ID              string      `xml:"cbc:ID"`
+	IssueDate       string      `xml:"cbc:IssueDate"`
+	DueDate         string      `xml:"cbc:DueDate,omitempty"`
+	InvoiceTypeCode string      `xml:"cbc:InvoiceTypeCode,omitempty"`
+	CreditNoteType  string      `xml:"cbc:CreditNoteTypeCode,omitempty"`
+	Note            string      `xml:"cbc:Note,omitempty"`
+	Currency        string      `xml:"cbc:DocumentCurrencyCode"`
+	BuyerReference  string      `xml:"cbc:BuyerReference,omitempty"`
+	Supplier        ublPartyOut `xml:"cac:AccountingSupplierParty>cac:Party"`
+	Customer        ublPartyOut `xml:"cac:AccountingCustomerParty>cac:Party"`
+	Delivery        string      `xml:"cac:Delivery>cbc:ActualDeliveryDate"`
+	PaymentMeans    struct {
+		Code           string         `xml:"cbc:PaymentMeansCode"`
+		PaymentDueDate string         `xml:"cbc:PaymentDueDate,omitempty"`
+		Account        *ublAccountOut `xml:"cac:PayeeFinancialAccount"`
+	} `xml:"cac:PaymentMeans"`
+	PaymentTerms string                  `xml:"cac:PaymentTerms>cbc:Note,omitempty"`
+	Allowances   []ublAllowanceChargeOut `xml:"cac:AllowanceCharge"`
+	TaxTotal     struct {
+		TaxAmount ublAmountOut        `xml:"cbc:TaxAmount"`
+		Subtotals []ublTaxSubtotalOut `xml:"cac:TaxSubtotal"`
+	} `xml:"cac:TaxTotal"`
+	Totals struct {
+		LineExtensionAmount  ublAmountOut  `xml:"cbc:LineExtensionAmount"`
+		TaxExclusiveAmount   ublAmountOut  `xml:"cbc:TaxExclusiveAmount"`
+		TaxInclusiveAmount   ublAmountOut  `xml:"cbc:TaxInclusiveAmount"`
+		AllowanceTotalAmount *ublAmountOut `xml:"cbc:AllowanceTotalAmount,omitempty"`
+		ChargeTotalAmount    *ublAmountOut `xml:"cbc:ChargeTotalAmount,omitempty"`
+		PayableAmount        ublAmountOut  `xml:"cbc:PayableAmount"`
+	} `xml:"cac:LegalMonetaryTotal"`
+	Lines []ublLineOut `xml:"cac:InvoiceLine"`
+}
+
+// BuildUBLInvoiceXML renders an outgoing invoice as an XRechnung in OASIS UBL
+// 2.1. A credit note is written as a UBL <CreditNote>.
+func BuildUBLInvoiceXML(inv OutgoingEInvoice) ([]byte, error) {
+	d, err := inv.document()
+	if err != nil {
+		return nil, err
+	}
+	amount := func(v float64) ublAmountOut { return ublAmountOut{CurrencyID: d.currency, Value: xmlAmount(v)} }
+	category := func(cat string, rate float64) ublTaxCategoryOut {
+		return ublTaxCategoryOut{ID: cat, Percent: xmlRate(cat, rate), TaxScheme: "VAT"}
+	}
+	creditNote := d.typeCode == "381"
+
+	var x ublInvoiceOut
+	x.XMLName.Local = "Invoice"
+	x.NS = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	x.NScac = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	x.NScbc = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+	x.CustomizationID = GuidelineXRechnung
+	x.ProfileID = peppolBillingProcess
+	x.ID = d.number
+	x.IssueDate = d.issue.Format("2006-01-02")
+	x.InvoiceTypeCode = d.typeCode
+	if creditNote {
+		x.XMLName.Local = "CreditNote"
+		x.NS = "urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+		x.InvoiceTypeCode, x.CreditNoteType = "", d.typeCode
+	}
+	if !d.due.IsZero() {
+		if creditNote {
+			x.PaymentMeans.PaymentDueDate = d.due.Format("2006-01-02")
+		} else {
+			x.DueDate = d.due.Format("2006-01-02")
+		}
+	}
+	x.Note = d.note
+	x.Currency = d.currency
+	x.BuyerReference = d.buyerReference
+	x.Delivery = d.delivery.Format("2006-01-02")
+	x.Supplier = newUBLParty(d.seller, d.sellerVATID, true)
+	x.Customer = newUBLParty(d.buyer, d.buyerVATID, false)
+	x.PaymentMeans.Code = d.paymentMeans
+	if d.paymentMeans == "58" {
+		x.PaymentMeans.Account = &ublAccountOut{
+			ID:     strings.ReplaceAll(strings.TrimSpace(d.seller.IBAN), " ", ""),
+			Branch: strings.TrimSpace(d.seller.BIC),
+		}
+	}
+	x.PaymentTerms = d.terms
+	for _, ac := range d.allowances {
+		x.Allowances = append(x.Allowances, ublAllowanceChargeOut{
+			ChargeIndicator: strconv.FormatBool(ac.charge), Reason: ac.reason,
+			Amount: amount(ac.amount), TaxCategory: category(ac.category, ac.rate),
+		})
+	}
+	x.TaxTotal.TaxAmount = amount(d.tax)
+	for _, v := range d.vat {
+		c := category(v.category, v.rate)
+		c.ExemptionCode, c.Exemption = v.reasonCode, v.reason
+		x.TaxTotal.Subtotals = append(x.TaxTotal.Subtotals, ublTaxSubtotalOut{
+			TaxableAmount: amount(v.basis), TaxAmount: amount(v.amount), TaxCategory: c,
+		})
+	}
+	x.Totals.LineExtensionAmount = amount(d.lineTotal)
+	x.Totals.TaxExclusiveAmount = amount(d.basis)
+	x.Totals.TaxInclusiveAmount = amount(d.basis + d.tax)
+	if len(d.allowances) > 0 {
+		at, ct := amount(d.allowTotal), amount(d.chargeTotal)
+		x.Totals.AllowanceTotalAmount, x.Totals.ChargeTotalAmount = &at, &ct
+	}
+	x.Totals.PayableAmount = amount(d.payable)
+	for _, l := range d.lines {
+		var ul ublLineOut
+		ul.ID = l.id
+		ul.Quantity.UnitCode, ul.Quantity.Value = l.unit, xmlDecimal(l.qty)
+		if creditNote {
+			ul.XMLName.Local = "cac:CreditNoteLine"
+			ul.Quantity.XMLName.Local = "cbc:CreditedQuantity"
+		}
+		ul.LineExtensionAmount = amount(l.net)
+		ul.Item.Name = l.name
+		ul.Item.TaxCategory = category(l.category, l.rate)
+		ul.Price = amount(l.price)
+		x.Lines = append(x.Lines, ul)
+	}
+
+	out, err := xml.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
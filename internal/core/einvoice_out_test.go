@@ -0,0 +1,202 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testOutgoingInvoice is an Ausgangsrechnung at 19 % and 7 % with complete
+// seller and buyer data, so the XRechnung CIUS rules can pass.
+func testOutgoingInvoice() OutgoingEInvoice {
+	return OutgoingEInvoice{
+		Meta: Meta{
+			Auftraggeber:     "Stadtverwaltung Musterstadt",
+			Verwendungszweck: "Beratung März",
+			Rechnungsnummer:  "AR-2026-0042",
+			Rechnungsdatum:   "31.03.2026",
+			Waehrung:         "EUR",
+			TaxLines: []TaxLine{
+				{Netto: 1000, SatzProzent: 19, MwStBetrag: 190},
+				{Netto: 100, SatzProzent: 7, MwStBetrag: 7},
+			},
+			BetragNetto:      1100,
+			SteuersatzBetrag: 197,
+			Bruttobetrag:     1297,
+			Ausgangsrechnung: true,
+		},
+		Verkaeufer: Firmendaten{
+			Name: "Beispiel Consulting GmbH", Strasse: "Hauptstr. 1", PLZ: "10115", Ort: "Berlin",
+			Steuernummer: "30/123/45678", Ansprechpartner: "Erika Beispiel",
+			Telefon: "+49 30 123456", Email: "rechnung@beispiel.de",
+			IBAN: "DE02 1203 0000 0000 2020 51", BIC: "BYLADEM1001",
+		},
+		VerkaeuferUStID: "DE123456789",
+		Kaeufer:         Firmendaten{Strasse: "Rathausplatz 1", PLZ: "12345", Ort: "Musterstadt", Email: "eingang@musterstadt.de"},
+		Kaeuferreferenz: "04011000-12345-67",
+		Faelligkeit:     "30.04.2026",
+	}
+}
+
+func TestBuildCIIInvoiceXML_XRechnungRoundTrip(t *testing.T) {
+	data, err := BuildCIIInvoiceXML(testOutgoingInvoice(), GuidelineXRechnung)
+	if err != nil {
+		t.Fatalf("BuildCIIInvoiceXML: %v", err)
+	}
+	v, err := ValidateEInvoiceXML(data)
+	if err != nil {
+		t.Fatalf("ValidateEInvoiceXML: %v", err)
+	}
+	if !v.XRechnung || len(v.Findings) != 0 {
+		t.Fatalf("generated CII XRechnung has findings: %v\n%s", v.Findings, data)
+	}
+	meta, err := NewEInvoiceExtractor().extractFromXML(data)
+	if err != nil {
+		t.Fatalf("extractFromXML: %v", err)
+	}
+	if meta.Rechnungsnummer != "AR-2026-0042" || meta.Rechnungsdatum != "31.03.2026" || meta.Faelligkeit != "30.04.2026" {
+		t.Errorf("header = %q %q %q", meta.Rechnungsnummer, meta.Rechnungsdatum, meta.Faelligkeit)
+	}
+	if meta.BetragNetto != 1100 || meta.SteuersatzBetrag != 197 || meta.Bruttobetrag != 1297 {
+		t.Errorf("totals = %v / %v / %v, want 1100 / 197 / 1297", meta.BetragNetto, meta.SteuersatzBetrag, meta.Bruttobetrag)
+	}
+	if len(meta.TaxLines) != 2 || meta.VATID != "DE123456789" {
+		t.Errorf("TaxLines = %+v, VATID = %q", meta.TaxLines, meta.VATID)
+	}
+}
+
+func TestBuildUBLInvoiceXML_PositionsAndAllowance(t *testing.T) {
+	inv := testOutgoingInvoice()
+	inv.Meta.Positionen = []InvoicePosition{
+		{Nr: "1", Bezeichnung: "Workshop", Menge: 2, Einheit: "DAY", Einzelpreis: 500, Netto: 1000, SatzProzent: 19},
+		{Nr: "2", Bezeichnung: "Fachbuch", Menge: 1, Einheit: "H87", Einzelpreis: 100, Netto: 100, SatzProzent: 7},
+		{Bezeichnung: "Nachlass: Treuerabatt", Netto: -100, SatzProzent: 19},
+	}
+	inv.Meta.TaxLines = []TaxLine{
+		{Netto: 900, SatzProzent: 19, MwStBetrag: 171},
+		{Netto: 100, SatzProzent: 7, MwStBetrag: 7},
+	}
+	data, err := BuildUBLInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("BuildUBLInvoiceXML: %v", err)
+	}
+	v, err := ValidateEInvoiceXML(data)
+	if err != nil {
+		t.Fatalf("ValidateEInvoiceXML: %v", err)
+	}
+	if v.Syntax != SyntaxUBL || len(v.Findings) != 0 {
+		t.Fatalf("generated UBL has findings: %v\n%s", v.Findings, data)
+	}
+	meta, err := NewEInvoiceExtractor().extractFromXML(data)
+	if err != nil {
+		t.Fatalf("extractFromXML: %v", err)
+	}
+	if meta.BetragNetto != 1000 || meta.Bruttobetrag != 1178 {
+		t.Errorf("netto/brutto = %v / %v, want 1000 / 1178", meta.BetragNetto, meta.Bruttobetrag)
+	}
+	if len(meta.Positionen) != 3 || meta.Positionen[2].Netto != -100 {
+		t.Errorf("Positionen = %+v", meta.Positionen)
+	}
+}
+
+func TestBuildEInvoice_ReverseChargeAndCreditNote(t *testing.T) {
+	inv := testOutgoingInvoice()
+	inv.Meta.VATID = "ATU12345678"
+	inv.Meta.TaxLines = []TaxLine{{Netto: 500}}
+	data, err := BuildCIIInvoiceXML(inv, GuidelineEN16931)
+	if err != nil {
+		t.Fatalf("BuildCIIInvoiceXML: %v", err)
+	}
+	if v, _ := ValidateEInvoiceXML(data); len(v.Findings) != 0 {
+		t.Fatalf("reverse-charge invoice has findings: %v", v.Findings)
+	}
+	for _, want := range []string{"<ram:CategoryCode>AE</ram:CategoryCode>", "VATEX-EU-AE", "<ram:CountryID>AT</ram:CountryID>"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("CII lacks %s", want)
+		}
+	}
+
+	inv = testOutgoingInvoice()
+	inv.Meta.TaxLines = []TaxLine{{Netto: -200, SatzProzent: 19, MwStBetrag: -38}}
+	data, err = BuildUBLInvoiceXML(inv)
+	if err != nil {
+		t.Fatalf("BuildUBLInvoiceXML: %v", err)
+	}
+	s := string(data)
+	if !strings.Contains(s, "<CreditNote") || !strings.Contains(s, "<cbc:CreditNoteTypeCode>381") ||
+		!strings.Contains(s, "<cac:CreditNoteLine>") || !strings.Contains(s, "<cbc:PayableAmount currencyID=\"EUR\">238.00") {
+		t.Errorf("negative invoice not rendered as credit note:\n%s", s)
+	}
+	if v, _ := ValidateEInvoiceXML(data); len(v.Findings) != 0 {
+		t.Errorf("credit note has findings: %v", v.Findings)
+	}
+}
+
+func TestBuildEInvoice_Errors(t *testing.T) {
+	inv := testOutgoingInvoice()
+	inv.Meta.Rechnungsnummer = ""
+	if _, err := BuildCIIInvoiceXML(inv, GuidelineEN16931); err == nil {
+		t.Error("missing invoice number: expected error")
+	}
+	inv = testOutgoingInvoice()
+	inv.Meta.Rechnungsdatum = "2026-03-31"
+	if _, err := BuildUBLInvoiceXML(inv); err == nil {
+		t.Error("bad invoice date: expected error")
+	}
+}
+
+func TestEmbedFacturX(t *testing.T) {
+	inv := testOutgoingInvoice()
+	cii, err := BuildCIIInvoiceXML(inv, GuidelineEN16931)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := BuildEInvoiceSichtbelegPDF(inv.Meta, FormatZUGFeRD, FacturXFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := EmbedFacturX(base, cii, "Rechnung AR-2026-0042", time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("EmbedFacturX: %v", err)
+	}
+	if !strings.HasPrefix(string(out), "%PDF-1.7") {
+		t.Errorf("header = %q, want %%PDF-1.7", out[:8])
+	}
+	path := filepath.Join(t.TempDir(), EInvoiceFileName("AR-2026-0042.pdf", true))
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEInvoiceExtractor()
+	if f, ok := e.DetectFormat(path); !ok || f != FormatZUGFeRD {
+		t.Errorf("DetectFormat = %q, %v", f, ok)
+	}
+	meta, _, err := e.Extract(path)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if meta.Rechnungsnummer != "AR-2026-0042" || meta.Bruttobetrag != 1297 {
+		t.Errorf("extracted %q / %v", meta.Rechnungsnummer, meta.Bruttobetrag)
+	}
+	if meta.Pruefbericht == nil || !meta.Pruefbericht.Valid() {
+		t.Errorf("embedded XML does not validate: %+v", meta.Pruefbericht)
+	}
+	// The Sichtbeleg uses the standard fonts without embedding them, so it
+	// must not claim PDF/A.
+	if strings.Contains(string(out), "pdfaid") {
+		t.Error("PDF with non-embedded fonts claims PDF/A")
+	}
+	if _, err := EmbedFacturX(out, cii, "", time.Now()); err == nil {
+		t.Error("second embedding: expected error")
+	}
+}
+
+func TestEInvoiceFileName(t *testing.T) {
+	if got := EInvoiceFileName("2026-03-31_Stadt_AR-42.pdf", false); got != "2026-03-31_Stadt_AR-42_XRechnung.xml" {
+		t.Errorf("xml name = %q", got)
+	}
+	if got := EInvoiceFileName("AR-42.pdf", true); got != "AR-42_ZUGFeRD.pdf" {
+		t.Errorf("pdf name = %q", got)
+	}
+}
@@ -0,0 +1,405 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const ublInvoiceXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ubl:Invoice xmlns:ubl="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+  xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+  xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:CustomizationID>urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0</cbc:CustomizationID>
+  <cbc:ID>RE-2026-0815</cbc:ID>
+  <cbc:IssueDate>2026-03-14</cbc:IssueDate>
+  <cbc:DueDate>2026-04-13</cbc:DueDate>
+  <cbc:InvoiceTypeCode>380</cbc:InvoiceTypeCode>
+  <cbc:DocumentCurrencyCode>EUR</cbc:DocumentCurrencyCode>
+  <cac:AccountingSupplierParty>
+    <cac:Party>
+      <cac:PartyName><cbc:Name>Muster Bürobedarf GmbH</cbc:Name></cac:PartyName>
+      <cac:PartyTaxScheme>
+        <cbc:CompanyID>201/113/40209</cbc:CompanyID>
+        <cac:TaxScheme><cbc:ID>FC</cbc:ID></cac:TaxScheme>
+      </cac:PartyTaxScheme>
+      <cac:PartyTaxScheme>
+        <cbc:CompanyID>DE123456789</cbc:CompanyID>
+        <cac:TaxScheme><cbc:ID>VAT</cbc:ID></cac:TaxScheme>
+      </cac:PartyTaxScheme>
+      <cac:PartyLegalEntity><cbc:RegistrationName>Muster Bürobedarf GmbH</cbc:RegistrationName></cac:PartyLegalEntity>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:AccountingCustomerParty>
+    <cac:Party>
+      <cac:PartyLegalEntity><cbc:RegistrationName>Kunde AG</cbc:RegistrationName></cac:PartyLegalEntity>
+    </cac:Party>
+  </cac:AccountingCustomerParty>
+  <cac:TaxTotal>
+    <cbc:TaxAmount currencyID="EUR">26.00</cbc:TaxAmount>
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="EUR">100.00</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="EUR">19.00</cbc:TaxAmount>
+      <cac:TaxCategory><cbc:ID>S</cbc:ID><cbc:Percent>19</cbc:Percent><cac:TaxScheme><cbc:ID>VAT</cbc:ID></cac:TaxScheme></cac:TaxCategory>
+    </cac:TaxSubtotal>
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="EUR">100.00</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="EUR">7.00</cbc:TaxAmount>
+      <cac:TaxCategory><cbc:ID>S</cbc:ID><cbc:Percent>7</cbc:Percent><cac:TaxScheme><cbc:ID>VAT</cbc:ID></cac:TaxScheme></cac:TaxCategory>
+    </cac:TaxSubtotal>
+  </cac:TaxTotal>
+  <cac:LegalMonetaryTotal>
+    <cbc:LineExtensionAmount currencyID="EUR">200.00</cbc:LineExtensionAmount>
+    <cbc:TaxExclusiveAmount currencyID="EUR">200.00</cbc:TaxExclusiveAmount>
+    <cbc:TaxInclusiveAmount currencyID="EUR">226.00</cbc:TaxInclusiveAmount>
+    <cbc:PayableAmount currencyID="EUR">226.00</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+  <cac:InvoiceLine>
+    <cbc:ID>1</cbc:ID>
+    <cbc:InvoicedQuantity unitCode="H87">4</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="EUR">100.00</cbc:LineExtensionAmount>
+    <cac:Item><cbc:Name>Toner</cbc:Name><cac:ClassifiedTaxCategory><cbc:ID>S</cbc:ID><cbc:Percent>19</cbc:Percent><cac:TaxScheme><cbc:ID>VAT</cbc:ID></cac:TaxScheme></cac:ClassifiedTaxCategory></cac:Item>
+    <cac:Price><cbc:PriceAmount currencyID="EUR">25.00</cbc:PriceAmount></cac:Price>
+  </cac:InvoiceLine>
+  <cac:InvoiceLine>
+    <cbc:ID>2</cbc:ID>
+    <cbc:InvoicedQuantity unitCode="H87">10</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="EUR">100.00</cbc:LineExtensionAmount>
+    <cac:Item><cbc:Name>Fachbuch</cbc:Name><cac:ClassifiedTaxCategory><cbc:ID>S</cbc:ID><cbc:Percent>7</cbc:Percent><cac:TaxScheme><cbc:ID>VAT</cbc:ID></cac:TaxScheme></cac:ClassifiedTaxCategory></cac:Item>
+    <cac:Price><cbc:PriceAmount currencyID="EUR">10.00</cbc:PriceAmount></cac:Price>
+  </cac:InvoiceLine>
+</ubl:Invoice>`
+
+const ublCreditNoteXML = `<?xml version="1.0" encoding="UTF-8"?>
+<CreditNote xmlns="urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+  xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+  xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:ID>GS-17</cbc:ID>
+  <cbc:IssueDate>2026-05-02</cbc:IssueDate>
+  <cbc:CreditNoteTypeCode>381</cbc:CreditNoteTypeCode>
+  <cbc:DocumentCurrencyCode>EUR</cbc:DocumentCurrencyCode>
+  <cac:AccountingSupplierParty>
+    <cac:Party>
+      <cac:PartyLegalEntity><cbc:RegistrationName>Lieferant KG</cbc:RegistrationName></cac:PartyLegalEntity>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:PaymentMeans>
+    <cbc:PaymentMeansCode>58</cbc:PaymentMeansCode>
+    <cbc:PaymentDueDate>2026-05-16</cbc:PaymentDueDate>
+  </cac:PaymentMeans>
+  <cac:TaxTotal>
+    <cbc:TaxAmount currencyID="EUR">9.50</cbc:TaxAmount>
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="EUR">50.00</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="EUR">9.50</cbc:TaxAmount>
+      <cac:TaxCategory><cbc:ID>S</cbc:ID><cbc:Percent>19</cbc:Percent></cac:TaxCategory>
+    </cac:TaxSubtotal>
+  </cac:TaxTotal>
+  <cac:LegalMonetaryTotal>
+    <cbc:TaxExclusiveAmount currencyID="EUR">50.00</cbc:TaxExclusiveAmount>
+    <cbc:TaxInclusiveAmount currencyID="EUR">59.50</cbc:TaxInclusiveAmount>
+    <cbc:PayableAmount currencyID="EUR">59.50</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+</CreditNote>`
+
+const ciiInvoiceXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+  xmlns:ram="urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+  xmlns:udt="urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100">
+  <rsm:ExchangedDocument>
+    <ram:ID>471102</ram:ID>
+    <ram:TypeCode>380</ram:TypeCode>
+    <ram:IssueDateTime><udt:DateTimeString format="102">20260305</udt:DateTimeString></ram:IssueDateTime>
+  </rsm:ExchangedDocument>
+  <rsm:SupplyChainTradeTransaction>
+    <ram:ApplicableHeaderTradeAgreement>
+      <ram:SellerTradeParty>
+        <ram:Name>Lieferant GmbH</ram:Name>
+        <ram:SpecifiedTaxRegistration><ram:ID schemeID="FC">201/113/40209</ram:ID></ram:SpecifiedTaxRegistration>
+        <ram:SpecifiedTaxRegistration><ram:ID schemeID="VA">DE123456789</ram:ID></ram:SpecifiedTaxRegistration>
+      </ram:SellerTradeParty>
+    </ram:ApplicableHeaderTradeAgreement>
+    <ram:ApplicableHeaderTradeSettlement>
+      <ram:InvoiceCurrencyCode>EUR</ram:InvoiceCurrencyCode>
+      <ram:ApplicableTradeTax>
+        <ram:CalculatedAmount>19.00</ram:CalculatedAmount>
+        <ram:TypeCode>VAT</ram:TypeCode>
+        <ram:BasisAmount>100.00</ram:BasisAmount>
+        <ram:CategoryCode>S</ram:CategoryCode>
+        <ram:RateApplicablePercent>19</ram:RateApplicablePercent>
+      </ram:ApplicableTradeTax>
+      <ram:SpecifiedTradeSettlementHeaderMonetarySummation>
+        <ram:LineTotalAmount>100.00</ram:LineTotalAmount>
+        <ram:TaxBasisTotalAmount>100.00</ram:TaxBasisTotalAmount>
+        <ram:TaxTotalAmount currencyID="EUR">19.00</ram:TaxTotalAmount>
+        <ram:GrandTotalAmount>119.00</ram:GrandTotalAmount>
+        <ram:DuePayableAmount>119.00</ram:DuePayableAmount>
+      </ram:SpecifiedTradeSettlementHeaderMonetarySummation>
+    </ram:ApplicableHeaderTradeSettlement>
+  </rsm:SupplyChainTradeTransaction>
+</rsm:CrossIndustryInvoice>`
+
+// ciiMixedInvoiceXML has a 19 % and a 7 % line, a 10 € allowance at 19 %
+// and a 5 € charge at 7 %; the breakdown bases include them.
+const ciiMixedInvoiceXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+  xmlns:ram="urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+  xmlns:udt="urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100">
+  <rsm:ExchangedDocument>
+    <ram:ID>MIX-1</ram:ID>
+    <ram:TypeCode>380</ram:TypeCode>
+    <ram:IssueDateTime><udt:DateTimeString format="102">20260310</udt:DateTimeString></ram:IssueDateTime>
+  </rsm:ExchangedDocument>
+  <rsm:SupplyChainTradeTransaction>
+    <ram:IncludedSupplyChainTradeLineItem>
+      <ram:AssociatedDocumentLineDocument><ram:LineID>1</ram:LineID></ram:AssociatedDocumentLineDocument>
+      <ram:SpecifiedTradeProduct><ram:Name>Bürostuhl</ram:Name></ram:SpecifiedTradeProduct>
+      <ram:SpecifiedLineTradeAgreement><ram:NetPriceProductTradePrice><ram:ChargeAmount>200.00</ram:ChargeAmount></ram:NetPriceProductTradePrice></ram:SpecifiedLineTradeAgreement>
+      <ram:SpecifiedLineTradeDelivery><ram:BilledQuantity unitCode="H87">1</ram:BilledQuantity></ram:SpecifiedLineTradeDelivery>
+      <ram:SpecifiedLineTradeSettlement>
+        <ram:ApplicableTradeTax><ram:TypeCode>VAT</ram:TypeCode><ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>19</ram:RateApplicablePercent></ram:ApplicableTradeTax>
+        <ram:SpecifiedTradeSettlementLineMonetarySummation><ram:LineTotalAmount>200.00</ram:LineTotalAmount></ram:SpecifiedTradeSettlementLineMonetarySummation>
+      </ram:SpecifiedLineTradeSettlement>
+    </ram:IncludedSupplyChainTradeLineItem>
+    <ram:IncludedSupplyChainTradeLineItem>
+      <ram:AssociatedDocumentLineDocument><ram:LineID>2</ram:LineID></ram:AssociatedDocumentLineDocument>
+      <ram:SpecifiedTradeProduct><ram:Name>Fachzeitschrift</ram:Name></ram:SpecifiedTradeProduct>
+      <ram:SpecifiedLineTradeAgreement><ram:NetPriceProductTradePrice><ram:ChargeAmount>50.00</ram:ChargeAmount></ram:NetPriceProductTradePrice></ram:SpecifiedLineTradeAgreement>
+      <ram:SpecifiedLineTradeDelivery><ram:BilledQuantity unitCode="H87">2</ram:BilledQuantity></ram:SpecifiedLineTradeDelivery>
+      <ram:SpecifiedLineTradeSettlement>
+        <ram:ApplicableTradeTax><ram:TypeCode>VAT</ram:TypeCode><ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>7</ram:RateApplicablePercent></ram:ApplicableTradeTax>
+        <ram:SpecifiedTradeSettlementLineMonetarySummation><ram:LineTotalAmount>100.00</ram:LineTotalAmount></ram:SpecifiedTradeSettlementLineMonetarySummation>
+      </ram:SpecifiedLineTradeSettlement>
+    </ram:IncludedSupplyChainTradeLineItem>
+    <ram:ApplicableHeaderTradeAgreement>
+      <ram:SellerTradeParty><ram:Name>Büro Mix GmbH</ram:Name></ram:SellerTradeParty>
+    </ram:ApplicableHeaderTradeAgreement>
+    <ram:ApplicableHeaderTradeSettlement>
+      <ram:InvoiceCurrencyCode>EUR</ram:InvoiceCurrencyCode>
+      <ram:ApplicableTradeTax>
+        <ram:CalculatedAmount>36.10</ram:CalculatedAmount>
+        <ram:TypeCode>VAT</ram:TypeCode>
+        <ram:BasisAmount>190.00</ram:BasisAmount>
+        <ram:CategoryCode>S</ram:CategoryCode>
+        <ram:RateApplicablePercent>19</ram:RateApplicablePercent>
+      </ram:ApplicableTradeTax>
+      <ram:ApplicableTradeTax>
+        <ram:CalculatedAmount>7.35</ram:CalculatedAmount>
+        <ram:TypeCode>VAT</ram:TypeCode>
+        <ram:BasisAmount>105.00</ram:BasisAmount>
+        <ram:CategoryCode>S</ram:CategoryCode>
+        <ram:RateApplicablePercent>7</ram:RateApplicablePercent>
+      </ram:ApplicableTradeTax>
+      <ram:SpecifiedTradeAllowanceCharge>
+        <ram:ChargeIndicator><udt:Indicator>false</udt:Indicator></ram:ChargeIndicator>
+        <ram:ActualAmount>10.00</ram:ActualAmount>
+        <ram:Reason>Treuerabatt</ram:Reason>
+        <ram:CategoryTradeTax><ram:TypeCode>VAT</ram:TypeCode><ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>19</ram:RateApplicablePercent></ram:CategoryTradeTax>
+      </ram:SpecifiedTradeAllowanceCharge>
+      <ram:SpecifiedTradeAllowanceCharge>
+        <ram:ChargeIndicator><udt:Indicator>true</udt:Indicator></ram:ChargeIndicator>
+        <ram:ActualAmount>5.00</ram:ActualAmount>
+        <ram:Reason>Versand</ram:Reason>
+        <ram:CategoryTradeTax><ram:TypeCode>VAT</ram:TypeCode><ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>7</ram:RateApplicablePercent></ram:CategoryTradeTax>
+      </ram:SpecifiedTradeAllowanceCharge>
+      <ram:SpecifiedTradeSettlementHeaderMonetarySummation>
+        <ram:LineTotalAmount>300.00</ram:LineTotalAmount>
+        <ram:ChargeTotalAmount>5.00</ram:ChargeTotalAmount>
+        <ram:AllowanceTotalAmount>10.00</ram:AllowanceTotalAmount>
+        <ram:TaxBasisTotalAmount>295.00</ram:TaxBasisTotalAmount>
+        <ram:TaxTotalAmount currencyID="EUR">43.45</ram:TaxTotalAmount>
+        <ram:GrandTotalAmount>338.45</ram:GrandTotalAmount>
+        <ram:DuePayableAmount>338.45</ram:DuePayableAmount>
+      </ram:SpecifiedTradeSettlementHeaderMonetarySummation>
+    </ram:ApplicableHeaderTradeSettlement>
+  </rsm:SupplyChainTradeTransaction>
+</rsm:CrossIndustryInvoice>`
+
+func TestDetectSyntax(t *testing.T) {
+	cases := map[string]EInvoiceSyntax{
+		ublInvoiceXML:    SyntaxUBL,
+		ublCreditNoteXML: SyntaxUBL,
+		ciiInvoiceXML:    SyntaxCII,
+		`<Document/>`:    SyntaxNone,
+		`not xml at all`: SyntaxNone,
+		``:               SyntaxNone,
+	}
+	for in, want := range cases {
+		if got := DetectSyntax([]byte(in)); got != want {
+			t.Errorf("DetectSyntax(%.40q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExtractFromXML_UBLInvoice(t *testing.T) {
+	meta, err := NewEInvoiceExtractor().extractFromXML([]byte(ublInvoiceXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Rechnungsnummer != "RE-2026-0815" || meta.Rechnungsdatum != "14.03.2026" ||
+		meta.Jahr != "2026" || meta.Monat != "03" {
+		t.Errorf("header = %q %q %q/%q", meta.Rechnungsnummer, meta.Rechnungsdatum, meta.Jahr, meta.Monat)
+	}
+	if meta.Auftraggeber != "Muster Bürobedarf GmbH" || meta.VATID != "DE123456789" {
+		t.Errorf("seller = %q / %q", meta.Auftraggeber, meta.VATID)
+	}
+	if meta.Waehrung != "EUR" || !almost(meta.BetragNetto, 200) || !almost(meta.Bruttobetrag, 226) ||
+		!almost(meta.SteuersatzBetrag, 26) || !almost(meta.SteuersatzProzent, 19) {
+		t.Errorf("amounts = %+v", meta)
+	}
+	if len(meta.TaxLines) != 2 || !almost(meta.TaxLines[1].SatzProzent, 7) || !almost(meta.TaxLines[1].MwStBetrag, 7) {
+		t.Errorf("TaxLines = %+v", meta.TaxLines)
+	}
+	if len(meta.Positionen) != 2 || meta.Positionen[0].Bezeichnung != "Toner" || !almost(meta.Positionen[0].Menge, 4) ||
+		!almost(meta.Positionen[1].SatzProzent, 7) || !almost(SumPositionen(meta.Positionen), 200) {
+		t.Errorf("Positionen = %+v", meta.Positionen)
+	}
+	if meta.Faelligkeit != "13.04.2026" || meta.Bezahldatum != "" {
+		t.Errorf("Faelligkeit/Bezahldatum = %q / %q, want due date 13.04.2026 and no payment", meta.Faelligkeit, meta.Bezahldatum)
+	}
+	if meta.Verwendungszweck != "Rechnung RE-2026-0815" {
+		t.Errorf("Verwendungszweck = %q", meta.Verwendungszweck)
+	}
+}
+
+func TestExtractFromXML_UBLCreditNote(t *testing.T) {
+	meta, err := NewEInvoiceExtractor().extractFromXML([]byte(ublCreditNoteXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No PartyName → legal name; due date from PaymentMeans.
+	if meta.Auftraggeber != "Lieferant KG" || meta.Faelligkeit != "16.05.2026" || meta.Bezahldatum != "" {
+		t.Errorf("seller/due/paid = %q / %q / %q", meta.Auftraggeber, meta.Faelligkeit, meta.Bezahldatum)
+	}
+	// A credit note reverses: net, VAT and gross are negative.
+	if meta.Verwendungszweck != "Gutschrift GS-17" || !almost(meta.Bruttobetrag, -59.5) ||
+		!almost(meta.BetragNetto, -50) || !almost(meta.SteuersatzBetrag, -9.5) {
+		t.Errorf("credit note = %q / %v / %v / %v", meta.Verwendungszweck, meta.BetragNetto, meta.SteuersatzBetrag, meta.Bruttobetrag)
+	}
+	if len(meta.TaxLines) != 1 || !almost(meta.TaxLines[0].Netto, -50) || !almost(meta.TaxLines[0].MwStBetrag, -9.5) ||
+		!almost(meta.TaxLines[0].SatzProzent, 19) {
+		t.Errorf("TaxLines = %+v, want −50 / −9.50 at 19 %%", meta.TaxLines)
+	}
+}
+
+func TestExtractFromXML_CII(t *testing.T) {
+	meta, err := NewEInvoiceExtractor().extractFromXML([]byte(ciiInvoiceXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Rechnungsnummer != "471102" || meta.Rechnungsdatum != "05.03.2026" || meta.Auftraggeber != "Lieferant GmbH" {
+		t.Errorf("header = %+v", meta)
+	}
+	if meta.VATID != "DE123456789" {
+		t.Errorf("VATID = %q, want the schemeID=VA registration", meta.VATID)
+	}
+	if !almost(meta.Bruttobetrag, 119) || !almost(meta.SteuersatzBetrag, 19) {
+		t.Errorf("amounts = %v / %v", meta.Bruttobetrag, meta.SteuersatzBetrag)
+	}
+}
+
+func TestExtractFromXML_CIICreditNote(t *testing.T) {
+	xml := strings.Replace(ciiInvoiceXML, "<ram:TypeCode>380</ram:TypeCode>", "<ram:TypeCode>381</ram:TypeCode>", 1)
+	meta, err := NewEInvoiceExtractor().extractFromXML([]byte(xml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Verwendungszweck != "Gutschrift 471102" || !almost(meta.Bruttobetrag, -119) || !almost(meta.SteuersatzBetrag, -19) {
+		t.Errorf("credit note = %q / %v / %v", meta.Verwendungszweck, meta.Bruttobetrag, meta.SteuersatzBetrag)
+	}
+	for _, l := range meta.TaxLines {
+		if l.Netto > 0 || l.MwStBetrag > 0 {
+			t.Errorf("TaxLine %+v not negated", l)
+		}
+	}
+}
+
+func TestExtractFromXML_CIIMixedRates(t *testing.T) {
+	meta, err := NewEInvoiceExtractor().extractFromXML([]byte(ciiMixedInvoiceXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TaxLine{{Netto: 190, SatzProzent: 19, MwStBetrag: 36.10}, {Netto: 105, SatzProzent: 7, MwStBetrag: 7.35}}
+	if len(meta.TaxLines) != len(want) {
+		t.Fatalf("TaxLines = %+v, want %+v", meta.TaxLines, want)
+	}
+	for i, w := range want {
+		g := meta.TaxLines[i]
+		if !almost(g.Netto, w.Netto) || !almost(g.SatzProzent, w.SatzProzent) || !almost(g.MwStBetrag, w.MwStBetrag) {
+			t.Errorf("TaxLines[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+	if !almost(meta.BetragNetto, 295) || !almost(meta.SteuersatzBetrag, 43.45) ||
+		!almost(meta.Bruttobetrag, 338.45) || !almost(meta.SteuersatzProzent, 19) {
+		t.Errorf("amounts = %v / %v / %v / %v", meta.BetragNetto, meta.SteuersatzBetrag, meta.Bruttobetrag, meta.SteuersatzProzent)
+	}
+
+	// Two items, the allowance (negative) and the charge; together they
+	// add up to the tax basis.
+	if len(meta.Positionen) != 4 {
+		t.Fatalf("Positionen = %+v", meta.Positionen)
+	}
+	p := meta.Positionen
+	if p[0].Nr != "1" || p[0].Bezeichnung != "Bürostuhl" || !almost(p[0].Netto, 200) || !almost(p[0].SatzProzent, 19) {
+		t.Errorf("Positionen[0] = %+v", p[0])
+	}
+	if !almost(p[1].Menge, 2) || p[1].Einheit != "H87" || !almost(p[1].Einzelpreis, 50) {
+		t.Errorf("Positionen[1] = %+v", p[1])
+	}
+	if p[2].Bezeichnung != "Nachlass: Treuerabatt" || !almost(p[2].Netto, -10) {
+		t.Errorf("Positionen[2] = %+v", p[2])
+	}
+	if p[3].Bezeichnung != "Zuschlag: Versand" || !almost(p[3].Netto, 5) || !almost(p[3].SatzProzent, 7) {
+		t.Errorf("Positionen[3] = %+v", p[3])
+	}
+	if !almost(SumPositionen(p), meta.BetragNetto) {
+		t.Errorf("SumPositionen = %v, want %v", SumPositionen(p), meta.BetragNetto)
+	}
+}
+
+func TestCIITaxLines_MissingBasis(t *testing.T) {
+	positions := []InvoicePosition{{Netto: 200, SatzProzent: 19}, {Netto: -10, SatzProzent: 19}, {Netto: 100, SatzProzent: 7}}
+	settlement := HeaderTradeSettlement{ApplicableTradeTax: []TradeTax{
+		{RateApplicablePercent: "19"},
+		{RateApplicablePercent: "7", CalculatedAmount: Amount{Value: "7.00"}},
+	}}
+	lines := ciiTaxLines(settlement, positions)
+	if len(lines) != 2 || !almost(lines[0].Netto, 190) || !almost(lines[0].MwStBetrag, 36.10) ||
+		!almost(lines[1].Netto, 100) || !almost(lines[1].MwStBetrag, 7) {
+		t.Errorf("lines = %+v", lines)
+	}
+}
+
+func TestExtractFromXML_Unknown(t *testing.T) {
+	if _, err := NewEInvoiceExtractor().extractFromXML([]byte(`<Document/>`)); err == nil {
+		t.Error("expected error for non-invoice XML")
+	}
+}
+
+func TestStandaloneXMLFile(t *testing.T) {
+	dir := t.TempDir()
+	ubl := filepath.Join(dir, "rechnung.xml")
+	if err := os.WriteFile(ubl, []byte(ublInvoiceXML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	camt := filepath.Join(dir, "auszug.xml")
+	if err := os.WriteFile(camt, []byte(`<Document><BkToCstmrStmt/></Document>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEInvoiceExtractor()
+	if f, ok := e.DetectFormat(ubl); !ok || f != FormatXRechnungUBL {
+		t.Errorf("DetectFormat(ubl) = %q, %v", f, ok)
+	}
+	if f, ok := e.DetectFormat(camt); ok {
+		t.Errorf("DetectFormat(camt) = %q, want no e-invoice", f)
+	}
+	meta, conf, err := e.Extract(ubl)
+	if err != nil || conf != 1.0 || meta.Rechnungsnummer != "RE-2026-0815" {
+		t.Errorf("Extract = %+v, %v, %v", meta, conf, err)
+	}
+	if meta.Pruefbericht == nil || !meta.Pruefbericht.XRechnung {
+		t.Errorf("Extract did not attach the validation: %+v", meta.Pruefbericht)
+	}
+}
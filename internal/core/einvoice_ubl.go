@@ -0,0 +1,285 @@
+package core
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// UBLInvoice represents the root element of an OASIS UBL 2.1 document. The
+// same struct decodes both an <Invoice> and a <CreditNote>: the two share the
+// EN 16931 business terms and differ only in the root name and a few element
+// names (type code, due date location), which are mapped side by side.
+type UBLInvoice struct {
+	XMLName                 xml.Name
+	CustomizationID         string               `xml:"CustomizationID"`
+	ID                      string               `xml:"ID"`
+	IssueDate               string               `xml:"IssueDate"`
+	DueDate                 string               `xml:"DueDate"` // Invoice only
+	InvoiceTypeCode         string               `xml:"InvoiceTypeCode"`
+	CreditNoteTypeCode      string               `xml:"CreditNoteTypeCode"`
+	DocumentCurrencyCode    string               `xml:"DocumentCurrencyCode"`
+	BuyerReference          string               `xml:"BuyerReference"` // BT-10, Leitweg-ID for XRechnung
+	AccountingSupplierParty UBLParty             `xml:"AccountingSupplierParty>Party"`
+	AccountingCustomerParty UBLParty             `xml:"AccountingCustomerParty>Party"`
+	PaymentMeans            []UBLPaymentMeans    `xml:"PaymentMeans"`
+	PaymentTerms            []UBLPaymentTerms    `xml:"PaymentTerms"`
+	AllowanceCharge         []UBLAllowanceCharge `xml:"AllowanceCharge"`
+	TaxTotal                []UBLTaxTotal        `xml:"TaxTotal"`
+	LegalMonetaryTotal      UBLMonetaryTotal     `xml:"LegalMonetaryTotal"`
+	InvoiceLine             []UBLInvoiceLine     `xml:"InvoiceLine"`
+	CreditNoteLine          []UBLInvoiceLine     `xml:"CreditNoteLine"`
+}
+
+// UBLInvoiceLine is one invoice line (BG-25); an <InvoiceLine> carries an
+// InvoicedQuantity, a <CreditNoteLine> a CreditedQuantity.
+type UBLInvoiceLine struct {
+	ID                  string      `xml:"ID"`
+	InvoicedQuantity    UBLQuantity `xml:"InvoicedQuantity"`
+	CreditedQuantity    UBLQuantity `xml:"CreditedQuantity"`
+	LineExtensionAmount UBLAmount   `xml:"LineExtensionAmount"`
+	ItemName            string      `xml:"Item>Name"`
+	CategoryID          string      `xml:"Item>ClassifiedTaxCategory>ID"`
+	Percent             string      `xml:"Item>ClassifiedTaxCategory>Percent"`
+	PriceAmount         UBLAmount   `xml:"Price>PriceAmount"`
+}
+
+// UBLQuantity is a quantity with its UN/ECE unit code.
+type UBLQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// UBLAllowanceCharge is a document-level allowance (BG-20) or charge (BG-21).
+type UBLAllowanceCharge struct {
+	ChargeIndicator       string    `xml:"ChargeIndicator"`
+	AllowanceChargeReason string    `xml:"AllowanceChargeReason"`
+	Amount                UBLAmount `xml:"Amount"`
+	CategoryID            string    `xml:"TaxCategory>ID"`
+	Percent               string    `xml:"TaxCategory>Percent"`
+}
+
+// UBLParty is a seller or buyer party.
+type UBLParty struct {
+	Name             string              `xml:"PartyName>Name"`
+	PostalAddress    UBLAddress          `xml:"PostalAddress"`
+	PartyTaxScheme   []UBLPartyTaxScheme `xml:"PartyTaxScheme"`
+	RegistrationName string              `xml:"PartyLegalEntity>RegistrationName"`
+	Contact          UBLContact          `xml:"Contact"`
+}
+
+// UBLAddress is a party's postal address.
+type UBLAddress struct {
+	CityName    string `xml:"CityName"`
+	PostalZone  string `xml:"PostalZone"`
+	CountryCode string `xml:"Country>IdentificationCode"`
+}
+
+// UBLContact is a party's contact point (BG-6 for the seller).
+type UBLContact struct {
+	Name           string `xml:"Name"`
+	Telephone      string `xml:"Telephone"`
+	ElectronicMail string `xml:"ElectronicMail"`
+}
+
+// UBLPartyTaxScheme is a party's tax registration; TaxScheme "VAT" carries
+// the USt-IdNr, anything else (e.g. "FC") a national tax number.
+type UBLPartyTaxScheme struct {
+	CompanyID string `xml:"CompanyID"`
+	TaxScheme string `xml:"TaxScheme>ID"`
+}
+
+// UBLPaymentMeans is a payment instruction (BG-16); it also carries the
+// payment due date of a CreditNote.
+type UBLPaymentMeans struct {
+	PaymentMeansCode string `xml:"PaymentMeansCode"`
+	PaymentDueDate   string `xml:"PaymentDueDate"`
+}
+
+// UBLPaymentTerms is the free-text payment terms note.
+type UBLPaymentTerms struct {
+	Note string `xml:"Note"`
+}
+
+// UBLTaxTotal is the document VAT total with its per-category breakdown.
+type UBLTaxTotal struct {
+	TaxAmount   UBLAmount        `xml:"TaxAmount"`
+	TaxSubtotal []UBLTaxSubtotal `xml:"TaxSubtotal"`
+}
+
+// UBLTaxSubtotal is one VAT breakdown line (BG-23).
+type UBLTaxSubtotal struct {
+	TaxableAmount UBLAmount `xml:"TaxableAmount"`
+	TaxAmount     UBLAmount `xml:"TaxAmount"`
+	CategoryID    string    `xml:"TaxCategory>ID"`
+	Percent       string    `xml:"TaxCategory>Percent"`
+	ExemptionCode string    `xml:"TaxCategory>TaxExemptionReasonCode"`
+	Exemption     string    `xml:"TaxCategory>TaxExemptionReason"`
+}
+
+// UBLAmount is a monetary amount with its currency attribute.
+type UBLAmount struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+// UBLMonetaryTotal contains the document totals (BG-22).
+type UBLMonetaryTotal struct {
+	LineExtensionAmount   UBLAmount `xml:"LineExtensionAmount"`
+	TaxExclusiveAmount    UBLAmount `xml:"TaxExclusiveAmount"`
+	TaxInclusiveAmount    UBLAmount `xml:"TaxInclusiveAmount"`
+	AllowanceTotalAmount  UBLAmount `xml:"AllowanceTotalAmount"`
+	ChargeTotalAmount     UBLAmount `xml:"ChargeTotalAmount"`
+	PrepaidAmount         UBLAmount `xml:"PrepaidAmount"`
+	PayableRoundingAmount UBLAmount `xml:"PayableRoundingAmount"`
+	PayableAmount         UBLAmount `xml:"PayableAmount"`
+}
+
+// IsCreditNote reports whether the document is a UBL <CreditNote>.
+func (u *UBLInvoice) IsCreditNote() bool {
+	return u.XMLName.Local == "CreditNote"
+}
+
+// VATID returns the party's USt-IdNr (the PartyTaxScheme with TaxScheme VAT),
+// or "" if it has none.
+func (p UBLParty) VATID() string {
+	for _, s := range p.PartyTaxScheme {
+		if strings.EqualFold(strings.TrimSpace(s.TaxScheme), "VAT") {
+			return strings.TrimSpace(s.CompanyID)
+		}
+	}
+	return ""
+}
+
+// DisplayName returns the trading name, falling back to the legal name.
+func (p UBLParty) DisplayName() string {
+	if n := strings.TrimSpace(p.Name); n != "" {
+		return n
+	}
+	return strings.TrimSpace(p.RegistrationName)
+}
+
+// documentTaxTotal returns the TaxTotal in the document currency — the one
+// carrying the breakdown. A second TaxTotal (BT-111, VAT in accounting
+// currency) has no subtotals and is skipped.
+func (u *UBLInvoice) documentTaxTotal() UBLTaxTotal {
+	for _, t := range u.TaxTotal {
+		if len(t.TaxSubtotal) > 0 {
+			return t
+		}
+	}
+	if len(u.TaxTotal) > 0 {
+		return u.TaxTotal[0]
+	}
+	return UBLTaxTotal{}
+}
+
+// parseUBL parses a UBL Invoice or CreditNote.
+func (e *EInvoiceExtractor) parseUBL(xmlData []byte) (*UBLInvoice, error) {
+	var invoice UBLInvoice
+	if err := xml.Unmarshal(xmlData, &invoice); err != nil {
+		return nil, fmt.Errorf("XML unmarshal error: %w", err)
+	}
+	return &invoice, nil
+}
+
+// mapUBLToMeta maps a parsed UBL document to our Meta structure, mirroring
+// mapToMeta for CII: Auftraggeber is always the seller, the legacy VAT fields
+// carry the first breakdown line and the total VAT.
+func (e *EInvoiceExtractor) mapUBLToMeta(invoice *UBLInvoice) Meta {
+	meta := Meta{}
+
+	meta.Rechnungsnummer = strings.TrimSpace(invoice.ID)
+
+	// Invoice date (convert YYYY-MM-DD to DD.MM.YYYY)
+	dateStr := strings.TrimSpace(invoice.IssueDate)
+	meta.Rechnungsdatum = convertISODate(dateStr)
+	if len(dateStr) >= 7 {
+		meta.Jahr = dateStr[0:4]  // YYYY
+		meta.Monat = dateStr[5:7] // MM
+	}
+
+	seller := invoice.AccountingSupplierParty
+	meta.Auftraggeber = seller.DisplayName()
+	meta.VATID = seller.VATID()
+
+	meta.Waehrung = strings.TrimSpace(invoice.DocumentCurrencyCode)
+
+	totals := invoice.LegalMonetaryTotal
+	meta.BetragNetto = parseXMLAmount(totals.TaxExclusiveAmount.Value)
+	meta.Bruttobetrag = parseXMLAmount(totals.TaxInclusiveAmount.Value)
+
+	meta.Positionen = ublPositions(invoice)
+
+	taxTotal := invoice.documentTaxTotal()
+	for _, st := range taxTotal.TaxSubtotal {
+		meta.TaxLines = append(meta.TaxLines, TaxLine{
+			Netto:       parseXMLAmount(st.TaxableAmount.Value),
+			SatzProzent: parseXMLAmount(st.Percent),
+			MwStBetrag:  parseXMLAmount(st.TaxAmount.Value),
+		})
+	}
+	meta.SteuersatzProzent = PrimarySatz(meta.TaxLines)
+	meta.SteuersatzBetrag = parseXMLAmount(taxTotal.TaxAmount.Value)
+
+	// Payment due date: on the root for an Invoice, inside PaymentMeans for
+	// a CreditNote.
+	due := strings.TrimSpace(invoice.DueDate)
+	if due == "" {
+		for _, pm := range invoice.PaymentMeans {
+			if d := strings.TrimSpace(pm.PaymentDueDate); d != "" {
+				due = d
+				break
+			}
+		}
+	}
+	if due != "" {
+		meta.Faelligkeit = convertISODate(due)
+	}
+
+	if invoice.IsCreditNote() {
+		negateGutschrift(&meta)
+		meta.Verwendungszweck = fmt.Sprintf("Gutschrift %s", meta.Rechnungsnummer)
+	} else {
+		meta.Verwendungszweck = fmt.Sprintf("Rechnung %s", meta.Rechnungsnummer)
+	}
+
+	return meta
+}
+
+// ublPositions collects the item lines and document-level
+// allowances/charges of a UBL Invoice or CreditNote.
+func ublPositions(invoice *UBLInvoice) []InvoicePosition {
+	var out []InvoicePosition
+	for _, li := range append(invoice.InvoiceLine, invoice.CreditNoteLine...) {
+		qty := li.InvoicedQuantity
+		if strings.TrimSpace(qty.Value) == "" {
+			qty = li.CreditedQuantity
+		}
+		out = append(out, InvoicePosition{
+			Nr:          strings.TrimSpace(li.ID),
+			Bezeichnung: strings.TrimSpace(li.ItemName),
+			Menge:       parseXMLAmount(qty.Value),
+			Einheit:     strings.TrimSpace(qty.UnitCode),
+			Einzelpreis: parseXMLAmount(li.PriceAmount.Value),
+			Netto:       parseXMLAmount(li.LineExtensionAmount.Value),
+			SatzProzent: parseXMLAmount(li.Percent),
+		})
+	}
+	for _, ac := range invoice.AllowanceCharge {
+		isCharge := strings.EqualFold(strings.TrimSpace(ac.ChargeIndicator), "true")
+		out = append(out, allowanceChargePosition(isCharge,
+			parseXMLAmount(ac.Amount.Value), parseXMLAmount(ac.Percent), ac.AllowanceChargeReason))
+	}
+	return out
+}
+
+// convertISODate converts YYYY-MM-DD to DD.MM.YYYY. Anything else is
+// returned unchanged.
+func convertISODate(dateStr string) string {
+	dateStr = strings.TrimSpace(dateStr)
+	if len(dateStr) != 10 || dateStr[4] != '-' || dateStr[7] != '-' {
+		return dateStr
+	}
+	return fmt.Sprintf("%s.%s.%s", dateStr[8:10], dateStr[5:7], dateStr[0:4])
+}
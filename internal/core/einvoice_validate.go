@@ -0,0 +1,623 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// Severity levels of a validation finding. A "Fehler" violates a rule the
+// standard marks as fatal (the invoice may be rejected); a "Warnung" is
+// advisory.
+const (
+	SeverityFehler  = "Fehler"
+	SeverityWarnung = "Warnung"
+)
+
+// ValidationFinding is one violated business rule of an e-invoice.
+type ValidationFinding struct {
+	Rule     string // rule ID, e.g. "BR-CO-15", "BR-DE-15"
+	Severity string // SeverityFehler or SeverityWarnung
+	Message  string // German description incl. the offending values
+}
+
+// String renders the finding as "BR-CO-15: message".
+func (f ValidationFinding) String() string {
+	return f.Rule + ": " + f.Message
+}
+
+// EInvoiceValidation is the result of checking one e-invoice against the
+// EN 16931 core rules and, for XRechnung documents, the German CIUS rules.
+type EInvoiceValidation struct {
+	Syntax    EInvoiceSyntax
+	Profile   string // specification identifier (BT-24)
+	XRechnung bool   // BT-24 names the XRechnung CIUS, so BR-DE-* apply
+	Findings  []ValidationFinding
+}
+
+// Valid reports whether no fatal rule is violated.
+func (v EInvoiceValidation) Valid() bool {
+	for _, f := range v.Findings {
+		if f.Severity == SeverityFehler {
+			return false
+		}
+	}
+	return true
+}
+
+// Warnings returns the findings as warning strings in the style of
+// InvoiceWarnings, prefixed so they are recognisable as e-invoice findings.
+func (v EInvoiceValidation) Warnings() []string {
+	out := make([]string, 0, len(v.Findings))
+	for _, f := range v.Findings {
+		out = append(out, "E-Rechnung "+f.String())
+	}
+	return out
+}
+
+// ValidateEInvoiceXML parses e-invoice XML (CII or UBL) and checks it. It
+// errors only when the XML is not an invoice in either syntax.
+func ValidateEInvoiceXML(xmlData []byte) (EInvoiceValidation, error) {
+	e := NewEInvoiceExtractor()
+	switch DetectSyntax(xmlData) {
+	case SyntaxCII:
+		inv, err := e.parseXML(xmlData)
+		if err != nil {
+			return EInvoiceValidation{}, fmt.Errorf("failed to parse XML: %w", err)
+		}
+		return validateEInvoiceDoc(ciiDoc(inv)), nil
+	case SyntaxUBL:
+		inv, err := e.parseUBL(xmlData)
+		if err != nil {
+			return EInvoiceValidation{}, fmt.Errorf("failed to parse XML: %w", err)
+		}
+		return validateEInvoiceDoc(ublDoc(inv)), nil
+	}
+	return EInvoiceValidation{}, fmt.Errorf("unknown e-invoice syntax (neither CII nor UBL)")
+}
+
+// einvoiceDoc is the syntax-neutral view of an invoice the rules run on.
+// Amounts stay strings so a missing element can be told apart from 0.
+type einvoiceDoc struct {
+	syntax         EInvoiceSyntax
+	specID         string // BT-24
+	number         string // BT-1
+	issueDate      string // BT-2
+	typeCode       string // BT-3
+	currency       string // BT-5
+	buyerReference string // BT-10
+	seller, buyer  einvoiceParty
+	paymentMeans   []string // BT-81 per BG-16
+	lines          []einvoiceLine
+	allowances     []einvoiceAllowanceCharge
+	breakdown      []einvoiceVAT
+	lineTotal      string // BT-106
+	allowanceTotal string // BT-107
+	chargeTotal    string // BT-108
+	taxBasisTotal  string // BT-109
+	taxTotal       string // BT-110
+	grandTotal     string // BT-112
+	prepaid        string // BT-113
+	rounding       string // BT-114
+	duePayable     string // BT-115
+}
+
+type einvoiceParty struct {
+	name, vatID, taxNumber    string
+	city, postCode, country   string
+	contactName, phone, email string
+	hasContact                bool
+}
+
+type einvoiceLine struct {
+	id, quantity, unit, net, name, price, category, rate string
+}
+
+type einvoiceAllowanceCharge struct {
+	charge                 bool
+	amount, category, rate string
+}
+
+type einvoiceVAT struct {
+	basis, amount, category, rate, exemption string
+}
+
+// ciiDoc maps a CII invoice to the neutral view.
+func ciiDoc(inv *CrossIndustryInvoice) einvoiceDoc {
+	tx := inv.SupplyChainTradeTransaction
+	agr := tx.ApplicableHeaderTradeAgreement
+	st := tx.ApplicableHeaderTradeSettlement
+	sums := st.SpecifiedTradeSettlementHeaderMonetarySummation
+	party := func(p TradeParty) einvoiceParty {
+		c := p.DefinedTradeContact
+		return einvoiceParty{
+			name: p.Name, vatID: p.VATID(), taxNumber: p.TaxNumber(),
+			city: p.PostalTradeAddress.CityName, postCode: p.PostalTradeAddress.PostcodeCode,
+			country:     p.PostalTradeAddress.CountryID,
+			contactName: c.PersonName, phone: c.Telephone, email: c.Email,
+			hasContact: c != TradeContact{},
+		}
+	}
+	d := einvoiceDoc{
+		syntax:         SyntaxCII,
+		specID:         inv.GuidelineID,
+		number:         inv.ExchangedDocument.ID,
+		issueDate:      inv.ExchangedDocument.IssueDateTime.DateTimeString.Value,
+		typeCode:       inv.ExchangedDocument.TypeCode,
+		currency:       st.InvoiceCurrencyCode,
+		buyerReference: agr.BuyerReference,
+		seller:         party(agr.SellerTradeParty),
+		buyer:          party(agr.BuyerTradeParty),
+		lineTotal:      sums.LineTotalAmount.Value,
+		allowanceTotal: sums.AllowanceTotalAmount.Value,
+		chargeTotal:    sums.ChargeTotalAmount.Value,
+		taxBasisTotal:  sums.TaxBasisTotalAmount.Value,
+		grandTotal:     sums.GrandTotalAmount.Value,
+		prepaid:        sums.TotalPrepaidAmount.Value,
+		rounding:       sums.RoundingAmount.Value,
+		duePayable:     sums.DuePayableAmount.Value,
+	}
+	if t, ok := sums.TaxTotal(st.InvoiceCurrencyCode); ok {
+		d.taxTotal = t.Value
+	}
+	for _, pm := range st.SpecifiedTradeSettlementPaymentMeans {
+		d.paymentMeans = append(d.paymentMeans, pm.TypeCode)
+	}
+	for _, li := range tx.IncludedSupplyChainTradeLineItem {
+		d.lines = append(d.lines, einvoiceLine{
+			id: li.LineID, quantity: li.BilledQuantity.Value, unit: li.BilledQuantity.UnitCode,
+			net: li.LineTotal.Value, name: li.ProductName, price: li.NetPrice.Value,
+			category: li.Tax.CategoryCode, rate: li.Tax.RateApplicablePercent,
+		})
+	}
+	for _, ac := range st.SpecifiedTradeAllowanceCharge {
+		d.allowances = append(d.allowances, einvoiceAllowanceCharge{
+			charge: ac.IsCharge(), amount: ac.ActualAmount.Value,
+			category: ac.Tax.CategoryCode, rate: ac.Tax.RateApplicablePercent,
+		})
+	}
+	for _, t := range st.ApplicableTradeTax {
+		d.breakdown = append(d.breakdown, einvoiceVAT{
+			basis: t.BasisAmount.Value, amount: t.CalculatedAmount.Value,
+			category: t.CategoryCode, rate: t.RateApplicablePercent,
+			exemption: strings.TrimSpace(t.ExemptionReason + t.ExemptionReasonCode),
+		})
+	}
+	return d
+}
+
+// ublDoc maps a UBL Invoice or CreditNote to the neutral view.
+func ublDoc(inv *UBLInvoice) einvoiceDoc {
+	party := func(p UBLParty) einvoiceParty {
+		c := p.Contact
+		return einvoiceParty{
+			name: p.DisplayName(), vatID: p.VATID(), taxNumber: p.taxNumber(),
+			city: p.PostalAddress.CityName, postCode: p.PostalAddress.PostalZone,
+			country:     p.PostalAddress.CountryCode,
+			contactName: c.Name, phone: c.Telephone, email: c.ElectronicMail,
+			hasContact: c != UBLContact{},
+		}
+	}
+	sums := inv.LegalMonetaryTotal
+	taxTotal := inv.documentTaxTotal()
+	typeCode := inv.InvoiceTypeCode
+	if inv.IsCreditNote() {
+		typeCode = inv.CreditNoteTypeCode
+	}
+	d := einvoiceDoc{
+		syntax:         SyntaxUBL,
+		specID:         inv.CustomizationID,
+		number:         inv.ID,
+		issueDate:      inv.IssueDate,
+		typeCode:       typeCode,
+		currency:       inv.DocumentCurrencyCode,
+		buyerReference: inv.BuyerReference,
+		seller:         party(inv.AccountingSupplierParty),
+		buyer:          party(inv.AccountingCustomerParty),
+		lineTotal:      sums.LineExtensionAmount.Value,
+		allowanceTotal: sums.AllowanceTotalAmount.Value,
+		chargeTotal:    sums.ChargeTotalAmount.Value,
+		taxBasisTotal:  sums.TaxExclusiveAmount.Value,
+		taxTotal:       taxTotal.TaxAmount.Value,
+		grandTotal:     sums.TaxInclusiveAmount.Value,
+		prepaid:        sums.PrepaidAmount.Value,
+		rounding:       sums.PayableRoundingAmount.Value,
+		duePayable:     sums.PayableAmount.Value,
+	}
+	for _, pm := range inv.PaymentMeans {
+		d.paymentMeans = append(d.paymentMeans, pm.PaymentMeansCode)
+	}
+	for _, li := range append(inv.InvoiceLine, inv.CreditNoteLine...) {
+		qty := li.InvoicedQuantity
+		if strings.TrimSpace(qty.Value) == "" {
+			qty = li.CreditedQuantity
+		}
+		d.lines = append(d.lines, einvoiceLine{
+			id: li.ID, quantity: qty.Value, unit: qty.UnitCode,
+			net: li.LineExtensionAmount.Value, name: li.ItemName, price: li.PriceAmount.Value,
+			category: li.CategoryID, rate: li.Percent,
+		})
+	}
+	for _, ac := range inv.AllowanceCharge {
+		d.allowances = append(d.allowances, einvoiceAllowanceCharge{
+			charge:   strings.EqualFold(strings.TrimSpace(ac.ChargeIndicator), "true"),
+			amount:   ac.Amount.Value,
+			category: ac.CategoryID, rate: ac.Percent,
+		})
+	}
+	for _, t := range taxTotal.TaxSubtotal {
+		d.breakdown = append(d.breakdown, einvoiceVAT{
+			basis: t.TaxableAmount.Value, amount: t.TaxAmount.Value,
+			category: t.CategoryID, rate: t.Percent,
+			exemption: strings.TrimSpace(t.Exemption + t.ExemptionCode),
+		})
+	}
+	return d
+}
+
+// taxNumber returns the party's national tax number: a PartyTaxScheme whose
+// scheme is not VAT.
+func (p UBLParty) taxNumber() string {
+	for _, s := range p.PartyTaxScheme {
+		if !strings.EqualFold(strings.TrimSpace(s.TaxScheme), "VAT") {
+			return strings.TrimSpace(s.CompanyID)
+		}
+	}
+	return ""
+}
+
+// vatCategories are the UNTDID 5305 codes EN 16931 allows (BR-CL-18):
+// standard, zero, exempt, reverse charge, intra-community, export, not
+// subject, IGIC and IPSI.
+var vatCategories = map[string]string{
+	"S": "Normalsatz", "Z": "Nullsatz", "E": "steuerbefreit", "AE": "Reverse Charge",
+	"K": "innergemeinschaftliche Lieferung", "G": "Ausfuhr", "O": "nicht steuerbar",
+	"L": "IGIC", "M": "IPSI",
+}
+
+// validateEInvoiceDoc runs the rules on the neutral view.
+func validateEInvoiceDoc(d einvoiceDoc) EInvoiceValidation {
+	v := EInvoiceValidation{
+		Syntax:    d.syntax,
+		Profile:   strings.TrimSpace(d.specID),
+		XRechnung: strings.Contains(strings.ToLower(d.specID), "xrechnung"),
+	}
+	fail := func(rule, format string, args ...any) {
+		v.Findings = append(v.Findings, ValidationFinding{Rule: rule, Severity: SeverityFehler, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(rule, format string, args ...any) {
+		v.Findings = append(v.Findings, ValidationFinding{Rule: rule, Severity: SeverityWarnung, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Mandatory header fields (BR-01 … BR-15).
+	mandatory := []struct{ rule, value, label string }{
+		{"BR-01", d.specID, "Spezifikationskennung (BT-24) fehlt"},
+		{"BR-02", d.number, "Rechnungsnummer (BT-1) fehlt"},
+		{"BR-03", d.issueDate, "Rechnungsdatum (BT-2) fehlt"},
+		{"BR-04", d.typeCode, "Rechnungsart (BT-3) fehlt"},
+		{"BR-05", d.currency, "Währung (BT-5) fehlt"},
+		{"BR-06", d.seller.name, "Name des Verkäufers (BT-27) fehlt"},
+		{"BR-07", d.buyer.name, "Name des Käufers (BT-44) fehlt"},
+		{"BR-09", d.seller.country, "Ländercode des Verkäufers (BT-40) fehlt"},
+		{"BR-11", d.buyer.country, "Ländercode des Käufers (BT-55) fehlt"},
+		{"BR-12", d.lineTotal, "Summe der Positionen (BT-106) fehlt"},
+		{"BR-13", d.taxBasisTotal, "Gesamtbetrag ohne USt (BT-109) fehlt"},
+		{"BR-14", d.grandTotal, "Gesamtbetrag mit USt (BT-112) fehlt"},
+		{"BR-15", d.duePayable, "Zahlbetrag (BT-115) fehlt"},
+	}
+	for _, m := range mandatory {
+		if strings.TrimSpace(m.value) == "" {
+			fail(m.rule, "%s", m.label)
+		}
+	}
+	if len(d.lines) == 0 {
+		fail("BR-16", "Rechnung enthält keine Position")
+	}
+
+	// Line rules (BR-21 … BR-27, BR-CO-4).
+	for i, l := range d.lines {
+		pos := strings.TrimSpace(l.id)
+		if pos == "" {
+			pos = fmt.Sprintf("#%d", i+1)
+			fail("BR-21", "Position %s ohne Positionskennung (BT-126)", pos)
+		}
+		if strings.TrimSpace(l.quantity) == "" {
+			fail("BR-22", "Position %s ohne Menge (BT-129)", pos)
+		}
+		if strings.TrimSpace(l.unit) == "" {
+			fail("BR-23", "Position %s ohne Mengeneinheit (BT-130)", pos)
+		}
+		if strings.TrimSpace(l.net) == "" {
+			fail("BR-24", "Position %s ohne Nettobetrag (BT-131)", pos)
+		}
+		if strings.TrimSpace(l.name) == "" {
+			fail("BR-25", "Position %s ohne Artikelbezeichnung (BT-153)", pos)
+		}
+		if strings.TrimSpace(l.price) == "" {
+			fail("BR-26", "Position %s ohne Nettopreis (BT-146)", pos)
+		} else if parseXMLAmount(l.price) < 0 {
+			fail("BR-27", "Position %s: Nettopreis ist negativ (%s)", pos, pdfAmount(parseXMLAmount(l.price)))
+		}
+		if strings.TrimSpace(l.category) == "" {
+			fail("BR-CO-4", "Position %s ohne USt-Kategorie (BT-151)", pos)
+		}
+	}
+
+	// Sum rules (BR-CO-10 … BR-CO-16), only where both sides are present.
+	amt := func(s string) (float64, bool) {
+		if strings.TrimSpace(s) == "" {
+			return 0, false
+		}
+		return parseXMLAmount(s), true
+	}
+	check := func(rule, label string, stated string, want float64) {
+		if got, ok := amt(stated); ok && math.Abs(got-want) > 0.01 {
+			fail(rule, "%s %s ≠ berechnet %s", label, pdfAmount(got), pdfAmount(round2(want)))
+		}
+	}
+	var sumLines, sumAllow, sumCharge float64
+	for _, l := range d.lines {
+		sumLines += parseXMLAmount(l.net)
+	}
+	for _, ac := range d.allowances {
+		if ac.charge {
+			sumCharge += parseXMLAmount(ac.amount)
+		} else {
+			sumAllow += parseXMLAmount(ac.amount)
+		}
+	}
+	if len(d.lines) > 0 {
+		check("BR-CO-10", "Summe der Positionen (BT-106)", d.lineTotal, sumLines)
+	}
+	check("BR-CO-11", "Summe der Nachlässe (BT-107)", d.allowanceTotal, sumAllow)
+	check("BR-CO-12", "Summe der Zuschläge (BT-108)", d.chargeTotal, sumCharge)
+	allowTotal, ok := amt(d.allowanceTotal)
+	if !ok {
+		allowTotal = sumAllow
+	}
+	chargeTotal, ok := amt(d.chargeTotal)
+	if !ok {
+		chargeTotal = sumCharge
+	}
+	if lineTotal, ok := amt(d.lineTotal); ok {
+		check("BR-CO-13", "Gesamtbetrag ohne USt (BT-109)", d.taxBasisTotal, lineTotal-allowTotal+chargeTotal)
+	}
+	var sumVAT float64
+	for _, b := range d.breakdown {
+		sumVAT += parseXMLAmount(b.amount)
+	}
+	if len(d.breakdown) > 0 {
+		check("BR-CO-14", "USt-Gesamtbetrag (BT-110)", d.taxTotal, sumVAT)
+	}
+	if basis, ok := amt(d.taxBasisTotal); ok {
+		taxTotal, _ := amt(d.taxTotal)
+		check("BR-CO-15", "Gesamtbetrag mit USt (BT-112)", d.grandTotal, basis+taxTotal)
+	}
+	if grand, ok := amt(d.grandTotal); ok {
+		prepaid, _ := amt(d.prepaid)
+		rounding, _ := amt(d.rounding)
+		check("BR-CO-16", "Zahlbetrag (BT-115)", d.duePayable, grand-prepaid+rounding)
+	}
+
+	// VAT breakdown (BR-CO-17/18, BR-45 … BR-48) and category consistency.
+	if len(d.breakdown) == 0 {
+		fail("BR-CO-18", "Keine USt-Aufschlüsselung (BG-23) vorhanden")
+	}
+	type key struct {
+		cat  string
+		rate float64
+	}
+	keyOf := func(cat, rate string) key {
+		return key{strings.ToUpper(strings.TrimSpace(cat)), round2(parseXMLAmount(rate))}
+	}
+	basisByKey := map[key]float64{}
+	for _, l := range d.lines {
+		basisByKey[keyOf(l.category, l.rate)] += parseXMLAmount(l.net)
+	}
+	for _, ac := range d.allowances {
+		k := keyOf(ac.category, ac.rate)
+		if ac.charge {
+			basisByKey[k] += parseXMLAmount(ac.amount)
+		} else {
+			basisByKey[k] -= parseXMLAmount(ac.amount)
+		}
+	}
+	inBreakdown := map[key]bool{}
+	for _, b := range d.breakdown {
+		cat := strings.ToUpper(strings.TrimSpace(b.category))
+		k := keyOf(b.category, b.rate)
+		inBreakdown[k] = true
+		label := fmt.Sprintf("USt-Aufschlüsselung %s %s %%", cat, pdfAmount(k.rate))
+		if strings.TrimSpace(b.basis) == "" {
+			fail("BR-45", "%s ohne Bemessungsgrundlage (BT-116)", label)
+		}
+		if strings.TrimSpace(b.amount) == "" {
+			fail("BR-46", "%s ohne Steuerbetrag (BT-117)", label)
+		}
+		if cat == "" {
+			fail("BR-47", "USt-Aufschlüsselung ohne Kategorie (BT-118)")
+			continue
+		}
+		if _, ok := vatCategories[cat]; !ok {
+			fail("BR-CL-18", "Unbekannte USt-Kategorie %q", cat)
+		}
+		if strings.TrimSpace(b.rate) == "" && cat != "O" {
+			fail("BR-48", "%s ohne Steuersatz (BT-119)", label)
+		}
+		basis := parseXMLAmount(b.basis)
+		if strings.TrimSpace(b.basis) != "" && strings.TrimSpace(b.amount) != "" {
+			if want := round2(basis * k.rate / 100); math.Abs(parseXMLAmount(b.amount)-want) > 0.01 {
+				fail("BR-CO-17", "%s: Steuerbetrag %s ≠ Bemessungsgrundlage × Satz = %s",
+					label, pdfAmount(parseXMLAmount(b.amount)), pdfAmount(want))
+			}
+		}
+		if want, ok := basisByKey[k]; ok && strings.TrimSpace(b.basis) != "" && math.Abs(basis-want) > 0.01 {
+			fail("BR-"+cat+"-08", "%s: Bemessungsgrundlage %s ≠ Positionen − Nachlässe + Zuschläge = %s",
+				label, pdfAmount(basis), pdfAmount(round2(want)))
+		}
+		switch cat {
+		case "S":
+			if k.rate <= 0 {
+				fail("BR-S-05", "%s: Normalsatz muss größer als 0 sein", label)
+			}
+		case "Z", "E", "AE", "K", "G", "O":
+			if k.rate != 0 || parseXMLAmount(b.amount) != 0 {
+				fail("BR-"+cat+"-09", "%s: Kategorie %s verlangt Satz und Steuerbetrag 0", label, vatCategories[cat])
+			}
+		}
+		switch cat {
+		case "E", "AE", "K", "G", "O":
+			if b.exemption == "" {
+				fail("BR-"+cat+"-10", "%s: Befreiungsgrund (BT-120/121) fehlt", label)
+			}
+		}
+		switch cat {
+		case "AE", "K":
+			if d.seller.vatID == "" && d.seller.taxNumber == "" {
+				fail("BR-"+cat+"-02", "%s: USt-IdNr. des Verkäufers fehlt", label)
+			}
+			if d.buyer.vatID == "" {
+				fail("BR-"+cat+"-02", "%s: USt-IdNr. des Käufers fehlt", label)
+			}
+		case "S", "Z", "E", "G":
+			if d.seller.vatID == "" && d.seller.taxNumber == "" {
+				fail("BR-"+cat+"-02", "%s: USt-IdNr. oder Steuernummer des Verkäufers fehlt", label)
+			}
+		}
+	}
+	for k := range basisByKey {
+		if k.cat != "" && !inBreakdown[k] {
+			fail("BR-"+k.cat+"-01", "USt-Kategorie %s %s %% der Positionen fehlt in der USt-Aufschlüsselung", k.cat, pdfAmount(k.rate))
+		}
+	}
+
+	// XRechnung CIUS (BR-DE-*), only for documents declaring XRechnung.
+	if v.XRechnung {
+		if len(d.paymentMeans) == 0 {
+			fail("BR-DE-1", "Zahlungsanweisungen (BG-16) fehlen")
+		}
+		if !d.seller.hasContact {
+			fail("BR-DE-2", "Kontaktdaten des Verkäufers (BG-6) fehlen")
+		}
+		if strings.TrimSpace(d.seller.city) == "" {
+			fail("BR-DE-3", "Ort des Verkäufers (BT-37) fehlt")
+		}
+		if strings.TrimSpace(d.seller.postCode) == "" {
+			fail("BR-DE-4", "Postleitzahl des Verkäufers (BT-38) fehlt")
+		}
+		if d.seller.hasContact {
+			if strings.TrimSpace(d.seller.contactName) == "" {
+				fail("BR-DE-5", "Ansprechpartner des Verkäufers (BT-41) fehlt")
+			}
+			if strings.TrimSpace(d.seller.phone) == "" {
+				fail("BR-DE-6", "Telefonnummer des Verkäufers (BT-42) fehlt")
+			}
+			if strings.TrimSpace(d.seller.email) == "" {
+				fail("BR-DE-7", "E-Mail-Adresse des Verkäufers (BT-43) fehlt")
+			}
+		}
+		if strings.TrimSpace(d.buyer.city) == "" {
+			fail("BR-DE-8", "Ort des Käufers (BT-52) fehlt")
+		}
+		if strings.TrimSpace(d.buyer.postCode) == "" {
+			fail("BR-DE-9", "Postleitzahl des Käufers (BT-53) fehlt")
+		}
+		if strings.TrimSpace(d.buyerReference) == "" {
+			fail("BR-DE-15", "Käuferreferenz / Leitweg-ID (BT-10) fehlt")
+		}
+		if d.seller.vatID == "" && d.seller.taxNumber == "" {
+			fail("BR-DE-16", "Weder USt-IdNr. (BT-31) noch Steuernummer (BT-32) des Verkäufers angegeben")
+		}
+		switch strings.TrimSpace(d.typeCode) {
+		case "", "326", "380", "384", "389", "381", "875", "876", "877":
+		default:
+			warn("BR-DE-17", "Rechnungsart %s ist in XRechnung nicht vorgesehen", d.typeCode)
+		}
+	}
+	return v
+}
+
+// ValidationReportName returns the file name of the validation report for
+// an invoice file: "<base>_Pruefbericht.pdf".
+func ValidationReportName(invoiceName string) string {
+	return strings.TrimSuffix(invoiceName, filepath.Ext(invoiceName)) + "_Pruefbericht.pdf"
+}
+
+// BuildEInvoiceValidationPDF renders the validation result of one e-invoice
+// as a portrait PDF for the correspondence with the issuer (e.g. to reject
+// the invoice): the invoice identification, the checked profile, the
+// verdict and one row per violated rule. company is the recipient (own
+// company) shown in the sub-header.
+func BuildEInvoiceValidationPDF(meta Meta, v EInvoiceValidation, company string) ([]byte, error) {
+	pdf, tr := newReportPDF("Prüfbericht E-Rechnung", "P", company)
+
+	field := func(label, value string) {
+		if strings.TrimSpace(value) == "" {
+			return
+		}
+		pdf.SetFont("Arial", "B", 9)
+		pdf.CellFormat(45, 6, tr(label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 9)
+		pdf.CellFormat(0, 6, tr(truncate(value, 90)), "", 1, "L", false, 0, "")
+	}
+	field("Rechnungssteller", meta.Auftraggeber)
+	field("USt-IdNr.", meta.VATID)
+	field("Rechnungsnummer", meta.Rechnungsnummer)
+	field("Rechnungsdatum", meta.Rechnungsdatum)
+	if meta.Bruttobetrag != 0 {
+		field("Bruttobetrag", strings.TrimSpace(pdfAmount(meta.Bruttobetrag)+" "+meta.Waehrung))
+	}
+	field("Syntax", string(v.Syntax))
+	field("Spezifikation (BT-24)", v.Profile)
+	rules := "EN 16931"
+	if v.XRechnung {
+		rules += " und XRechnung (BR-DE)"
+	}
+	field("Geprüfte Regeln", rules)
+	pdf.Ln(3)
+
+	pdf.SetFont("Arial", "B", 11)
+	verdict := "Ergebnis: keine Regelverstöße festgestellt."
+	if !v.Valid() {
+		verdict = fmt.Sprintf("Ergebnis: Die Rechnung verletzt %d Pflichtregel(n) und entspricht nicht der Norm.", countSeverity(v.Findings, SeverityFehler))
+	} else if len(v.Findings) > 0 {
+		verdict = fmt.Sprintf("Ergebnis: gültig, %d Hinweis(e).", len(v.Findings))
+	}
+	pdf.MultiCell(0, 6, tr(verdict), "", "L", false)
+	pdf.Ln(2)
+
+	if len(v.Findings) > 0 {
+		headers := []string{"Regel", "Schwere", "Beschreibung"}
+		widths := []float64{25, 20, 145}
+		pdfTableHeader(pdf, tr, headers, widths)
+		for _, f := range v.Findings {
+			lines := pdf.SplitText(tr(f.Message), widths[2]-2)
+			h := 5 * float64(max(1, len(lines)))
+			pdfPageBreak(pdf, tr, headers, widths, h)
+			x, y := pdf.GetXY()
+			pdf.CellFormat(widths[0], h, tr(f.Rule), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(widths[1], h, tr(f.Severity), "1", 0, "L", false, 0, "")
+			pdf.MultiCell(widths[2], 5, tr(f.Message), "1", "L", false)
+			pdf.SetXY(x, y+h)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// countSeverity counts the findings of one severity.
+func countSeverity(findings []ValidationFinding, severity string) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
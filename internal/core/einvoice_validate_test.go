@@ -0,0 +1,233 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// xrechnungCIIXML is a complete XRechnung 3.0 invoice in CII syntax with a
+// 19 % and a 7 % line, an allowance and a charge.
+const xrechnungCIIXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+  xmlns:ram="urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+  xmlns:udt="urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100">
+  <rsm:ExchangedDocumentContext>
+    <ram:GuidelineSpecifiedDocumentContextParameter><ram:ID>urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0</ram:ID></ram:GuidelineSpecifiedDocumentContextParameter>
+  </rsm:ExchangedDocumentContext>
+  <rsm:ExchangedDocument>
+    <ram:ID>XR-2026-7</ram:ID>
+    <ram:TypeCode>380</ram:TypeCode>
+    <ram:IssueDateTime><udt:DateTimeString format="102">20260310</udt:DateTimeString></ram:IssueDateTime>
+  </rsm:ExchangedDocument>
+  <rsm:SupplyChainTradeTransaction>
+    <ram:IncludedSupplyChainTradeLineItem>
+      <ram:AssociatedDocumentLineDocument><ram:LineID>1</ram:LineID></ram:AssociatedDocumentLineDocument>
+      <ram:SpecifiedTradeProduct><ram:Name>Bürostuhl</ram:Name></ram:SpecifiedTradeProduct>
+      <ram:SpecifiedLineTradeAgreement><ram:NetPriceProductTradePrice><ram:ChargeAmount>200.00</ram:ChargeAmount></ram:NetPriceProductTradePrice></ram:SpecifiedLineTradeAgreement>
+      <ram:SpecifiedLineTradeDelivery><ram:BilledQuantity unitCode="H87">1</ram:BilledQuantity></ram:SpecifiedLineTradeDelivery>
+      <ram:SpecifiedLineTradeSettlement>
+        <ram:ApplicableTradeTax><ram:TypeCode>VAT</ram:TypeCode><ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>19</ram:RateApplicablePercent></ram:ApplicableTradeTax>
+        <ram:SpecifiedTradeSettlementLineMonetarySummation><ram:LineTotalAmount>200.00</ram:LineTotalAmount></ram:SpecifiedTradeSettlementLineMonetarySummation>
+      </ram:SpecifiedLineTradeSettlement>
+    </ram:IncludedSupplyChainTradeLineItem>
+    <ram:IncludedSupplyChainTradeLineItem>
+      <ram:AssociatedDocumentLineDocument><ram:LineID>2</ram:LineID></ram:AssociatedDocumentLineDocument>
+      <ram:SpecifiedTradeProduct><ram:Name>Fachzeitschrift</ram:Name></ram:SpecifiedTradeProduct>
+      <ram:SpecifiedLineTradeAgreement><ram:NetPriceProductTradePrice><ram:ChargeAmount>50.00</ram:ChargeAmount></ram:NetPriceProductTradePrice></ram:SpecifiedLineTradeAgreement>
+      <ram:SpecifiedLineTradeDelivery><ram:BilledQuantity unitCode="H87">2</ram:BilledQuantity></ram:SpecifiedLineTradeDelivery>
+      <ram:SpecifiedLineTradeSettlement>
+        <ram:ApplicableTradeTax><ram:TypeCode>VAT</ram:TypeCode><ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>7</ram:RateApplicablePercent></ram:ApplicableTradeTax>
+        <ram:SpecifiedTradeSettlementLineMonetarySummation><ram:LineTotalAmount>100.00</ram:LineTotalAmount></ram:SpecifiedTradeSettlementLineMonetarySummation>
+      </ram:SpecifiedLineTradeSettlement>
+    </ram:IncludedSupplyChainTradeLineItem>
+    <ram:ApplicableHeaderTradeAgreement>
+      <ram:BuyerReference>04011000-12345-34</ram:BuyerReference>
+      <ram:SellerTradeParty>
+        <ram:Name>Büro Mix GmbH</ram:Name>
+        <ram:DefinedTradeContact>
+          <ram:PersonName>Erika Muster</ram:PersonName>
+          <ram:TelephoneUniversalCommunication><ram:CompleteNumber>+49 30 123456</ram:CompleteNumber></ram:TelephoneUniversalCommunication>
+          <ram:EmailURIUniversalCommunication><ram:URIID>rechnung@bueromix.example</ram:URIID></ram:EmailURIUniversalCommunication>
+        </ram:DefinedTradeContact>
+        <ram:PostalTradeAddress><ram:PostcodeCode>10115</ram:PostcodeCode><ram:CityName>Berlin</ram:CityName><ram:CountryID>DE</ram:CountryID></ram:PostalTradeAddress>
+        <ram:SpecifiedTaxRegistration><ram:ID schemeID="VA">DE123456789</ram:ID></ram:SpecifiedTaxRegistration>
+      </ram:SellerTradeParty>
+      <ram:BuyerTradeParty>
+        <ram:Name>Kunde AG</ram:Name>
+        <ram:PostalTradeAddress><ram:PostcodeCode>80331</ram:PostcodeCode><ram:CityName>München</ram:CityName><ram:CountryID>DE</ram:CountryID></ram:PostalTradeAddress>
+      </ram:BuyerTradeParty>
+    </ram:ApplicableHeaderTradeAgreement>
+    <ram:ApplicableHeaderTradeSettlement>
+      <ram:InvoiceCurrencyCode>EUR</ram:InvoiceCurrencyCode>
+      <ram:SpecifiedTradeSettlementPaymentMeans><ram:TypeCode>58</ram:TypeCode></ram:SpecifiedTradeSettlementPaymentMeans>
+      <ram:ApplicableTradeTax>
+        <ram:CalculatedAmount>36.10</ram:CalculatedAmount>
+        <ram:TypeCode>VAT</ram:TypeCode>
+        <ram:BasisAmount>190.00</ram:BasisAmount>
+        <ram:CategoryCode>S</ram:CategoryCode>
+        <ram:RateApplicablePercent>19</ram:RateApplicablePercent>
+      </ram:ApplicableTradeTax>
+      <ram:ApplicableTradeTax>
+        <ram:CalculatedAmount>7.35</ram:CalculatedAmount>
+        <ram:TypeCode>VAT</ram:TypeCode>
+        <ram:BasisAmount>105.00</ram:BasisAmount>
+        <ram:CategoryCode>S</ram:CategoryCode>
+        <ram:RateApplicablePercent>7</ram:RateApplicablePercent>
+      </ram:ApplicableTradeTax>
+      <ram:SpecifiedTradeAllowanceCharge>
+        <ram:ChargeIndicator><udt:Indicator>false</udt:Indicator></ram:ChargeIndicator>
+        <ram:ActualAmount>10.00</ram:ActualAmount>
+        <ram:Reason>Treuerabatt</ram:Reason>
+        <ram:CategoryTradeTax><ram:TypeCode>VAT</ram:TypeCode><ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>19</ram:RateApplicablePercent></ram:CategoryTradeTax>
+      </ram:SpecifiedTradeAllowanceCharge>
+      <ram:SpecifiedTradeAllowanceCharge>
+        <ram:ChargeIndicator><udt:Indicator>true</udt:Indicator></ram:ChargeIndicator>
+        <ram:ActualAmount>5.00</ram:ActualAmount>
+        <ram:Reason>Versand</ram:Reason>
+        <ram:CategoryTradeTax><ram:TypeCode>VAT</ram:TypeCode><ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>7</ram:RateApplicablePercent></ram:CategoryTradeTax>
+      </ram:SpecifiedTradeAllowanceCharge>
+      <ram:SpecifiedTradeSettlementHeaderMonetarySummation>
+        <ram:LineTotalAmount>300.00</ram:LineTotalAmount>
+        <ram:ChargeTotalAmount>5.00</ram:ChargeTotalAmount>
+        <ram:AllowanceTotalAmount>10.00</ram:AllowanceTotalAmount>
+        <ram:TaxBasisTotalAmount>295.00</ram:TaxBasisTotalAmount>
+        <ram:TaxTotalAmount currencyID="EUR">43.45</ram:TaxTotalAmount>
+        <ram:GrandTotalAmount>338.45</ram:GrandTotalAmount>
+        <ram:DuePayableAmount>338.45</ram:DuePayableAmount>
+      </ram:SpecifiedTradeSettlementHeaderMonetarySummation>
+    </ram:ApplicableHeaderTradeSettlement>
+  </rsm:SupplyChainTradeTransaction>
+</rsm:CrossIndustryInvoice>`
+
+// ruleIDs returns the rule IDs of the findings.
+func ruleIDs(v EInvoiceValidation) []string {
+	var ids []string
+	for _, f := range v.Findings {
+		ids = append(ids, f.Rule)
+	}
+	return ids
+}
+
+func hasRule(v EInvoiceValidation, rule string) bool {
+	for _, f := range v.Findings {
+		if f.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateEInvoiceXML_Valid(t *testing.T) {
+	v, err := ValidateEInvoiceXML([]byte(xrechnungCIIXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.XRechnung || v.Syntax != SyntaxCII {
+		t.Errorf("profile = %+v", v)
+	}
+	if !v.Valid() || len(v.Findings) != 0 {
+		t.Errorf("valid XRechnung reported findings: %v", ruleIDs(v))
+	}
+}
+
+func TestValidateEInvoiceXML_SumRules(t *testing.T) {
+	// Grand total off by one euro, payable amount consistent with it.
+	broken := strings.Replace(xrechnungCIIXML, "<ram:GrandTotalAmount>338.45", "<ram:GrandTotalAmount>339.45", 1)
+	broken = strings.Replace(broken, "<ram:DuePayableAmount>338.45", "<ram:DuePayableAmount>339.45", 1)
+	v, err := ValidateEInvoiceXML([]byte(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Valid() || !hasRule(v, "BR-CO-15") || hasRule(v, "BR-CO-16") {
+		t.Errorf("findings = %v, want only BR-CO-15", ruleIDs(v))
+	}
+
+	// 7 % basis does not match its lines + charge; tax amount no longer fits.
+	broken = strings.Replace(xrechnungCIIXML, "<ram:BasisAmount>105.00", "<ram:BasisAmount>100.00", 1)
+	v, _ = ValidateEInvoiceXML([]byte(broken))
+	if !hasRule(v, "BR-S-08") || !hasRule(v, "BR-CO-17") {
+		t.Errorf("findings = %v, want BR-S-08 and BR-CO-17", ruleIDs(v))
+	}
+}
+
+func TestValidateEInvoiceXML_Mandatory(t *testing.T) {
+	// The plain CII test invoice lacks BT-24, buyer, countries and lines.
+	v, err := ValidateEInvoiceXML([]byte(ciiInvoiceXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range []string{"BR-01", "BR-07", "BR-09", "BR-11", "BR-16"} {
+		if !hasRule(v, rule) {
+			t.Errorf("missing %s in %v", rule, ruleIDs(v))
+		}
+	}
+	if v.XRechnung || hasRule(v, "BR-DE-15") {
+		t.Errorf("BR-DE rules must only apply to XRechnung: %v", ruleIDs(v))
+	}
+}
+
+func TestValidateEInvoiceXML_XRechnungCIUS(t *testing.T) {
+	broken := strings.Replace(xrechnungCIIXML, "<ram:BuyerReference>04011000-12345-34</ram:BuyerReference>", "", 1)
+	broken = strings.Replace(broken, "<ram:TypeCode>58</ram:TypeCode>", "", 1)
+	broken = strings.Replace(broken, "<ram:EmailURIUniversalCommunication><ram:URIID>rechnung@bueromix.example</ram:URIID></ram:EmailURIUniversalCommunication>", "", 1)
+	v, _ := ValidateEInvoiceXML([]byte(broken))
+	for _, rule := range []string{"BR-DE-15", "BR-DE-7"} {
+		if !hasRule(v, rule) {
+			t.Errorf("missing %s in %v", rule, ruleIDs(v))
+		}
+	}
+	// An empty TypeCode still counts as a BG-16 entry; removing the whole
+	// payment means triggers BR-DE-1.
+	broken = strings.Replace(xrechnungCIIXML, "<ram:SpecifiedTradeSettlementPaymentMeans><ram:TypeCode>58</ram:TypeCode></ram:SpecifiedTradeSettlementPaymentMeans>", "", 1)
+	v, _ = ValidateEInvoiceXML([]byte(broken))
+	if !hasRule(v, "BR-DE-1") {
+		t.Errorf("missing BR-DE-1 in %v", ruleIDs(v))
+	}
+}
+
+func TestValidateEInvoiceXML_VATCategories(t *testing.T) {
+	// Reverse charge (AE) at 19 % without exemption reason or buyer VAT-ID.
+	broken := strings.ReplaceAll(xrechnungCIIXML, "<ram:CategoryCode>S</ram:CategoryCode><ram:RateApplicablePercent>7", "<ram:CategoryCode>AE</ram:CategoryCode><ram:RateApplicablePercent>7")
+	broken = strings.Replace(broken, "<ram:CategoryCode>S</ram:CategoryCode>\n        <ram:RateApplicablePercent>7", "<ram:CategoryCode>AE</ram:CategoryCode>\n        <ram:RateApplicablePercent>7", 1)
+	v, _ := ValidateEInvoiceXML([]byte(broken))
+	for _, rule := range []string{"BR-AE-09", "BR-AE-10", "BR-AE-02"} {
+		if !hasRule(v, rule) {
+			t.Errorf("missing %s in %v", rule, ruleIDs(v))
+		}
+	}
+}
+
+func TestValidateEInvoiceXML_UBL(t *testing.T) {
+	// The UBL test invoice is consistent in its sums but lacks countries
+	// and the buyer reference.
+	v, err := ValidateEInvoiceXML([]byte(ublInvoiceXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.XRechnung || hasRule(v, "BR-CO-10") || hasRule(v, "BR-CO-15") || !hasRule(v, "BR-DE-15") || !hasRule(v, "BR-09") {
+		t.Errorf("findings = %v", ruleIDs(v))
+	}
+	if _, err := ValidateEInvoiceXML([]byte(`<Document/>`)); err == nil {
+		t.Error("expected error for non-invoice XML")
+	}
+}
+
+func TestEInvoiceValidationWarningsAndPDF(t *testing.T) {
+	v := EInvoiceValidation{Syntax: SyntaxUBL, XRechnung: true, Findings: []ValidationFinding{
+		{Rule: "BR-CO-15", Severity: SeverityFehler, Message: "Gesamtbetrag mit USt (BT-112) 339,45 ≠ berechnet 338,45"},
+		{Rule: "BR-DE-17", Severity: SeverityWarnung, Message: "Rechnungsart 71 ist in XRechnung nicht vorgesehen"},
+	}}
+	if w := v.Warnings(); len(w) != 2 || !strings.HasPrefix(w[0], "E-Rechnung BR-CO-15: ") {
+		t.Errorf("Warnings = %q", w)
+	}
+	data, err := BuildEInvoiceValidationPDF(Meta{Auftraggeber: "Büro Mix GmbH", Rechnungsnummer: "XR-2026-7"}, v, "Meine Firma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 100 || string(data[:4]) != "%PDF" {
+		t.Fatalf("not a PDF (%d bytes)", len(data))
+	}
+	if got := ValidationReportName("Rechnung 4711.pdf"); got != "Rechnung 4711_Pruefbericht.pdf" {
+		t.Errorf("ValidationReportName = %q", got)
+	}
+}
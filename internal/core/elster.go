@@ -0,0 +1,469 @@
+package core
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ELSTER export of the UStVA: the Anmeldungssteuern data part in the schema
+// version of the return's year, wrapped in the ElsterXML v11 envelope with
+// the Finanzamt as recipient. Encryption, signing and transmission are left
+// to the ERiC-based software the file is imported into.
+
+const (
+	// elsterUStVANamespace is the namespace of the UStVA data part; %d is the
+	// schema version, which is the year of the return.
+	elsterUStVANamespace = "http://finkonsens.de/elster/elsteranmeldung/ustva/v%d"
+	// ElsterTestHerstellerID is ERiC's public test manufacturer ID. It is
+	// only valid in a test case (ElsterTestmerker), which the Finanzamt does
+	// not process; a production file needs the HerstellerID the user's
+	// software registered with ELSTER (settings).
+	ElsterTestHerstellerID = "74931"
+	// ElsterTestmerker marks a TransferHeader as a test case.
+	ElsterTestmerker = "700000004"
+)
+
+var elsterHerstellerIDFormat = regexp.MustCompile(`^[0-9]{5}$`)
+
+// ElsterHerstellerIDProduktiv reports whether id can go into a production
+// file: five digits and not the ERiC test ID.
+func ElsterHerstellerIDProduktiv(id string) bool {
+	id = strings.TrimSpace(id)
+	return elsterHerstellerIDFormat.MatchString(id) && id != ElsterTestHerstellerID
+}
+
+// elsterUStVAVersionen are the years whose UStVA data part schema is known.
+// A new year needs its own entry once ELSTER publishes the schema: the
+// Kennzahlen below are those of the forms since 2021.
+var elsterUStVAVersionen = map[int]bool{2022: true, 2023: true, 2024: true, 2025: true, 2026: true}
+
+// elsterBasisKz are the Bemessungsgrundlagen, transmitted in whole euros
+// (cents dropped).
+var elsterBasisKz = map[string]bool{
+	"21": true, "35": true, "41": true, "42": true, "43": true, "44": true, "45": true,
+	"46": true, "48": true, "49": true, "50": true, "60": true, "73": true, "76": true,
+	"77": true, "81": true, "84": true, "86": true, "87": true, "89": true, "90": true,
+	"91": true, "93": true, "94": true, "95": true,
+}
+
+// elsterSteuerKz are the tax amounts, transmitted with two decimals.
+var elsterSteuerKz = map[string]bool{
+	"36": true, "39": true, "47": true, "59": true, "61": true, "62": true, "63": true,
+	"64": true, "65": true, "66": true, "67": true, "69": true, "74": true, "80": true,
+	"83": true, "85": true, "96": true, "98": true,
+}
+
+// elsterMerkerKz are the flags, transmitted as "1" when set (Kz 10:
+// berichtigte Anmeldung, Kz 22: Belege werden nachgereicht, Kz 26:
+// Verrechnungswunsch, Kz 29: SEPA-Lastschriftmandat widerrufen).
+var elsterMerkerKz = map[string]bool{"10": true, "22": true, "26": true, "29": true}
+
+// ElsterUStVAKopf is what the file needs besides the Kennzahlen.
+type ElsterUStVAKopf struct {
+	Jahr         int
+	Zeitraum     string      // "01".."12" or "41".."44" (ElsterZeitraum)
+	Firma        Firmendaten // Name/Strasse/PLZ/Ort: DatenLieferant; Steuernummer and Finanzamt: Steuerfall
+	Berichtigt   bool        // Kz 10: berichtigte Anmeldung
+	Erstellt     time.Time
+	HerstellerID string // manufacturer ID registered with ELSTER (settings)
+	Testfall     bool   // test case: Testmerker set, ElsterTestHerstellerID if HerstellerID is empty
+}
+
+// ElsterZeitraum is the ELSTER code of a Voranmeldung period: the month
+// "01".."12", or "41".."44" for a calendar quarter. Other ranges (the whole
+// year) are no Voranmeldung.
+func ElsterZeitraum(vonMonat, bisMonat int) (string, error) {
+	switch {
+	case vonMonat >= 1 && vonMonat <= 12 && bisMonat == vonMonat:
+		return fmt.Sprintf("%02d", vonMonat), nil
+	case vonMonat%3 == 1 && bisMonat == vonMonat+2 && bisMonat <= 12:
+		return fmt.Sprintf("4%d", (vonMonat+2)/3), nil
+	}
+	return "", fmt.Errorf("Zeitraum %d–%d ist kein Voranmeldungszeitraum (Monat oder Quartal)", vonMonat, bisMonat)
+}
+
+// ElsterSteuernummer converts a Steuernummer to the 13-digit federal format
+// ELSTER expects: the Bundesfinanzamtsnummer, a 0 and the last eight digits
+// of the regional number (Bezirk, Unterscheidungsnummer, Prüfziffer). The
+// Finanzamt part of the regional number must end the Finanzamtsnummer; a
+// number already in the federal format must start with it.
+func ElsterSteuernummer(steuernummer, finanzamt string) (string, error) {
+	fa := ziffern(finanzamt)
+	if len(fa) != 4 || len(fa) != len(strings.TrimSpace(finanzamt)) {
+		return "", fmt.Errorf("Finanzamtsnummer %q muss vierstellig sein", finanzamt)
+	}
+	d := ziffern(steuernummer)
+	switch {
+	case len(d) == 13:
+		if d[:4] != fa || d[4] != '0' {
+			return "", fmt.Errorf("Steuernummer %s gehört nicht zum Finanzamt %s", d, fa)
+		}
+		return d, nil
+	case len(d) == 10 || len(d) == 11:
+		if !strings.HasSuffix(fa, strings.TrimLeft(d[:len(d)-8], "0")) {
+			return "", fmt.Errorf("Steuernummer %s gehört nicht zum Finanzamt %s", strings.TrimSpace(steuernummer), fa)
+		}
+		return fa + "0" + d[len(d)-8:], nil
+	}
+	return "", fmt.Errorf("Steuernummer %q ist weder im Landes- noch im 13-stelligen ELSTER-Format", steuernummer)
+}
+
+// ziffern returns the digits of s.
+func ziffern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// ElsterUStVAFelder returns the Kennzahlen of the data part, keyed by
+// Kennzahl without the "Kz" prefix: the non-zero ones of u, bases in whole
+// euros, taxes with two decimals. The tax on Kz 81/86/89/93 is not
+// transmitted (ELSTER derives it from the base), so Kz 83 is recomputed
+// from the whole-euro bases to match ELSTER's own calculation.
+func ElsterUStVAFelder(u UStVAOfficial, berichtigt bool) map[string]string {
+	f := map[string]string{}
+	basis := func(kz string, v float64) float64 {
+		e := math.Trunc(round2(v))
+		if v != 0 {
+			f[kz] = strconv.FormatFloat(e, 'f', 0, 64)
+		}
+		return e
+	}
+	steuer := func(kz string, v float64) float64 {
+		v = round2(v)
+		if v != 0 {
+			f[kz] = elsterBetrag(v)
+		}
+		return v
+	}
+	b81 := basis("81", u.Kz81)
+	b86 := basis("86", u.Kz86)
+	basis("35", u.Kz35)
+	basis("41", u.Kz41)
+	basis("44", u.Kz44)
+	basis("43", u.Kz43)
+	b89 := basis("89", u.Kz89)
+	b93 := basis("93", u.Kz93)
+	basis("46", u.Kz46)
+	basis("84", u.Kz84)
+	basis("21", u.Kz21)
+	basis("45", u.Kz45)
+	st := b81*0.19 + b86*0.07 + b89*0.19 + b93*0.07 +
+		steuer("36", u.Kz36) + steuer("47", u.Kz47) + steuer("85", u.Kz85)
+	vst := steuer("66", u.Kz66) + steuer("61", u.Kz61) + steuer("62", u.Kz62) +
+		steuer("67", u.Kz67) + steuer("64", u.Kz64)
+	svz := steuer("39", u.Kz39)
+	f["83"] = elsterBetrag(round2(st - vst - svz))
+	if berichtigt {
+		f["10"] = "1"
+	}
+	return f
+}
+
+// elsterBetrag formats a tax amount: two decimals, decimal point.
+func elsterBetrag(v float64) string {
+	if v == 0 {
+		v = 0 // no "-0.00"
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// elsterFeld is one Kennzahl element of the data part.
+type elsterFeld struct {
+	XMLName xml.Name
+	Wert    string `xml:",chardata"`
+}
+
+type elsterUStVADoc struct {
+	XMLName        xml.Name `xml:"http://www.elster.de/elsterxml/schema/v11 Elster"`
+	TransferHeader struct {
+		Version        string `xml:"version,attr"`
+		Verfahren      string `xml:"Verfahren"`
+		DatenArt       string `xml:"DatenArt"`
+		Vorgang        string `xml:"Vorgang"`
+		Testmerker     string `xml:"Testmerker,omitempty"`
+		HerstellerID   string `xml:"HerstellerID"`
+		DatenLieferant string `xml:"DatenLieferant"`
+		Datei          struct {
+			Verschluesselung    string `xml:"Verschluesselung"`
+			Kompression         string `xml:"Kompression"`
+			TransportSchluessel string `xml:"TransportSchluessel"`
+		} `xml:"Datei"`
+	} `xml:"TransferHeader"`
+	DatenTeil struct {
+		Nutzdatenblock struct {
+			NutzdatenHeader struct {
+				Version         string `xml:"version,attr"`
+				NutzdatenTicket string `xml:"NutzdatenTicket"`
+				Empfaenger      struct {
+					ID   string `xml:"id,attr"`
+					Wert string `xml:",chardata"`
+				} `xml:"Empfaenger"`
+			} `xml:"NutzdatenHeader"`
+			Nutzdaten struct {
+				Anmeldungssteuern elsterAnmeldungssteuern `xml:"Anmeldungssteuern"`
+			} `xml:"Nutzdaten"`
+		} `xml:"Nutzdatenblock"`
+	} `xml:"DatenTeil"`
+}
+
+type elsterAnmeldungssteuern struct {
+	XMLName        xml.Name
+	Art            string `xml:"art,attr"`
+	Version        string `xml:"version,attr"`
+	DatenLieferant struct {
+		Name    string `xml:"Name"`
+		Strasse string `xml:"Strasse,omitempty"`
+		PLZ     string `xml:"PLZ"`
+		Ort     string `xml:"Ort"`
+	} `xml:"DatenLieferant"`
+	Erstellungsdatum string `xml:"Erstellungsdatum"`
+	Steuerfall       struct {
+		Umsatzsteuervoranmeldung struct {
+			Jahr         string       `xml:"Jahr"`
+			Zeitraum     string       `xml:"Zeitraum"`
+			Steuernummer string       `xml:"Steuernummer"`
+			Felder       []elsterFeld `xml:",any"`
+		} `xml:"Umsatzsteuervoranmeldung"`
+	} `xml:"Steuerfall"`
+}
+
+// BuildUStVAElster renders the UStVA as ElsterXML with the data part in the
+// schema version of k.Jahr. The Steuernummer is converted to the federal
+// format (ElsterSteuernummer). A production file needs k.HerstellerID; a
+// test case (k.Testfall) gets the Testmerker and, without an ID, the ERiC
+// test ID. The result is checked with ValidateUStVAElster; any Fehler is
+// returned as error, so a production file with the test ID is refused.
+func BuildUStVAElster(u UStVAOfficial, k ElsterUStVAKopf) ([]byte, error) {
+	if !elsterUStVAVersionen[k.Jahr] {
+		return nil, fmt.Errorf("kein ELSTER-Schema für die UStVA %d", k.Jahr)
+	}
+	stnr, err := ElsterSteuernummer(k.Firma.Steuernummer, k.Firma.Finanzamt)
+	if err != nil {
+		return nil, err
+	}
+	var d elsterUStVADoc
+	th := &d.TransferHeader
+	th.Version = "11"
+	th.Verfahren = "ElsterAnmeldung"
+	th.DatenArt = "UStVA"
+	th.Vorgang = "send-Auth"
+	th.HerstellerID = strings.TrimSpace(k.HerstellerID)
+	if k.Testfall {
+		th.Testmerker = ElsterTestmerker
+		if th.HerstellerID == "" {
+			th.HerstellerID = ElsterTestHerstellerID
+		}
+	}
+	th.DatenLieferant = k.Firma.Name
+	// The file is plain XML: Verschluesselung and Kompression stay empty
+	// until ERiC or the ELSTER software encrypts it for transmission.
+	nb := &d.DatenTeil.Nutzdatenblock
+	nb.NutzdatenHeader.Version = "11"
+	nb.NutzdatenHeader.NutzdatenTicket = "1"
+	nb.NutzdatenHeader.Empfaenger.ID = "F"
+	nb.NutzdatenHeader.Empfaenger.Wert = stnr[:4]
+	an := &nb.Nutzdaten.Anmeldungssteuern
+	an.XMLName = xml.Name{Space: fmt.Sprintf(elsterUStVANamespace, k.Jahr), Local: "Anmeldungssteuern"}
+	an.Art = "UStVA"
+	an.Version = strconv.Itoa(k.Jahr)
+	an.DatenLieferant.Name = k.Firma.Name
+	an.DatenLieferant.Strasse = k.Firma.Strasse
+	an.DatenLieferant.PLZ = k.Firma.PLZ
+	an.DatenLieferant.Ort = k.Firma.Ort
+	an.Erstellungsdatum = k.Erstellt.Format("20060102")
+	va := &an.Steuerfall.Umsatzsteuervoranmeldung
+	va.Jahr = strconv.Itoa(k.Jahr)
+	va.Zeitraum = k.Zeitraum
+	va.Steuernummer = stnr
+	felder := ElsterUStVAFelder(u, k.Berichtigt)
+	for _, kz := range sortedKz(felder) {
+		va.Felder = append(va.Felder, elsterFeld{XMLName: xml.Name{Local: "Kz" + kz}, Wert: felder[kz]})
+	}
+
+	out, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	data := append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"), out...)
+	if err := findingsError(ValidateUStVAElster(data)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// sortedKz returns the Kennzahlen of f in ascending numeric order, the order
+// of the schema.
+func sortedKz(f map[string]string) []string {
+	kz := make([]string, 0, len(f))
+	for k := range f {
+		kz = append(kz, k)
+	}
+	sort.Slice(kz, func(i, j int) bool {
+		a, _ := strconv.Atoi(kz[i])
+		b, _ := strconv.Atoi(kz[j])
+		return a < b
+	})
+	return kz
+}
+
+var (
+	elsterGanzzahl = regexp.MustCompile(`^-?[0-9]{1,12}$`)
+	elsterDezimal  = regexp.MustCompile(`^-?[0-9]{1,12}\.[0-9]{2}$`)
+	elsterZeitraum = regexp.MustCompile(`^(0[1-9]|1[0-2]|4[1-4])$`)
+)
+
+// ValidateUStVAElster checks an ElsterXML UStVA against the rules below,
+// hand-written after the field rules of the data part schema and ELSTER's
+// plausibility checks. It is no XSD validation — the ELSTER schemas are not
+// bundled — and no substitute for the check ERiC runs on transmission:
+//
+//	UStVA-01  ElsterXML v11 envelope of an ElsterAnmeldung of DatenArt UStVA; HerstellerID of
+//	          five digits, the ERiC test ID only in a test case (Testmerker)
+//	UStVA-02  Anmeldungssteuern art="UStVA" in a known schema version, namespace of that version
+//	UStVA-03  Jahr equals the schema version
+//	UStVA-04  Zeitraum 01–12 or 41–44
+//	UStVA-05  Steuernummer in the 13-digit federal format
+//	UStVA-06  Empfänger is the Finanzamt (id "F") the Steuernummer belongs to
+//	UStVA-07  DatenLieferant with Name, PLZ and Ort; Erstellungsdatum YYYYMMDD
+//	UStVA-08  only Kennzahlen of the form, each at most once, in ascending order
+//	UStVA-09  field format: bases whole euros, taxes two decimals, flags "1"
+//	UStVA-10  bases and taxes in pairs: Kz 35/36, 46/47, 84/85
+//	UStVA-11  Kz 39 (Sondervorauszahlung) only in the December return
+//	UStVA-12  Kz 83 present and equal to the tax computed from the fields
+func ValidateUStVAElster(data []byte) []ValidationFinding {
+	var out []ValidationFinding
+	fehler := func(rule, format string, args ...interface{}) {
+		out = append(out, ValidationFinding{Rule: rule, Severity: SeverityFehler, Message: fmt.Sprintf(format, args...)})
+	}
+	var d elsterUStVADoc
+	if err := xml.Unmarshal(data, &d); err != nil {
+		fehler("UStVA-01", "kein ElsterXML-Dokument: %v", err)
+		return out
+	}
+	th := d.TransferHeader
+	if th.Version != "11" || th.Verfahren != "ElsterAnmeldung" || th.DatenArt != "UStVA" {
+		fehler("UStVA-01", "TransferHeader %q/%q/%q ist keine UStVA-Anmeldung (ElsterXML v11)", th.Version, th.Verfahren, th.DatenArt)
+	}
+	switch {
+	case th.Testmerker != "" && th.Testmerker != ElsterTestmerker:
+		fehler("UStVA-01", "Testmerker %q ist unbekannt (Testfall: %s)", th.Testmerker, ElsterTestmerker)
+	case !elsterHerstellerIDFormat.MatchString(th.HerstellerID):
+		fehler("UStVA-01", "HerstellerID %q ist nicht fünfstellig (Einstellungen → ELSTER-HerstellerID)", th.HerstellerID)
+	case th.HerstellerID == ElsterTestHerstellerID && th.Testmerker == "":
+		fehler("UStVA-01", "HerstellerID %s ist die Test-ID von ERiC und nur in einem Testfall zulässig", th.HerstellerID)
+	}
+	nb := d.DatenTeil.Nutzdatenblock
+	an := nb.Nutzdaten.Anmeldungssteuern
+	jahr, _ := strconv.Atoi(an.Version)
+	switch {
+	case an.Art != "UStVA":
+		fehler("UStVA-02", "Anmeldungssteuern art=%q statt UStVA", an.Art)
+	case !elsterUStVAVersionen[jahr]:
+		fehler("UStVA-02", "unbekannte Schemaversion %q", an.Version)
+	case an.XMLName.Space != fmt.Sprintf(elsterUStVANamespace, jahr):
+		fehler("UStVA-02", "Namensraum %q passt nicht zur Version %s", an.XMLName.Space, an.Version)
+	}
+	va := an.Steuerfall.Umsatzsteuervoranmeldung
+	if va.Jahr != an.Version {
+		fehler("UStVA-03", "Jahr %q weicht von der Schemaversion %q ab", va.Jahr, an.Version)
+	}
+	if !elsterZeitraum.MatchString(va.Zeitraum) {
+		fehler("UStVA-04", "Zeitraum %q ist weder Monat (01–12) noch Quartal (41–44)", va.Zeitraum)
+	}
+	if len(va.Steuernummer) != 13 || ziffern(va.Steuernummer) != va.Steuernummer || va.Steuernummer[4] != '0' {
+		fehler("UStVA-05", "Steuernummer %q ist nicht im 13-stelligen ELSTER-Format", va.Steuernummer)
+	}
+	emp := nb.NutzdatenHeader.Empfaenger
+	if emp.ID != "F" || len(emp.Wert) != 4 || ziffern(emp.Wert) != emp.Wert || !strings.HasPrefix(va.Steuernummer, emp.Wert) {
+		fehler("UStVA-06", "Empfänger %s %q ist nicht das Finanzamt der Steuernummer %s", emp.ID, emp.Wert, va.Steuernummer)
+	}
+	dl := an.DatenLieferant
+	if strings.TrimSpace(dl.Name) == "" || strings.TrimSpace(dl.PLZ) == "" || strings.TrimSpace(dl.Ort) == "" {
+		fehler("UStVA-07", "Datenlieferant braucht Name, PLZ und Ort (Firmendaten)")
+	}
+	if _, err := time.Parse("20060102", an.Erstellungsdatum); err != nil {
+		fehler("UStVA-07", "Erstellungsdatum %q ist kein Datum JJJJMMTT", an.Erstellungsdatum)
+	}
+
+	werte := map[string]float64{}
+	letzte := 0
+	for _, f := range va.Felder {
+		kz := strings.TrimPrefix(f.XMLName.Local, "Kz")
+		nr, err := strconv.Atoi(kz)
+		if !strings.HasPrefix(f.XMLName.Local, "Kz") || err != nil ||
+			!(elsterBasisKz[kz] || elsterSteuerKz[kz] || elsterMerkerKz[kz]) {
+			fehler("UStVA-08", "Element %s ist keine Kennzahl der UStVA", f.XMLName.Local)
+			continue
+		}
+		if _, dup := werte[kz]; dup {
+			fehler("UStVA-08", "Kz %s ist mehrfach angegeben", kz)
+		} else if nr < letzte {
+			fehler("UStVA-08", "Kz %s steht nicht in aufsteigender Reihenfolge", kz)
+		}
+		letzte = nr
+		switch {
+		case elsterBasisKz[kz] && !elsterGanzzahl.MatchString(f.Wert):
+			fehler("UStVA-09", "Kz %s = %q: Bemessungsgrundlage in vollen Euro erwartet", kz, f.Wert)
+		case elsterSteuerKz[kz] && !elsterDezimal.MatchString(f.Wert):
+			fehler("UStVA-09", "Kz %s = %q: Betrag mit zwei Nachkommastellen erwartet", kz, f.Wert)
+		case elsterMerkerKz[kz] && f.Wert != "1":
+			fehler("UStVA-09", "Kz %s = %q: Kennzeichen erwartet den Wert 1", kz, f.Wert)
+		}
+		werte[kz], _ = strconv.ParseFloat(f.Wert, 64)
+	}
+	for _, p := range [][2]string{{"35", "36"}, {"46", "47"}, {"84", "85"}} {
+		_, basis := werte[p[0]]
+		_, steuer := werte[p[1]]
+		if basis != steuer {
+			fehler("UStVA-10", "Kz %s und Kz %s sind nur gemeinsam zulässig", p[0], p[1])
+		}
+	}
+	if _, ok := werte["39"]; ok && va.Zeitraum != "12" {
+		fehler("UStVA-11", "Kz 39 (Sondervorauszahlung) ist nur in der Dezember-Anmeldung zulässig, nicht im Zeitraum %s", va.Zeitraum)
+	}
+	kz83, ok := werte["83"]
+	var soll float64
+	for kz, satz := range map[string]float64{"81": 0.19, "86": 0.07, "89": 0.19, "93": 0.07} {
+		soll += werte[kz] * satz
+	}
+	for _, kz := range []string{"36", "47", "65", "69", "74", "80", "85", "96", "98"} {
+		soll += werte[kz]
+	}
+	for _, kz := range []string{"39", "59", "61", "62", "63", "64", "66", "67"} {
+		soll -= werte[kz]
+	}
+	soll = round2(soll)
+	switch {
+	case !ok:
+		fehler("UStVA-12", "Kz 83 fehlt")
+	case math.Abs(kz83-soll) > 0.005:
+		fehler("UStVA-12", "Kz 83 = %.2f, aus den Kennzahlen berechnet %.2f", kz83, soll)
+	}
+	return out
+}
+
+// findingsError joins the Fehler among findings into one error; nil when
+// there is none.
+func findingsError(findings []ValidationFinding) error {
+	var msgs []string
+	for _, f := range findings {
+		if f.Severity == SeverityFehler {
+			msgs = append(msgs, f.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
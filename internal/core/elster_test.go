@@ -0,0 +1,165 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func elsterKopf() ElsterUStVAKopf {
+	return ElsterUStVAKopf{
+		Jahr:         2025,
+		Zeitraum:     "05",
+		Firma:        Firmendaten{Name: "Muster GmbH", Strasse: "Hauptstr. 1", PLZ: "80331", Ort: "München", Steuernummer: "143/123/45678", Finanzamt: "9143"},
+		Erstellt:     time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC),
+		HerstellerID: "40123",
+	}
+}
+
+func TestElsterSteuernummer(t *testing.T) {
+	cases := []struct{ stnr, fa, want string }{
+		{"143/123/45678", "9143", "9143012345678"}, // Bayern FFF/BBB/UUUUP
+		{"93815/08152", "2893", "2893081508152"},   // Baden-Württemberg FFBBB/UUUUP
+		{"013 815 08153", "2613", "2613081508153"}, // Hessen 0FF BBB UUUUP
+		{"5133081508159", "5133", "5133081508159"}, // already federal
+	}
+	for _, c := range cases {
+		got, err := ElsterSteuernummer(c.stnr, c.fa)
+		if err != nil || got != c.want {
+			t.Errorf("ElsterSteuernummer(%q, %q) = %q, %v; want %q", c.stnr, c.fa, got, err, c.want)
+		}
+	}
+	for _, c := range [][2]string{{"143/123/45678", "9144"}, {"143/123/45678", "914"}, {"12345", "9143"}, {"9144012345678", "9143"}} {
+		if got, err := ElsterSteuernummer(c[0], c[1]); err == nil {
+			t.Errorf("ElsterSteuernummer(%q, %q) = %q, want error", c[0], c[1], got)
+		}
+	}
+}
+
+func TestElsterZeitraum(t *testing.T) {
+	for _, c := range []struct {
+		von, bis int
+		want     string
+	}{{5, 5, "05"}, {12, 12, "12"}, {1, 3, "41"}, {10, 12, "44"}} {
+		if got, err := ElsterZeitraum(c.von, c.bis); err != nil || got != c.want {
+			t.Errorf("ElsterZeitraum(%d, %d) = %q, %v; want %q", c.von, c.bis, got, err, c.want)
+		}
+	}
+	if _, err := ElsterZeitraum(1, 12); err == nil {
+		t.Error("whole year accepted as Voranmeldung")
+	}
+}
+
+func TestBuildUStVAElster(t *testing.T) {
+	u := UStVAOfficial{Kz81: 1000.99, Kz86: 200.50, Kz46: 500, Kz47: 95, Kz66: 19.37, Kz67: 95}
+	data, err := BuildUStVAElster(u, elsterKopf())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	for _, want := range []string{
+		`<Elster xmlns="http://www.elster.de/elsterxml/schema/v11">`,
+		`<DatenArt>UStVA</DatenArt>`, `<HerstellerID>40123</HerstellerID>`,
+		`<Empfaenger id="F">9143</Empfaenger>`,
+		`<Anmeldungssteuern xmlns="http://finkonsens.de/elster/elsteranmeldung/ustva/v2025" art="UStVA" version="2025">`,
+		`<Erstellungsdatum>20250603</Erstellungsdatum>`,
+		`<Jahr>2025</Jahr>`, `<Zeitraum>05</Zeitraum>`, `<Steuernummer>9143012345678</Steuernummer>`,
+		// Bases in whole euros (cents dropped), taxes with two decimals.
+		`<Kz46>500</Kz46>`, `<Kz47>95.00</Kz47>`, `<Kz66>19.37</Kz66>`, `<Kz81>1000</Kz81>`, `<Kz86>200</Kz86>`,
+		// 1000 × 19 % + 200 × 7 % + 95 − 19.37 − 95 = 184.63
+		`<Kz83>184.63</Kz83>`,
+		// Plain XML: nothing claims an encryption or compression.
+		`<Verschluesselung></Verschluesselung>`, `<Kompression></Kompression>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("ELSTER XML missing %q:\n%s", want, s)
+		}
+	}
+	if strings.Index(s, "<Kz46>") > strings.Index(s, "<Kz81>") || strings.Index(s, "<Kz81>") > strings.Index(s, "<Kz83>") {
+		t.Error("Kennzahlen not in ascending order")
+	}
+	if strings.Contains(s, "<Kz10>") {
+		t.Error("Kz10 set on a first return")
+	}
+	if strings.Contains(s, "<Testmerker>") {
+		t.Error("production file carries a Testmerker")
+	}
+
+	// The ERiC test ID only goes into a test case.
+	k := elsterKopf()
+	k.HerstellerID = ElsterTestHerstellerID
+	if _, err := BuildUStVAElster(u, k); err == nil || !strings.Contains(err.Error(), "UStVA-01") {
+		t.Errorf("production file with the test ID: %v", err)
+	}
+	k.HerstellerID = ""
+	if _, err := BuildUStVAElster(u, k); err == nil {
+		t.Error("production file without HerstellerID accepted")
+	}
+	k.Testfall = true
+	if data, err := BuildUStVAElster(u, k); err != nil ||
+		!strings.Contains(string(data), "<Testmerker>700000004</Testmerker>") ||
+		!strings.Contains(string(data), "<HerstellerID>74931</HerstellerID>") {
+		t.Errorf("test case: %v\n%s", err, data)
+	}
+
+	k = elsterKopf()
+	k.Berichtigt = true
+	if data, err := BuildUStVAElster(u, k); err != nil || !strings.Contains(string(data), "<Kz10>1</Kz10>") {
+		t.Errorf("berichtigte Anmeldung: %v", err)
+	}
+	k = elsterKopf()
+	k.Jahr = 2019
+	if _, err := BuildUStVAElster(u, k); err == nil {
+		t.Error("unknown schema year accepted")
+	}
+	// The Sondervorauszahlung belongs to the December return only.
+	if _, err := BuildUStVAElster(u.MitSondervorauszahlung(300), elsterKopf()); err == nil || !strings.Contains(err.Error(), "UStVA-11") {
+		t.Errorf("Kz39 in May: %v", err)
+	}
+}
+
+func TestElsterHerstellerIDProduktiv(t *testing.T) {
+	for id, want := range map[string]bool{"40123": true, " 40123 ": true, "74931": false, "": false, "4012": false, "4012a": false} {
+		if got := ElsterHerstellerIDProduktiv(id); got != want {
+			t.Errorf("ElsterHerstellerIDProduktiv(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestValidateUStVAElster(t *testing.T) {
+	data, err := BuildUStVAElster(UStVAOfficial{Kz81: 1000, Kz66: 50}, elsterKopf())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	rules := func(xml string) string {
+		var r []string
+		for _, f := range ValidateUStVAElster([]byte(xml)) {
+			r = append(r, f.Rule)
+		}
+		return strings.Join(r, ",")
+	}
+	if got := rules(s); got != "" {
+		t.Fatalf("generated file has findings: %s", got)
+	}
+	for _, c := range []struct{ from, to, rule string }{
+		{"<Kz81>1000</Kz81>", "<Kz81>1000.00</Kz81>", "UStVA-09"},
+		{"<Kz83>140.00</Kz83>", "<Kz83>150.00</Kz83>", "UStVA-12"},
+		{"<Kz66>50.00</Kz66>", "<Kz66>50.00</Kz66><Kz99>1</Kz99>", "UStVA-08"},
+		{"<Zeitraum>05</Zeitraum>", "<Zeitraum>13</Zeitraum>", "UStVA-04"},
+		{"<Steuernummer>9143012345678</Steuernummer>", "<Steuernummer>143/123/45678</Steuernummer>", "UStVA-05"},
+		{`<Empfaenger id="F">9143</Empfaenger>`, `<Empfaenger id="F">9144</Empfaenger>`, "UStVA-06"},
+		{"<Jahr>2025</Jahr>", "<Jahr>2024</Jahr>", "UStVA-03"},
+		{`version="2025"`, `version="2019"`, "UStVA-02"},
+		{"<HerstellerID>40123</HerstellerID>", "<HerstellerID>74931</HerstellerID>", "UStVA-01"},
+		{"<HerstellerID>40123</HerstellerID>", "<HerstellerID>4012</HerstellerID>", "UStVA-01"},
+		{"<Kz81>1000</Kz81>", "<Kz81>1000</Kz81><Kz84>100</Kz84>", "UStVA-10"},
+	} {
+		if !strings.Contains(s, c.from) {
+			t.Fatalf("fixture lacks %q", c.from)
+		}
+		if got := rules(strings.Replace(s, c.from, c.to, 1)); !strings.Contains(got, c.rule) {
+			t.Errorf("%s → %s: findings %q, want %s", c.from, c.to, got, c.rule)
+		}
+	}
+}
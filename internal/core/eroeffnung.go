@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SaldenvortragKonto is the DATEV Saldenvortrag account (9000 in SKR03 and
+// SKR04) that the EB-Werte post against.
+const SaldenvortragKonto = 9000
+
+// OpeningBalance is the Eröffnungsbilanzwert (EB-Wert) of an account for a
+// fiscal year: its balance on 1 January, positive = Soll, negative = Haben.
+// Vortrag marks values carried forward from the previous year; manual
+// values replace them.
+type OpeningBalance struct {
+	Jahr       string
+	Konto      int
+	Saldo      float64
+	Vortrag    bool
+	Exportiert bool
+}
+
+// CarriesForward reports whether konto is a balance-sheet account whose
+// closing balance becomes next year's EB-Wert: fixed and current assets
+// (bank, cash, Forderungen, Vorsteuer), Rückstellungen and Verbindlichkeiten
+// (including the VAT accounts). Income and expense accounts close into the
+// result; equity and private accounts (SKR04 class 2, SKR03 0800–0949 and
+// 1800–1999) are settled with it at year end and are not carried either.
+// variant is "SKR03" or "SKR04" ("" = SKR04).
+func CarriesForward(konto int, variant string) bool {
+	if variant == "SKR03" {
+		return (konto > 0 && konto < 800) || (konto >= 950 && konto < 1800)
+	}
+	return (konto > 0 && konto < 2000) || (konto >= 3000 && konto < 4000)
+}
+
+// ComputeCarryForward returns the EB-Werte of jahr from the previous year's
+// trial balance (ComputeSuSa including that year's own EB-Werte): the closing
+// Saldo of every account that CarriesForward, zero balances left out.
+func ComputeCarryForward(prevSuSa []AccountBalance, variant, jahr string) []OpeningBalance {
+	var out []OpeningBalance
+	for _, b := range prevSuSa {
+		if !CarriesForward(b.Konto, variant) || math.Abs(b.Saldo) < 0.005 {
+			continue
+		}
+		out = append(out, OpeningBalance{Jahr: jahr, Konto: b.Konto, Saldo: round2(b.Saldo), Vortrag: true})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Konto < out[j].Konto })
+	return out
+}
+
+// OpeningBalanceFor returns the EB-Wert of konto among obs.
+func OpeningBalanceFor(obs []OpeningBalance, konto int) (float64, bool) {
+	for _, o := range obs {
+		if o.Konto == konto {
+			return o.Saldo, true
+		}
+	}
+	return 0, false
+}
+
+// OpeningRows turns EB-Werte into booking rows dated 1 January (Belegnummer
+// "EB-YYYY"), one per account against SaldenvortragKonto, so ComputeSuSa and
+// the exporters pick them up. ComputeSuSa shows them as EB-Wert, not as
+// movement of the year.
+func OpeningRows(obs []OpeningBalance) []CSVRow {
+	out := make([]CSVRow, 0, len(obs))
+	for _, o := range obs {
+		if math.Abs(o.Saldo) < 0.005 {
+			continue
+		}
+		betrag := round2(math.Abs(o.Saldo))
+		soll := o.Saldo > 0
+		out = append(out, CSVRow{
+			Belegnummer:      "EB-" + o.Jahr,
+			Rechnungsdatum:   "01.01." + o.Jahr,
+			Jahr:             o.Jahr,
+			Monat:            "01",
+			Verwendungszweck: fmt.Sprintf("EB-Wert %d", o.Konto),
+			Waehrung:         "EUR",
+			Buchung: Booking{Info: "Eröffnungsbilanzwert", Entries: []BookingEntry{
+				{Konto: o.Konto, Betrag: betrag, Soll: soll},
+				{Konto: SaldenvortragKonto, Betrag: betrag, Soll: !soll},
+			}},
+			Exportiert: o.Exportiert,
+			Eroeffnung: true,
+		})
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCarriesForward(t *testing.T) {
+	cases := []struct {
+		konto   int
+		variant string
+		want    bool
+	}{
+		{1800, "SKR04", true},  // Bank
+		{1600, "SKR04", true},  // Kasse
+		{1200, "SKR04", true},  // Forderungen
+		{1406, "SKR04", true},  // Vorsteuer
+		{3300, "SKR04", true},  // Verbindlichkeiten
+		{3806, "SKR04", true},  // Umsatzsteuer
+		{2100, "SKR04", false}, // Privatentnahmen
+		{4400, "SKR04", false}, // Erlöse
+		{6815, "SKR04", false}, // Bürobedarf
+		{1200, "SKR03", true},  // Bank
+		{1400, "SKR03", true},  // Forderungen
+		{1576, "SKR03", true},  // Vorsteuer
+		{1776, "SKR03", true},  // Umsatzsteuer
+		{1800, "SKR03", false}, // Privatentnahmen
+		{880, "SKR03", false},  // Eigenkapital
+		{8400, "SKR03", false}, // Erlöse
+	}
+	for _, c := range cases {
+		if got := CarriesForward(c.konto, c.variant); got != c.want {
+			t.Errorf("CarriesForward(%d, %s) = %v", c.konto, c.variant, got)
+		}
+	}
+}
+
+func TestComputeCarryForward(t *testing.T) {
+	prev := []AccountBalance{
+		{Konto: 4400, Saldo: -10000},
+		{Konto: 1800, Saldo: 2500.004},
+		{Konto: 3300, Saldo: -1000},
+		{Konto: 1200, Saldo: 0.001},
+		{Konto: 2100, Saldo: 500},
+	}
+	got := ComputeCarryForward(prev, "SKR04", "2027")
+	if len(got) != 2 || got[0].Konto != 1800 || got[0].Saldo != 2500 || !got[0].Vortrag ||
+		got[0].Jahr != "2027" || got[1].Konto != 3300 || got[1].Saldo != -1000 {
+		t.Fatalf("ComputeCarryForward = %+v", got)
+	}
+	if s, ok := OpeningBalanceFor(got, 3300); !ok || s != -1000 {
+		t.Errorf("OpeningBalanceFor = %v, %v", s, ok)
+	}
+}
+
+func TestOpeningRows_SuSaAndDATEV(t *testing.T) {
+	eb := OpeningRows([]OpeningBalance{
+		{Jahr: "2026", Konto: 1800, Saldo: 2500},
+		{Jahr: "2026", Konto: 3300, Saldo: -1000},
+		{Jahr: "2026", Konto: 1200, Saldo: 0},
+	})
+	if len(eb) != 2 || !eb[0].Eroeffnung || eb[0].Belegnummer != "EB-2026" || eb[0].Monat != "01" {
+		t.Fatalf("OpeningRows = %+v", eb)
+	}
+	// A bank fee in the year moves 1800 on top of its EB-Wert.
+	rows := append(eb, UmbuchungRows([]Umbuchung{{
+		Belegnummer: "U-2026-0001",
+		Datum:       "31.01.2026",
+		Text:        "Kontoführung",
+		Buchung: Booking{Entries: []BookingEntry{
+			{Konto: 6855, Betrag: 10, Soll: true},
+			{Konto: 1800, Betrag: 10, Soll: false},
+		}},
+	}})...)
+
+	bal := map[int]AccountBalance{}
+	for _, b := range ComputeSuSa(rows, nil) {
+		bal[b.Konto] = b
+	}
+	if b := bal[1800]; b.EBWert != 2500 || b.SollSumme != 0 || b.HabenSumme != 10 || b.Saldo != 2490 {
+		t.Errorf("1800 = %+v", b)
+	}
+	if b := bal[3300]; b.EBWert != -1000 || b.Saldo != -1000 {
+		t.Errorf("3300 = %+v", b)
+	}
+	if b := bal[SaldenvortragKonto]; b.EBWert != -1500 || b.Saldo != -1500 {
+		t.Errorf("9000 = %+v", b)
+	}
+
+	data, exported, skipped := BuildDATEVStapel(DATEVHeader{}, eb)
+	if exported != 2 || skipped != 0 {
+		t.Fatalf("exported=%d skipped=%d", exported, skipped)
+	}
+	for _, want := range []string{
+		`2500,00;"S";"EUR";;;;1800;9000;;0101;"EB-2026"`,
+		`1000,00;"H";"EUR";;;;3300;9000;;0101;"EB-2026"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("DATEV missing %s:\n%s", want, data)
+		}
+	}
+}
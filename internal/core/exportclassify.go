@@ -20,13 +20,17 @@ type ExportClassification struct {
 func ClassifyForExport(rows []CSVRow, includeExported bool) ExportClassification {
 	var c ExportClassification
 	for _, r := range rows {
-		_, _, ok := r.Buchung.PaymentAndCounters(r.Ausgangsrechnung)
+		_, _, ok := r.ExportSplit()
 		if !r.Buchung.Balanced() || !ok {
 			grund := "nicht ausgeglichen"
 			if len(r.Buchung.Entries) == 0 {
 				grund = "keine Buchung"
 			}
-			c.Skipped = append(c.Skipped, ExportSkip{Dateiname: r.Dateiname, Grund: grund})
+			name := r.Dateiname
+			if r.Umbuchung {
+				name = r.Belegnummer
+			}
+			c.Skipped = append(c.Skipped, ExportSkip{Dateiname: name, Grund: grund})
 			continue
 		}
 		if r.Exportiert {
@@ -40,3 +44,28 @@ func ClassifyForExport(rows []CSVRow, includeExported bool) ExportClassification
 	}
 	return c
 }
+
+// ExportSplit splits the row's booking into the base entry and the counter
+// entries the exporters write against it (see Booking.PaymentAndCounters).
+// A receipt's base is fixed by its direction; an Umbuchung may have its
+// single account on either side; an EB-Wert posts against
+// SaldenvortragKonto.
+func (r CSVRow) ExportSplit() (BookingEntry, []BookingEntry, bool) {
+	if r.Eroeffnung {
+		var base BookingEntry
+		var counters []BookingEntry
+		for _, e := range r.Buchung.Entries {
+			if e.Konto == SaldenvortragKonto && base.Konto == 0 {
+				base = e
+			} else {
+				counters = append(counters, e)
+			}
+		}
+		return base, counters, base.Konto != 0 && len(counters) > 0
+	}
+	base, counters, ok := r.Buchung.PaymentAndCounters(r.Ausgangsrechnung)
+	if !ok && r.Umbuchung {
+		return r.Buchung.PaymentAndCounters(!r.Ausgangsrechnung)
+	}
+	return base, counters, ok
+}
@@ -20,9 +20,10 @@ type BelegFile struct {
 //   - "belege/<sanitized>.pdf"   — one entry per BelegFile
 //   - "manifest.csv"             — semicolon-separated: Belegnummer;Dateiname;Auftraggeber;Rechnungsdatum;Bruttobetrag;Gegenkonto
 //   - "index.xml"                — GoBD-orientiert (nicht DTD-zertifiziert): DataSet/Media/Table describing manifest.csv + DATEV file
+//   - AuditAnkerDatei            — the head of the audit log's hash chain at export time (only when anker != nil)
 //
 // It uses only stdlib (archive/zip, encoding/xml).
-func BuildExportPackage(rows []CSVRow, datevCSV []byte, belege []BelegFile, period string) ([]byte, error) {
+func BuildExportPackage(rows []CSVRow, datevCSV []byte, belege []BelegFile, period string, anker *AuditAnker) ([]byte, error) {
 	buf := new(bytes.Buffer)
 	w := zip.NewWriter(buf)
 
@@ -55,6 +56,13 @@ func BuildExportPackage(rows []CSVRow, datevCSV []byte, belege []BelegFile, peri
 		return nil, fmt.Errorf("exportpackage: writing index.xml: %w", err)
 	}
 
+	// 5. Kettenkopf of the audit log, kept outside the database
+	if anker != nil {
+		if err := addZipEntry(w, AuditAnkerDatei, []byte(anker.Format())); err != nil {
+			return nil, fmt.Errorf("exportpackage: writing %s: %w", AuditAnkerDatei, err)
+		}
+	}
+
 	if err := w.Close(); err != nil {
 		return nil, fmt.Errorf("exportpackage: closing zip: %w", err)
 	}
@@ -63,7 +63,8 @@ func TestBuildExportPackage(t *testing.T) {
 		},
 	}
 
-	zipBytes, err := BuildExportPackage(rows, datevCSV, belege, period)
+	anker := &AuditAnker{ID: 7, TS: "2026-02-01 09:30:00", Hash: strings.Repeat("ab", 32)}
+	zipBytes, err := BuildExportPackage(rows, datevCSV, belege, period, anker)
 	if err != nil {
 		t.Fatalf("BuildExportPackage returned error: %v", err)
 	}
@@ -145,4 +146,9 @@ func TestBuildExportPackage(t *testing.T) {
 	if belegeFound != len(belege) {
 		t.Errorf("expected %d belege/ entries, got %d; entries: %v", len(belege), belegeFound, entryNames)
 	}
+
+	// 5. the audit chain anchor must read back from the zip
+	if got, err := ReadAuditAnker(zipBytes); err != nil || got != *anker {
+		t.Errorf("ReadAuditAnker = %+v, %v, want %+v", got, err, *anker)
+	}
 }
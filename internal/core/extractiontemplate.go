@@ -0,0 +1,466 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Confidence of a value read through a learned template anchor. An anchor
+// confirmed by at least two saved receipts is trusted; a fresh one is still
+// highlighted for review.
+const (
+	ConfidenceTemplate          = 0.7
+	ConfidenceTemplateConfirmed = 0.85
+)
+
+// templateFields are the fields located by position in the text layer.
+// Auftraggeber and VAT-ID are constant per supplier and stored on the
+// template itself.
+var templateFields = []string{
+	FieldRechnungsnummer, FieldRechnungsdatum, FieldBezahldatum,
+	FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag,
+}
+
+// TextAnchor records where a field's value sat in the text layer: the words
+// in front of it on the same line (Label), or — when the value starts its
+// line — the words of the previous line (Vorzeile).
+type TextAnchor struct {
+	Feld     string `json:"feld"`
+	Label    string `json:"label"`
+	Vorzeile bool   `json:"vorzeile,omitempty"`
+	Nth      int    `json:"nth,omitempty"` // 0-based occurrence among lines carrying Label
+	Treffer  int    `json:"treffer"`       // saved receipts that confirmed this anchor
+}
+
+// ExtractionTemplate is the learned layout of one supplier's receipts.
+type ExtractionTemplate struct {
+	Auftraggeber string       `json:"auftraggeber"`
+	VATID        string       `json:"vat_id,omitempty"`
+	Anker        []TextAnchor `json:"anker"`
+	Aktualisiert string       `json:"aktualisiert,omitempty"` // YYYY-MM-DD
+}
+
+// anchor returns the anchor for field.
+func (t ExtractionTemplate) anchor(field string) (TextAnchor, bool) {
+	for _, a := range t.Anker {
+		if a.Feld == field {
+			return a, true
+		}
+	}
+	return TextAnchor{}, false
+}
+
+// ExtractionTemplateKey is the store key of a supplier: the compacted VAT-ID
+// when known, otherwise the normalised company name.
+func ExtractionTemplateKey(auftraggeber, vatID string) string {
+	if v := compactAlnum(vatID); len(v) >= 4 {
+		return v
+	}
+	return NormalizeCompanyName(auftraggeber)
+}
+
+// ExtractionTemplateStore persists supplier→ExtractionTemplate per profile.
+type ExtractionTemplateStore struct {
+	path      string
+	templates map[string]ExtractionTemplate
+}
+
+// NewExtractionTemplateStore creates a store rooted at configDir.
+func NewExtractionTemplateStore(configDir string) *ExtractionTemplateStore {
+	return &ExtractionTemplateStore{
+		path:      filepath.Join(configDir, "extraction_templates.json"),
+		templates: map[string]ExtractionTemplate{},
+	}
+}
+
+// Load reads the persisted templates (a missing file is not an error).
+func (s *ExtractionTemplateStore) Load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil // no file yet
+	}
+	m := map[string]ExtractionTemplate{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("failed to parse extraction templates: %w", err)
+	}
+	s.templates = m
+	return nil
+}
+
+// Get returns the template stored under key (see ExtractionTemplateKey).
+func (s *ExtractionTemplateStore) Get(key string) (ExtractionTemplate, bool) {
+	t, ok := s.templates[key]
+	return t, ok
+}
+
+// Set remembers and persists a template under key.
+func (s *ExtractionTemplateStore) Set(key string, t ExtractionTemplate) error {
+	s.templates[key] = t
+	data, err := json.MarshalIndent(s.templates, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save extraction templates: %w", err)
+	}
+	return nil
+}
+
+// Learn updates the supplier's template from a saved receipt: m holds the
+// final (corrected) values, text the receipt's text layer. It reports
+// whether a template was stored.
+func (s *ExtractionTemplateStore) Learn(text string, m Meta) (bool, error) {
+	key := ExtractionTemplateKey(m.Auftraggeber, m.VATID)
+	if key == "" {
+		return false, nil
+	}
+	var prev *ExtractionTemplate
+	if t, ok := s.templates[key]; ok {
+		prev = &t
+	}
+	t, ok := LearnExtractionTemplate(prev, text, m)
+	if !ok {
+		return false, nil
+	}
+	return true, s.Set(key, t)
+}
+
+// Match finds the template of the supplier that issued text: a template
+// whose VAT-ID occurs in the text wins, otherwise the one with the longest
+// normalised company name found in it. The bool reports a VAT-ID match.
+func (s *ExtractionTemplateStore) Match(text string) (t ExtractionTemplate, byVATID, ok bool) {
+	pt := newProvenanceText(text)
+	if pt.lower == "" {
+		return ExtractionTemplate{}, false, false
+	}
+	keys := make([]string, 0, len(s.templates))
+	for k := range s.templates {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	best := ""
+	for _, k := range keys {
+		tpl := s.templates[k]
+		if v := compactAlnum(tpl.VATID); len(v) >= 4 && strings.Contains(pt.compact, v) {
+			return tpl, true, true
+		}
+		name := NormalizeCompanyName(tpl.Auftraggeber)
+		if len([]rune(name)) >= 4 && strings.Contains(pt.lower, name) && len(name) > len(best) {
+			best, t, ok = name, tpl, true
+		}
+	}
+	return t, false, ok
+}
+
+// LearnExtractionTemplate derives a template from a saved receipt. Each
+// template field whose value is found in the text layer gets an anchor; an
+// anchor equal to the one in prev counts one more Treffer. Anchors of fields
+// not found this time are kept from prev. Returns false when the receipt has
+// no supplier name or no field could be anchored.
+func LearnExtractionTemplate(prev *ExtractionTemplate, text string, m Meta) (ExtractionTemplate, bool) {
+	if strings.TrimSpace(m.Auftraggeber) == "" {
+		return ExtractionTemplate{}, false
+	}
+	t := ExtractionTemplate{
+		Auftraggeber: strings.TrimSpace(m.Auftraggeber),
+		VATID:        strings.TrimSpace(m.VATID),
+		Aktualisiert: time.Now().Format("2006-01-02"),
+	}
+	if t.VATID != "" && !strings.Contains(compactAlnum(text), compactAlnum(t.VATID)) {
+		t.VATID = "" // not printed on the receipt — useless for matching
+	}
+	lines := templateLines(text)
+	for _, f := range templateFields {
+		v := provenanceValue(m, f)
+		a, found := locateAnchor(lines, f, v)
+		if !found {
+			if prev != nil {
+				if old, ok := prev.anchor(f); ok {
+					t.Anker = append(t.Anker, old)
+				}
+			}
+			continue
+		}
+		a.Treffer = 1
+		if prev != nil {
+			if old, ok := prev.anchor(f); ok && old.Label == a.Label && old.Vorzeile == a.Vorzeile && old.Nth == a.Nth {
+				a.Treffer = old.Treffer + 1
+			}
+		}
+		t.Anker = append(t.Anker, a)
+	}
+	if len(t.Anker) == 0 {
+		return ExtractionTemplate{}, false
+	}
+	return t, true
+}
+
+// ApplyExtractionTemplate reads the anchored fields of text and overlays them
+// on fallback (the generic heuristics' result). Fields the template cannot
+// read keep the fallback value and provenance; read fields are marked
+// SourceTemplate. byVATID tells whether the template was matched through
+// its VAT-ID, which makes the supplier name certain.
+func ApplyExtractionTemplate(t ExtractionTemplate, byVATID bool, text string, fallback Meta) Meta {
+	m := fallback
+	m.Provenance = Provenance{}
+	for f, p := range fallback.Provenance {
+		m.Provenance[f] = p
+	}
+	set := func(field string, conf float64) {
+		m.Provenance[field] = FieldProvenance{Source: SourceTemplate, Confidence: conf}
+	}
+
+	nameConf := ConfidenceTemplateConfirmed
+	if byVATID {
+		nameConf = ConfidenceTextLayer
+	}
+	m.Auftraggeber = t.Auftraggeber
+	set(FieldAuftraggeber, nameConf)
+	if byVATID {
+		m.VATID = t.VATID
+		set(FieldVATID, ConfidenceTextLayer)
+	}
+
+	lines := templateLines(text)
+	amounts := false
+	for _, a := range t.Anker {
+		raw, ok := anchoredText(lines, a)
+		if !ok {
+			continue
+		}
+		conf := ConfidenceTemplate
+		if a.Treffer >= 2 {
+			conf = ConfidenceTemplateConfirmed
+		}
+		switch a.Feld {
+		case FieldRechnungsnummer:
+			if v := templateInvoiceNumber(raw); v != "" {
+				m.Rechnungsnummer = v
+				set(a.Feld, conf)
+			}
+		case FieldRechnungsdatum, FieldBezahldatum:
+			v := (&LocalExtractor{}).extractDate(raw)
+			if v == "" {
+				continue
+			}
+			if a.Feld == FieldRechnungsdatum {
+				m.Rechnungsdatum = v
+				if parts := strings.Split(v, "."); len(parts) == 3 {
+					m.Jahr, m.Monat = parts[2], parts[1]
+				}
+			} else {
+				m.Bezahldatum = v
+			}
+			set(a.Feld, conf)
+		case FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag:
+			s := templateAmountPattern.FindString(raw)
+			if s == "" {
+				continue
+			}
+			v := math.Abs(parseAmount(s))
+			switch a.Feld {
+			case FieldBetragNetto:
+				m.BetragNetto = v
+			case FieldSteuersatzBetrag:
+				m.SteuersatzBetrag = v
+			default:
+				m.Bruttobetrag = v
+			}
+			set(a.Feld, conf)
+			amounts = true
+		}
+	}
+	if amounts {
+		if m.BetragNetto > 0 && m.SteuersatzBetrag > 0 {
+			m.SteuersatzProzent = math.Round(m.SteuersatzBetrag / m.BetragNetto * 100)
+		} else if m.SteuersatzBetrag == 0 {
+			m.SteuersatzProzent = 0
+		}
+		m.TaxLines = ReconstructTaxLines(m.BetragNetto, m.SteuersatzProzent, m.SteuersatzBetrag, m.Bruttobetrag)
+	}
+	capInconsistentAmounts(&m)
+	return m
+}
+
+// templateAmountPattern matches an amount with optional thousands grouping,
+// e.g. "1.234,56", "1,234.56", "119,00" or "42".
+var templateAmountPattern = regexp.MustCompile(`\d{1,3}(?:[.,']\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?`)
+
+// templateLine is one non-empty line of the text layer with whitespace
+// collapsed; lower is used for matching, orig keeps the case of values.
+type templateLine struct {
+	orig, lower string
+}
+
+func templateLines(text string) []templateLine {
+	var out []templateLine
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.Join(strings.Fields(l), " ")
+		if l == "" {
+			continue
+		}
+		tl := templateLine{orig: l, lower: strings.ToLower(l)}
+		if len(tl.lower) != len(tl.orig) {
+			tl.orig = tl.lower // offsets must line up
+		}
+		out = append(out, tl)
+	}
+	return out
+}
+
+// anchorLabel returns up to four trailing words of s, stopping at a word
+// with three or more digits (customer numbers, amounts and dates change from
+// receipt to receipt and would make a useless anchor).
+func anchorLabel(s string) string {
+	words := strings.Fields(s)
+	start := len(words)
+	for start > 0 && len(words)-start < 4 {
+		digits := 0
+		for _, r := range words[start-1] {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits >= 3 {
+			break
+		}
+		start--
+	}
+	label := strings.Join(words[start:], " ")
+	if strings.IndexFunc(label, unicode.IsLetter) < 0 {
+		return "" // only punctuation and short numbers
+	}
+	return label
+}
+
+// valueIndex returns the byte offset of value (of field) in line, or -1.
+func valueIndex(line, field, value string) int {
+	var spellings []string
+	switch field {
+	case FieldRechnungsdatum, FieldBezahldatum:
+		d, err := time.Parse("02.01.2006", value)
+		if err != nil {
+			return -1
+		}
+		spellings = dateSpellings(d)
+	case FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag:
+		var v float64
+		if _, err := fmt.Sscanf(value, "%f", &v); err != nil {
+			return -1
+		}
+		for _, s := range amountSpellings(math.Abs(v)) {
+			if i := numberIndex(line, s); i >= 0 {
+				return i
+			}
+		}
+		return -1
+	default:
+		spellings = []string{strings.ToLower(strings.Join(strings.Fields(value), " "))}
+	}
+	for _, s := range spellings {
+		if i := strings.Index(line, s); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// numberIndex is the position-returning variant of containsNumber.
+func numberIndex(text, s string) int {
+	for from := 0; ; {
+		i := strings.Index(text[from:], s)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		end := i + len(s)
+		if (i == 0 || !isASCIIDigit(text[i-1])) && (end == len(text) || !isASCIIDigit(text[end])) {
+			return i
+		}
+		from = i + 1
+	}
+}
+
+// locateAnchor finds value in lines and describes its position. Amounts take
+// the last usable occurrence (totals follow the line items), everything else
+// the first.
+func locateAnchor(lines []templateLine, field, value string) (TextAnchor, bool) {
+	if value == "" {
+		return TextAnchor{}, false
+	}
+	var found []TextAnchor
+	for i, l := range lines {
+		at := valueIndex(l.lower, field, value)
+		if at < 0 {
+			continue
+		}
+		labelLine := i
+		a := TextAnchor{Feld: field, Label: anchorLabel(l.lower[:at])}
+		if a.Label == "" && at == 0 && i > 0 {
+			labelLine = i - 1
+			a = TextAnchor{Feld: field, Label: anchorLabel(lines[i-1].lower), Vorzeile: true}
+		}
+		if a.Label == "" {
+			continue
+		}
+		for j := 0; j < labelLine; j++ {
+			if strings.Contains(lines[j].lower, a.Label) {
+				a.Nth++
+			}
+		}
+		found = append(found, a)
+	}
+	if len(found) == 0 {
+		return TextAnchor{}, false
+	}
+	switch field {
+	case FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag:
+		return found[len(found)-1], true
+	}
+	return found[0], true
+}
+
+// anchoredText returns the text where a's value is expected: the rest of
+// the line after the label, or the following line for a Vorzeile anchor.
+func anchoredText(lines []templateLine, a TextAnchor) (string, bool) {
+	n := 0
+	for i, l := range lines {
+		at := strings.Index(l.lower, a.Label)
+		if at < 0 {
+			continue
+		}
+		if n < a.Nth {
+			n++
+			continue
+		}
+		if a.Vorzeile {
+			if i+1 < len(lines) {
+				return lines[i+1].orig, true
+			}
+			return "", false
+		}
+		return strings.TrimSpace(l.orig[at+len(a.Label):]), true
+	}
+	return "", false
+}
+
+// templateInvoiceNumber takes the first word of s that contains a digit,
+// stripped of surrounding punctuation.
+func templateInvoiceNumber(s string) string {
+	for _, w := range strings.Fields(s) {
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if strings.IndexFunc(w, unicode.IsDigit) >= 0 {
+			return w
+		}
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Two receipts of the same supplier with a layout the generic heuristics
+// mis-read: the total sits under its label, the net amount is labelled
+// "Zwischensumme" and the invoice number "Beleg".
+const (
+	tplReceiptJan = `Druckerei Sauer GmbH
+Hauptstr. 5, 80331 München
+USt-IdNr.: DE 811 222 333
+Kunde 40711
+Beleg RS-1042
+Ausgestellt am 14.01.2026
+Visitenkarten 500 Stk 100,00
+Zwischensumme 100,00
+zzgl. 19 % USt 19,00
+Zu zahlen
+119,00 EUR`
+
+	tplReceiptFeb = `Druckerei Sauer GmbH
+Hauptstr. 5, 80331 München
+USt-IdNr.: DE 811 222 333
+Kunde 40711
+Beleg RS-1107
+Ausgestellt am 03.02.2026
+Flyer A5 2.000 Stk 1.050,00
+Briefpapier 200,00
+Zwischensumme 1.250,00
+zzgl. 19 % USt 237,50
+Zu zahlen
+1.487,50 EUR`
+)
+
+func corrected(nr, datum string, netto, mwst, brutto float64) Meta {
+	return Meta{
+		Auftraggeber: "Druckerei Sauer GmbH", VATID: "DE811222333",
+		Rechnungsnummer: nr, Rechnungsdatum: datum,
+		BetragNetto: netto, SteuersatzBetrag: mwst, Bruttobetrag: brutto,
+	}
+}
+
+func TestLearnAndApplyExtractionTemplate(t *testing.T) {
+	s := NewExtractionTemplateStore(t.TempDir())
+	learned, err := s.Learn(tplReceiptJan, corrected("RS-1042", "14.01.2026", 100, 19, 119))
+	if err != nil || !learned {
+		t.Fatalf("Learn = %v, %v", learned, err)
+	}
+
+	tpl, byVATID, ok := s.Match(tplReceiptFeb)
+	if !ok || !byVATID {
+		t.Fatalf("Match = %v, byVATID %v", ok, byVATID)
+	}
+	if a, _ := tpl.anchor(FieldBruttobetrag); !a.Vorzeile || a.Label != "zu zahlen" {
+		t.Errorf("gross anchor = %+v, want value on the line after \"zu zahlen\"", a)
+	}
+	if a, _ := tpl.anchor(FieldSteuersatzBetrag); a.Label != "zzgl. 19 % ust" {
+		t.Errorf("vat anchor = %+v", a)
+	}
+
+	fallback, _, _ := NewLocalExtractor().Extract(tplReceiptFeb)
+	m := ApplyExtractionTemplate(tpl, byVATID, tplReceiptFeb, fallback)
+	if m.Auftraggeber != "Druckerei Sauer GmbH" || m.VATID != "DE811222333" {
+		t.Errorf("supplier = %q / %q", m.Auftraggeber, m.VATID)
+	}
+	if m.Rechnungsnummer != "RS-1107" || m.Rechnungsdatum != "03.02.2026" || m.Monat != "02" || m.Jahr != "2026" {
+		t.Errorf("nr/date = %q %q %s/%s", m.Rechnungsnummer, m.Rechnungsdatum, m.Monat, m.Jahr)
+	}
+	if m.BetragNetto != 1250 || m.SteuersatzBetrag != 237.5 || m.Bruttobetrag != 1487.5 || m.SteuersatzProzent != 19 {
+		t.Errorf("amounts = %.2f + %.2f (%.0f %%) = %.2f", m.BetragNetto, m.SteuersatzBetrag, m.SteuersatzProzent, m.Bruttobetrag)
+	}
+	if len(m.TaxLines) != 1 || m.TaxLines[0].Netto != 1250 {
+		t.Errorf("tax lines = %+v", m.TaxLines)
+	}
+	if p := m.Provenance[FieldBruttobetrag]; p.Source != SourceTemplate || !p.Low() {
+		t.Errorf("a once-seen anchor must still be reviewed: %+v", p)
+	}
+	if p := m.Provenance[FieldAuftraggeber]; p.Source != SourceTemplate || p.Low() {
+		t.Errorf("supplier matched by VAT-ID is certain: %+v", p)
+	}
+	if p := m.Provenance[FieldBezahldatum]; p.Source == SourceTemplate {
+		t.Errorf("no anchor for Bezahldatum, got %+v", p)
+	}
+
+	// Saving the second receipt confirms the anchors.
+	if _, err := s.Learn(tplReceiptFeb, corrected("RS-1107", "03.02.2026", 1250, 237.5, 1487.5)); err != nil {
+		t.Fatal(err)
+	}
+	s2 := NewExtractionTemplateStore(filepath.Dir(s.path))
+	if err := s2.Load(); err != nil {
+		t.Fatal(err)
+	}
+	tpl, _ = s2.Get("DE811222333")
+	if a, _ := tpl.anchor(FieldBruttobetrag); a.Treffer != 2 {
+		t.Errorf("Treffer = %d, want 2", a.Treffer)
+	}
+	m = ApplyExtractionTemplate(tpl, true, tplReceiptFeb, fallback)
+	if p := m.Provenance[FieldBruttobetrag]; p.Low() {
+		t.Errorf("confirmed anchor still low: %+v", p)
+	}
+	if uf := m.UncertainFields(); len(uf) != 0 {
+		t.Errorf("uncertain = %v", uf)
+	}
+}
+
+func TestExtractionTemplate_MatchByName(t *testing.T) {
+	s := NewExtractionTemplateStore(t.TempDir())
+	m := corrected("RS-1042", "14.01.2026", 100, 19, 119)
+	m.VATID = ""
+	if _, err := s.Learn(tplReceiptJan, m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("druckerei sauer"); !ok {
+		t.Fatal("template without VAT-ID must be keyed by the normalised name")
+	}
+	tpl, byVATID, ok := s.Match(tplReceiptFeb)
+	if !ok || byVATID {
+		t.Fatalf("Match = %v, byVATID %v", ok, byVATID)
+	}
+	got := ApplyExtractionTemplate(tpl, byVATID, tplReceiptFeb, Meta{})
+	if got.VATID != "" || got.Provenance[FieldAuftraggeber].Low() {
+		t.Errorf("name match: VATID %q, supplier %+v", got.VATID, got.Provenance[FieldAuftraggeber])
+	}
+	if _, _, ok := s.Match("Hetzner Online GmbH\nRechnung 99"); ok {
+		t.Error("other supplier must not match")
+	}
+}
+
+func TestApplyExtractionTemplate_FallsBack(t *testing.T) {
+	tpl, ok := LearnExtractionTemplate(nil, tplReceiptJan, corrected("RS-1042", "14.01.2026", 100, 19, 119))
+	if !ok {
+		t.Fatal("nothing learned")
+	}
+	// The supplier changed the layout: no "Zu zahlen", no "Beleg".
+	text := "Druckerei Sauer GmbH\nDE811222333\nRechnungsnr: RS-2000\nDatum: 01.03.2026\nGesamt: 59,50\n"
+	fallback, _, _ := NewLocalExtractor().Extract(text)
+	m := ApplyExtractionTemplate(tpl, true, text, fallback)
+	if m.Bruttobetrag != 59.5 || m.Provenance[FieldBruttobetrag].Source != SourceLocal {
+		t.Errorf("gross = %.2f from %+v, want the heuristic value", m.Bruttobetrag, m.Provenance[FieldBruttobetrag])
+	}
+	if m.Rechnungsnummer != "RS-2000" || m.Provenance[FieldRechnungsnummer].Source != SourceLocal {
+		t.Errorf("nr = %q from %+v", m.Rechnungsnummer, m.Provenance[FieldRechnungsnummer])
+	}
+	if fallback.Provenance[FieldAuftraggeber].Source != SourceLocal {
+		t.Error("fallback provenance must not be modified")
+	}
+}
+
+func TestLearnExtractionTemplate_KeepsUnfoundAnchors(t *testing.T) {
+	prev, _ := LearnExtractionTemplate(nil, tplReceiptJan, corrected("RS-1042", "14.01.2026", 100, 19, 119))
+	// Invoice number corrected to a value that is not on the page.
+	next, ok := LearnExtractionTemplate(&prev, tplReceiptFeb, corrected("X", "03.02.2026", 1250, 237.5, 1487.5))
+	if !ok {
+		t.Fatal("nothing learned")
+	}
+	if a, ok := next.anchor(FieldRechnungsnummer); !ok || a.Treffer != 1 || a.Label != "beleg" {
+		t.Errorf("kept anchor = %+v, %v", a, ok)
+	}
+	if a, _ := next.anchor(FieldRechnungsdatum); a.Treffer != 2 {
+		t.Errorf("date anchor = %+v", a)
+	}
+	if _, ok := LearnExtractionTemplate(nil, tplReceiptJan, Meta{Bruttobetrag: 119}); ok {
+		t.Error("a receipt without supplier must not be learned")
+	}
+}
@@ -8,9 +8,10 @@ import (
 )
 
 // supportedExtensions is the set of file extensions BuchISY accepts as an
-// invoice main file or as an attachment (lower-case, leading dot).
+// invoice main file or as an attachment (lower-case, leading dot). ".xml" is
+// a standalone e-invoice (XRechnung/ZUGFeRD XML without a PDF carrier).
 var supportedExtensions = map[string]struct{}{
-	".pdf": {},
+	".pdf": {}, ".xml": {},
 	".doc": {}, ".docx": {},
 	".xls": {}, ".xlsx": {},
 	".ppt": {}, ".pptx": {},
@@ -53,6 +54,12 @@ func IsPDF(name string) bool {
 	return strings.ToLower(filepath.Ext(name)) == ".pdf"
 }
 
+// IsXML reports whether the file name is an XML file — as an invoice main
+// file that is a standalone e-invoice.
+func IsXML(name string) bool {
+	return strings.ToLower(filepath.Ext(name)) == ".xml"
+}
+
 // ImageMediaType returns the Claude-Vision-compatible media type for an
 // image file based on its extension. Returns "" for non-image / non-
 // vision-supported files. Used to decide whether to route a non-PDF
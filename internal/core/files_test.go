@@ -4,21 +4,22 @@ import "testing"
 
 func TestIsSupportedFile(t *testing.T) {
 	cases := map[string]bool{
-		"rechnung.pdf": true,
-		"Rechnung.PDF": true,
-		"tabelle.xlsx": true,
-		"alt.xls":      true,
-		"brief.docx":   true,
-		"brief.doc":    true,
-		"folien.pptx":  true,
-		"calc.ods":     true,
-		"text.odt":     true,
-		"foto.JPG":     true,
-		"bild.png":     true,
-		"scan.tiff":    true,
-		"archiv.zip":   false,
-		"daten.csv":    false,
-		"noext":        false,
+		"rechnung.pdf":  true,
+		"Rechnung.PDF":  true,
+		"tabelle.xlsx":  true,
+		"alt.xls":       true,
+		"brief.docx":    true,
+		"brief.doc":     true,
+		"folien.pptx":   true,
+		"calc.ods":      true,
+		"text.odt":      true,
+		"foto.JPG":      true,
+		"bild.png":      true,
+		"scan.tiff":     true,
+		"xrechnung.xml": true,
+		"archiv.zip":    false,
+		"daten.csv":     false,
+		"noext":         false,
 	}
 	for name, want := range cases {
 		if got := IsSupportedFile(name); got != want {
@@ -69,7 +70,7 @@ func TestParseAttachmentName(t *testing.T) {
 	}{
 		{"2025-08-01_AWS_EUR_Anhang1.xlsx", 1, true},
 		{"2025-08-01_AWS_EUR_Anhang12.pdf", 12, true},
-		{"2025-08-01_AWS_EUR.pdf", 0, false},      // the main file itself
+		{"2025-08-01_AWS_EUR.pdf", 0, false},         // the main file itself
 		{"2025-08-01_AWS_EUR_Anhang0.pdf", 0, false}, // 0 is not a valid index
 		{"2025-08-01_AWS_EUR_AnhangX.pdf", 0, false}, // non-numeric
 		{"other_Anhang1.pdf", 0, false},              // different invoice
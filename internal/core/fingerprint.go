@@ -0,0 +1,261 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	// Decoders for the image receipts image.Decode has to read.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// Fingerprint identifies the content of one archived file: the main receipt
+// (Anhang 0) or its "_Anhang<n>" sibling.
+type Fingerprint struct {
+	Anhang int    // 0 = main file, n = "_Anhang<n>"
+	SHA256 string // hex digest of the file bytes
+	Bild   string // 256-bit difference hash of the (first page) image, hex; "" = none
+	Zahlen string // sorted hashes of the numbers in the text layer; "" = no text
+}
+
+// FingerprintMatch is an archived file that matches a fingerprint.
+type FingerprintMatch struct {
+	Jahr, Monat, Dateiname string
+	Belegnummer            string
+	Anhang                 int
+	Exact                  bool // same bytes; otherwise a near-duplicate
+}
+
+// Label names the matched receipt for messages ("2026-0042", "… Anhang 1").
+func (m FingerprintMatch) Label() string {
+	label := m.Belegnummer
+	if label == "" {
+		label = m.Dateiname
+	}
+	if m.Anhang > 0 {
+		label = fmt.Sprintf("%s, Anhang %d", label, m.Anhang)
+	}
+	return label
+}
+
+// Near-duplicate thresholds. Re-scans differ in a few bits of the image
+// hash; two different receipts of the same supplier share the layout but
+// not their numbers (invoice number, dates, amounts), so the text layer
+// decides whenever both files have one.
+const (
+	nearImageBits    = 24  // of 256
+	nearNumbersShare = 0.8 // Jaccard similarity of the number sets
+	nearNumbersMin   = 4   // fewer numbers are too little evidence
+	maxNumberHashes  = 128
+	fingerprintDPI   = 24.0
+	bildHashSide     = 16
+	bildHashMargin   = 0x0400 // of 0xffff: ignore paper noise
+)
+
+// fingerprintNumberPattern finds numbers with separators ("1.487,50",
+// "14.01.2026", "RS-1042" → "1042").
+var fingerprintNumberPattern = regexp.MustCompile(`\d[\d.,/]*\d|\d`)
+
+// ComputeFingerprint fingerprints a receipt file. PDFs get a text-layer
+// number profile and an image hash of page 1, images an image hash; other
+// files only the SHA-256.
+func ComputeFingerprint(path string) (Fingerprint, error) {
+	sum, err := FileSHA256(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	fp := Fingerprint{SHA256: sum}
+	switch {
+	case IsPDF(path):
+		doc, err := fitz.New(path)
+		if err != nil {
+			return fp, nil // unreadable PDF: the byte hash still works
+		}
+		defer doc.Close()
+		var text strings.Builder
+		for i := 0; i < doc.NumPage(); i++ {
+			if t, err := doc.Text(i); err == nil {
+				text.WriteString(t)
+				text.WriteByte('\n')
+			}
+		}
+		fp.Zahlen = NumberProfile(text.String())
+		if doc.NumPage() > 0 {
+			if img, err := doc.ImageDPI(0, fingerprintDPI); err == nil {
+				fp.Bild = ImageHash(img)
+			}
+		}
+	case ImageMediaType(path) != "" || isRasterImage(path):
+		f, err := os.Open(path)
+		if err != nil {
+			return fp, nil
+		}
+		defer func() { _ = f.Close() }()
+		if img, _, err := image.Decode(f); err == nil {
+			fp.Bild = ImageHash(img)
+		}
+	}
+	return fp, nil
+}
+
+func isRasterImage(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".bmp", ".tif", ".tiff":
+		return true
+	}
+	return false
+}
+
+// FileSHA256 returns the hex SHA-256 of a file's bytes.
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ImageHash is a 256-bit average hash: the image is reduced to 16×16 grey
+// cells and each bit says whether a cell is darker than the page average,
+// i.e. carries print. Robust against scan resolution, compression, paper
+// noise and slight shifts.
+func ImageHash(img image.Image) string {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return ""
+	}
+	const side = bildHashSide
+	var grey [side * side]float64
+	var mean float64
+	for y := 0; y < side; y++ {
+		y0 := b.Min.Y + y*b.Dy()/side
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/side, y0+1)
+		for x := 0; x < side; x++ {
+			x0 := b.Min.X + x*b.Dx()/side
+			x1 := max(b.Min.X+(x+1)*b.Dx()/side, x0+1)
+			var sum float64
+			n := 0
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, bl, _ := img.At(px, py).RGBA()
+					sum += float64(r)*0.299 + float64(g)*0.587 + float64(bl)*0.114
+					n++
+				}
+			}
+			grey[y*side+x] = sum / float64(n)
+			mean += grey[y*side+x]
+		}
+	}
+	mean /= side * side
+	out := make([]byte, side*side/8)
+	for i, g := range grey {
+		if g < mean-bildHashMargin {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return hex.EncodeToString(out)
+}
+
+// imageHashDistance is the number of differing bits of two image hashes,
+// or -1 when they cannot be compared.
+func imageHashDistance(a, b string) int {
+	x, errA := hex.DecodeString(a)
+	y, errB := hex.DecodeString(b)
+	if errA != nil || errB != nil || len(x) == 0 || len(x) != len(y) {
+		return -1
+	}
+	d := 0
+	for i := range x {
+		d += bits.OnesCount8(x[i] ^ y[i])
+	}
+	return d
+}
+
+// NumberProfile returns the sorted, de-duplicated FNV hashes of every number
+// with at least three digits in text ("" when there are none). Numbers carry
+// what tells two receipts apart: invoice number, dates, amounts.
+func NumberProfile(text string) string {
+	seen := map[string]bool{}
+	for _, m := range fingerprintNumberPattern.FindAllString(text, -1) {
+		digits := strings.Map(func(r rune) rune {
+			if isDigitRune(r) {
+				return r
+			}
+			return -1
+		}, m)
+		if len(digits) < 3 {
+			continue
+		}
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(digits))
+		seen[fmt.Sprintf("%08x", h.Sum32())] = true
+	}
+	out := make([]string, 0, len(seen))
+	for k := range seen {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	if len(out) > maxNumberHashes {
+		out = out[:maxNumberHashes]
+	}
+	return strings.Join(out, " ")
+}
+
+// numberSimilarity is the Jaccard similarity of two number profiles, or -1
+// when either has too few numbers to judge.
+func numberSimilarity(a, b string) float64 {
+	x, y := strings.Fields(a), strings.Fields(b)
+	if len(x) < nearNumbersMin || len(y) < nearNumbersMin {
+		return -1
+	}
+	set := make(map[string]bool, len(x))
+	for _, v := range x {
+		set[v] = true
+	}
+	common := 0
+	for _, v := range y {
+		if set[v] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(x)+len(y)-common)
+}
+
+// SameContent reports whether two fingerprints are the same file (equal
+// bytes) or a near-duplicate: a re-scan, or a mailed and an uploaded copy
+// of one receipt. With a text layer on both sides the number profiles
+// decide; otherwise the image hashes do.
+func SameContent(a, b Fingerprint) (same, exact bool) {
+	if a.SHA256 != "" && a.SHA256 == b.SHA256 {
+		return true, true
+	}
+	if s := numberSimilarity(a.Zahlen, b.Zahlen); s >= 0 {
+		return s >= nearNumbersShare, false
+	}
+	if d := imageHashDistance(a.Bild, b.Bild); d >= 0 {
+		return d <= nearImageBits, false
+	}
+	return false, false
+}
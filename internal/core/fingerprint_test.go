@@ -0,0 +1,93 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+)
+
+func TestSameContent_NumberProfile(t *testing.T) {
+	jan := NumberProfile(tplReceiptJan)
+	feb := NumberProfile(tplReceiptFeb)
+	// The same receipt, extracted from a re-generated PDF with other line breaks.
+	janCopy := NumberProfile("Druckerei Sauer GmbH, Hauptstr. 5, 80331 München USt-IdNr.: DE 811 222 333 " +
+		"Kunde 40711 Beleg RS-1042 Ausgestellt am 14.01.2026 Visitenkarten 500 Stk 100,00 " +
+		"Zwischensumme 100,00 zzgl. 19 % USt 19,00 Zu zahlen 119,00 EUR")
+
+	if same, exact := SameContent(Fingerprint{SHA256: "x", Zahlen: jan}, Fingerprint{SHA256: "y", Zahlen: janCopy}); !same || exact {
+		t.Errorf("copy of the same receipt: same=%v exact=%v", same, exact)
+	}
+	if same, _ := SameContent(Fingerprint{SHA256: "x", Zahlen: jan}, Fingerprint{SHA256: "y", Zahlen: feb}); same {
+		t.Error("two receipts of one supplier must not be near-duplicates")
+	}
+	if same, exact := SameContent(Fingerprint{SHA256: "x"}, Fingerprint{SHA256: "x"}); !same || !exact {
+		t.Error("equal bytes must be an exact duplicate")
+	}
+	if same, _ := SameContent(Fingerprint{SHA256: "x", Zahlen: NumberProfile("119,00")}, Fingerprint{SHA256: "y", Zahlen: NumberProfile("119,00")}); same {
+		t.Error("a single number is too little evidence")
+	}
+}
+
+func TestImageHash_Rescan(t *testing.T) {
+	receipt := func(shift int, noise bool, lines []int) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, 420, 600))
+		draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+		for _, y := range lines {
+			draw.Draw(img, image.Rect(40+shift, y+shift, 380+shift, y+12+shift), image.NewUniform(color.Gray{40}), image.Point{}, draw.Src)
+		}
+		draw.Draw(img, image.Rect(250+shift, 480, 380+shift, 520), image.Black, image.Point{}, draw.Src)
+		if noise {
+			for i := 0; i < 400; i++ {
+				img.Set((i*37)%420, (i*53)%600, color.Gray{200})
+			}
+		}
+		return img
+	}
+	orig := ImageHash(receipt(0, false, []int{60, 100, 140, 300}))
+	rescan := ImageHash(receipt(2, true, []int{60, 100, 140, 300}))
+	other := ImageHash(receipt(0, false, []int{200, 230, 260, 400, 430}))
+	if len(orig) != 64 {
+		t.Fatalf("hash length = %d hex digits", len(orig))
+	}
+	if same, _ := SameContent(Fingerprint{SHA256: "a", Bild: orig}, Fingerprint{SHA256: "b", Bild: rescan}); !same {
+		t.Errorf("re-scan not recognised: distance %d", imageHashDistance(orig, rescan))
+	}
+	if same, _ := SameContent(Fingerprint{SHA256: "a", Bild: orig}, Fingerprint{SHA256: "b", Bild: other}); same {
+		t.Errorf("different layout matched: distance %d", imageHashDistance(orig, other))
+	}
+}
+
+func TestComputeFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "RE-4711.pdf")
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.Text(20, 30, "Rechnung 4711 vom 01.03.2026 Netto 100,00 USt 19,00 Gesamt 119,00")
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := ComputeFingerprint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fp.SHA256) != 64 || fp.Bild == "" || len(fp.Zahlen) == 0 {
+		t.Fatalf("fingerprint = %+v", fp)
+	}
+	data, _ := os.ReadFile(path)
+	copyPath := filepath.Join(dir, "Kopie.pdf")
+	_ = os.WriteFile(copyPath, data, 0o644)
+	if fp2, _ := ComputeFingerprint(copyPath); fp2 != fp {
+		t.Errorf("copy fingerprint differs: %+v", fp2)
+	}
+	other := filepath.Join(dir, "notiz.docx")
+	_ = os.WriteFile(other, []byte("docx"), 0o644)
+	if fp3, err := ComputeFingerprint(other); err != nil || fp3.Bild != "" || fp3.Zahlen != "" || fp3.SHA256 == "" {
+		t.Errorf("non-receipt file = %+v, %v", fp3, err)
+	}
+}
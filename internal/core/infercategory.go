@@ -6,21 +6,26 @@ package core
 // and overwrite the stored one on save). Returns "" when no special category is
 // evident (caller keeps its default / learned template).
 //
-//   - §13b reverse-charge accounts (SKR03 1577/1787, SKR04 1407/3837) → "reverse_charge"
+//   - §13b reverse-charge accounts (SKR03 1577/1787, SKR04 1407/3837) → "reverse_charge",
+//     or "reverse_charge_eu" when they carry the EU tax key (SteuerschluesselRCEU)
 //   - both a Bewirtung deductible (4650/6640) AND non-deductible (4654/6644)
 //     entry → "bewirtung"
 func InferBookingCategory(b Booking) string {
-	var hasRC, hasBewAbz, hasBewNicht bool
+	var hasRC, hasRCEU, hasBewAbz, hasBewNicht bool
 	for _, e := range b.Entries {
 		switch e.Konto {
 		case 1577, 1787, 1407, 3837:
 			hasRC = true
+			hasRCEU = hasRCEU || e.Steuerschluessel == SteuerschluesselRCEU
 		case 4650, 6640:
 			hasBewAbz = true
 		case 4654, 6644:
 			hasBewNicht = true
 		}
 	}
+	if hasRCEU {
+		return "reverse_charge_eu"
+	}
 	if hasRC {
 		return "reverse_charge"
 	}
@@ -0,0 +1,441 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IntegrityKind classifies a finding of the archive integrity scan.
+type IntegrityKind string
+
+const (
+	IntegrityMissing  IntegrityKind = "fehlt"     // booked file not in its month folder
+	IntegrityOrphan   IntegrityKind = "verwaist"  // file in a month folder without booking
+	IntegrityModified IntegrityKind = "geaendert" // bytes differ from the stored fingerprint
+	IntegrityCSV      IntegrityKind = "csv"       // invoices.csv differs from the database
+)
+
+// IntegrityIssue is one finding of the archive integrity scan.
+type IntegrityIssue struct {
+	Art         IntegrityKind
+	Jahr, Monat string // "" for orphans in a folder without bookings
+	Dateiname   string // booked main file (fehlt, geaendert)
+	Anhang      int    // 0 = main file, n = "_Anhang<n>"
+	Pfad        string // expected path (fehlt), file on disk, or the invoices.csv
+	Kandidat    string // fehlt: unbooked file with the same content or name
+	Detail      string
+
+	sha string // fingerprinted SHA-256 of a missing main file
+}
+
+// Describe renders the finding as one German report line.
+func (is IntegrityIssue) Describe() string {
+	where := is.Jahr + "-" + is.Monat
+	if is.Jahr == "" {
+		where = filepath.Base(filepath.Dir(is.Pfad))
+	}
+	name := is.Dateiname
+	if name == "" {
+		name = filepath.Base(is.Pfad)
+	}
+	if is.Anhang > 0 {
+		name = fmt.Sprintf("%s, Anhang %d", name, is.Anhang)
+	}
+	return where + " · " + name + ": " + is.Detail
+}
+
+// IntegrityReport is the result of CheckArchive.
+type IntegrityReport struct {
+	Monate  int // month folders scanned
+	Belege  int // booked invoices
+	Dateien int // files found in the month folders
+	Issues  []IntegrityIssue
+}
+
+// OK reports whether archive and database agree.
+func (r IntegrityReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Count returns the number of findings of one kind.
+func (r IntegrityReport) Count(k IntegrityKind) int {
+	n := 0
+	for _, is := range r.Issues {
+		if is.Art == k {
+			n++
+		}
+	}
+	return n
+}
+
+// ArchiveMonth is what the database holds for one booked month.
+type ArchiveMonth struct {
+	Jahr, Monat  string
+	Rows         []CSVRow                 // as ExportToCSV writes them
+	Fingerprints map[string][]Fingerprint // by Dateiname, main file first
+}
+
+// archiveSubfolders are the category subfolders a month folder may hold.
+var archiveSubfolders = []string{"", "Bar", "Ausgangsrechnungen"}
+
+// archiveDataFiles are the files BuchISY itself keeps in a month folder.
+var archiveDataFiles = map[string]bool{"invoices.csv": true, "kassenbuch.json": true}
+
+// yearFolderPattern matches a YYYY year folder name.
+var yearFolderPattern = regexp.MustCompile(`^\d{4}$`)
+
+// ignoredArchiveFile reports files the operating system or office programs
+// leave in folders; they are never receipts.
+func ignoredArchiveFile(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") ||
+		lower == "thumbs.db" || lower == "desktop.ini"
+}
+
+// archiveFolder collects the months sharing one folder: all of them with
+// month subfolders off, one otherwise.
+type archiveFolder struct {
+	path   string
+	months []ArchiveMonth
+}
+
+// CheckArchive compares the month folders under the storage root with the
+// database: booked files that are missing, files nobody booked (including
+// "_Anhang" files without their invoice), files whose bytes no longer match
+// the fingerprint stored when they were archived, and invoices.csv files
+// that differ from what ExportToCSV writes. A missing file gets an unbooked
+// file with the same SHA-256 (or else the same name) as Kandidat for
+// re-linking; such a file is not reported as orphan itself.
+func (sm *StorageManager) CheckArchive(months []ArchiveMonth, csvRepo *CSVRepository) IntegrityReport {
+	folders := map[string]*archiveFolder{}
+	for _, m := range months {
+		y, errY := strconv.Atoi(m.Jahr)
+		mo, errM := strconv.Atoi(m.Monat)
+		if errY != nil || errM != nil {
+			continue
+		}
+		path := sm.GetMonthFolder(y, time.Month(mo))
+		if folders[path] == nil {
+			folders[path] = &archiveFolder{path: path}
+		}
+		folders[path].months = append(folders[path].months, m)
+	}
+	if sm.settings.UseMonthSubfolders {
+		for _, path := range sm.diskMonthFolders() {
+			if folders[path] == nil {
+				folders[path] = &archiveFolder{path: path}
+			}
+		}
+	}
+	paths := make([]string, 0, len(folders))
+	for p := range folders {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var rep IntegrityReport
+	var missing, orphans []IntegrityIssue
+	for _, p := range paths {
+		f := folders[p]
+		rep.Monate++
+		m, o, n := checkArchiveFolder(f)
+		missing = append(missing, m...)
+		orphans = append(orphans, o...)
+		rep.Dateien += n
+		for _, mon := range f.months {
+			rep.Belege += len(mon.Rows)
+			if sm.settings.UseMonthSubfolders {
+				if is, ok := checkMonthCSV(f.path, mon, csvRepo); ok {
+					rep.Issues = append(rep.Issues, is)
+				}
+			}
+		}
+		if m := monthFolderPattern.FindStringSubmatch(filepath.Base(f.path)); len(f.months) == 0 && m != nil {
+			if is, ok := checkMonthCSV(f.path, ArchiveMonth{Jahr: m[1], Monat: m[2]}, csvRepo); ok {
+				rep.Issues = append(rep.Issues, is)
+			}
+		}
+	}
+	missing, orphans = assignRelinkCandidates(missing, orphans)
+	rep.Issues = append(append(missing, orphans...), rep.Issues...)
+	sort.SliceStable(rep.Issues, func(i, j int) bool {
+		a, b := rep.Issues[i], rep.Issues[j]
+		if a.Jahr+a.Monat != b.Jahr+b.Monat {
+			return a.Jahr+a.Monat < b.Jahr+b.Monat
+		}
+		return a.Pfad < b.Pfad
+	})
+	return rep
+}
+
+// diskMonthFolders lists the YYYY/YYYY-MM folders under the storage root.
+func (sm *StorageManager) diskMonthFolders() []string {
+	root := sm.settings.StorageRoot
+	years, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, y := range years {
+		if !y.IsDir() || !yearFolderPattern.MatchString(y.Name()) {
+			continue
+		}
+		months, err := os.ReadDir(filepath.Join(root, y.Name()))
+		if err != nil {
+			continue
+		}
+		for _, m := range months {
+			if m.IsDir() && monthFolderPattern.MatchString(m.Name()) && strings.HasPrefix(m.Name(), y.Name()) {
+				out = append(out, filepath.Join(root, y.Name(), m.Name()))
+			}
+		}
+	}
+	return out
+}
+
+// checkArchiveFolder checks the booked files of one folder and returns the
+// findings for them (missing, modified), the unbooked files and the number
+// of files seen.
+func checkArchiveFolder(f *archiveFolder) (issues, orphans []IntegrityIssue, files int) {
+	subs := append([]string(nil), archiveSubfolders...)
+	for _, mon := range f.months {
+		for _, row := range mon.Rows {
+			if row.Unterordner != "" && !containsString(subs, row.Unterordner) {
+				subs = append(subs, row.Unterordner)
+			}
+		}
+	}
+	onDisk := map[string]bool{}
+	for _, sub := range subs {
+		entries, err := os.ReadDir(filepath.Join(f.path, sub))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || ignoredArchiveFile(e.Name()) {
+				continue
+			}
+			if sub == "" && archiveDataFiles[strings.ToLower(e.Name())] {
+				continue
+			}
+			onDisk[filepath.Join(f.path, sub, e.Name())] = false
+		}
+	}
+	files = len(onDisk)
+	claim := func(p string) {
+		if _, ok := onDisk[p]; ok {
+			onDisk[p] = true
+		}
+	}
+
+	for _, mon := range f.months {
+		for _, row := range mon.Rows {
+			main := InvoiceFilePath(f.path, row)
+			fps := mon.Fingerprints[row.Dateiname]
+			attachments := AttachmentPathsIn(filepath.Dir(main), row.Dateiname)
+			for _, a := range attachments {
+				claim(a)
+			}
+			issue := IntegrityIssue{Jahr: mon.Jahr, Monat: mon.Monat, Dateiname: row.Dateiname}
+			if _, ok := onDisk[main]; !ok {
+				is := issue
+				is.Art, is.Pfad, is.Detail = IntegrityMissing, main, "Datei fehlt"
+				if len(fps) > 0 && fps[0].Anhang == 0 {
+					is.sha = fps[0].SHA256
+				}
+				issues = append(issues, is)
+				continue
+			}
+			claim(main)
+			paths := append([]string{main}, attachments...)
+			for _, fp := range fps {
+				is := issue
+				is.Anhang = fp.Anhang
+				if fp.Anhang >= len(paths) {
+					is.Art, is.Detail = IntegrityMissing, "Anhang fehlt"
+					is.Pfad = filepath.Join(filepath.Dir(main), fmt.Sprintf("%s_Anhang%d", ReplaceExtension(row.Dateiname, ""), fp.Anhang))
+					issues = append(issues, is)
+					continue
+				}
+				sum, err := FileSHA256(paths[fp.Anhang])
+				if err != nil {
+					is.Art, is.Pfad, is.Detail = IntegrityModified, paths[fp.Anhang], "nicht lesbar: "+err.Error()
+					issues = append(issues, is)
+				} else if fp.SHA256 != "" && sum != fp.SHA256 {
+					is.Art, is.Pfad = IntegrityModified, paths[fp.Anhang]
+					is.Detail = "Inhalt weicht vom Fingerabdruck bei der Ablage ab"
+					issues = append(issues, is)
+				}
+			}
+		}
+	}
+
+	for p, claimed := range onDisk {
+		if claimed {
+			continue
+		}
+		is := IntegrityIssue{Art: IntegrityOrphan, Pfad: p, Detail: "keine Buchung"}
+		if len(f.months) == 1 {
+			is.Jahr, is.Monat = f.months[0].Jahr, f.months[0].Monat
+		} else if m := monthFolderPattern.FindStringSubmatch(filepath.Base(f.path)); len(f.months) == 0 && m != nil {
+			is.Jahr, is.Monat = m[1], m[2]
+		}
+		if attachmentPattern.MatchString(filepath.Base(p)) {
+			is.Detail = "Anhang ohne Beleg"
+		}
+		orphans = append(orphans, is)
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Pfad < orphans[j].Pfad })
+	return issues, orphans, files
+}
+
+// attachmentPattern matches a "<base>_Anhang<n>[.<ext>]" file name.
+var attachmentPattern = regexp.MustCompile(`_Anhang\d+(\.[^.]*)?$`)
+
+// assignRelinkCandidates pairs missing main files with unbooked files:
+// first by the SHA-256 stored when the file was archived, then by name.
+// A paired file and its "_Anhang" siblings leave the orphan list.
+func assignRelinkCandidates(issues, orphans []IntegrityIssue) ([]IntegrityIssue, []IntegrityIssue) {
+	sums := map[string]string{}
+	sumOf := func(p string) string {
+		if s, ok := sums[p]; ok {
+			return s
+		}
+		s, _ := FileSHA256(p)
+		sums[p] = s
+		return s
+	}
+	used := map[string]bool{}
+	pick := func(match func(p string) bool) string {
+		for _, o := range orphans {
+			if !used[o.Pfad] && !attachmentPattern.MatchString(filepath.Base(o.Pfad)) && match(o.Pfad) {
+				used[o.Pfad] = true
+				return o.Pfad
+			}
+		}
+		return ""
+	}
+	for i, is := range issues {
+		if is.Art != IntegrityMissing || is.Anhang > 0 || is.sha == "" {
+			continue
+		}
+		issues[i].Kandidat = pick(func(p string) bool { return sumOf(p) == is.sha })
+	}
+	for i, is := range issues {
+		if is.Art != IntegrityMissing || is.Anhang > 0 || is.Kandidat != "" {
+			continue
+		}
+		issues[i].Kandidat = pick(func(p string) bool { return filepath.Base(p) == is.Dateiname })
+	}
+	for i, is := range issues {
+		if is.Kandidat != "" {
+			issues[i].Detail = "Datei fehlt, gefunden als " + filepath.Base(is.Kandidat)
+		}
+	}
+	rest := orphans[:0]
+	for _, o := range orphans {
+		sibling := false
+		for p := range used {
+			if filepath.Dir(p) == filepath.Dir(o.Pfad) {
+				if _, ok := ParseAttachmentName(filepath.Base(o.Pfad), filepath.Base(p)); ok {
+					sibling = true
+				}
+			}
+		}
+		if !used[o.Pfad] && !sibling {
+			rest = append(rest, o)
+		}
+	}
+	return issues, rest
+}
+
+// checkMonthCSV compares a month's invoices.csv with the bytes ExportToCSV
+// would write for mon.Rows.
+func checkMonthCSV(folder string, mon ArchiveMonth, csvRepo *CSVRepository) (IntegrityIssue, bool) {
+	path := filepath.Join(folder, "invoices.csv")
+	is := IntegrityIssue{Art: IntegrityCSV, Jahr: mon.Jahr, Monat: mon.Monat, Pfad: path}
+	actual, err := os.ReadFile(path)
+	if err != nil {
+		if len(mon.Rows) == 0 {
+			return is, false
+		}
+		is.Detail = "Datei fehlt"
+		return is, true
+	}
+	var want bytes.Buffer
+	if err := csvRepo.WriteTo(&want, mon.Rows); err != nil {
+		is.Detail = err.Error()
+		return is, true
+	}
+	if bytes.Equal(want.Bytes(), actual) {
+		return is, false
+	}
+	got, err := csvRepo.Load(path)
+	if err != nil {
+		is.Detail = "nicht lesbar: " + err.Error()
+		return is, true
+	}
+	if len(mon.Rows) == 0 && len(got) == 0 {
+		return is, false // header only; a month without bookings
+	}
+	record := func(r CSVRow) string { return strings.Join(csvRepo.rowToRecord(r), "\x1f") }
+	want2 := map[string]string{}
+	for _, r := range mon.Rows {
+		want2[r.Dateiname] = record(r)
+	}
+	onlyCSV, changed := 0, 0
+	for _, r := range got {
+		w, ok := want2[r.Dateiname]
+		switch {
+		case !ok:
+			onlyCSV++
+		case w != record(r):
+			changed++
+		}
+		delete(want2, r.Dateiname)
+	}
+	var parts []string
+	if changed > 0 {
+		parts = append(parts, fmt.Sprintf("%d Zeile(n) abweichend", changed))
+	}
+	if len(want2) > 0 {
+		parts = append(parts, fmt.Sprintf("%d Beleg(e) fehlen", len(want2)))
+	}
+	if onlyCSV > 0 {
+		parts = append(parts, fmt.Sprintf("%d Zeile(n) ohne Buchung", onlyCSV))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "Format oder Reihenfolge abweichend")
+	}
+	is.Detail = strings.Join(parts, ", ")
+	return is, true
+}
+
+// Relink moves the Kandidat of a missing-file finding, with its "_Anhang"
+// siblings, to the path the database expects.
+func (sm *StorageManager) Relink(is IntegrityIssue) error {
+	if is.Art != IntegrityMissing || is.Kandidat == "" {
+		return fmt.Errorf("no file to re-link for %s", is.Dateiname)
+	}
+	if FileExists(is.Pfad) {
+		return fmt.Errorf("target already exists: %s", is.Pfad)
+	}
+	dir := filepath.Dir(is.Pfad)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create target folder: %w", err)
+	}
+	if err := os.Rename(is.Kandidat, is.Pfad); err != nil {
+		if err := copyFile(is.Kandidat, is.Pfad); err != nil {
+			return fmt.Errorf("failed to copy file: %w", err)
+		}
+		_ = os.Remove(is.Kandidat)
+	}
+	return sm.MoveInvoiceAttachments(filepath.Dir(is.Kandidat), filepath.Base(is.Kandidat), dir, filepath.Base(is.Pfad))
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// integrityArchive builds a March 2026 month folder with two booked
+// invoices (one with an attachment), their fingerprints and a matching
+// invoices.csv.
+func integrityArchive(t *testing.T) (*StorageManager, *CSVRepository, ArchiveMonth, string) {
+	t.Helper()
+	root := t.TempDir()
+	s := Settings{StorageRoot: root, UseMonthSubfolders: true}
+	sm := NewStorageManager(&s)
+	folder := filepath.Join(root, "2026", "2026-03")
+	write := func(name, content string) {
+		p := filepath.Join(folder, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.pdf", "beleg a")
+	write("a_Anhang1.png", "anhang a")
+	write(filepath.Join("Bar", "b.pdf"), "beleg b")
+	mon := ArchiveMonth{
+		Jahr: "2026", Monat: "03",
+		Rows: []CSVRow{
+			{Dateiname: "a.pdf", Jahr: "2026", Monat: "03", Bruttobetrag: 119},
+			{Dateiname: "b.pdf", Jahr: "2026", Monat: "03", Bruttobetrag: 10, Unterordner: "Bar"},
+		},
+		Fingerprints: map[string][]Fingerprint{},
+	}
+	for _, f := range []struct{ name, path string }{
+		{"a.pdf", "a.pdf"}, {"a.pdf", "a_Anhang1.png"}, {"b.pdf", filepath.Join("Bar", "b.pdf")},
+	} {
+		sum, err := FileSHA256(filepath.Join(folder, f.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fps := mon.Fingerprints[f.name]
+		mon.Fingerprints[f.name] = append(fps, Fingerprint{Anhang: len(fps), SHA256: sum})
+	}
+	csvRepo := NewCSVRepository()
+	if err := csvRepo.Rewrite(filepath.Join(folder, "invoices.csv"), mon.Rows); err != nil {
+		t.Fatal(err)
+	}
+	return sm, csvRepo, mon, folder
+}
+
+func TestCheckArchive_Clean(t *testing.T) {
+	sm, csvRepo, mon, folder := integrityArchive(t)
+	_ = os.WriteFile(filepath.Join(folder, "Thumbs.db"), []byte("x"), 0644)
+	_ = os.WriteFile(filepath.Join(folder, "kassenbuch.json"), []byte("[]"), 0644)
+	rep := sm.CheckArchive([]ArchiveMonth{mon}, csvRepo)
+	if !rep.OK() {
+		t.Fatalf("issues = %+v", rep.Issues)
+	}
+	if rep.Monate != 1 || rep.Belege != 2 || rep.Dateien != 3 {
+		t.Errorf("counts = %d/%d/%d", rep.Monate, rep.Belege, rep.Dateien)
+	}
+}
+
+func TestCheckArchive_Findings(t *testing.T) {
+	sm, csvRepo, mon, folder := integrityArchive(t)
+	// Renamed in the file manager, attachment and all.
+	_ = os.Rename(filepath.Join(folder, "a.pdf"), filepath.Join(folder, "scan.pdf"))
+	_ = os.Rename(filepath.Join(folder, "a_Anhang1.png"), filepath.Join(folder, "scan_Anhang1.png"))
+	// Edited after archiving.
+	_ = os.WriteFile(filepath.Join(folder, "Bar", "b.pdf"), []byte("beleg b, geändert"), 0644)
+	// Dropped in by hand, plus a stray attachment.
+	_ = os.WriteFile(filepath.Join(folder, "neu.pdf"), []byte("neu"), 0644)
+	_ = os.WriteFile(filepath.Join(folder, "x_Anhang2.pdf"), []byte("x"), 0644)
+	// A month folder the database knows nothing about.
+	other := filepath.Join(filepath.Dir(folder), "2026-04")
+	_ = os.MkdirAll(other, 0755)
+	_ = os.WriteFile(filepath.Join(other, "april.pdf"), []byte("april"), 0644)
+	// invoices.csv edited by hand.
+	drift := mon
+	drift.Rows = []CSVRow{mon.Rows[0]}
+	drift.Rows[0].Bruttobetrag = 120
+	_ = csvRepo.Rewrite(filepath.Join(folder, "invoices.csv"), drift.Rows)
+
+	rep := sm.CheckArchive([]ArchiveMonth{mon}, csvRepo)
+	var lines []string
+	for _, is := range rep.Issues {
+		lines = append(lines, is.Describe())
+	}
+	got := strings.Join(lines, "\n")
+	if rep.Count(IntegrityMissing) != 1 || rep.Count(IntegrityModified) != 1 ||
+		rep.Count(IntegrityOrphan) != 3 || rep.Count(IntegrityCSV) != 1 {
+		t.Fatalf("findings:\n%s", got)
+	}
+	for _, want := range []string{
+		"2026-03 · a.pdf: Datei fehlt, gefunden als scan.pdf",
+		"2026-03 · b.pdf: Inhalt weicht vom Fingerabdruck bei der Ablage ab",
+		"2026-03 · neu.pdf: keine Buchung",
+		"2026-03 · x_Anhang2.pdf: Anhang ohne Beleg",
+		"2026-04 · april.pdf: keine Buchung",
+		"2026-03 · invoices.csv: 1 Zeile(n) abweichend, 1 Beleg(e) fehlen",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "scan_Anhang1") {
+		t.Errorf("the candidate's attachment is not an orphan:\n%s", got)
+	}
+
+	var relink IntegrityIssue
+	for _, is := range rep.Issues {
+		if is.Art == IntegrityMissing {
+			relink = is
+		}
+	}
+	if err := sm.Relink(relink); err != nil {
+		t.Fatal(err)
+	}
+	if !FileExists(filepath.Join(folder, "a.pdf")) || !FileExists(filepath.Join(folder, "a_Anhang1.png")) {
+		t.Error("re-link did not restore a.pdf with its attachment")
+	}
+	if rep := sm.CheckArchive([]ArchiveMonth{mon}, csvRepo); rep.Count(IntegrityMissing) != 0 {
+		t.Errorf("still missing after re-link: %+v", rep.Issues)
+	}
+}
+
+func TestCheckArchive_MissingAttachmentAndCSV(t *testing.T) {
+	sm, csvRepo, mon, folder := integrityArchive(t)
+	_ = os.Remove(filepath.Join(folder, "a_Anhang1.png"))
+	_ = os.Remove(filepath.Join(folder, "invoices.csv"))
+	rep := sm.CheckArchive([]ArchiveMonth{mon}, csvRepo)
+	if len(rep.Issues) != 2 {
+		t.Fatalf("issues = %+v", rep.Issues)
+	}
+	for _, is := range rep.Issues {
+		switch is.Art {
+		case IntegrityMissing:
+			if is.Anhang != 1 || is.Kandidat != "" {
+				t.Errorf("attachment finding = %+v", is)
+			}
+		case IntegrityCSV:
+			if is.Detail != "Datei fehlt" {
+				t.Errorf("csv finding = %+v", is)
+			}
+		default:
+			t.Errorf("unexpected %+v", is)
+		}
+	}
+}
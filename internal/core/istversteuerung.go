@@ -0,0 +1,182 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Zahlung is a payment received on an outgoing invoice: the day it arrived
+// and its EUR amount.
+type Zahlung struct {
+	Datum  string // DD.MM.YYYY
+	Betrag float64
+}
+
+// Zahlungen returns the payments of an outgoing invoice. Only evidence of a
+// payment counts: the statement lines its BuchungRef links to (linked,
+// resolved by the caller), a cash receipt confirmed against the Kassenbuch
+// (CashConfirmedRef; paid on its Bezahldatum, else its invoice date), or a
+// Bezahldatum entered in the Beleg dialog — the e-invoice due date is kept
+// apart in Meta.Faelligkeit and never lands there. A fully paid invoice
+// always adds up to its EUR amount (bank lines may differ by fees or exchange
+// rates); a Teilzahlung counts only the linked lines, as a Bezahldatum does
+// not tell how much arrived. Anything else — a receipt merely filed under
+// "Bar", an open Teilzahlung — is unpaid (nil).
+func Zahlungen(r CSVRow, linked []StatementBooking) []Zahlung {
+	gesamt := InvoiceEURAmount(r)
+	fallback := r.Bezahldatum
+	if fallback == "" {
+		fallback = r.Rechnungsdatum
+	}
+	var out []Zahlung
+	var summe float64
+	for _, l := range linked {
+		if l.Betrag <= 0 {
+			continue
+		}
+		t, ok := parseFlexDate(l.Date, fallback)
+		if !ok {
+			continue
+		}
+		out = append(out, Zahlung{Datum: t.Format("02.01.2006"), Betrag: l.Betrag})
+		summe += l.Betrag
+	}
+	if len(out) > 0 {
+		if r.Teilzahlung {
+			return capZahlungen(out, gesamt)
+		}
+		for i := range out {
+			out[i].Betrag = round2(out[i].Betrag / summe * gesamt)
+		}
+		return out
+	}
+	if r.Teilzahlung {
+		return nil
+	}
+	datum := strings.TrimSpace(r.Bezahldatum)
+	if datum == "" && r.BuchungRef == CashConfirmedRef {
+		datum = r.Rechnungsdatum
+	}
+	if _, err := time.Parse("02.01.2006", datum); err != nil {
+		return nil
+	}
+	return []Zahlung{{Datum: datum, Betrag: gesamt}}
+}
+
+// capZahlungen keeps partial payments in date order until they reach the
+// invoice amount.
+func capZahlungen(zs []Zahlung, gesamt float64) []Zahlung {
+	day := func(d string) time.Time {
+		t, _ := time.Parse("02.01.2006", d)
+		return t
+	}
+	sort.SliceStable(zs, func(i, j int) bool { return day(zs[i].Datum).Before(day(zs[j].Datum)) })
+	var out []Zahlung
+	offen := gesamt
+	for _, z := range zs {
+		if offen < 0.005 {
+			break
+		}
+		z.Betrag = round2(math.Min(z.Betrag, offen))
+		offen -= z.Betrag
+		out = append(out, z)
+	}
+	return out
+}
+
+// IstRows prepares rows for a VAT return of the periods von..bis ("YYYY-MM",
+// inclusive) under Ist-Versteuerung (§ 20 UStG): the output VAT of a taxable
+// outgoing invoice belongs to the period its payment arrived, so such an
+// invoice contributes one copy per payment in the range, scaled to the paid
+// share and filed under the payment's month. A Storno of one is reported in
+// its own period, scaled to the paid share of the reversed invoice, and
+// dropped while that is unpaid. All other rows (incoming invoices: Vorsteuer
+// follows the invoice; non-taxable EU and foreign supplies: § 13b/§ 18b tie
+// them to the period of supply) are kept when their own period is in range.
+// rows must reach back far enough to contain the invoices paid in the range;
+// zahlungen returns the payments of a row (see Zahlungen).
+func IstRows(rows []CSVRow, zahlungen func(CSVRow) []Zahlung, von, bis string) []CSVRow {
+	inRange := func(p string) bool { return p >= von && p <= bis }
+	byKey := map[string]CSVRow{}
+	for _, r := range rows {
+		if !r.IsStorno() {
+			byKey[r.StornoKey()] = r
+		}
+	}
+	paidShare := func(r CSVRow) float64 {
+		gesamt := InvoiceEURAmount(r)
+		if math.Abs(gesamt) < 0.005 {
+			return 0
+		}
+		var s float64
+		for _, z := range zahlungen(r) {
+			s += z.Betrag
+		}
+		return math.Min(s/gesamt, 1)
+	}
+
+	var out []CSVRow
+	for _, r := range rows {
+		own := r.Jahr + "-" + r.Monat
+		if !r.Ausgangsrechnung || math.Abs(SumMwSt(r.TaxLines)) < 0.005 {
+			if inRange(own) {
+				out = append(out, r)
+			}
+			continue
+		}
+		if r.IsStorno() {
+			orig, ok := byKey[r.StornoZu]
+			if !ok || !inRange(own) {
+				continue
+			}
+			if f := paidShare(orig); f > 0 {
+				out = append(out, scaleRow(r, f))
+			}
+			continue
+		}
+		gesamt := InvoiceEURAmount(r)
+		if math.Abs(gesamt) < 0.005 {
+			continue
+		}
+		for _, z := range zahlungen(r) {
+			t, err := time.Parse("02.01.2006", z.Datum)
+			if err != nil {
+				continue
+			}
+			jahr, monat := fmt.Sprintf("%04d", t.Year()), fmt.Sprintf("%02d", int(t.Month()))
+			if !inRange(jahr + "-" + monat) {
+				continue
+			}
+			c := scaleRow(r, math.Min(z.Betrag/gesamt, 1))
+			c.Jahr, c.Monat = jahr, monat
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// scaleRow returns a copy of r with amounts, tax lines and booking scaled by
+// f (the paid share); f = 1 returns r unchanged.
+func scaleRow(r CSVRow, f float64) CSVRow {
+	if math.Abs(f-1) < 1e-9 {
+		return r
+	}
+	s := r
+	s.BetragNetto = round2(r.BetragNetto * f)
+	s.SteuersatzBetrag = round2(r.SteuersatzBetrag * f)
+	s.Bruttobetrag = round2(r.Bruttobetrag * f)
+	s.BetragNetto_EUR = round2(r.BetragNetto_EUR * f)
+	s.TaxLines = make([]TaxLine, len(r.TaxLines))
+	for i, l := range r.TaxLines {
+		s.TaxLines[i] = TaxLine{Netto: round2(l.Netto * f), SatzProzent: l.SatzProzent, MwStBetrag: round2(l.MwStBetrag * f)}
+	}
+	s.Buchung.Entries = make([]BookingEntry, len(r.Buchung.Entries))
+	for i, e := range r.Buchung.Entries {
+		e.Betrag = round2(e.Betrag * f)
+		s.Buchung.Entries[i] = e
+	}
+	return s
+}
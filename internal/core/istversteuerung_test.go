@@ -0,0 +1,156 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// ausgang is a domestic 19 % outgoing invoice of net 1000 filed in jahr/monat.
+func ausgang(beleg, datum, jahr, monat string) CSVRow {
+	return CSVRow{
+		Belegnummer:      beleg,
+		Rechnungsdatum:   datum,
+		Jahr:             jahr,
+		Monat:            monat,
+		Ausgangsrechnung: true,
+		Waehrung:         "EUR",
+		BetragNetto:      1000,
+		SteuersatzBetrag: 190,
+		Bruttobetrag:     1190,
+		TaxLines:         []TaxLine{{Netto: 1000, SatzProzent: 19, MwStBetrag: 190}},
+		Buchung: Booking{Entries: []BookingEntry{
+			{Konto: 1200, Betrag: 1190, Soll: true},
+			{Konto: 4400, Betrag: 1000, Soll: false},
+			{Konto: 3806, Betrag: 190, Soll: false},
+		}},
+	}
+}
+
+func TestZahlungen(t *testing.T) {
+	r := ausgang("2026-0001", "20.01.2026", "2026", "01")
+	if z := Zahlungen(r, nil); z != nil {
+		t.Errorf("unpaid: %+v", z)
+	}
+	r.Bezahldatum = "03.02.2026"
+	if z := Zahlungen(r, nil); len(z) != 1 || z[0].Datum != "03.02.2026" || z[0].Betrag != 1190 {
+		t.Errorf("Bezahldatum: %+v", z)
+	}
+	// A linked line wins over the Bezahldatum; fees do not make it partial.
+	z := Zahlungen(r, []StatementBooking{{Date: "05.02.", Betrag: 1185}})
+	if len(z) != 1 || z[0].Datum != "05.02.2026" || z[0].Betrag != 1190 {
+		t.Errorf("linked: %+v", z)
+	}
+	// Partial payments count as received, capped at the invoice amount.
+	r.Teilzahlung = true
+	z = Zahlungen(r, []StatementBooking{{Date: "10.03.2026", Betrag: 900}, {Date: "10.02.2026", Betrag: 595}})
+	if len(z) != 2 || z[0].Datum != "10.02.2026" || z[0].Betrag != 595 || z[1].Betrag != 595 {
+		t.Errorf("partial: %+v", z)
+	}
+	cash := ausgang("2026-0002", "21.01.2026", "2026", "01")
+	cash.BuchungRef = CashConfirmedRef
+	if z := Zahlungen(cash, nil); len(z) != 1 || z[0].Datum != "21.01.2026" {
+		t.Errorf("cash: %+v", z)
+	}
+}
+
+func TestIstRows(t *testing.T) {
+	dez := ausgang("2025-0100", "15.12.2025", "2025", "12")
+	dez.Bezahldatum = "12.01.2026"
+	teil := ausgang("2026-0001", "20.01.2026", "2026", "01")
+	teil.Teilzahlung = true
+	teil.BuchungRef = "a.pdf|0|1;a.pdf|0|2"
+	offen := ausgang("2026-0002", "25.01.2026", "2026", "01")
+	storno := BuildStorno(offen, time.Date(2026, 1, 30, 0, 0, 0, 0, time.UTC), "falscher Kunde")
+	eu := CSVRow{
+		Belegnummer: "2026-0003", Rechnungsdatum: "28.01.2026", Jahr: "2026", Monat: "01",
+		Ausgangsrechnung: true, VATID: "FR12345678901", Bezahldatum: "10.02.2026",
+		TaxLines: []TaxLine{{Netto: 500}}, Bruttobetrag: 500,
+	}
+	eingang := CSVRow{
+		Belegnummer: "2026-0004", Rechnungsdatum: "05.01.2026", Jahr: "2026", Monat: "01",
+		TaxLines: []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}}, Bruttobetrag: 119,
+	}
+	rows := []CSVRow{dez, teil, offen, storno, eu, eingang}
+	zahlungen := func(r CSVRow) []Zahlung {
+		if r.Belegnummer == teil.Belegnummer {
+			return Zahlungen(r, []StatementBooking{{Date: "10.01.2026", Betrag: 595}, {Date: "10.02.2026", Betrag: 595}})
+		}
+		return Zahlungen(r, nil)
+	}
+
+	jan := IstRows(rows, zahlungen, "2026-01", "2026-01")
+	u := ComputeUStVAOfficial(jan, &BookingRules{})
+	// December invoice paid in January (1000) + half of the partial (500);
+	// the unpaid invoice and its Storno stay out; EU supply and Vorsteuer
+	// follow their own period.
+	if u.Kz81 != 1500 || u.USt81 != 285 || u.Kz21 != 500 || u.Kz66 != 19 {
+		t.Errorf("January = %+v", u)
+	}
+	for _, r := range jan {
+		if r.Belegnummer == dez.Belegnummer && (r.Jahr != "2026" || r.Monat != "01") {
+			t.Errorf("paid row filed under %s-%s", r.Jahr, r.Monat)
+		}
+	}
+	ledger := ComputeUStVA(jan, &BookingRules{UmsatzsteuerKonten: map[string]int{"19": 3806}})
+	if ledger.UmsatzsteuerGesamt != 285 {
+		t.Errorf("ledger USt = %+v", ledger)
+	}
+
+	feb := ComputeUStVAOfficial(IstRows(rows, zahlungen, "2026-02", "2026-02"), &BookingRules{})
+	if feb.Kz81 != 500 || feb.Kz21 != 0 {
+		t.Errorf("February = %+v", feb)
+	}
+	dec := ComputeUStVAOfficial(IstRows(rows, zahlungen, "2025-12", "2025-12"), &BookingRules{})
+	if dec.Kz81 != 0 {
+		t.Errorf("December = %+v", dec)
+	}
+}
+
+// TestIstRows_UnpaidOutgoingInvoice: without a linked line, a Kassenbuch
+// confirmation or an entered Bezahldatum an outgoing invoice is unpaid and
+// reports no output VAT in any period.
+func TestIstRows_UnpaidOutgoingInvoice(t *testing.T) {
+	offen := ausgang("2026-0001", "20.01.2026", "2026", "01")
+	bar := ausgang("2026-0002", "21.01.2026", "2026", "01")
+	bar.Unterordner = "Bar" // filed as cash, but not confirmed
+	teil := ausgang("2026-0003", "22.01.2026", "2026", "01")
+	teil.Teilzahlung = true
+	teil.Bezahldatum = "05.02.2026" // says nothing about the amount
+	rows := []CSVRow{offen, bar, teil}
+	for _, r := range rows {
+		if z := Zahlungen(r, nil); z != nil {
+			t.Errorf("%s: Zahlungen = %+v, want unpaid", r.Belegnummer, z)
+		}
+	}
+	got := IstRows(rows, func(r CSVRow) []Zahlung { return Zahlungen(r, nil) }, "2026-01", "2026-12")
+	if len(got) != 0 {
+		t.Errorf("IstRows = %+v, want none", got)
+	}
+	if u := ComputeUStVAOfficial(got, &BookingRules{}); u.Kz81 != 0 || u.Kz83 != 0 {
+		t.Errorf("UStVA = %+v, want empty", u)
+	}
+}
+
+func TestIstRows_StornoOfPaidInvoice(t *testing.T) {
+	orig := ausgang("2026-0001", "20.01.2026", "2026", "01")
+	orig.Bezahldatum = "25.01.2026"
+	storno := BuildStorno(orig, time.Date(2026, 2, 25, 0, 0, 0, 0, time.UTC), "Gutschrift")
+	got := ComputeUStVAOfficial(IstRows([]CSVRow{orig, storno}, func(r CSVRow) []Zahlung { return Zahlungen(r, nil) },
+		"2026-02", "2026-02"), &BookingRules{})
+	if got.Kz81 != -1000 {
+		t.Errorf("Storno = %+v", got)
+	}
+}
+
+func TestVATExports_Besteuerung(t *testing.T) {
+	u := UStVAOfficial{Kz81: 1000, USt81: 190, Kz83: 190, Ist: true}
+	data, err := BuildUStVAXML(u, "2026-01", "DE123456789")
+	if err != nil || !strings.Contains(string(data), `besteuerung="ist"`) {
+		t.Errorf("UStVA XML: %v\n%s", err, data)
+	}
+	data, err = BuildZMXML(ZM{}, "2026-Q1", "")
+	if err != nil || !strings.Contains(string(data), `besteuerung="soll"`) {
+		t.Errorf("ZM XML: %v\n%s", err, data)
+	}
+}
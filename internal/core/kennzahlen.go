@@ -0,0 +1,258 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// SteuerschluesselRCEU is the DATEV tax key the booking engine puts on the
+// tax entries of a "reverse_charge_eu" booking (§ 13b Abs. 1 UStG: services
+// of an entrepreneur from another EU country), so the UStVA can tell them
+// (Kz 46/47) from the other § 13b cases (Kz 84/85) on the same accounts.
+const SteuerschluesselRCEU = "46"
+
+// KennzahlKonto assigns the booking entries on an account — optionally only
+// those carrying a DATEV tax key — to the UStVA. Kz receives the entry's
+// amount; BasisKz (tax accounts) receives the net the tax was charged on,
+// taken from the invoice's tax lines at Satz (all lines when none has that
+// rate, as on § 13b and ig Erwerb invoices).
+type KennzahlKonto struct {
+	Konto            int     `json:"konto"`
+	Steuerschluessel string  `json:"steuerschluessel,omitempty"`
+	Kz               string  `json:"kz,omitempty"`
+	BasisKz          string  `json:"basis_kz,omitempty"`
+	Satz             float64 `json:"satz,omitempty"`
+}
+
+// UStVAKennzahlen are the Kennzahlen a KennzahlKonto may name, in form order.
+var UStVAKennzahlen = []string{"81", "86", "35", "36", "41", "44", "43", "89", "93", "46", "47", "84", "85", "21", "45", "66", "61", "62", "67", "64"}
+
+// vorsteuerKz are the Kennzahlen of the deduction side: their amount is
+// Soll − Haben; every other Kennzahl counts Haben − Soll.
+var vorsteuerKz = map[string]bool{"61": true, "62": true, "64": true, "66": true, "67": true}
+
+// skrKennzahlKonten are the standard accounts of each chart variant for the
+// Kennzahlen no role in the rules implies: tax-free ig Lieferungen (Kz 41,
+// new vehicles Kz 44) and Ausfuhren (Kz 43), § 18b and Drittland services
+// (Kz 21/45), the Erwerbsteuer (base Kz 89/93) and Vorsteuer (Kz 61) of an
+// ig Erwerb and the Einfuhrumsatzsteuer (Kz 62). § 15a corrections (Kz 64)
+// have no standard account; see KennzahlenOhneKonto.
+var skrKennzahlKonten = map[string][]KennzahlKonto{
+	"SKR03": {
+		{Konto: 8125, Kz: "41"},
+		{Konto: 8135, Kz: "44"},
+		{Konto: 8120, Kz: "43"},
+		{Konto: 8336, Kz: "21"},
+		{Konto: 8338, Kz: "45"},
+		{Konto: 1774, BasisKz: "89", Satz: 19},
+		{Konto: 1772, BasisKz: "93", Satz: 7},
+		{Konto: 1574, Kz: "61"},
+		{Konto: 1572, Kz: "61"},
+		{Konto: 1588, Kz: "62"},
+	},
+	"SKR04": {
+		{Konto: 4125, Kz: "41"},
+		{Konto: 4135, Kz: "44"},
+		{Konto: 4120, Kz: "43"},
+		{Konto: 4336, Kz: "21"},
+		{Konto: 4338, Kz: "45"},
+		{Konto: 3804, BasisKz: "89", Satz: 19},
+		{Konto: 3802, BasisKz: "93", Satz: 7},
+		{Konto: 1404, Kz: "61"},
+		{Konto: 1402, Kz: "61"},
+		{Konto: 1433, Kz: "62"},
+	},
+}
+
+// skrVariante tells the chart variant of the rules from their Vorsteuer
+// 19 % account (as DetectSKRVariant does for a chart); "" if unknown.
+func (r *BookingRules) skrVariante() string {
+	switch r.VorsteuerKonten["19"] {
+	case 1576:
+		return "SKR03"
+	case 1406:
+		return "SKR04"
+	}
+	return ""
+}
+
+// IsUStVAKennzahl reports whether kz is one of UStVAKennzahlen.
+func IsUStVAKennzahl(kz string) bool {
+	for _, k := range UStVAKennzahlen {
+		if k == kz {
+			return true
+		}
+	}
+	return false
+}
+
+// KennzahlKonten returns the account → Kennzahl mapping of the rules: the
+// explicit UStVAKonten first, then the standard accounts of the rules' chart
+// variant (skrKennzahlKonten), then for every account those leave out the
+// mapping its role in the rules implies — Umsatzsteuer 19 %/7 % → base of
+// Kz 81/86 (other rates: Kz 35/36), Vorsteuer → Kz 66, the reverse_charge
+// accounts → Kz 84/85 and 67 (reverse_charge_eu: Kz 46/47 and 67 on entries
+// with SteuerschluesselRCEU), the EU and Drittland revenue accounts → Kz 21
+// and 45. So in SKR04 the EU revenue account 4125 (ig Lieferungen) reports
+// to Kz 41 and the Drittland account 4120 (Ausfuhren) to Kz 43.
+func (r *BookingRules) KennzahlKonten() []KennzahlKonto {
+	out := append([]KennzahlKonto(nil), r.UStVAKonten...)
+	have := map[string]bool{}
+	key := func(konto int, schluessel string) string { return fmt.Sprintf("%d|%s", konto, schluessel) }
+	for _, k := range out {
+		have[key(k.Konto, k.Steuerschluessel)] = true
+	}
+	add := func(k KennzahlKonto) {
+		if k.Konto == 0 || have[key(k.Konto, k.Steuerschluessel)] {
+			return
+		}
+		have[key(k.Konto, k.Steuerschluessel)] = true
+		out = append(out, k)
+	}
+
+	for _, k := range skrKennzahlKonten[r.skrVariante()] {
+		add(k)
+	}
+	for _, satz := range sortedKeys(r.UmsatzsteuerKonten) {
+		s, err := strconv.ParseFloat(satz, 64)
+		if err != nil {
+			continue
+		}
+		switch int(s + 0.5) {
+		case 19:
+			add(KennzahlKonto{Konto: r.UmsatzsteuerKonten[satz], BasisKz: "81", Satz: s})
+		case 7:
+			add(KennzahlKonto{Konto: r.UmsatzsteuerKonten[satz], BasisKz: "86", Satz: s})
+		default:
+			add(KennzahlKonto{Konto: r.UmsatzsteuerKonten[satz], Kz: "36", BasisKz: "35", Satz: s})
+		}
+	}
+	for _, satz := range sortedKeys(r.VorsteuerKonten) {
+		add(KennzahlKonto{Konto: r.VorsteuerKonten[satz], Kz: "66"})
+	}
+	if rc, ok := r.Rule("reverse_charge_eu"); ok {
+		add(KennzahlKonto{Konto: rc.KontoUStRC, Steuerschluessel: SteuerschluesselRCEU, Kz: "47", BasisKz: "46", Satz: rc.RcSatz})
+		add(KennzahlKonto{Konto: rc.KontoVStRC, Steuerschluessel: SteuerschluesselRCEU, Kz: "67"})
+	}
+	if rc, ok := r.Rule("reverse_charge"); ok {
+		add(KennzahlKonto{Konto: rc.KontoUStRC, Kz: "85", BasisKz: "84", Satz: rc.RcSatz})
+		add(KennzahlKonto{Konto: rc.KontoVStRC, Kz: "67"})
+	}
+	add(KennzahlKonto{Konto: r.ErloesKonten["eu"], Kz: "21"})
+	add(KennzahlKonto{Konto: r.ErloesKonten["drittland"], Kz: "45"})
+	return out
+}
+
+// KennzahlenOhneKonto returns the Kennzahlen of the deduction and tax-free
+// side that no account of KennzahlKonten reports to (Kz 41/43/44/89/93/61/
+// 62/64): bookings for them are missing from the UStVA until the mapping is
+// completed.
+func (r *BookingRules) KennzahlenOhneKonto() []string {
+	gemappt := map[string]bool{}
+	for _, k := range r.KennzahlKonten() {
+		gemappt[k.Kz] = true
+		gemappt[k.BasisKz] = true
+	}
+	var out []string
+	for _, kz := range []string{"41", "44", "43", "89", "93", "61", "62", "64"} {
+		if !gemappt[kz] {
+			out = append(out, kz)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// KennzahlIndex looks up the mapping of a booking entry.
+type KennzahlIndex map[string]KennzahlKonto
+
+// NewKennzahlIndex indexes a mapping; the first entry for an account and tax
+// key wins.
+func NewKennzahlIndex(konten []KennzahlKonto) KennzahlIndex {
+	idx := KennzahlIndex{}
+	for _, k := range konten {
+		key := fmt.Sprintf("%d|%s", k.Konto, k.Steuerschluessel)
+		if _, ok := idx[key]; !ok {
+			idx[key] = k
+		}
+	}
+	return idx
+}
+
+// find returns the mapping of an entry: the one for its account and tax key,
+// else the one for its account without a key.
+func (idx KennzahlIndex) find(e BookingEntry) (KennzahlKonto, bool) {
+	if e.Steuerschluessel != "" {
+		if k, ok := idx[fmt.Sprintf("%d|%s", e.Konto, e.Steuerschluessel)]; ok {
+			return k, true
+		}
+	}
+	k, ok := idx[fmt.Sprintf("%d|", e.Konto)]
+	return k, ok
+}
+
+// KennzahlBeitrag is what one booking entry adds to a Kennzahl.
+type KennzahlBeitrag struct {
+	Kz     string
+	Konto  int
+	Betrag float64
+}
+
+// RowKennzahlen assigns a row's booking entries to Kennzahlen through the
+// mapping. The row must be in EUR (RowEUR). ok is false when no entry of the
+// booking is mapped.
+func RowKennzahlen(r CSVRow, idx KennzahlIndex) (beitraege []KennzahlBeitrag, ok bool) {
+	for _, e := range r.Buchung.Entries {
+		k, found := idx.find(e)
+		if !found || (k.Kz == "" && k.BasisKz == "") {
+			continue
+		}
+		ok = true
+		betrag := e.Betrag
+		if e.Soll {
+			betrag = -betrag
+		}
+		if k.Kz != "" {
+			b := betrag
+			if vorsteuerKz[k.Kz] {
+				b = -b
+			}
+			beitraege = append(beitraege, KennzahlBeitrag{Kz: k.Kz, Konto: e.Konto, Betrag: b})
+		}
+		if k.BasisKz != "" {
+			beitraege = append(beitraege, KennzahlBeitrag{Kz: k.BasisKz, Konto: e.Konto, Betrag: basisNetto(r.TaxLines, k.Satz, betrag)})
+		}
+	}
+	return beitraege, ok
+}
+
+// basisNetto is the net a tax entry of steuer was charged on: the net of the
+// tax lines at satz, scaled to the entry's share of their VAT; without such
+// a line (the invoice shows no VAT) the net of all lines, with the sign of
+// the entry.
+func basisNetto(lines []TaxLine, satz, steuer float64) float64 {
+	var netto, mwst float64
+	for _, l := range lines {
+		if l.MwStBetrag != 0 && int(l.SatzProzent+0.5) == int(satz+0.5) {
+			netto += l.Netto
+			mwst += l.MwStBetrag
+		}
+	}
+	if mwst != 0 {
+		return netto * steuer / mwst
+	}
+	netto = SumNetto(lines)
+	if (netto < 0) != (steuer < 0) {
+		netto = -netto
+	}
+	return netto
+}
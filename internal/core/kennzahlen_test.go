@@ -0,0 +1,181 @@
+package core
+
+import "testing"
+
+func kennzahlRules(t *testing.T) *BookingRules {
+	t.Helper()
+	rules, err := ParseBookingRules([]byte(`{
+		"vorsteuer_konten":{"19":1406,"7":1401},
+		"umsatzsteuer_konten":{"19":3806,"7":3801},
+		"erloes_konten":{"inland":4400,"eu":4336,"drittland":4338},
+		"regeln":[
+			{"kategorie":"standard","name":"Standard"},
+			{"kategorie":"reverse_charge","name":"RC","rc_satz":19,"konto_vst_rc":1407,"konto_ust_rc":3837},
+			{"kategorie":"reverse_charge_eu","name":"RC EU","rc_satz":19,"konto_vst_rc":1407,"konto_ust_rc":3837}],
+		"ustva_konten":[
+			{"konto":4125,"kz":"41"},
+			{"konto":3802,"basis_kz":"89","satz":19},
+			{"konto":1402,"kz":"61"},
+			{"konto":1409,"kz":"64"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestComputeUStVAOfficialFromBookings(t *testing.T) {
+	rules := kennzahlRules(t)
+	rcEU, _ := BuildBooking(rules, "reverse_charge_eu", []TaxLine{{Netto: 500}}, 0, 6300, 1800, 0)
+	rc, _ := BuildBooking(rules, "reverse_charge", []TaxLine{{Netto: 200}}, 0, 6300, 1800, 0)
+	rows := []CSVRow{
+		// Domestic sale 19 % booked on the Umsatzsteuer account → Kz 81.
+		{Belegnummer: "A1", Ausgangsrechnung: true, VATID: "DE123",
+			TaxLines: []TaxLine{{Netto: 1000, SatzProzent: 19, MwStBetrag: 190}},
+			Buchung: Booking{Entries: []BookingEntry{
+				{Konto: 1200, Betrag: 1190, Soll: true}, {Konto: 4400, Betrag: 1000}, {Konto: 3806, Betrag: 190}}}},
+		// Tax-free ig Lieferung on the explicitly mapped account → Kz 41, not
+		// Kz 21 as the VAT-ID heuristic would have it.
+		{Belegnummer: "A2", Ausgangsrechnung: true, VATID: "FR12345678901",
+			TaxLines: []TaxLine{{Netto: 3000}},
+			Buchung:  Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 3000, Soll: true}, {Konto: 4125, Betrag: 3000}}}},
+		// § 13b Abs. 1 service from another EU country → Kz 46/47/67.
+		{Belegnummer: "E1", VATID: "IE123", TaxLines: []TaxLine{{Netto: 500}}, Buchung: rcEU},
+		// Domestic § 13b → Kz 84/85/67.
+		{Belegnummer: "E2", VATID: "DE999", TaxLines: []TaxLine{{Netto: 200}}, Buchung: rc},
+		// ig Erwerb: Erwerbsteuer → base Kz 89, its Vorsteuer → Kz 61.
+		{Belegnummer: "E3", VATID: "AT123", TaxLines: []TaxLine{{Netto: 800}},
+			Buchung: Booking{Entries: []BookingEntry{
+				{Konto: 5425, Betrag: 800, Soll: true}, {Konto: 1402, Betrag: 152, Soll: true},
+				{Konto: 3802, Betrag: 152}, {Konto: 1600, Betrag: 800}}}},
+		// Normal purchase → Kz 66; its Storno (entries swapped) takes it back.
+		{Belegnummer: "E4", TaxLines: []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 6815, Betrag: 100, Soll: true}, {Konto: 1406, Betrag: 19, Soll: true}, {Konto: 1600, Betrag: 119}}}},
+		{Belegnummer: "E5", TaxLines: []TaxLine{{Netto: 50, SatzProzent: 19, MwStBetrag: 9.5}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 6815, Betrag: 50, Soll: true}, {Konto: 1406, Betrag: 9.5, Soll: true}, {Konto: 1600, Betrag: 59.5}}}},
+		{Belegnummer: "E5-S", TaxLines: []TaxLine{{Netto: -50, SatzProzent: 19, MwStBetrag: -9.5}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 1600, Betrag: 59.5, Soll: true}, {Konto: 6815, Betrag: 50}, {Konto: 1406, Betrag: 9.5}}}},
+		// § 15a correction reducing the Vorsteuer → negative Kz 64.
+		{Belegnummer: "U1", Buchung: Booking{Entries: []BookingEntry{{Konto: 1409, Betrag: 40}, {Konto: 6300, Betrag: 40, Soll: true}}}},
+	}
+	u := ComputeUStVAOfficial(rows, rules)
+	check := func(name string, got, want float64) {
+		t.Helper()
+		if !almost(got, want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	check("Kz81", u.Kz81, 1000)
+	check("USt81", u.USt81, 190)
+	check("Kz41", u.Kz41, 3000)
+	check("Kz21", u.Kz21, 0)
+	check("Kz46", u.Kz46, 500)
+	check("Kz47", u.Kz47, 95)
+	check("Kz84", u.Kz84, 200)
+	check("Kz85", u.Kz85, 38)
+	check("Kz67", u.Kz67, 133)
+	check("Kz89", u.Kz89, 800)
+	check("USt89", u.USt89, 152)
+	check("Kz61", u.Kz61, 152)
+	check("Kz66", u.Kz66, 19)
+	check("Kz64", u.Kz64, -40)
+	// (190 + 152 + 95 + 38) − (19 + 152 + 133 − 40) = 211
+	check("Kz83", u.Kz83, 211)
+	if g := UStVAGeschaetzt(rows, rules); len(g) != 0 {
+		t.Errorf("geschätzt = %v", g)
+	}
+}
+
+func TestUStVAGeschaetzt(t *testing.T) {
+	rules := kennzahlRules(t)
+	rows := []CSVRow{
+		// Not booked yet: classified from the invoice data.
+		{Belegnummer: "A1", Ausgangsrechnung: true, TaxLines: []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}}},
+		// Booked without any mapped account, but nothing to report.
+		{Belegnummer: "E1", TaxLines: []TaxLine{{Netto: 30}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 6815, Betrag: 30, Soll: true}, {Konto: 1600, Betrag: 30}}}},
+	}
+	u := ComputeUStVAOfficial(rows, rules)
+	if !almost(u.Kz81, 100) {
+		t.Errorf("Kz81 = %v", u.Kz81)
+	}
+	if g := UStVAGeschaetzt(rows, rules); len(g) != 1 || g[0] != "A1" {
+		t.Errorf("geschätzt = %v", g)
+	}
+}
+
+func TestKennzahlKontenOverride(t *testing.T) {
+	rules := kennzahlRules(t)
+	// Revenue on the EU account is a tax-free ig Lieferung in this profile.
+	rules.UStVAKonten = append(rules.UStVAKonten, KennzahlKonto{Konto: 4336, Kz: "41"})
+	idx := NewKennzahlIndex(rules.KennzahlKonten())
+	b, ok := RowKennzahlen(CSVRow{Buchung: Booking{Entries: []BookingEntry{{Konto: 4336, Betrag: 70}}}}, idx)
+	if !ok || len(b) != 1 || b[0].Kz != "41" || b[0].Betrag != 70 {
+		t.Errorf("override = %+v", b)
+	}
+	// A key-specific entry wins; other keys fall back to the plain account.
+	if k, _ := idx.find(BookingEntry{Konto: 3837, Steuerschluessel: SteuerschluesselRCEU}); k.Kz != "47" {
+		t.Errorf("3837/46 = %+v", k)
+	}
+	if k, _ := idx.find(BookingEntry{Konto: 3837, Steuerschluessel: "94"}); k.Kz != "85" {
+		t.Errorf("3837/94 = %+v", k)
+	}
+}
+
+func TestKennzahlKontenSKRStandard(t *testing.T) {
+	rules := ApplySKRVariant(&BookingRules{}, "SKR04")
+	rows := []CSVRow{
+		// ig Lieferung on the EU revenue account 4125 → Kz 41, not Kz 21.
+		{Belegnummer: "A1", Ausgangsrechnung: true, VATID: "FR12345678901", TaxLines: []TaxLine{{Netto: 3000}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 3000, Soll: true}, {Konto: 4125, Betrag: 3000}}}},
+		// Ausfuhr on the Drittland account 4120 → Kz 43.
+		{Belegnummer: "A2", Ausgangsrechnung: true, TaxLines: []TaxLine{{Netto: 700}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 700, Soll: true}, {Konto: 4120, Betrag: 700}}}},
+		// ig Erwerb 19 % → base Kz 89, Vorsteuer Kz 61; Einfuhrumsatzsteuer → Kz 62.
+		{Belegnummer: "E1", VATID: "AT123", TaxLines: []TaxLine{{Netto: 400}},
+			Buchung: Booking{Entries: []BookingEntry{
+				{Konto: 5425, Betrag: 400, Soll: true}, {Konto: 1404, Betrag: 76, Soll: true},
+				{Konto: 3804, Betrag: 76}, {Konto: 1800, Betrag: 400}}}},
+		{Belegnummer: "E2", Buchung: Booking{Entries: []BookingEntry{{Konto: 1433, Betrag: 57, Soll: true}, {Konto: 1800, Betrag: 57}}}},
+	}
+	u := ComputeUStVAOfficial(rows, rules)
+	if u.Kz41 != 3000 || u.Kz21 != 0 || u.Kz43 != 700 || u.Kz45 != 0 {
+		t.Errorf("Kz41/21/43/45 = %v/%v/%v/%v, want 3000/0/700/0", u.Kz41, u.Kz21, u.Kz43, u.Kz45)
+	}
+	if u.Kz89 != 400 || u.USt89 != 76 || u.Kz61 != 76 || u.Kz62 != 57 {
+		t.Errorf("Kz89/USt89/61/62 = %v/%v/%v/%v, want 400/76/76/57", u.Kz89, u.USt89, u.Kz61, u.Kz62)
+	}
+	if z := ComputeZMMitRegeln(rows, rules); len(z.Zeilen) != 1 || z.Zeilen[0].Art != ZMArtLieferung {
+		t.Errorf("ZM = %+v, want one ig Lieferung", z.Zeilen)
+	}
+
+	// Only Kz 64 lacks a standard account; an unknown chart lacks them all.
+	if got := rules.KennzahlenOhneKonto(); len(got) != 1 || got[0] != "64" {
+		t.Errorf("ohne Konto = %v, want [64]", got)
+	}
+	if got := (&BookingRules{}).KennzahlenOhneKonto(); len(got) != 8 {
+		t.Errorf("ohne Konto (no chart) = %v", got)
+	}
+}
+
+func TestReverseChargeEUCategory(t *testing.T) {
+	rules := kennzahlRules(t)
+	b, err := BuildBooking(rules, "reverse_charge_eu", []TaxLine{{Netto: 100}}, 0, 6300, 1800, 0)
+	if err != nil || !b.Balanced() {
+		t.Fatalf("booking = %+v, %v", b, err)
+	}
+	if got := InferBookingCategory(b); got != "reverse_charge_eu" {
+		t.Errorf("InferBookingCategory = %q", got)
+	}
+	plain, _ := BuildBooking(rules, "reverse_charge", []TaxLine{{Netto: 100}}, 0, 6300, 1800, 0)
+	if got := InferBookingCategory(plain); got != "reverse_charge" {
+		t.Errorf("InferBookingCategory = %q", got)
+	}
+
+	// A saved SKR03 profile gets the new category on its own § 13b accounts.
+	saved, _ := ParseBookingRules([]byte(`{"regeln":[{"kategorie":"reverse_charge","rc_satz":19,"konto_vst_rc":1577,"konto_ust_rc":1787}]}`))
+	bundled, _ := ParseBookingRules([]byte(`{"regeln":[{"kategorie":"reverse_charge_eu","rc_satz":19,"konto_vst_rc":1407,"konto_ust_rc":3837}]}`))
+	merged := mergeBundledIntoSaved(saved, bundled)
+	if r, ok := merged.Rule("reverse_charge_eu"); !ok || r.KontoVStRC != 1577 || r.KontoUStRC != 1787 {
+		t.Errorf("merged = %+v", merged.Regeln)
+	}
+}
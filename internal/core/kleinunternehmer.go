@@ -0,0 +1,202 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Revenue limits of the Kleinunternehmerregelung (§ 19 Abs. 1 UStG, from
+// 2025): the Gesamtumsatz of the previous year must not have exceeded the
+// first, the current year's must not exceed the second.
+const (
+	KleinunternehmerGrenzeVorjahr = 25000.0
+	KleinunternehmerGrenzeJahr    = 100000.0
+)
+
+// BuildKleinunternehmerBooking is BuildBooking for a Kleinunternehmer, who
+// may not deduct Vorsteuer: the VAT of each line is booked with its net to
+// the expense account(s), so the category rules (Bewirtung split, Geschenke
+// limit) apply to the gross cost. A § 13b service still owes the
+// Umsatzsteuer, but without the matching Vorsteuer it is part of the cost.
+func BuildKleinunternehmerBooking(rules *BookingRules, kategorie string, lines []TaxLine, trinkgeld float64, expenseAccount, paymentAccount int, rabatt float64) (Booking, error) {
+	if kategorie == "reverse_charge" || kategorie == "reverse_charge_eu" {
+		rule, ok := rules.Rule(kategorie)
+		if !ok {
+			return Booking{}, fmt.Errorf("unbekannte Buchungskategorie: %s", kategorie)
+		}
+		net := round2(SumNetto(lines) + trinkgeld)
+		vat := round2(net * rule.RcSatz / 100)
+		return Booking{Entries: []BookingEntry{
+			{Konto: expenseAccount, Betrag: round2(net + vat), Soll: true},
+			{Konto: rule.KontoUStRC, Betrag: vat, Soll: false, Steuerschluessel: rcSteuerschluessel(kategorie)},
+			{Konto: paymentAccount, Betrag: net, Soll: false},
+		}}, nil
+	}
+	gross := make([]TaxLine, len(lines))
+	for i, l := range lines {
+		gross[i] = TaxLine{Netto: round2(l.Netto + l.MwStBetrag), SatzProzent: l.SatzProzent}
+	}
+	return BuildBooking(rules, kategorie, gross, trinkgeld, expenseAccount, paymentAccount, rabatt)
+}
+
+// KleinunternehmerWarnings adapts InvoiceWarnings to a Kleinunternehmer: an
+// outgoing invoice without VAT is the rule, not a missing ZM entry, while
+// one that shows VAT owes it anyway (§ 14c Abs. 2 UStG).
+func KleinunternehmerWarnings(row CSVRow, warnings []string) []string {
+	out := make([]string, 0, len(warnings)+1)
+	for _, w := range warnings {
+		if w != warnAusgangOhneUSt {
+			out = append(out, w)
+		}
+	}
+	if row.Ausgangsrechnung && row.SteuersatzBetrag != 0 {
+		out = append(out, "Kleinunternehmer (§ 19 UStG): Ausgangsrechnung weist USt aus — sie wird nach § 14c UStG trotzdem geschuldet; Rechnung ohne USt neu ausstellen")
+	}
+	return out
+}
+
+// KleinunternehmerStatus is the Gesamtumsatz of a year and its previous year
+// measured against the § 19 limits.
+type KleinunternehmerStatus struct {
+	Jahr          string
+	Monate        [12]float64 // Gesamtumsatz per month of Jahr
+	Umsatz        float64     // Gesamtumsatz of Jahr
+	UmsatzVorjahr float64
+	// GrenzeUeberschrittenMit is the Belegnummer of the invoice whose payment
+	// took Umsatz past KleinunternehmerGrenzeJahr ("" = not passed); from
+	// that invoice on the regular taxation applies.
+	GrenzeUeberschrittenMit string
+}
+
+// VorjahrUeberschritten reports whether the previous year's Gesamtumsatz
+// rules out the Kleinunternehmerregelung for the whole of Jahr.
+func (s KleinunternehmerStatus) VorjahrUeberschritten() bool {
+	return s.UmsatzVorjahr > KleinunternehmerGrenzeVorjahr
+}
+
+// Zulaessig reports whether both limits are kept.
+func (s KleinunternehmerStatus) Zulaessig() bool {
+	return !s.VorjahrUeberschritten() && s.GrenzeUeberschrittenMit == ""
+}
+
+// Gesamtumsatz returns the revenue an outgoing invoice adds to the
+// Gesamtumsatz once fully paid: its EUR net amount (a Storno subtracts) less
+// the share booked to an AnlagenerloesKonto — sales of fixed assets do not
+// count (§ 19 Abs. 2 UStG). An unbooked row counts in full unless its
+// Gegenkonto is such an account. ok is false for incoming invoices.
+func Gesamtumsatz(r CSVRow, rules *BookingRules) (float64, bool) {
+	if !r.Ausgangsrechnung {
+		return 0, false
+	}
+	eur, _ := RowEUR(r)
+	if rules == nil {
+		return round2(eur.BetragNetto), true
+	}
+	if len(r.Buchung.Entries) == 0 {
+		if rules.IsAnlagenerloesKonto(r.Gegenkonto) {
+			return 0, true
+		}
+		return round2(eur.BetragNetto), true
+	}
+	var anlagen float64
+	for _, e := range r.Buchung.Entries {
+		if !rules.IsAnlagenerloesKonto(e.Konto) {
+			continue
+		}
+		if e.Soll {
+			anlagen -= e.Betrag
+		} else {
+			anlagen += e.Betrag
+		}
+	}
+	netto := SumNetto(r.TaxLines)
+	if math.Abs(anlagen) < 0.005 || math.Abs(netto) < 0.005 {
+		return round2(eur.BetragNetto), true
+	}
+	anteil := math.Min(math.Abs(anlagen/netto), 1)
+	return round2(eur.BetragNetto * (1 - anteil)), true
+}
+
+// ComputeKleinunternehmer sums the Gesamtumsatz of jahr ("YYYY") per month
+// and of the previous year, and finds the invoice whose payment passed the
+// current-year limit. § 19 Abs. 2 UStG measures the vereinnahmte Entgelte:
+// an invoice counts in the year and month of each payment (zahlungen, see
+// Zahlungen) with the paid share of its Gesamtumsatz, an unpaid one not at
+// all; a Storno counts in its own month, scaled to the paid share of the
+// reversed invoice. rows must reach back far enough to contain the invoices
+// paid in the previous year.
+func ComputeKleinunternehmer(rows []CSVRow, zahlungen func(CSVRow) []Zahlung, rules *BookingRules, jahr string) KleinunternehmerStatus {
+	s := KleinunternehmerStatus{Jahr: jahr}
+	y, _ := time.Parse("2006", jahr)
+	byKey := map[string]CSVRow{}
+	for _, r := range rows {
+		if !r.IsStorno() {
+			byKey[r.StornoKey()] = r
+		}
+	}
+	paidShare := func(r CSVRow) float64 {
+		gesamt := InvoiceEURAmount(r)
+		if math.Abs(gesamt) < 0.005 {
+			return 0
+		}
+		var summe float64
+		for _, z := range zahlungen(r) {
+			summe += z.Betrag
+		}
+		return math.Min(summe/gesamt, 1)
+	}
+
+	type eingang struct {
+		tag    time.Time
+		beleg  string
+		betrag float64
+	}
+	var aktuell []eingang
+	add := func(datum, beleg string, betrag float64) {
+		t, err := time.Parse("02.01.2006", strings.TrimSpace(datum))
+		if err != nil {
+			return
+		}
+		switch t.Year() {
+		case y.Year() - 1:
+			s.UmsatzVorjahr += betrag
+		case y.Year():
+			s.Monate[t.Month()-1] = round2(s.Monate[t.Month()-1] + betrag)
+			aktuell = append(aktuell, eingang{tag: t, beleg: beleg, betrag: betrag})
+		}
+	}
+	for _, r := range rows {
+		u, ok := Gesamtumsatz(r, rules)
+		if !ok || math.Abs(u) < 0.005 {
+			continue
+		}
+		if r.IsStorno() {
+			if orig, ok := byKey[r.StornoZu]; ok {
+				if f := paidShare(orig); f > 0 {
+					add(r.Rechnungsdatum, r.Belegnummer, round2(u*f))
+				}
+			}
+			continue
+		}
+		gesamt := InvoiceEURAmount(r)
+		if math.Abs(gesamt) < 0.005 {
+			continue
+		}
+		for _, z := range zahlungen(r) {
+			add(z.Datum, r.Belegnummer, round2(u*z.Betrag/gesamt))
+		}
+	}
+	s.UmsatzVorjahr = round2(s.UmsatzVorjahr)
+
+	sort.SliceStable(aktuell, func(i, j int) bool { return aktuell[i].tag.Before(aktuell[j].tag) })
+	for _, e := range aktuell {
+		s.Umsatz = round2(s.Umsatz + e.betrag)
+		if s.GrenzeUeberschrittenMit == "" && s.Umsatz > KleinunternehmerGrenzeJahr {
+			s.GrenzeUeberschrittenMit = e.beleg
+		}
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package core
+
+import "testing"
+
+func TestBuildKleinunternehmerBooking(t *testing.T) {
+	rules, _ := ParseBookingRules([]byte(`{"vorsteuer_konten":{"19":1406,"7":1401},"regeln":[
+		{"kategorie":"standard","name":"Standard"},
+		{"kategorie":"bewirtung","name":"B","abziehbar_prozent":70,"konto_abziehbar":6640,"konto_nicht_abziehbar":6644},
+		{"kategorie":"reverse_charge","name":"RC","rc_satz":19,"konto_vst_rc":1407,"konto_ust_rc":3837},
+		{"kategorie":"geschenke","name":"G","schwelle":50,"konto_abziehbar":6610,"konto_nicht_abziehbar":6620}]}`))
+
+	std, err := BuildKleinunternehmerBooking(rules, "standard", []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}}, 0, 6815, 1800, 0)
+	if err != nil || !std.Balanced() || len(std.Entries) != 2 {
+		t.Fatalf("standard = %+v, %v", std, err)
+	}
+	if !almost(sollByKonto(std)[6815], 119) || !almost(habenByKonto(std)[1800], 119) {
+		t.Errorf("standard = %+v", std)
+	}
+
+	// Bewirtung splits the gross cost 70/30.
+	bew, _ := BuildKleinunternehmerBooking(rules, "bewirtung", []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}}, 0, 0, 1800, 0)
+	if got := sollByKonto(bew); !almost(got[6640], 83.3) || !almost(got[6644], 35.7) || got[1406] != 0 {
+		t.Errorf("bewirtung = %+v", got)
+	}
+
+	// A gift of net 45 is over the limit once its VAT counts.
+	g, _ := BuildKleinunternehmerBooking(rules, "geschenke", []TaxLine{{Netto: 45, SatzProzent: 19, MwStBetrag: 8.55}}, 0, 0, 1800, 0)
+	if got := sollByKonto(g); !almost(got[6620], 53.55) {
+		t.Errorf("geschenke = %+v", got)
+	}
+
+	// § 13b: the Umsatzsteuer is owed, the missing Vorsteuer is cost.
+	rc, _ := BuildKleinunternehmerBooking(rules, "reverse_charge", []TaxLine{{Netto: 100}}, 0, 6300, 1800, 0)
+	if !rc.Balanced() || !almost(sollByKonto(rc)[6300], 119) || sollByKonto(rc)[1407] != 0 ||
+		!almost(habenByKonto(rc)[3837], 19) || !almost(habenByKonto(rc)[1800], 100) {
+		t.Errorf("reverse_charge = %+v", rc)
+	}
+}
+
+func TestKleinunternehmerWarnings(t *testing.T) {
+	ohne := CSVRow{Ausgangsrechnung: true, BetragNetto: 500, Bruttobetrag: 500, Gegenkonto: 4400, Rechnungsdatum: "01.02.2026"}
+	if w := KleinunternehmerWarnings(ohne, InvoiceWarnings(ohne)); len(w) != 0 {
+		t.Errorf("invoice without VAT: %v", w)
+	}
+	mit := CSVRow{Ausgangsrechnung: true, BetragNetto: 500, SteuersatzBetrag: 95, Bruttobetrag: 595, Gegenkonto: 4400, Rechnungsdatum: "01.02.2026"}
+	if w := KleinunternehmerWarnings(mit, InvoiceWarnings(mit)); len(w) != 1 {
+		t.Errorf("invoice with VAT: %v", w)
+	}
+}
+
+func TestComputeKleinunternehmer(t *testing.T) {
+	var rows []CSVRow
+	add := func(beleg, datum, jahr, monat, bezahlt string, netto float64) {
+		rows = append(rows, CSVRow{Belegnummer: beleg, Rechnungsdatum: datum, Jahr: jahr, Monat: monat, Bezahldatum: bezahlt,
+			Ausgangsrechnung: true, BetragNetto: netto, Bruttobetrag: netto, TaxLines: []TaxLine{{Netto: netto}}})
+	}
+	add("2025-0001", "10.06.2025", "2025", "06", "20.06.2025", 24000)
+	add("2025-0002", "15.12.2025", "2025", "12", "10.01.2026", 3000) // paid in 2026
+	add("2026-0001", "15.01.2026", "2026", "01", "20.01.2026", 40000)
+	add("2026-0002", "20.03.2026", "2026", "03", "30.03.2026", 50000)
+	add("2026-0003", "05.03.2026", "2026", "03", "02.04.2026", 5000)
+	add("2026-0004", "02.05.2026", "2026", "05", "06.05.2026", 8000)
+	add("2026-0005", "10.06.2026", "2026", "06", "", 9000)                   // unpaid
+	rows = append(rows, CSVRow{Jahr: "2026", Monat: "02", BetragNetto: 900}) // incoming
+	// Sale of a fixed asset: outside the Gesamtumsatz.
+	rows = append(rows, CSVRow{Belegnummer: "2026-0006", Rechnungsdatum: "01.02.2026", Jahr: "2026", Monat: "02", Bezahldatum: "01.02.2026",
+		Ausgangsrechnung: true, BetragNetto: 7000, Bruttobetrag: 7000, TaxLines: []TaxLine{{Netto: 7000}},
+		Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 7000, Soll: true}, {Konto: 4849, Betrag: 7000}}}})
+
+	rules := &BookingRules{AnlagenerloesKonten: []int{4845, 4849}}
+	zahlungen := func(r CSVRow) []Zahlung { return Zahlungen(r, nil) }
+	s := ComputeKleinunternehmer(rows, zahlungen, rules, "2026")
+	if s.UmsatzVorjahr != 24000 || s.VorjahrUeberschritten() {
+		t.Errorf("Vorjahr = %v", s.UmsatzVorjahr)
+	}
+	if s.Umsatz != 106000 || s.Monate[0] != 43000 || s.Monate[1] != 0 || s.Monate[2] != 50000 || s.Monate[3] != 5000 {
+		t.Errorf("Umsatz = %v, Monate = %v", s.Umsatz, s.Monate)
+	}
+	// In payment order 2026-0004 passes 100.000, not 2026-0002.
+	if s.GrenzeUeberschrittenMit != "2026-0004" || s.Zulaessig() {
+		t.Errorf("Grenze = %q", s.GrenzeUeberschrittenMit)
+	}
+
+	vorjahr := ComputeKleinunternehmer(append(rows, CSVRow{Belegnummer: "2025-0003", Rechnungsdatum: "01.12.2025", Jahr: "2025", Monat: "12",
+		Bezahldatum: "05.12.2025", Ausgangsrechnung: true, BetragNetto: 1500, Bruttobetrag: 1500}), zahlungen, rules, "2026")
+	if !vorjahr.VorjahrUeberschritten() {
+		t.Errorf("Vorjahr 25.500 = %v", vorjahr.UmsatzVorjahr)
+	}
+}
+
+func TestComputeKleinunternehmerTeilzahlungStorno(t *testing.T) {
+	orig := CSVRow{Belegnummer: "2026-0001", Rechnungsdatum: "10.03.2026", Jahr: "2026", Monat: "03", Teilzahlung: true,
+		Ausgangsrechnung: true, BetragNetto: 1000, Bruttobetrag: 1000}
+	storno := orig
+	storno.Belegnummer = "2026-0002"
+	storno.Rechnungsdatum = "20.05.2026"
+	storno.Monat = "05"
+	storno.Teilzahlung = false
+	storno.BetragNetto, storno.Bruttobetrag = -1000, -1000
+	storno.StornoZu = orig.StornoKey()
+	zahlungen := func(r CSVRow) []Zahlung {
+		if r.Belegnummer != orig.Belegnummer {
+			return nil
+		}
+		return []Zahlung{{Datum: "15.03.2026", Betrag: 400}, {Datum: "15.04.2026", Betrag: 200}}
+	}
+	s := ComputeKleinunternehmer([]CSVRow{orig, storno}, zahlungen, nil, "2026")
+	if s.Monate[2] != 400 || s.Monate[3] != 200 || s.Monate[4] != -600 || s.Umsatz != 0 {
+		t.Errorf("Monate = %v, Umsatz = %v", s.Monate, s.Umsatz)
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kunde is a customer of the invoice writer: the address plus the data
+// that recurs on every invoice to it.
+type Kunde struct {
+	Firmendaten
+	UStID            string `json:"ust_id,omitempty"`
+	Kaeuferreferenz  string `json:"kaeuferreferenz,omitempty"` // Leitweg-ID for public customers
+	ZahlungszielTage int    `json:"zahlungsziel_tage,omitempty"`
+}
+
+// KundenStore persists the customers invoiced from BuchISY, so the invoice
+// writer and the e-invoice export can prefill the address.
+//
+// File: <configDir>/kunden.json
+// Schema: map[NormalizeCompanyName(name)]Kunde
+type KundenStore struct {
+	filePath string
+	kunden   map[string]Kunde
+}
+
+// NewKundenStore creates a store backed by kunden.json in configDir.
+func NewKundenStore(configDir string) *KundenStore {
+	return &KundenStore{
+		filePath: filepath.Join(configDir, "kunden.json"),
+		kunden:   make(map[string]Kunde),
+	}
+}
+
+// Load reads the customers from disk; a missing file is not an error.
+func (s *KundenStore) Load() error {
+	if _, err := os.Stat(s.filePath); os.IsNotExist(err) {
+		return nil
+	}
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read customers: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.kunden); err != nil {
+		return fmt.Errorf("failed to parse customers: %w", err)
+	}
+	return nil
+}
+
+// Save writes the customers to disk.
+func (s *KundenStore) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s.kunden, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal customers: %w", err)
+	}
+	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write customers: %w", err)
+	}
+	return nil
+}
+
+// Get returns the customer stored under name (normalized).
+func (s *KundenStore) Get(name string) (Kunde, bool) {
+	k, ok := s.kunden[NormalizeCompanyName(name)]
+	return k, ok
+}
+
+// Set stores k under its name; a customer without a name is ignored.
+func (s *KundenStore) Set(k Kunde) {
+	if strings.TrimSpace(k.Name) == "" {
+		return
+	}
+	s.kunden[NormalizeCompanyName(k.Name)] = k
+}
+
+// Namen returns the display names of all customers, sorted.
+func (s *KundenStore) Namen() []string {
+	out := make([]string, 0, len(s.kunden))
+	for _, k := range s.kunden {
+		out = append(out, k.Name)
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i]) < strings.ToLower(out[j]) })
+	return out
+}
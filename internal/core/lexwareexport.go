@@ -12,7 +12,7 @@ func BuildLexwareCSV(rows []CSVRow) ([]byte, int, int) {
 	b.WriteString("Datum;Belegnr;Buchungstext;Betrag;Sollkonto;Habenkonto\r\n")
 	exported, skipped := 0, 0
 	for _, r := range rows {
-		base, counters, ok := r.Buchung.PaymentAndCounters(r.Ausgangsrechnung)
+		base, counters, ok := r.ExportSplit()
 		if !r.Buchung.Balanced() || !ok {
 			skipped++
 			continue
@@ -26,6 +26,7 @@ func BuildLexwareCSV(rows []CSVRow) ([]byte, int, int) {
 		}
 		beleg := lexClean(belegRef)
 		for _, e := range counters {
+			e = e.Unsigned()
 			soll, haben := e.Konto, base.Konto
 			if !e.Soll {
 				soll, haben = base.Konto, e.Konto
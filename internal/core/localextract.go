@@ -79,6 +79,7 @@ func (e *LocalExtractor) Extract(text string) (Meta, float64, error) {
 	if total > 0 {
 		confidence = float64(matched) / float64(total)
 	}
+	SetProvenance(&meta, SourceLocal, ConfidenceLocal)
 
 	return meta, confidence, nil
 }
@@ -0,0 +1,544 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ErrNoMailReceipts is returned by WriteMailImport for an e-mail without a
+// PDF, XML or image attachment.
+var ErrNoMailReceipts = errors.New("e-mail has no receipt attachment")
+
+// MailAttachment is a decoded file attached to an e-mail.
+type MailAttachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// MailMessage is the part of an e-mail BuchISY uses for receipt capture.
+type MailMessage struct {
+	MessageID   string
+	From        string // display name ("" when the header has none)
+	FromAddr    string // lower-case address
+	Subject     string
+	Date        time.Time
+	Body        string // plain text; HTML-only mails are converted
+	Attachments []MailAttachment
+}
+
+var mailWordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// charsetReader converts a non-UTF-8 header or body charset (ISO-8859-15,
+// Windows-1252, …) to UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// ParseMail reads an RFC 5322 message (.eml, one Maildir file or one mbox
+// entry) and decodes its sender, subject, text body and attachments. An
+// attached e-mail (message/rfc822, e.g. a forwarded invoice) contributes its
+// attachments.
+func ParseMail(r io.Reader) (MailMessage, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return MailMessage{}, fmt.Errorf("failed to parse e-mail: %w", err)
+	}
+	m := MailMessage{
+		MessageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>"),
+		Subject:   decodeMailHeader(msg.Header.Get("Subject")),
+	}
+	from := msg.Header.Get("From")
+	parser := mail.AddressParser{WordDecoder: mailWordDecoder}
+	if addr, err := parser.Parse(from); err == nil {
+		m.From, m.FromAddr = strings.TrimSpace(addr.Name), strings.ToLower(addr.Address)
+	} else {
+		m.From = decodeMailHeader(from)
+	}
+	if d, err := msg.Header.Date(); err == nil {
+		m.Date = d
+	}
+	var htmlBody string
+	walkMailPart(textproto.MIMEHeader(msg.Header), msg.Body, &m, &htmlBody, 0)
+	if m.Body == "" && htmlBody != "" {
+		m.Body = htmlToText(htmlBody)
+	}
+	m.Body = strings.TrimSpace(m.Body)
+	return m, nil
+}
+
+func decodeMailHeader(s string) string {
+	if d, err := mailWordDecoder.DecodeHeader(s); err == nil {
+		s = d
+	}
+	return strings.TrimSpace(s)
+}
+
+// walkMailPart decodes one MIME part into m, descending into multiparts.
+func walkMailPart(h textproto.MIMEHeader, body io.Reader, m *MailMessage, htmlBody *string, depth int) {
+	ct, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || ct == "" {
+		ct, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(ct, "multipart/") {
+		if depth > 10 || params["boundary"] == "" {
+			return
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err != nil {
+				return
+			}
+			walkMailPart(p.Header, p, m, htmlBody, depth+1)
+		}
+	}
+
+	data, err := io.ReadAll(transferDecoder(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return
+	}
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	name := dparams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	name = decodeMailHeader(name)
+
+	switch {
+	case ct == "message/rfc822":
+		if inner, err := ParseMail(bytes.NewReader(data)); err == nil {
+			m.Attachments = append(m.Attachments, inner.Attachments...)
+		}
+	case name != "" || disposition == "attachment":
+		m.Attachments = append(m.Attachments, MailAttachment{Name: name, ContentType: ct, Data: data})
+	case ct == "text/plain":
+		if m.Body == "" {
+			m.Body = decodeCharset(data, params["charset"])
+		}
+	case ct == "text/html":
+		if *htmlBody == "" {
+			*htmlBody = decodeCharset(data, params["charset"])
+		}
+	}
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+func decodeCharset(data []byte, charset string) string {
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "us-ascii") {
+		return string(data)
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return string(data)
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+var (
+	htmlDropPattern  = regexp.MustCompile(`(?is)<(style|script|head)\b.*?</(style|script|head)>`)
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|li|h[1-6]|table)>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	blankRunPattern  = regexp.MustCompile(`\n\s*\n(\s*\n)+`)
+)
+
+// htmlToText turns an HTML mail body into readable plain text.
+func htmlToText(s string) string {
+	s = htmlDropPattern.ReplaceAllString(s, "")
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTagPattern.ReplaceAllString(s, ""))
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.Join(strings.Fields(l), " ")
+	}
+	return blankRunPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
+
+// SplitMbox splits an mbox file into its messages. A message starts at a
+// "From " line at the top of the file or after an empty line; ">From "
+// quoting (mboxrd) is undone.
+func SplitMbox(data []byte) [][]byte {
+	var out [][]byte
+	var cur *bytes.Buffer
+	prevBlank := true
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if prevBlank && strings.HasPrefix(line, "From ") {
+			if cur != nil {
+				out = append(out, bytes.TrimRight(cur.Bytes(), "\r\n"))
+			}
+			cur = &bytes.Buffer{}
+			prevBlank = false
+			continue
+		}
+		prevBlank = strings.TrimRight(line, "\r") == ""
+		if cur == nil {
+			continue // garbage before the first separator
+		}
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") && strings.HasPrefix(line, ">") {
+			line = line[1:]
+		}
+		cur.WriteString(line)
+		cur.WriteByte('\n')
+	}
+	if cur != nil {
+		out = append(out, bytes.TrimRight(cur.Bytes(), "\r\n"))
+	}
+	return out
+}
+
+// IsMailFile reports whether name is an e-mail export BuchISY can import:
+// a single message (.eml) or an mbox file (.mbox).
+func IsMailFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".eml", ".mbox":
+		return true
+	}
+	return false
+}
+
+// ReadMailFile returns the raw messages of an .eml, a Maildir file or an
+// mbox file (detected by its leading "From " line).
+func ReadMailFile(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte("From ")) {
+		return SplitMbox(data), nil
+	}
+	return [][]byte{data}, nil
+}
+
+// MailSources lists the files to import from a watched mail folder: the
+// message files of a Maildir (new/ and cur/), or the .eml and .mbox files
+// of a plain folder. Sorted for a stable import order.
+func MailSources(folder string) ([]string, error) {
+	var out []string
+	if isDir(filepath.Join(folder, "new")) && isDir(filepath.Join(folder, "cur")) {
+		for _, sub := range []string{"new", "cur"} {
+			entries, err := os.ReadDir(filepath.Join(folder, sub))
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+					out = append(out, filepath.Join(folder, sub, e.Name()))
+				}
+			}
+		}
+	} else {
+		entries, err := os.ReadDir(folder)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && IsMailFile(e.Name()) {
+				out = append(out, filepath.Join(folder, e.Name()))
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// MailImportKey identifies a message across imports: its Message-ID, or a
+// SHA-256 of the raw message when the header is missing.
+func MailImportKey(raw []byte, m MailMessage) string {
+	if m.MessageID != "" {
+		return m.MessageID
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// MailImport is an e-mail unpacked for the normal capture flow: Main is the
+// receipt to extract, Attachments the other receipt files plus the mail text.
+type MailImport struct {
+	Main        string
+	Attachments []string
+	Hint        MailSenderHint
+	Kommentar   string // prefill for the comment field
+}
+
+// mailReceiptExt returns the extension under which an attachment is kept,
+// "" when it is not a receipt (PDF, XML or image).
+func mailReceiptExt(a MailAttachment) string {
+	ext := strings.ToLower(filepath.Ext(a.Name))
+	if IsPDF(a.Name) || IsXML(a.Name) || ImageMediaType(a.Name) != "" {
+		return ext
+	}
+	if ext == ".tif" || ext == ".tiff" || ext == ".heic" {
+		return ext
+	}
+	switch a.ContentType {
+	case "application/pdf":
+		return ".pdf"
+	case "application/xml", "text/xml":
+		return ".xml"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	}
+	return ""
+}
+
+// WriteMailImport writes the receipt attachments of m and a text file with
+// the mail (sender, date, subject, body) into dir. The main receipt is an
+// e-invoice XML when one is attached, else the first PDF, else the first
+// image. Returns ErrNoMailReceipts when nothing can be captured.
+func WriteMailImport(m MailMessage, dir string) (MailImport, error) {
+	var xmls, pdfs, images []string
+	used := map[string]bool{}
+	for i, a := range m.Attachments {
+		ext := mailReceiptExt(a)
+		if ext == "" {
+			continue
+		}
+		base := SanitizeFilename(strings.TrimSuffix(a.Name, filepath.Ext(a.Name)))
+		if base == "" {
+			base = fmt.Sprintf("Anhang%d", i+1)
+		}
+		name := base + ext
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s_%d%s", base, n, ext)
+		}
+		used[strings.ToLower(name)] = true
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, a.Data, 0o644); err != nil {
+			return MailImport{}, fmt.Errorf("failed to write attachment: %w", err)
+		}
+		switch {
+		case ext == ".xml":
+			xmls = append(xmls, path)
+		case ext == ".pdf":
+			pdfs = append(pdfs, path)
+		default:
+			images = append(images, path)
+		}
+	}
+
+	var files []string
+	ex := NewEInvoiceExtractor()
+	for _, x := range xmls {
+		if _, ok := ex.DetectFormat(x); ok {
+			files = append(files, x)
+		}
+	}
+	files = append(files, pdfs...)
+	for _, x := range xmls {
+		if !containsString(files, x) {
+			files = append(files, x)
+		}
+	}
+	files = append(files, images...)
+	if len(files) == 0 {
+		return MailImport{}, ErrNoMailReceipts
+	}
+
+	imp := MailImport{
+		Main:        files[0],
+		Attachments: files[1:],
+		Hint:        MailSenderHint{Name: m.From, Address: m.FromAddr},
+		Kommentar:   mailComment(m),
+	}
+	textPath := filepath.Join(dir, "E-Mail.txt")
+	if err := os.WriteFile(textPath, []byte(mailText(m)), 0o644); err != nil {
+		return MailImport{}, fmt.Errorf("failed to write mail text: %w", err)
+	}
+	imp.Attachments = append(imp.Attachments, textPath)
+	return imp, nil
+}
+
+// mailComment is the one-line comment prefill: sender and subject.
+func mailComment(m MailMessage) string {
+	from := m.From
+	if from == "" {
+		from = m.FromAddr
+	}
+	c := "E-Mail von " + from
+	if m.Subject != "" {
+		c += ": " + m.Subject
+	}
+	return c
+}
+
+// mailText renders the mail as the archived text attachment.
+func mailText(m MailMessage) string {
+	var b strings.Builder
+	from := m.FromAddr
+	if m.From != "" {
+		from = fmt.Sprintf("%s <%s>", m.From, m.FromAddr)
+	}
+	fmt.Fprintf(&b, "Von: %s\n", from)
+	if !m.Date.IsZero() {
+		fmt.Fprintf(&b, "Datum: %s\n", m.Date.Format("02.01.2006 15:04"))
+	}
+	fmt.Fprintf(&b, "Betreff: %s\n", m.Subject)
+	if m.MessageID != "" {
+		fmt.Fprintf(&b, "Message-ID: <%s>\n", m.MessageID)
+	}
+	b.WriteString("\n")
+	b.WriteString(m.Body)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// MailSenderHint is the sender of an imported e-mail, used to suggest the
+// supplier and its account when the receipt itself does not give them away.
+type MailSenderHint struct {
+	Name    string
+	Address string
+}
+
+// freemailDomains are shared by unrelated senders; their domain says
+// nothing about the supplier.
+var freemailDomains = map[string]bool{
+	"gmail.com": true, "googlemail.com": true, "gmx.de": true, "gmx.net": true,
+	"web.de": true, "t-online.de": true, "outlook.com": true, "hotmail.com": true,
+	"live.com": true, "yahoo.com": true, "yahoo.de": true, "icloud.com": true,
+	"me.com": true, "posteo.de": true, "mailbox.org": true, "freenet.de": true,
+}
+
+// CompanyMapKeys returns the CompanyAccountMap keys of the sender, most
+// specific first: the address, then "@domain" unless it is a freemail
+// domain.
+func (h MailSenderHint) CompanyMapKeys() []string {
+	addr := strings.ToLower(strings.TrimSpace(h.Address))
+	at := strings.LastIndex(addr, "@")
+	if at <= 0 {
+		return nil
+	}
+	keys := []string{addr}
+	if domain := addr[at+1:]; domain != "" && !freemailDomains[domain] {
+		keys = append(keys, "@"+domain)
+	}
+	return keys
+}
+
+// Supplier returns the sender's display name as a supplier fallback, ""
+// for technical senders such as "noreply" or "Rechnung".
+func (h MailSenderHint) Supplier() string {
+	name := strings.TrimSpace(h.Name)
+	switch strings.ToLower(name) {
+	case "", "noreply", "no-reply", "no reply", "rechnung", "rechnungen", "invoice", "invoices", "billing", "buchhaltung", "service", "info":
+		return ""
+	}
+	if strings.Contains(name, "@") {
+		return ""
+	}
+	return name
+}
+
+// SuggestAccountForSender returns the account remembered for the sender's
+// address or domain.
+func SuggestAccountForSender(cam *CompanyAccountMap, h MailSenderHint) (int, bool) {
+	for _, k := range h.CompanyMapKeys() {
+		if code, ok := cam.Get(k); ok {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// RememberSender stores account for the sender's address and domain (the
+// caller saves the map).
+func RememberSender(cam *CompanyAccountMap, h MailSenderHint, account int) {
+	for _, k := range h.CompanyMapKeys() {
+		cam.Set(k, account)
+	}
+}
+
+// MailImportLog remembers which messages of the watched mail folder were
+// already imported, so a restart does not offer them again.
+type MailImportLog struct {
+	path     string
+	imported map[string]string // MailImportKey -> import date YYYY-MM-DD
+}
+
+// NewMailImportLog creates a log rooted at configDir.
+func NewMailImportLog(configDir string) *MailImportLog {
+	return &MailImportLog{
+		path:     filepath.Join(configDir, "mail_imported.json"),
+		imported: map[string]string{},
+	}
+}
+
+// Load reads the persisted log (a missing file is not an error).
+func (l *MailImportLog) Load() error {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil // no file yet
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("failed to parse mail import log: %w", err)
+	}
+	l.imported = m
+	return nil
+}
+
+// Has reports whether the message with key was imported before.
+func (l *MailImportLog) Has(key string) bool {
+	_, ok := l.imported[key]
+	return ok
+}
+
+// Add records key as imported and persists the log.
+func (l *MailImportLog) Add(key string) error {
+	l.imported[key] = time.Now().Format("2006-01-02")
+	data, err := json.MarshalIndent(l.imported, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save mail import log: %w", err)
+	}
+	return nil
+}
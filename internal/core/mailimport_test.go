@@ -0,0 +1,191 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testInvoiceMail = "From: =?utf-8?q?Druckerei_M=C3=BCller?= <Rechnung@Druckerei-Mueller.de>\r\n" +
+	"To: buchhaltung@example.com\r\n" +
+	"Subject: =?iso-8859-1?q?Ihre_Rechnung_f=FCr_M=E4rz?=\r\n" +
+	"Date: Tue, 03 Mar 2026 09:15:00 +0100\r\n" +
+	"Message-ID: <abc.123@druckerei-mueller.de>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"XX\"\r\n" +
+	"\r\n" +
+	"--XX\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"YY\"\r\n" +
+	"\r\n" +
+	"--YY\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Anbei die Rechnung f=FCr M=E4rz.\r\n" +
+	"--YY\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Anbei die Rechnung</p>\r\n" +
+	"--YY--\r\n" +
+	"--XX\r\n" +
+	"Content-Type: application/pdf; name=\"RE-2026-031.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"RE-2026-031.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--XX\r\n" +
+	"Content-Type: application/octet-stream\r\n" +
+	"Content-Disposition: attachment; filename=\"AGB.docx\"\r\n" +
+	"\r\n" +
+	"docx\r\n" +
+	"--XX\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Disposition: inline; filename=\"Scan.png\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw==\r\n" +
+	"--XX--\r\n"
+
+func TestParseMail(t *testing.T) {
+	m, err := ParseMail(strings.NewReader(testInvoiceMail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.From != "Druckerei Müller" || m.FromAddr != "rechnung@druckerei-mueller.de" {
+		t.Errorf("from = %q <%s>", m.From, m.FromAddr)
+	}
+	if m.Subject != "Ihre Rechnung für März" || m.MessageID != "abc.123@druckerei-mueller.de" {
+		t.Errorf("subject/id = %q / %q", m.Subject, m.MessageID)
+	}
+	if m.Body != "Anbei die Rechnung für März." {
+		t.Errorf("body = %q (plain part preferred over HTML)", m.Body)
+	}
+	if m.Date.Day() != 3 {
+		t.Errorf("date = %v", m.Date)
+	}
+	if len(m.Attachments) != 3 || m.Attachments[0].Name != "RE-2026-031.pdf" || string(m.Attachments[0].Data) != "%PDF-1.4\n" {
+		t.Fatalf("attachments = %+v", m.Attachments)
+	}
+}
+
+func TestParseMail_HTMLOnlyAndForwarded(t *testing.T) {
+	inner := "From: shop@example.org\r\nSubject: Rechnung\r\nContent-Type: multipart/mixed; boundary=I\r\n\r\n" +
+		"--I\r\nContent-Type: application/pdf\r\nContent-Disposition: attachment; filename=r.pdf\r\n\r\n%PDF\r\n--I--\r\n"
+	raw := "From: Kollege <k@example.com>\r\nSubject: Fwd: Rechnung\r\nContent-Type: multipart/mixed; boundary=O\r\n\r\n" +
+		"--O\r\nContent-Type: text/html\r\n\r\n<html><head><style>p{}</style></head><body><p>Siehe&nbsp;Anhang</p><p>Gruß</p></body></html>\r\n" +
+		"--O\r\nContent-Type: message/rfc822\r\n\r\n" + inner + "\r\n--O--\r\n"
+	m, err := ParseMail(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Body != "Siehe Anhang\nGruß" {
+		t.Errorf("body = %q", m.Body)
+	}
+	if len(m.Attachments) != 1 || m.Attachments[0].Name != "r.pdf" {
+		t.Errorf("forwarded attachment missing: %+v", m.Attachments)
+	}
+}
+
+func TestSplitMbox(t *testing.T) {
+	mbox := "From a@b Mon Jan  1 00:00:00 2026\nSubject: eins\n\nText\n>From the past\n\n" +
+		"From c@d Mon Jan  1 00:00:00 2026\nSubject: zwei\n\nText\nFrom here on, body\n"
+	msgs := SplitMbox([]byte(mbox))
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages", len(msgs))
+	}
+	if !strings.Contains(string(msgs[0]), "\nFrom the past") || !strings.HasPrefix(string(msgs[1]), "Subject: zwei") {
+		t.Errorf("messages = %q", msgs)
+	}
+	if !strings.Contains(string(msgs[1]), "From here on") {
+		t.Error("a From line directly after a header/body line is body text")
+	}
+}
+
+func TestWriteMailImport(t *testing.T) {
+	m, _ := ParseMail(strings.NewReader(testInvoiceMail))
+	dir := t.TempDir()
+	imp, err := WriteMailImport(m, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(imp.Main) != "RE-2026-031.pdf" {
+		t.Errorf("main = %s", imp.Main)
+	}
+	var names []string
+	for _, a := range imp.Attachments {
+		names = append(names, filepath.Base(a))
+	}
+	if strings.Join(names, ",") != "Scan.png,E-Mail.txt" {
+		t.Errorf("attachments = %v (the .docx is not a receipt)", names)
+	}
+	text, _ := os.ReadFile(filepath.Join(dir, "E-Mail.txt"))
+	if !strings.Contains(string(text), "Von: Druckerei Müller <rechnung@druckerei-mueller.de>") || !strings.Contains(string(text), "Anbei die Rechnung") {
+		t.Errorf("mail text = %q", text)
+	}
+	if imp.Kommentar != "E-Mail von Druckerei Müller: Ihre Rechnung für März" {
+		t.Errorf("Kommentar = %q", imp.Kommentar)
+	}
+
+	_, err = WriteMailImport(MailMessage{Body: "Newsletter"}, t.TempDir())
+	if !errors.Is(err, ErrNoMailReceipts) {
+		t.Errorf("mail without receipts: %v", err)
+	}
+}
+
+func TestMailSenderHint(t *testing.T) {
+	cam := NewCompanyAccountMap(t.TempDir())
+	h := MailSenderHint{Name: "Druckerei Müller", Address: "Rechnung@Druckerei-Mueller.de"}
+	if got := h.CompanyMapKeys(); strings.Join(got, " ") != "rechnung@druckerei-mueller.de @druckerei-mueller.de" {
+		t.Errorf("keys = %v", got)
+	}
+	if _, ok := SuggestAccountForSender(cam, h); ok {
+		t.Error("nothing remembered yet")
+	}
+	RememberSender(cam, h, 6815)
+	other := MailSenderHint{Address: "mahnung@druckerei-mueller.de"}
+	if acc, ok := SuggestAccountForSender(cam, other); !ok || acc != 6815 {
+		t.Errorf("domain fallback = %d, %v", acc, ok)
+	}
+	gmail := MailSenderHint{Name: "noreply", Address: "someone@gmail.com"}
+	if keys := gmail.CompanyMapKeys(); len(keys) != 1 || gmail.Supplier() != "" {
+		t.Errorf("freemail keys = %v, supplier %q", keys, gmail.Supplier())
+	}
+	if h.Supplier() != "Druckerei Müller" {
+		t.Errorf("supplier = %q", h.Supplier())
+	}
+}
+
+func TestMailSourcesAndImportLog(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"new", "cur", "tmp"} {
+		_ = os.MkdirAll(filepath.Join(dir, sub), 0o755)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "new", "1700.M1.host"), []byte(testInvoiceMail), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "tmp", "partial"), []byte("x"), 0o644)
+	src, err := MailSources(dir)
+	if err != nil || len(src) != 1 || filepath.Base(src[0]) != "1700.M1.host" {
+		t.Fatalf("maildir sources = %v, %v", src, err)
+	}
+
+	plain := t.TempDir()
+	_ = os.WriteFile(filepath.Join(plain, "a.eml"), []byte(testInvoiceMail), 0o644)
+	_ = os.WriteFile(filepath.Join(plain, "b.pdf"), []byte("%PDF"), 0o644)
+	if src, _ := MailSources(plain); len(src) != 1 || filepath.Base(src[0]) != "a.eml" {
+		t.Errorf("plain sources = %v", src)
+	}
+
+	cfg := t.TempDir()
+	log := NewMailImportLog(cfg)
+	if err := log.Add("abc.123@druckerei-mueller.de"); err != nil {
+		t.Fatal(err)
+	}
+	log2 := NewMailImportLog(cfg)
+	if err := log2.Load(); err != nil || !log2.Has("abc.123@druckerei-mueller.de") || log2.Has("other") {
+		t.Errorf("log not persisted: %v", err)
+	}
+	if k := MailImportKey([]byte("raw"), MailMessage{}); !strings.HasPrefix(k, "sha256:") {
+		t.Errorf("key without Message-ID = %q", k)
+	}
+}
@@ -0,0 +1,280 @@
+package core
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Art of a filed return (Meldung).
+const (
+	MeldungUStVA = "UStVA"
+	MeldungZM    = "ZM"
+)
+
+// Status of a receipt in a MeldungAbweichung.
+const (
+	BelegNeu       = "neu"      // not part of the filed return
+	BelegEntfernt  = "entfernt" // part of the filed return, gone now
+	BelegGeaendert = "geändert" // part of both, with other values
+)
+
+// MeldungBeleg is what one receipt contributed to a return: per Kennzahl
+// (UStVA) or per ZMSchluessel (ZM).
+type MeldungBeleg struct {
+	Schluessel     string             `json:"schluessel"` // StornoKey
+	Belegnummer    string             `json:"belegnummer,omitempty"`
+	Auftraggeber   string             `json:"auftraggeber,omitempty"`
+	Rechnungsdatum string             `json:"rechnungsdatum,omitempty"`
+	Werte          map[string]float64 `json:"werte"`
+}
+
+// Meldung is the snapshot of a filed UStVA or ZM. Once stored it is never
+// changed; a berichtigte Meldung for the same period is a new snapshot with
+// the next Nr.
+type Meldung struct {
+	ID         int64
+	Art        string // MeldungUStVA or MeldungZM
+	Zeitraum   string // "YYYY-MM" or "YYYY-QN"
+	Nr         int    // 1 = Erstmeldung, 2… = Berichtigung
+	GemeldetAm string // DATETIME of the "gemeldet" action
+	UStVA      UStVAOfficial
+	ZM         ZM
+	Belege     []MeldungBeleg
+}
+
+// Berichtigung reports whether the snapshot is a berichtigte Meldung.
+func (m Meldung) Berichtigung() bool {
+	return m.Nr > 1
+}
+
+// Datum is the day the return was marked as filed, "DD.MM.YYYY" (the stored
+// timestamp is UTC).
+func (m Meldung) Datum() string {
+	t, err := time.Parse("2006-01-02 15:04:05", m.GemeldetAm)
+	if err != nil {
+		return m.GemeldetAm
+	}
+	return t.Local().Format("02.01.2006")
+}
+
+// ZMSchluessel is the key of a ZM line in MeldungBeleg.Werte.
+func ZMSchluessel(ustIdNr, art string) string {
+	return ustIdNr + " " + art
+}
+
+// NewUStVAMeldung builds the snapshot of the UStVA u for zeitraum from the
+// rows it was computed from (after IstRows; u may carry Kz 39).
+func NewUStVAMeldung(zeitraum string, u UStVAOfficial, rows []CSVRow, rules *BookingRules) Meldung {
+	idx := NewKennzahlIndex(rules.KennzahlKonten())
+	rcSatz := rules.schaetzRCSatz()
+	belege := map[string]*MeldungBeleg{}
+	var order []string
+	for _, r := range RowsEUR(rows) {
+		beitraege, _ := zeilenKennzahlen(r, idx, rcSatz)
+		for _, b := range beitraege {
+			meldungBeleg(belege, &order, r).Werte[b.Kz] += b.Betrag
+		}
+	}
+	return Meldung{Art: MeldungUStVA, Zeitraum: zeitraum, UStVA: u, Belege: meldungBelege(belege, order)}
+}
+
+// NewZMMeldung builds the snapshot of the ZM z for zeitraum from its rows.
+func NewZMMeldung(zeitraum string, z ZM, rows []CSVRow, rules *BookingRules) Meldung {
+	var idx KennzahlIndex
+	if rules != nil {
+		idx = NewKennzahlIndex(rules.KennzahlKonten())
+	}
+	belege := map[string]*MeldungBeleg{}
+	var order []string
+	for _, r := range RowsEUR(rows) {
+		if vat, art, ok := zmZeile(r, idx); ok {
+			meldungBeleg(belege, &order, r).Werte[ZMSchluessel(vat, art)] += SumNetto(r.TaxLines)
+		}
+	}
+	return Meldung{Art: MeldungZM, Zeitraum: zeitraum, ZM: z, Belege: meldungBelege(belege, order)}
+}
+
+// meldungBeleg returns the entry of r's receipt, adding it on first use. An
+// invoice paid in parts (IstRows) shares one entry.
+func meldungBeleg(belege map[string]*MeldungBeleg, order *[]string, r CSVRow) *MeldungBeleg {
+	key := r.StornoKey()
+	b, ok := belege[key]
+	if !ok {
+		b = &MeldungBeleg{Schluessel: key, Belegnummer: r.Belegnummer, Auftraggeber: r.Auftraggeber,
+			Rechnungsdatum: r.Rechnungsdatum, Werte: map[string]float64{}}
+		belege[key] = b
+		*order = append(*order, key)
+	}
+	return b
+}
+
+// meldungBelege returns the receipts in first-seen order, values round2'd,
+// those that contribute nothing dropped.
+func meldungBelege(belege map[string]*MeldungBeleg, order []string) []MeldungBeleg {
+	var out []MeldungBeleg
+	for _, key := range order {
+		b := belege[key]
+		for k, v := range b.Werte {
+			if v = round2(v); v == 0 {
+				delete(b.Werte, k)
+			} else {
+				b.Werte[k] = v
+			}
+		}
+		if len(b.Werte) > 0 {
+			out = append(out, *b)
+		}
+	}
+	return out
+}
+
+// MeldungWert names a key of a return of art: "Kz 81" and the Kennzahl's
+// name for the UStVA; the VAT-ID and the Art der Leistung for a ZM line;
+// "Kontrollsumme" for the ZM key "summe".
+func MeldungWert(art, key string) (kurz, lang string) {
+	if art != MeldungZM {
+		return "Kz " + key, UStVABezeichnung(key)
+	}
+	if key == "summe" {
+		return "Kontrollsumme", ""
+	}
+	vat, a, _ := strings.Cut(key, " ")
+	return vat, ZMArtBezeichnung(a)
+}
+
+// WertAbweichung is a Kennzahl (UStVA) or ZM line (ZMSchluessel; "summe" =
+// Kontrollsumme) whose current value differs from the filed one.
+type WertAbweichung struct {
+	Schluessel string
+	Gemeldet   float64
+	Aktuell    float64
+}
+
+// Differenz is Aktuell − Gemeldet.
+func (w WertAbweichung) Differenz() float64 {
+	return round2(w.Aktuell - w.Gemeldet)
+}
+
+// BelegAbweichung is a receipt whose contribution differs from the filed one.
+type BelegAbweichung struct {
+	Beleg    MeldungBeleg // current data; the filed one for BelegEntfernt
+	Status   string       // BelegNeu, BelegEntfernt or BelegGeaendert
+	Gemeldet map[string]float64
+	Aktuell  map[string]float64
+}
+
+// Differenz is the receipt's change per key, non-zero keys only.
+func (b BelegAbweichung) Differenz() map[string]float64 {
+	out := map[string]float64{}
+	for k, v := range b.Aktuell {
+		out[k] = v
+	}
+	for k, v := range b.Gemeldet {
+		out[k] -= v
+	}
+	for k, v := range out {
+		if v = round2(v); v == 0 {
+			delete(out, k)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// MeldungAbweichung compares a filed return with the current one.
+type MeldungAbweichung struct {
+	Gemeldet Meldung
+	Aktuell  Meldung
+	Werte    []WertAbweichung  // UStVA: in XML order; ZM: lines, then "summe"
+	Belege   []BelegAbweichung // current receipts first, then the removed ones
+}
+
+// Abweichend reports whether the return would be filed with other values:
+// a berichtigte Meldung is due.
+func (a MeldungAbweichung) Abweichend() bool {
+	return len(a.Werte) > 0
+}
+
+// VergleicheMeldung compares the filed snapshot with the current one (built
+// the same way from the current rows) value by value and receipt by receipt.
+func VergleicheMeldung(gemeldet, aktuell Meldung) MeldungAbweichung {
+	a := MeldungAbweichung{Gemeldet: gemeldet, Aktuell: aktuell}
+	add := func(key string, alt, neu float64) {
+		if math.Abs(neu-alt) >= 0.005 {
+			a.Werte = append(a.Werte, WertAbweichung{key, alt, neu})
+		}
+	}
+	if gemeldet.Art == MeldungZM {
+		zeilen := func(z ZM) map[string]float64 {
+			m := map[string]float64{}
+			for _, l := range z.Zeilen {
+				m[ZMSchluessel(l.UStIdNr, l.Leistungsart())] = l.Netto
+			}
+			return m
+		}
+		alt, neu := zeilen(gemeldet.ZM), zeilen(aktuell.ZM)
+		for _, key := range zmSchluessel(alt, neu) {
+			add(key, alt[key], neu[key])
+		}
+		add("summe", gemeldet.ZM.Kontrollsumme, aktuell.ZM.Kontrollsumme)
+	} else {
+		for _, z := range ustvaXMLZeilen {
+			add(z.kz, gemeldet.UStVA.Wert(z.kz), aktuell.UStVA.Wert(z.kz))
+		}
+	}
+
+	alt := map[string]MeldungBeleg{}
+	for _, b := range gemeldet.Belege {
+		alt[b.Schluessel] = b
+	}
+	seen := map[string]bool{}
+	for _, b := range aktuell.Belege {
+		seen[b.Schluessel] = true
+		d := BelegAbweichung{Beleg: b, Status: BelegNeu, Aktuell: b.Werte}
+		if g, ok := alt[b.Schluessel]; ok {
+			d.Status, d.Gemeldet = BelegGeaendert, g.Werte
+		}
+		if len(d.Differenz()) > 0 {
+			a.Belege = append(a.Belege, d)
+		}
+	}
+	for _, b := range gemeldet.Belege {
+		if !seen[b.Schluessel] {
+			a.Belege = append(a.Belege, BelegAbweichung{Beleg: b, Status: BelegEntfernt, Gemeldet: b.Werte})
+		}
+	}
+	return a
+}
+
+// zmSchluessel returns the keys of both maps, sorted.
+func zmSchluessel(a, b map[string]float64) []string {
+	set := map[string]bool{}
+	for k := range a {
+		set[k] = true
+	}
+	for k := range b {
+		set[k] = true
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LetzteMeldung returns the snapshot with the highest Nr for art and
+// zeitraum among ms.
+func LetzteMeldung(ms []Meldung, art, zeitraum string) (Meldung, bool) {
+	var last Meldung
+	found := false
+	for _, m := range ms {
+		if m.Art == art && m.Zeitraum == zeitraum && (!found || m.Nr > last.Nr) {
+			last, found = m, true
+		}
+	}
+	return last, found
+}
@@ -0,0 +1,83 @@
+package core
+
+import "testing"
+
+func TestVergleicheMeldungUStVA(t *testing.T) {
+	rules, _ := ParseBookingRules([]byte(`{"regeln":[{"kategorie":"reverse_charge","rc_satz":19}]}`))
+	sale := func(nr string, netto float64) CSVRow {
+		return CSVRow{Belegnummer: nr, Auftraggeber: "Kunde", Rechnungsdatum: "10.01.2026", Ausgangsrechnung: true,
+			TaxLines: []TaxLine{{Netto: netto, SatzProzent: 19, MwStBetrag: round2(netto * 0.19)}}}
+	}
+	einkauf := CSVRow{Belegnummer: "2026-0003", Rechnungsdatum: "12.01.2026", TaxLines: []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}}}
+	rows := []CSVRow{sale("2026-0001", 1000), sale("2026-0002", 500), einkauf}
+	gemeldet := NewUStVAMeldung("2026-01", ComputeUStVAOfficial(rows, rules), rows, rules)
+	if len(gemeldet.Belege) != 3 || gemeldet.Belege[0].Werte["81"] != 1000 || gemeldet.Belege[2].Werte["66"] != 19 {
+		t.Fatalf("Belege = %+v", gemeldet.Belege)
+	}
+	if a := VergleicheMeldung(gemeldet, gemeldet); a.Abweichend() || len(a.Belege) != 0 {
+		t.Errorf("unchanged return differs: %+v", a)
+	}
+
+	// Late receipt, a changed sale, a deleted purchase.
+	neu := []CSVRow{sale("2026-0001", 1000), sale("2026-0002", 600),
+		{Belegnummer: "2026-0010", Rechnungsdatum: "28.01.2026", TaxLines: []TaxLine{{Netto: 50, SatzProzent: 19, MwStBetrag: 9.5}}}}
+	a := VergleicheMeldung(gemeldet, NewUStVAMeldung("2026-01", ComputeUStVAOfficial(neu, rules), neu, rules))
+	if !a.Abweichend() {
+		t.Fatal("changed return not detected")
+	}
+	werte := map[string]float64{}
+	for _, w := range a.Werte {
+		werte[w.Schluessel] = w.Differenz()
+	}
+	// Kz 81 +100, Kz 66 −9.50, Kz 83 +19 + 9.50
+	if len(werte) != 3 || werte["81"] != 100 || werte["66"] != -9.5 || werte["83"] != 28.5 {
+		t.Errorf("Werte = %+v", a.Werte)
+	}
+	status := map[string]string{}
+	for _, b := range a.Belege {
+		status[b.Beleg.Schluessel] = b.Status
+	}
+	if len(status) != 3 || status["2026-0002"] != BelegGeaendert || status["2026-0010"] != BelegNeu || status["2026-0003"] != BelegEntfernt {
+		t.Errorf("Belege = %+v", a.Belege)
+	}
+	if d := a.Belege[0].Differenz(); len(d) != 1 || d["81"] != 100 {
+		t.Errorf("Differenz 2026-0002 = %v", d)
+	}
+}
+
+func TestVergleicheMeldungZM(t *testing.T) {
+	eu := func(nr string, netto float64) CSVRow {
+		return CSVRow{Belegnummer: nr, Ausgangsrechnung: true, VATID: "ATU12345678", TaxLines: []TaxLine{{Netto: netto}}}
+	}
+	rows := []CSVRow{eu("2026-0001", 1000)}
+	gemeldet := NewZMMeldung("2026-Q1", ComputeZM(rows), rows, nil)
+	if len(gemeldet.Belege) != 1 || gemeldet.Belege[0].Werte[ZMSchluessel("ATU12345678", ZMArtSonstige)] != 1000 {
+		t.Fatalf("Belege = %+v", gemeldet.Belege)
+	}
+	rows = append(rows, eu("2026-0005", 250))
+	a := VergleicheMeldung(gemeldet, NewZMMeldung("2026-Q1", ComputeZM(rows), rows, nil))
+	if !a.Abweichend() || len(a.Werte) != 2 || a.Werte[0].Schluessel != "ATU12345678 S" || a.Werte[1].Schluessel != "summe" ||
+		a.Werte[1].Differenz() != 250 || len(a.Belege) != 1 || a.Belege[0].Status != BelegNeu {
+		t.Errorf("ZM Abweichung = %+v", a)
+	}
+	if kurz, lang := MeldungWert(MeldungZM, "ATU12345678 S"); kurz != "ATU12345678" || lang != "Sonstige Leistung" {
+		t.Errorf("MeldungWert = %q, %q", kurz, lang)
+	}
+}
+
+func TestLetzteMeldung(t *testing.T) {
+	ms := []Meldung{
+		{Art: MeldungUStVA, Zeitraum: "2026-01", Nr: 1},
+		{Art: MeldungUStVA, Zeitraum: "2026-01", Nr: 2},
+		{Art: MeldungZM, Zeitraum: "2026-01", Nr: 3},
+	}
+	if m, ok := LetzteMeldung(ms, MeldungUStVA, "2026-01"); !ok || m.Nr != 2 || !m.Berichtigung() {
+		t.Errorf("LetzteMeldung = %+v, %v", m, ok)
+	}
+	if _, ok := LetzteMeldung(ms, MeldungUStVA, "2026-02"); ok {
+		t.Error("unfiled period found")
+	}
+	if d := (Meldung{GemeldetAm: "2026-02-10 09:30:00"}).Datum(); d != "10.02.2026" {
+		t.Errorf("Datum = %q", d)
+	}
+}
@@ -41,7 +41,8 @@ func agingBucket(days int) string {
 // ComputeOpenItems returns all open receivables (Forderungen) and payables
 // (Verbindlichkeiten) from rows, evaluated as of asOf.
 //
-// An item is OPEN when Bezahldatum == "" AND BuchungRef == "".
+// An item is OPEN when Bezahldatum == "" AND BuchungRef == "". A Storno and
+// the booking it reverses are never open: together they cancel out.
 // Open + Ausgangsrechnung == true  → Forderung  (receivable).
 // Open + Ausgangsrechnung == false → Verbindlichkeit (payable).
 // Betrag = Bruttobetrag. AgeDays = days from Rechnungsdatum to asOf (0 if
@@ -50,10 +51,14 @@ func agingBucket(days int) string {
 func ComputeOpenItems(rows []CSVRow, asOf time.Time) OpenItems {
 	rows = RowsEUR(rows)
 	var oi OpenItems
+	reversed := ReversedKeys(rows)
 	for _, r := range rows {
 		if strings.TrimSpace(r.Bezahldatum) != "" || strings.TrimSpace(r.BuchungRef) != "" {
 			continue
 		}
+		if r.IsStorno() || reversed[r.StornoKey()] {
+			continue
+		}
 
 		var ageDays int
 		if t, err := time.Parse("02.01.2006", strings.TrimSpace(r.Rechnungsdatum)); err == nil {
@@ -3,6 +3,7 @@ package core
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -113,6 +114,8 @@ func BuildCashYearOverviewPDF(summaries []MonthSummary, account string, year int
 
 // BuildBookingJournalPDF renders the booking journal: one row per Soll entry of
 // each balanced booking, against the payment account as counter-account.
+// Umbuchungen and EB-Werte get one row per entry against their
+// single-account side (the Saldenvortrag account for an EB-Wert).
 // All amounts are shown in EUR. Foreign-currency rows show the original currency
 // and gross amount as a suffix in the Auftraggeber column, e.g. "(USD 200,00)".
 func BuildBookingJournalPDF(rows []CSVRow, chart *ChartOfAccounts, title, company string) ([]byte, error) {
@@ -133,7 +136,45 @@ func BuildBookingJournalPDF(rows []CSVRow, chart *ChartOfAccounts, title, compan
 	rows = RowsEUR(rows)
 
 	var total float64
+	line := func(datum, beleg, text string, soll, haben int, betrag float64) {
+		pdfPageBreak(pdf, tr, headers, widths, 6)
+		cells := []struct {
+			w     float64
+			txt   string
+			align string
+		}{
+			{widths[0], datum, "L"},
+			{widths[1], truncate(beleg, 22), "L"},
+			{widths[2], text, "L"},
+			{widths[3], truncate(kontoLabelPDF(chart, soll), 32), "L"},
+			{widths[4], truncate(kontoLabelPDF(chart, haben), 32), "L"},
+			{widths[5], pdfAmount(betrag), "R"},
+		}
+		for _, c := range cells {
+			pdf.CellFormat(c.w, 6, tr(c.txt), "1", 0, c.align, false, 0, "")
+		}
+		pdf.Ln(6)
+		total += betrag
+	}
 	for i, r := range rows {
+		if r.Umbuchung || r.Eroeffnung {
+			base, counters, ok := r.ExportSplit()
+			if !r.Buchung.Balanced() || !ok {
+				continue
+			}
+			for _, e := range counters {
+				soll, haben := e.Konto, base.Konto
+				if !e.Soll {
+					soll, haben = base.Konto, e.Konto
+				}
+				text := "Umbuchung: " + r.Verwendungszweck
+				if r.Eroeffnung {
+					text = r.Verwendungszweck
+				}
+				line(r.Rechnungsdatum, r.Belegnummer, truncate(text, 40), soll, haben, e.Betrag)
+			}
+			continue
+		}
 		pay, ok := r.Buchung.PaymentEntry()
 		if !r.Buchung.Balanced() || !ok {
 			continue
@@ -144,25 +185,12 @@ func BuildBookingJournalPDF(rows []CSVRow, chart *ChartOfAccounts, title, compan
 			suffix := fmt.Sprintf(" (%s %s)", orig.waehrung, pdfAmount(orig.brutto))
 			auftraggeber = truncate(r.Auftraggeber, 27) + suffix
 		}
+		beleg := r.Rechnungsnummer
+		if r.IsStorno() {
+			beleg = "Storno " + r.StornoZu
+		}
 		for _, e := range r.Buchung.DebitEntries() {
-			pdfPageBreak(pdf, tr, headers, widths, 6)
-			cells := []struct {
-				w     float64
-				txt   string
-				align string
-			}{
-				{widths[0], r.Rechnungsdatum, "L"},
-				{widths[1], truncate(r.Rechnungsnummer, 22), "L"},
-				{widths[2], auftraggeber, "L"},
-				{widths[3], truncate(kontoLabelPDF(chart, e.Konto), 32), "L"},
-				{widths[4], truncate(kontoLabelPDF(chart, pay.Konto), 32), "L"},
-				{widths[5], pdfAmount(e.Betrag), "R"},
-			}
-			for _, c := range cells {
-				pdf.CellFormat(c.w, 6, tr(c.txt), "1", 0, c.align, false, 0, "")
-			}
-			pdf.Ln(6)
-			total += e.Betrag
+			line(r.Rechnungsdatum, beleg, auftraggeber, e.Konto, pay.Konto, e.Betrag)
 		}
 	}
 
@@ -293,9 +321,9 @@ func BuildInvoiceListPDF(rows []CSVRow, title, company string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// BuildUStVAPDF renders the official UStVA Kennzahlen (Kz 81/86/21/45/84/85/
-// 66/67/83) as a form — the document the user hands to the tax advisor. Only
-// non-zero Kennzahlen are shown; Kz 83 (Zahllast/Überschuss) is always shown.
+// BuildUStVAPDF renders the official UStVA Kennzahlen as a form — the
+// document the user hands to the tax advisor. Only non-zero Kennzahlen are
+// shown; Kz 83 (Zahllast/Überschuss) is always shown.
 func BuildUStVAPDF(u UStVAOfficial, title, company string) ([]byte, error) {
 	pdf, tr := newReportPDF(title, "P", company)
 	wKz, wLabel, wVal := 18.0, 122.0, 35.0
@@ -319,6 +347,13 @@ func BuildUStVAPDF(u UStVAOfficial, title, company string) ([]byte, error) {
 		pdf.SetFont("Arial", "", 9)
 	}
 
+	pdf.SetFont("Arial", "", 9)
+	if u.Ist {
+		pdf.CellFormat(0, 6, tr("Besteuerungsart: Ist-Versteuerung (§ 20 UStG) – Umsatzsteuer nach Zahlungseingang"), "", 1, "L", false, 0, "")
+	} else {
+		pdf.CellFormat(0, 6, tr("Besteuerungsart: Soll-Versteuerung"), "", 1, "L", false, 0, "")
+	}
+
 	section("A. Steuerpflichtige Umsätze")
 	if u.Kz81 != 0 {
 		row("Kz 81", "Umsätze 19 % (Bemessungsgrundlage)", u.Kz81, false)
@@ -328,10 +363,41 @@ func BuildUStVAPDF(u UStVAOfficial, title, company string) ([]byte, error) {
 		row("Kz 86", "Umsätze 7 % (Bemessungsgrundlage)", u.Kz86, false)
 		row("", "   davon Umsatzsteuer", u.USt86, false)
 	}
+	if u.Kz35 != 0 || u.Kz36 != 0 {
+		row("Kz 35", "Umsätze zu anderen Steuersätzen (Bemessungsgrundlage)", u.Kz35, false)
+		row("Kz 36", "   Steuer", u.Kz36, false)
+	}
+	if u.Kz41 != 0 || u.Kz44 != 0 || u.Kz43 != 0 {
+		section("B. Steuerfreie Umsätze mit Vorsteuerabzug")
+		if u.Kz41 != 0 {
+			row("Kz 41", "Innergem. Lieferungen an Abnehmer mit USt-IdNr.", u.Kz41, false)
+		}
+		if u.Kz44 != 0 {
+			row("Kz 44", "Innergem. Lieferungen neuer Fahrzeuge ohne USt-IdNr.", u.Kz44, false)
+		}
+		if u.Kz43 != 0 {
+			row("Kz 43", "Weitere steuerfreie Umsätze mit Vorsteuerabzug", u.Kz43, false)
+		}
+	}
+	if u.Kz89 != 0 || u.Kz93 != 0 {
+		section("C. Innergemeinschaftliche Erwerbe")
+		if u.Kz89 != 0 {
+			row("Kz 89", "Erwerbe 19 % (Bemessungsgrundlage)", u.Kz89, false)
+			row("", "   davon Umsatzsteuer", u.USt89, false)
+		}
+		if u.Kz93 != 0 {
+			row("Kz 93", "Erwerbe 7 % (Bemessungsgrundlage)", u.Kz93, false)
+			row("", "   davon Umsatzsteuer", u.USt93, false)
+		}
+	}
 	section("D. Leistungsempfänger als Steuerschuldner (§ 13b UStG)")
-	if u.Kz84 != 0 {
-		row("Kz 84", "§ 13b Bemessungsgrundlage", u.Kz84, false)
-		row("Kz 85", "§ 13b Steuer", u.Kz85, false)
+	if u.Kz46 != 0 || u.Kz47 != 0 {
+		row("Kz 46", "Sonstige Leistungen aus dem übrigen Gemeinschaftsgebiet", u.Kz46, false)
+		row("Kz 47", "   Steuer", u.Kz47, false)
+	}
+	if u.Kz84 != 0 || u.Kz85 != 0 {
+		row("Kz 84", "Andere Leistungen (Bemessungsgrundlage)", u.Kz84, false)
+		row("Kz 85", "   Steuer", u.Kz85, false)
 	}
 	section("E. Nicht steuerbare Umsätze")
 	if u.Kz21 != 0 {
@@ -344,10 +410,22 @@ func BuildUStVAPDF(u UStVAOfficial, title, company string) ([]byte, error) {
 	if u.Kz66 != 0 {
 		row("Kz 66", "Vorsteuer aus Rechnungen", u.Kz66, false)
 	}
+	if u.Kz61 != 0 {
+		row("Kz 61", "Vorsteuer aus innergem. Erwerb", u.Kz61, false)
+	}
+	if u.Kz62 != 0 {
+		row("Kz 62", "Entrichtete Einfuhrumsatzsteuer", u.Kz62, false)
+	}
 	if u.Kz67 != 0 {
 		row("Kz 67", "Vorsteuer aus § 13b-Leistungen", u.Kz67, false)
 	}
+	if u.Kz64 != 0 {
+		row("Kz 64", "Berichtigung des Vorsteuerabzugs (§ 15a UStG)", u.Kz64, false)
+	}
 	section("H. Verbleibende Vorauszahlung / Überschuss")
+	if u.Kz39 != 0 {
+		row("Kz 39", "Abzug der festgesetzten Sondervorauszahlung (Dauerfristverlängerung)", u.Kz39, false)
+	}
 	row("Kz 83", "Zahllast / Überschuss", u.Kz83, true)
 
 	var buf bytes.Buffer
@@ -357,8 +435,277 @@ func BuildUStVAPDF(u UStVAOfficial, title, company string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// BuildUStJahrPDF renders the annual VAT return (landscape): per line the
+// UStVA and annual-form Kennzahl, the annual value, the sum of the
+// Voranmeldungen and the difference; Vorauszahlungssoll and Abschlusszahlung;
+// the Voranmeldungen with their Kz 83; and the receipts dated in a locked
+// period but filed later (Nachbuchungen).
+func BuildUStJahrPDF(j UStJahreserklaerung, title, company string) ([]byte, error) {
+	pdf, tr := newReportPDF(title, "L", company)
+	pdf.SetFont("Arial", "", 9)
+	if j.Ist {
+		pdf.CellFormat(0, 6, tr("Besteuerungsart: Ist-Versteuerung (§ 20 UStG) – Umsatzsteuer nach Zahlungseingang"), "", 1, "L", false, 0, "")
+	} else {
+		pdf.CellFormat(0, 6, tr("Besteuerungsart: Soll-Versteuerung"), "", 1, "L", false, 0, "")
+	}
+
+	headers := []string{"UStVA", "Jahr", "Bezeichnung", "Jahreserklärung", "Summe Voranmeldungen", "Differenz"}
+	widths := []float64{16, 16, 130, 38, 42, 35}
+	pdfTableHeader(pdf, tr, headers, widths)
+	for _, z := range UStJahrZeilen {
+		wert, summe, diff := j.Erklaerung.Wert(z.Kz), j.Summe.Wert(z.Kz), j.Differenz(z.Kz)
+		if wert == 0 && summe == 0 && z.Kz != "83" {
+			continue
+		}
+		pdfPageBreak(pdf, tr, headers, widths, 6)
+		if z.Kz == "83" {
+			pdf.SetFont("Arial", "B", 9)
+		}
+		jahrKz := "—"
+		if z.JahrKz != "" {
+			jahrKz = "Kz " + z.JahrKz
+		}
+		pdf.CellFormat(widths[0], 6, tr("Kz "+z.Kz), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, tr(jahrKz), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(truncate(z.Bezeichnung, 75)), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 6, tr(pdfAmount(wert)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, tr(pdfAmount(summe)), "1", 0, "R", false, 0, "")
+		if diff != 0 {
+			pdf.SetFont("Arial", "B", 9)
+		}
+		pdf.CellFormat(widths[5], 6, tr(pdfAmount(diff)), "1", 0, "R", false, 0, "")
+		pdf.SetFont("Arial", "", 9)
+		pdf.Ln(6)
+	}
+	pdf.Ln(2)
+	pdf.SetFont("Arial", "", 9)
+	pdf.CellFormat(162, 6, tr("Vorauszahlungssoll (Summe Kz 83 der Voranmeldungen)"), "1", 0, "L", false, 0, "")
+	pdf.CellFormat(38, 6, tr(pdfAmount(j.Vorauszahlungssoll)), "1", 1, "R", false, 0, "")
+	pdf.SetFont("Arial", "B", 9)
+	abschluss := "Abschlusszahlung"
+	if j.Abschlusszahlung < 0 {
+		abschluss = "Erstattung"
+	}
+	pdf.CellFormat(162, 6, tr(abschluss), "1", 0, "L", false, 0, "")
+	pdf.CellFormat(38, 6, tr(pdfAmount(j.Abschlusszahlung)), "1", 1, "R", false, 0, "")
+
+	pdf.Ln(4)
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(0, 7, tr("Voranmeldungen"), "", 1, "L", false, 0, "")
+	vHeaders := []string{"Zeitraum", "Kz 83", "Nachbuchungen aus festgeschriebenen Perioden"}
+	vWidths := []float64{30, 38, 120}
+	pdfTableHeader(pdf, tr, vHeaders, vWidths)
+	for _, p := range j.Voranmeldungen {
+		pdfPageBreak(pdf, tr, vHeaders, vWidths, 6)
+		nach := ""
+		if n := j.NachbuchungenIn(p); len(n) > 0 {
+			var kz83 float64
+			for _, b := range n {
+				kz83 += b.UStVA().Kz83
+			}
+			nach = fmt.Sprintf("%d Beleg(e), Kz 83 %s", len(n), pdfAmount(round2(kz83)))
+		}
+		pdf.CellFormat(vWidths[0], 6, tr(fmt.Sprintf("%04d-%s", j.Jahr, p.Zeitraum())), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(vWidths[1], 6, tr(pdfAmount(p.UStVA.Kz83)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(vWidths[2], 6, tr(nach), "1", 0, "L", false, 0, "")
+		pdf.Ln(6)
+	}
+
+	if len(j.Nachbuchungen) > 0 {
+		pdf.Ln(4)
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(0, 7, tr("Nachbuchungen: Belege aus festgeschriebenen Perioden"), "", 1, "L", false, 0, "")
+		nHeaders := []string{"Beleg", "Datum", "Auftraggeber", "Belegperiode", "gebucht in", "Kennzahlen"}
+		nWidths := []float64{28, 24, 60, 26, 26, 113}
+		pdfTableHeader(pdf, tr, nHeaders, nWidths)
+		for _, n := range j.Nachbuchungen {
+			pdfPageBreak(pdf, tr, nHeaders, nWidths, 6)
+			var kz []string
+			for _, b := range n.Beitraege() {
+				kz = append(kz, "Kz "+b.Kz+" "+pdfAmount(b.Betrag))
+			}
+			text := strings.Join(kz, "; ")
+			if n.Geschaetzt {
+				text += " (geschätzt)"
+			}
+			pdf.CellFormat(nWidths[0], 6, tr(truncate(n.Belegnummer, 16)), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(nWidths[1], 6, tr(n.Rechnungsdatum), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(nWidths[2], 6, tr(truncate(n.Auftraggeber, 34)), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(nWidths[3], 6, tr(n.Belegperiode), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(nWidths[4], 6, tr(n.Buchungsperiode), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(nWidths[5], 6, tr(truncate(text, 70)), "1", 0, "L", false, 0, "")
+			pdf.Ln(6)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BuildMeldungAbweichungPDF renders the delta report of a filed return
+// (landscape): the values that changed since it was marked as filed and the
+// receipts that caused the change, each with its change per key.
+func BuildMeldungAbweichungPDF(a MeldungAbweichung, title, company string) ([]byte, error) {
+	pdf, tr := newReportPDF(title, "L", company)
+	g := a.Gemeldet
+	meldung := "Erstmeldung"
+	if g.Berichtigung() {
+		meldung = fmt.Sprintf("Berichtigung %d", g.Nr-1)
+	}
+	pdf.SetFont("Arial", "", 9)
+	pdf.CellFormat(0, 6, tr(fmt.Sprintf("%s %s, gemeldet am %s (%s)", g.Art, g.Zeitraum, g.Datum(), meldung)), "", 1, "L", false, 0, "")
+	if !a.Abweichend() {
+		pdf.CellFormat(0, 6, tr("Die aktuellen Werte entsprechen der Meldung."), "", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(2)
+	headers := []string{"Kennzahl", "Bezeichnung", "Gemeldet", "Aktuell", "Differenz"}
+	widths := []float64{34, 130, 38, 38, 37}
+	pdfTableHeader(pdf, tr, headers, widths)
+	for _, w := range a.Werte {
+		pdfPageBreak(pdf, tr, headers, widths, 6)
+		kurz, lang := MeldungWert(g.Art, w.Schluessel)
+		pdf.CellFormat(widths[0], 6, tr(kurz), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, tr(truncate(lang, 75)), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(pdfAmount(w.Gemeldet)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, tr(pdfAmount(w.Aktuell)), "1", 0, "R", false, 0, "")
+		pdf.SetFont("Arial", "B", 9)
+		pdf.CellFormat(widths[4], 6, tr(pdfAmount(w.Differenz())), "1", 0, "R", false, 0, "")
+		pdf.SetFont("Arial", "", 9)
+		pdf.Ln(6)
+	}
+
+	if len(a.Belege) > 0 {
+		pdf.Ln(4)
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(0, 7, tr("Belege, die die Änderung verursachen"), "", 1, "L", false, 0, "")
+		bHeaders := []string{"Beleg", "Datum", "Auftraggeber", "Status", "Änderung"}
+		bWidths := []float64{30, 24, 70, 22, 131}
+		pdfTableHeader(pdf, tr, bHeaders, bWidths)
+		for _, b := range a.Belege {
+			pdfPageBreak(pdf, tr, bHeaders, bWidths, 6)
+			diff := b.Differenz()
+			keys := make([]string, 0, len(diff))
+			for k := range diff {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			var parts []string
+			for _, k := range keys {
+				kurz, _ := MeldungWert(g.Art, k)
+				parts = append(parts, kurz+" "+pdfAmount(diff[k]))
+			}
+			beleg := b.Beleg.Belegnummer
+			if beleg == "" {
+				beleg = b.Beleg.Schluessel
+			}
+			pdf.CellFormat(bWidths[0], 6, tr(truncate(beleg, 18)), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(bWidths[1], 6, tr(b.Beleg.Rechnungsdatum), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(bWidths[2], 6, tr(truncate(b.Beleg.Auftraggeber, 40)), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(bWidths[3], 6, tr(b.Status), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(bWidths[4], 6, tr(truncate(strings.Join(parts, "; "), 80)), "1", 0, "L", false, 0, "")
+			pdf.Ln(6)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BuildUStVerprobungPDF renders the Verprobung of a UStVA period
+// (landscape): per Bereich the booked account balances against the value of
+// the Kennzahlen, the Zahllast against Kz 83, then per Bereich the receipts
+// that cause a difference and the remaining Rundung.
+func BuildUStVerprobungPDF(v UStVerprobung, title, company string) ([]byte, error) {
+	pdf, tr := newReportPDF(title, "L", company)
+	pdf.SetFont("Arial", "", 9)
+	if v.Ist {
+		pdf.CellFormat(0, 6, tr("Besteuerungsart: Ist-Versteuerung (§ 20 UStG) – Umsatzsteuer nach Zahlungseingang"), "", 1, "L", false, 0, "")
+	}
+	if !v.Abweichend() {
+		pdf.CellFormat(0, 6, tr("Die gebuchten Konten stimmen mit den Kennzahlen überein."), "", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(2)
+	headers := []string{"Bereich", "Kennzahlen", "Konten", "Gebucht", "Kennzahlen", "Differenz"}
+	widths := []float64{38, 64, 75, 34, 34, 32}
+	pdfTableHeader(pdf, tr, headers, widths)
+	for _, b := range v.Bereiche {
+		pdfPageBreak(pdf, tr, headers, widths, 6)
+		var konten []string
+		for _, k := range b.Konten {
+			konten = append(konten, fmt.Sprintf("%d: %s", k.Konto, pdfAmount(k.Saldo)))
+		}
+		titel := verprobungTitel[b.Bereich]
+		pdf.CellFormat(widths[0], 6, tr(titel[0]), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, tr(titel[1]), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(truncate(strings.Join(konten, "; "), 45)), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 6, tr(pdfAmount(b.Gebucht)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, tr(pdfAmount(b.Kennzahlen)), "1", 0, "R", false, 0, "")
+		if b.Differenz() != 0 {
+			pdf.SetFont("Arial", "B", 9)
+		}
+		pdf.CellFormat(widths[5], 6, tr(pdfAmount(b.Differenz())), "1", 0, "R", false, 0, "")
+		pdf.SetFont("Arial", "", 9)
+		pdf.Ln(6)
+	}
+	pdf.SetFont("Arial", "B", 9)
+	pdf.CellFormat(widths[0]+widths[1]+widths[2], 6, tr("Zahllast (Umsatzsteuer – Vorsteuer) / Kz 83"), "1", 0, "L", false, 0, "")
+	pdf.CellFormat(widths[3], 6, tr(pdfAmount(v.Zahllast())), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[4], 6, tr(pdfAmount(v.UStVA.Kz83)), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[5], 6, tr(pdfAmount(v.ZahllastDifferenz())), "1", 1, "R", false, 0, "")
+
+	bHeaders := []string{"Beleg", "Datum", "Auftraggeber", "Gebucht", "Kennzahlen", "Differenz", "Hinweis"}
+	bWidths := []float64{30, 24, 85, 34, 34, 32, 38}
+	for _, b := range v.Bereiche {
+		if len(b.Belege) == 0 && b.Rundung() == 0 {
+			continue
+		}
+		pdf.Ln(4)
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(0, 7, tr(verprobungTitel[b.Bereich][0]+": Belege mit Differenz"), "", 1, "L", false, 0, "")
+		pdfTableHeader(pdf, tr, bHeaders, bWidths)
+		for _, r := range b.Belege {
+			pdfPageBreak(pdf, tr, bHeaders, bWidths, 6)
+			hinweis := ""
+			if r.Geschaetzt {
+				hinweis = "geschätzt"
+			}
+			pdf.CellFormat(bWidths[0], 6, tr(truncate(r.Belegnummer, 18)), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(bWidths[1], 6, tr(r.Rechnungsdatum), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(bWidths[2], 6, tr(truncate(r.Auftraggeber, 50)), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(bWidths[3], 6, tr(pdfAmount(r.Gebucht)), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(bWidths[4], 6, tr(pdfAmount(r.Kennzahlen)), "1", 0, "R", false, 0, "")
+			pdf.SetFont("Arial", "B", 9)
+			pdf.CellFormat(bWidths[5], 6, tr(pdfAmount(r.Differenz())), "1", 0, "R", false, 0, "")
+			pdf.SetFont("Arial", "", 9)
+			pdf.CellFormat(bWidths[6], 6, tr(hinweis), "1", 0, "L", false, 0, "")
+			pdf.Ln(6)
+		}
+		if rd := b.Rundung(); rd != 0 {
+			pdfPageBreak(pdf, tr, bHeaders, bWidths, 6)
+			pdf.CellFormat(bWidths[0]+bWidths[1]+bWidths[2]+bWidths[3]+bWidths[4], 6, tr("Rundung (Steuer aus der Summe der Bemessungsgrundlagen)"), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(bWidths[5], 6, tr(pdfAmount(rd)), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(bWidths[6], 6, "", "1", 0, "L", false, 0, "")
+			pdf.Ln(6)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // BuildZMPDF renders the Zusammenfassende Meldung: one row per EU customer
-// VAT-ID with its net sum + "Sonstige Leistung", plus the Kontrollsumme and the
+// VAT-ID and Art der Leistung with its net sum, plus the Kontrollsumme and the
 // own VAT-ID in the header (when set).
 func BuildZMPDF(z ZM, ownVatID, title, company string) ([]byte, error) {
 	pdf, tr := newReportPDF(title, "P", company)
@@ -367,6 +714,11 @@ func BuildZMPDF(z ZM, ownVatID, title, company string) ([]byte, error) {
 		pdf.CellFormat(0, 6, tr("Eigene USt-IdNr.: "+ownVatID), "", 1, "L", false, 0, "")
 		pdf.Ln(1)
 	}
+	if z.Ist {
+		pdf.SetFont("Arial", "", 9)
+		pdf.CellFormat(0, 6, tr("Ist-Versteuerung: gemeldet nach Zeitraum der Leistung (§ 18a UStG)"), "", 1, "L", false, 0, "")
+		pdf.Ln(1)
+	}
 	headers := []string{"USt-IdNr. (Kunde)", "Summe (netto)", "Art der Leistung"}
 	widths := []float64{60, 40, 70}
 	pdfTableHeader(pdf, tr, headers, widths)
@@ -374,7 +726,7 @@ func BuildZMPDF(z ZM, ownVatID, title, company string) ([]byte, error) {
 		pdfPageBreak(pdf, tr, headers, widths, 6)
 		pdf.CellFormat(widths[0], 6, tr(l.UStIdNr), "1", 0, "L", false, 0, "")
 		pdf.CellFormat(widths[1], 6, tr(pdfAmount(l.Netto)), "1", 0, "R", false, 0, "")
-		pdf.CellFormat(widths[2], 6, tr("Sonstige Leistung"), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(ZMArtBezeichnung(l.Leistungsart())), "1", 0, "L", false, 0, "")
 		pdf.Ln(6)
 	}
 	pdfPageBreak(pdf, tr, headers, widths, 7)
@@ -452,19 +804,21 @@ func BuildOpenItemsPDF(oi OpenItems, title, company string) ([]byte, error) {
 func BuildSuSaPDF(bals []AccountBalance, title, company string) ([]byte, error) {
 	pdf, tr := newReportPDF(title, "P", company)
 
-	headers := []string{"Konto", "Bezeichnung", "Soll", "Haben", "Saldo"}
-	widths := []float64{18, 97, 25, 25, 25}
+	headers := []string{"Konto", "Bezeichnung", "EB-Wert", "Soll", "Haben", "Saldo"}
+	widths := []float64{18, 72, 25, 25, 25, 25}
 	pdfTableHeader(pdf, tr, headers, widths)
 
-	var totalSoll, totalHaben, totalSaldo float64
+	var totalEB, totalSoll, totalHaben, totalSaldo float64
 	for _, b := range bals {
 		pdfPageBreak(pdf, tr, headers, widths, 6)
 		pdf.CellFormat(widths[0], 6, tr(fmt.Sprintf("%d", b.Konto)), "1", 0, "L", false, 0, "")
-		pdf.CellFormat(widths[1], 6, tr(truncate(b.Name, 57)), "1", 0, "L", false, 0, "")
-		pdf.CellFormat(widths[2], 6, tr(pdfAmount(b.SollSumme)), "1", 0, "R", false, 0, "")
-		pdf.CellFormat(widths[3], 6, tr(pdfAmount(b.HabenSumme)), "1", 0, "R", false, 0, "")
-		pdf.CellFormat(widths[4], 6, tr(pdfAmount(b.Saldo)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[1], 6, tr(truncate(b.Name, 42)), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(pdfAmount(b.EBWert)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, tr(pdfAmount(b.SollSumme)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, tr(pdfAmount(b.HabenSumme)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[5], 6, tr(pdfAmount(b.Saldo)), "1", 0, "R", false, 0, "")
 		pdf.Ln(6)
+		totalEB += b.EBWert
 		totalSoll += b.SollSumme
 		totalHaben += b.HabenSumme
 		totalSaldo += b.Saldo
@@ -473,9 +827,10 @@ func BuildSuSaPDF(bals []AccountBalance, title, company string) ([]byte, error)
 	pdfPageBreak(pdf, tr, headers, widths, 7)
 	pdf.SetFont("Arial", "B", 9)
 	pdf.CellFormat(widths[0]+widths[1], 7, tr("Summe"), "1", 0, "R", false, 0, "")
-	pdf.CellFormat(widths[2], 7, tr(pdfAmount(round2(totalSoll))), "1", 0, "R", false, 0, "")
-	pdf.CellFormat(widths[3], 7, tr(pdfAmount(round2(totalHaben))), "1", 0, "R", false, 0, "")
-	pdf.CellFormat(widths[4], 7, tr(pdfAmount(round2(totalSaldo))), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[2], 7, tr(pdfAmount(round2(totalEB))), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[3], 7, tr(pdfAmount(round2(totalSoll))), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[4], 7, tr(pdfAmount(round2(totalHaben))), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[5], 7, tr(pdfAmount(round2(totalSaldo))), "1", 0, "R", false, 0, "")
 	pdf.Ln(7)
 
 	var buf bytes.Buffer
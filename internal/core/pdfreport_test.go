@@ -87,6 +87,63 @@ func TestBuildZMPDF(t *testing.T) {
 	}
 }
 
+func TestBuildUStJahrPDF(t *testing.T) {
+	j := UStJahreserklaerung{
+		Jahr:           2025,
+		Erklaerung:     UStVAOfficial{Kz81: 1000.06, USt81: 190.01, Kz66: 19, Kz83: 171.01},
+		Summe:          UStVAOfficial{Kz81: 1000.06, USt81: 190.02, Kz66: 19, Kz83: 171.02},
+		Voranmeldungen: []UStJahrPeriode{{VonMonat: 1, BisMonat: 3, UStVA: UStVAOfficial{Kz83: 171.02}}},
+		Nachbuchungen: []UStNachbuchung{{Belegnummer: "ER-7", Rechnungsdatum: "28.01.2025", Belegperiode: "2025-01",
+			Buchungsperiode: "2025-03", Kennzahlen: map[string]float64{"66": 19}}},
+		Vorauszahlungssoll: 171.02,
+		Abschlusszahlung:   -0.01,
+	}
+	data, err := BuildUStJahrPDF(j, "Umsatzsteuer-Jahreserklärung 2025", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 100 || string(data[:4]) != "%PDF" {
+		t.Fatalf("not a PDF (%d bytes)", len(data))
+	}
+	if _, err := BuildUStJahrPDF(UStJahreserklaerung{Jahr: 2025}, "Leer", ""); err != nil {
+		t.Errorf("empty annual return PDF errored: %v", err)
+	}
+}
+
+func TestBuildMeldungAbweichungPDF(t *testing.T) {
+	a := MeldungAbweichung{
+		Gemeldet: Meldung{Art: MeldungUStVA, Zeitraum: "2026-01", Nr: 1, GemeldetAm: "2026-02-10 09:30:00"},
+		Werte:    []WertAbweichung{{"66", 19, 28.5}, {"83", 171, 161.5}},
+		Belege: []BelegAbweichung{{Beleg: MeldungBeleg{Schluessel: "2026-0010", Belegnummer: "2026-0010", Rechnungsdatum: "28.01.2026"},
+			Status: BelegNeu, Aktuell: map[string]float64{"66": 9.5}}},
+	}
+	data, err := BuildMeldungAbweichungPDF(a, "Abweichungsbericht UStVA 2026-01", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 100 || string(data[:4]) != "%PDF" {
+		t.Fatalf("not a PDF (%d bytes)", len(data))
+	}
+}
+
+func TestBuildUStVerprobungPDF(t *testing.T) {
+	v := UStVerprobung{
+		UStVA: UStVAOfficial{Kz81: 1500, USt81: 285, Kz66: 19, Kz83: 266},
+		Bereiche: []VerprobungBereich{
+			{Bereich: VerprobungUSt, Konten: []VerprobungKonto{{1776, 190}}, Gebucht: 190, Kennzahlen: 285,
+				Belege: []VerprobungBeleg{{Belegnummer: "2026-0003", Rechnungsdatum: "12.01.2026", Kennzahlen: 95, Geschaetzt: true}}},
+			{Bereich: VerprobungVSt, Konten: []VerprobungKonto{{1576, 19}}, Gebucht: 19, Kennzahlen: 19},
+		},
+	}
+	data, err := BuildUStVerprobungPDF(v, "UStVA-Verprobung 2026-01", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 100 || string(data[:4]) != "%PDF" {
+		t.Fatalf("not a PDF (%d bytes)", len(data))
+	}
+}
+
 func TestBuildSalesJournalPDF(t *testing.T) {
 	rows := []CSVRow{
 		{Ausgangsrechnung: true, Belegnummer: "2025-0002", Rechnungsnummer: "RA-1", Rechnungsdatum: "10.12.2025", Auftraggeber: "Symeo GmbH", Gegenkonto: 8400, BetragNetto: 6500, SteuersatzBetrag: 1235, Bruttobetrag: 7735},
@@ -132,3 +189,29 @@ func TestPDFReportsPaginate(t *testing.T) {
 		t.Fatalf("controlling: err=%v len=%d", err, len(cp))
 	}
 }
+
+func TestBuildEInvoiceSichtbelegPDF(t *testing.T) {
+	meta := Meta{
+		Auftraggeber: "Muster Bürobedarf GmbH", VATID: "DE123456789",
+		Rechnungsnummer: "RE-1", Rechnungsdatum: "14.03.2026", Waehrung: "EUR",
+		BetragNetto: 200, SteuersatzBetrag: 26, Bruttobetrag: 226,
+		TaxLines: []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}, {Netto: 100, SatzProzent: 7, MwStBetrag: 7}},
+		Positionen: []InvoicePosition{
+			{Nr: "1", Bezeichnung: "Toner", Menge: 4, Einheit: "H87", Einzelpreis: 25, Netto: 100, SatzProzent: 19},
+			{Nr: "2", Bezeichnung: "Fachbuch", Menge: 10, Einheit: "H87", Einzelpreis: 10, Netto: 100, SatzProzent: 7},
+		},
+	}
+	data, err := BuildEInvoiceSichtbelegPDF(meta, FormatXRechnungUBL, "rechnung.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 100 || string(data[:4]) != "%PDF" {
+		t.Fatalf("not a PDF (%d bytes)", len(data))
+	}
+	if _, err := BuildEInvoiceSichtbelegPDF(Meta{}, FormatNone, ""); err != nil {
+		t.Errorf("empty Sichtbeleg errored: %v", err)
+	}
+	if got := SichtbelegName("Rechnung 4711.xml"); got != "Rechnung 4711.pdf" {
+		t.Errorf("SichtbelegName = %q", got)
+	}
+}
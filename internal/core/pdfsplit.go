@@ -0,0 +1,216 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gen2brain/go-fitz"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// PageInfo is what the receipt-boundary detection knows about one page.
+type PageInfo struct {
+	Text  string
+	Blank bool // no text and (almost) no ink: a separator page
+	Patch bool // patch-code / separator sheet
+}
+
+// Separator reports whether the page only separates receipts.
+func (p PageInfo) Separator() bool {
+	return p.Blank || p.Patch
+}
+
+// PageRange is a 1-based, inclusive page range of one receipt.
+type PageRange struct {
+	From, To int
+}
+
+// String renders the range as a pdfcpu page selection ("3" or "3-5").
+func (r PageRange) String() string {
+	if r.From == r.To {
+		return fmt.Sprintf("%d", r.From)
+	}
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// Page-level signals for a new receipt.
+var (
+	pageInvoiceNumberPattern = regexp.MustCompile(`(?i)(?:rechnungs?\s*-?\s*(?:nr|nummer)|invoice\s*(?:no|number|#)|beleg\s*-?\s*(?:nr|nummer))[.:\s#]*([A-Z0-9][A-Z0-9\-/]{2,})`)
+	pageVATIDPattern         = regexp.MustCompile(`\b(?:DE|AT|FR|NL|BE|LU|IT|ES|IE|DK|PL|CZ)\s?(?:U|[0-9A-Z]{2})?\s?\d(?:\s?\d){7,11}(?:B\d{2})?\b`)
+	nextDigitPattern         = regexp.MustCompile(`^\s?\d`)
+	pageFirstPattern         = regexp.MustCompile(`(?i)\b(?:seite|page|blatt)\s+1\s*(?:von|of|/)\s*\d+`)
+	patchTextPattern         = regexp.MustCompile(`(?i)\bpatch\s*(?:t|ii|iii|iv|vi|[1-6])\b|trennblatt|separator\s+sheet`)
+)
+
+// DetectReceiptBoundaries splits the pages of a scan batch into receipts.
+// Blank and patch pages end the current receipt and are dropped. A text
+// page starts a new receipt when it says "Seite 1 von n", carries an invoice
+// number different from the current receipt's, or shows only VAT-IDs not
+// seen on the current receipt (a different supplier). ownVATIDs are
+// ignored, since they appear on every incoming invoice.
+func DetectReceiptBoundaries(pages []PageInfo, ownVATIDs []string) []PageRange {
+	own := map[string]bool{}
+	for _, v := range ownVATIDs {
+		if c := compactAlnum(v); c != "" {
+			own[c] = true
+		}
+	}
+	var out []PageRange
+	cur := PageRange{}
+	curNumber := ""
+	curVATs := map[string]bool{}
+	closeCur := func() {
+		if cur.From > 0 {
+			out = append(out, cur)
+		}
+		cur, curNumber, curVATs = PageRange{}, "", map[string]bool{}
+	}
+	for i, p := range pages {
+		n := i + 1
+		if p.Separator() {
+			closeCur()
+			continue
+		}
+		number := pageInvoiceNumber(p.Text)
+		vats := pageVATIDs(p.Text, own)
+		if cur.From > 0 {
+			newSupplier := len(vats) > 0 && len(curVATs) > 0
+			for v := range vats {
+				if curVATs[v] {
+					newSupplier = false
+				}
+			}
+			newNumber := number != "" && curNumber != "" && number != curNumber
+			if pageFirstPattern.MatchString(p.Text) || newNumber || newSupplier {
+				closeCur()
+			}
+		}
+		if cur.From == 0 {
+			cur.From = n
+		}
+		cur.To = n
+		if curNumber == "" {
+			curNumber = number
+		}
+		for v := range vats {
+			curVATs[v] = true
+		}
+	}
+	closeCur()
+	return out
+}
+
+func pageInvoiceNumber(text string) string {
+	if m := pageInvoiceNumberPattern.FindStringSubmatch(text); len(m) > 1 && strings.IndexFunc(m[1], isDigitRune) >= 0 {
+		return compactAlnum(m[1])
+	}
+	return ""
+}
+
+func isDigitRune(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// pageVATIDs returns the VAT-IDs on a page other than own. A match that
+// continues with more digit groups is the start of an IBAN, not a VAT-ID.
+func pageVATIDs(text string, own map[string]bool) map[string]bool {
+	out := map[string]bool{}
+	upper := strings.ToUpper(text)
+	for _, loc := range pageVATIDPattern.FindAllStringIndex(upper, -1) {
+		if nextDigitPattern.MatchString(upper[loc[1]:]) {
+			continue
+		}
+		if c := compactAlnum(upper[loc[0]:loc[1]]); !own[c] {
+			out[c] = true
+		}
+	}
+	return out
+}
+
+// classifyPageImage inspects a low-resolution rendering of a page. A page
+// with under 0.3 % dark pixels is blank. A patch sheet is dominated by a few
+// wide vertical bars: at least three dark columns covering ≥80 % of the
+// page height and an overall ink share between 10 % and 70 %.
+func classifyPageImage(img image.Image) (blank, patch bool) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return true, false
+	}
+	dark, darkCols := 0, 0
+	prevBar := false
+	bars := 0
+	for x := b.Min.X; x < b.Max.X; x++ {
+		col := 0
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			if (r+g+bl)/3 < 0x6000 {
+				col++
+			}
+		}
+		dark += col
+		isBar := col*5 >= h*4
+		if isBar {
+			darkCols++
+			if !prevBar {
+				bars++
+			}
+		}
+		prevBar = isBar
+	}
+	share := float64(dark) / float64(w*h)
+	if share < 0.003 {
+		return true, false
+	}
+	return false, bars >= 3 && share >= 0.10 && share <= 0.70 && darkCols*10 >= w
+}
+
+// AnalyzePDFPages reads the text layer and a 24-DPI rendering of every page
+// for DetectReceiptBoundaries.
+func AnalyzePDFPages(path string) ([]PageInfo, error) {
+	doc, err := fitz.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer doc.Close()
+	pages := make([]PageInfo, doc.NumPage())
+	for i := range pages {
+		if h, err := doc.HTML(i, false); err == nil {
+			pages[i].Text = strings.TrimSpace(buildPlainTextFromHTML([]string{h}))
+		}
+		// A printed separator sheet has little more than its label on it.
+		if len(pages[i].Text) < 40 && patchTextPattern.MatchString(pages[i].Text) {
+			pages[i].Patch = true
+			continue
+		}
+		if HasText(pages[i].Text) {
+			continue // a page with text is never a separator
+		}
+		img, err := doc.ImageDPI(i, 24)
+		if err != nil {
+			continue
+		}
+		pages[i].Blank, pages[i].Patch = classifyPageImage(img)
+	}
+	return pages, nil
+}
+
+// SplitPDF writes one PDF per range into outDir, named
+// "<name>_Teil<n>.pdf", and returns their paths in order.
+func SplitPDF(path string, ranges []PageRange, outDir string) ([]string, error) {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	conf := model.NewDefaultConfiguration()
+	out := make([]string, 0, len(ranges))
+	for i, r := range ranges {
+		target := filepath.Join(outDir, fmt.Sprintf("%s_Teil%d.pdf", base, i+1))
+		if err := api.TrimFile(path, target, []string{r.String()}, conf); err != nil {
+			return nil, fmt.Errorf("failed to write pages %s: %w", r, err)
+		}
+		out = append(out, target)
+	}
+	return out, nil
+}
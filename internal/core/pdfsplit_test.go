@@ -0,0 +1,126 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+)
+
+func TestDetectReceiptBoundaries(t *testing.T) {
+	own := []string{"DE 999 888 777"}
+	pages := []PageInfo{
+		{Text: "Hetzner Online GmbH DE812871812\nRechnungsnr: R-1001\nAn: Muster GmbH DE999888777\nSeite 1 von 2"},
+		{Text: "Hetzner Online GmbH DE812871812\nSeite 2 von 2\nSumme 119,00"},
+		{Blank: true},
+		{Text: "Aral Tankstelle DE126453218 Summe 60,00"},
+		{Text: "Druckerei Sauer DE811222333\nBeleg-Nr. RS-1042"},
+		{Text: "Druckerei Sauer DE811222333\nBeleg-Nr. RS-1043"},
+		{Patch: true},
+		{Text: "Büro Meier DE111222333 IBAN DE89 3704 0044 0532 0130 00"},
+		{Text: "Schreibwaren Lang DE444555666\nRechnung"},
+	}
+	got := DetectReceiptBoundaries(pages, own)
+	want := []PageRange{{1, 2}, {4, 4}, {5, 5}, {6, 6}, {8, 8}, {9, 9}}
+	if len(got) != len(want) {
+		t.Fatalf("ranges = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("range %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if s := (PageRange{3, 5}).String(); s != "3-5" {
+		t.Errorf("String = %q", s)
+	}
+}
+
+func TestDetectReceiptBoundaries_KeepsMultiPageInvoice(t *testing.T) {
+	pages := []PageInfo{
+		{Text: "Telekom DE123475223 Rechnungsnummer 4711-0001 Seite 1 von 3"},
+		{Text: "Telekom DE123475223 Einzelverbindungen Seite 2 von 3"},
+		{Text: "Rechnungsnummer 4711-0001 Seite 3 von 3"},
+	}
+	if got := DetectReceiptBoundaries(pages, nil); len(got) != 1 || got[0] != (PageRange{1, 3}) {
+		t.Errorf("ranges = %v, want one receipt", got)
+	}
+	if got := DetectReceiptBoundaries([]PageInfo{{Blank: true}, {Blank: true}}, nil); len(got) != 0 {
+		t.Errorf("blank batch = %v", got)
+	}
+}
+
+func TestClassifyPageImage(t *testing.T) {
+	page := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 200, 280))
+		draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+		return img
+	}
+	black := image.NewUniform(color.Black)
+
+	blank := page()
+	blank.Set(10, 10, color.Black) // scanner dust
+	if b, p := classifyPageImage(blank); !b || p {
+		t.Errorf("blank page = %v, %v", b, p)
+	}
+
+	patch := page()
+	for _, x := range []int{30, 80, 130} {
+		draw.Draw(patch, image.Rect(x, 10, x+15, 270), black, image.Point{}, draw.Src)
+	}
+	if b, p := classifyPageImage(patch); b || !p {
+		t.Errorf("patch sheet = %v, %v", b, p)
+	}
+
+	text := page()
+	for y := 20; y < 260; y += 12 {
+		draw.Draw(text, image.Rect(20, y, 180, y+4), black, image.Point{}, draw.Src)
+	}
+	if b, p := classifyPageImage(text); b || p {
+		t.Errorf("text page = %v, %v", b, p)
+	}
+}
+
+func TestAnalyzeAndSplitPDF(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Stapel.pdf")
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	for _, line := range []string{"Rechnungsnr: A-100", "", "Rechnungsnr: B-200", "Rechnungsnr: B-200 Seite 2"} {
+		pdf.AddPage()
+		if line != "" {
+			pdf.Text(20, 30, line)
+		}
+	}
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := AnalyzePDFPages(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 4 || !pages[1].Blank || pages[0].Blank {
+		t.Fatalf("pages = %+v", pages)
+	}
+	ranges := DetectReceiptBoundaries(pages, nil)
+	if len(ranges) != 2 || ranges[1] != (PageRange{3, 4}) {
+		t.Fatalf("ranges = %v", ranges)
+	}
+
+	out := t.TempDir()
+	parts, err := SplitPDF(path, ranges, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 || filepath.Base(parts[1]) != "Stapel_Teil2.pdf" {
+		t.Fatalf("parts = %v", parts)
+	}
+	for i, want := range []int{1, 2} {
+		if n, err := PDFPageCount(parts[i]); err != nil || n != want {
+			t.Errorf("part %d has %d pages (%v), want %d", i+1, n, err, want)
+		}
+	}
+}
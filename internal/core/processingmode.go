@@ -0,0 +1,33 @@
+package core
+
+import "strings"
+
+// Values of Settings.ProcessingMode.
+const (
+	ProcessingModeClaude = "claude" // Anthropic Claude API
+	ProcessingModeOpenAI = "openai" // OpenAI-compatible chat-completions API (OpenAIBaseURL)
+	ProcessingModeLocal  = "local"  // offline heuristics (LocalExtractor)
+)
+
+// UsesAI reports whether receipts are extracted by a language model
+// (Claude or an OpenAI-compatible backend) rather than the local heuristics.
+func (s Settings) UsesAI() bool {
+	return s.ProcessingMode == ProcessingModeClaude || s.ProcessingMode == ProcessingModeOpenAI
+}
+
+// AIModel returns the model name for the active backend.
+func (s Settings) AIModel() string {
+	if s.ProcessingMode == ProcessingModeOpenAI {
+		return s.OpenAIModel
+	}
+	return s.AnthropicModel
+}
+
+// openAIEndpoint names the OpenAI-compatible endpoint for the Verfahrens-
+// dokumentation: the configured base URL, or the public OpenAI API.
+func openAIEndpoint(baseURL string) string {
+	if u := strings.TrimSpace(baseURL); u != "" {
+		return u
+	}
+	return "api.openai.com"
+}
@@ -0,0 +1,330 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Sources of an extracted field (FieldProvenance.Source).
+const (
+	SourceEInvoice  = "E-Rechnung" // structured XML (XRechnung / ZUGFeRD)
+	SourceTextLayer = "Textebene"  // AI value found verbatim in the PDF text layer
+	SourceVision    = "Vision"     // AI value read from the page images only
+	SourceModel     = "KI"         // AI value without evidence in the text sent (text-only request)
+	SourceLocal     = "Lokal"      // local regex heuristics
+	SourceTemplate  = "Vorlage"    // learned per-supplier template
+	SourceMail      = "E-Mail"     // sender of an imported e-mail
+)
+
+// Fields that carry a provenance, named after the Meta fields.
+const (
+	FieldAuftraggeber     = "Auftraggeber"
+	FieldRechnungsnummer  = "Rechnungsnummer"
+	FieldVATID            = "VATID"
+	FieldRechnungsdatum   = "Rechnungsdatum"
+	FieldBezahldatum      = "Bezahldatum"
+	FieldBetragNetto      = "BetragNetto"
+	FieldSteuersatzBetrag = "SteuersatzBetrag"
+	FieldBruttobetrag     = "Bruttobetrag"
+)
+
+// ProvenanceFields lists the fields with a provenance in display order.
+var ProvenanceFields = []string{
+	FieldAuftraggeber, FieldRechnungsnummer, FieldVATID, FieldRechnungsdatum,
+	FieldBezahldatum, FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag,
+}
+
+// AutobookKeyFields must be present and certain before a receipt is booked
+// without review.
+var AutobookKeyFields = []string{
+	FieldAuftraggeber, FieldRechnungsdatum, FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag,
+}
+
+// Confidence levels of the extraction paths. A field below LowConfidence is
+// highlighted in the confirmation dialog and blocks autobooking.
+const (
+	LowConfidence          = 0.75
+	ConfidenceTextLayer    = 0.95 // AI value confirmed by the text layer
+	ConfidenceVision       = 0.8  // AI value read from the page image
+	ConfidenceModel        = 0.5  // AI value not found in the text it was given
+	ConfidenceLocal        = 0.6  // regex heuristics always deserve a look
+	ConfidenceUncertain    = 0.4  // the model itself marked the field as uncertain
+	ConfidenceInconsistent = 0.5  // amounts that do not add up
+)
+
+// FieldProvenance records where an extracted value came from and how far it
+// can be trusted (0..1).
+type FieldProvenance struct {
+	Source     string
+	Confidence float64
+}
+
+// Low reports whether the value should be checked by hand.
+func (p FieldProvenance) Low() bool {
+	return p.Confidence < LowConfidence
+}
+
+// Provenance maps a field (Field* constant) to its FieldProvenance.
+type Provenance map[string]FieldProvenance
+
+// provenanceValue returns the value of field in m as text, "" when empty.
+// Amounts of 0 count as empty.
+func provenanceValue(m Meta, field string) string {
+	amount := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", v)
+	}
+	switch field {
+	case FieldAuftraggeber:
+		return strings.TrimSpace(m.Auftraggeber)
+	case FieldRechnungsnummer:
+		return strings.TrimSpace(m.Rechnungsnummer)
+	case FieldVATID:
+		return strings.TrimSpace(m.VATID)
+	case FieldRechnungsdatum:
+		return strings.TrimSpace(m.Rechnungsdatum)
+	case FieldBezahldatum:
+		return strings.TrimSpace(m.Bezahldatum)
+	case FieldBetragNetto:
+		return amount(m.BetragNetto)
+	case FieldSteuersatzBetrag:
+		return amount(m.SteuersatzBetrag)
+	case FieldBruttobetrag:
+		return amount(m.Bruttobetrag)
+	}
+	return ""
+}
+
+// SetProvenance stamps every non-empty field of m with source and
+// confidence, replacing earlier entries. Amounts that do not add up are
+// capped at ConfidenceInconsistent.
+func SetProvenance(m *Meta, source string, confidence float64) {
+	m.Provenance = Provenance{}
+	for _, f := range ProvenanceFields {
+		if provenanceValue(*m, f) != "" {
+			m.Provenance[f] = FieldProvenance{Source: source, Confidence: confidence}
+		}
+	}
+	capInconsistentAmounts(m)
+}
+
+// AssessProvenance rates the fields of an AI extraction. text is the text
+// layer that was sent along ("" for a pure image request) and withImages
+// tells whether page images were sent. A value found in text is
+// SourceTextLayer; otherwise it was read from an image (SourceVision) or has
+// no evidence at all (SourceModel). Entries already present — fields the
+// model marked as uncertain — keep their lower confidence.
+func AssessProvenance(m *Meta, text string, withImages bool) {
+	flagged := m.Provenance
+	m.Provenance = Provenance{}
+	t := newProvenanceText(text)
+	for _, f := range ProvenanceFields {
+		v := provenanceValue(*m, f)
+		if v == "" {
+			continue
+		}
+		p := FieldProvenance{Source: SourceModel, Confidence: ConfidenceModel}
+		switch {
+		case t.contains(f, v):
+			p = FieldProvenance{Source: SourceTextLayer, Confidence: ConfidenceTextLayer}
+		case withImages:
+			p = FieldProvenance{Source: SourceVision, Confidence: ConfidenceVision}
+		}
+		if prev, ok := flagged[f]; ok && prev.Confidence < p.Confidence {
+			p.Confidence = prev.Confidence
+		}
+		m.Provenance[f] = p
+	}
+	capInconsistentAmounts(m)
+}
+
+// capInconsistentAmounts lowers the amount confidences when
+// Netto + MwSt + Trinkgeld does not match the gross amount.
+func capInconsistentAmounts(m *Meta) {
+	if math.Abs(m.Bruttobetrag-(m.BetragNetto+m.SteuersatzBetrag+m.Trinkgeld)) <= 0.02 {
+		return
+	}
+	for _, f := range []string{FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag} {
+		if p, ok := m.Provenance[f]; ok && p.Confidence > ConfidenceInconsistent {
+			p.Confidence = ConfidenceInconsistent
+			m.Provenance[f] = p
+		}
+	}
+}
+
+// UncertainFields returns the fields (in display order) that should be
+// checked by hand: those with a low confidence, plus the AutobookKeyFields
+// that came out empty. A Meta without provenance (manual entry, old rows)
+// has no uncertain fields.
+func (m Meta) UncertainFields() []string {
+	if m.Provenance == nil {
+		return nil
+	}
+	var out []string
+	for _, f := range ProvenanceFields {
+		p, ok := m.Provenance[f]
+		switch {
+		case ok && p.Low():
+			out = append(out, f)
+		case !ok && provenanceValue(m, f) == "" && containsString(AutobookKeyFields, f):
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// provenanceText is the text layer prepared for value lookups.
+type provenanceText struct {
+	lower   string // lower case, whitespace collapsed
+	compact string // upper case letters and digits only
+}
+
+func newProvenanceText(text string) provenanceText {
+	return provenanceText{
+		lower:   strings.ToLower(strings.Join(strings.Fields(text), " ")),
+		compact: compactAlnum(text),
+	}
+}
+
+// compactAlnum keeps only letters and digits, upper-cased, so "DE 123 456
+// 789" and "DE123456789" compare equal.
+func compactAlnum(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// contains reports whether value of field occurs in the text layer, in any
+// of the spellings a document may use for it.
+func (t provenanceText) contains(field, value string) bool {
+	if t.lower == "" {
+		return false
+	}
+	switch field {
+	case FieldAuftraggeber:
+		v := strings.ToLower(strings.Join(strings.Fields(value), " "))
+		if strings.Contains(t.lower, v) {
+			return true
+		}
+		// The model often completes or shortens a name ("Hetzner Online
+		// GmbH" for "HETZNER ONLINE"); accept it when every word of five or
+		// more letters is on the document (legal forms are shorter).
+		words := 0
+		for _, w := range strings.Fields(v) {
+			w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+			if len([]rune(w)) < 5 {
+				continue
+			}
+			if !strings.Contains(t.lower, w) {
+				return false
+			}
+			words++
+		}
+		return words > 0
+	case FieldRechnungsnummer, FieldVATID:
+		c := compactAlnum(value)
+		if len(c) < 3 {
+			return strings.Contains(t.lower, strings.ToLower(value))
+		}
+		return strings.Contains(t.compact, c)
+	case FieldRechnungsdatum, FieldBezahldatum:
+		d, err := time.Parse("02.01.2006", value)
+		if err != nil {
+			return strings.Contains(t.lower, strings.ToLower(value))
+		}
+		for _, s := range dateSpellings(d) {
+			if strings.Contains(t.lower, s) {
+				return true
+			}
+		}
+		return false
+	default: // amounts
+		var v float64
+		if _, err := fmt.Sscanf(value, "%f", &v); err != nil {
+			return false
+		}
+		for _, s := range amountSpellings(math.Abs(v)) {
+			if containsNumber(t.lower, s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var (
+	monthsDE = []string{"januar", "februar", "märz", "april", "mai", "juni", "juli", "august", "september", "oktober", "november", "dezember"}
+	monthsEN = []string{"january", "february", "march", "april", "may", "june", "july", "august", "september", "october", "november", "december"}
+)
+
+// dateSpellings returns the usual spellings of d on German and English
+// receipts, lower case.
+func dateSpellings(d time.Time) []string {
+	out := []string{
+		d.Format("02.01.2006"), d.Format("2.1.2006"), d.Format("02.01.06"),
+		d.Format("2006-01-02"), d.Format("02/01/2006"), d.Format("01/02/2006"),
+		d.Format("02-01-2006"),
+	}
+	de, en := monthsDE[d.Month()-1], monthsEN[d.Month()-1]
+	day, year := d.Day(), d.Year()
+	out = append(out,
+		fmt.Sprintf("%d. %s %d", day, de, year),
+		fmt.Sprintf("%02d. %s %d", day, de, year),
+		fmt.Sprintf("%d %s %d", day, en, year),
+		fmt.Sprintf("%s %d, %d", en, day, year),
+		fmt.Sprintf("%d %s %d", day, en[:3], year),
+		fmt.Sprintf("%s %d, %d", en[:3], day, year),
+	)
+	return out
+}
+
+// amountSpellings returns v with decimal comma or point, with and without
+// thousands grouping.
+func amountSpellings(v float64) []string {
+	plain := fmt.Sprintf("%.2f", v)
+	return []string{
+		strings.Replace(plain, ".", ",", 1), plain,
+		FormatAmount(v, ","), FormatAmount(v, "."),
+	}
+}
+
+// containsNumber reports whether s occurs in text as a whole number, i.e.
+// not directly preceded or followed by another digit.
+func containsNumber(text, s string) bool {
+	for from := 0; ; {
+		i := strings.Index(text[from:], s)
+		if i < 0 {
+			return false
+		}
+		i += from
+		end := i + len(s)
+		before := i == 0 || !isASCIIDigit(text[i-1])
+		after := end == len(text) || !isASCIIDigit(text[end])
+		if before && after {
+			return true
+		}
+		from = i + 1
+	}
+}
+
+func isASCIIDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
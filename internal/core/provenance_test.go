@@ -0,0 +1,112 @@
+package core
+
+import "testing"
+
+const provenanceReceiptText = `HETZNER ONLINE
+Industriestr. 25, 91710 Gunzenhausen
+USt-IdNr.: DE 812 871 812
+Rechnung Nr. R0012-345   Datum: 3. Februar 2026
+Netto 1.234,56 EUR
+USt 19 % 234,57 EUR
+Gesamt 1.469,13 EUR`
+
+func provenanceMeta() Meta {
+	return Meta{
+		Auftraggeber:     "Hetzner Online GmbH",
+		Rechnungsnummer:  "R0012345",
+		VATID:            "DE812871812",
+		Rechnungsdatum:   "03.02.2026",
+		BetragNetto:      1234.56,
+		SteuersatzBetrag: 234.57,
+		Bruttobetrag:     1469.13,
+	}
+}
+
+func TestAssessProvenance_TextLayer(t *testing.T) {
+	m := provenanceMeta()
+	AssessProvenance(&m, provenanceReceiptText, true)
+	for _, f := range []string{FieldAuftraggeber, FieldRechnungsnummer, FieldVATID, FieldRechnungsdatum, FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag} {
+		if p := m.Provenance[f]; p.Source != SourceTextLayer || p.Confidence != ConfidenceTextLayer {
+			t.Errorf("%s = %+v, want text layer", f, p)
+		}
+	}
+	if _, ok := m.Provenance[FieldBezahldatum]; ok {
+		t.Error("empty Bezahldatum must not get a provenance")
+	}
+	if u := m.UncertainFields(); len(u) != 0 {
+		t.Errorf("UncertainFields = %v", u)
+	}
+}
+
+func TestAssessProvenance_VisionModelAndFlags(t *testing.T) {
+	m := provenanceMeta()
+	m.Rechnungsdatum = "04.02.2026" // not on the text layer
+	m.Provenance = Provenance{FieldAuftraggeber: {Source: SourceModel, Confidence: ConfidenceUncertain}}
+	AssessProvenance(&m, provenanceReceiptText, true)
+	if p := m.Provenance[FieldRechnungsdatum]; p.Source != SourceVision || p.Low() {
+		t.Errorf("date from image = %+v", p)
+	}
+	if p := m.Provenance[FieldAuftraggeber]; p.Source != SourceTextLayer || p.Confidence != ConfidenceUncertain {
+		t.Errorf("model-flagged field = %+v, want text layer with low confidence", p)
+	}
+
+	// Text-only request: a value not in the text has no evidence at all.
+	m = provenanceMeta()
+	m.Rechnungsnummer = "R0099"
+	AssessProvenance(&m, provenanceReceiptText, false)
+	if p := m.Provenance[FieldRechnungsnummer]; p.Source != SourceModel || !p.Low() {
+		t.Errorf("unsupported value = %+v", p)
+	}
+
+	// Pure image request.
+	m = provenanceMeta()
+	AssessProvenance(&m, "", true)
+	if p := m.Provenance[FieldBruttobetrag]; p.Source != SourceVision {
+		t.Errorf("image-only = %+v", p)
+	}
+}
+
+func TestProvenance_InconsistentAmountsAndMissingKeys(t *testing.T) {
+	m := provenanceMeta()
+	m.Bruttobetrag = 1496.13 // transposed digits
+	m.Rechnungsdatum = ""
+	AssessProvenance(&m, provenanceReceiptText+"\n1.496,13", true)
+	u := m.UncertainFields()
+	want := []string{FieldRechnungsdatum, FieldBetragNetto, FieldSteuersatzBetrag, FieldBruttobetrag}
+	if len(u) != len(want) {
+		t.Fatalf("UncertainFields = %v, want %v", u, want)
+	}
+	for i := range want {
+		if u[i] != want[i] {
+			t.Errorf("UncertainFields[%d] = %s, want %s", i, u[i], want[i])
+		}
+	}
+	if (Meta{}).UncertainFields() != nil {
+		t.Error("Meta without provenance must have no uncertain fields")
+	}
+}
+
+func TestProvenanceText_Spellings(t *testing.T) {
+	txt := newProvenanceText("Invoice date: Feb 3, 2026  Total: 1,469.13 USD  Ref 21469.13")
+	if !txt.contains(FieldRechnungsdatum, "03.02.2026") {
+		t.Error("English short month date not found")
+	}
+	if !txt.contains(FieldBruttobetrag, "1469.13") {
+		t.Error("grouped amount with decimal point not found")
+	}
+	if newProvenanceText("Ref 21469.13").contains(FieldBruttobetrag, "1469.13") {
+		t.Error("amount must not match inside a longer number")
+	}
+}
+
+func TestSetProvenance(t *testing.T) {
+	m := provenanceMeta()
+	SetProvenance(&m, SourceEInvoice, 1)
+	if len(m.Provenance) != 7 || m.Provenance[FieldVATID].Source != SourceEInvoice {
+		t.Errorf("Provenance = %v", m.Provenance)
+	}
+	SetProvenance(&m, SourceLocal, ConfidenceLocal)
+	if p := m.Provenance[FieldBruttobetrag]; p.Source != SourceLocal || !p.Low() {
+		t.Errorf("local = %+v", p)
+	}
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultNummernkreis is the outgoing invoice number format used when the
+// layout leaves it empty: "RE-2026-0001", restarting every year.
+const DefaultNummernkreis = "RE-${YYYY}-${NNNN}"
+
+// DefaultZahlungszielTage is the payment term used when neither the layout
+// nor the invoice sets one.
+const DefaultZahlungszielTage = 14
+
+// Rechnungslayout configures invoices written in BuchISY: the number range,
+// the default payment term and the texts and logo of the PDF.
+type Rechnungslayout struct {
+	Nummernkreis     string `json:"nummernkreis,omitempty"`      // e.g. "RE-${YYYY}-${NNNN}"; "" = DefaultNummernkreis
+	ZahlungszielTage int    `json:"zahlungsziel_tage,omitempty"` // 0 = DefaultZahlungszielTage
+	Einleitung       string `json:"einleitung,omitempty"`        // text above the positions
+	Schlusstext      string `json:"schlusstext,omitempty"`       // text below the payment terms
+	Fusszeile        string `json:"fusszeile,omitempty"`         // page footer; "" = built from the company data
+	LogoPfad         string `json:"logo_pfad,omitempty"`         // PNG/JPEG shown top right
+	Akzentfarbe      string `json:"akzentfarbe,omitempty"`       // "#RRGGBB" for the table header; "" = grey
+}
+
+// NummernkreisOrDefault returns the configured number format, falling back
+// to DefaultNummernkreis. A format without ${NNNN} gets "-${NNNN}" appended
+// so every number stays unique.
+func (l Rechnungslayout) NummernkreisOrDefault() string {
+	p := strings.TrimSpace(l.Nummernkreis)
+	if p == "" {
+		return DefaultNummernkreis
+	}
+	if !strings.Contains(p, "${NNNN}") {
+		p += "-${NNNN}"
+	}
+	return p
+}
+
+// ZahlungszielOrDefault returns the configured payment term in days.
+func (l Rechnungslayout) ZahlungszielOrDefault() int {
+	if l.ZahlungszielTage > 0 {
+		return l.ZahlungszielTage
+	}
+	return DefaultZahlungszielTage
+}
+
+// rechnungsnummerParts splits a number format for a year into the fixed text
+// before and after the running number.
+func rechnungsnummerParts(pattern string, year int) (prefix, suffix string) {
+	p := strings.ReplaceAll(pattern, "${YYYY}", fmt.Sprintf("%04d", year))
+	p = strings.ReplaceAll(p, "${YY}", fmt.Sprintf("%02d", year%100))
+	i := strings.Index(p, "${NNNN}")
+	if i < 0 {
+		return p, ""
+	}
+	return p[:i], p[i+len("${NNNN}"):]
+}
+
+// RechnungsnummerPrefix returns the fixed text before the running number,
+// the key under which the numbers of one range are looked up.
+func RechnungsnummerPrefix(pattern string, year int) string {
+	prefix, _ := rechnungsnummerParts(pattern, year)
+	return prefix
+}
+
+// NextRechnungsnummer returns the next number of the outgoing invoice range
+// pattern for year, given the numbers already issued. Only numbers of the
+// same range (same prefix and suffix, numeric running part) count; the
+// result is max + 1, zero-padded to four digits. Like NextBelegnummer the
+// value is derived, not reserved: it is only taken once the invoice is
+// saved, so a cancelled invoice leaves no gap.
+func NextRechnungsnummer(pattern string, year int, existing []string) string {
+	prefix, suffix := rechnungsnummerParts(pattern, year)
+	max := 0
+	for _, nr := range existing {
+		nr = strings.TrimSpace(nr)
+		if !strings.HasPrefix(nr, prefix) || !strings.HasSuffix(nr, suffix) || len(nr) <= len(prefix)+len(suffix) {
+			continue
+		}
+		if n, err := strconv.Atoi(nr[len(prefix) : len(nr)-len(suffix)]); err == nil && n > max {
+			max = n
+		}
+	}
+	return fmt.Sprintf("%s%04d%s", prefix, max+1, suffix)
+}
+
+// Rechnungsentwurf is an outgoing invoice being written in BuchISY: the
+// customer, the positions and the payment terms. Positions carry net
+// amounts; the VAT is computed per rate (TaxLines).
+type Rechnungsentwurf struct {
+	Kunde            Firmendaten
+	KundeUStID       string // customer VAT-ID (reverse charge within the EU)
+	Kaeuferreferenz  string // Leitweg-ID / buyer reference (BT-10)
+	Rechnungsnummer  string // assigned on save from the number range
+	Rechnungsdatum   string // DD.MM.YYYY
+	Leistungsdatum   string // DD.MM.YYYY; "" = Rechnungsdatum
+	Betreff          string // becomes the Verwendungszweck
+	Positionen       []InvoicePosition
+	ZahlungszielTage int
+	Waehrung         string
+}
+
+// Validate reports the first missing or invalid field.
+func (r Rechnungsentwurf) Validate() error {
+	if strings.TrimSpace(r.Kunde.Name) == "" {
+		return fmt.Errorf("Bitte einen Kunden angeben.")
+	}
+	if _, err := time.Parse("02.01.2006", r.Rechnungsdatum); err != nil {
+		return fmt.Errorf("Ungültiges Rechnungsdatum: %q", r.Rechnungsdatum)
+	}
+	if d := strings.TrimSpace(r.Leistungsdatum); d != "" {
+		if _, err := time.Parse("02.01.2006", d); err != nil {
+			return fmt.Errorf("Ungültiges Leistungsdatum: %q", d)
+		}
+	}
+	if len(r.Positionen) == 0 {
+		return fmt.Errorf("Die Rechnung hat keine Positionen.")
+	}
+	for i, p := range r.Positionen {
+		if strings.TrimSpace(p.Bezeichnung) == "" {
+			return fmt.Errorf("Position %d hat keine Bezeichnung.", i+1)
+		}
+	}
+	if SumPositionen(r.Positionen) == 0 {
+		return fmt.Errorf("Der Rechnungsbetrag ist 0.")
+	}
+	return nil
+}
+
+// TaxLines groups the positions by VAT rate (in order of first appearance)
+// and computes the VAT per rate from the rounded net sum, as §14 UStG
+// requires it on the invoice.
+func (r Rechnungsentwurf) TaxLines() []TaxLine {
+	var lines []TaxLine
+	idx := map[float64]int{}
+	for _, p := range r.Positionen {
+		i, ok := idx[p.SatzProzent]
+		if !ok {
+			i = len(lines)
+			idx[p.SatzProzent] = i
+			lines = append(lines, TaxLine{SatzProzent: p.SatzProzent})
+		}
+		lines[i].Netto += p.Netto
+	}
+	for i := range lines {
+		lines[i].Netto = round2(lines[i].Netto)
+		lines[i].MwStBetrag = round2(lines[i].Netto * lines[i].SatzProzent / 100)
+	}
+	return lines
+}
+
+// Faelligkeit returns the due date (Rechnungsdatum + payment term) as
+// DD.MM.YYYY, or "" when the invoice date is invalid.
+func (r Rechnungsentwurf) Faelligkeit() string {
+	t, err := time.Parse("02.01.2006", r.Rechnungsdatum)
+	if err != nil {
+		return ""
+	}
+	days := r.ZahlungszielTage
+	if days <= 0 {
+		days = DefaultZahlungszielTage
+	}
+	return t.AddDate(0, 0, days).Format("02.01.2006")
+}
+
+// Meta returns the invoice as an Ausgangsrechnung row: the customer as
+// Auftraggeber, the computed tax lines and totals, and the positions
+// (numbered in order where the number is missing). Bezahldatum stays empty,
+// so the invoice is an open Forderung until paid.
+func (r Rechnungsentwurf) Meta() Meta {
+	lines := r.TaxLines()
+	waehrung := r.Waehrung
+	if waehrung == "" {
+		waehrung = "EUR"
+	}
+	betreff := strings.TrimSpace(r.Betreff)
+	if betreff == "" {
+		betreff = "Rechnung " + r.Rechnungsnummer
+	}
+	positionen := make([]InvoicePosition, len(r.Positionen))
+	for i, p := range r.Positionen {
+		if p.Nr == "" {
+			p.Nr = strconv.Itoa(i + 1)
+		}
+		positionen[i] = p
+	}
+	m := Meta{
+		Auftraggeber:      strings.TrimSpace(r.Kunde.Name),
+		Verwendungszweck:  betreff,
+		Rechnungsnummer:   r.Rechnungsnummer,
+		VATID:             strings.TrimSpace(r.KundeUStID),
+		Rechnungsdatum:    r.Rechnungsdatum,
+		TaxLines:          lines,
+		BetragNetto:       round2(SumNetto(lines)),
+		SteuersatzProzent: PrimarySatz(lines),
+		SteuersatzBetrag:  round2(SumMwSt(lines)),
+		Bruttobetrag:      round2(ComputeBrutto(lines, 0)),
+		Waehrung:          waehrung,
+		Positionen:        positionen,
+		Ausgangsrechnung:  true,
+	}
+	if parts := strings.Split(r.Rechnungsdatum, "."); len(parts) == 3 {
+		m.Jahr, m.Monat = parts[2], parts[1]
+	}
+	return m
+}
+
+// EInvoice returns the invoice as an OutgoingEInvoice for the ZUGFeRD data
+// embedded in the written PDF.
+func (r Rechnungsentwurf) EInvoice(verkaeufer Firmendaten, verkaeuferUStID string) OutgoingEInvoice {
+	return OutgoingEInvoice{
+		Meta:            r.Meta(),
+		Verkaeufer:      verkaeufer,
+		VerkaeuferUStID: verkaeuferUStID,
+		Kaeufer:         r.Kunde,
+		Kaeuferreferenz: r.Kaeuferreferenz,
+		Faelligkeit:     r.Faelligkeit(),
+		Leistungsdatum:  r.Leistungsdatum,
+	}
+}
+
+// steuerhinweis returns the legally required note for an invoice without
+// German VAT: reverse charge for an EU business customer (§14a Abs. 1
+// UStG), not taxable in Germany for other foreign customers. "" when VAT is
+// charged or the customer is domestic.
+func (r Rechnungsentwurf) steuerhinweis() string {
+	if SumMwSt(r.TaxLines()) != 0 {
+		return ""
+	}
+	switch {
+	case IsEUVatID(r.KundeUStID):
+		return "Steuerschuldnerschaft des Leistungsempfängers (Reverse Charge). " +
+			"USt-IdNr. des Leistungsempfängers: " + strings.ToUpper(strings.TrimSpace(r.KundeUStID))
+	case r.Kunde.Land != "" && !strings.EqualFold(r.Kunde.Land, "DE"):
+		return "Nicht im Inland steuerbare Leistung."
+	}
+	return ""
+}
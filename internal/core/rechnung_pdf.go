@@ -0,0 +1,334 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bergx2/buchisy/assets"
+	"github.com/go-pdf/fpdf"
+)
+
+// Einheit is a unit of quantity offered by the invoice writer: the UN/ECE
+// Rec. 20 code stored in the position (and the e-invoice) and its label.
+type Einheit struct {
+	Code  string
+	Label string
+}
+
+// Einheiten lists the units the invoice writer offers, most common first.
+var Einheiten = []Einheit{
+	{"H87", "Stück"},
+	{"HUR", "Std."},
+	{"DAY", "Tag"},
+	{"MON", "Monat"},
+	{"LS", "pauschal"},
+	{"KGM", "kg"},
+	{"MTR", "m"},
+	{"KMT", "km"},
+}
+
+// EinheitLabel returns the printable label of a unit code; unknown codes are
+// returned unchanged.
+func EinheitLabel(code string) string {
+	for _, e := range Einheiten {
+		if e.Code == code {
+			return e.Label
+		}
+	}
+	return code
+}
+
+// parseHexColor parses "#RRGGBB"; ok is false for anything else.
+func parseHexColor(s string) (r, g, b int, ok bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), true
+}
+
+// rechnungFusszeile builds the default page footer from the company data:
+// address, contact, tax numbers and bank details, " · "-separated, one line
+// per group.
+func rechnungFusszeile(firma Firmendaten, ustID string) string {
+	join := func(parts ...string) string {
+		var out []string
+		for _, p := range parts {
+			if strings.TrimSpace(p) != "" {
+				out = append(out, strings.TrimSpace(p))
+			}
+		}
+		return strings.Join(out, " · ")
+	}
+	var lines []string
+	for _, l := range []string{
+		join(firma.Name, firma.Strasse, strings.TrimSpace(firma.PLZ+" "+firma.Ort)),
+		join(labelled("Tel.", firma.Telefon), firma.Email,
+			labelled("St.-Nr.", firma.Steuernummer), labelled("USt-IdNr.", ustID)),
+		join(labelled("IBAN", firma.IBAN), labelled("BIC", firma.BIC)),
+	} {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// labelled returns "label value", or "" for an empty value.
+func labelled(label, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return ""
+	}
+	return label + " " + strings.TrimSpace(value)
+}
+
+// rechnungFont is the family the invoice PDF registers for the bundled Inter
+// TrueType fonts. They are embedded (as subsets) so the hybrid e-invoice can
+// be PDF/A-3 and so text outside cp1252 renders unchanged.
+const rechnungFont = "Inter"
+
+// BuildRechnungPDF renders an invoice written in BuchISY as an A4 PDF in
+// DIN 5008 layout: the sender line and customer address in the window
+// area, the invoice data block on the right, the positions, the totals per
+// VAT rate, the tax note for invoices without German VAT, the payment terms
+// and the company data in the footer of every page. The layout adds the
+// logo, the texts and the accent colour of title and table header.
+func BuildRechnungPDF(r Rechnungsentwurf, firma Firmendaten, ustID string, layout Rechnungslayout) ([]byte, error) {
+	meta := r.Meta()
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(rechnungFont, "", assets.InterRegularTTF)
+	pdf.AddUTF8FontFromBytes(rechnungFont, "B", assets.InterBoldTTF)
+	pdf.SetMargins(20, 15, 20)
+	pdf.SetAutoPageBreak(true, 30)
+	pdf.SetTitle("Rechnung "+r.Rechnungsnummer, true)
+	pdf.SetAuthor(firma.Name, true)
+	pdf.SetCreator("BuchISY", false)
+
+	footer := strings.TrimSpace(layout.Fusszeile)
+	if footer == "" {
+		footer = rechnungFusszeile(firma, ustID)
+	}
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-27)
+		pdf.SetDrawColor(180, 180, 180)
+		pdf.Line(20, pdf.GetY(), 190, pdf.GetY())
+		pdf.Ln(1.5)
+		pdf.SetFont(rechnungFont, "", 7)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.MultiCell(0, 3.5, footer, "", "C", false)
+		pdf.SetY(-10)
+		pdf.CellFormat(0, 4, fmt.Sprintf("Seite %d / {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+
+	// Logo top right; an unreadable logo is skipped, not fatal.
+	if logo := strings.TrimSpace(layout.LogoPfad); logo != "" && FileExists(logo) {
+		opt := fpdf.ImageOptions{ImageType: strings.TrimPrefix(strings.ToLower(filepath.Ext(logo)), "."), ReadDpi: true}
+		if opt.ImageType == "jpeg" {
+			opt.ImageType = "jpg"
+		}
+		if data, err := os.ReadFile(logo); err == nil {
+			pdf.RegisterImageOptionsReader(logo, opt, bytes.NewReader(data))
+			if pdf.Ok() {
+				pdf.ImageOptions(logo, 140, 12, 50, 0, false, opt, 0, "")
+			}
+			pdf.ClearError()
+		}
+	}
+
+	// Sender line and customer address (DIN 5008 form B window area).
+	pdf.SetXY(20, 45)
+	pdf.SetFont(rechnungFont, "U", 7)
+	sender := []string{firma.Name, firma.Strasse, strings.TrimSpace(firma.PLZ + " " + firma.Ort)}
+	pdf.CellFormat(85, 4, strings.Join(nonEmpty(sender), " · "), "", 1, "L", false, 0, "")
+	pdf.SetFont(rechnungFont, "", 10)
+	addr := []string{r.Kunde.Name, r.Kunde.Ansprechpartner, r.Kunde.Strasse, strings.TrimSpace(r.Kunde.PLZ + " " + r.Kunde.Ort)}
+	if land := strings.ToUpper(strings.TrimSpace(r.Kunde.Land)); land != "" && land != "DE" {
+		addr = append(addr, land)
+	}
+	for _, l := range nonEmpty(addr) {
+		pdf.SetX(20)
+		pdf.CellFormat(85, 5, l, "", 1, "L", false, 0, "")
+	}
+
+	// Invoice data block on the right.
+	leistung := strings.TrimSpace(r.Leistungsdatum)
+	if leistung == "" {
+		leistung = r.Rechnungsdatum
+	}
+	info := [][2]string{
+		{"Rechnungsnummer", r.Rechnungsnummer},
+		{"Rechnungsdatum", r.Rechnungsdatum},
+		{"Leistungsdatum", leistung},
+		{"Fällig am", r.Faelligkeit()},
+		{"Ihre Referenz", r.Kaeuferreferenz},
+		{"Ihre USt-IdNr.", strings.ToUpper(strings.TrimSpace(r.KundeUStID))},
+		{"Unsere USt-IdNr.", ustID},
+		{"Steuernummer", firma.Steuernummer},
+	}
+	y := 50.0
+	for _, kv := range info {
+		if strings.TrimSpace(kv[1]) == "" {
+			continue
+		}
+		pdf.SetXY(125, y)
+		pdf.SetFont(rechnungFont, "", 9)
+		pdf.CellFormat(32, 5, kv[0], "", 0, "L", false, 0, "")
+		pdf.SetFont(rechnungFont, "B", 9)
+		pdf.CellFormat(33, 5, kv[1], "", 0, "R", false, 0, "")
+		y += 5
+	}
+
+	// Title, subject and introduction.
+	pdf.SetXY(20, 98)
+	title := "Rechnung"
+	if meta.Bruttobetrag < 0 {
+		title = "Rechnungskorrektur"
+	}
+	ar, ag, ab, accent := parseHexColor(layout.Akzentfarbe)
+	if accent {
+		pdf.SetTextColor(ar, ag, ab)
+	}
+	pdf.SetFont(rechnungFont, "B", 14)
+	pdf.CellFormat(0, 8, title+" "+r.Rechnungsnummer, "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	if b := strings.TrimSpace(r.Betreff); b != "" {
+		pdf.SetFont(rechnungFont, "B", 10)
+		pdf.CellFormat(0, 6, b, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(2)
+	if t := strings.TrimSpace(layout.Einleitung); t != "" {
+		pdf.SetFont(rechnungFont, "", 10)
+		pdf.MultiCell(0, 5, t, "", "L", false)
+		pdf.Ln(3)
+	}
+
+	// Positions: the description wraps, every other column is one line.
+	headers := []string{"Pos.", "Bezeichnung", "Menge", "Einheit", "Einzelpreis", "USt", "Gesamt"}
+	widths := []float64{11, 69, 17, 17, 23, 12, 21}
+	aligns := []string{"L", "L", "R", "L", "R", "R", "R"}
+	header := func() {
+		if accent {
+			pdf.SetFillColor(ar, ag, ab)
+			pdf.SetTextColor(255, 255, 255)
+		} else {
+			pdf.SetFillColor(230, 230, 230)
+		}
+		pdf.SetFont(rechnungFont, "B", 9)
+		for i, h := range headers {
+			pdf.CellFormat(widths[i], 7, h, "", 0, aligns[i], true, 0, "")
+		}
+		pdf.Ln(7)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont(rechnungFont, "", 9)
+	}
+	header()
+	_, pageH := pdf.GetPageSize()
+	for _, p := range meta.Positionen {
+		desc := pdf.SplitText(p.Bezeichnung, widths[1]-2)
+		rowH := 5 * float64(max(len(desc), 1))
+		if pdf.GetY()+rowH > pageH-30 {
+			pdf.AddPage()
+			header()
+		}
+		menge, preis := "", ""
+		if p.Menge != 0 {
+			menge = pdfNumber(p.Menge)
+			preis = FormatAmount(p.Einzelpreis, ",")
+		}
+		x0, y0 := pdf.GetXY()
+		cells := []string{p.Nr, "", menge, EinheitLabel(p.Einheit), preis, pdfNumber(p.SatzProzent) + " %", FormatAmount(p.Netto, ",")}
+		x := x0
+		for c, s := range cells {
+			pdf.SetXY(x, y0)
+			if c == 1 {
+				pdf.MultiCell(widths[c], 5, strings.Join(desc, "\n"), "", "L", false)
+			} else {
+				pdf.CellFormat(widths[c], 5, s, "", 0, aligns[c], false, 0, "")
+			}
+			x += widths[c]
+		}
+		pdf.SetXY(x0, y0+rowH)
+		pdf.SetDrawColor(210, 210, 210)
+		pdf.Line(x0, pdf.GetY(), x0+170, pdf.GetY())
+	}
+	pdf.Ln(3)
+
+	// Totals: net, VAT per rate, gross.
+	total := func(label, value string, bold bool) {
+		style := ""
+		if bold {
+			style = "B"
+		}
+		pdf.SetFont(rechnungFont, style, 10)
+		pdf.SetX(90)
+		pdf.CellFormat(70, 6, label, "", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 6, value, "", 1, "R", false, 0, "")
+	}
+	cur := " " + meta.Waehrung
+	total("Summe netto", FormatAmount(meta.BetragNetto, ",")+cur, false)
+	for _, l := range meta.TaxLines {
+		total(fmt.Sprintf("zzgl. USt %s %% auf %s", pdfNumber(l.SatzProzent), FormatAmount(l.Netto, ",")),
+			FormatAmount(l.MwStBetrag, ",")+cur, false)
+	}
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.Line(120, pdf.GetY(), 190, pdf.GetY())
+	total("Gesamtbetrag", FormatAmount(meta.Bruttobetrag, ",")+cur, true)
+	pdf.Ln(4)
+
+	// Tax note, payment terms and closing text.
+	pdf.SetFont(rechnungFont, "", 10)
+	if hinweis := r.steuerhinweis(); hinweis != "" {
+		pdf.MultiCell(0, 5, hinweis, "", "L", false)
+		pdf.Ln(2)
+	}
+	if meta.Bruttobetrag > 0 {
+		terms := fmt.Sprintf("Bitte überweisen Sie den Betrag von %s%s bis zum %s ohne Abzug",
+			FormatAmount(meta.Bruttobetrag, ","), cur, r.Faelligkeit())
+		if iban := strings.TrimSpace(firma.IBAN); iban != "" {
+			terms += " auf das Konto IBAN " + iban
+			if bic := strings.TrimSpace(firma.BIC); bic != "" {
+				terms += " (BIC " + bic + ")"
+			}
+		}
+		terms += " unter Angabe der Rechnungsnummer " + r.Rechnungsnummer + "."
+		pdf.MultiCell(0, 5, terms, "", "L", false)
+		pdf.Ln(2)
+	}
+	if t := strings.TrimSpace(layout.Schlusstext); t != "" {
+		pdf.MultiCell(0, 5, t, "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pdfNumber formats a quantity or rate with a decimal comma and without
+// trailing zeros: 2 → "2", 1.5 → "1,5".
+func pdfNumber(v float64) string {
+	return strings.Replace(strconv.FormatFloat(round2(v), 'f', -1, 64), ".", ",", 1)
+}
+
+// nonEmpty returns the trimmed, non-empty strings of in.
+func nonEmpty(in []string) []string {
+	var out []string
+	for _, s := range in {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
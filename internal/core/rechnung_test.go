@@ -0,0 +1,218 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+func testRechnungsentwurf() Rechnungsentwurf {
+	return Rechnungsentwurf{
+		Kunde:            Firmendaten{Name: "Muster AG", Strasse: "Ring 5", PLZ: "80331", Ort: "München"},
+		Rechnungsnummer:  "RE-2026-0007",
+		Rechnungsdatum:   "15.04.2026",
+		Betreff:          "Entwicklung April",
+		ZahlungszielTage: 30,
+		Positionen: []InvoicePosition{
+			{Bezeichnung: "Softwareentwicklung", Menge: 12.5, Einheit: "HUR", Einzelpreis: 96, Netto: 1200, SatzProzent: 19},
+			{Bezeichnung: "Fachbuch", Menge: 1, Einheit: "H87", Einzelpreis: 39.9, Netto: 39.9, SatzProzent: 7},
+			{Bezeichnung: "Reisekosten pauschal", Menge: 1, Einheit: "LS", Einzelpreis: 80.33, Netto: 80.33, SatzProzent: 19},
+		},
+	}
+}
+
+func TestNextRechnungsnummer(t *testing.T) {
+	existing := []string{"RE-2026-0001", "RE-2026-0003", "RE-2025-0099", "RE-2026-00x4", "2026-0042", ""}
+	if got := NextRechnungsnummer(DefaultNummernkreis, 2026, existing); got != "RE-2026-0004" {
+		t.Errorf("next = %q, want RE-2026-0004", got)
+	}
+	if got := NextRechnungsnummer(DefaultNummernkreis, 2027, existing); got != "RE-2027-0001" {
+		t.Errorf("new year = %q, want RE-2027-0001", got)
+	}
+	if got := NextRechnungsnummer("${YY}/${NNNN}-K", 2026, []string{"26/0009-K", "26/0010"}); got != "26/0010-K" {
+		t.Errorf("suffix range = %q, want 26/0010-K", got)
+	}
+	// A range without the year counts on across years.
+	if got := NextRechnungsnummer("A-${NNNN}", 2027, []string{"A-0041"}); got != "A-0042" {
+		t.Errorf("continuous range = %q, want A-0042", got)
+	}
+	if got := (Rechnungslayout{Nummernkreis: "AR${YYYY}"}).NummernkreisOrDefault(); got != "AR${YYYY}-${NNNN}" {
+		t.Errorf("pattern without counter = %q", got)
+	}
+}
+
+func TestRechnungsentwurf_MetaAndTaxLines(t *testing.T) {
+	r := testRechnungsentwurf()
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	m := r.Meta()
+	if len(m.TaxLines) != 2 {
+		t.Fatalf("TaxLines = %+v, want 19 %% and 7 %%", m.TaxLines)
+	}
+	if l := m.TaxLines[0]; l.SatzProzent != 19 || l.Netto != 1280.33 || l.MwStBetrag != 243.26 {
+		t.Errorf("19 %% line = %+v, want 1280.33 / 243.26", l)
+	}
+	if m.BetragNetto != 1320.23 || m.SteuersatzBetrag != 246.05 || m.Bruttobetrag != 1566.28 {
+		t.Errorf("totals = %v / %v / %v", m.BetragNetto, m.SteuersatzBetrag, m.Bruttobetrag)
+	}
+	if !m.Ausgangsrechnung || m.Bezahldatum != "" || m.Jahr != "2026" || m.Monat != "04" || m.Waehrung != "EUR" {
+		t.Errorf("meta = %+v", m)
+	}
+	if m.Positionen[2].Nr != "3" || r.Positionen[2].Nr != "" {
+		t.Errorf("positions not numbered on a copy: %q / %q", m.Positionen[2].Nr, r.Positionen[2].Nr)
+	}
+	if got := r.Faelligkeit(); got != "15.05.2026" {
+		t.Errorf("Faelligkeit = %q, want 15.05.2026", got)
+	}
+}
+
+func TestRechnungsentwurf_Validate(t *testing.T) {
+	cases := map[string]func(*Rechnungsentwurf){
+		"kein Kunde":        func(r *Rechnungsentwurf) { r.Kunde.Name = " " },
+		"Datum":             func(r *Rechnungsentwurf) { r.Rechnungsdatum = "2026-04-15" },
+		"Leistungsdatum":    func(r *Rechnungsentwurf) { r.Leistungsdatum = "April" },
+		"keine Positionen":  func(r *Rechnungsentwurf) { r.Positionen = nil },
+		"leere Bezeichnung": func(r *Rechnungsentwurf) { r.Positionen[1].Bezeichnung = "" },
+		"Rechnungsbetrag 0,00": func(r *Rechnungsentwurf) {
+			r.Positionen = []InvoicePosition{{Bezeichnung: "Gratis", Menge: 1}}
+		},
+	}
+	for name, mutate := range cases {
+		r := testRechnungsentwurf()
+		mutate(&r)
+		if err := r.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRechnungsentwurf_Steuerhinweis(t *testing.T) {
+	r := testRechnungsentwurf()
+	if h := r.steuerhinweis(); h != "" {
+		t.Errorf("domestic invoice with VAT: hint %q", h)
+	}
+	for i := range r.Positionen {
+		r.Positionen[i].SatzProzent = 0
+	}
+	r.KundeUStID = "fr12345678901"
+	if h := r.steuerhinweis(); !strings.Contains(h, "Reverse Charge") || !strings.Contains(h, "FR12345678901") {
+		t.Errorf("EU customer: hint %q", h)
+	}
+	r.KundeUStID = ""
+	r.Kunde.Land = "CH"
+	if h := r.steuerhinweis(); !strings.Contains(h, "Nicht im Inland steuerbar") {
+		t.Errorf("third country: hint %q", h)
+	}
+}
+
+func TestBuildRechnungPDF_WithFacturX(t *testing.T) {
+	r := testRechnungsentwurf()
+	firma := Firmendaten{
+		Name: "Beispiel Consulting GmbH", Strasse: "Hauptstr. 1", PLZ: "10115", Ort: "Berlin",
+		Steuernummer: "30/123/45678", Email: "rechnung@beispiel.de",
+		IBAN: "DE02 1203 0000 0000 2020 51", BIC: "BYLADEM1001",
+	}
+	layout := Rechnungslayout{Einleitung: "Vielen Dank für Ihren Auftrag.", Akzentfarbe: "#1F4E79", LogoPfad: "/nonexistent/logo.png"}
+	pdfData, err := BuildRechnungPDF(r, firma, "DE123456789", layout)
+	if err != nil {
+		t.Fatalf("BuildRechnungPDF: %v", err)
+	}
+	if !bytes.HasPrefix(pdfData, []byte("%PDF-")) {
+		t.Fatalf("not a PDF: %q", pdfData[:8])
+	}
+
+	cii, err := BuildCIIInvoiceXML(r.EInvoice(firma, "DE123456789"), GuidelineEN16931)
+	if err != nil {
+		t.Fatalf("BuildCIIInvoiceXML: %v", err)
+	}
+	if v, _ := ValidateEInvoiceXML(cii); len(v.Findings) != 0 {
+		t.Fatalf("written invoice e-invoice data has findings: %v", v.Findings)
+	}
+	out, err := EmbedFacturX(pdfData, cii, "Rechnung "+r.Rechnungsnummer, time.Now())
+	if err != nil {
+		t.Fatalf("EmbedFacturX: %v", err)
+	}
+
+	// PDF/A-3: embedded fonts, sRGB output intent, Info and XMP in sync.
+	ctx, err := api.ReadContext(bytes.NewReader(out), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("read hybrid PDF: %v", err)
+	}
+	xrt := ctx.XRefTable
+	if !fontsEingebettet(xrt) {
+		t.Error("invoice fonts are not embedded")
+	}
+	catalog, err := xrt.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, _ := catalog.Find("OutputIntents")
+	intents, err := xrt.DereferenceArray(o)
+	if err != nil || len(intents) != 1 {
+		t.Fatalf("OutputIntents = %v, %v", o, err)
+	}
+	oi, err := xrt.DereferenceDict(intents[0])
+	if err != nil || oi.NameEntry("S") == nil || *oi.NameEntry("S") != "GTS_PDFA1" {
+		t.Fatalf("output intent = %v, %v", oi, err)
+	}
+	profile, _, err := xrt.DereferenceStreamDict(oi["DestOutputProfile"])
+	if err != nil || profile == nil || profile.IntEntry("N") == nil || *profile.IntEntry("N") != 3 {
+		t.Fatalf("DestOutputProfile = %v, %v", profile, err)
+	}
+	if !bytes.Contains(out, []byte("<pdfaid:part>3</pdfaid:part>")) {
+		t.Error("XMP lacks the PDF/A-3 identification")
+	}
+	info, err := xrt.DereferenceDict(*xrt.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, ok := types.DateTime(info.StringLiteralEntry("CreationDate").Value(), true)
+	if !ok {
+		t.Fatalf("Info CreationDate = %v", info["CreationDate"])
+	}
+	if want := "<xmp:CreateDate>" + created.Format(xmpDateLayout) + "</xmp:CreateDate>"; !bytes.Contains(out, []byte(want)) {
+		t.Errorf("XMP lacks %s", want)
+	}
+	if bytes.Contains(out, []byte(xmpDatePlaceholder)) {
+		t.Error("XMP date placeholder left in the file")
+	}
+	if !bytes.Contains(out, []byte("<rdf:li>"+firma.Name+"</rdf:li>")) {
+		t.Error("XMP lacks the author")
+	}
+}
+
+func TestRechnungFusszeile(t *testing.T) {
+	got := rechnungFusszeile(Firmendaten{Name: "A GmbH", Ort: "Berlin", IBAN: "DE02"}, "DE1")
+	want := "A GmbH · Berlin\nUSt-IdNr. DE1\nIBAN DE02"
+	if got != want {
+		t.Errorf("footer = %q, want %q", got, want)
+	}
+}
+
+func TestKundenStore(t *testing.T) {
+	dir := t.TempDir()
+	s := NewKundenStore(dir)
+	s.Set(Kunde{Firmendaten: Firmendaten{Name: "Muster AG", Ort: "München"}, UStID: "DE999999999"})
+	s.Set(Kunde{Firmendaten: Firmendaten{Name: "alpha GmbH"}})
+	s.Set(Kunde{})
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded := NewKundenStore(dir)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	k, ok := loaded.Get("MUSTER AG")
+	if !ok || k.Ort != "München" || k.UStID != "DE999999999" {
+		t.Errorf("Get = %+v, %v", k, ok)
+	}
+	if names := loaded.Namen(); len(names) != 2 || names[0] != "alpha GmbH" {
+		t.Errorf("Namen = %v", names)
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SichtbelegName returns the file name of the human-readable rendering that
+// is generated for a standalone e-invoice XML: the same base name as ".pdf".
+func SichtbelegName(xmlName string) string {
+	return ReplaceExtension(xmlName, ".pdf")
+}
+
+// BuildEInvoiceSichtbelegPDF renders a standalone e-invoice as a portrait PDF
+// (Sichtbeleg) so the table, the preview and the Belegpaket have a readable
+// document: the header data, the positions, the VAT breakdown and the totals. The XML stays
+// the original; the PDF says so and names it (xmlName).
+func BuildEInvoiceSichtbelegPDF(meta Meta, format EInvoiceFormat, xmlName string) ([]byte, error) {
+	title := "Sichtbeleg E-Rechnung"
+	if format != FormatNone {
+		title += " (" + string(format) + ")"
+	}
+	pdf, tr := newReportPDF(title, "P", meta.Auftraggeber)
+
+	field := func(label, value string) {
+		if strings.TrimSpace(value) == "" {
+			return
+		}
+		pdf.SetFont("Arial", "B", 9)
+		pdf.CellFormat(45, 6, tr(label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 9)
+		pdf.CellFormat(0, 6, tr(truncate(value, 90)), "", 1, "L", false, 0, "")
+	}
+	field("Rechnungssteller", meta.Auftraggeber)
+	field("USt-IdNr.", meta.VATID)
+	field("Rechnungsnummer", meta.Rechnungsnummer)
+	field("Rechnungsdatum", meta.Rechnungsdatum)
+	field("Fällig am", meta.Faelligkeit)
+	field("Verwendungszweck", meta.Verwendungszweck)
+	field("Währung", meta.Waehrung)
+	pdf.Ln(3)
+
+	// Positions (invoice lines, allowances, charges).
+	if len(meta.Positionen) > 0 {
+		ph := []string{"Pos.", "Bezeichnung", "Menge", "Einzelpreis", "USt %", "Netto"}
+		pw := []float64{12, 73, 22, 25, 18, 30}
+		pdfTableHeader(pdf, tr, ph, pw)
+		for _, p := range meta.Positionen {
+			pdfPageBreak(pdf, tr, ph, pw, 6)
+			menge, preis := "", ""
+			if p.Menge != 0 {
+				menge = strings.TrimSpace(pdfAmount(p.Menge) + " " + p.Einheit)
+				preis = pdfAmount(p.Einzelpreis)
+			}
+			pdf.CellFormat(pw[0], 6, tr(p.Nr), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(pw[1], 6, tr(truncate(p.Bezeichnung, 45)), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(pw[2], 6, tr(menge), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(pw[3], 6, tr(preis), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(pw[4], 6, tr(pdfAmount(p.SatzProzent)), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(pw[5], 6, tr(pdfAmount(p.Netto)), "1", 0, "R", false, 0, "")
+			pdf.Ln(6)
+		}
+		pdf.Ln(4)
+	}
+
+	// VAT breakdown (one row per Steuerzeile).
+	headers := []string{"Steuersatz", "Netto", "USt", "Brutto"}
+	widths := []float64{40, 45, 45, 45}
+	pdfTableHeader(pdf, tr, headers, widths)
+	lines := meta.TaxLines
+	if len(lines) == 0 {
+		lines = ReconstructTaxLines(meta.BetragNetto, meta.SteuersatzProzent, meta.SteuersatzBetrag, meta.Bruttobetrag)
+	}
+	for _, l := range lines {
+		pdfPageBreak(pdf, tr, headers, widths, 6)
+		pdf.CellFormat(widths[0], 6, tr(fmt.Sprintf("%s %%", pdfAmount(l.SatzProzent))), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, tr(pdfAmount(l.Netto)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(pdfAmount(l.MwStBetrag)), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, tr(pdfAmount(l.Netto+l.MwStBetrag)), "1", 0, "R", false, 0, "")
+		pdf.Ln(6)
+	}
+	pdfPageBreak(pdf, tr, headers, widths, 7)
+	pdf.SetFont("Arial", "B", 9)
+	pdf.CellFormat(widths[0], 7, tr("Summe"), "1", 0, "L", false, 0, "")
+	pdf.CellFormat(widths[1], 7, tr(pdfAmount(meta.BetragNetto)), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[2], 7, tr(pdfAmount(meta.SteuersatzBetrag)), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[3], 7, tr(pdfAmount(meta.Bruttobetrag)), "1", 0, "R", false, 0, "")
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "I", 8)
+	note := "Maschinell erzeugte Sichtdarstellung einer elektronischen Rechnung. " +
+		"Maßgeblich ist die strukturierte XML-Datei"
+	if xmlName != "" {
+		note += " (" + xmlName + ")"
+	}
+	note += ", die als Anhang zu diesem Beleg archiviert ist."
+	pdf.MultiCell(0, 4.5, tr(note), "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
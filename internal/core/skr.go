@@ -9,6 +9,7 @@ type SKRAccounts struct {
 	VStRC, UStRC int // §13b reverse charge
 	BewAbz, BewNicht int // Bewirtung abziehbar / nicht abziehbar
 	ErloesInland, ErloesEU, ErloesDrittland int
+	Anlagenerloes []int // Erlöse aus Verkäufen Sachanlagevermögen (§ 19 Gesamtumsatz)
 }
 
 // StandardSKR returns the standard account numbers for the given variant
@@ -26,6 +27,7 @@ func StandardSKR(variant string) (SKRAccounts, bool) {
 			ErloesInland:    8400,
 			ErloesEU:        8341,
 			ErloesDrittland: 8200,
+			Anlagenerloes:   []int{8800, 8801, 8820, 8829},
 		}, true
 	case "SKR04":
 		return SKRAccounts{
@@ -38,15 +40,17 @@ func StandardSKR(variant string) (SKRAccounts, bool) {
 			ErloesInland:    4400,
 			ErloesEU:        4125,
 			ErloesDrittland: 4120,
+			Anlagenerloes:   []int{4845, 4849, 6885, 6889},
 		}, true
 	}
 	return SKRAccounts{}, false
 }
 
 // ApplySKRVariant returns a deep copy of rules with all standard accounts set
-// to the variant's: Vorsteuer/Umsatzsteuer/Erloes maps, plus the bewirtung
-// and reverse_charge rule accounts. Other rule fields (percentages, names,
-// DefaultKonto, etc.) are preserved from the original.
+// to the variant's: Vorsteuer/Umsatzsteuer/Erloes maps, the Anlagenerlös
+// accounts, plus the bewirtung and reverse_charge(_eu) rule accounts. Other
+// rule fields (percentages, names, DefaultKonto, etc.) are preserved from the
+// original.
 // Returns nil if the variant is unknown.
 func ApplySKRVariant(rules *BookingRules, variant string) *BookingRules {
 	accs, ok := StandardSKR(variant)
@@ -95,7 +99,7 @@ func ApplySKRVariant(rules *BookingRules, variant string) *BookingRules {
 		case "bewirtung":
 			regeln[i].KontoAbziehbar = accs.BewAbz
 			regeln[i].KontoNichtAbziehbar = accs.BewNicht
-		case "reverse_charge":
+		case "reverse_charge", "reverse_charge_eu":
 			regeln[i].KontoVStRC = accs.VStRC
 			regeln[i].KontoUStRC = accs.UStRC
 		}
@@ -125,6 +129,10 @@ func ApplySKRVariant(rules *BookingRules, variant string) *BookingRules {
 		ForderungsKonto:    rules.ForderungsKonto,
 		KontoStichwoerter:  stichwoerter,
 		Regeln:             regeln,
+		// The explicit Kennzahl mapping is the user's; ValidateBookingAccounts
+		// flags entries whose accounts the new chart lacks.
+		UStVAKonten:         append([]KennzahlKonto(nil), rules.UStVAKonten...),
+		AnlagenerloesKonten: append([]int(nil), accs.Anlagenerloes...),
 	}
 }
 
@@ -174,6 +182,15 @@ func ValidateBookingAccounts(rules *BookingRules, chart *ChartOfAccounts) []stri
 		check(fmt.Sprintf("%s DefaultKonto", label), r.DefaultKonto)
 	}
 
+	// Explicit UStVA Kennzahl mapping.
+	for _, k := range rules.UStVAKonten {
+		kz := k.Kz
+		if kz == "" {
+			kz = k.BasisKz
+		}
+		check(fmt.Sprintf("UStVA-Zuordnung Kz %s", kz), k.Konto)
+	}
+
 	return issues
 }
 
@@ -145,6 +145,10 @@ func TestApplySKRVariant_SKR03(t *testing.T) {
 		t.Errorf("UmsatzsteuerKonten[19] = %d, want 1776", applied.UmsatzsteuerKonten["19"])
 	}
 
+	if !applied.IsAnlagenerloesKonto(8820) || applied.IsAnlagenerloesKonto(4845) {
+		t.Errorf("AnlagenerloesKonten = %v, want the SKR03 accounts", applied.AnlagenerloesKonten)
+	}
+
 	// Erloes maps.
 	if applied.ErloesKonten["inland"] != 8400 {
 		t.Errorf("ErloesKonten[inland] = %d, want 8400", applied.ErloesKonten["inland"])
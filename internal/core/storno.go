@@ -0,0 +1,140 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IsStorno reports whether the row reverses another booking.
+func (r CSVRow) IsStorno() bool {
+	return r.StornoZu != ""
+}
+
+// StornoKey is the reference a Storno uses for this row: its Belegnummer, or
+// the file name for receipts archived before Belegnummern existed.
+func (r CSVRow) StornoKey() string {
+	if r.Belegnummer != "" {
+		return r.Belegnummer
+	}
+	return r.Dateiname
+}
+
+// BuildStorno returns the mirrored booking that reverses orig on datum
+// (Rot-Storno: the same accounts and sides with negated amounts, so Journal,
+// SuSa and UStVA net to zero over both periods). Belegnummer and Dateiname
+// are left for the caller; grund goes into the Kommentar. The original's
+// bank link, attachments and export flag are not carried over.
+func BuildStorno(orig CSVRow, datum time.Time, grund string) CSVRow {
+	s := orig
+	s.Belegnummer = ""
+	s.Dateiname = ""
+	s.StornoZu = orig.StornoKey()
+	s.Rechnungsdatum = datum.Format("02.01.2006")
+	s.Jahr = fmt.Sprintf("%04d", datum.Year())
+	s.Monat = fmt.Sprintf("%02d", int(datum.Month()))
+	s.Verwendungszweck = truncate("Storno "+s.StornoZu+": "+orig.Verwendungszweck, 80)
+	s.Kommentar = strings.TrimSpace(grund)
+	s.BewirtungAnlass, s.BewirtungTeilnehmer, s.BewirtungAngabenAufBeleg = "", "", false
+
+	s.BetragNetto = -orig.BetragNetto
+	s.SteuersatzBetrag = -orig.SteuersatzBetrag
+	s.Bruttobetrag = -orig.Bruttobetrag
+	s.Trinkgeld = -orig.Trinkgeld
+	s.BetragNetto_EUR = -orig.BetragNetto_EUR
+	s.Gebuehr = -orig.Gebuehr
+	s.Rabatt = -orig.Rabatt
+	s.TaxLines = make([]TaxLine, len(orig.TaxLines))
+	for i, l := range orig.TaxLines {
+		s.TaxLines[i] = TaxLine{Netto: -l.Netto, SatzProzent: l.SatzProzent, MwStBetrag: -l.MwStBetrag}
+	}
+	s.Buchung = Booking{
+		Info:    "Storno zu Beleg " + s.StornoZu,
+		Manuell: true,
+		Entries: make([]BookingEntry, len(orig.Buchung.Entries)),
+	}
+	for i, e := range orig.Buchung.Entries {
+		e.Betrag = -e.Betrag
+		s.Buchung.Entries[i] = e
+	}
+
+	s.BuchungRef = ""
+	s.Exportiert = false
+	s.HatAnhaenge = false
+	s.AnzahlAnhaenge = 0
+	s.Originalwaehrung, s.Originalbetrag_Brutto = "", 0
+	return s
+}
+
+// ReversedKeys returns the StornoKeys of the rows reversed by a Storno
+// among rows.
+func ReversedKeys(rows []CSVRow) map[string]bool {
+	out := map[string]bool{}
+	for _, r := range rows {
+		if r.IsStorno() {
+			out[r.StornoZu] = true
+		}
+	}
+	return out
+}
+
+// BuildStornoBelegPDF renders the Eigenbeleg filed with a Storno: which
+// booking it reverses, the reason, the amounts and the reversing entries.
+func BuildStornoBelegPDF(storno, orig CSVRow, chart *ChartOfAccounts, company string) ([]byte, error) {
+	pdf, tr := newReportPDF("Stornobeleg "+storno.Belegnummer, "P", company)
+
+	field := func(label, value string) {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(50, 6, tr(label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 10)
+		pdf.MultiCell(0, 6, tr(value), "", "L", false)
+	}
+	pdf.Ln(2)
+	field("Stornodatum", storno.Rechnungsdatum)
+	field("Periode", storno.Jahr+"-"+storno.Monat)
+	field("Storniert wird", fmt.Sprintf("Beleg %s vom %s (Periode %s-%s)",
+		storno.StornoZu, orig.Rechnungsdatum, orig.Jahr, orig.Monat))
+	field("Auftraggeber", orig.Auftraggeber)
+	if orig.Rechnungsnummer != "" {
+		field("Rechnungsnummer", orig.Rechnungsnummer)
+	}
+	field("Verwendungszweck", orig.Verwendungszweck)
+	grund := storno.Kommentar
+	if grund == "" {
+		grund = "-"
+	}
+	field("Grund", grund)
+	cur := storno.Waehrung
+	if cur == "" {
+		cur = "EUR"
+	}
+	field("Betrag", fmt.Sprintf("netto %s · USt %s · brutto %s %s",
+		pdfAmount(storno.BetragNetto), pdfAmount(storno.SteuersatzBetrag), pdfAmount(storno.Bruttobetrag), cur))
+	pdf.Ln(4)
+
+	headers := []string{"Konto", "Soll", "Haben"}
+	widths := []float64{110, 35, 35}
+	pdfTableHeader(pdf, tr, headers, widths)
+	for _, e := range storno.Buchung.Entries {
+		pdfPageBreak(pdf, tr, headers, widths, 6)
+		soll, haben := pdfAmount(e.Betrag), ""
+		if !e.Soll {
+			soll, haben = "", soll
+		}
+		pdf.CellFormat(widths[0], 6, tr(truncate(kontoLabelPDF(chart, e.Konto), 60)), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, tr(soll), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(haben), "1", 0, "R", false, 0, "")
+		pdf.Ln(6)
+	}
+	pdf.Ln(4)
+	pdf.SetFont("Arial", "I", 9)
+	pdf.MultiCell(0, 5, tr("Eigenbeleg. Die festgeschriebene Buchung bleibt unverändert; "+
+		"diese Gegenbuchung hebt sie in der laufenden Periode auf."), "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
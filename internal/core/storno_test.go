@@ -0,0 +1,108 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stornoOriginal is a locked-period incoming invoice: 100 net + 19 VAT,
+// booked on 6815/1406 against the bank.
+func stornoOriginal() CSVRow {
+	return CSVRow{
+		Belegnummer: "2026-0007", Dateiname: "a.pdf", Jahr: "2026", Monat: "01",
+		Rechnungsdatum: "14.01.2026", Auftraggeber: "Büro AG", Verwendungszweck: "Papier",
+		BetragNetto: 100, SteuersatzProzent: 19, SteuersatzBetrag: 19, Bruttobetrag: 119,
+		TaxLines:  []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}},
+		Bankkonto: "Giro", BuchungRef: "kontoauszug.pdf|1|3", Exportiert: true, HatAnhaenge: true,
+		Buchung: Booking{Entries: []BookingEntry{
+			{Konto: 6815, Betrag: 100, Soll: true},
+			{Konto: 1406, Betrag: 19, Soll: true},
+			{Konto: 1800, Betrag: 119, Soll: false},
+		}},
+	}
+}
+
+func TestBuildStorno(t *testing.T) {
+	orig := stornoOriginal()
+	s := BuildStorno(orig, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), " falsches Konto ")
+	if !s.IsStorno() || s.StornoZu != "2026-0007" {
+		t.Fatalf("StornoZu = %q", s.StornoZu)
+	}
+	if s.Rechnungsdatum != "05.03.2026" || s.Jahr != "2026" || s.Monat != "03" {
+		t.Errorf("period = %s %s-%s", s.Rechnungsdatum, s.Jahr, s.Monat)
+	}
+	if s.Bruttobetrag != -119 || s.BetragNetto != -100 || s.TaxLines[0].MwStBetrag != -19 {
+		t.Errorf("amounts = %v/%v/%+v", s.BetragNetto, s.Bruttobetrag, s.TaxLines)
+	}
+	if !s.Buchung.Balanced() || s.Buchung.Entries[2].Betrag != -119 || s.Buchung.Entries[2].Soll {
+		t.Errorf("booking = %+v", s.Buchung)
+	}
+	if s.Kommentar != "falsches Konto" || s.BuchungRef != "" || s.Exportiert || s.HatAnhaenge || s.Belegnummer != "" {
+		t.Errorf("carried over: %+v", s)
+	}
+	if orig.TaxLines[0].Netto != 100 || orig.Buchung.Entries[0].Betrag != 100 {
+		t.Error("BuildStorno modified the original")
+	}
+}
+
+func TestStorno_NetsToZero(t *testing.T) {
+	rules, _ := ParseBookingRules([]byte(`{"regeln":[{"kategorie":"reverse_charge","rc_satz":19}]}`))
+	in := stornoOriginal()
+	out := CSVRow{Belegnummer: "2026-0008", Ausgangsrechnung: true, BetragNetto: 200, Bruttobetrag: 238,
+		TaxLines: []TaxLine{{Netto: 200, SatzProzent: 19, MwStBetrag: 38}},
+		Buchung: Booking{Entries: []BookingEntry{
+			{Konto: 1200, Betrag: 238, Soll: true},
+			{Konto: 4400, Betrag: 200, Soll: false},
+			{Konto: 3806, Betrag: 38, Soll: false},
+		}}}
+	rc := CSVRow{Belegnummer: "2026-0009", VATID: "IE6388047V", BetragNetto: 50, Bruttobetrag: 50,
+		TaxLines: []TaxLine{{Netto: 50}}}
+	now := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	rows := []CSVRow{in, out, rc, BuildStorno(in, now, ""), BuildStorno(out, now, ""), BuildStorno(rc, now, "")}
+
+	if u := ComputeUStVAOfficial(rows, rules); u != (UStVAOfficial{}) {
+		t.Errorf("UStVA = %+v, want all zero", u)
+	}
+	// The Storno alone is the negative of the original in its own month.
+	if u := ComputeUStVAOfficial(rows[3:], rules); u.Kz66 != -19 || u.Kz81 != -200 || u.Kz46 != -50 {
+		t.Errorf("Storno UStVA = %+v", u)
+	}
+	for _, b := range ComputeSuSa(rows, nil) {
+		if b.Saldo != 0 || b.SollSumme != 0 || b.HabenSumme != 0 {
+			t.Errorf("SuSa %d = %+v", b.Konto, b)
+		}
+	}
+}
+
+func TestStorno_ExportsAndOpenItems(t *testing.T) {
+	orig := stornoOriginal()
+	orig.BuchungRef = ""
+	s := BuildStorno(orig, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), "")
+	s.Belegnummer = "2026-0031"
+
+	data, exported, skipped := BuildDATEVStapel(DATEVHeader{}, []CSVRow{s})
+	if exported != 2 || skipped != 0 {
+		t.Fatalf("exported=%d skipped=%d", exported, skipped)
+	}
+	// -100 Soll 6815 against 1800 goes out as 100 Haben.
+	if !strings.Contains(string(data), `100,00;"H";"EUR";;;;6815;1800;;0503;"2026-0031"`) {
+		t.Errorf("DATEV:\n%s", data)
+	}
+	lex, _, _ := BuildLexwareCSV([]CSVRow{s})
+	if !strings.Contains(string(lex), ";100,00;1800;6815") {
+		t.Errorf("Lexware:\n%s", lex)
+	}
+
+	other := CSVRow{Belegnummer: "2026-0010", Rechnungsdatum: "01.02.2026", Bruttobetrag: 5}
+	oi := ComputeOpenItems([]CSVRow{orig, s, other}, time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC))
+	if len(oi.Verbindlichkeiten) != 1 || oi.Verbindlichkeiten[0].Belegnummer != "2026-0010" {
+		t.Errorf("open items = %+v", oi.Verbindlichkeiten)
+	}
+
+	pdf, err := BuildStornoBelegPDF(s, orig, nil, "Test GmbH")
+	if err != nil || !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Errorf("Stornobeleg: %v", err)
+	}
+}
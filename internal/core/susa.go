@@ -6,10 +6,12 @@ import (
 )
 
 // AccountBalance is one row in the trial balance (Summen- und Saldenliste).
-// Saldo = SollSumme − HabenSumme (positive = debit excess, negative = credit excess).
+// Saldo = EBWert + SollSumme − HabenSumme (positive = debit excess, negative
+// = credit excess).
 type AccountBalance struct {
 	Konto      int
 	Name       string
+	EBWert     float64 // opening balance (Eröffnungsbilanzwert), Soll positive
 	SollSumme  float64
 	HabenSumme float64
 	Saldo      float64
@@ -17,11 +19,12 @@ type AccountBalance struct {
 
 // ComputeSuSa computes the trial balance from all booking entries in rows.
 // It accumulates Soll and Haben per account number, then returns a sorted
-// slice (ascending by Konto). Name is resolved via chart.Find; if chart is
+// slice (ascending by Konto). EB-Wert rows (OpeningRows) go into EBWert
+// instead of the sums. Name is resolved via chart.Find; if chart is
 // nil or the account is not found, Name falls back to the number string.
 func ComputeSuSa(rows []CSVRow, chart *ChartOfAccounts) []AccountBalance {
 	rows = RowsEUR(rows)
-	type sums struct{ soll, haben float64 }
+	type sums struct{ eb, soll, haben float64 }
 	acc := make(map[int]*sums)
 
 	for _, r := range rows {
@@ -31,7 +34,13 @@ func ComputeSuSa(rows []CSVRow, chart *ChartOfAccounts) []AccountBalance {
 				s = &sums{}
 				acc[e.Konto] = s
 			}
-			if e.Soll {
+			if r.Eroeffnung {
+				if e.Soll {
+					s.eb += e.Betrag
+				} else {
+					s.eb -= e.Betrag
+				}
+			} else if e.Soll {
 				s.soll += e.Betrag
 			} else {
 				s.haben += e.Betrag
@@ -50,9 +59,10 @@ func ComputeSuSa(rows []CSVRow, chart *ChartOfAccounts) []AccountBalance {
 		out = append(out, AccountBalance{
 			Konto:      konto,
 			Name:       name,
+			EBWert:     round2(s.eb),
 			SollSumme:  round2(s.soll),
 			HabenSumme: round2(s.haben),
-			Saldo:      round2(s.soll - s.haben),
+			Saldo:      round2(s.eb + s.soll - s.haben),
 		})
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].Konto < out[j].Konto })
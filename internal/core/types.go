@@ -8,32 +8,34 @@ import (
 
 // Meta represents the invoice metadata extracted from a PDF.
 type Meta struct {
-	Belegnummer         string    // Sequential receipt number per profile+year, "YYYY-NNNN"
-	Auftraggeber        string    // Company name (previously Firmenname)
-	Verwendungszweck    string    // Purpose/description (previously Kurzbezeichnung)
-	Rechnungsnummer     string    // Invoice number
-	VATID               string    // counterparty VAT-ID: supplier (incoming) or customer (Ausgangsrechnung); used for the ZM
-	BetragNetto         float64   // Net amount
-	SteuersatzProzent   float64   // Tax rate in percent
-	SteuersatzBetrag    float64   // Tax amount
-	Bruttobetrag        float64   // Gross amount
-	TaxLines            []TaxLine // VAT lines; aggregates above are their sums
-	Trinkgeld           float64   // tip, no VAT, only part of Bruttobetrag
-	Waehrung            string    // Currency (EUR, USD, etc.)
-	Rechnungsdatum      string    // Invoice date DD.MM.YYYY
-	Jahr                string    // Year YYYY
-	Monat               string    // Month MM
-	Gegenkonto          int       // Account code
-	KontoVorschlaege    []int     // transient: AI-suggested Gegenkonten for unknown suppliers (not persisted)
-	Bankkonto           string    // Bank account
-	Bezahldatum         string    // Payment date DD.MM.YYYY
-	BarBezahlt          bool      `json:"-"` // transient: invoice was paid in cash (bar/Bargeld); not persisted to DB or CSV
-	Teilzahlung         bool      // Partial payment flag
-	Ausgangsrechnung    bool      // true = outgoing/revenue invoice (Erlös)
-	Dateiname           string    // Final filename
-	Kommentar           string    // Comment/note for this invoice
-	BewirtungAnlass     string    // Occasion/purpose for entertainment expenses (§ 4 Abs. 5 EStG)
-	BewirtungTeilnehmer string    // Participants for entertainment expenses (§ 4 Abs. 5 EStG)
+	Belegnummer         string            // Sequential receipt number per profile+year, "YYYY-NNNN"
+	Auftraggeber        string            // Company name (previously Firmenname)
+	Verwendungszweck    string            // Purpose/description (previously Kurzbezeichnung)
+	Rechnungsnummer     string            // Invoice number
+	VATID               string            // counterparty VAT-ID: supplier (incoming) or customer (Ausgangsrechnung); used for the ZM
+	BetragNetto         float64           // Net amount
+	SteuersatzProzent   float64           // Tax rate in percent
+	SteuersatzBetrag    float64           // Tax amount
+	Bruttobetrag        float64           // Gross amount
+	TaxLines            []TaxLine         // VAT lines; aggregates above are their sums
+	Positionen          []InvoicePosition // transient: e-invoice line items incl. allowances/charges (not persisted; the XML stays the source)
+	Trinkgeld           float64           // tip, no VAT, only part of Bruttobetrag
+	Waehrung            string            // Currency (EUR, USD, etc.)
+	Rechnungsdatum      string            // Invoice date DD.MM.YYYY
+	Jahr                string            // Year YYYY
+	Monat               string            // Month MM
+	Gegenkonto          int               // Account code
+	KontoVorschlaege    []int             // transient: AI-suggested Gegenkonten for unknown suppliers (not persisted)
+	Bankkonto           string            // Bank account
+	Bezahldatum         string            // Payment date DD.MM.YYYY
+	Faelligkeit         string            // transient: e-invoice due date DD.MM.YYYY (BT-9); not persisted, not a payment
+	BarBezahlt          bool              `json:"-"` // transient: invoice was paid in cash (bar/Bargeld); not persisted to DB or CSV
+	Teilzahlung         bool              // Partial payment flag
+	Ausgangsrechnung    bool              // true = outgoing/revenue invoice (Erlös)
+	Dateiname           string            // Final filename
+	Kommentar           string            // Comment/note for this invoice
+	BewirtungAnlass     string            // Occasion/purpose for entertainment expenses (§ 4 Abs. 5 EStG)
+	BewirtungTeilnehmer string            // Participants for entertainment expenses (§ 4 Abs. 5 EStG)
 	// BewirtungAngabenAufBeleg = true when Anlass/Teilnehmer are handwritten on
 	// the receipt/attachment instead of entered electronically; suppresses the
 	// "Anlass/Teilnehmer fehlen" warning for an otherwise-valid Bewirtung.
@@ -51,7 +53,17 @@ type Meta struct {
 	BuchungRef string
 	Buchung    Booking // double-entry booking for this invoice
 	Exportiert bool    // true once this invoice has been included in a booking export
+	StornoZu   string  // Belegnummer of the booking this one reverses; "" = no Storno
 	Quelle     string  // transient: extraction source label (e.g., "E-Rechnung", "Claude (Text)", "Lokal", "Vision"); not persisted
+	// Pruefbericht is the EN 16931/XRechnung validation of an e-invoice
+	// (transient, set by EInvoiceExtractor.Extract; nil for other sources).
+	Pruefbericht *EInvoiceValidation `json:"-"`
+	// Provenance rates the extracted key fields (source and confidence per
+	// field; transient, nil for manual entries and stored rows).
+	Provenance Provenance `json:"-"`
+	// Textebene is the PDF text layer the fields were read from (transient;
+	// used to learn the supplier's ExtractionTemplate after saving).
+	Textebene string `json:"-"`
 }
 
 // Account represents a user-defined account (Gegenkonto).
@@ -93,10 +105,29 @@ type BankAccount struct {
 	FolderName        string `json:"folder_name,omitempty"` // folder currently holding this account's statements ("" = uninitialised)
 }
 
+// Firmendaten is a company's postal, tax and payment data. In Settings it
+// describes the profile's own company (the seller on generated e-invoices);
+// the same shape carries a customer's address.
+type Firmendaten struct {
+	Name            string `json:"name,omitempty"`
+	Strasse         string `json:"strasse,omitempty"`
+	PLZ             string `json:"plz,omitempty"`
+	Ort             string `json:"ort,omitempty"`
+	Land            string `json:"land,omitempty"`         // ISO 3166-1 alpha-2, "" = DE
+	Steuernummer    string `json:"steuernummer,omitempty"` // national tax number (BT-32)
+	Finanzamt       string `json:"finanzamt,omitempty"`    // 4-digit Bundesfinanzamtsnummer (ELSTER)
+	Ansprechpartner string `json:"ansprechpartner,omitempty"`
+	Telefon         string `json:"telefon,omitempty"`
+	Email           string `json:"email,omitempty"`
+	IBAN            string `json:"iban,omitempty"`
+	BIC             string `json:"bic,omitempty"`
+}
+
 // Settings represents the application settings.
 type Settings struct {
 	StorageRoot              string             `json:"storage_root"`
 	ScanInboxFolder          string             `json:"scan_inbox_folder"`
+	MailInboxFolder          string             `json:"mail_inbox_folder,omitempty"` // Maildir or folder of .eml/.mbox files; "" = off
 	UseMonthSubfolders       bool               `json:"use_month_subfolders"`
 	NamingTemplate           string             `json:"naming_template"`
 	DecimalSeparator         string             `json:"decimal_separator"`
@@ -104,7 +135,9 @@ type Settings struct {
 	AnthropicModel           string             `json:"anthropic_model"`
 	AnthropicAPIKeyRef       string             `json:"anthropic_api_key_ref"`
 	Language                 string             `json:"language"`
-	ProcessingMode           string             `json:"processing_mode"` // "claude" or "local"
+	ProcessingMode           string             `json:"processing_mode"`           // ProcessingModeClaude, ProcessingModeOpenAI or ProcessingModeLocal
+	OpenAIBaseURL            string             `json:"openai_base_url,omitempty"` // OpenAI-compatible endpoint, e.g. "http://gpu-box:11434/v1"; "" = api.openai.com
+	OpenAIModel              string             `json:"openai_model,omitempty"`    // model name for the OpenAI-compatible backend
 	DefaultAccount           int                `json:"default_account"`
 	Accounts                 []Account          `json:"accounts"`
 	DefaultBankAccount       string             `json:"default_bank_account"`
@@ -115,6 +148,13 @@ type Settings struct {
 	LastUsedFolder           string             `json:"last_used_folder"`                   // Last folder for Belege / attachments
 	LastStatementFolder      string             `json:"last_statement_folder"`              // Last folder for Kontoauszüge
 	OwnVATID                 string             `json:"own_vat_id"`                         // The user's own company VAT-ID — excluded during auto-extract
+	IstVersteuerung          bool               `json:"ist_versteuerung,omitempty"`         // Ist-Versteuerung (§ 20 UStG): output VAT in the period the payment arrived
+	Kleinunternehmer         bool               `json:"kleinunternehmer,omitempty"`         // Kleinunternehmer (§ 19 UStG): no Vorsteuer, no UStVA
+	VoranmeldungQuartal      bool               `json:"voranmeldung_quartal,omitempty"`     // UStVA filed quarterly instead of monthly
+	Dauerfristverlaengerung  bool               `json:"dauerfristverlaengerung,omitempty"`  // Dauerfristverlängerung: returns due a month later; monthly filers pay the Sondervorauszahlung
+	ElsterHerstellerID       string             `json:"elster_hersteller_id,omitempty"`     // manufacturer ID registered with ELSTER; "" = UStVA export only as a test case
+	Firma                    Firmendaten        `json:"firma,omitempty"`                    // own company data for outgoing e-invoices
+	Rechnungslayout          Rechnungslayout    `json:"rechnungslayout,omitempty"`          // number range, payment term and PDF layout of written invoices
 	DatevBeraterNr           string             `json:"datev_berater_nr,omitempty"`         // optional DATEV consultant number
 	DatevMandantNr           string             `json:"datev_mandant_nr,omitempty"`         // optional DATEV client number
 	DatevWJBeginn            string             `json:"datev_wj_beginn,omitempty"`          // fiscal-year start YYYYMMDD (optional)
@@ -146,7 +186,7 @@ func DefaultSettings() Settings {
 		AnthropicModel:     "claude-sonnet-4-6",
 		AnthropicAPIKeyRef: "claude", // keyring account name
 		Language:           "de",
-		ProcessingMode:     "claude",
+		ProcessingMode:     ProcessingModeClaude,
 		DefaultAccount:     2000,
 		Accounts: []Account{
 			{Code: 2000, Label: "Ausgaben"},
@@ -174,8 +214,8 @@ func DefaultSettings() Settings {
 
 // ProcessingResult represents the result of processing a PDF.
 type ProcessingResult struct {
-	Meta       Meta
-	Confidence float64 // Confidence score (0-1), mainly for local extraction
+	Meta       Meta    // per-field confidence and source in Meta.Provenance
+	Confidence float64 // overall confidence score (0-1), mainly for local extraction
 	Error      error
 }
 
@@ -227,6 +267,9 @@ type CSVRow struct {
 	BuchungRef               string // statementFilename|page|lineIdx (within the Bankkonto's folder)
 	Buchung                  Booking
 	Exportiert               bool
+	StornoZu                 string // Belegnummer of the reversed booking (Storno)
+	Umbuchung                bool   // transient: row stands for a manual journal entry (core.Umbuchung); not persisted
+	Eroeffnung               bool   // transient: row carries an EB-Wert (core.OpeningRows); not persisted
 	// Documentation columns for foreign-currency invoices.
 	// Set by the CSV/PDF export layer (not persisted in the DB):
 	//   Originalwaehrung      = the original currency code before EUR normalisation
@@ -278,6 +321,7 @@ func (m Meta) ToCSVRow() CSVRow {
 		BuchungRef:               m.BuchungRef,
 		Buchung:                  m.Buchung,
 		Exportiert:               m.Exportiert,
+		StornoZu:                 m.StornoZu,
 	}
 }
 
@@ -318,6 +362,7 @@ func (r CSVRow) ToMeta() Meta {
 		BuchungRef:               r.BuchungRef,
 		Buchung:                  r.Buchung,
 		Exportiert:               r.Exportiert,
+		StornoZu:                 r.StornoZu,
 	}
 }
 
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UmbuchungPrefix starts every Umbuchung Belegnummer ("U-YYYY-NNNN"), a
+// number range of its own next to the receipts' "YYYY-NNNN".
+const UmbuchungPrefix = "U-"
+
+// Umbuchung is a manual journal entry without a receipt: a reclassification,
+// a bank fee, a private withdrawal or a tax payment. The Belegnummer is
+// assigned when the entry is first saved.
+type Umbuchung struct {
+	ID          int64
+	Belegnummer string
+	Datum       string // DD.MM.YYYY; the filing period follows from it
+	Text        string
+	Buchung     Booking
+	Exportiert  bool
+}
+
+// Period returns the filing period (jahr "YYYY", monat "MM") of the entry.
+func (u Umbuchung) Period() (jahr, monat string, err error) {
+	d, err := time.Parse("02.01.2006", strings.TrimSpace(u.Datum))
+	if err != nil {
+		return "", "", fmt.Errorf("ungültiges Datum %q (TT.MM.JJJJ)", u.Datum)
+	}
+	return fmt.Sprintf("%04d", d.Year()), fmt.Sprintf("%02d", int(d.Month())), nil
+}
+
+// Validate checks that the entry can be booked and exported: a date, a text,
+// at least two lines with an account and a positive amount, Soll equal to
+// Haben, and one side with a single account (the DATEV Gegenkonto).
+func (u Umbuchung) Validate() error {
+	if _, _, err := u.Period(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(u.Text) == "" {
+		return fmt.Errorf("Buchungstext fehlt")
+	}
+	if len(u.Buchung.Entries) < 2 {
+		return fmt.Errorf("eine Umbuchung braucht mindestens eine Soll- und eine Haben-Zeile")
+	}
+	for i, e := range u.Buchung.Entries {
+		if e.Konto <= 0 {
+			return fmt.Errorf("Zeile %d: Konto fehlt", i+1)
+		}
+		if e.Betrag <= 0 {
+			return fmt.Errorf("Zeile %d: Betrag muss positiv sein", i+1)
+		}
+	}
+	if !u.Buchung.Balanced() {
+		return fmt.Errorf("nicht ausgeglichen: Soll %.2f, Haben %.2f", u.Buchung.SollSum(), u.Buchung.HabenSum())
+	}
+	if _, _, ok := u.Row().ExportSplit(); !ok {
+		return fmt.Errorf("Soll oder Haben muss genau ein Konto haben")
+	}
+	return nil
+}
+
+// Row returns the entry as a CSVRow, so SuSa, the booking journal and the
+// exporters treat it like the booking of a receipt. The text goes into the
+// Verwendungszweck; there is no file, counterparty or tax line.
+func (u Umbuchung) Row() CSVRow {
+	jahr, monat, _ := u.Period()
+	return CSVRow{
+		Belegnummer:      u.Belegnummer,
+		Rechnungsdatum:   u.Datum,
+		Jahr:             jahr,
+		Monat:            monat,
+		Verwendungszweck: u.Text,
+		Waehrung:         "EUR",
+		Buchung:          u.Buchung,
+		Exportiert:       u.Exportiert,
+		Umbuchung:        true,
+	}
+}
+
+// UmbuchungRows converts entries with Umbuchung.Row.
+func UmbuchungRows(us []Umbuchung) []CSVRow {
+	out := make([]CSVRow, len(us))
+	for i, u := range us {
+		out[i] = u.Row()
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// privatentnahme is a private withdrawal from the bank account, 500 € from
+// 1800 to 1200, plus a bank fee without a receipt split off the same entry.
+func privatentnahme() Umbuchung {
+	return Umbuchung{
+		Belegnummer: "U-2026-0001",
+		Datum:       "31.03.2026",
+		Text:        "Privatentnahme und Kontoführung",
+		Buchung: Booking{Entries: []BookingEntry{
+			{Konto: 1800, Betrag: 500, Soll: true},
+			{Konto: 6855, Betrag: 12.5, Soll: true},
+			{Konto: 1200, Betrag: 512.5, Soll: false},
+		}},
+	}
+}
+
+func TestUmbuchung_Validate(t *testing.T) {
+	if err := privatentnahme().Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	cases := map[string]func(u *Umbuchung){
+		"Datum":        func(u *Umbuchung) { u.Datum = "2026-03-31" },
+		"Buchungstext": func(u *Umbuchung) { u.Text = " " },
+		"ausgeglichen": func(u *Umbuchung) { u.Buchung.Entries[1].Betrag = 10 },
+		"Konto fehlt":  func(u *Umbuchung) { u.Buchung.Entries[0].Konto = 0 },
+		"positiv":      func(u *Umbuchung) { u.Buchung.Entries[1].Betrag = -12.5 },
+		"genau ein Konto": func(u *Umbuchung) {
+			u.Buchung.Entries = append(u.Buchung.Entries, BookingEntry{Konto: 1210, Betrag: 1, Soll: true},
+				BookingEntry{Konto: 1810, Betrag: 1, Soll: false})
+		},
+	}
+	for want, mutate := range cases {
+		u := privatentnahme()
+		u.Buchung.Entries = append([]BookingEntry(nil), u.Buchung.Entries...)
+		mutate(&u)
+		if err := u.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v", want, err)
+		}
+	}
+}
+
+func TestUmbuchung_InReportsAndExports(t *testing.T) {
+	// Reclassification with the single account on the Haben side and the
+	// split on the Soll side.
+	umb := Umbuchung{
+		Belegnummer: "U-2026-0002",
+		Datum:       "15.04.2026",
+		Text:        "Umbuchung Bürobedarf",
+		Buchung: Booking{Entries: []BookingEntry{
+			{Konto: 6815, Betrag: 100, Soll: false},
+			{Konto: 6800, Betrag: 60, Soll: true},
+			{Konto: 6845, Betrag: 40, Soll: true},
+		}},
+	}
+	// Tax payment with the single account on the Soll side.
+	steuer := Umbuchung{
+		Belegnummer: "U-2026-0003",
+		Datum:       "10.04.2026",
+		Text:        "USt-Zahllast März",
+		Buchung: Booking{Entries: []BookingEntry{
+			{Konto: 3820, Betrag: 300, Soll: true},
+			{Konto: 1800, Betrag: 250, Soll: false},
+			{Konto: 1810, Betrag: 50, Soll: false},
+		}},
+	}
+	rows := UmbuchungRows([]Umbuchung{privatentnahme(), umb, steuer})
+	if !rows[0].Umbuchung || rows[0].Jahr != "2026" || rows[0].Monat != "03" || rows[0].Verwendungszweck != "Privatentnahme und Kontoführung" {
+		t.Fatalf("Row = %+v", rows[0])
+	}
+
+	saldo := map[int]float64{}
+	for _, b := range ComputeSuSa(rows, nil) {
+		saldo[b.Konto] = b.Saldo
+	}
+	if saldo[1200] != -512.5 || saldo[6815] != -100 || saldo[6800] != 60 || saldo[3820] != 300 || saldo[1810] != -50 {
+		t.Errorf("SuSa = %v", saldo)
+	}
+
+	c := ClassifyForExport(rows, false)
+	if len(c.Exportable) != 3 || len(c.Skipped) != 0 {
+		t.Fatalf("classify = %+v", c)
+	}
+	data, exported, skipped := BuildDATEVStapel(DATEVHeader{}, rows)
+	if exported != 6 || skipped != 0 {
+		t.Fatalf("exported=%d skipped=%d", exported, skipped)
+	}
+	for _, want := range []string{
+		`500,00;"S";"EUR";;;;1800;1200;;3103;"U-2026-0001"`,
+		`60,00;"S";"EUR";;;;6800;6815;;1504;"U-2026-0002"`,
+		`250,00;"H";"EUR";;;;1800;3820;;1004;"U-2026-0003"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("DATEV missing %s:\n%s", want, data)
+		}
+	}
+	lex, n, _ := BuildLexwareCSV(rows)
+	if n != 6 || !strings.Contains(string(lex), "10.04.2026;U-2026-0003;USt-Zahllast März;50,00;3820;1810") {
+		t.Errorf("Lexware:\n%s", lex)
+	}
+
+	pdf, err := BuildBookingJournalPDF(rows, nil, "Buchungsjournal", "Test GmbH")
+	if err != nil || !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Errorf("journal: %v", err)
+	}
+}
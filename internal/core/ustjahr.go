@@ -0,0 +1,192 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UStJahrZeile is one line of the annual VAT return: the UStVA Kennzahl whose
+// annual value it reports and the Kennzahl of the annual form (USt 2 A,
+// Anlage UR). JahrKz is empty where the annual form splits the UStVA line by
+// kind of transaction (Kz 43, 45, 84/85, 64) — the advisor assigns the value.
+type UStJahrZeile struct {
+	Kz          string
+	JahrKz      string
+	Bezeichnung string
+}
+
+// UStJahrZeilen are the lines of the annual return in form order, Kz 83
+// (the tax for the year) last.
+var UStJahrZeilen = []UStJahrZeile{
+	{"81", "177", "Lieferungen und sonstige Leistungen 19 %"},
+	{"86", "275", "Lieferungen und sonstige Leistungen 7 %"},
+	{"35", "155", "Umsätze zu anderen Steuersätzen"},
+	{"36", "156", "Steuer auf Umsätze zu anderen Steuersätzen"},
+	{"41", "741", "Innergem. Lieferungen an Abnehmer mit USt-IdNr."},
+	{"44", "744", "Innergem. Lieferungen neuer Fahrzeuge ohne USt-IdNr."},
+	{"43", "", "Weitere steuerfreie Umsätze mit Vorsteuerabzug"},
+	{"89", "781", "Innergem. Erwerbe 19 %"},
+	{"93", "793", "Innergem. Erwerbe 7 %"},
+	{"46", "846", "§ 13b Abs. 1: Leistungen aus dem übrigen Gemeinschaftsgebiet"},
+	{"47", "847", "§ 13b Abs. 1: Steuer"},
+	{"84", "", "Andere § 13b-Leistungen (Bemessungsgrundlage)"},
+	{"85", "", "Andere § 13b-Leistungen (Steuer)"},
+	{"21", "721", "Nicht steuerbare sonstige Leistungen (§ 18b UStG)"},
+	{"45", "", "Übrige nicht steuerbare Umsätze (Ausland)"},
+	{"66", "320", "Vorsteuer aus Rechnungen"},
+	{"61", "761", "Vorsteuer aus innergem. Erwerb"},
+	{"62", "762", "Entstandene Einfuhrumsatzsteuer"},
+	{"67", "467", "Vorsteuer aus § 13b-Leistungen"},
+	{"64", "", "Berichtigung des Vorsteuerabzugs (§ 15a UStG)"},
+	{"83", "", "Umsatzsteuer / Überschuss für das Jahr"},
+}
+
+// UStJahrPeriode is one Voranmeldung of the year, computed from the rows
+// filed in its months.
+type UStJahrPeriode struct {
+	VonMonat int
+	BisMonat int
+	UStVA    UStVAOfficial
+}
+
+// Zeitraum is "MM" for a month, "QN" for a quarter.
+func (p UStJahrPeriode) Zeitraum() string {
+	if p.VonMonat == p.BisMonat {
+		return fmt.Sprintf("%02d", p.VonMonat)
+	}
+	return "Q" + strconv.Itoa((p.BisMonat+2)/3)
+}
+
+// UStNachbuchung is a receipt dated in a locked (festgeschriebene) period but
+// filed in a later one, so its Voranmeldung differs from the period the
+// receipt belongs to.
+type UStNachbuchung struct {
+	Belegnummer     string
+	Auftraggeber    string
+	Rechnungsdatum  string
+	Belegperiode    string             // "YYYY-MM" of the Rechnungsdatum (locked)
+	Buchungsperiode string             // "YYYY-MM" the row is filed in
+	Kennzahlen      map[string]float64 // what the receipt adds, per Kennzahl
+	Geschaetzt      bool               // Kennzahlen from the fallback classification
+}
+
+// UStJahreserklaerung is the annual VAT return with its reconciliation
+// against the Voranmeldungen of the year.
+type UStJahreserklaerung struct {
+	Jahr           int
+	Erklaerung     UStVAOfficial // the year's rows computed at once
+	Voranmeldungen []UStJahrPeriode
+	Summe          UStVAOfficial // field-wise sum of the Voranmeldungen
+	// Vorauszahlungssoll is Σ Kz 83 of the Voranmeldungen before the
+	// Sondervorauszahlung is deducted (it is part of the Soll itself).
+	Vorauszahlungssoll float64
+	// Abschlusszahlung is Erklaerung.Kz83 − Vorauszahlungssoll; negative:
+	// Erstattung.
+	Abschlusszahlung float64
+	Nachbuchungen    []UStNachbuchung
+	Ist              bool // set by the caller, as on UStVAOfficial
+}
+
+// Differenz is the annual value of Kennzahl kz minus the sum of the
+// Voranmeldungen: the cents from rounding each period (the taxes on
+// Kz 81/86/89/93 are derived per return).
+func (j UStJahreserklaerung) Differenz(kz string) float64 {
+	return round2(j.Erklaerung.Wert(kz) - j.Summe.Wert(kz))
+}
+
+// UStVA is the receipt's share of its Voranmeldung (Kz 83: its effect on the
+// Zahllast).
+func (n UStNachbuchung) UStVA() UStVAOfficial {
+	return ustvaAusKennzahlen(n.Kennzahlen)
+}
+
+// Beitraege returns the receipt's Kennzahlen in form order.
+func (n UStNachbuchung) Beitraege() []KennzahlBeitrag {
+	var out []KennzahlBeitrag
+	for _, kz := range UStVAKennzahlen {
+		if v, ok := n.Kennzahlen[kz]; ok {
+			out = append(out, KennzahlBeitrag{Kz: kz, Betrag: v})
+		}
+	}
+	return out
+}
+
+// NachbuchungenIn returns the Nachbuchungen filed in the Voranmeldung p.
+func (j UStJahreserklaerung) NachbuchungenIn(p UStJahrPeriode) []UStNachbuchung {
+	var out []UStNachbuchung
+	for _, n := range j.Nachbuchungen {
+		_, mm, _ := strings.Cut(n.Buchungsperiode, "-")
+		m, _ := strconv.Atoi(mm)
+		if m >= p.VonMonat && m <= p.BisMonat {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// ComputeUStJahreserklaerung computes the annual return from the rows filed
+// in jahr (vatRows of January..December) and recomputes each monthly or
+// quarterly Voranmeldung from the rows filed in its months. locked reports
+// whether a period "YYYY-MM" is festgeschrieben; rows dated in a locked
+// period before the one they are filed in are listed as Nachbuchungen. Under
+// Ist-Versteuerung taxable outgoing invoices are filed by payment and not
+// listed.
+func ComputeUStJahreserklaerung(rows []CSVRow, rules *BookingRules, jahr int, quartal, ist bool, locked func(periode string) bool) UStJahreserklaerung {
+	j := UStJahreserklaerung{Jahr: jahr, Ist: ist}
+	j.Erklaerung = ComputeUStVAOfficial(rows, rules)
+	j.Erklaerung.Ist = ist
+	schritt := 1
+	if quartal {
+		schritt = 3
+	}
+	j.Summe.Ist = ist
+	for von := 1; von <= 12; von += schritt {
+		var p []CSVRow
+		for _, r := range rows {
+			if m, _ := strconv.Atoi(r.Monat); m >= von && m < von+schritt {
+				p = append(p, r)
+			}
+		}
+		u := ComputeUStVAOfficial(p, rules)
+		u.Ist = ist
+		j.Voranmeldungen = append(j.Voranmeldungen, UStJahrPeriode{VonMonat: von, BisMonat: von + schritt - 1, UStVA: u})
+		j.Summe = j.Summe.Plus(u)
+	}
+	j.Vorauszahlungssoll = j.Summe.Kz83
+	j.Abschlusszahlung = round2(j.Erklaerung.Kz83 - j.Vorauszahlungssoll)
+
+	idx := NewKennzahlIndex(rules.KennzahlKonten())
+	rcSatz := rules.schaetzRCSatz()
+	for _, r := range rows {
+		if ist && r.Ausgangsrechnung && math.Abs(SumMwSt(r.TaxLines)) >= 0.005 {
+			continue
+		}
+		t, err := time.Parse("02.01.2006", strings.TrimSpace(r.Rechnungsdatum))
+		if err != nil {
+			continue
+		}
+		beleg, buchung := t.Format("2006-01"), r.Jahr+"-"+r.Monat
+		if beleg >= buchung || locked == nil || !locked(beleg) {
+			continue
+		}
+		eur, _ := RowEUR(r)
+		beitraege, geschaetzt := zeilenKennzahlen(eur, idx, rcSatz)
+		n := UStNachbuchung{
+			Belegnummer:     r.Belegnummer,
+			Auftraggeber:    r.Auftraggeber,
+			Rechnungsdatum:  r.Rechnungsdatum,
+			Belegperiode:    beleg,
+			Buchungsperiode: buchung,
+			Kennzahlen:      map[string]float64{},
+			Geschaetzt:      geschaetzt,
+		}
+		for _, b := range beitraege {
+			n.Kennzahlen[b.Kz] = round2(n.Kennzahlen[b.Kz] + b.Betrag)
+		}
+		j.Nachbuchungen = append(j.Nachbuchungen, n)
+	}
+	return j
+}
@@ -0,0 +1,67 @@
+package core
+
+import "testing"
+
+func TestComputeUStJahreserklaerung(t *testing.T) {
+	rules, _ := ParseBookingRules([]byte(`{"regeln":[{"kategorie":"reverse_charge","rc_satz":19}]}`))
+	sale := func(monat, datum string, netto float64) CSVRow {
+		return CSVRow{Jahr: "2025", Monat: monat, Rechnungsdatum: datum, Ausgangsrechnung: true, VATID: "DE123",
+			TaxLines: []TaxLine{{Netto: netto, SatzProzent: 19, MwStBetrag: round2(netto * 0.19)}}}
+	}
+	rows := []CSVRow{
+		sale("01", "10.01.2025", 0.03),
+		sale("02", "10.02.2025", 0.03),
+		sale("05", "02.05.2025", 1000),
+		// Dated January (locked), filed in March: a Nachbuchung.
+		{Jahr: "2025", Monat: "03", Rechnungsdatum: "28.01.2025", Belegnummer: "ER-7", Auftraggeber: "Lieferant",
+			VATID: "DE999", TaxLines: []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}}},
+		// Dated February (not locked), filed in March: not listed.
+		{Jahr: "2025", Monat: "03", Rechnungsdatum: "27.02.2025", VATID: "DE998",
+			TaxLines: []TaxLine{{Netto: 50, SatzProzent: 19, MwStBetrag: 9.5}}},
+	}
+	locked := func(p string) bool { return p == "2025-01" }
+
+	j := ComputeUStJahreserklaerung(rows, rules, 2025, false, false, locked)
+	if len(j.Voranmeldungen) != 12 || j.Voranmeldungen[2].Zeitraum() != "03" {
+		t.Fatalf("Voranmeldungen = %+v", j.Voranmeldungen)
+	}
+	// 0.03 × 19 % rounds to 0.01 per month; 0.06 × 19 % to 0.01 for the year.
+	if !almost(j.Erklaerung.Wert("81"), 1000.06) || !almost(j.Summe.Wert("81"), 1000.06) || j.Differenz("81") != 0 {
+		t.Errorf("Kz81: %v / %v", j.Erklaerung.Wert("81"), j.Summe.Wert("81"))
+	}
+	if !almost(j.Erklaerung.USt81, 190.01) || !almost(j.Summe.USt81, 190.02) {
+		t.Errorf("USt81: %v / %v", j.Erklaerung.USt81, j.Summe.USt81)
+	}
+	// Kz83: 190.01 − 28.50 = 161.51 for the year, 190.02 − 28.50 = 161.52 filed.
+	if !almost(j.Erklaerung.Kz83, 161.51) || !almost(j.Vorauszahlungssoll, 161.52) ||
+		!almost(j.Abschlusszahlung, -0.01) || !almost(j.Differenz("83"), -0.01) {
+		t.Errorf("Kz83 %v, Soll %v, Abschluss %v", j.Erklaerung.Kz83, j.Vorauszahlungssoll, j.Abschlusszahlung)
+	}
+
+	if len(j.Nachbuchungen) != 1 {
+		t.Fatalf("Nachbuchungen = %+v", j.Nachbuchungen)
+	}
+	n := j.Nachbuchungen[0]
+	if n.Belegnummer != "ER-7" || n.Belegperiode != "2025-01" || n.Buchungsperiode != "2025-03" ||
+		!almost(n.Kennzahlen["66"], 19) || !almost(n.UStVA().Kz83, -19) || !n.Geschaetzt {
+		t.Errorf("Nachbuchung = %+v", n)
+	}
+	if got := j.NachbuchungenIn(j.Voranmeldungen[2]); len(got) != 1 {
+		t.Errorf("March: %d Nachbuchungen", len(got))
+	}
+	if got := j.NachbuchungenIn(j.Voranmeldungen[0]); len(got) != 0 {
+		t.Errorf("January: %d Nachbuchungen", len(got))
+	}
+
+	q := ComputeUStJahreserklaerung(rows, rules, 2025, true, false, locked)
+	if len(q.Voranmeldungen) != 4 || q.Voranmeldungen[0].Zeitraum() != "Q1" || len(q.NachbuchungenIn(q.Voranmeldungen[0])) != 1 {
+		t.Errorf("quarterly: %+v", q.Voranmeldungen)
+	}
+	// Both sales of Q1 are in one return: no rounding difference.
+	if q.Differenz("83") != 0 {
+		t.Errorf("quarterly Differenz Kz83 = %v", q.Differenz("83"))
+	}
+	if j := ComputeUStJahreserklaerung(rows, rules, 2025, false, false, nil); len(j.Nachbuchungen) != 0 {
+		t.Errorf("no locks: %+v", j.Nachbuchungen)
+	}
+}
@@ -1,67 +1,271 @@
 package core
 
+import "math"
+
 // UStVAOfficial is the VAT return in the official ELSTER Kennzahlen, computed
-// from invoice metadata. Net bases (Kz81/86/21/45/84) plus the derived output
-// VAT and the Zahllast (Kz83). Structured to feed an ELSTER export later.
+// from the booking entries through the Kennzahl mapping (KennzahlKonten).
+// Net bases (Kz81/86/35/41/44/43/89/93/46/84/21/45), the taxes reported with
+// them, the Vorsteuer side and the Zahllast (Kz83). Feeds the ELSTER XML.
 type UStVAOfficial struct {
 	Kz81  float64 // steuerpflichtige Umsätze 19 % (Bemessungsgrundlage, netto)
 	Kz86  float64 // steuerpflichtige Umsätze 7 % (Bemessungsgrundlage, netto)
+	Kz35  float64 // Umsätze zu anderen Steuersätzen (Bemessungsgrundlage)
+	Kz36  float64 // Steuer auf Kz35
+	Kz41  float64 // innergem. Lieferungen an Abnehmer mit USt-IdNr. (steuerfrei)
+	Kz44  float64 // innergem. Lieferungen neuer Fahrzeuge an Abnehmer ohne USt-IdNr.
+	Kz43  float64 // weitere steuerfreie Umsätze mit Vorsteuerabzug (z. B. Ausfuhren)
+	Kz89  float64 // innergem. Erwerbe 19 % (Bemessungsgrundlage)
+	Kz93  float64 // innergem. Erwerbe 7 % (Bemessungsgrundlage)
+	Kz46  float64 // § 13b Abs. 1: sonstige Leistungen eines im übrigen Gemeinschaftsgebiet ansässigen Unternehmers (netto)
+	Kz47  float64 // Steuer auf Kz46
 	Kz21  float64 // nicht steuerbare innergem. sonstige Leistungen (§ 18b), netto
 	Kz45  float64 // übrige nicht steuerbare Umsätze (Leistungsort nicht im Inland), netto
-	Kz84  float64 // § 13b Bemessungsgrundlage (netto)
-	Kz85  float64 // § 13b Steuer
+	Kz84  float64 // andere § 13b Leistungen (Bemessungsgrundlage, netto)
+	Kz85  float64 // Steuer auf Kz84
 	Kz66  float64 // Vorsteuer aus Rechnungen anderer Unternehmer
-	Kz67  float64 // Vorsteuer aus § 13b Leistungen
+	Kz61  float64 // Vorsteuer aus innergem. Erwerb
+	Kz62  float64 // entrichtete Einfuhrumsatzsteuer
+	Kz67  float64 // Vorsteuer aus § 13b Leistungen (Kz47 und Kz85)
+	Kz64  float64 // Berichtigung des Vorsteuerabzugs (§ 15a UStG)
 	USt81 float64 // = Kz81 × 19 % (derived)
 	USt86 float64 // = Kz86 × 7 % (derived)
-	Kz83  float64 // Zahllast/Überschuss = (USt81+USt86+Kz85) − (Kz66+Kz67)
+	USt89 float64 // = Kz89 × 19 % (derived)
+	USt93 float64 // = Kz93 × 7 % (derived)
+	Kz39  float64 // Abzug der Sondervorauszahlung (December, Dauerfristverlängerung); see MitSondervorauszahlung
+	Kz83  float64 // Zahllast/Überschuss = Steuer (USt81+USt86+Kz36+USt89+USt93+Kz47+Kz85) − Vorsteuer (Kz66+Kz61+Kz62+Kz67+Kz64) − Kz39
+	Ist   bool    // computed from IstRows (Ist-Versteuerung); set by the caller
 }
 
-// ComputeUStVAOfficial classifies each invoice into its Kennzahl from the
-// Ausgangsrechnung flag, the tax lines, and the counterparty VAT-ID.
+// ComputeUStVAOfficial assigns each row's booking entries to their Kennzahl
+// through the rules' explicit mapping (KennzahlKonten). A row none of whose
+// entries is mapped — not yet booked, or booked on unmapped accounts — falls
+// back to the classification from the Ausgangsrechnung flag, the tax lines
+// and the counterparty VAT-ID (see UStVAGeschaetzt).
 func ComputeUStVAOfficial(rows []CSVRow, rules *BookingRules) UStVAOfficial {
+	u, _ := computeUStVAOfficial(rows, rules)
+	return u
+}
+
+// UStVAGeschaetzt returns the Belegnummern of the rows ComputeUStVAOfficial
+// classified from the invoice data because none of their booking entries is
+// mapped to a Kennzahl; their booking or the mapping should be checked.
+func UStVAGeschaetzt(rows []CSVRow, rules *BookingRules) []string {
+	_, geschaetzt := computeUStVAOfficial(rows, rules)
+	return geschaetzt
+}
+
+func computeUStVAOfficial(rows []CSVRow, rules *BookingRules) (u UStVAOfficial, geschaetzt []string) {
 	rows = RowsEUR(rows)
-	rcSatz := 19.0
-	if rc, ok := rules.Rule("reverse_charge"); ok && rc.RcSatz > 0 {
-		rcSatz = rc.RcSatz
-	}
-	var u UStVAOfficial
+	idx := NewKennzahlIndex(rules.KennzahlKonten())
+	rcSatz := rules.schaetzRCSatz()
+	kz := map[string]float64{}
 	for _, r := range rows {
-		net := SumNetto(r.TaxLines)
-		vat := SumMwSt(r.TaxLines)
-		if r.Ausgangsrechnung {
-			if vat > 0.005 { // domestic taxable sale
-				for _, l := range r.TaxLines {
-					switch int(l.SatzProzent + 0.5) {
-					case 19:
-						u.Kz81 += l.Netto
-					case 7:
-						u.Kz86 += l.Netto
-					}
-				}
-			} else if IsEUVatID(r.VATID) {
-				u.Kz21 += net
-			} else {
-				u.Kz45 += net
-			}
-		} else { // incoming
-			if IsEUVatID(r.VATID) && vat < 0.005 { // § 13b reverse-charge
-				u.Kz84 += net
-			} else {
-				u.Kz66 += vat
-			}
+		beitraege, g := zeilenKennzahlen(r, idx, rcSatz)
+		for _, b := range beitraege {
+			kz[b.Kz] += b.Betrag
+		}
+		if g {
+			geschaetzt = append(geschaetzt, r.Belegnummer)
 		}
 	}
-	u.Kz81 = round2(u.Kz81)
-	u.Kz86 = round2(u.Kz86)
-	u.Kz21 = round2(u.Kz21)
-	u.Kz45 = round2(u.Kz45)
-	u.Kz84 = round2(u.Kz84)
-	u.Kz66 = round2(u.Kz66)
-	u.Kz85 = round2(u.Kz84 * rcSatz / 100)
-	u.Kz67 = u.Kz85
+	return ustvaAusKennzahlen(kz), geschaetzt
+}
+
+// ustvaAusKennzahlen builds the return from summed Kennzahlen: each value
+// round2'd, the taxes on Kz 81/86/89/93 and Kz 83 derived.
+func ustvaAusKennzahlen(kz map[string]float64) (u UStVAOfficial) {
+	u.Kz81 = round2(kz["81"])
+	u.Kz86 = round2(kz["86"])
+	u.Kz35 = round2(kz["35"])
+	u.Kz36 = round2(kz["36"])
+	u.Kz41 = round2(kz["41"])
+	u.Kz44 = round2(kz["44"])
+	u.Kz43 = round2(kz["43"])
+	u.Kz89 = round2(kz["89"])
+	u.Kz93 = round2(kz["93"])
+	u.Kz46 = round2(kz["46"])
+	u.Kz47 = round2(kz["47"])
+	u.Kz21 = round2(kz["21"])
+	u.Kz45 = round2(kz["45"])
+	u.Kz84 = round2(kz["84"])
+	u.Kz85 = round2(kz["85"])
+	u.Kz66 = round2(kz["66"])
+	u.Kz61 = round2(kz["61"])
+	u.Kz62 = round2(kz["62"])
+	u.Kz67 = round2(kz["67"])
+	u.Kz64 = round2(kz["64"])
 	u.USt81 = round2(u.Kz81 * 0.19)
 	u.USt86 = round2(u.Kz86 * 0.07)
-	u.Kz83 = round2((u.USt81 + u.USt86 + u.Kz85) - (u.Kz66 + u.Kz67))
+	u.USt89 = round2(u.Kz89 * 0.19)
+	u.USt93 = round2(u.Kz93 * 0.07)
+	u.Kz83 = round2(u.Steuer() - u.Vorsteuer())
 	return u
 }
+
+// schaetzRCSatz is the § 13b rate of the fallback classification: the
+// RcSatz of the reverse_charge_eu rule (the only § 13b case it recognises),
+// else of the reverse_charge rule, else 19 %.
+func (r *BookingRules) schaetzRCSatz() float64 {
+	for _, kategorie := range []string{"reverse_charge_eu", "reverse_charge"} {
+		if rc, ok := r.Rule(kategorie); ok && rc.RcSatz > 0 {
+			return rc.RcSatz
+		}
+	}
+	return 19
+}
+
+// zeilenKennzahlen returns what an EUR row adds to the Kennzahlen: its mapped
+// booking entries, or — none being mapped — the fallback classification,
+// one contribution per Kennzahl; geschaetzt reports a fallback that
+// contributed anything.
+func zeilenKennzahlen(r CSVRow, idx KennzahlIndex, rcSatz float64) (beitraege []KennzahlBeitrag, geschaetzt bool) {
+	if b, ok := RowKennzahlen(r, idx); ok {
+		return b, false
+	}
+	kz := map[string]float64{}
+	geschaetzt = schaetzeKennzahlen(r, rcSatz, kz)
+	for _, k := range UStVAKennzahlen {
+		if v, ok := kz[k]; ok {
+			beitraege = append(beitraege, KennzahlBeitrag{Kz: k, Betrag: v})
+		}
+	}
+	return beitraege, geschaetzt
+}
+
+// Wert returns the value of Kennzahl kz ("81", "83", …); 0 for an unknown one.
+func (u UStVAOfficial) Wert(kz string) float64 {
+	switch kz {
+	case "81":
+		return u.Kz81
+	case "86":
+		return u.Kz86
+	case "35":
+		return u.Kz35
+	case "36":
+		return u.Kz36
+	case "41":
+		return u.Kz41
+	case "44":
+		return u.Kz44
+	case "43":
+		return u.Kz43
+	case "89":
+		return u.Kz89
+	case "93":
+		return u.Kz93
+	case "46":
+		return u.Kz46
+	case "47":
+		return u.Kz47
+	case "21":
+		return u.Kz21
+	case "45":
+		return u.Kz45
+	case "84":
+		return u.Kz84
+	case "85":
+		return u.Kz85
+	case "66":
+		return u.Kz66
+	case "61":
+		return u.Kz61
+	case "62":
+		return u.Kz62
+	case "67":
+		return u.Kz67
+	case "64":
+		return u.Kz64
+	case "39":
+		return u.Kz39
+	case "83":
+		return u.Kz83
+	}
+	return 0
+}
+
+// Plus returns the field-wise sum of two returns (each value round2'd), e.g.
+// the Voranmeldungen of a year. Ist is kept from u.
+func (u UStVAOfficial) Plus(v UStVAOfficial) UStVAOfficial {
+	s := u
+	add := func(a *float64, b float64) { *a = round2(*a + b) }
+	add(&s.Kz81, v.Kz81)
+	add(&s.Kz86, v.Kz86)
+	add(&s.Kz35, v.Kz35)
+	add(&s.Kz36, v.Kz36)
+	add(&s.Kz41, v.Kz41)
+	add(&s.Kz44, v.Kz44)
+	add(&s.Kz43, v.Kz43)
+	add(&s.Kz89, v.Kz89)
+	add(&s.Kz93, v.Kz93)
+	add(&s.Kz46, v.Kz46)
+	add(&s.Kz47, v.Kz47)
+	add(&s.Kz21, v.Kz21)
+	add(&s.Kz45, v.Kz45)
+	add(&s.Kz84, v.Kz84)
+	add(&s.Kz85, v.Kz85)
+	add(&s.Kz66, v.Kz66)
+	add(&s.Kz61, v.Kz61)
+	add(&s.Kz62, v.Kz62)
+	add(&s.Kz67, v.Kz67)
+	add(&s.Kz64, v.Kz64)
+	add(&s.USt81, v.USt81)
+	add(&s.USt86, v.USt86)
+	add(&s.USt89, v.USt89)
+	add(&s.USt93, v.USt93)
+	add(&s.Kz39, v.Kz39)
+	add(&s.Kz83, v.Kz83)
+	return s
+}
+
+// Steuer is the tax the return declares: on the taxable sales, the ig
+// Erwerbe and the § 13b services.
+func (u UStVAOfficial) Steuer() float64 {
+	return u.USt81 + u.USt86 + u.Kz36 + u.USt89 + u.USt93 + u.Kz47 + u.Kz85
+}
+
+// Vorsteuer is the deductible input tax the return declares.
+func (u UStVAOfficial) Vorsteuer() float64 {
+	return u.Kz66 + u.Kz61 + u.Kz62 + u.Kz67 + u.Kz64
+}
+
+// schaetzeKennzahlen classifies a row without mapped booking entries from
+// its invoice data into kz: outgoing with VAT → Kz81/86 by line rate, without
+// VAT → Kz21 (EU VAT-ID) or Kz45; incoming without VAT from an EU VAT-ID →
+// service of an entrepreneur from another EU country (§ 13b Abs. 1 UStG,
+// Kz46/47/67), otherwise its VAT → Kz66. The other § 13b cases (Kz84/85)
+// cannot be told from the invoice data and come only from the booking.
+// Reports whether the row contributed anything.
+func schaetzeKennzahlen(r CSVRow, rcSatz float64, kz map[string]float64) bool {
+	net := SumNetto(r.TaxLines)
+	vat := SumMwSt(r.TaxLines)
+	if r.Ausgangsrechnung {
+		if math.Abs(vat) > 0.005 { // domestic taxable sale (negative: Storno)
+			beitrag := false
+			for _, l := range r.TaxLines {
+				switch int(l.SatzProzent + 0.5) {
+				case 19:
+					kz["81"] += l.Netto
+					beitrag = true
+				case 7:
+					kz["86"] += l.Netto
+					beitrag = true
+				}
+			}
+			return beitrag
+		}
+		if IsEUVatID(r.VATID) {
+			kz["21"] += net
+		} else {
+			kz["45"] += net
+		}
+		return math.Abs(net) > 0.005
+	}
+	if IsEUVatID(r.VATID) && math.Abs(vat) < 0.005 { // § 13b Abs. 1 reverse-charge
+		kz["46"] += net
+		kz["47"] += net * rcSatz / 100
+		kz["67"] += net * rcSatz / 100
+		return math.Abs(net) > 0.005
+	}
+	kz["66"] += vat
+	return math.Abs(vat) > 0.005
+}
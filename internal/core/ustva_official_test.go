@@ -11,7 +11,7 @@ func TestComputeUStVAOfficial(t *testing.T) {
 		{Ausgangsrechnung: true, VATID: "", TaxLines: []TaxLine{{Netto: 1000, SatzProzent: 0, MwStBetrag: 0}}},
 		// intra-EU service (EU customer): 0%, EU VAT-ID → Kz21
 		{Ausgangsrechnung: true, VATID: "FI26378052", TaxLines: []TaxLine{{Netto: 2000, SatzProzent: 0, MwStBetrag: 0}}},
-		// §13b incoming (Google IE): 0%, EU supplier VAT-ID → Kz84/85/67
+		// §13b Abs. 1 incoming (Google IE): 0%, EU supplier VAT-ID → Kz46/47/67
 		{Ausgangsrechnung: false, VATID: "IE123", TaxLines: []TaxLine{{Netto: 462.40, SatzProzent: 0, MwStBetrag: 0}}},
 		// normal incoming with VAT: Kz66 += 31.19
 		{Ausgangsrechnung: false, VATID: "DE999", TaxLines: []TaxLine{{Netto: 164.16, SatzProzent: 19, MwStBetrag: 31.19}}},
@@ -25,8 +25,9 @@ func TestComputeUStVAOfficial(t *testing.T) {
 	check("Kz81", u.Kz81, 6500)
 	check("Kz45", u.Kz45, 1000)
 	check("Kz21", u.Kz21, 2000)
-	check("Kz84", u.Kz84, 462.40)
-	check("Kz85", u.Kz85, 87.86) // 462.40 * 19%
+	check("Kz46", u.Kz46, 462.40)
+	check("Kz47", u.Kz47, 87.86) // 462.40 * 19%
+	check("Kz84", u.Kz84, 0)
 	check("Kz67", u.Kz67, 87.86)
 	check("Kz66", u.Kz66, 31.19)
 	check("USt81", u.USt81, 1235) // 6500 * 19%
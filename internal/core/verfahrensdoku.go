@@ -25,8 +25,11 @@ func BuildVerfahrensdokumentationPDF(s Settings, chartAccounts int, profilName,
 
 	// Resolve dynamic values.
 	modus := "Lokale Mustererkennung (offline)"
-	if s.ProcessingMode == "claude" {
+	switch s.ProcessingMode {
+	case ProcessingModeClaude:
 		modus = "KI-Extraktion via Claude (Anthropic API)"
+	case ProcessingModeOpenAI:
+		modus = "KI-Extraktion via OpenAI-kompatibler Schnittstelle (" + openAIEndpoint(s.OpenAIBaseURL) + ")"
 	}
 	if profilName == "" {
 		profilName = "-"
@@ -101,7 +104,11 @@ func BuildVerfahrensdokumentationPDF(s Settings, chartAccounts int, profilName,
 			"explizites Entsperren wieder geoeffnet werden.\n\n"+
 			"Aenderungsprotokoll (Audit-Trail): Jede Anlage, Aenderung und "+
 			"Loeschung wird mit Zeitstempel und Aktion in einem Audit-Log "+
-			"festgehalten.\n\n"+
+			"festgehalten. Jeder Eintrag ist ueber einen SHA-256-Hash mit seinem "+
+			"Vorgaenger verkettet; die Hash-Werte der abgelegten Belegdateien "+
+			"werden in diese Kette aufgenommen. Die Funktion Kette pruefen "+
+			"meldet den ersten nachtraeglich geaenderten, geloeschten oder "+
+			"eingefuegten Eintrag.\n\n"+
 			"Nach Festschreibung sind nur noch Stornobuchungen moeglich; "+
 			"direkte Aenderungen an gesperrten Datensaetzen werden abgewiesen.")
 
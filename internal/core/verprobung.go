@@ -0,0 +1,282 @@
+package core
+
+import (
+	"math"
+	"sort"
+)
+
+// Bereiche of the UStVA-Verprobung: the tax accounts on either side and the
+// revenue accounts per kind of sale.
+const (
+	VerprobungUSt       = "umsatzsteuer"
+	VerprobungVSt       = "vorsteuer"
+	VerprobungInland    = "inland"
+	VerprobungEU        = "eu"
+	VerprobungDrittland = "drittland"
+)
+
+// VerprobungBereiche are the Bereiche in report order.
+var VerprobungBereiche = []string{VerprobungUSt, VerprobungVSt, VerprobungInland, VerprobungEU, VerprobungDrittland}
+
+// verprobungTitel names a Bereich and the Kennzahlen it is checked against,
+// for the report.
+var verprobungTitel = map[string][2]string{
+	VerprobungUSt:       {"Umsatzsteuerkonten", "Steuer zu Kz 81, 86, 89, 93; Kz 36, 47, 85"},
+	VerprobungVSt:       {"Vorsteuerkonten", "Kz 66, 61, 62, 67, 64"},
+	VerprobungInland:    {"Erlöse Inland", "Kz 81, 86, 35"},
+	VerprobungEU:        {"Erlöse EU", "Kz 21, 41, 44"},
+	VerprobungDrittland: {"Erlöse Drittland", "Kz 43, 45"},
+}
+
+// verprobungAnteil is the share of a Kennzahl a Bereich's accounts should
+// carry: the tax on a base (Kz 81 × 19 %) or the value itself.
+type verprobungAnteil struct {
+	bereich string
+	faktor  float64
+}
+
+// verprobungKz assigns the Kennzahlen to the Bereiche they are checked in.
+// The § 13b and ig Erwerb bases (Kz 46/84/89/93) are no revenue; only the
+// tax on Kz 89/93 is checked.
+var verprobungKz = map[string][]verprobungAnteil{
+	"81": {{VerprobungUSt, 0.19}, {VerprobungInland, 1}},
+	"86": {{VerprobungUSt, 0.07}, {VerprobungInland, 1}},
+	"35": {{VerprobungInland, 1}},
+	"36": {{VerprobungUSt, 1}},
+	"89": {{VerprobungUSt, 0.19}},
+	"93": {{VerprobungUSt, 0.07}},
+	"47": {{VerprobungUSt, 1}},
+	"85": {{VerprobungUSt, 1}},
+	"66": {{VerprobungVSt, 1}},
+	"61": {{VerprobungVSt, 1}},
+	"62": {{VerprobungVSt, 1}},
+	"67": {{VerprobungVSt, 1}},
+	"64": {{VerprobungVSt, 1}},
+	"21": {{VerprobungEU, 1}},
+	"41": {{VerprobungEU, 1}},
+	"44": {{VerprobungEU, 1}},
+	"43": {{VerprobungDrittland, 1}},
+	"45": {{VerprobungDrittland, 1}},
+}
+
+// VerprobungKonto is the balance of one account in the period: Soll − Haben
+// on a Vorsteuer account, Haben − Soll on every other.
+type VerprobungKonto struct {
+	Konto int
+	Saldo float64
+}
+
+// VerprobungBeleg is a row whose booking on a Bereich's accounts differs
+// from what it adds to the Bereich's Kennzahlen.
+type VerprobungBeleg struct {
+	Belegnummer    string
+	Auftraggeber   string
+	Rechnungsdatum string
+	Gebucht        float64 // the row's entries on the Bereich's accounts
+	Kennzahlen     float64 // its share of the Bereich's Kennzahlen
+	Geschaetzt     bool    // Kennzahlen from the fallback classification
+}
+
+// Differenz is Gebucht − Kennzahlen.
+func (b VerprobungBeleg) Differenz() float64 {
+	return round2(b.Gebucht - b.Kennzahlen)
+}
+
+// VerprobungBereich checks the balance of a Bereich's accounts against the
+// Kennzahlen of the return.
+type VerprobungBereich struct {
+	Bereich    string
+	Konten     []VerprobungKonto // by account number
+	Gebucht    float64           // Σ Konten
+	Kennzahlen float64           // the return's value (Umsatzsteuer: Steuer())
+	Belege     []VerprobungBeleg // in row order
+}
+
+// Differenz is Gebucht − Kennzahlen.
+func (b VerprobungBereich) Differenz() float64 {
+	return round2(b.Gebucht - b.Kennzahlen)
+}
+
+// Rundung is the part of Differenz no row explains: the cents from deriving
+// the taxes on Kz 81/86/89/93 from the summed bases of the return.
+func (b VerprobungBereich) Rundung() float64 {
+	d := b.Differenz()
+	for _, r := range b.Belege {
+		d -= r.Differenz()
+	}
+	return round2(d)
+}
+
+// UStVerprobung reconciles the balances of the Umsatzsteuer, Vorsteuer and
+// revenue accounts with the return in Kennzahlen (ComputeUStVAOfficial) for
+// the same rows.
+type UStVerprobung struct {
+	UStVA    UStVAOfficial       // without Kz 39
+	Bereiche []VerprobungBereich // in VerprobungBereiche order
+	Ist      bool                // set by the caller, as on UStVAOfficial
+}
+
+// Bereich returns the check of bereich.
+func (v UStVerprobung) Bereich(bereich string) VerprobungBereich {
+	for _, b := range v.Bereiche {
+		if b.Bereich == bereich {
+			return b
+		}
+	}
+	return VerprobungBereich{Bereich: bereich}
+}
+
+// Zahllast is the booked Umsatzsteuer minus the booked Vorsteuer.
+func (v UStVerprobung) Zahllast() float64 {
+	return round2(v.Bereich(VerprobungUSt).Gebucht - v.Bereich(VerprobungVSt).Gebucht)
+}
+
+// ZahllastDifferenz is Zahllast − Kz 83.
+func (v UStVerprobung) ZahllastDifferenz() float64 {
+	return round2(v.Zahllast() - v.UStVA.Kz83)
+}
+
+// Abweichend reports whether any Bereich or the Zahllast differs.
+func (v UStVerprobung) Abweichend() bool {
+	for _, b := range v.Bereiche {
+		if b.Differenz() != 0 {
+			return true
+		}
+	}
+	return v.ZahllastDifferenz() != 0
+}
+
+// verprobungBereich is the Bereich whose accounts include k's account: tax
+// accounts (those reporting a base, or Kz 36/47/85) on the Umsatzsteuer
+// side, the Vorsteuer Kennzahlen on the other, revenue by its Kennzahl.
+func verprobungBereich(k KennzahlKonto) string {
+	switch {
+	case k.BasisKz != "", k.Kz == "36", k.Kz == "47", k.Kz == "85":
+		return VerprobungUSt
+	case vorsteuerKz[k.Kz]:
+		return VerprobungVSt
+	}
+	for _, a := range verprobungKz[k.Kz] {
+		if a.bereich != VerprobungUSt {
+			return a.bereich
+		}
+	}
+	return ""
+}
+
+// verprobungKonten maps each checked account to its Bereich: the accounts of
+// the Kennzahl mapping (KennzahlKonten), then the revenue accounts
+// (ErloesKonten) it leaves out. The first assignment of an account wins.
+func (r *BookingRules) verprobungKonten() map[int]string {
+	konten := map[int]string{}
+	add := func(konto int, bereich string) {
+		if _, ok := konten[konto]; !ok && konto != 0 && bereich != "" {
+			konten[konto] = bereich
+		}
+	}
+	for _, k := range r.KennzahlKonten() {
+		add(k.Konto, verprobungBereich(k))
+	}
+	add(r.ErloesKonten["inland"], VerprobungInland)
+	add(r.ErloesKonten["eu"], VerprobungEU)
+	add(r.ErloesKonten["drittland"], VerprobungDrittland)
+	return konten
+}
+
+// ComputeUStVerprobung checks, for the rows of a period, the balances of the
+// Umsatzsteuer and Vorsteuer accounts against the taxes of the return's
+// Kennzahlen and the balances of the revenue accounts against its bases
+// (Inland: Kz 81/86/35, EU: Kz 21/41/44, Drittland: Kz 43/45). Each Bereich
+// lists the rows whose booking differs from their contribution to the
+// Kennzahlen by a cent or more — wrongly booked or unbooked receipts, tax
+// amounts that are not the rate of their net, rows classified from the
+// invoice data; what remains is Rundung.
+//
+// Umbuchungen among rows (CSVRow.Umbuchung) count on the booked side only:
+// the return is built from the receipts, as the UStVA dialog files it, so a
+// manual entry on a checked account shows up as the difference it causes.
+func ComputeUStVerprobung(rows []CSVRow, rules *BookingRules) UStVerprobung {
+	rows = RowsEUR(rows)
+	var belegRows []CSVRow
+	for _, r := range rows {
+		if !r.Umbuchung {
+			belegRows = append(belegRows, r)
+		}
+	}
+	v := UStVerprobung{UStVA: ComputeUStVAOfficial(belegRows, rules)}
+	konten := rules.verprobungKonten()
+	idx := NewKennzahlIndex(rules.KennzahlKonten())
+	rcSatz := rules.schaetzRCSatz()
+
+	salden := map[int]float64{}
+	belege := map[string][]VerprobungBeleg{}
+	for _, r := range rows {
+		gebucht := map[string]float64{}
+		for _, e := range r.Buchung.Entries {
+			bereich, ok := konten[e.Konto]
+			if !ok {
+				continue
+			}
+			betrag := e.Betrag
+			if e.Soll != (bereich == VerprobungVSt) {
+				betrag = -betrag
+			}
+			salden[e.Konto] += betrag
+			gebucht[bereich] += betrag
+		}
+		erwartet := map[string]float64{}
+		var beitraege []KennzahlBeitrag
+		geschaetzt := false
+		if !r.Umbuchung {
+			beitraege, geschaetzt = zeilenKennzahlen(r, idx, rcSatz)
+		}
+		for _, b := range beitraege {
+			for _, a := range verprobungKz[b.Kz] {
+				erwartet[a.bereich] += b.Betrag * a.faktor
+			}
+		}
+		for _, bereich := range VerprobungBereiche {
+			g, k := round2(gebucht[bereich]), round2(erwartet[bereich])
+			if math.Abs(g-k) >= 0.005 {
+				belege[bereich] = append(belege[bereich], VerprobungBeleg{
+					Belegnummer:    r.Belegnummer,
+					Auftraggeber:   r.Auftraggeber,
+					Rechnungsdatum: r.Rechnungsdatum,
+					Gebucht:        g,
+					Kennzahlen:     k,
+					Geschaetzt:     geschaetzt,
+				})
+			}
+		}
+	}
+
+	u := v.UStVA
+	for _, bereich := range VerprobungBereiche {
+		b := VerprobungBereich{Bereich: bereich, Belege: belege[bereich]}
+		for konto, saldo := range salden {
+			if konten[konto] == bereich {
+				b.Konten = append(b.Konten, VerprobungKonto{Konto: konto, Saldo: round2(saldo)})
+				b.Gebucht += saldo
+			}
+		}
+		sort.Slice(b.Konten, func(i, j int) bool { return b.Konten[i].Konto < b.Konten[j].Konto })
+		b.Gebucht = round2(b.Gebucht)
+		switch bereich {
+		case VerprobungUSt:
+			b.Kennzahlen = round2(u.Steuer())
+		case VerprobungVSt:
+			b.Kennzahlen = round2(u.Vorsteuer())
+		default:
+			for kz, anteile := range verprobungKz {
+				for _, a := range anteile {
+					if a.bereich == bereich {
+						b.Kennzahlen += u.Wert(kz) * a.faktor
+					}
+				}
+			}
+			b.Kennzahlen = round2(b.Kennzahlen)
+		}
+		v.Bereiche = append(v.Bereiche, b)
+	}
+	return v
+}
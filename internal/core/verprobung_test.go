@@ -0,0 +1,132 @@
+package core
+
+import "testing"
+
+func verprobungRules(t *testing.T) *BookingRules {
+	t.Helper()
+	rules, err := ParseBookingRules([]byte(`{
+		"vorsteuer_konten":{"19":1576},
+		"umsatzsteuer_konten":{"19":1776},
+		"erloes_konten":{"inland":8400,"eu":8336,"drittland":8338},
+		"regeln":[{"kategorie":"reverse_charge","rc_satz":19,"konto_vst_rc":1577,"konto_ust_rc":1787}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestComputeUStVerprobung(t *testing.T) {
+	rules := verprobungRules(t)
+	rows := []CSVRow{
+		// sale 19 %, booked correctly
+		{Belegnummer: "A1", Ausgangsrechnung: true, TaxLines: []TaxLine{{Netto: 1000, SatzProzent: 19, MwStBetrag: 190}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 1190, Soll: true}, {Konto: 8400, Betrag: 1000}, {Konto: 1776, Betrag: 190}}}},
+		// expense 19 %, booked correctly
+		{Belegnummer: "E1", TaxLines: []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 4240, Betrag: 100, Soll: true}, {Konto: 1576, Betrag: 19, Soll: true}, {Konto: 1200, Betrag: 119}}}},
+		// sale 19 %, not booked: Kz 81 from the invoice data
+		{Belegnummer: "A2", Auftraggeber: "Kunde", Ausgangsrechnung: true, TaxLines: []TaxLine{{Netto: 500, SatzProzent: 19, MwStBetrag: 95}}},
+		// EU service booked on the domestic revenue account: Kz 21 from the invoice data
+		{Belegnummer: "A3", Ausgangsrechnung: true, VATID: "FR12345678901", TaxLines: []TaxLine{{Netto: 300}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 300, Soll: true}, {Konto: 8400, Betrag: 300}}}},
+		// § 13b, booked correctly
+		{Belegnummer: "E2", VATID: "AT12345678", TaxLines: []TaxLine{{Netto: 200}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 4900, Betrag: 200, Soll: true}, {Konto: 1577, Betrag: 38, Soll: true}, {Konto: 1787, Betrag: 38}, {Konto: 1200, Betrag: 200}}}},
+	}
+	v := ComputeUStVerprobung(rows, rules)
+
+	ust := v.Bereich(VerprobungUSt)
+	if !almost(ust.Gebucht, 228) || !almost(ust.Kennzahlen, 323) || !almost(ust.Differenz(), -95) {
+		t.Errorf("USt = %v / %v / %v, want 228 / 323 / -95", ust.Gebucht, ust.Kennzahlen, ust.Differenz())
+	}
+	if len(ust.Konten) != 2 || ust.Konten[0].Konto != 1776 || ust.Konten[1].Konto != 1787 {
+		t.Errorf("USt Konten = %+v, want 1776, 1787", ust.Konten)
+	}
+	if len(ust.Belege) != 1 || ust.Belege[0].Belegnummer != "A2" || !ust.Belege[0].Geschaetzt || !almost(ust.Belege[0].Differenz(), -95) {
+		t.Errorf("USt Belege = %+v, want A2 −95 (geschätzt)", ust.Belege)
+	}
+	if ust.Rundung() != 0 {
+		t.Errorf("USt Rundung = %v, want 0", ust.Rundung())
+	}
+
+	if vst := v.Bereich(VerprobungVSt); !almost(vst.Gebucht, 57) || vst.Differenz() != 0 || len(vst.Belege) != 0 {
+		t.Errorf("VSt = %+v, want 57 without difference", vst)
+	}
+
+	inland := v.Bereich(VerprobungInland)
+	if !almost(inland.Gebucht, 1300) || !almost(inland.Kennzahlen, 1500) || len(inland.Belege) != 2 {
+		t.Fatalf("Inland = %+v, want 1300 / 1500 with 2 rows", inland)
+	}
+	if inland.Belege[0].Belegnummer != "A2" || !almost(inland.Belege[0].Differenz(), -500) ||
+		inland.Belege[1].Belegnummer != "A3" || !almost(inland.Belege[1].Differenz(), 300) {
+		t.Errorf("Inland Belege = %+v, want A2 −500, A3 +300", inland.Belege)
+	}
+
+	eu := v.Bereich(VerprobungEU)
+	if eu.Gebucht != 0 || !almost(eu.Kennzahlen, 300) || len(eu.Belege) != 1 || eu.Belege[0].Belegnummer != "A3" {
+		t.Errorf("EU = %+v, want 0 / 300 with A3", eu)
+	}
+	if d := v.Bereich(VerprobungDrittland); d.Differenz() != 0 || len(d.Belege) != 0 {
+		t.Errorf("Drittland = %+v, want no difference", d)
+	}
+
+	// Zahllast: booked 228 − 57 = 171, Kz 83 = 323 − 57 = 266
+	if !almost(v.Zahllast(), 171) || !almost(v.ZahllastDifferenz(), -95) || !v.Abweichend() {
+		t.Errorf("Zahllast = %v, Differenz = %v, want 171 / −95", v.Zahllast(), v.ZahllastDifferenz())
+	}
+}
+
+func TestComputeUStVerprobungRundung(t *testing.T) {
+	rules := verprobungRules(t)
+	var rows []CSVRow
+	for i := 0; i < 3; i++ {
+		rows = append(rows, CSVRow{Ausgangsrechnung: true, TaxLines: []TaxLine{{Netto: 0.33, SatzProzent: 19, MwStBetrag: 0.06}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 0.39, Soll: true}, {Konto: 8400, Betrag: 0.33}, {Konto: 1776, Betrag: 0.06}}}})
+	}
+	v := ComputeUStVerprobung(rows, rules)
+
+	// booked 3 × 0.06 = 0.18; Kz 81 = 0.99 → USt 0.19. No row differs.
+	ust := v.Bereich(VerprobungUSt)
+	if len(ust.Belege) != 0 || !almost(ust.Differenz(), -0.01) || !almost(ust.Rundung(), -0.01) {
+		t.Errorf("USt = %+v (Differenz %v, Rundung %v), want −0.01 Rundung only", ust, ust.Differenz(), ust.Rundung())
+	}
+	if inland := v.Bereich(VerprobungInland); inland.Differenz() != 0 {
+		t.Errorf("Inland Differenz = %v, want 0", inland.Differenz())
+	}
+}
+
+// TestComputeUStVerprobungUmbuchung: a manual journal entry on a tax account
+// changes the booked balance but not the return, so it is listed as the
+// cause of the difference — on the Umsatzsteuer and on the Vorsteuer side.
+func TestComputeUStVerprobungUmbuchung(t *testing.T) {
+	rules := verprobungRules(t)
+	u := Umbuchung{Belegnummer: "U-1", Datum: "31.01.2026", Text: "USt-Korrektur",
+		Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 10, Soll: true}, {Konto: 1776, Betrag: 10}}}}
+	rows := append([]CSVRow{{Belegnummer: "A1", Ausgangsrechnung: true, TaxLines: []TaxLine{{Netto: 100, SatzProzent: 19, MwStBetrag: 19}},
+		Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 119, Soll: true}, {Konto: 8400, Betrag: 100}, {Konto: 1776, Betrag: 19}}}}},
+		UmbuchungRows([]Umbuchung{u})...)
+	v := ComputeUStVerprobung(rows, rules)
+
+	ust := v.Bereich(VerprobungUSt)
+	if !almost(ust.Gebucht, 29) || !almost(ust.Kennzahlen, 19) || len(ust.Belege) != 1 || ust.Belege[0].Belegnummer != "U-1" {
+		t.Errorf("USt = %+v, want 29 / 19 with U-1", ust)
+	}
+	if !almost(v.ZahllastDifferenz(), 10) {
+		t.Errorf("ZahllastDifferenz = %v, want 10", v.ZahllastDifferenz())
+	}
+
+	vst := Umbuchung{Belegnummer: "U-2", Datum: "31.01.2026", Text: "Vorsteuer-Korrektur",
+		Buchung: Booking{Entries: []BookingEntry{{Konto: 1576, Betrag: 10, Soll: true}, {Konto: 1200, Betrag: 10}}}}
+	v = ComputeUStVerprobung(append(rows[:1:1], UmbuchungRows([]Umbuchung{vst})...), rules)
+	if v.UStVA.Kz66 != 0 {
+		t.Errorf("Kz66 = %v, want 0: Umbuchungen are not part of the return", v.UStVA.Kz66)
+	}
+	b := v.Bereich(VerprobungVSt)
+	if !almost(b.Gebucht, 10) || b.Kennzahlen != 0 || len(b.Belege) != 1 || b.Belege[0].Belegnummer != "U-2" || !almost(b.Belege[0].Differenz(), 10) {
+		t.Errorf("VSt = %+v, want 10 / 0 with U-2", b)
+	}
+	if !almost(v.ZahllastDifferenz(), -10) {
+		t.Errorf("ZahllastDifferenz = %v, want -10", v.ZahllastDifferenz())
+	}
+}
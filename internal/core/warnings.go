@@ -7,6 +7,10 @@ import (
 	"time"
 )
 
+// warnAusgangOhneUSt is the missing-VAT-ID nudge for outgoing invoices without
+// VAT; KleinunternehmerWarnings drops it, as such invoices never carry VAT.
+const warnAusgangOhneUSt = "Ausgangsrechnung ohne USt und ohne Kunden-USt-IdNr — bei EU-Kunden fehlt sonst der ZM-Eintrag (bei Drittland/Schweiz ok)"
+
 // vatIDRegex validates VAT-ID format: 2-letter country code + 6-14 alphanumeric chars.
 var vatIDRegex = regexp.MustCompile(`^[A-Z]{2}[0-9A-Za-z]{6,14}$`)
 
@@ -31,7 +35,7 @@ func InvoiceWarningsAsOf(row CSVRow, today time.Time) []string {
 	// Zusammenfassende Meldung (and Kz 21). Harmless for genuine third-country
 	// (Drittland) supplies — hence advisory.
 	if row.Ausgangsrechnung && row.SteuersatzBetrag == 0 && strings.TrimSpace(row.VATID) == "" {
-		w = append(w, "Ausgangsrechnung ohne USt und ohne Kunden-USt-IdNr — bei EU-Kunden fehlt sonst der ZM-Eintrag (bei Drittland/Schweiz ok)")
+		w = append(w, warnAusgangOhneUSt)
 	}
 
 	// Future date check
@@ -1,6 +1,9 @@
 package core
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"fmt"
+)
 
 // BuildUStVAXML renders the UStVA as a structured, machine-readable XML of the
 // official Kennzahlen (net bases + derived VAT + Zahllast) for the period. Not
@@ -13,26 +16,18 @@ func BuildUStVAXML(u UStVAOfficial, zeitraum, ownVatID string) ([]byte, error) {
 		Wert        float64 `xml:"wert"`
 	}
 	type doc struct {
-		XMLName   xml.Name `xml:"UmsatzsteuerVoranmeldung"`
-		Zeitraum  string   `xml:"zeitraum,attr"`
-		UStIdNr   string   `xml:"ust_idnr,attr,omitempty"`
-		Kennzahl  []kz     `xml:"kennzahl"`
-	}
-	d := doc{Zeitraum: zeitraum, UStIdNr: ownVatID}
-	add := func(nr, name string, wert float64, always bool) {
-		if wert != 0 || always {
-			d.Kennzahl = append(d.Kennzahl, kz{nr, name, round2(wert)})
+		XMLName     xml.Name `xml:"UmsatzsteuerVoranmeldung"`
+		Zeitraum    string   `xml:"zeitraum,attr"`
+		UStIdNr     string   `xml:"ust_idnr,attr,omitempty"`
+		Besteuerung string   `xml:"besteuerung,attr"`
+		Kennzahl    []kz     `xml:"kennzahl"`
+	}
+	d := doc{Zeitraum: zeitraum, UStIdNr: ownVatID, Besteuerung: besteuerung(u.Ist)}
+	for _, z := range ustvaXMLZeilen {
+		if wert := u.Wert(z.kz); wert != 0 || z.kz == "83" {
+			d.Kennzahl = append(d.Kennzahl, kz{z.kz, z.bezeichnung, round2(wert)})
 		}
 	}
-	add("81", "Steuerpflichtige Umsätze 19 %", u.Kz81, false)
-	add("86", "Steuerpflichtige Umsätze 7 %", u.Kz86, false)
-	add("21", "Innergem. sonstige Leistungen (§ 18b UStG)", u.Kz21, false)
-	add("45", "Übrige nicht steuerbare Umsätze (Ausland)", u.Kz45, false)
-	add("84", "§ 13b Bemessungsgrundlage", u.Kz84, false)
-	add("85", "§ 13b Steuer", u.Kz85, false)
-	add("66", "Vorsteuer aus Rechnungen", u.Kz66, false)
-	add("67", "Vorsteuer aus § 13b-Leistungen", u.Kz67, false)
-	add("83", "Verbleibende Vorauszahlung / Überschuss", u.Kz83, true)
 
 	out, err := xml.MarshalIndent(d, "", "  ")
 	if err != nil {
@@ -41,6 +36,52 @@ func BuildUStVAXML(u UStVAOfficial, zeitraum, ownVatID string) ([]byte, error) {
 	return append([]byte(xml.Header), out...), nil
 }
 
+// ustvaXMLZeilen are the Kennzahlen of the UStVA XML in emit order, with
+// their bezeichnung.
+var ustvaXMLZeilen = []struct{ kz, bezeichnung string }{
+	{"81", "Steuerpflichtige Umsätze 19 %"},
+	{"86", "Steuerpflichtige Umsätze 7 %"},
+	{"35", "Umsätze zu anderen Steuersätzen"},
+	{"36", "Steuer auf Umsätze zu anderen Steuersätzen"},
+	{"41", "Innergem. Lieferungen an Abnehmer mit USt-IdNr."},
+	{"44", "Innergem. Lieferungen neuer Fahrzeuge ohne USt-IdNr."},
+	{"43", "Weitere steuerfreie Umsätze mit Vorsteuerabzug"},
+	{"89", "Innergem. Erwerbe 19 %"},
+	{"93", "Innergem. Erwerbe 7 %"},
+	{"46", "§ 13b Abs. 1: Leistungen aus dem übrigen Gemeinschaftsgebiet"},
+	{"47", "§ 13b Abs. 1: Steuer"},
+	{"84", "Andere § 13b-Leistungen (Bemessungsgrundlage)"},
+	{"85", "Andere § 13b-Leistungen (Steuer)"},
+	{"21", "Innergem. sonstige Leistungen (§ 18b UStG)"},
+	{"45", "Übrige nicht steuerbare Umsätze (Ausland)"},
+	{"66", "Vorsteuer aus Rechnungen"},
+	{"61", "Vorsteuer aus innergem. Erwerb"},
+	{"62", "Entrichtete Einfuhrumsatzsteuer"},
+	{"67", "Vorsteuer aus § 13b-Leistungen"},
+	{"64", "Berichtigung des Vorsteuerabzugs (§ 15a UStG)"},
+	{"39", "Abzug der Sondervorauszahlung (Dauerfristverlängerung)"},
+	{"83", "Verbleibende Vorauszahlung / Überschuss"},
+}
+
+// UStVABezeichnung is the name of a UStVA Kennzahl as in the XML export; ""
+// for an unknown one.
+func UStVABezeichnung(kz string) string {
+	for _, z := range ustvaXMLZeilen {
+		if z.kz == kz {
+			return z.bezeichnung
+		}
+	}
+	return ""
+}
+
+// besteuerung is the XML value of the taxation basis: "ist" or "soll".
+func besteuerung(ist bool) string {
+	if ist {
+		return "ist"
+	}
+	return "soll"
+}
+
 // BuildZMXML renders the Zusammenfassende Meldung as structured XML: one
 // Meldezeile per EU customer VAT-ID (net + Art der Leistung) plus the control
 // total and the own VAT-ID.
@@ -54,12 +95,85 @@ func BuildZMXML(z ZM, zeitraum, ownVatID string) ([]byte, error) {
 		XMLName       xml.Name `xml:"ZusammenfassendeMeldung"`
 		Zeitraum      string   `xml:"zeitraum,attr"`
 		UStIdNr       string   `xml:"ust_idnr,attr,omitempty"`
+		Besteuerung   string   `xml:"besteuerung,attr"`
 		Kontrollsumme float64  `xml:"kontrollsumme"`
 		Meldezeile    []zeile  `xml:"meldezeile"`
 	}
-	d := doc{Zeitraum: zeitraum, UStIdNr: ownVatID, Kontrollsumme: z.Kontrollsumme}
+	d := doc{Zeitraum: zeitraum, UStIdNr: ownVatID, Besteuerung: besteuerung(z.Ist), Kontrollsumme: z.Kontrollsumme}
 	for _, l := range z.Zeilen {
-		d.Meldezeile = append(d.Meldezeile, zeile{UStIdNr: l.UStIdNr, Summe: l.Netto, ArtDerLeistung: "Sonstige Leistung"})
+		d.Meldezeile = append(d.Meldezeile, zeile{UStIdNr: l.UStIdNr, Summe: l.Netto, ArtDerLeistung: ZMArtBezeichnung(l.Leistungsart())})
+	}
+	out, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// BuildUStJahrXML renders the annual VAT return as structured XML: per line
+// the annual value, the sum of the Voranmeldungen and their difference
+// (lines where both are zero are omitted, Kz 83 is always emitted), the
+// Vorauszahlungssoll and Abschlusszahlung, the Voranmeldungen and the
+// Nachbuchungen in locked periods. Like BuildUStVAXML a clean export for the
+// tax advisor, not an ELSTER transmission.
+func BuildUStJahrXML(j UStJahreserklaerung, ownVatID string) ([]byte, error) {
+	type kz struct {
+		Nr          string  `xml:"nr,attr"`
+		JahrNr      string  `xml:"jahr_nr,attr,omitempty"`
+		Bezeichnung string  `xml:"bezeichnung,attr"`
+		Wert        float64 `xml:"wert"`
+		Summe       float64 `xml:"summe_voranmeldungen"`
+		Differenz   float64 `xml:"differenz"`
+	}
+	type voranmeldung struct {
+		Zeitraum      string  `xml:"zeitraum,attr"`
+		Kz83          float64 `xml:"kz83"`
+		Nachbuchungen int     `xml:"nachbuchungen,omitempty"`
+	}
+	type beitrag struct {
+		Nr   string  `xml:"nr,attr"`
+		Wert float64 `xml:",chardata"`
+	}
+	type nachbuchung struct {
+		Belegnummer     string    `xml:"belegnummer,attr"`
+		Rechnungsdatum  string    `xml:"rechnungsdatum,attr"`
+		Belegperiode    string    `xml:"belegperiode,attr"`
+		Buchungsperiode string    `xml:"buchungsperiode,attr"`
+		Geschaetzt      bool      `xml:"geschaetzt,attr,omitempty"`
+		Auftraggeber    string    `xml:"auftraggeber,omitempty"`
+		Kennzahl        []beitrag `xml:"kennzahl"`
+	}
+	type doc struct {
+		XMLName            xml.Name       `xml:"UmsatzsteuerJahreserklaerung"`
+		Jahr               int            `xml:"jahr,attr"`
+		UStIdNr            string         `xml:"ust_idnr,attr,omitempty"`
+		Besteuerung        string         `xml:"besteuerung,attr"`
+		Kennzahl           []kz           `xml:"kennzahl"`
+		Vorauszahlungssoll float64        `xml:"vorauszahlungssoll"`
+		Abschlusszahlung   float64        `xml:"abschlusszahlung"`
+		Voranmeldung       []voranmeldung `xml:"voranmeldung"`
+		Nachbuchung        []nachbuchung  `xml:"nachbuchung"`
+	}
+	d := doc{Jahr: j.Jahr, UStIdNr: ownVatID, Besteuerung: besteuerung(j.Ist),
+		Vorauszahlungssoll: j.Vorauszahlungssoll, Abschlusszahlung: j.Abschlusszahlung}
+	for _, z := range UStJahrZeilen {
+		wert, summe := j.Erklaerung.Wert(z.Kz), j.Summe.Wert(z.Kz)
+		if wert == 0 && summe == 0 && z.Kz != "83" {
+			continue
+		}
+		d.Kennzahl = append(d.Kennzahl, kz{z.Kz, z.JahrKz, z.Bezeichnung, wert, summe, j.Differenz(z.Kz)})
+	}
+	for _, p := range j.Voranmeldungen {
+		d.Voranmeldung = append(d.Voranmeldung, voranmeldung{
+			Zeitraum: fmt.Sprintf("%04d-%s", j.Jahr, p.Zeitraum()), Kz83: p.UStVA.Kz83, Nachbuchungen: len(j.NachbuchungenIn(p))})
+	}
+	for _, n := range j.Nachbuchungen {
+		nb := nachbuchung{Belegnummer: n.Belegnummer, Rechnungsdatum: n.Rechnungsdatum, Belegperiode: n.Belegperiode,
+			Buchungsperiode: n.Buchungsperiode, Geschaetzt: n.Geschaetzt, Auftraggeber: n.Auftraggeber}
+		for _, b := range n.Beitraege() {
+			nb.Kennzahl = append(nb.Kennzahl, beitrag{b.Kz, b.Betrag})
+		}
+		d.Nachbuchung = append(d.Nachbuchung, nb)
 	}
 	out, err := xml.MarshalIndent(d, "", "  ")
 	if err != nil {
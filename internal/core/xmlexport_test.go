@@ -46,3 +46,39 @@ func TestBuildZMXML(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildUStJahrXML(t *testing.T) {
+	j := UStJahreserklaerung{
+		Jahr:       2025,
+		Erklaerung: UStVAOfficial{Kz81: 1000.06, USt81: 190.01, Kz83: 190.01},
+		Summe:      UStVAOfficial{Kz81: 1000.06, USt81: 190.02, Kz83: 190.02},
+		Voranmeldungen: []UStJahrPeriode{
+			{VonMonat: 1, BisMonat: 3, UStVA: UStVAOfficial{Kz83: 190.02}},
+			{VonMonat: 4, BisMonat: 6},
+		},
+		Nachbuchungen: []UStNachbuchung{{Belegnummer: "AR-1", Rechnungsdatum: "10.01.2025", Belegperiode: "2025-01",
+			Buchungsperiode: "2025-02", Kennzahlen: map[string]float64{"81": 0.03}}},
+		Vorauszahlungssoll: 190.02,
+		Abschlusszahlung:   -0.01,
+	}
+	data, err := BuildUStJahrXML(j, "287472874")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var probe struct{}
+	if err := xml.Unmarshal(data, &probe); err != nil {
+		t.Fatalf("not valid XML: %v", err)
+	}
+	s := string(data)
+	for _, want := range []string{`jahr="2025"`, `besteuerung="soll"`, `nr="81" jahr_nr="177"`, "<wert>1000.06</wert>",
+		"<summe_voranmeldungen>190.02</summe_voranmeldungen>", "<differenz>-0.01</differenz>",
+		"<abschlusszahlung>-0.01</abschlusszahlung>", `<voranmeldung zeitraum="2025-Q1">`, "<nachbuchungen>1</nachbuchungen>",
+		`belegnummer="AR-1"`, `<kennzahl nr="81">0.03</kennzahl>`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("XML missing %q:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, `nr="86"`) {
+		t.Error("zero Kz86 should be omitted")
+	}
+}
@@ -26,10 +26,38 @@ func IsEUVatID(s string) bool {
 	return euVatPrefixes[s[:2]]
 }
 
-// ZMZeile is one ZM line: a customer's EU VAT-ID and the summed net supplies.
+// Art der Leistung of a ZM line, as coded in the BZSt upload (§ 18a UStG).
+const (
+	ZMArtLieferung = "L" // innergemeinschaftliche Warenlieferung
+	ZMArtDreieck   = "D" // innergemeinschaftliches Dreiecksgeschäft
+	ZMArtSonstige  = "S" // sonstige Leistung (§ 3a Abs. 2 UStG)
+)
+
+// ZMZeile is one ZM line: a customer's EU VAT-ID, the Art der Leistung and the
+// summed net supplies.
 type ZMZeile struct {
 	UStIdNr string
 	Netto   float64
+	Art     string // ZMArt*; "" = ZMArtSonstige
+}
+
+// Leistungsart is the line's Art der Leistung, ZMArtSonstige when unset.
+func (l ZMZeile) Leistungsart() string {
+	if l.Art == "" {
+		return ZMArtSonstige
+	}
+	return l.Art
+}
+
+// ZMArtBezeichnung is the German name of an Art der Leistung.
+func ZMArtBezeichnung(art string) string {
+	switch art {
+	case ZMArtLieferung:
+		return "Innergemeinschaftliche Lieferung"
+	case ZMArtDreieck:
+		return "Dreiecksgeschäft"
+	}
+	return "Sonstige Leistung"
 }
 
 // ZM is the Zusammenfassende Meldung for a period: one line per EU customer
@@ -37,26 +65,62 @@ type ZMZeile struct {
 type ZM struct {
 	Zeilen        []ZMZeile
 	Kontrollsumme float64
+	Ist           bool // profile uses Ist-Versteuerung; the ZM still follows the period of supply (§ 18a UStG)
 }
 
 // ComputeZM sums the net of intra-EU reverse-charge supplies (outgoing invoices
 // to an EU customer VAT-ID with no VAT charged), grouped per customer VAT-ID.
+// Every line is a sonstige Leistung; ComputeZMMitRegeln tells the ig
+// Lieferungen apart.
 func ComputeZM(rows []CSVRow) ZM {
+	return ComputeZMMitRegeln(rows, nil)
+}
+
+// zmZeile reports whether an EUR row belongs in the ZM and under which
+// customer VAT-ID and Art der Leistung.
+func zmZeile(r CSVRow, idx KennzahlIndex) (vat, art string, ok bool) {
+	if !r.Ausgangsrechnung || !IsEUVatID(r.VATID) || SumMwSt(r.TaxLines) != 0 {
+		return "", "", false
+	}
+	art = ZMArtSonstige
+	beitraege, _ := RowKennzahlen(r, idx)
+	for _, b := range beitraege {
+		if b.Kz == "41" {
+			art = ZMArtLieferung
+		}
+	}
+	return strings.ToUpper(strings.TrimSpace(r.VATID)), art, true
+}
+
+// ComputeZMMitRegeln is ComputeZM with the Art der Leistung taken from the
+// bookings: a row with an entry mapped to Kz 41 (KennzahlKonten) is an ig
+// Lieferung, every other row a sonstige Leistung. Lines are grouped per
+// customer VAT-ID and Art. rules may be nil.
+func ComputeZMMitRegeln(rows []CSVRow, rules *BookingRules) ZM {
 	rows = RowsEUR(rows) // EU reverse-charge sales must be reported in EUR
-	byVat := map[string]float64{}
+	var idx KennzahlIndex
+	if rules != nil {
+		idx = NewKennzahlIndex(rules.KennzahlKonten())
+	}
+	type key struct{ vat, art string }
+	sums := map[key]float64{}
 	for _, r := range rows {
-		if !r.Ausgangsrechnung || !IsEUVatID(r.VATID) || SumMwSt(r.TaxLines) != 0 {
-			continue
+		if vat, art, ok := zmZeile(r, idx); ok {
+			sums[key{vat, art}] += SumNetto(r.TaxLines)
 		}
-		byVat[strings.ToUpper(strings.TrimSpace(r.VATID))] += SumNetto(r.TaxLines)
 	}
 	var z ZM
-	for vat, netto := range byVat {
+	for k, netto := range sums {
 		netto = round2(netto)
-		z.Zeilen = append(z.Zeilen, ZMZeile{UStIdNr: vat, Netto: netto})
+		z.Zeilen = append(z.Zeilen, ZMZeile{UStIdNr: k.vat, Netto: netto, Art: k.art})
 		z.Kontrollsumme += netto
 	}
-	sort.Slice(z.Zeilen, func(i, j int) bool { return z.Zeilen[i].UStIdNr < z.Zeilen[j].UStIdNr })
+	sort.Slice(z.Zeilen, func(i, j int) bool {
+		if z.Zeilen[i].UStIdNr != z.Zeilen[j].UStIdNr {
+			return z.Zeilen[i].UStIdNr < z.Zeilen[j].UStIdNr
+		}
+		return z.Zeilen[i].Art < z.Zeilen[j].Art
+	})
 	z.Kontrollsumme = round2(z.Kontrollsumme)
 	return z
 }
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// BZSt CSV upload of the Zusammenfassende Meldung: two version lines, the
+// column header and one line per customer VAT-ID and Art der Leistung, in
+// ISO-8859-1 with CRLF line ends. The period and the own VAT-ID are entered
+// in the BZStOnline-Portal form the file is uploaded into.
+
+var zmCSVKopf = []string{"#v1.0", "#ve0002", "Länderkennzeichen,USt-IdNr.,Betrag(Euro),Art der Leistung"}
+
+// zmUStIdNrFormat is the format of the VAT-ID after the country code, per
+// member state (VIES).
+var zmUStIdNrFormat = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^U[0-9]{8}$`),
+	"BE": regexp.MustCompile(`^[01][0-9]{9}$`),
+	"BG": regexp.MustCompile(`^[0-9]{9,10}$`),
+	"CY": regexp.MustCompile(`^[0-9]{8}[A-Z]$`),
+	"CZ": regexp.MustCompile(`^[0-9]{8,10}$`),
+	"DK": regexp.MustCompile(`^[0-9]{8}$`),
+	"EE": regexp.MustCompile(`^[0-9]{9}$`),
+	"EL": regexp.MustCompile(`^[0-9]{9}$`),
+	"ES": regexp.MustCompile(`^[0-9A-Z][0-9]{7}[0-9A-Z]$`),
+	"FI": regexp.MustCompile(`^[0-9]{8}$`),
+	"FR": regexp.MustCompile(`^[0-9A-Z]{2}[0-9]{9}$`),
+	"HR": regexp.MustCompile(`^[0-9]{11}$`),
+	"HU": regexp.MustCompile(`^[0-9]{8}$`),
+	"IE": regexp.MustCompile(`^[0-9][0-9A-Z+*][0-9]{5}[A-Z]{1,2}$`),
+	"IT": regexp.MustCompile(`^[0-9]{11}$`),
+	"LT": regexp.MustCompile(`^([0-9]{9}|[0-9]{12})$`),
+	"LU": regexp.MustCompile(`^[0-9]{8}$`),
+	"LV": regexp.MustCompile(`^[0-9]{11}$`),
+	"MT": regexp.MustCompile(`^[0-9]{8}$`),
+	"NL": regexp.MustCompile(`^[0-9]{9}B[0-9]{2}$`),
+	"PL": regexp.MustCompile(`^[0-9]{10}$`),
+	"PT": regexp.MustCompile(`^[0-9]{9}$`),
+	"RO": regexp.MustCompile(`^[0-9]{2,10}$`),
+	"SE": regexp.MustCompile(`^[0-9]{12}$`),
+	"SI": regexp.MustCompile(`^[0-9]{8}$`),
+	"SK": regexp.MustCompile(`^[0-9]{10}$`),
+}
+
+// zmEuro is a ZM amount: whole euros, cents dropped.
+func zmEuro(v float64) string {
+	return strconv.FormatFloat(math.Trunc(round2(v)), 'f', 0, 64)
+}
+
+// BuildZMCSV renders the ZM in the BZSt CSV upload format. VAT-IDs are
+// split into country code and number; amounts are whole euros. The result
+// is checked with ValidateZMCSV; any Fehler is returned as error.
+func BuildZMCSV(z ZM) ([]byte, error) {
+	var b strings.Builder
+	for _, l := range zmCSVKopf {
+		b.WriteString(l + "\r\n")
+	}
+	for _, l := range z.Zeilen {
+		id := strings.ToUpper(strings.Join(strings.Fields(l.UStIdNr), ""))
+		if len(id) < 3 {
+			return nil, fmt.Errorf("USt-IdNr. %q ist unvollständig", l.UStIdNr)
+		}
+		fmt.Fprintf(&b, "%s,%s,%s,%s\r\n", id[:2], id[2:], zmEuro(l.Netto), l.Leistungsart())
+	}
+	data, err := charmap.ISO8859_1.NewEncoder().Bytes([]byte(b.String()))
+	if err != nil {
+		return nil, err
+	}
+	if err := findingsError(ValidateZMCSV(data)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+var zmBetrag = regexp.MustCompile(`^-?[0-9]{1,12}$`)
+
+// ValidateZMCSV checks a BZSt ZM upload (ISO-8859-1) against the rules below,
+// hand-written after the BZSt's field description. It is no validation
+// against the BZSt's own specification files and no substitute for the
+// check of the BZStOnline-Portal on upload:
+//
+//	ZM-01  version lines #v1.0 / #ve0002 and the column header
+//	ZM-02  at least one Meldezeile
+//	ZM-03  four fields per line
+//	ZM-04  Länderkennzeichen of another EU member state
+//	ZM-05  USt-IdNr. in the format of that member state
+//	ZM-06  Betrag in whole euros
+//	ZM-07  Art der Leistung L, D or S
+//	ZM-08  one line per USt-IdNr. and Art der Leistung
+//	ZM-09  Betrag 0 (Warnung: the line has nothing to report)
+func ValidateZMCSV(data []byte) []ValidationFinding {
+	var out []ValidationFinding
+	add := func(rule, severity, format string, args ...interface{}) {
+		out = append(out, ValidationFinding{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	text, err := charmap.ISO8859_1.NewDecoder().Bytes(data)
+	if err != nil {
+		add("ZM-01", SeverityFehler, "Datei ist nicht ISO-8859-1-kodiert: %v", err)
+		return out
+	}
+	lines := strings.Split(strings.TrimRight(string(bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))), "\n"), "\n")
+	for i, want := range zmCSVKopf {
+		if i >= len(lines) || lines[i] != want {
+			add("ZM-01", SeverityFehler, "Zeile %d muss %q lauten", i+1, want)
+			return out
+		}
+	}
+	zeilen := lines[len(zmCSVKopf):]
+	if len(zeilen) == 0 {
+		add("ZM-02", SeverityFehler, "keine Meldezeile")
+	}
+	seen := map[string]bool{}
+	for i, l := range zeilen {
+		nr := i + len(zmCSVKopf) + 1
+		f := strings.Split(l, ",")
+		if len(f) != 4 {
+			add("ZM-03", SeverityFehler, "Zeile %d: %d statt 4 Felder", nr, len(f))
+			continue
+		}
+		land, id, betrag, art := f[0], f[1], f[2], f[3]
+		if format, ok := zmUStIdNrFormat[land]; !ok {
+			add("ZM-04", SeverityFehler, "Zeile %d: %q ist kein Länderkennzeichen eines anderen EU-Mitgliedstaats", nr, land)
+		} else if !format.MatchString(id) {
+			add("ZM-05", SeverityFehler, "Zeile %d: USt-IdNr. %s%s entspricht nicht dem Format von %s", nr, land, id, land)
+		}
+		if !zmBetrag.MatchString(betrag) {
+			add("ZM-06", SeverityFehler, "Zeile %d: Betrag %q ist kein Betrag in vollen Euro", nr, betrag)
+		} else if strings.TrimLeft(betrag, "-0") == "" {
+			add("ZM-09", SeverityWarnung, "Zeile %d: Betrag 0 für %s%s", nr, land, id)
+		}
+		if art != ZMArtLieferung && art != ZMArtDreieck && art != ZMArtSonstige {
+			add("ZM-07", SeverityFehler, "Zeile %d: Art der Leistung %q statt L, D oder S", nr, art)
+		}
+		key := land + id + "|" + art
+		if seen[key] {
+			add("ZM-08", SeverityFehler, "Zeile %d: %s%s mit Art %s ist mehrfach gemeldet", nr, land, id, art)
+		}
+		seen[key] = true
+	}
+	return out
+}
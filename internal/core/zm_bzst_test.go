@@ -0,0 +1,83 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestBuildZMCSV(t *testing.T) {
+	z := ZM{Zeilen: []ZMZeile{
+		{UStIdNr: "ATU12345678", Netto: 1200.75, Art: ZMArtLieferung},
+		{UStIdNr: "FI26378052", Netto: 44795},
+		{UStIdNr: "FR12345678901", Netto: -300.40},
+	}}
+	data, err := BuildZMCSV(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := charmap.ISO8859_1.NewDecoder().Bytes(data)
+	want := "#v1.0\r\n#ve0002\r\nLänderkennzeichen,USt-IdNr.,Betrag(Euro),Art der Leistung\r\n" +
+		"AT,U12345678,1200,L\r\nFI,26378052,44795,S\r\nFR,12345678901,-300,S\r\n"
+	if string(text) != want {
+		t.Errorf("ZM CSV =\n%q\nwant\n%q", text, want)
+	}
+	if strings.Contains(string(data), "ä") {
+		t.Error("header not ISO-8859-1 encoded")
+	}
+
+	if _, err := BuildZMCSV(ZM{Zeilen: []ZMZeile{{UStIdNr: "ATU1234", Netto: 10}}}); err == nil || !strings.Contains(err.Error(), "ZM-05") {
+		t.Errorf("malformed VAT-ID: %v", err)
+	}
+	if _, err := BuildZMCSV(ZM{}); err == nil || !strings.Contains(err.Error(), "ZM-02") {
+		t.Errorf("empty ZM: %v", err)
+	}
+}
+
+func TestValidateZMCSV(t *testing.T) {
+	kopf := "#v1.0\r\n#ve0002\r\nLänderkennzeichen,USt-IdNr.,Betrag(Euro),Art der Leistung\r\n"
+	rules := func(body string) string {
+		data, _ := charmap.ISO8859_1.NewEncoder().Bytes([]byte(kopf + body))
+		var r []string
+		for _, f := range ValidateZMCSV(data) {
+			r = append(r, f.Rule)
+		}
+		return strings.Join(r, ",")
+	}
+	for body, want := range map[string]string{
+		"NL,123456789B01,500,S\r\n":                        "",
+		"DE,123456789,500,S\r\n":                           "ZM-04",
+		"NL,123456789,500,S\r\n":                           "ZM-05",
+		"NL,123456789B01,500.50,S\r\n":                     "ZM-06",
+		"NL,123456789B01,500,X\r\n":                        "ZM-07",
+		"NL,123456789B01,500,S\r\nNL,123456789B01,7,S\r\n": "ZM-08",
+		"NL,123456789B01,500,L\r\nNL,123456789B01,7,S\r\n": "",
+		"NL,123456789B01,0,S\r\n":                          "ZM-09",
+		"NL,123456789B01,500\r\n":                          "ZM-03",
+	} {
+		if got := rules(body); got != want {
+			t.Errorf("%q: findings %q, want %q", body, got, want)
+		}
+	}
+	if f := ValidateZMCSV([]byte("Land,Id,Betrag,Art\r\n")); len(f) != 1 || f[0].Rule != "ZM-01" {
+		t.Errorf("missing version lines: %v", f)
+	}
+}
+
+func TestComputeZMMitRegeln(t *testing.T) {
+	rules := kennzahlRules(t) // account 4125 → Kz 41
+	rows := []CSVRow{
+		{Ausgangsrechnung: true, VATID: "ATU12345678", TaxLines: []TaxLine{{Netto: 3000}},
+			Buchung: Booking{Entries: []BookingEntry{{Konto: 1200, Betrag: 3000, Soll: true}, {Konto: 4125, Betrag: 3000}}}},
+		{Ausgangsrechnung: true, VATID: "ATU12345678", TaxLines: []TaxLine{{Netto: 400}}},
+	}
+	z := ComputeZMMitRegeln(rows, rules)
+	if len(z.Zeilen) != 2 || z.Zeilen[0].Art != ZMArtLieferung || !almost(z.Zeilen[0].Netto, 3000) ||
+		z.Zeilen[1].Art != ZMArtSonstige || !almost(z.Zeilen[1].Netto, 400) || !almost(z.Kontrollsumme, 3400) {
+		t.Errorf("ZM = %+v", z)
+	}
+	if z := ComputeZM(rows); len(z.Zeilen) != 1 || z.Zeilen[0].Leistungsart() != ZMArtSonstige {
+		t.Errorf("ComputeZM = %+v", z)
+	}
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"database/sql"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -143,3 +145,98 @@ func TestAuditLogLimit(t *testing.T) {
 		t.Errorf("AuditLog(3) = %d entries, want 3", len(entries))
 	}
 }
+
+// TestAuditChainDetectsTampering edits and deletes audit rows behind the
+// repository's back and expects VerifyAuditChain to point at the first
+// broken link.
+func TestAuditChainDetectsTampering(t *testing.T) {
+	repo := newTestRepo(t)
+	for _, name := range []string{"a.pdf", "b.pdf", "c.pdf"} {
+		if _, err := repo.Insert(sampleRow("2026", "06", name)); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+	if err := repo.SetFingerprints("2026", "06", "a.pdf", []core.Fingerprint{{SHA256: "aa"}}); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := repo.VerifyAuditChain()
+	if err != nil || !rep.OK() || rep.Eintraege != 5 || rep.Fingerprints != 1 {
+		t.Fatalf("fresh chain = %+v, %v", rep, err)
+	}
+	entries, _ := repo.AuditChain()
+	if entries[0].Aktion != core.AuditActionChain || entries[4].Details != `{"0":"aa"}` {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	if _, err := repo.db.Exec(`UPDATE audit_log SET schluessel = 'x.pdf' WHERE id = 3`); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ = repo.VerifyAuditChain()
+	if rep.OK() || rep.Bruch.Entry.ID != 3 {
+		t.Fatalf("edited row: %+v", rep.Bruch)
+	}
+	if _, err := repo.db.Exec(`DELETE FROM audit_log WHERE id = 3`); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ = repo.VerifyAuditChain()
+	if rep.OK() || rep.Bruch.Entry.ID != 4 {
+		t.Fatalf("deleted row: %+v", rep.Bruch)
+	}
+}
+
+// TestAuditChainAnker anchors the head, deletes the newest audit rows behind
+// the repository's back and expects only the anchored check to notice.
+func TestAuditChainAnker(t *testing.T) {
+	repo := newTestRepo(t)
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		if _, err := repo.Insert(sampleRow("2026", "06", name)); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+	anker, ok, err := repo.AuditAnker()
+	if err != nil || !ok || anker.ID != 3 {
+		t.Fatalf("AuditAnker = %+v, %v, %v", anker, ok, err)
+	}
+	if rep, err := repo.VerifyAuditChainAnker(anker); err != nil || !rep.OK() {
+		t.Fatalf("anchored fresh chain = %+v, %v", rep, err)
+	}
+	if _, err := repo.db.Exec(`DELETE FROM audit_log WHERE id >= 2`); err != nil {
+		t.Fatal(err)
+	}
+	if rep, _ := repo.VerifyAuditChain(); !rep.OK() {
+		t.Fatalf("truncated chain without anchor = %+v, want intact", rep.Bruch)
+	}
+	if rep, _ := repo.VerifyAuditChainAnker(anker); rep.OK() || rep.Bruch.Entry.ID != 3 {
+		t.Errorf("truncated chain with anchor = %+v", rep.Bruch)
+	}
+}
+
+// TestAuditChainStartsOverLegacyEntries opens a log written before the hash
+// chain: the old entries are chained once, and stay chained on reopen.
+func TestAuditChainStartsOverLegacyEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := raw.Exec(`CREATE TABLE audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, ts DATETIME DEFAULT CURRENT_TIMESTAMP,
+		aktion TEXT NOT NULL, entitaet TEXT, schluessel TEXT, details TEXT);
+		INSERT INTO audit_log (aktion, entitaet, schluessel, details) VALUES
+		('create', 'invoice', '2025-0001 alt.pdf', ''), ('lock', 'period', '2025-12', '');`); err != nil {
+		t.Fatal(err)
+	}
+	_ = raw.Close()
+
+	for i := 0; i < 2; i++ {
+		repo, err := NewRepository(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rep, err := repo.VerifyAuditChain()
+		_ = repo.Close()
+		if err != nil || !rep.OK() || rep.Eintraege != 3 {
+			t.Fatalf("open %d: %+v, %v", i+1, rep, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/bergx2/buchisy/internal/core"
@@ -19,3 +20,75 @@ func TestAusgangsrechnungPersists(t *testing.T) {
 		t.Fatalf("Ausgangsrechnung not persisted: %+v", rows)
 	}
 }
+
+func TestAusgangsRechnungsnummern(t *testing.T) {
+	repo := newTestRepo(t)
+	for i, row := range []core.CSVRow{
+		{Rechnungsnummer: "RE-2026-0001", Ausgangsrechnung: true},
+		{Rechnungsnummer: "RE-2026-0002", Ausgangsrechnung: true},
+		{Rechnungsnummer: "RE-2026-0009"}, // incoming invoice with a look-alike number
+		{Rechnungsnummer: "RE-2025-0040", Ausgangsrechnung: true},
+	} {
+		row.Dateiname = fmt.Sprintf("r%d.pdf", i)
+		row.Jahr, row.Monat = "2026", "04"
+		if _, err := repo.Insert(row); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+	got, err := repo.AusgangsRechnungsnummern("RE-2026-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := core.NextRechnungsnummer(core.DefaultNummernkreis, 2026, got); len(got) != 2 || next != "RE-2026-0003" {
+		t.Errorf("numbers = %v, next = %q", got, next)
+	}
+}
+
+func TestInsertRechnung(t *testing.T) {
+	repo := newTestRepo(t)
+	row := core.CSVRow{Dateiname: "re.pdf", Jahr: "2026", Monat: "04", Rechnungsnummer: "RE-2026-0001", Ausgangsrechnung: true}
+	if _, err := repo.InsertRechnung(row); err != nil {
+		t.Fatalf("InsertRechnung: %v", err)
+	}
+	// A number is never taken twice; the second row is not stored either.
+	twice := row
+	twice.Dateiname = "re2.pdf"
+	if _, err := repo.InsertRechnung(twice); err == nil {
+		t.Error("issuing a number twice succeeded")
+	}
+	if rows, _ := repo.List("2026", "04"); len(rows) != 1 {
+		t.Errorf("rows after the rejected insert = %d, want 1", len(rows))
+	}
+
+	// Deleting the invoice does not free its number.
+	if err := repo.Delete("2026", "04", "re.pdf"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	got, err := repo.AusgangsRechnungsnummern("RE-2026-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := core.NextRechnungsnummer(core.DefaultNummernkreis, 2026, got); next != "RE-2026-0002" {
+		t.Errorf("next after delete = %q, want RE-2026-0002", next)
+	}
+	if _, err := repo.db.Exec(`DELETE FROM rechnungsnummern`); err == nil {
+		t.Error("issued numbers could be deleted")
+	}
+
+	// A failed insert does not take the number: no gap.
+	if _, err := repo.db.Exec(`CREATE TRIGGER fail_insert BEFORE INSERT ON invoices BEGIN SELECT RAISE(ABORT, 'boom'); END`); err != nil {
+		t.Fatal(err)
+	}
+	failed := row
+	failed.Dateiname, failed.Rechnungsnummer = "re3.pdf", "RE-2026-0002"
+	if _, err := repo.InsertRechnung(failed); err == nil {
+		t.Fatal("insert with the failing trigger succeeded")
+	}
+	got, err = repo.AusgangsRechnungsnummern("RE-2026-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := core.NextRechnungsnummer(core.DefaultNummernkreis, 2026, got); next != "RE-2026-0002" {
+		t.Errorf("next after a failed insert = %q, want RE-2026-0002", next)
+	}
+}
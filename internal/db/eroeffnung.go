@@ -0,0 +1,164 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// OpeningBalances returns the EB-Werte of a fiscal year, ordered by account.
+func (r *Repository) OpeningBalances(jahr string) ([]core.OpeningBalance, error) {
+	rows, err := r.db.Query(
+		`SELECT konto, saldo, vortrag, exportiert FROM eroeffnungswerte WHERE jahr = ? ORDER BY konto`, jahr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read opening balances: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []core.OpeningBalance
+	for rows.Next() {
+		o := core.OpeningBalance{Jahr: jahr}
+		if err := rows.Scan(&o.Konto, &o.Saldo, &o.Vortrag, &o.Exportiert); err != nil {
+			return nil, fmt.Errorf("failed to scan opening balance: %w", err)
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// openingLocked reports whether the EB-Werte of jahr are fixed: they belong
+// to January, so a lock on January locks them.
+func (r *Repository) openingLocked(jahr string) error {
+	if locked, err := r.IsPeriodLocked(jahr, "01"); err != nil {
+		return fmt.Errorf("period lock check: %w", err)
+	} else if locked {
+		return ErrPeriodLocked
+	}
+	return nil
+}
+
+// SetOpeningBalance stores a manually entered EB-Wert. It replaces a carried
+// forward value for the same account and is kept by later carry-forwards.
+func (r *Repository) SetOpeningBalance(o core.OpeningBalance) error {
+	if err := r.openingLocked(o.Jahr); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`
+		INSERT INTO eroeffnungswerte (jahr, konto, saldo, vortrag, exportiert) VALUES (?, ?, ?, 0, 0)
+		ON CONFLICT(jahr, konto) DO UPDATE SET saldo = excluded.saldo, vortrag = 0, exportiert = 0,
+			updated_at = CURRENT_TIMESTAMP`,
+		o.Jahr, o.Konto, round2(o.Saldo)); err != nil {
+		return fmt.Errorf("failed to store opening balance: %w", err)
+	}
+	if auditErr := r.LogAudit(core.AuditEntry{
+		Aktion:     "update",
+		Entitaet:   "eroeffnung",
+		Schluessel: fmt.Sprintf("%s %d", o.Jahr, o.Konto),
+		Details:    fmt.Sprintf(`{"saldo":%.2f}`, round2(o.Saldo)),
+	}); auditErr != nil {
+		log.Printf("[WARN] audit_log opening balance failed: %v", auditErr)
+	}
+	return nil
+}
+
+// DeleteOpeningBalance removes the EB-Wert of an account; a carried forward
+// value comes back with the next carry-forward.
+func (r *Repository) DeleteOpeningBalance(jahr string, konto int) error {
+	if err := r.openingLocked(jahr); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`DELETE FROM eroeffnungswerte WHERE jahr = ? AND konto = ?`, jahr, konto); err != nil {
+		return fmt.Errorf("failed to delete opening balance: %w", err)
+	}
+	if auditErr := r.LogAudit(core.AuditEntry{
+		Aktion:     "delete",
+		Entitaet:   "eroeffnung",
+		Schluessel: fmt.Sprintf("%s %d", jahr, konto),
+	}); auditErr != nil {
+		log.Printf("[WARN] audit_log opening balance failed: %v", auditErr)
+	}
+	return nil
+}
+
+// ApplyCarryForward replaces the carried forward EB-Werte of jahr with vals
+// (core.ComputeCarryForward). Manual values and exported carried forward
+// values stay as they are. Returns the number of accounts whose value
+// changed; nothing is written (or audited) when nothing changed. A locked
+// January leaves the values untouched.
+func (r *Repository) ApplyCarryForward(jahr string, vals []core.OpeningBalance) (int, error) {
+	if locked, err := r.IsPeriodLocked(jahr, "01"); err != nil || locked {
+		return 0, err
+	}
+	existing, err := r.OpeningBalances(jahr)
+	if err != nil {
+		return 0, err
+	}
+	kept := map[int]bool{}
+	old := map[int]float64{}
+	for _, o := range existing {
+		if !o.Vortrag || o.Exportiert {
+			kept[o.Konto] = true
+		} else {
+			old[o.Konto] = o.Saldo
+		}
+	}
+	changed := 0
+	want := map[int]bool{}
+	for _, v := range vals {
+		if kept[v.Konto] {
+			continue
+		}
+		want[v.Konto] = true
+		if prev, ok := old[v.Konto]; !ok || math.Abs(prev-v.Saldo) >= 0.005 {
+			changed++
+		}
+	}
+	for k := range old {
+		if !want[k] {
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin carry-forward: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM eroeffnungswerte WHERE jahr = ? AND vortrag = 1 AND exportiert = 0`, jahr); err != nil {
+		return 0, fmt.Errorf("failed to clear carry-forward: %w", err)
+	}
+	for _, v := range vals {
+		if kept[v.Konto] {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO eroeffnungswerte (jahr, konto, saldo, vortrag) VALUES (?, ?, ?, 1)`,
+			jahr, v.Konto, round2(v.Saldo)); err != nil {
+			return 0, fmt.Errorf("failed to store carry-forward: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit carry-forward: %w", err)
+	}
+	if auditErr := r.LogAudit(core.AuditEntry{
+		Aktion:     "vortrag",
+		Entitaet:   "eroeffnung",
+		Schluessel: jahr,
+		Details:    fmt.Sprintf(`{"konten":%d,"geaendert":%d}`, len(want), changed),
+	}); auditErr != nil {
+		log.Printf("[WARN] audit_log carry-forward failed: %v", auditErr)
+	}
+	return changed, nil
+}
+
+// MarkOpeningBalancesExported flags the EB-Werte of jahr as included in a
+// booking export.
+func (r *Repository) MarkOpeningBalancesExported(jahr string) error {
+	if _, err := r.db.Exec(`UPDATE eroeffnungswerte SET exportiert = 1 WHERE jahr = ?`, jahr); err != nil {
+		return fmt.Errorf("failed to mark opening balances exported: %w", err)
+	}
+	return nil
+}
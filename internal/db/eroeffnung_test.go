@@ -0,0 +1,91 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+func TestOpeningBalances_CarryForwardKeepsManual(t *testing.T) {
+	repo := newTestRepo(t)
+	vals := []core.OpeningBalance{
+		{Jahr: "2027", Konto: 1800, Saldo: 2500, Vortrag: true},
+		{Jahr: "2027", Konto: 3300, Saldo: -1000, Vortrag: true},
+	}
+	if n, err := repo.ApplyCarryForward("2027", vals); err != nil || n != 2 {
+		t.Fatalf("ApplyCarryForward = %d, %v", n, err)
+	}
+	// Unchanged values are not written again.
+	if n, err := repo.ApplyCarryForward("2027", vals); err != nil || n != 0 {
+		t.Fatalf("second ApplyCarryForward = %d, %v", n, err)
+	}
+
+	if err := repo.SetOpeningBalance(core.OpeningBalance{Jahr: "2027", Konto: 1800, Saldo: 2600}); err != nil {
+		t.Fatalf("SetOpeningBalance: %v", err)
+	}
+	// A new carry-forward replaces 3300 and drops nothing manual.
+	vals[0].Saldo, vals[1].Saldo = 2700, -900
+	if n, err := repo.ApplyCarryForward("2027", vals); err != nil || n != 1 {
+		t.Fatalf("ApplyCarryForward after manual = %d, %v", n, err)
+	}
+	got, err := repo.OpeningBalances("2027")
+	if err != nil || len(got) != 2 {
+		t.Fatalf("OpeningBalances = %+v, %v", got, err)
+	}
+	if got[0].Konto != 1800 || got[0].Saldo != 2600 || got[0].Vortrag {
+		t.Errorf("manual 1800 = %+v", got[0])
+	}
+	if got[1].Konto != 3300 || got[1].Saldo != -900 || !got[1].Vortrag {
+		t.Errorf("carried 3300 = %+v", got[1])
+	}
+
+	// Exported carried values stay put; accounts no longer carried go.
+	if err := repo.MarkOpeningBalancesExported("2027"); err != nil {
+		t.Fatalf("MarkOpeningBalancesExported: %v", err)
+	}
+	if n, err := repo.ApplyCarryForward("2027", nil); err != nil || n != 0 {
+		t.Fatalf("ApplyCarryForward exported = %d, %v", n, err)
+	}
+	if err := repo.DeleteOpeningBalance("2027", 1800); err != nil {
+		t.Fatalf("DeleteOpeningBalance: %v", err)
+	}
+	if got, _ := repo.OpeningBalances("2027"); len(got) != 1 || got[0].Konto != 3300 || !got[0].Exportiert {
+		t.Errorf("after delete = %+v", got)
+	}
+
+	entries, err := repo.AuditLog(10)
+	if err != nil {
+		t.Fatalf("AuditLog: %v", err)
+	}
+	aktionen := map[string]int{}
+	for _, e := range entries {
+		if e.Entitaet == "eroeffnung" {
+			aktionen[e.Aktion]++
+		}
+	}
+	if aktionen["vortrag"] != 2 || aktionen["update"] != 1 || aktionen["delete"] != 1 {
+		t.Errorf("audit = %v", aktionen)
+	}
+}
+
+func TestOpeningBalances_LockedJanuary(t *testing.T) {
+	repo := newTestRepo(t)
+	if err := repo.LockPeriod("2027", "01"); err != nil {
+		t.Fatalf("LockPeriod: %v", err)
+	}
+	err := repo.SetOpeningBalance(core.OpeningBalance{Jahr: "2027", Konto: 1800, Saldo: 100})
+	if !errors.Is(err, ErrPeriodLocked) {
+		t.Errorf("SetOpeningBalance in locked January: %v", err)
+	}
+	if !errors.Is(repo.DeleteOpeningBalance("2027", 1800), ErrPeriodLocked) {
+		t.Error("DeleteOpeningBalance in locked January allowed")
+	}
+	n, err := repo.ApplyCarryForward("2027", []core.OpeningBalance{{Jahr: "2027", Konto: 1800, Saldo: 1}})
+	if err != nil || n != 0 {
+		t.Errorf("ApplyCarryForward locked = %d, %v", n, err)
+	}
+	if got, _ := repo.OpeningBalances("2027"); len(got) != 0 {
+		t.Errorf("locked year got values: %+v", got)
+	}
+}
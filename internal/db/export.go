@@ -15,10 +15,25 @@ import (
 // gross amount are preserved in the documentation columns Originalwaehrung and
 // Originalbetrag_Brutto so the source data is not lost.
 func (r *Repository) ExportToCSV(jahr, monat, csvPath string, csvRepo *core.CSVRepository) error {
+	rows, err := r.csvExportRows(jahr, monat)
+	if err != nil {
+		return err
+	}
+
+	// Rewrite the CSV file with all rows
+	if err := csvRepo.Rewrite(csvPath, rows); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+
+	return nil
+}
+
+// csvExportRows returns a month's invoices the way ExportToCSV writes them.
+func (r *Repository) csvExportRows(jahr, monat string) ([]core.CSVRow, error) {
 	// Get all invoices for this month from database
 	rows, err := r.List(jahr, monat)
 	if err != nil {
-		return fmt.Errorf("failed to list invoices from database: %w", err)
+		return nil, fmt.Errorf("failed to list invoices from database: %w", err)
 	}
 
 	// Stamp documentation columns BEFORE EUR normalisation so the original
@@ -32,14 +47,7 @@ func (r *Repository) ExportToCSV(jahr, monat, csvPath string, csvRepo *core.CSVR
 
 	// Normalise all money fields to EUR (foreign rows divided by Wechselkurs).
 	// EUR rows and rows with missing rates are returned unchanged.
-	rows = core.RowsEUR(rows)
-
-	// Rewrite the CSV file with all rows
-	if err := csvRepo.Rewrite(csvPath, rows); err != nil {
-		return fmt.Errorf("failed to write CSV file: %w", err)
-	}
-
-	return nil
+	return core.RowsEUR(rows), nil
 }
 
 // ImportFromCSV imports invoices from a CSV file into the database.
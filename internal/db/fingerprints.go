@@ -0,0 +1,134 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// SetFingerprints replaces the fingerprints of an invoice's archived files.
+func (r *Repository) SetFingerprints(jahr, monat, dateiname string, fps []core.Fingerprint) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("fingerprints: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM fingerprints WHERE jahr = ? AND monat = ? AND dateiname = ?`,
+		jahr, monat, dateiname); err != nil {
+		return fmt.Errorf("fingerprints delete: %w", err)
+	}
+	for _, fp := range fps {
+		if _, err := tx.Exec(
+			`INSERT OR REPLACE INTO fingerprints (jahr, monat, dateiname, anhang, sha256, bild, zahlen)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			jahr, monat, dateiname, fp.Anhang, fp.SHA256, fp.Bild, fp.Zahlen,
+		); err != nil {
+			return fmt.Errorf("fingerprints insert: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("fingerprints: %w", err)
+	}
+	// Anchor the file hashes in the audit chain, so a replaced archive file
+	// can be told from the one that was recorded.
+	if len(fps) > 0 {
+		if err := r.LogAudit(core.AuditEntry{
+			Aktion:     core.AuditActionFingerprint,
+			Entitaet:   "invoice",
+			Schluessel: jahr + "-" + monat + " " + dateiname,
+			Details:    core.AuditFingerprintDetails(fps),
+		}); err != nil {
+			log.Printf("[WARN] audit_log fingerprint failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// Fingerprints returns the stored fingerprints of one invoice, main file
+// first.
+func (r *Repository) Fingerprints(jahr, monat, dateiname string) ([]core.Fingerprint, error) {
+	rows, err := r.db.Query(
+		`SELECT anhang, sha256, bild, zahlen FROM fingerprints
+		 WHERE jahr = ? AND monat = ? AND dateiname = ? ORDER BY anhang`,
+		jahr, monat, dateiname,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprints query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []core.Fingerprint
+	for rows.Next() {
+		var fp core.Fingerprint
+		if err := rows.Scan(&fp.Anhang, &fp.SHA256, &fp.Bild, &fp.Zahlen); err != nil {
+			return nil, fmt.Errorf("fingerprints scan: %w", err)
+		}
+		out = append(out, fp)
+	}
+	return out, rows.Err()
+}
+
+// FindSameContent returns the archived files whose content matches fp:
+// byte-identical ones first, then near-duplicates (core.SameContent).
+func (r *Repository) FindSameContent(fp core.Fingerprint) ([]core.FingerprintMatch, error) {
+	rows, err := r.db.Query(`
+		SELECT f.jahr, f.monat, f.dateiname, COALESCE(i.belegnummer, ''), f.anhang,
+		       f.sha256, COALESCE(f.bild, ''), COALESCE(f.zahlen, '')
+		FROM fingerprints f
+		JOIN invoices i ON i.jahr = f.jahr AND i.monat = f.monat AND i.dateiname = f.dateiname
+		ORDER BY f.jahr, f.monat, f.dateiname, f.anhang`)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprints query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var exact, near []core.FingerprintMatch
+	for rows.Next() {
+		var m core.FingerprintMatch
+		var other core.Fingerprint
+		if err := rows.Scan(&m.Jahr, &m.Monat, &m.Dateiname, &m.Belegnummer, &m.Anhang,
+			&other.SHA256, &other.Bild, &other.Zahlen); err != nil {
+			return nil, fmt.Errorf("fingerprints scan: %w", err)
+		}
+		same, isExact := core.SameContent(fp, other)
+		if !same {
+			continue
+		}
+		m.Exact = isExact
+		if isExact {
+			exact = append(exact, m)
+		} else {
+			near = append(near, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fingerprints iterate: %w", err)
+	}
+	return append(exact, near...), nil
+}
+
+// InvoicesWithoutFingerprints lists the invoices whose main file has not
+// been fingerprinted yet (archived before fingerprints existed).
+func (r *Repository) InvoicesWithoutFingerprints() ([]core.CSVRow, error) {
+	rows, err := r.db.Query(`
+		SELECT
+			dateiname, rechnungsdatum, jahr, monat,
+			auftraggeber, verwendungszweck, rechnungsnummer,
+			betrag_netto, steuersatz_prozent, steuersatz_betrag, bruttobetrag,
+			waehrung, gegenkonto, bankkonto, bezahldatum, teilzahlung,
+			kommentar, bewirtung_anlass, bewirtung_teilnehmer,
+			betrag_netto_eur, gebuehr, rabatt, hat_anhaenge, ustidnr,
+			trinkgeld, steuerzeilen, buchung, exportiert,
+			wechselkurs, gebuehr_prozent, buchung_ref, belegnummer, ausgangsrechnung,
+			bewirtung_auf_beleg, storno_zu
+		FROM invoices i
+		WHERE NOT EXISTS (
+			SELECT 1 FROM fingerprints f
+			WHERE f.jahr = i.jahr AND f.monat = i.monat AND f.dateiname = i.dateiname
+		)
+		ORDER BY jahr, monat, dateiname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invoices: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return scanInvoiceRows(rows)
+}
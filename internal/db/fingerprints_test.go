@@ -0,0 +1,83 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+func TestFingerprints_FindFollowDelete(t *testing.T) {
+	repo := newTestRepo(t)
+	row := sampleRow("2026", "03", "2026-03-01-Hetzner.pdf")
+	row.Belegnummer = "2026-0007"
+	if _, err := repo.Insert(row); err != nil {
+		t.Fatal(err)
+	}
+	if missing, _ := repo.InvoicesWithoutFingerprints(); len(missing) != 1 {
+		t.Fatalf("missing = %d, want 1", len(missing))
+	}
+	numbers := core.NumberProfile("Rechnung 4711 vom 01.03.2026 über 119,00 EUR, Kunde 40711")
+	err := repo.SetFingerprints("2026", "03", row.Dateiname, []core.Fingerprint{
+		{SHA256: "aa", Zahlen: numbers},
+		{Anhang: 1, SHA256: "bb"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing, _ := repo.InvoicesWithoutFingerprints(); len(missing) != 0 {
+		t.Errorf("missing after Set = %d", len(missing))
+	}
+
+	got, err := repo.FindSameContent(core.Fingerprint{SHA256: "bb"})
+	if err != nil || len(got) != 1 || !got[0].Exact || got[0].Label() != "2026-0007, Anhang 1" {
+		t.Fatalf("exact = %+v, %v", got, err)
+	}
+	got, _ = repo.FindSameContent(core.Fingerprint{SHA256: "cc", Zahlen: numbers})
+	if len(got) != 1 || got[0].Exact || got[0].Anhang != 0 {
+		t.Errorf("near = %+v", got)
+	}
+
+	// A rename moves the fingerprints along; deleting removes them.
+	moved := row
+	moved.Monat, moved.Dateiname = "04", "2026-03-01-Hetzner-Online.pdf"
+	if err := repo.Update("2026", "03", row.Dateiname, moved); err != nil {
+		t.Fatal(err)
+	}
+	if fps, _ := repo.Fingerprints("2026", "04", moved.Dateiname); len(fps) != 2 || fps[1].SHA256 != "bb" {
+		t.Errorf("after move = %+v", fps)
+	}
+	if err := repo.Delete("2026", "04", moved.Dateiname); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.FindSameContent(core.Fingerprint{SHA256: "aa"}); len(got) != 0 {
+		t.Errorf("after delete = %+v", got)
+	}
+}
+
+func TestArchiveMonths(t *testing.T) {
+	repo := newTestRepo(t)
+	usd := sampleRow("2026", "03", "usd.pdf")
+	usd.Waehrung, usd.Wechselkurs, usd.Bruttobetrag = "USD", 2, 200
+	for _, row := range []core.CSVRow{usd, sampleRow("2026", "01", "jan.pdf")} {
+		if _, err := repo.Insert(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.SetFingerprints("2026", "03", "usd.pdf", []core.Fingerprint{{SHA256: "aa"}}); err != nil {
+		t.Fatal(err)
+	}
+	months, err := repo.ArchiveMonths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(months) != 2 || months[0].Monat != "01" || months[1].Monat != "03" {
+		t.Fatalf("months = %+v", months)
+	}
+	mar := months[1]
+	if len(mar.Rows) != 1 || mar.Rows[0].Originalwaehrung != "USD" || mar.Rows[0].Bruttobetrag != 100 {
+		t.Errorf("rows not as exported: %+v", mar.Rows)
+	}
+	if fps := mar.Fingerprints["usd.pdf"]; len(fps) != 1 || fps[0].SHA256 != "aa" {
+		t.Errorf("fingerprints = %+v", mar.Fingerprints)
+	}
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// ArchiveMonths returns every booked month with its invoices as
+// ExportToCSV writes them and their stored fingerprints, the database side
+// of core.StorageManager.CheckArchive.
+func (r *Repository) ArchiveMonths() ([]core.ArchiveMonth, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT jahr, monat FROM invoices ORDER BY jahr, monat`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query months: %w", err)
+	}
+	var months []core.ArchiveMonth
+	for rows.Next() {
+		var m core.ArchiveMonth
+		if err := rows.Scan(&m.Jahr, &m.Monat); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan month: %w", err)
+		}
+		months = append(months, m)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating months: %w", err)
+	}
+
+	fps, err := r.allFingerprints()
+	if err != nil {
+		return nil, err
+	}
+	for i := range months {
+		m := &months[i]
+		if m.Rows, err = r.csvExportRows(m.Jahr, m.Monat); err != nil {
+			return nil, err
+		}
+		m.Fingerprints = fps[m.Jahr+"-"+m.Monat]
+	}
+	return months, nil
+}
+
+// allFingerprints returns the stored fingerprints keyed by "jahr-monat",
+// then Dateiname, main file first.
+func (r *Repository) allFingerprints() (map[string]map[string][]core.Fingerprint, error) {
+	rows, err := r.db.Query(
+		`SELECT jahr, monat, dateiname, anhang, sha256, bild, zahlen FROM fingerprints
+		 ORDER BY jahr, monat, dateiname, anhang`)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprints query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	out := map[string]map[string][]core.Fingerprint{}
+	for rows.Next() {
+		var jahr, monat, name string
+		var fp core.Fingerprint
+		if err := rows.Scan(&jahr, &monat, &name, &fp.Anhang, &fp.SHA256, &fp.Bild, &fp.Zahlen); err != nil {
+			return nil, fmt.Errorf("fingerprints scan: %w", err)
+		}
+		key := jahr + "-" + monat
+		if out[key] == nil {
+			out[key] = map[string][]core.Fingerprint{}
+		}
+		out[key][name] = append(out[key][name], fp)
+	}
+	return out, rows.Err()
+}
@@ -20,7 +20,7 @@ func round2(v float64) float64 {
 // This is a destructive operation - all invoice data will be lost!
 func (r *Repository) WipeDatabase() error {
 	// Drop all tables
-	_, err := r.db.Exec(`DROP TABLE IF EXISTS invoices`)
+	_, err := r.db.Exec(`DROP TABLE IF EXISTS invoices; DROP TABLE IF EXISTS fingerprints; DROP TABLE IF EXISTS umbuchungen; DROP TABLE IF EXISTS eroeffnungswerte`)
 	if err != nil {
 		return fmt.Errorf("failed to drop tables: %w", err)
 	}
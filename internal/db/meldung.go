@@ -0,0 +1,101 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// SaveMeldung stores the snapshot of a filed return with the next Nr of its
+// Art and Zeitraum (1 for the Erstmeldung). The row can neither be updated
+// nor deleted afterwards. Returns the snapshot with ID, Nr and GemeldetAm
+// set.
+func (r *Repository) SaveMeldung(m core.Meldung) (core.Meldung, error) {
+	var daten any = m.UStVA
+	if m.Art == core.MeldungZM {
+		daten = m.ZM
+	} else if m.Art != core.MeldungUStVA {
+		return m, fmt.Errorf("unbekannte Meldungsart %q", m.Art)
+	}
+	datenJSON, err := json.Marshal(daten)
+	if err != nil {
+		return m, fmt.Errorf("failed to encode meldung: %w", err)
+	}
+	belege := m.Belege
+	if belege == nil {
+		belege = []core.MeldungBeleg{}
+	}
+	belegeJSON, err := json.Marshal(belege)
+	if err != nil {
+		return m, fmt.Errorf("failed to encode meldung belege: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return m, fmt.Errorf("failed to begin meldung: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	var max sql.NullInt64
+	if err := tx.QueryRow(`SELECT MAX(nr) FROM meldungen WHERE art = ? AND zeitraum = ?`, m.Art, m.Zeitraum).Scan(&max); err != nil {
+		return m, fmt.Errorf("failed to read meldung nr: %w", err)
+	}
+	m.Nr = int(max.Int64) + 1
+	res, err := tx.Exec(`INSERT INTO meldungen (art, zeitraum, nr, daten, belege) VALUES (?, ?, ?, ?, ?)`,
+		m.Art, m.Zeitraum, m.Nr, string(datenJSON), string(belegeJSON))
+	if err != nil {
+		return m, fmt.Errorf("failed to insert meldung: %w", err)
+	}
+	if m.ID, err = res.LastInsertId(); err != nil {
+		return m, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	if err := tx.QueryRow(`SELECT gemeldet_am FROM meldungen WHERE id = ?`, m.ID).Scan(&m.GemeldetAm); err != nil {
+		return m, fmt.Errorf("failed to read meldung: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return m, fmt.Errorf("failed to commit meldung: %w", err)
+	}
+	if auditErr := r.LogAudit(core.AuditEntry{
+		Aktion:     "meldung",
+		Entitaet:   "meldung",
+		Schluessel: m.Art + " " + m.Zeitraum,
+		Details:    fmt.Sprintf(`{"nr":%d,"daten":%s}`, m.Nr, datenJSON),
+	}); auditErr != nil {
+		log.Printf("[WARN] audit_log meldung failed: %v", auditErr)
+	}
+	return m, nil
+}
+
+// Meldungen returns the snapshots of art whose Zeitraum starts with jahr,
+// ordered by Zeitraum and Nr.
+func (r *Repository) Meldungen(art, jahr string) ([]core.Meldung, error) {
+	rows, err := r.db.Query(`
+		SELECT id, zeitraum, nr, daten, belege, gemeldet_am FROM meldungen
+		WHERE art = ? AND zeitraum LIKE ? ORDER BY zeitraum, nr`, art, jahr+"-%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list meldungen: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []core.Meldung
+	for rows.Next() {
+		m := core.Meldung{Art: art}
+		var daten, belege string
+		if err := rows.Scan(&m.ID, &m.Zeitraum, &m.Nr, &daten, &belege, &m.GemeldetAm); err != nil {
+			return nil, fmt.Errorf("failed to scan meldung: %w", err)
+		}
+		target := any(&m.UStVA)
+		if art == core.MeldungZM {
+			target = &m.ZM
+		}
+		if err := json.Unmarshal([]byte(daten), target); err != nil {
+			return nil, fmt.Errorf("meldung %s %s/%d: %w", art, m.Zeitraum, m.Nr, err)
+		}
+		if err := json.Unmarshal([]byte(belege), &m.Belege); err != nil {
+			return nil, fmt.Errorf("meldung %s %s/%d: %w", art, m.Zeitraum, m.Nr, err)
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
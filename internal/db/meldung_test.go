@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+func TestMeldung_SaveAndImmutable(t *testing.T) {
+	repo := newTestRepo(t)
+	u := core.UStVAOfficial{Kz81: 1000, USt81: 190, Kz83: 190}
+	belege := []core.MeldungBeleg{{Schluessel: "2026-0001", Belegnummer: "2026-0001", Werte: map[string]float64{"81": 1000}}}
+	m, err := repo.SaveMeldung(core.Meldung{Art: core.MeldungUStVA, Zeitraum: "2026-Q1", UStVA: u, Belege: belege})
+	if err != nil || m.Nr != 1 || m.ID == 0 || m.GemeldetAm == "" {
+		t.Fatalf("SaveMeldung = %+v, %v", m, err)
+	}
+	u.Kz83 = 171
+	if b, err := repo.SaveMeldung(core.Meldung{Art: core.MeldungUStVA, Zeitraum: "2026-Q1", UStVA: u}); err != nil || b.Nr != 2 || !b.Berichtigung() {
+		t.Fatalf("Berichtigung = %+v, %v", b, err)
+	}
+	z := core.ZM{Zeilen: []core.ZMZeile{{UStIdNr: "ATU12345678", Netto: 500, Art: core.ZMArtSonstige}}, Kontrollsumme: 500}
+	if zm, err := repo.SaveMeldung(core.Meldung{Art: core.MeldungZM, Zeitraum: "2026-Q1", ZM: z}); err != nil || zm.Nr != 1 {
+		t.Fatalf("ZM = %+v, %v", zm, err)
+	}
+
+	ms, err := repo.Meldungen(core.MeldungUStVA, "2026")
+	if err != nil || len(ms) != 2 {
+		t.Fatalf("Meldungen = %+v, %v", ms, err)
+	}
+	if ms[0].UStVA.Kz83 != 190 || len(ms[0].Belege) != 1 || ms[0].Belege[0].Werte["81"] != 1000 {
+		t.Errorf("first snapshot = %+v", ms[0])
+	}
+	if last, ok := core.LetzteMeldung(ms, core.MeldungUStVA, "2026-Q1"); !ok || last.Nr != 2 || last.UStVA.Kz83 != 171 {
+		t.Errorf("LetzteMeldung = %+v", last)
+	}
+	if zs, _ := repo.Meldungen(core.MeldungZM, "2026"); len(zs) != 1 || zs[0].ZM.Zeilen[0].UStIdNr != "ATU12345678" {
+		t.Errorf("ZM snapshots = %+v", zs)
+	}
+	if other, _ := repo.Meldungen(core.MeldungUStVA, "2025"); len(other) != 0 {
+		t.Errorf("2025 = %+v", other)
+	}
+
+	if _, err := repo.db.Exec(`UPDATE meldungen SET daten = '{}' WHERE id = ?`, m.ID); err == nil {
+		t.Error("snapshot updated")
+	}
+	if _, err := repo.db.Exec(`DELETE FROM meldungen WHERE id = ?`, m.ID); err == nil {
+		t.Error("snapshot deleted")
+	}
+	if err := repo.WipeDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	if ms, _ := repo.Meldungen(core.MeldungUStVA, "2026"); len(ms) != 2 {
+		t.Errorf("snapshots after wipe: %d", len(ms))
+	}
+}
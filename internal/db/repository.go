@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	_ "modernc.org/sqlite" // SQLite driver
 
@@ -22,6 +24,9 @@ var ErrPeriodLocked = errors.New("Periode ist festgeschrieben")
 type Repository struct {
 	db     *sql.DB
 	dbPath string
+	// auditMu serialises LogAudit: reading the chain head and appending
+	// the next entry must not interleave.
+	auditMu sync.Mutex
 }
 
 // NewRepository creates a new database repository.
@@ -90,6 +95,9 @@ func (r *Repository) initSchema() error {
 		"ALTER TABLE invoices ADD COLUMN bewirtung_anlass TEXT DEFAULT ''",
 		"ALTER TABLE invoices ADD COLUMN bewirtung_teilnehmer TEXT DEFAULT ''",
 		"ALTER TABLE invoices ADD COLUMN bewirtung_auf_beleg INTEGER DEFAULT 0",
+		"ALTER TABLE invoices ADD COLUMN storno_zu TEXT DEFAULT ''",
+		"ALTER TABLE audit_log ADD COLUMN prev_hash TEXT DEFAULT ''",
+		"ALTER TABLE audit_log ADD COLUMN hash TEXT DEFAULT ''",
 	} {
 		if _, err := r.db.Exec(col); err != nil &&
 			!strings.Contains(err.Error(), "duplicate column name") {
@@ -104,11 +112,29 @@ func (r *Repository) initSchema() error {
 		"CREATE INDEX IF NOT EXISTS idx_invoices_belegnummer ON invoices(belegnummer)"); err != nil {
 		return fmt.Errorf("failed to create belegnummer index: %w", err)
 	}
-	return nil
+	return r.startAuditChain()
 }
 
 // Insert adds a new invoice to the database.
 func (r *Repository) Insert(row core.CSVRow) (int64, error) {
+	return r.insert(row, false)
+}
+
+// InsertRechnung adds an invoice written in BuchISY and records its
+// Rechnungsnummer as issued in the same transaction: when the number was
+// issued before or the insert fails, neither is stored, so a failed save
+// leaves no gap and a number is never taken twice. The number stays issued
+// when the invoice is deleted later.
+func (r *Repository) InsertRechnung(row core.CSVRow) (int64, error) {
+	if strings.TrimSpace(row.Rechnungsnummer) == "" {
+		return 0, fmt.Errorf("failed to issue invoice number: empty number")
+	}
+	return r.insert(row, true)
+}
+
+// insert adds row to the invoices table; with issue set, row's
+// Rechnungsnummer goes into rechnungsnummern in the same transaction.
+func (r *Repository) insert(row core.CSVRow, issue bool) (int64, error) {
 	if locked, err := r.IsPeriodLocked(row.Jahr, row.Monat); err != nil {
 		return 0, fmt.Errorf("period lock check: %w", err)
 	} else if locked {
@@ -125,7 +151,7 @@ func (r *Repository) Insert(row core.CSVRow) (int64, error) {
 			betrag_netto_eur, gebuehr, rabatt, hat_anhaenge, ustidnr,
 			trinkgeld, steuerzeilen, buchung, exportiert,
 			wechselkurs, gebuehr_prozent, buchung_ref, belegnummer, ausgangsrechnung,
-			bewirtung_auf_beleg
+			bewirtung_auf_beleg, storno_zu
 		) VALUES (
 			?, ?, ?, ?,
 			?, ?, ?,
@@ -135,11 +161,21 @@ func (r *Repository) Insert(row core.CSVRow) (int64, error) {
 			?, ?, ?, ?, ?,
 			?, ?, ?, ?,
 			?, ?, ?, ?, ?,
-			?
+			?, ?
 		)
 	`
 
-	result, err := r.db.Exec(query,
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert invoice: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if issue {
+		if _, err := tx.Exec(`INSERT INTO rechnungsnummern (rechnungsnummer) VALUES (?)`, row.Rechnungsnummer); err != nil {
+			return 0, fmt.Errorf("failed to issue invoice number %s: %w", row.Rechnungsnummer, err)
+		}
+	}
+	result, err := tx.Exec(query,
 		row.Dateiname, row.Rechnungsdatum, row.Jahr, row.Monat,
 		row.Auftraggeber, row.Verwendungszweck, row.Rechnungsnummer,
 		row.BetragNetto, row.SteuersatzProzent, row.SteuersatzBetrag, row.Bruttobetrag,
@@ -148,7 +184,7 @@ func (r *Repository) Insert(row core.CSVRow) (int64, error) {
 		row.BetragNetto_EUR, row.Gebuehr, row.Rabatt, row.HatAnhaenge, row.VATID,
 		row.Trinkgeld, core.MarshalTaxLines(row.TaxLines), core.MarshalBooking(row.Buchung), 0,
 		row.Wechselkurs, row.GebuehrProzent, row.BuchungRef, row.Belegnummer, row.Ausgangsrechnung,
-		row.BewirtungAngabenAufBeleg,
+		row.BewirtungAngabenAufBeleg, row.StornoZu,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert invoice: %w", err)
@@ -158,6 +194,9 @@ func (r *Repository) Insert(row core.CSVRow) (int64, error) {
 	if err != nil {
 		return 0, fmt.Errorf("failed to get last insert id: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to insert invoice: %w", err)
+	}
 
 	// Best-effort audit log: log a warning on failure but never fail the Insert.
 	if auditErr := r.LogAudit(core.AuditEntry{
@@ -279,6 +318,15 @@ func (r *Repository) Update(jahr, monat string, oldDateiname string, row core.CS
 		return fmt.Errorf("failed to update invoice: %w", err)
 	}
 
+	// Fingerprints follow the invoice to its new key (rename / month move).
+	if row.Jahr != jahr || row.Monat != monat || row.Dateiname != oldDateiname {
+		if _, err := r.db.Exec(`UPDATE fingerprints SET jahr = ?, monat = ?, dateiname = ?
+			WHERE jahr = ? AND monat = ? AND dateiname = ?`,
+			row.Jahr, row.Monat, row.Dateiname, jahr, monat, oldDateiname); err != nil {
+			log.Printf("[WARN] fingerprint re-key failed: %v", err)
+		}
+	}
+
 	// Best-effort audit log.
 	diff := "{}"
 	if hasOld {
@@ -326,6 +374,31 @@ func (r *Repository) NextBelegnummer(jahr string) (string, error) {
 	return fmt.Sprintf("%s-%04d", jahr, n+1), nil
 }
 
+// AusgangsRechnungsnummern returns the invoice numbers starting with prefix
+// of all outgoing invoices and of every number issued by InsertRechnung,
+// the input for core.NextRechnungsnummer. A number stays taken after its
+// invoice was deleted: it may already have gone to the customer.
+func (r *Repository) AusgangsRechnungsnummern(prefix string) ([]string, error) {
+	rows, err := r.db.Query(
+		`SELECT rechnungsnummer FROM invoices WHERE ausgangsrechnung = 1 AND substr(rechnungsnummer, 1, ?) = ?
+		 UNION SELECT rechnungsnummer FROM rechnungsnummern WHERE substr(rechnungsnummer, 1, ?) = ?`,
+		len(prefix), prefix, len(prefix), prefix,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outgoing invoice numbers: %w", err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var nr sql.NullString
+		if err := rows.Scan(&nr); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice number: %w", err)
+		}
+		out = append(out, nr.String)
+	}
+	return out, rows.Err()
+}
+
 // RenumberBelegnummern reassigns every invoice's Belegnummer per year, in
 // chronological order (by Rechnungsdatum, ties broken by id), gap-free as
 // "YYYY-NNNN". Backfills empty numbers AND closes gaps from deletions. Returns
@@ -383,6 +456,11 @@ func (r *Repository) Delete(jahr, monat, dateiname string) error {
 		return fmt.Errorf("invoice not found")
 	}
 
+	if _, err := r.db.Exec(`DELETE FROM fingerprints WHERE jahr = ? AND monat = ? AND dateiname = ?`,
+		jahr, monat, dateiname); err != nil {
+		log.Printf("[WARN] fingerprint delete failed: %v", err)
+	}
+
 	// Best-effort audit log.
 	if auditErr := r.LogAudit(core.AuditEntry{
 		Aktion:     "delete",
@@ -420,6 +498,7 @@ func scanInvoiceRows(rows *sql.Rows) ([]core.CSVRow, error) {
 		var bewirtungAnlass sql.NullString
 		var bewirtungTeilnehmer sql.NullString
 		var bewirtungAufBeleg sql.NullInt64
+		var stornoZu sql.NullString
 		err := rows.Scan(
 			&row.Dateiname, &row.Rechnungsdatum, &row.Jahr, &row.Monat,
 			&row.Auftraggeber, &row.Verwendungszweck, &row.Rechnungsnummer,
@@ -429,7 +508,7 @@ func scanInvoiceRows(rows *sql.Rows) ([]core.CSVRow, error) {
 			&row.BetragNetto_EUR, &row.Gebuehr, &rabatt, &row.HatAnhaenge, &row.VATID,
 			&trinkgeld, &steuerzeilen, &buchung, &exportiert,
 			&wechselkurs, &gebuehrProzent, &buchungRef, &belegnummer, &ausgangsrechnung,
-			&bewirtungAufBeleg,
+			&bewirtungAufBeleg, &stornoZu,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -445,6 +524,7 @@ func scanInvoiceRows(rows *sql.Rows) ([]core.CSVRow, error) {
 		row.BewirtungAnlass = bewirtungAnlass.String
 		row.BewirtungTeilnehmer = bewirtungTeilnehmer.String
 		row.BewirtungAngabenAufBeleg = bewirtungAufBeleg.Int64 != 0
+		row.StornoZu = stornoZu.String
 
 		row.TaxLines = core.ParseTaxLines(steuerzeilen.String)
 		if len(row.TaxLines) == 0 {
@@ -475,7 +555,7 @@ func (r *Repository) List(jahr, monat string) ([]core.CSVRow, error) {
 			betrag_netto_eur, gebuehr, rabatt, hat_anhaenge, ustidnr,
 			trinkgeld, steuerzeilen, buchung, exportiert,
 			wechselkurs, gebuehr_prozent, buchung_ref, belegnummer, ausgangsrechnung,
-			bewirtung_auf_beleg
+			bewirtung_auf_beleg, storno_zu
 		FROM invoices
 		WHERE jahr = ? AND monat = ?
 		ORDER BY rechnungsdatum DESC, dateiname ASC
@@ -511,7 +591,7 @@ func (r *Repository) SearchInvoices(query string) ([]core.CSVRow, error) {
 			betrag_netto_eur, gebuehr, rabatt, hat_anhaenge, ustidnr,
 			trinkgeld, steuerzeilen, buchung, exportiert,
 			wechselkurs, gebuehr_prozent, buchung_ref, belegnummer, ausgangsrechnung,
-			bewirtung_auf_beleg
+			bewirtung_auf_beleg, storno_zu
 		FROM invoices
 		WHERE LOWER(auftraggeber) LIKE ?
 		   OR LOWER(verwendungszweck) LIKE ?
@@ -624,7 +704,7 @@ func (r *Repository) getByKey(jahr, monat, dateiname string) (core.CSVRow, bool,
 			betrag_netto_eur, gebuehr, rabatt, hat_anhaenge, ustidnr,
 			trinkgeld, steuerzeilen, buchung, exportiert,
 			wechselkurs, gebuehr_prozent, buchung_ref, belegnummer, ausgangsrechnung,
-			bewirtung_auf_beleg
+			bewirtung_auf_beleg, storno_zu
 		FROM invoices
 		WHERE jahr = ? AND monat = ? AND dateiname = ?
 		LIMIT 1
@@ -645,23 +725,38 @@ func (r *Repository) getByKey(jahr, monat, dateiname string) (core.CSVRow, bool,
 	return results[0], true, nil
 }
 
-// LogAudit appends an entry to the audit_log table. It is best-effort: callers
-// log a warning and continue on error rather than propagating the failure.
+// LogAudit appends an entry to the audit_log table, chained to the previous
+// entry by core.AuditHash. It is best-effort: callers log a warning and
+// continue on error rather than propagating the failure.
 func (r *Repository) LogAudit(e core.AuditEntry) error {
-	_, err := r.db.Exec(
-		`INSERT INTO audit_log (aktion, entitaet, schluessel, details) VALUES (?, ?, ?, ?)`,
-		e.Aktion, e.Entitaet, e.Schluessel, e.Details,
-	)
+	r.auditMu.Lock()
+	defer r.auditMu.Unlock()
+	tx, err := r.db.Begin()
 	if err != nil {
 		return fmt.Errorf("audit_log insert: %w", err)
 	}
-	return nil
+	defer func() { _ = tx.Rollback() }()
+	var prev sql.NullString
+	if err := tx.QueryRow(`SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&prev); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("audit_log head: %w", err)
+	}
+	// Set here rather than by the column default: the hash covers it.
+	e.TS = time.Now().UTC().Format("2006-01-02 15:04:05")
+	e.PrevHash = prev.String
+	e.Hash = core.AuditHash(e.PrevHash, e)
+	if _, err := tx.Exec(
+		`INSERT INTO audit_log (ts, aktion, entitaet, schluessel, details, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.TS, e.Aktion, e.Entitaet, e.Schluessel, e.Details, e.PrevHash, e.Hash,
+	); err != nil {
+		return fmt.Errorf("audit_log insert: %w", err)
+	}
+	return tx.Commit()
 }
 
 // AuditLog returns up to limit audit entries ordered newest-first.
 func (r *Repository) AuditLog(limit int) ([]core.AuditEntry, error) {
 	rows, err := r.db.Query(
-		`SELECT ts, aktion, entitaet, schluessel, details
+		`SELECT id, ts, aktion, entitaet, schluessel, details, prev_hash, hash
 		 FROM audit_log
 		 ORDER BY ts DESC, id DESC
 		 LIMIT ?`,
@@ -671,18 +766,73 @@ func (r *Repository) AuditLog(limit int) ([]core.AuditEntry, error) {
 		return nil, fmt.Errorf("audit_log query: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
+	return scanAuditRows(rows)
+}
+
+// AuditChain returns the complete audit log in chain (id) order for
+// core.VerifyAuditChain.
+func (r *Repository) AuditChain() ([]core.AuditEntry, error) {
+	rows, err := r.db.Query(
+		`SELECT id, ts, aktion, entitaet, schluessel, details, prev_hash, hash
+		 FROM audit_log ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("audit_log query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return scanAuditRows(rows)
+}
+
+// VerifyAuditChain checks the hash chain of the whole audit log.
+func (r *Repository) VerifyAuditChain() (core.AuditChainReport, error) {
+	entries, err := r.AuditChain()
+	if err != nil {
+		return core.AuditChainReport{}, err
+	}
+	return core.VerifyAuditChain(entries), nil
+}
+
+// VerifyAuditChainAnker checks the hash chain of the whole audit log against
+// a Kettenkopf kept outside the database.
+func (r *Repository) VerifyAuditChainAnker(anker core.AuditAnker) (core.AuditChainReport, error) {
+	entries, err := r.AuditChain()
+	if err != nil {
+		return core.AuditChainReport{}, err
+	}
+	return core.VerifyAuditChainAnker(entries, anker), nil
+}
 
+// AuditAnker returns the current head of the audit log's hash chain, to be
+// stored outside the database. ok is false while the log is empty.
+func (r *Repository) AuditAnker() (anker core.AuditAnker, ok bool, err error) {
+	var ts, hash sql.NullString
+	err = r.db.QueryRow(`SELECT id, ts, hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&anker.ID, &ts, &hash)
+	if err == sql.ErrNoRows {
+		return core.AuditAnker{}, false, nil
+	}
+	if err != nil {
+		return core.AuditAnker{}, false, fmt.Errorf("audit_log head: %w", err)
+	}
+	anker.TS = ts.String
+	anker.Hash = hash.String
+	return anker, anker.Hash != "", nil
+}
+
+// scanAuditRows reads audit_log rows selected with the column list of
+// AuditLog / AuditChain.
+func scanAuditRows(rows *sql.Rows) ([]core.AuditEntry, error) {
 	var entries []core.AuditEntry
 	for rows.Next() {
 		var e core.AuditEntry
-		var ts, entitaet, schluessel, details sql.NullString
-		if err := rows.Scan(&ts, &e.Aktion, &entitaet, &schluessel, &details); err != nil {
+		var ts, entitaet, schluessel, details, prevHash, hash sql.NullString
+		if err := rows.Scan(&e.ID, &ts, &e.Aktion, &entitaet, &schluessel, &details, &prevHash, &hash); err != nil {
 			return nil, fmt.Errorf("audit_log scan: %w", err)
 		}
 		e.TS = ts.String
 		e.Entitaet = entitaet.String
 		e.Schluessel = schluessel.String
 		e.Details = details.String
+		e.PrevHash = prevHash.String
+		e.Hash = hash.String
 		entries = append(entries, e)
 	}
 	if err := rows.Err(); err != nil {
@@ -691,6 +841,47 @@ func (r *Repository) AuditLog(limit int) ([]core.AuditEntry, error) {
 	return entries, nil
 }
 
+// startAuditChain chains the audit log once, on the first start with hash
+// support: entries written before get their hashes in id order, and a
+// core.AuditActionChain entry records how many there were. A log that
+// already has hashes is left alone — a verification failure must stay
+// visible, never be "repaired" on the next start.
+func (r *Repository) startAuditChain() error {
+	var chained int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE hash <> ''`).Scan(&chained); err != nil {
+		return fmt.Errorf("audit chain: %w", err)
+	}
+	if chained > 0 {
+		return nil
+	}
+	legacy, err := r.AuditChain()
+	if err != nil {
+		return fmt.Errorf("audit chain: %w", err)
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("audit chain: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	prev := ""
+	for _, e := range legacy {
+		e.PrevHash = prev
+		e.Hash = core.AuditHash(prev, e)
+		if _, err := tx.Exec(`UPDATE audit_log SET prev_hash = ?, hash = ? WHERE id = ?`, e.PrevHash, e.Hash, e.ID); err != nil {
+			return fmt.Errorf("audit chain: %w", err)
+		}
+		prev = e.Hash
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("audit chain: %w", err)
+	}
+	return r.LogAudit(core.AuditEntry{
+		Aktion:   core.AuditActionChain,
+		Entitaet: "audit_log",
+		Details:  fmt.Sprintf(`{"vorhandene_eintraege":%d}`, len(legacy)),
+	})
+}
+
 // LockPeriod marks a filing period (jahr/monat) as locked (festgeschrieben).
 // Subsequent Insert/Update/Delete calls on this period will return ErrPeriodLocked.
 // The action is recorded in the audit log.
@@ -37,6 +37,7 @@ CREATE TABLE IF NOT EXISTS invoices (
 	buchung_ref TEXT DEFAULT '',
 	belegnummer TEXT DEFAULT '',
 	ausgangsrechnung INTEGER DEFAULT 0,
+	storno_zu TEXT DEFAULT '',
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -75,6 +76,81 @@ CREATE TABLE IF NOT EXISTS period_locks (
 	locked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 	PRIMARY KEY(jahr, monat)
 );
+
+-- Content fingerprints of the archived files of an invoice (anhang 0 = main
+-- file, n = "_Anhang<n>"); see core.Fingerprint.
+CREATE TABLE IF NOT EXISTS fingerprints (
+	jahr TEXT NOT NULL,
+	monat TEXT NOT NULL,
+	dateiname TEXT NOT NULL,
+	anhang INTEGER NOT NULL DEFAULT 0,
+	sha256 TEXT NOT NULL,
+	bild TEXT DEFAULT '',
+	zahlen TEXT DEFAULT '',
+	PRIMARY KEY(jahr, monat, dateiname, anhang)
+);
+CREATE INDEX IF NOT EXISTS idx_fingerprints_sha256 ON fingerprints(sha256);
+
+-- Manual journal entries without a receipt (core.Umbuchung), numbered
+-- "U-YYYY-NNNN" independently of the invoices.
+CREATE TABLE IF NOT EXISTS umbuchungen (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	belegnummer TEXT NOT NULL UNIQUE,
+	datum TEXT NOT NULL,
+	jahr TEXT NOT NULL,
+	monat TEXT NOT NULL,
+	text TEXT DEFAULT '',
+	buchung TEXT NOT NULL,
+	exportiert INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_umbuchungen_monat ON umbuchungen(jahr, monat);
+
+-- Opening balances (EB-Werte) per fiscal year and account, Soll positive
+-- (core.OpeningBalance). vortrag = 1: carried forward from the previous year.
+CREATE TABLE IF NOT EXISTS eroeffnungswerte (
+	jahr TEXT NOT NULL,
+	konto INTEGER NOT NULL,
+	saldo REAL NOT NULL,
+	vortrag INTEGER DEFAULT 0,
+	exportiert INTEGER DEFAULT 0,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(jahr, konto)
+);
+
+-- Filed VAT returns (core.Meldung): one immutable snapshot per filing, the
+-- return as JSON in daten and each receipt's contribution in belege. A
+-- berichtigte Meldung for the same period is a new row with the next nr.
+CREATE TABLE IF NOT EXISTS meldungen (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	art TEXT NOT NULL,
+	zeitraum TEXT NOT NULL,
+	nr INTEGER NOT NULL,
+	daten TEXT NOT NULL,
+	belege TEXT NOT NULL,
+	gemeldet_am DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(art, zeitraum, nr)
+);
+CREATE TRIGGER IF NOT EXISTS meldungen_no_update BEFORE UPDATE ON meldungen
+BEGIN
+	SELECT RAISE(ABORT, 'Meldungen sind unveränderlich');
+END;
+CREATE TRIGGER IF NOT EXISTS meldungen_no_delete BEFORE DELETE ON meldungen
+BEGIN
+	SELECT RAISE(ABORT, 'Meldungen sind unveränderlich');
+END;
+
+-- Numbers of the invoices written in BuchISY (Rechnung schreiben). A number
+-- stays here when its invoice is deleted, so it is never issued again.
+CREATE TABLE IF NOT EXISTS rechnungsnummern (
+	rechnungsnummer TEXT PRIMARY KEY,
+	vergeben_am DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TRIGGER IF NOT EXISTS rechnungsnummern_no_delete BEFORE DELETE ON rechnungsnummern
+BEGIN
+	SELECT RAISE(ABORT, 'Vergebene Rechnungsnummern bleiben vergeben');
+END;
 `
 
 // CurrentSchemaVersion is the current database schema version.
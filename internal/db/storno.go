@@ -0,0 +1,63 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// StornoFor returns the Belegnummer (or file name) of the Storno that
+// reverses the booking with the given StornoKey, or "" when there is none.
+func (r *Repository) StornoFor(key string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+	var belegnummer, dateiname sql.NullString
+	err := r.db.QueryRow(`SELECT belegnummer, dateiname FROM invoices WHERE storno_zu = ? LIMIT 1`, key).
+		Scan(&belegnummer, &dateiname)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up storno: %w", err)
+	}
+	if belegnummer.String != "" {
+		return belegnummer.String, nil
+	}
+	return dateiname.String, nil
+}
+
+// Storno files storno (built by core.BuildStorno) as the reversal of orig.
+// A booking is reversed at most once and a Storno is never reversed itself;
+// correct it with a new booking instead. The Storno's own period must be open.
+func (r *Repository) Storno(orig, storno core.CSVRow) (int64, error) {
+	if orig.IsStorno() {
+		return 0, fmt.Errorf("Beleg %s ist selbst ein Storno", orig.StornoKey())
+	}
+	if storno.StornoZu != orig.StornoKey() {
+		return 0, fmt.Errorf("Storno verweist auf %q statt auf %q", storno.StornoZu, orig.StornoKey())
+	}
+	existing, err := r.StornoFor(storno.StornoZu)
+	if err != nil {
+		return 0, err
+	}
+	if existing != "" {
+		return 0, fmt.Errorf("Beleg %s ist bereits storniert (Storno %s)", storno.StornoZu, existing)
+	}
+	id, err := r.Insert(storno)
+	if err != nil {
+		return 0, err
+	}
+	if auditErr := r.LogAudit(core.AuditEntry{
+		Aktion:     "storno",
+		Entitaet:   "invoice",
+		Schluessel: storno.Belegnummer + " " + storno.Dateiname,
+		Details: fmt.Sprintf(`{"storno_zu":%q,"periode":"%s-%s","brutto":%.2f}`,
+			storno.StornoZu, orig.Jahr, orig.Monat, orig.Bruttobetrag),
+	}); auditErr != nil {
+		log.Printf("[WARN] audit_log storno failed: %v", auditErr)
+	}
+	return id, nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+func TestStorno_LockedOriginal(t *testing.T) {
+	repo := newTestRepo(t)
+	orig := sampleRow("2026", "01", "a.pdf")
+	orig.Belegnummer = "2026-0001"
+	if _, err := repo.Insert(orig); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := repo.LockPeriod("2026", "01"); err != nil {
+		t.Fatalf("LockPeriod: %v", err)
+	}
+
+	s := core.BuildStorno(orig, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), "Doppelt erfasst")
+	s.Belegnummer = "2026-0002"
+	s.Dateiname = "Storno_2026-0001.pdf"
+	if _, err := repo.Storno(orig, s); err != nil {
+		t.Fatalf("Storno: %v", err)
+	}
+	if got, err := repo.StornoFor("2026-0001"); err != nil || got != "2026-0002" {
+		t.Errorf("StornoFor = %q, %v", got, err)
+	}
+	rows, err := repo.List("2026", "03")
+	if err != nil || len(rows) != 1 || rows[0].StornoZu != "2026-0001" || rows[0].Bruttobetrag != -119 {
+		t.Fatalf("List = %+v, %v", rows, err)
+	}
+
+	// A second reversal and a reversal of the Storno are refused.
+	again := s
+	again.Dateiname = "Storno2.pdf"
+	if _, err := repo.Storno(orig, again); err == nil || !strings.Contains(err.Error(), "bereits storniert") {
+		t.Errorf("second Storno: %v", err)
+	}
+	if _, err := repo.Storno(rows[0], core.BuildStorno(rows[0], time.Now(), "")); err == nil {
+		t.Error("Storno of a Storno accepted")
+	}
+
+	// The Storno's own period must be open.
+	if err := repo.LockPeriod("2026", "04"); err != nil {
+		t.Fatalf("LockPeriod: %v", err)
+	}
+	orig2 := sampleRow("2026", "03", "b.pdf")
+	orig2.Belegnummer = "2026-0003"
+	if _, err := repo.Storno(orig2, core.BuildStorno(orig2, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), "")); err != ErrPeriodLocked {
+		t.Errorf("Storno into a locked period: %v", err)
+	}
+
+	entries, err := repo.AuditLog(10)
+	if err != nil {
+		t.Fatalf("AuditLog: %v", err)
+	}
+	found := false
+	for _, e := range entries {
+		if e.Aktion == "storno" && strings.Contains(e.Details, `"storno_zu":"2026-0001"`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected audit entry for the storno")
+	}
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// NextUmbuchungsnummer returns the next Belegnummer of the Umbuchung range for
+// a year, "U-YYYY-NNNN". Like NextBelegnummer it is read, not reserved.
+func (r *Repository) NextUmbuchungsnummer(jahr string) (string, error) {
+	prefix := core.UmbuchungPrefix + jahr + "-"
+	var max sql.NullString
+	if err := r.db.QueryRow(
+		`SELECT MAX(belegnummer) FROM umbuchungen WHERE belegnummer LIKE ?`, prefix+"%",
+	).Scan(&max); err != nil {
+		return "", fmt.Errorf("failed to read max umbuchung number: %w", err)
+	}
+	n := 0
+	if max.Valid {
+		if v, err := strconv.Atoi(strings.TrimPrefix(max.String, prefix)); err == nil {
+			n = v
+		}
+	}
+	return fmt.Sprintf("%s%04d", prefix, n+1), nil
+}
+
+// InsertUmbuchung validates and books a manual journal entry in its (open)
+// period and assigns the next number of the Umbuchung range. Returns the
+// entry with ID and Belegnummer set.
+func (r *Repository) InsertUmbuchung(u core.Umbuchung) (core.Umbuchung, error) {
+	if err := u.Validate(); err != nil {
+		return u, err
+	}
+	jahr, monat, _ := u.Period()
+	if locked, err := r.IsPeriodLocked(jahr, monat); err != nil {
+		return u, fmt.Errorf("period lock check: %w", err)
+	} else if locked {
+		return u, ErrPeriodLocked
+	}
+	nr, err := r.NextUmbuchungsnummer(jahr)
+	if err != nil {
+		return u, err
+	}
+	u.Belegnummer = nr
+	u.Exportiert = false
+	res, err := r.db.Exec(
+		`INSERT INTO umbuchungen (belegnummer, datum, jahr, monat, text, buchung) VALUES (?, ?, ?, ?, ?, ?)`,
+		u.Belegnummer, u.Datum, jahr, monat, strings.TrimSpace(u.Text), core.MarshalBooking(u.Buchung),
+	)
+	if err != nil {
+		return u, fmt.Errorf("failed to insert umbuchung: %w", err)
+	}
+	if u.ID, err = res.LastInsertId(); err != nil {
+		return u, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	if auditErr := r.LogAudit(core.AuditEntry{
+		Aktion:     "create",
+		Entitaet:   "umbuchung",
+		Schluessel: u.Belegnummer,
+		Details:    umbuchungDetails(u),
+	}); auditErr != nil {
+		log.Printf("[WARN] audit_log create umbuchung failed: %v", auditErr)
+	}
+	return u, nil
+}
+
+// UpdateUmbuchung replaces date, text and booking of the entry with u.ID. Both
+// the old and the new period must be open; the Belegnummer is kept and the
+// export flag is reset, as for invoices.
+func (r *Repository) UpdateUmbuchung(u core.Umbuchung) error {
+	old, err := r.getUmbuchung(u.ID)
+	if err != nil {
+		return err
+	}
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	oldJahr, oldMonat, _ := old.Period()
+	jahr, monat, _ := u.Period()
+	for _, p := range [][2]string{{oldJahr, oldMonat}, {jahr, monat}} {
+		if locked, err := r.IsPeriodLocked(p[0], p[1]); err != nil {
+			return fmt.Errorf("period lock check: %w", err)
+		} else if locked {
+			return ErrPeriodLocked
+		}
+	}
+	u.Belegnummer = old.Belegnummer
+	if _, err := r.db.Exec(
+		`UPDATE umbuchungen SET datum = ?, jahr = ?, monat = ?, text = ?, buchung = ?, exportiert = 0,
+			updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		u.Datum, jahr, monat, strings.TrimSpace(u.Text), core.MarshalBooking(u.Buchung), u.ID,
+	); err != nil {
+		return fmt.Errorf("failed to update umbuchung: %w", err)
+	}
+	if auditErr := r.LogAudit(core.AuditEntry{
+		Aktion:     "update",
+		Entitaet:   "umbuchung",
+		Schluessel: u.Belegnummer,
+		Details:    fmt.Sprintf(`{"vorher":%s,"nachher":%s}`, umbuchungDetails(old), umbuchungDetails(u)),
+	}); auditErr != nil {
+		log.Printf("[WARN] audit_log update umbuchung failed: %v", auditErr)
+	}
+	return nil
+}
+
+// DeleteUmbuchung removes the entry with the given id unless its period is
+// locked.
+func (r *Repository) DeleteUmbuchung(id int64) error {
+	old, err := r.getUmbuchung(id)
+	if err != nil {
+		return err
+	}
+	jahr, monat, _ := old.Period()
+	if locked, err := r.IsPeriodLocked(jahr, monat); err != nil {
+		return fmt.Errorf("period lock check: %w", err)
+	} else if locked {
+		return ErrPeriodLocked
+	}
+	if _, err := r.db.Exec(`DELETE FROM umbuchungen WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete umbuchung: %w", err)
+	}
+	if auditErr := r.LogAudit(core.AuditEntry{
+		Aktion:     "delete",
+		Entitaet:   "umbuchung",
+		Schluessel: old.Belegnummer,
+		Details:    umbuchungDetails(old),
+	}); auditErr != nil {
+		log.Printf("[WARN] audit_log delete umbuchung failed: %v", auditErr)
+	}
+	return nil
+}
+
+// Umbuchungen returns the entries filed from period von to bis (inclusive,
+// "YYYY-MM"), ordered by date and number.
+func (r *Repository) Umbuchungen(von, bis string) ([]core.Umbuchung, error) {
+	rows, err := r.db.Query(`
+		SELECT id, belegnummer, datum, text, buchung, exportiert FROM umbuchungen
+		WHERE jahr || '-' || monat BETWEEN ? AND ?
+		ORDER BY substr(datum,7,4)||substr(datum,4,2)||substr(datum,1,2), belegnummer`, von, bis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list umbuchungen: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return scanUmbuchungen(rows)
+}
+
+// MarkUmbuchungExported flags the entry with the given Belegnummer as
+// included in a booking export.
+func (r *Repository) MarkUmbuchungExported(belegnummer string) error {
+	if _, err := r.db.Exec(`UPDATE umbuchungen SET exportiert = 1 WHERE belegnummer = ?`, belegnummer); err != nil {
+		return fmt.Errorf("failed to mark umbuchung exported: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) getUmbuchung(id int64) (core.Umbuchung, error) {
+	rows, err := r.db.Query(`SELECT id, belegnummer, datum, text, buchung, exportiert FROM umbuchungen WHERE id = ?`, id)
+	if err != nil {
+		return core.Umbuchung{}, fmt.Errorf("failed to read umbuchung: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	us, err := scanUmbuchungen(rows)
+	if err != nil {
+		return core.Umbuchung{}, err
+	}
+	if len(us) == 0 {
+		return core.Umbuchung{}, fmt.Errorf("Umbuchung %d nicht gefunden", id)
+	}
+	return us[0], nil
+}
+
+func scanUmbuchungen(rows *sql.Rows) ([]core.Umbuchung, error) {
+	var out []core.Umbuchung
+	for rows.Next() {
+		var u core.Umbuchung
+		var text, buchung sql.NullString
+		if err := rows.Scan(&u.ID, &u.Belegnummer, &u.Datum, &text, &buchung, &u.Exportiert); err != nil {
+			return nil, fmt.Errorf("failed to scan umbuchung: %w", err)
+		}
+		u.Text = text.String
+		u.Buchung = core.ParseBooking(buchung.String)
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// umbuchungDetails is the audit detail of an entry: date, text and lines.
+func umbuchungDetails(u core.Umbuchung) string {
+	buchung := core.MarshalBooking(u.Buchung)
+	if buchung == "" {
+		buchung = "{}"
+	}
+	return fmt.Sprintf(`{"datum":%q,"text":%q,"buchung":%s}`, u.Datum, strings.TrimSpace(u.Text), buchung)
+}
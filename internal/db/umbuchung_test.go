@@ -0,0 +1,104 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+func bankgebuehr(datum string) core.Umbuchung {
+	return core.Umbuchung{
+		Datum: datum,
+		Text:  "Kontoführungsgebühr",
+		Buchung: core.Booking{Entries: []core.BookingEntry{
+			{Konto: 6855, Betrag: 9.9, Soll: true},
+			{Konto: 1200, Betrag: 9.9, Soll: false},
+		}},
+	}
+}
+
+func TestUmbuchung_CRUD(t *testing.T) {
+	repo := newTestRepo(t)
+	// Receipts and Umbuchungen have separate number ranges.
+	inv := sampleRow("2026", "03", "a.pdf")
+	inv.Belegnummer = "2026-0001"
+	if _, err := repo.Insert(inv); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	a, err := repo.InsertUmbuchung(bankgebuehr("31.03.2026"))
+	if err != nil || a.Belegnummer != "U-2026-0001" || a.ID == 0 {
+		t.Fatalf("InsertUmbuchung = %+v, %v", a, err)
+	}
+	b, err := repo.InsertUmbuchung(bankgebuehr("30.04.2026"))
+	if err != nil || b.Belegnummer != "U-2026-0002" {
+		t.Fatalf("second InsertUmbuchung = %+v, %v", b, err)
+	}
+	if nr, _ := repo.NextBelegnummer("2026"); nr != "2026-0002" {
+		t.Errorf("NextBelegnummer = %s", nr)
+	}
+	unbalanced := bankgebuehr("01.04.2026")
+	unbalanced.Buchung.Entries[1].Betrag = 9
+	if _, err := repo.InsertUmbuchung(unbalanced); err == nil {
+		t.Error("unbalanced entry accepted")
+	}
+
+	b.Text = "Kontoführung April"
+	b.Buchung.Entries[0].Konto = 6856
+	if err := repo.MarkUmbuchungExported(b.Belegnummer); err != nil {
+		t.Fatalf("MarkUmbuchungExported: %v", err)
+	}
+	if err := repo.UpdateUmbuchung(b); err != nil {
+		t.Fatalf("UpdateUmbuchung: %v", err)
+	}
+	list, err := repo.Umbuchungen("2026-04", "2026-12")
+	if err != nil || len(list) != 1 || list[0].Text != "Kontoführung April" ||
+		list[0].Buchung.Entries[0].Konto != 6856 || list[0].Exportiert {
+		t.Fatalf("Umbuchungen = %+v, %v", list, err)
+	}
+
+	// A locked period protects its entries and takes no new ones.
+	if err := repo.LockPeriod("2026", "03"); err != nil {
+		t.Fatalf("LockPeriod: %v", err)
+	}
+	if _, err := repo.InsertUmbuchung(bankgebuehr("15.03.2026")); err != ErrPeriodLocked {
+		t.Errorf("insert into locked period: %v", err)
+	}
+	a.Text = "geändert"
+	if err := repo.UpdateUmbuchung(a); err != ErrPeriodLocked {
+		t.Errorf("update in locked period: %v", err)
+	}
+	b.Datum = "20.03.2026"
+	if err := repo.UpdateUmbuchung(b); err != ErrPeriodLocked {
+		t.Errorf("move into locked period: %v", err)
+	}
+	if err := repo.DeleteUmbuchung(a.ID); err != ErrPeriodLocked {
+		t.Errorf("delete in locked period: %v", err)
+	}
+	if err := repo.DeleteUmbuchung(b.ID); err != nil {
+		t.Fatalf("DeleteUmbuchung: %v", err)
+	}
+	if list, _ := repo.Umbuchungen("2026-01", "2026-12"); len(list) != 1 || list[0].Belegnummer != "U-2026-0001" {
+		t.Errorf("after delete = %+v", list)
+	}
+
+	entries, err := repo.AuditLog(20)
+	if err != nil {
+		t.Fatalf("AuditLog: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if e.Entitaet == "umbuchung" {
+			seen[e.Aktion+" "+e.Schluessel] = true
+			if e.Aktion == "update" && !strings.Contains(e.Details, `"vorher":{"datum":"30.04.2026"`) {
+				t.Errorf("update details = %s", e.Details)
+			}
+		}
+	}
+	for _, want := range []string{"create U-2026-0001", "create U-2026-0002", "update U-2026-0002", "delete U-2026-0002"} {
+		if !seen[want] {
+			t.Errorf("missing audit entry %q (have %v)", want, seen)
+		}
+	}
+}
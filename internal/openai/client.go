@@ -0,0 +1,233 @@
+// Package openai provides an extraction backend for OpenAI-compatible
+// chat-completions APIs (OpenAI itself, or self-hosted servers such as
+// Ollama, vLLM or LocalAI reachable under a configurable base URL).
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is used when no base URL is configured.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+const (
+	maxRetries     = 3
+	defaultTimeout = 120 * time.Second // self-hosted models are often slower than hosted APIs
+)
+
+// retryDelay is the backoff unit between attempts (a variable so tests can
+// shorten it).
+var retryDelay = 2 * time.Second
+
+// Client talks to the /chat/completions endpoint below BaseURL. It
+// implements anthropic.Provider.
+type Client struct {
+	BaseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for baseURL, e.g. "http://gpu-box:11434/v1".
+// An empty baseURL falls back to DefaultBaseURL.
+func NewClient(baseURL string) *Client {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}
+}
+
+// Name implements anthropic.Provider.
+func (c *Client) Name() string {
+	return "OpenAI-kompatibel"
+}
+
+// ContentPart is one part of a multimodal user message (text or image).
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL carries an image as a data: URL.
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// Message represents a chat message.
+type Message struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"` // string or []ContentPart
+}
+
+// Request represents a chat-completions request.
+type Request struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+}
+
+// Response represents a chat-completions response.
+type Response struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// ErrorResponse represents an API error response.
+type ErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Send sends a text-only request with retries and backoff.
+func (c *Client) Send(ctx context.Context, apiKey, model, systemPrompt, userMessage string) (string, error) {
+	return c.send(ctx, apiKey, model, systemPrompt, userMessage)
+}
+
+// SendWithImages sends a request with page images (base64, mediaType e.g.
+// "image/png") followed by the text of userMessage. The model must support
+// vision input.
+func (c *Client) SendWithImages(ctx context.Context, apiKey, model, systemPrompt, userMessage string, imagesBase64 []string, mediaType string) (string, error) {
+	content := make([]ContentPart, 0, len(imagesBase64)+1)
+	for _, b := range imagesBase64 {
+		content = append(content, ContentPart{
+			Type:     "image_url",
+			ImageURL: &ImageURL{URL: "data:" + mediaType + ";base64," + b},
+		})
+	}
+	content = append(content, ContentPart{Type: "text", Text: userMessage})
+	return c.send(ctx, apiKey, model, systemPrompt, content)
+}
+
+// send builds the request and runs it with retries on 429 and 5xx.
+func (c *Client) send(ctx context.Context, apiKey, model, systemPrompt string, userContent any) (string, error) {
+	req := Request{
+		Model:     model,
+		MaxTokens: 4096,
+		Messages: []Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userContent},
+		},
+		Temperature: 0,
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(retryDelay * time.Duration(attempt)):
+			}
+		}
+		resp, err := c.doRequest(ctx, apiKey, req)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+		if !shouldRetry(err) {
+			break
+		}
+	}
+	return "", fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
+}
+
+// doRequest performs a single API request.
+func (c *Client) doRequest(ctx context.Context, apiKey string, req Request) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	// Self-hosted servers usually run without a key.
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		_ = httpResp.Body.Close()
+	}()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+			return "", &APIError{
+				StatusCode: httpResp.StatusCode,
+				Type:       errResp.Error.Type,
+				Message:    errResp.Error.Message,
+			}
+		}
+		return "", &APIError{
+			StatusCode: httpResp.StatusCode,
+			Message:    string(respBody),
+		}
+	}
+
+	var resp Response
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
+		return resp.Choices[0].Message.Content, nil
+	}
+	return "", fmt.Errorf("no content in response")
+}
+
+// APIError represents an API error.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("API error (%d): %s - %s", e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// shouldRetry determines if an error is retryable.
+func shouldRetry(err error) bool {
+	if apiErr, ok := err.(*APIError); ok {
+		// Retry on rate limit (429) and server errors (5xx)
+		return apiErr.StatusCode == 429 || apiErr.StatusCode >= 500
+	}
+	return false
+}
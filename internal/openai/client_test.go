@@ -0,0 +1,148 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bergx2/buchisy/internal/anthropic"
+)
+
+// Compile-time check: the client is a drop-in extraction backend.
+var _ anthropic.Provider = (*Client)(nil)
+
+// chatServer is a stand-in chat-completions server. It records the last
+// request and answers every call with reply (or the status codes in fail,
+// consumed one per call, first).
+func chatServer(t *testing.T, reply string, fail ...int) (*httptest.Server, *Request, *http.Header, *int32) {
+	t.Helper()
+	var last Request
+	var hdr http.Header
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Path != "/v1/chat/completions" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		hdr = r.Header.Clone()
+		if err := json.NewDecoder(r.Body).Decode(&last); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if int(n) <= len(fail) {
+			w.WriteHeader(fail[n-1])
+			_, _ = w.Write([]byte(`{"error":{"type":"server_error","message":"busy"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id": "chatcmpl-1",
+			"choices": []map[string]any{
+				{"index": 0, "message": map[string]string{"role": "assistant", "content": reply}, "finish_reason": "stop"},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &last, &hdr, &calls
+}
+
+func TestClient_SendText(t *testing.T) {
+	srv, last, hdr, _ := chatServer(t, "42")
+	c := NewClient(srv.URL + "/v1/")
+	got, err := c.Send(context.Background(), "sk-test", "llama3.1", "system", "frage")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got != "42" {
+		t.Errorf("reply = %q", got)
+	}
+	if a := hdr.Get("Authorization"); a != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", a)
+	}
+	if last.Model != "llama3.1" || len(last.Messages) != 2 || last.Messages[0].Role != "system" || last.Messages[1].Content != "frage" {
+		t.Errorf("request = %+v", *last)
+	}
+}
+
+func TestClient_SendWithImagesAndNoKey(t *testing.T) {
+	srv, last, hdr, _ := chatServer(t, "{}")
+	c := NewClient(srv.URL + "/v1")
+	if _, err := c.SendWithImages(context.Background(), "", "llava", "sys", "lies", []string{"QUJD", "REVG"}, "image/png"); err != nil {
+		t.Fatalf("SendWithImages: %v", err)
+	}
+	if a := hdr.Get("Authorization"); a != "" {
+		t.Errorf("no key configured, but Authorization = %q", a)
+	}
+	parts, ok := last.Messages[1].Content.([]any)
+	if !ok || len(parts) != 3 {
+		t.Fatalf("user content = %#v, want 2 images + text", last.Messages[1].Content)
+	}
+	first := parts[0].(map[string]any)
+	if first["type"] != "image_url" || first["image_url"].(map[string]any)["url"] != "data:image/png;base64,QUJD" {
+		t.Errorf("image part = %v", first)
+	}
+	if text := parts[2].(map[string]any); text["type"] != "text" || text["text"] != "lies" {
+		t.Errorf("text part = %v", text)
+	}
+}
+
+func TestClient_RetryAndErrors(t *testing.T) {
+	old := retryDelay
+	retryDelay = time.Millisecond
+	defer func() { retryDelay = old }()
+
+	srv, _, _, calls := chatServer(t, "ok", http.StatusServiceUnavailable)
+	if got, err := NewClient(srv.URL+"/v1").Send(context.Background(), "", "m", "s", "u"); err != nil || got != "ok" {
+		t.Fatalf("retry after 503: %q, %v", got, err)
+	}
+	if *calls != 2 {
+		t.Errorf("calls = %d, want 2", *calls)
+	}
+
+	srv, _, _, calls = chatServer(t, "ok", http.StatusUnauthorized)
+	_, err := NewClient(srv.URL+"/v1").Send(context.Background(), "bad", "m", "s", "u")
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "busy") {
+		t.Errorf("401 error = %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("401 must not be retried, calls = %d", *calls)
+	}
+}
+
+func TestNewClient_DefaultBaseURL(t *testing.T) {
+	if c := NewClient("  "); c.BaseURL != DefaultBaseURL {
+		t.Errorf("BaseURL = %q", c.BaseURL)
+	}
+}
+
+// The extractor's prompts and parsing work unchanged over this backend.
+func TestExtractorOverOpenAIBackend(t *testing.T) {
+	reply := "```json\n" + `{"auftraggeber":"Hetzner Online GmbH","rechnungsnummer":"R0012","vat_id":"DE287472874",` +
+		`"steuerzeilen":[{"satz":19,"netto":10.00,"mwst":1.90}],"bruttobetrag":11.90,"waehrung":"EUR","rechnungsdatum":"03.02.2026"}` + "\n```"
+	srv, last, _, _ := chatServer(t, reply)
+
+	e := anthropic.NewExtractor(nil, false)
+	e.SetProvider(NewClient(srv.URL + "/v1"))
+	if e.ProviderName() != "OpenAI-kompatibel" {
+		t.Errorf("ProviderName = %q", e.ProviderName())
+	}
+	meta, _, err := e.Extract(context.Background(), "", "qwen2.5", "Rechnung R0012 …", "DE287472874")
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if meta.Auftraggeber != "Hetzner Online GmbH" || meta.Bruttobetrag != 11.90 || meta.VATID != "" {
+		t.Errorf("meta = %+v (own VAT-ID must be dropped)", meta)
+	}
+	if !strings.Contains(last.Messages[0].Content.(string), "JSON") {
+		t.Errorf("system prompt not passed through")
+	}
+
+	e.SetProvider(nil)
+	if e.ProviderName() != "Claude" {
+		t.Errorf("nil provider must restore Claude, got %q", e.ProviderName())
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/bergx2/buchisy/internal/db"
 	"github.com/bergx2/buchisy/internal/i18n"
 	"github.com/bergx2/buchisy/internal/logging"
+	"github.com/bergx2/buchisy/internal/openai"
 	"github.com/zalando/go-keyring"
 )
 
@@ -43,6 +44,7 @@ type App struct {
 	companyMap         *core.CompanyAccountMap
 	accountPrefs       *core.AccountPrefs
 	statementAliases   *core.StatementAliasStore
+	kunden             *core.KundenStore
 	pdfExtractor       *core.PDFTextExtractor
 	localExtractor     *core.LocalExtractor
 	anthropicExtractor *anthropic.Extractor
@@ -55,6 +57,7 @@ type App struct {
 	bookingRules       *core.BookingRules
 	bookingRulesStore  *core.BookingRulesStore
 	bookingTemplates   *core.BookingTemplateStore
+	extractTemplates   *core.ExtractionTemplateStore
 	assets             []core.Asset
 	assetsPath         string
 
@@ -83,6 +86,13 @@ type App struct {
 	batchDone       int
 	batchAutoBooked int // E20.5: files silently auto-booked in this batch
 
+	// Unpacked e-mails by main receipt path (see mailimport.go).
+	mailImports map[string]core.MailImport
+	// PDFs already checked for several receipts (see pdfsplit.go) and files
+	// already compared with the archive (see fingerprint.go).
+	splitChecked map[string]bool
+	dupChecked   map[string]bool
+
 	// UI components
 	yearSelect   *highlightedSelect
 	monthSelect  *highlightedSelect
@@ -233,6 +243,11 @@ func (a *App) startProfile(profile string) {
 		logger.Warn("Failed to load statement aliases: %v", err)
 	}
 
+	kunden := core.NewKundenStore(configDir)
+	if err := kunden.Load(); err != nil {
+		logger.Warn("Failed to load customers: %v", err)
+	}
+
 	if settings.DebugMode {
 		logger.SetLevel(logging.DEBUG)
 		logger.Debug("Debug mode enabled")
@@ -293,6 +308,7 @@ func (a *App) startProfile(profile string) {
 	a.companyMap = companyMap
 	a.accountPrefs = accountPrefs
 	a.statementAliases = statementAliases
+	a.kunden = kunden
 	a.pdfExtractor = pdfExtractor
 	a.localExtractor = localExtractor
 	a.anthropicExtractor = anthropicExtractor
@@ -311,6 +327,7 @@ func (a *App) startProfile(profile string) {
 		a.chart = chart
 	}
 	a.anthropicExtractor.SetAccountHints(a.chart.All())
+	a.applyAIProvider()
 
 	a.bookingRulesStore = core.NewBookingRulesStore(configDir, assets.BuchungsregelnJSON)
 	if rules, err := a.bookingRulesStore.Load(); err != nil {
@@ -323,6 +340,10 @@ func (a *App) startProfile(profile string) {
 	if err := a.bookingTemplates.Load(); err != nil {
 		logger.Warn("Failed to load booking templates: %v", err)
 	}
+	a.extractTemplates = core.NewExtractionTemplateStore(configDir)
+	if err := a.extractTemplates.Load(); err != nil {
+		logger.Warn("Failed to load extraction templates: %v", err)
+	}
 
 	a.assetsPath = filepath.Join(configDir, "assets.json")
 	if loaded, err := core.LoadAssets(a.assetsPath); err != nil {
@@ -348,6 +369,10 @@ func (a *App) startProfile(profile string) {
 
 	// Start watching the scan-inbox folder for new PDFs.
 	newScanWatcher(a).start()
+	// And the e-mail inbox (Maildir or .eml/.mbox folder).
+	newMailWatcher(a).start()
+	// Fingerprint receipts archived before duplicate detection existed.
+	a.backfillFingerprints()
 }
 
 // keyringAccount returns the OS-keyring account name for the active
@@ -363,6 +388,55 @@ func (a *App) hasAPIKey() bool {
 	return err == nil && val != ""
 }
 
+// openAIKeyringAccount returns the keyring account for the optional key of
+// the OpenAI-compatible backend, e.g. "Bergx2-openai".
+func (a *App) openAIKeyringAccount() string {
+	return a.profile + "-" + core.ProcessingModeOpenAI
+}
+
+// aiKeyringAccount returns the keyring account holding the API key of the
+// active AI backend.
+func (a *App) aiKeyringAccount() string {
+	if a.settings.ProcessingMode == core.ProcessingModeOpenAI {
+		return a.openAIKeyringAccount()
+	}
+	return a.keyringAccount()
+}
+
+// aiCredentials returns the API key and model of the active AI backend. The
+// OpenAI-compatible backend may run without a key (self-hosted servers).
+func (a *App) aiCredentials() (apiKey, model string, err error) {
+	apiKey, err = keyring.Get("BuchISY", a.aiKeyringAccount())
+	if err != nil {
+		if a.settings.ProcessingMode != core.ProcessingModeOpenAI {
+			return "", "", fmt.Errorf("failed to get API key: %w", err)
+		}
+		apiKey = ""
+	}
+	return apiKey, a.settings.AIModel(), nil
+}
+
+// aiReady reports whether AI extraction is configured well enough to be
+// tried: Claude needs a stored key, the OpenAI-compatible backend a model.
+func (a *App) aiReady() bool {
+	switch a.settings.ProcessingMode {
+	case core.ProcessingModeClaude:
+		return a.hasAPIKey()
+	case core.ProcessingModeOpenAI:
+		return strings.TrimSpace(a.settings.OpenAIModel) != ""
+	}
+	return false
+}
+
+// applyAIProvider points the extractor at the backend chosen in Settings.
+func (a *App) applyAIProvider() {
+	if a.settings.ProcessingMode == core.ProcessingModeOpenAI {
+		a.anthropicExtractor.SetProvider(openai.NewClient(a.settings.OpenAIBaseURL))
+		return
+	}
+	a.anthropicExtractor.SetProvider(nil)
+}
+
 // ownVATIDList parses Settings.OwnVATID into a slice of VAT-IDs.
 // The setting accepts a comma- (or newline-) separated list so that
 // users with multiple companies (e.g. Bergx2 + Boomstraat) can exclude
@@ -871,11 +945,12 @@ func (a *App) buildBelegeContent() fyne.CanvasObject {
 			}
 			return
 		}
-		// In Belege mode: enqueue ALL supported files for sequential entry.
+		// In Belege mode: enqueue ALL supported files (and the receipts
+		// of dropped e-mails) for sequential entry.
 		var paths []string
 		for _, uri := range uris {
 			path := uri.Path()
-			if core.IsSupportedFile(filepath.Base(path)) {
+			if core.IsSupportedFile(filepath.Base(path)) || core.IsMailFile(path) {
 				paths = append(paths, path)
 			}
 		}
@@ -1964,19 +2039,10 @@ func (a *App) openTargetFolder() {
 
 // showSettingsView is now in settings.go
 
-// extractPDFData extracts metadata from a PDF (safe to call from background thread).
-// Returns Meta and error. UI calls should happen in main thread.
-func (a *App) extractPDFData(ctx context.Context, path string, status func(string)) (core.Meta, error) {
-	a.logger.Debug("=== PDF EXTRACTION START ===")
-	a.logger.Debug("File: %s", path)
-	step := func(s string) {
-		if status != nil {
-			status(s)
-		}
-	}
-
-	// STEP 1: Check for XRechnung/ZUGFeRD structured data (highest priority)
-	step("E-Rechnung (XRechnung/ZUGFeRD) wird geprüft …")
+// extractEInvoice runs the structured XRechnung/ZUGFeRD extraction on a PDF
+// or a standalone e-invoice XML and pre-selects the Gegenkonto. ok is false
+// when the file carries no e-invoice or its XML cannot be parsed.
+func (a *App) extractEInvoice(path string) (core.Meta, bool) {
 	if format, ok := a.eInvoiceExtractor.DetectFormat(path); ok {
 		a.logger.Info("✓ Detected %s format - using structured data extraction", format)
 		meta, confidence, err := a.eInvoiceExtractor.Extract(path)
@@ -2009,10 +2075,29 @@ func (a *App) extractPDFData(ctx context.Context, path string, status func(strin
 			}
 
 			meta.Quelle = "E-Rechnung"
-			return meta, nil
+			return meta, true
 		}
 		a.logger.Warn("E-invoice extraction failed: %v, falling back to text extraction", err)
 	}
+	return core.Meta{}, false
+}
+
+// extractPDFData extracts metadata from a PDF (safe to call from background thread).
+// Returns Meta and error. UI calls should happen in main thread.
+func (a *App) extractPDFData(ctx context.Context, path string, status func(string)) (core.Meta, error) {
+	a.logger.Debug("=== PDF EXTRACTION START ===")
+	a.logger.Debug("File: %s", path)
+	step := func(s string) {
+		if status != nil {
+			status(s)
+		}
+	}
+
+	// STEP 1: Check for XRechnung/ZUGFeRD structured data (highest priority)
+	step("E-Rechnung (XRechnung/ZUGFeRD) wird geprüft …")
+	if meta, ok := a.extractEInvoice(path); ok {
+		return meta, nil
+	}
 
 	// STEP 2: Extract text from PDF (guarded: the primary parser can hang on
 	// some PDFs, so fall back to go-fitz after a short timeout).
@@ -2035,9 +2120,9 @@ func (a *App) extractPDFData(ctx context.Context, path string, status func(strin
 		a.logger.Warn("No text found in PDF (length: %d, might be scanned image)", len(text))
 		a.logger.Debug("Full text content: '%s'", text)
 
-		// If using Claude mode, try vision extraction
-		if a.settings.ProcessingMode == "claude" {
-			a.logger.Info("Attempting vision extraction with Claude...")
+		// If using an AI backend, try vision extraction
+		if a.settings.UsesAI() {
+			a.logger.Info("Attempting vision extraction with %s...", a.anthropicExtractor.ProviderName())
 			return a.extractPDFWithVision(ctx, path, status)
 		}
 
@@ -2048,13 +2133,14 @@ func (a *App) extractPDFData(ctx context.Context, path string, status func(strin
 	// Extract metadata based on processing mode
 	var meta core.Meta
 	var confidence float64
+	usedTemplate := false
 
-	if a.settings.ProcessingMode == "claude" {
-		// Get API key from keyring
-		apiKey, err := keyring.Get("BuchISY", a.keyringAccount())
+	if a.settings.UsesAI() {
+		apiKey, model, err := a.aiCredentials()
 		if err != nil {
-			return core.Meta{}, fmt.Errorf("failed to get API key: %w", err)
+			return core.Meta{}, err
 		}
+		provider := a.anthropicExtractor.ProviderName()
 
 		// Multimodal: send the extracted text together with the rendered page
 		// images, so receipts whose tables are images (POS / SumUp / restaurant
@@ -2065,15 +2151,15 @@ func (a *App) extractPDFData(ctx context.Context, path string, status func(strin
 		})
 		if imgErr != nil || len(images) == 0 {
 			a.logger.Warn("Page rendering for multimodal extraction failed (%v); using text only", imgErr)
-			step("An Claude senden — Belegdaten werden erkannt …")
-			meta, confidence, err = a.anthropicExtractor.Extract(ctx, apiKey, a.settings.AnthropicModel, text, a.ownVATIDList()...)
+			step(fmt.Sprintf("An %s senden — Belegdaten werden erkannt …", provider))
+			meta, confidence, err = a.anthropicExtractor.Extract(ctx, apiKey, model, text, a.ownVATIDList()...)
 		} else {
 			a.logger.Info("Multimodal extraction: text + %d page image(s)", len(images))
-			step(fmt.Sprintf("An Claude senden (Text + %d Seite(n)) — Belegdaten werden erkannt …", len(images)))
-			meta, confidence, err = a.anthropicExtractor.ExtractMultimodal(ctx, apiKey, a.settings.AnthropicModel, text, images, mediaType, a.ownVATIDList()...)
+			step(fmt.Sprintf("An %s senden (Text + %d Seite(n)) — Belegdaten werden erkannt …", provider, len(images)))
+			meta, confidence, err = a.anthropicExtractor.ExtractMultimodal(ctx, apiKey, model, text, images, mediaType, a.ownVATIDList()...)
 		}
 		if err != nil {
-			return core.Meta{}, fmt.Errorf("claude extraction failed: %w", err)
+			return core.Meta{}, fmt.Errorf("%s extraction failed: %w", provider, err)
 		}
 	} else {
 		step("Lokale Analyse (Mustererkennung) …")
@@ -2081,6 +2167,13 @@ func (a *App) extractPDFData(ctx context.Context, path string, status func(strin
 		if err != nil {
 			return core.Meta{}, fmt.Errorf("local extraction failed: %w", err)
 		}
+		// A template learned from earlier corrections of this supplier wins
+		// over the generic heuristics for every field it can read.
+		if tpl, byVATID, ok := a.extractTemplates.Match(text); ok {
+			a.logger.Info("Applying extraction template of %s", tpl.Auftraggeber)
+			meta = core.ApplyExtractionTemplate(tpl, byVATID, text, meta)
+			usedTemplate = true
+		}
 	}
 
 	a.logger.Info("Extracted metadata with confidence %.2f", confidence)
@@ -2110,15 +2203,32 @@ func (a *App) extractPDFData(ctx context.Context, path string, status func(strin
 		meta.Gegenkonto = a.settings.DefaultAccount
 	}
 
-	if a.settings.ProcessingMode == "claude" {
-		meta.Quelle = "Claude (Text)"
+	if a.settings.UsesAI() {
+		meta.Quelle = a.anthropicExtractor.ProviderName() + " (Text)"
+	} else if usedTemplate {
+		meta.Quelle = "Lokal (Vorlage)"
 	} else {
 		meta.Quelle = "Lokal"
 	}
+	meta.Textebene = text
 	return meta, nil
 }
 
-// extractPDFWithVision extracts metadata from a PDF using Claude's vision API.
+// learnExtractionTemplate updates the supplier's extraction template from
+// a saved receipt (text layer plus the values as confirmed by the user).
+func (a *App) learnExtractionTemplate(text string, m core.Meta) {
+	learned, err := a.extractTemplates.Learn(text, m)
+	if err != nil {
+		a.logger.Warn("Failed to save extraction template: %v", err)
+		return
+	}
+	if learned {
+		a.logger.Info("Extraction template of %s updated", m.Auftraggeber)
+	}
+}
+
+// extractPDFWithVision extracts metadata from a PDF using the vision input
+// of the active AI backend.
 func (a *App) extractPDFWithVision(ctx context.Context, path string, status func(string)) (core.Meta, error) {
 	a.logger.Info("=== PDF VISION EXTRACTION START ===")
 	a.logger.Info("File: %s", path)
@@ -2135,26 +2245,25 @@ func (a *App) extractPDFWithVision(ctx context.Context, path string, status func
 
 	a.logger.Info("PDF page rendered to %s, base64 size: %d bytes", mediaType, len(imageBase64))
 
-	// Get API key from keyring
-	apiKey, err := keyring.Get("BuchISY", a.keyringAccount())
+	apiKey, model, err := a.aiCredentials()
 	if err != nil {
-		return core.Meta{}, fmt.Errorf("failed to get API key: %w", err)
+		return core.Meta{}, err
 	}
 
 	// Extract using vision API
 	if status != nil {
-		status("Vision-Analyse mit Claude — Belegdaten werden erkannt …")
+		status(fmt.Sprintf("Vision-Analyse mit %s — Belegdaten werden erkannt …", a.anthropicExtractor.ProviderName()))
 	}
 	meta, confidence, err := a.anthropicExtractor.ExtractFromImage(
 		ctx,
 		apiKey,
-		a.settings.AnthropicModel,
+		model,
 		imageBase64,
 		mediaType,
 		a.ownVATIDList()...,
 	)
 	if err != nil {
-		return core.Meta{}, fmt.Errorf("claude vision extraction failed: %w", err)
+		return core.Meta{}, fmt.Errorf("vision extraction failed: %w", err)
 	}
 
 	a.logger.Info("Vision extraction succeeded with confidence %.2f", confidence)
@@ -2187,7 +2296,7 @@ func (a *App) extractPDFWithVision(ctx context.Context, path string, status func
 }
 
 // extractImageData runs an image file (jpg, png, gif, webp) through the
-// Claude Vision API to pre-fill the invoice form, same JSON contract as
+// vision input of the active AI backend to pre-fill the invoice form, same JSON contract as
 // the PDF path. Safe to call from a background goroutine.
 func (a *App) extractImageData(ctx context.Context, path string, status func(string)) (core.Meta, error) {
 	mediaType := core.ImageMediaType(path)
@@ -2195,7 +2304,7 @@ func (a *App) extractImageData(ctx context.Context, path string, status func(str
 		return core.Meta{}, fmt.Errorf("unsupported image type: %s", filepath.Ext(path))
 	}
 	if status != nil {
-		status("Vision-Analyse mit Claude — Belegdaten werden erkannt …")
+		status(fmt.Sprintf("Vision-Analyse mit %s — Belegdaten werden erkannt …", a.anthropicExtractor.ProviderName()))
 	}
 
 	a.logger.Info("=== IMAGE VISION EXTRACTION START === file=%s mediaType=%s",
@@ -2207,13 +2316,13 @@ func (a *App) extractImageData(ctx context.Context, path string, status func(str
 	}
 	imageBase64 := base64.StdEncoding.EncodeToString(data)
 
-	apiKey, err := keyring.Get("BuchISY", a.keyringAccount())
+	apiKey, model, err := a.aiCredentials()
 	if err != nil {
-		return core.Meta{}, fmt.Errorf("failed to get API key: %w", err)
+		return core.Meta{}, err
 	}
 
 	meta, confidence, err := a.anthropicExtractor.ExtractFromImage(
-		ctx, apiKey, a.settings.AnthropicModel, imageBase64, mediaType, a.ownVATIDList()...,
+		ctx, apiKey, model, imageBase64, mediaType, a.ownVATIDList()...,
 	)
 	if err != nil {
 		return core.Meta{}, fmt.Errorf("image vision extraction failed: %w", err)
@@ -1,6 +1,10 @@
 package ui
 
 import (
+	"os"
+	"strings"
+	"time"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
@@ -36,6 +40,12 @@ func (a *App) showAuditLog() {
 			return a.bundle.T("audit.lock")
 		case "unlock":
 			return a.bundle.T("audit.unlock")
+		case "meldung":
+			return a.bundle.T("audit.meldung")
+		case core.AuditActionFingerprint:
+			return a.bundle.T("audit.fingerprint")
+		case core.AuditActionChain:
+			return a.bundle.T("audit.chain")
 		default:
 			return aktion
 		}
@@ -106,9 +116,12 @@ func (a *App) showAuditLog() {
 	tbl.SetColumnWidth(2, wBeleg)
 	tbl.SetColumnWidth(3, wDetails)
 
+	verifyBtn := widget.NewButton(a.bundle.T("audit.verify"), a.showAuditChainReport)
+	ankerBtn := widget.NewButton(a.bundle.T("audit.verify.anker"), a.showAuditChainAnker)
 	content := container.NewBorder(
 		container.NewVBox(makeHeader(), widget.NewSeparator()),
-		nil, nil, nil,
+		container.NewHBox(verifyBtn, ankerBtn),
+		nil, nil,
 		container.NewScroll(tbl),
 	)
 
@@ -122,6 +135,63 @@ func (a *App) showAuditLog() {
 	d.Show()
 }
 
+// showAuditChainReport verifies the audit log's hash chain and shows the
+// result, with the report as PDF on request.
+func (a *App) showAuditChainReport() {
+	rep, err := a.dbRepo.VerifyAuditChain()
+	if err != nil {
+		a.showError(a.bundle.T("audit.verify"), err.Error())
+		return
+	}
+	a.showAuditChainResult(rep)
+}
+
+// showAuditChainAnker verifies the hash chain against the Kettenkopf of a
+// GoBD export (the ZIP or its core.AuditAnkerDatei), which catches removed
+// newest entries and a recomputed chain.
+func (a *App) showAuditChainAnker() {
+	a.showFilePickerFor(pickerBeleg, func(path string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			a.showError(a.bundle.T("audit.verify.anker"), err.Error())
+			return
+		}
+		anker, err := core.ReadAuditAnker(data)
+		if err != nil {
+			a.showError(a.bundle.T("audit.verify.anker"), err.Error())
+			return
+		}
+		rep, err := a.dbRepo.VerifyAuditChainAnker(anker)
+		if err != nil {
+			a.showError(a.bundle.T("audit.verify.anker"), err.Error())
+			return
+		}
+		a.showAuditChainResult(rep)
+	})
+}
+
+// showAuditChainResult shows a verification report, with the report as PDF
+// on request.
+func (a *App) showAuditChainResult(rep core.AuditChainReport) {
+	text := widget.NewLabel(strings.Join(rep.Describe(), "\n"))
+	text.Wrapping = fyne.TextWrapWord
+	pdfBtn := widget.NewButton(a.bundle.T("report.pdf"), func() {
+		data, err := core.BuildAuditChainPDF(rep, a.bundle.T("audit.verify.title"), a.profile)
+		if err != nil {
+			a.showError(a.bundle.T("error.processing.title"), err.Error())
+			return
+		}
+		a.savePDF("Pruefbericht_Aenderungsprotokoll_"+time.Now().Format("2006-01-02")+".pdf", data)
+	})
+	d := dialog.NewCustom(a.bundle.T("audit.verify.title"), a.bundle.T("common.close"),
+		container.NewBorder(nil, container.NewHBox(pdfBtn), nil, nil, text), a.window)
+	d.Resize(fyne.NewSize(620, 320))
+	d.Show()
+	if !rep.OK() {
+		a.logger.Warn("Audit chain broken at entry %d: %s", rep.Bruch.Entry.ID, rep.Bruch.Grund)
+	}
+}
+
 // auditRowLayout is a fixed-column layout for the audit header row.
 type auditRowLayout struct {
 	wTime, wAction, wBeleg, wDetails float32
@@ -22,7 +22,7 @@ func (a *App) showBackup() {
 	}
 	files := map[string]string{}
 	files["invoices.db"] = db.GetGlobalDBPath(configDir)
-	for _, name := range []string{"settings.json", "chart_skr04.json", "buchungsregeln.json", "booking_templates.json", "company_accounts.json"} {
+	for _, name := range []string{"settings.json", "chart_skr04.json", "buchungsregeln.json", "booking_templates.json", "extraction_templates.json", "mail_imported.json", "company_accounts.json"} {
 		files["config/"+name] = filepath.Join(configDir, name)
 	}
 	// All invoices.csv under the storage root, keyed by their relative path.
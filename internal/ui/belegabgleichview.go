@@ -11,7 +11,6 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
-	"github.com/zalando/go-keyring"
 
 	"github.com/bergx2/buchisy/internal/core"
 )
@@ -305,11 +304,12 @@ func (a *App) showBelegabgleich() {
 	// For each suggestion with ≥2 candidates whose top-two scores are within
 	// 0.3 of each other, ask Claude which statement line best matches the
 	// supplier and reorder candidates so Claude's pick is first.
-	// Only runs when processing mode is "claude" and an API key is available.
+	// Only runs when an AI backend is configured (Claude with a key, or the
+	// OpenAI-compatible backend with a model).
 	// Errors are non-fatal: we just log and keep the heuristic order.
-	if a.settings.ProcessingMode == "claude" {
-		apiKey, keyErr := keyring.Get("BuchISY", a.keyringAccount())
-		if keyErr == nil && apiKey != "" {
+	if a.aiReady() {
+		apiKey, model, keyErr := a.aiCredentials()
+		if keyErr == nil {
 			for i := range suggestions {
 				sug := &suggestions[i]
 				if len(sug.candidates) < 2 {
@@ -325,7 +325,7 @@ func (a *App) showBelegabgleich() {
 				idx, rankErr := a.anthropicExtractor.RankStatementLine(
 					context.Background(),
 					apiKey,
-					a.settings.AnthropicModel,
+					model,
 					sug.row.Auftraggeber,
 					lineTexts,
 				)
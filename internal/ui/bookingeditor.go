@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -19,6 +20,7 @@ type bookingEditRow struct {
 	Konto  int
 	Betrag float64
 	Soll   bool
+	Label  string // e-invoice position the row was split from (display only)
 }
 
 // bookingFromRows builds a manual Booking from editor rows. Rows without an
@@ -34,6 +36,55 @@ func bookingFromRows(rows []bookingEditRow) core.Booking {
 	return core.Booking{Manuell: true, Entries: entries}
 }
 
+// splitRowsByPositions replaces the expense row by one row per e-invoice
+// position on the same account, so each position can get its own account.
+// The expense row is the Soll row whose amount equals the positions' total,
+// else the largest Soll row. Allowances (negative positions) become Haben
+// rows; a difference between the row and the total (e.g. Rabatt) stays on
+// the original account. Rows are returned unchanged when there is nothing to
+// split.
+func splitRowsByPositions(rows []bookingEditRow, positions []core.InvoicePosition) []bookingEditRow {
+	total := core.SumPositionen(positions)
+	if len(positions) == 0 || total == 0 {
+		return rows
+	}
+	target := -1
+	for i, r := range rows {
+		if !r.Soll {
+			continue
+		}
+		if math.Abs(r.Betrag-total) < 0.005 {
+			target = i
+			break
+		}
+		if target < 0 || r.Betrag > rows[target].Betrag {
+			target = i
+		}
+	}
+	if target < 0 {
+		return rows
+	}
+	orig := rows[target]
+	split := make([]bookingEditRow, 0, len(positions)+1)
+	add := func(betrag float64, label string) {
+		r := bookingEditRow{Konto: orig.Konto, Betrag: round2(betrag), Soll: true, Label: label}
+		if r.Betrag < 0 {
+			r.Betrag, r.Soll = -r.Betrag, false
+		}
+		split = append(split, r)
+	}
+	for _, p := range positions {
+		add(p.Netto, p.Label())
+	}
+	if rest := round2(orig.Betrag - total); rest != 0 {
+		add(rest, "")
+	}
+	out := make([]bookingEditRow, 0, len(rows)+len(split))
+	out = append(out, rows[:target]...)
+	out = append(out, split...)
+	return append(out, rows[target+1:]...)
+}
+
 // parseDecimal reads a German/English decimal string ("12,71" or "12.71").
 func parseDecimal(s string) float64 {
 	s = strings.TrimSpace(strings.ReplaceAll(s, ",", "."))
@@ -42,8 +93,9 @@ func parseDecimal(s string) float64 {
 }
 
 // showBookingEditor opens a dialog to hand-edit a booking's Soll/Haben lines.
-// On OK it calls onSave with a Booking{Manuell:true}.
-func (a *App) showBookingEditor(current core.Booking, parent fyne.Window, onSave func(core.Booking)) {
+// positions are the e-invoice line items, if any; they enable "split by
+// positions". On OK it calls onSave with a Booking{Manuell:true}.
+func (a *App) showBookingEditor(current core.Booking, positions []core.InvoicePosition, parent fyne.Window, onSave func(core.Booking)) {
 	if parent == nil {
 		parent = a.window
 	}
@@ -100,7 +152,13 @@ func (a *App) showBookingEditor(current core.Booking, parent fyne.Window, onSave
 				refreshBalance()
 			})
 			kontoCell := container.NewBorder(nil, nil, nil, pickBtn, kontoLabel)
-			row := container.NewBorder(nil, nil, nil, del,
+			var caption fyne.CanvasObject
+			if r.Label != "" {
+				l := widget.NewLabelWithStyle(r.Label, fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+				l.Truncation = fyne.TextTruncateEllipsis
+				caption = l
+			}
+			row := container.NewBorder(caption, nil, nil, del,
 				container.New(layout.NewGridLayoutWithColumns(3), kontoCell, betrag, sh))
 			rowsBox.Add(row)
 		}
@@ -113,10 +171,19 @@ func (a *App) showBookingEditor(current core.Booking, parent fyne.Window, onSave
 		refreshBalance()
 	})
 
+	buttons := container.NewHBox(addBtn)
+	if len(positions) > 0 {
+		buttons.Add(widget.NewButton(a.bundle.T("booking.editor.split", len(positions)), func() {
+			rows = splitRowsByPositions(rows, positions)
+			rebuild()
+			refreshBalance()
+		}))
+	}
+
 	rebuild()
 	refreshBalance()
 
-	content := container.NewBorder(nil, container.NewVBox(addBtn, balanceLabel), nil, nil,
+	content := container.NewBorder(nil, container.NewVBox(buttons, balanceLabel), nil, nil,
 		container.NewVScroll(rowsBox))
 	d := dialog.NewCustomConfirm(a.bundle.T("booking.editor.title"), a.bundle.T("btn.save"), a.bundle.T("btn.cancel"),
 		content, func(ok bool) {
@@ -2,6 +2,8 @@ package ui
 
 import (
 	"testing"
+
+	"github.com/bergx2/buchisy/internal/core"
 )
 
 func TestBookingFromRows(t *testing.T) {
@@ -24,3 +26,46 @@ func TestBookingFromRows(t *testing.T) {
 		t.Error("zero-account row should be dropped")
 	}
 }
+
+func TestSplitRowsByPositions(t *testing.T) {
+	rows := []bookingEditRow{
+		{Konto: 4930, Betrag: 295, Soll: true},
+		{Konto: 1576, Betrag: 36.10, Soll: true},
+		{Konto: 1571, Betrag: 7.35, Soll: true},
+		{Konto: 1200, Betrag: 338.45, Soll: false},
+	}
+	positions := []core.InvoicePosition{
+		{Nr: "1", Bezeichnung: "Bürostuhl", Netto: 200, SatzProzent: 19},
+		{Nr: "2", Bezeichnung: "Fachzeitschrift", Netto: 100, SatzProzent: 7},
+		{Bezeichnung: "Nachlass: Treuerabatt", Netto: -10, SatzProzent: 19},
+		{Bezeichnung: "Zuschlag: Versand", Netto: 5, SatzProzent: 7},
+	}
+	got := splitRowsByPositions(rows, positions)
+	if len(got) != 7 {
+		t.Fatalf("rows = %+v", got)
+	}
+	if got[0].Konto != 4930 || got[0].Betrag != 200 || !got[0].Soll || got[0].Label != "1 Bürostuhl" {
+		t.Errorf("first split row = %+v", got[0])
+	}
+	if got[2].Betrag != 10 || got[2].Soll {
+		t.Errorf("allowance should become a Haben row: %+v", got[2])
+	}
+	if got[4].Konto != 1576 || got[6].Konto != 1200 {
+		t.Errorf("other rows must keep their order: %+v", got)
+	}
+	if !bookingFromRows(got).Balanced() {
+		t.Errorf("split booking must stay balanced: %+v", got)
+	}
+
+	// A Rabatt reduces the expense row below the positions' total: the
+	// largest Soll row is split and the difference stays as a rest row.
+	rows[0].Betrag = 290
+	got = splitRowsByPositions(rows, positions)
+	if len(got) != 8 || got[4].Konto != 4930 || got[4].Betrag != 5 || got[4].Soll {
+		t.Errorf("rest row = %+v", got)
+	}
+
+	if got := splitRowsByPositions(rows, nil); len(got) != len(rows) {
+		t.Error("no positions must leave the rows unchanged")
+	}
+}
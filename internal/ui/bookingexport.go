@@ -74,7 +74,7 @@ func (a *App) showBookingExportDialog() {
 // runBookingExport collects rows for the given month range, shows a preview
 // dialog with the export classification, and on confirmation writes the files.
 func (a *App) runBookingExport(fromY, fromM, toY, toM int, period string) {
-	rows := a.collectInvoiceRows(fromY, fromM, toY, toM)
+	rows := a.collectBookingRows(fromY, fromM, toY, toM)
 
 	previewLabel := widget.NewLabel("")
 	updatePreview := func(include bool) {
@@ -146,7 +146,23 @@ func (a *App) writeBookingExport(exportable []core.CSVRow, fromY, fromM, toY, to
 		}
 
 		// Mark each exported row in the database.
+		ebMarked := map[string]bool{}
 		for _, r := range exportable {
+			if r.Eroeffnung {
+				if !ebMarked[r.Jahr] {
+					ebMarked[r.Jahr] = true
+					if merr := a.dbRepo.MarkOpeningBalancesExported(r.Jahr); merr != nil {
+						a.logger.Warn("MarkOpeningBalancesExported failed for %s: %v", r.Jahr, merr)
+					}
+				}
+				continue
+			}
+			if r.Umbuchung {
+				if merr := a.dbRepo.MarkUmbuchungExported(r.Belegnummer); merr != nil {
+					a.logger.Warn("MarkUmbuchungExported failed for %s: %v", r.Belegnummer, merr)
+				}
+				continue
+			}
 			if merr := a.dbRepo.MarkExported(r.Jahr, r.Monat, r.Dateiname); merr != nil {
 				a.logger.Warn("MarkExported failed for %s: %v", r.Dateiname, merr)
 			}
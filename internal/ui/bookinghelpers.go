@@ -48,8 +48,8 @@ func (a *App) computeRevenueBooking(lines []core.TaxLine, revenueAccount int, ba
 	return b, true, ""
 }
 
-// computeInvoiceBooking resolves the payment account and builds the booking.
-// Returns (booking, bookable, reasonIfNotBookable).
+// computeInvoiceBooking resolves the payment account and builds the booking
+// (without Vorsteuer for a Kleinunternehmer). Returns (booking, bookable, reasonIfNotBookable).
 func (a *App) computeInvoiceBooking(kategorie string, lines []core.TaxLine, trinkgeld float64, expenseAccount int, bankAccountName string, rabatt float64) (core.Booking, bool, string) {
 	if len(lines) == 0 {
 		return core.Booking{}, false, a.bundle.T("booking.no.lines")
@@ -58,9 +58,23 @@ func (a *App) computeInvoiceBooking(kategorie string, lines []core.TaxLine, trin
 	if !ok {
 		return core.Booking{}, false, a.bundle.T("booking.no.payment.account")
 	}
-	b, err := core.BuildBooking(a.bookingRules, kategorie, lines, trinkgeld, expenseAccount, payment, rabatt)
+	build := core.BuildBooking
+	if a.settings.Kleinunternehmer {
+		build = core.BuildKleinunternehmerBooking
+	}
+	b, err := build(a.bookingRules, kategorie, lines, trinkgeld, expenseAccount, payment, rabatt)
 	if err != nil {
 		return core.Booking{}, false, err.Error()
 	}
 	return b, true, ""
 }
+
+// invoiceWarnings returns the plausibility warnings for a row, adapted to the
+// profile's Kleinunternehmer mode.
+func (a *App) invoiceWarnings(row core.CSVRow) []string {
+	w := core.InvoiceWarnings(row)
+	if a.settings.Kleinunternehmer {
+		w = core.KleinunternehmerWarnings(row, w)
+	}
+	return w
+}
@@ -132,6 +132,37 @@ func (a *App) collectInvoiceRows(fromY, fromM, toY, toM int) []core.CSVRow {
 	return rows
 }
 
+// collectBookingRows returns the invoice rows of the month range plus the
+// Umbuchungen filed in it and, for every year whose January is in the range,
+// that year's EB-Werte — the input of SuSa, GuV and the booking export.
+func (a *App) collectBookingRows(fromY, fromM, toY, toM int) []core.CSVRow {
+	rows := a.collectInvoiceRows(fromY, fromM, toY, toM)
+	if a.dbRepo == nil {
+		return rows
+	}
+	rows = append(rows, a.umbuchungRows(fromY, fromM, toY, toM)...)
+	for y := fromY; y <= toY; y++ {
+		if y == fromY && fromM > 1 {
+			continue
+		}
+		rows = append(rows, core.OpeningRows(a.openingBalances(y))...)
+	}
+	return rows
+}
+
+// umbuchungRows returns the Umbuchungen filed in the month range as booking
+// rows; none without a database.
+func (a *App) umbuchungRows(fromY, fromM, toY, toM int) []core.CSVRow {
+	if a.dbRepo == nil {
+		return nil
+	}
+	us, err := a.dbRepo.Umbuchungen(fmt.Sprintf("%04d-%02d", fromY, fromM), fmt.Sprintf("%04d-%02d", toY, toM))
+	if err != nil {
+		a.logger.Warn("Umbuchungen %04d-%02d bis %04d-%02d: %v", fromY, fromM, toY, toM, err)
+	}
+	return core.UmbuchungRows(us)
+}
+
 // saveExportCSV builds the CSV in memory, then asks for a target file and
 // writes it there. Building first means a write failure cannot leave a
 // half-written file behind.
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// showEInvoiceExport generates an e-invoice for an Ausgangsrechnung: a
+// ZUGFeRD/Factur-X PDF (the archived invoice PDF with the CII XML embedded)
+// or a plain XRechnung in CII or UBL. The seller is the profile's own company
+// data; the customer address, Leitweg-ID and due date are asked for here
+// because BuchISY does not store them per invoice (a customer known from the
+// invoice writer prefills them). The result is validated with the same rules
+// as incoming e-invoices; findings are shown and the user decides whether to
+// save anyway.
+func (a *App) showEInvoiceExport(row core.CSVRow) {
+	if strings.TrimSpace(a.settings.Firma.Name) == "" {
+		a.showError(a.bundle.T("einvoice.out.title"), a.bundle.T("einvoice.out.nocompany"))
+		return
+	}
+	zugferd := a.bundle.T("einvoice.out.zugferd")
+	xrCII := a.bundle.T("einvoice.out.xrcii")
+	xrUBL := a.bundle.T("einvoice.out.xrubl")
+	formatSelect := widget.NewSelect([]string{zugferd, xrCII, xrUBL}, nil)
+	formatSelect.SetSelected(zugferd)
+
+	strasseEntry := widget.NewEntry()
+	plzEntry := widget.NewEntry()
+	ortEntry := widget.NewEntry()
+	landEntry := widget.NewEntry()
+	landEntry.SetPlaceHolder("DE")
+	emailEntry := widget.NewEntry()
+	referenceEntry := widget.NewEntry()
+	referenceEntry.SetPlaceHolder("z. B. 04011000-12345-67")
+	dueEntry := widget.NewEntry()
+	dueEntry.SetPlaceHolder("TT.MM.JJJJ")
+	if t, err := time.Parse("02.01.2006", row.Rechnungsdatum); err == nil {
+		dueEntry.SetText(t.AddDate(0, 0, 14).Format("02.01.2006"))
+	}
+	deliveryEntry := widget.NewEntry()
+	deliveryEntry.SetPlaceHolder(row.Rechnungsdatum)
+	// A customer known from the invoice writer prefills the address.
+	if k, ok := a.kunden.Get(row.Auftraggeber); ok {
+		strasseEntry.SetText(k.Strasse)
+		plzEntry.SetText(k.PLZ)
+		ortEntry.SetText(k.Ort)
+		landEntry.SetText(k.Land)
+		emailEntry.SetText(k.Email)
+		referenceEntry.SetText(k.Kaeuferreferenz)
+	}
+
+	content := container.NewVBox(
+		selectableForm(a.bundle,
+			fi(a.bundle.T("einvoice.out.format"), formatSelect),
+			fi(a.bundle.T("einvoice.out.buyer"), widget.NewLabel(row.Auftraggeber)),
+			fi(a.bundle.T("company.street"), strasseEntry),
+			fi(a.bundle.T("company.zip"), plzEntry),
+			fi(a.bundle.T("company.city"), ortEntry),
+			fi(a.bundle.T("company.country"), landEntry),
+			fi(a.bundle.T("company.email"), emailEntry),
+			fi(a.bundle.T("einvoice.out.reference"), referenceEntry),
+			fi(a.bundle.T("einvoice.out.delivery"), deliveryEntry),
+			fi(a.bundle.T("einvoice.out.due"), dueEntry),
+		),
+	)
+	d := dialog.NewCustomConfirm(a.bundle.T("einvoice.out.title"), a.bundle.T("einvoice.out.generate"), a.bundle.T("btn.cancel"), content,
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			inv := core.OutgoingEInvoice{
+				Meta:       row.ToMeta(),
+				Verkaeufer: a.settings.Firma,
+				Kaeufer: core.Firmendaten{
+					Strasse: strings.TrimSpace(strasseEntry.Text),
+					PLZ:     strings.TrimSpace(plzEntry.Text),
+					Ort:     strings.TrimSpace(ortEntry.Text),
+					Land:    strings.ToUpper(strings.TrimSpace(landEntry.Text)),
+					Email:   strings.TrimSpace(emailEntry.Text),
+				},
+				Kaeuferreferenz: strings.TrimSpace(referenceEntry.Text),
+				Faelligkeit:     strings.TrimSpace(dueEntry.Text),
+				Leistungsdatum:  strings.TrimSpace(deliveryEntry.Text),
+			}
+			if ids := a.ownVATIDList(); len(ids) > 0 {
+				inv.VerkaeuferUStID = ids[0]
+			}
+			a.generateEInvoice(row, inv, formatSelect.Selected == zugferd, formatSelect.Selected == xrUBL)
+		}, a.window)
+	d.Resize(fyne.NewSize(520, 0))
+	d.Show()
+}
+
+// generateEInvoice builds, validates and saves the e-invoice.
+func (a *App) generateEInvoice(row core.CSVRow, inv core.OutgoingEInvoice, hybrid, ubl bool) {
+	var (
+		xmlData []byte
+		err     error
+	)
+	switch {
+	case hybrid:
+		xmlData, err = core.BuildCIIInvoiceXML(inv, core.GuidelineEN16931)
+	case ubl:
+		xmlData, err = core.BuildUBLInvoiceXML(inv)
+	default:
+		xmlData, err = core.BuildCIIInvoiceXML(inv, core.GuidelineXRechnung)
+	}
+	if err != nil {
+		a.showError(a.bundle.T("error.processing.title"), err.Error())
+		return
+	}
+	name := core.EInvoiceFileName(row.Dateiname, hybrid)
+	data := xmlData
+	if hybrid {
+		// The archived invoice PDF is the visual part; a non-PDF original
+		// is replaced by the generated Sichtbeleg rendering.
+		path := a.resolveInvoicePath(row)
+		if f, ok := a.eInvoiceExtractor.DetectFormat(path); ok && f == core.FormatZUGFeRD {
+			// Written in BuchISY with embedded data: the archived PDF
+			// already is the hybrid invoice.
+			if archived, rerr := os.ReadFile(path); rerr == nil {
+				a.saveFile(name, archived)
+				return
+			}
+		}
+		base, rerr := os.ReadFile(path)
+		if rerr != nil || !strings.EqualFold(filepath.Ext(path), ".pdf") {
+			sicht := inv.Meta
+			sicht.Faelligkeit = inv.Faelligkeit
+			base, err = core.BuildEInvoiceSichtbelegPDF(sicht, core.FormatZUGFeRD, core.FacturXFileName)
+			if err != nil {
+				a.showError(a.bundle.T("error.processing.title"), err.Error())
+				return
+			}
+		}
+		data, err = core.EmbedFacturX(base, xmlData, "Rechnung "+inv.Meta.Rechnungsnummer, time.Now())
+		if err != nil {
+			a.showError(a.bundle.T("error.processing.title"), err.Error())
+			return
+		}
+	}
+
+	v, err := core.ValidateEInvoiceXML(xmlData)
+	if err != nil || len(v.Findings) == 0 {
+		a.saveFile(name, data)
+		return
+	}
+	lines := make([]string, 0, len(v.Findings))
+	for _, f := range v.Findings {
+		lines = append(lines, "• "+f.String())
+	}
+	msg := widget.NewLabel(a.bundle.T("einvoice.out.findings", len(v.Findings), strings.Join(lines, "\n")))
+	msg.Wrapping = fyne.TextWrapWord
+	c := dialog.NewCustomConfirm(a.bundle.T("einvoice.out.title"), a.bundle.T("btn.save"), a.bundle.T("btn.cancel"),
+		container.NewVScroll(msg), func(ok bool) {
+			if ok {
+				a.saveFile(name, data)
+			}
+		}, a.window)
+	c.Resize(fyne.NewSize(560, 360))
+	c.Show()
+}
@@ -11,7 +11,6 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
-	"github.com/zalando/go-keyring"
 
 	"github.com/bergx2/buchisy/internal/core"
 )
@@ -235,12 +234,12 @@ func (a *App) showErloesAbgleich() {
 	})
 
 	// ── Step 4: Claude re-ranking for close-call ambiguous suggestions ────────
-	// Mechanism 4: when processing mode is "claude" and the top-2 single-line
-	// candidates are within a small score margin (< 0.3), ask Claude to pick the
+	// Mechanism 4: when an AI backend is configured and the top-2 single-line
+	// candidates are within a small score margin (< 0.3), ask the model to pick the
 	// best matching credit line. Errors are non-fatal.
-	if a.settings.ProcessingMode == "claude" {
-		apiKey, keyErr := keyring.Get("BuchISY", a.keyringAccount())
-		if keyErr == nil && apiKey != "" {
+	if a.aiReady() {
+		apiKey, model, keyErr := a.aiCredentials()
+		if keyErr == nil {
 			for i := range suggestions {
 				sug := &suggestions[i]
 				if len(sug.candidates) < 2 {
@@ -256,7 +255,7 @@ func (a *App) showErloesAbgleich() {
 				idx, rankErr := a.anthropicExtractor.RankStatementLine(
 					context.Background(),
 					apiKey,
-					a.settings.AnthropicModel,
+					model,
 					sug.row.Auftraggeber,
 					lineTexts,
 				)
@@ -294,10 +293,29 @@ func (a *App) showErloesAbgleich() {
 	groupProcessedAcct := map[string]bool{}
 
 	for _, row := range rows {
-		// Only unlinked Ausgangsrechnungen on bank accounts.
-		if !row.Ausgangsrechnung || row.BuchungRef != "" || autoLinkedSet[row.Dateiname] {
+		// Only unlinked Ausgangsrechnungen on bank accounts; a Teilzahlung
+		// invoice stays open for further partial payments after its first link.
+		if !row.Ausgangsrechnung || autoLinkedSet[row.Dateiname] {
 			continue
 		}
+		if row.BuchungRef != "" {
+			if !row.Teilzahlung {
+				continue
+			}
+			ensureCache(row.Bankkonto)
+			var paid float64
+			for _, ref := range core.ParseBuchungRefs(row.BuchungRef) {
+				for _, sl := range stmtCache[row.Bankkonto] {
+					if sl.File == ref.StatementFilename && sl.Line.Page == ref.Page && sl.Line.LineIdx == ref.LineIdx {
+						paid += sl.Line.Betrag
+						break
+					}
+				}
+			}
+			if paid >= core.InvoiceEURAmount(row)-0.01 {
+				continue
+			}
+		}
 		if accountType(row.Bankkonto) != core.AccountTypeBank {
 			continue
 		}
@@ -318,7 +336,7 @@ func (a *App) showErloesAbgleich() {
 		}
 
 		// Mechanism 1: group detection per account (run once per account).
-		if !groupProcessedAcct[row.Bankkonto] {
+		if row.BuchungRef == "" && !groupProcessedAcct[row.Bankkonto] {
 			groupProcessedAcct[row.Bankkonto] = true
 
 			// Collect all unlinked Ausgangsrechnungen for this account.
@@ -703,11 +721,13 @@ func (a *App) showErloesAbgleich() {
 					confirmBtn.Disable()
 					return
 				}
-				psug.row.BuchungRef = core.BuchungRef{
+				// Further partial payments add their line to the earlier ones
+				// (Ist-Versteuerung files each payment in its own month).
+				psug.row.BuchungRef = core.JoinBuchungRefs(append(core.ParseBuchungRefs(psug.row.BuchungRef), core.BuchungRef{
 					StatementFilename: chosen.file,
 					Page:              chosen.scored.Line.Page,
 					LineIdx:           chosen.scored.Line.LineIdx,
-				}.String()
+				}))
 				if pay, ok := a.settings.PaymentAccountSKR04(psug.row.Bankkonto); ok {
 					psug.row.Buchung = psug.row.Buchung.WithSettlementAccount(pay)
 				}
@@ -0,0 +1,216 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+	"github.com/bergx2/buchisy/internal/db"
+)
+
+// openingBalances brings the carried forward EB-Werte of year up to date
+// with the previous year's closing balances (its invoices, Umbuchungen and
+// stored EB-Werte) and returns all EB-Werte of year. Manual and exported
+// values are kept; a locked January is left alone.
+func (a *App) openingBalances(year int) []core.OpeningBalance {
+	if a.dbRepo == nil {
+		return nil
+	}
+	jahr := fmt.Sprintf("%04d", year)
+	prev := a.collectInvoiceRows(year-1, 1, year-1, 12)
+	if us, err := a.dbRepo.Umbuchungen(fmt.Sprintf("%04d-01", year-1), fmt.Sprintf("%04d-12", year-1)); err == nil {
+		prev = append(prev, core.UmbuchungRows(us)...)
+	}
+	if eb, err := a.dbRepo.OpeningBalances(fmt.Sprintf("%04d", year-1)); err == nil {
+		prev = append(prev, core.OpeningRows(eb)...)
+	}
+	vals := core.ComputeCarryForward(core.ComputeSuSa(prev, nil), core.DetectSKRVariant(a.chart), jahr)
+	if n, err := a.dbRepo.ApplyCarryForward(jahr, vals); err != nil {
+		a.logger.Warn("EB-Vortrag %s: %v", jahr, err)
+	} else if n > 0 {
+		a.logger.Info("EB-Vortrag %s: %d Konten aktualisiert", jahr, n)
+	}
+	obs, err := a.dbRepo.OpeningBalances(jahr)
+	if err != nil {
+		a.logger.Warn("EB-Werte %s: %v", jahr, err)
+	}
+	return obs
+}
+
+// cashOpeningBalance returns the manually entered EB-Wert of a cash
+// account's booking account for year. Carried forward values are not used:
+// they come from the ledger, which does not see the Kassenbuch's Einlagen.
+func (a *App) cashOpeningBalance(account string, year int) (float64, bool) {
+	konto, ok := a.settings.PaymentAccountSKR04(account)
+	if !ok || a.dbRepo == nil {
+		return 0, false
+	}
+	obs, err := a.dbRepo.OpeningBalances(fmt.Sprintf("%04d", year))
+	if err != nil {
+		a.logger.Warn("EB-Werte %d: %v", year, err)
+		return 0, false
+	}
+	for _, o := range obs {
+		if o.Konto == konto && !o.Vortrag {
+			return o.Saldo, true
+		}
+	}
+	return 0, false
+}
+
+// showOpeningBalances opens the EB-Werte of the current year: carried
+// forward and manual values, with entry, change and removal of manual ones.
+func (a *App) showOpeningBalances() {
+	if a.dbRepo == nil {
+		a.showError(a.bundle.T("eb.title"), "Datenbank nicht verfügbar.")
+		return
+	}
+	year := a.currentYear
+	win := a.app.NewWindow(fmt.Sprintf("%s %d", a.bundle.T("eb.title"), year))
+	listBox := container.NewVBox()
+	var refresh func()
+
+	bold := func(key string, align fyne.TextAlign) *widget.Label {
+		return widget.NewLabelWithStyle(a.bundle.T(key), align, fyne.TextStyle{Bold: true})
+	}
+	refresh = func() {
+		obs := a.openingBalances(year)
+		var sum float64
+		objs := []fyne.CanvasObject{
+			container.NewGridWithColumns(4,
+				bold("eb.col.konto", fyne.TextAlignLeading),
+				bold("eb.col.wert", fyne.TextAlignTrailing),
+				bold("eb.col.quelle", fyne.TextAlignLeading),
+				widget.NewLabel(""),
+			),
+			widget.NewSeparator(),
+		}
+		if len(obs) == 0 {
+			objs = append(objs, widget.NewLabel(a.bundle.T("eb.empty")))
+		}
+		for _, o := range obs {
+			o := o
+			sum += o.Saldo
+			wert := newCopyableLabel(a.bundle, a.ebAmountLabel(o.Saldo))
+			wert.Alignment = fyne.TextAlignTrailing
+			quelle := a.bundle.T("eb.manuell")
+			if o.Vortrag {
+				quelle = a.bundle.T("eb.vortrag")
+			}
+			if o.Exportiert {
+				quelle += " ✓"
+			}
+			editBtn := widget.NewButton(a.bundle.T("eb.edit"), func() { a.showOpeningBalanceForm(win, o, refresh) })
+			editBtn.Importance = widget.LowImportance
+			actions := container.NewHBox(editBtn)
+			if !o.Vortrag {
+				delBtn := widget.NewButton(a.bundle.T("eb.delete"), func() {
+					if err := a.dbRepo.DeleteOpeningBalance(o.Jahr, o.Konto); err != nil {
+						dialog.ShowError(a.ebError(err, o.Jahr), win)
+						return
+					}
+					refresh()
+				})
+				delBtn.Importance = widget.LowImportance
+				actions.Add(delBtn)
+			}
+			objs = append(objs, container.NewGridWithColumns(4,
+				widget.NewLabel(a.bookingKontoLabel(o.Konto)), wert, widget.NewLabel(quelle), actions))
+		}
+		objs = append(objs, widget.NewSeparator(),
+			widget.NewLabel(a.bundle.T("eb.gegenkonto", core.SaldenvortragKonto, a.ebAmountLabel(-sum))))
+		listBox.Objects = objs
+		listBox.Refresh()
+	}
+	refresh()
+
+	scroll := container.NewVScroll(listBox)
+	scroll.SetMinSize(fyne.NewSize(640, 320))
+	info := widget.NewLabel(a.bundle.T("eb.info", year-1))
+	info.Wrapping = fyne.TextWrapWord
+	neuBtn := widget.NewButton(a.bundle.T("eb.new"), func() {
+		a.showOpeningBalanceForm(win, core.OpeningBalance{Jahr: fmt.Sprintf("%04d", year)}, refresh)
+	})
+	neuBtn.Importance = widget.HighImportance
+	closeBtn := widget.NewButton(a.bundle.T("common.close"), func() { win.Close() })
+	closeBtn.Importance = widget.LowImportance
+
+	win.SetContent(container.NewPadded(container.NewBorder(info,
+		container.NewPadded(container.NewHBox(neuBtn, widget.NewSeparator(), closeBtn)), nil, nil, scroll)))
+	win.Resize(fyne.NewSize(700, 480))
+	win.CenterOnScreen()
+	win.Show()
+}
+
+// ebAmountLabel renders an EB-Wert as its amount with the side ("S"/"H").
+func (a *App) ebAmountLabel(saldo float64) string {
+	side := a.bundle.T("eb.soll")
+	if saldo < 0 {
+		side = a.bundle.T("eb.haben")
+	}
+	return formatMoney(math.Abs(saldo), "EUR", a.settings.DecimalSeparator) + " " + side
+}
+
+// ebError turns a lock refusal into the dialog message.
+func (a *App) ebError(err error, jahr string) error {
+	if errors.Is(err, db.ErrPeriodLocked) {
+		return fmt.Errorf("%s", a.bundle.T("eb.locked", jahr))
+	}
+	return err
+}
+
+// showOpeningBalanceForm enters or changes the manual EB-Wert of an account
+// (o.Konto == 0: pick the account).
+func (a *App) showOpeningBalanceForm(parent fyne.Window, o core.OpeningBalance, onSaved func()) {
+	konto := o.Konto
+	kontoLabel := widget.NewLabel(a.bookingKontoLabel(konto))
+	pickBtn := widget.NewButton("…", func() {
+		a.showAccountSearch(konto, parent, func(n int) {
+			konto = n
+			kontoLabel.SetText(a.bookingKontoLabel(n))
+		})
+	})
+	pickBtn.Disable()
+	if o.Konto == 0 {
+		pickBtn.Enable()
+	}
+	betrag := widget.NewEntry()
+	betrag.SetText(formatDecimal(math.Abs(o.Saldo), a.settings.DecimalSeparator))
+	side := widget.NewSelect([]string{a.bundle.T("booking.soll"), a.bundle.T("booking.haben")}, nil)
+	if o.Saldo < 0 {
+		side.SetSelected(a.bundle.T("booking.haben"))
+	} else {
+		side.SetSelected(a.bundle.T("booking.soll"))
+	}
+	form := widget.NewForm(
+		widget.NewFormItem(a.bundle.T("eb.col.konto"), container.NewBorder(nil, nil, nil, pickBtn, kontoLabel)),
+		widget.NewFormItem(a.bundle.T("eb.col.wert"), betrag),
+		widget.NewFormItem(a.bundle.T("eb.seite"), side),
+	)
+	d := dialog.NewCustomConfirm(a.bundle.T("eb.form.title", o.Jahr), a.bundle.T("btn.save"), a.bundle.T("btn.cancel"), form, func(ok bool) {
+		if !ok {
+			return
+		}
+		if konto == 0 {
+			dialog.ShowError(fmt.Errorf("%s", a.bundle.T("eb.nokonto")), parent)
+			return
+		}
+		saldo := math.Abs(parseFloat(betrag.Text, a.settings.DecimalSeparator))
+		if side.Selected == a.bundle.T("booking.haben") {
+			saldo = -saldo
+		}
+		if err := a.dbRepo.SetOpeningBalance(core.OpeningBalance{Jahr: o.Jahr, Konto: konto, Saldo: saldo}); err != nil {
+			dialog.ShowError(a.ebError(err, o.Jahr), parent)
+			return
+		}
+		onSaved()
+	}, parent)
+	d.Resize(fyne.NewSize(480, 260))
+	d.Show()
+}
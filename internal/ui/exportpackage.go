@@ -10,9 +10,10 @@ import (
 
 // showExportPackage builds a GoBD/StB export ZIP for the current year
 // (full year, months 1–12), identical period to the "Ganzes Jahr" booking
-// export path. The ZIP contains the DATEV-EXTF Buchungsstapel, one PDF per
-// Beleg (skipped when the file is missing/unreadable), manifest.csv and a
-// GoBD-orientated index.xml.
+// export path. The ZIP contains the DATEV-EXTF Buchungsstapel (including the
+// Umbuchungen), one PDF per Beleg (skipped when the file is
+// missing/unreadable), manifest.csv, a GoBD-orientated index.xml and the
+// current Kettenkopf of the audit log (core.AuditAnkerDatei).
 func (a *App) showExportPackage() {
 	fromY, fromM, toY, toM := a.currentYear, 1, a.currentYear, 12
 	period := fmt.Sprintf("%04d", a.currentYear)
@@ -29,7 +30,7 @@ func (a *App) showExportPackage() {
 		DatumVon:  von,
 		DatumBis:  bis,
 	}
-	datev, _, _ := core.BuildDATEVStapel(h, rows)
+	datev, _, _ := core.BuildDATEVStapel(h, a.collectBookingRows(fromY, fromM, toY, toM))
 
 	// Collect Belegbilder; skip rows whose PDF cannot be read.
 	var belege []core.BelegFile
@@ -47,7 +48,17 @@ func (a *App) showExportPackage() {
 		})
 	}
 
-	zipBytes, err := core.BuildExportPackage(rows, datev, belege, period)
+	// Anchor the audit log's Kettenkopf outside the database.
+	var anker *core.AuditAnker
+	if a.dbRepo != nil {
+		if k, ok, err := a.dbRepo.AuditAnker(); err != nil {
+			a.logger.Warn("exportpackage: audit chain head: %v", err)
+		} else if ok {
+			anker = &k
+		}
+	}
+
+	zipBytes, err := core.BuildExportPackage(rows, datev, belege, period, anker)
 	if err != nil {
 		a.showError(a.bundle.T("error.processing.title"), err.Error())
 		return
@@ -278,16 +278,26 @@ func getDocumentsURI() (fyne.ListableURI, error) {
 }
 
 // processSubmission processes a selected main file plus its attachments.
-// A PDF main file is run through metadata extraction; a non-PDF main file
-// skips extraction and opens the confirmation modal for manual entry.
+// A PDF main file is run through metadata extraction; a standalone e-invoice
+// XML is read structurally (see processEInvoiceXML); any other non-PDF main
+// file skips extraction and opens the confirmation modal for manual entry.
 func (a *App) processSubmission(mainPath string, attachments []string, onComplete func()) {
 	a.logger.Info("Processing submission: main=%s, attachments=%d", mainPath, len(attachments))
 
+	if a.offerDuplicateCheck(mainPath, attachments, onComplete) {
+		return
+	}
+
+	if core.IsXML(mainPath) {
+		a.processEInvoiceXML(mainPath, attachments, onComplete)
+		return
+	}
+
 	if !core.IsPDF(mainPath) {
-		// Image files (jpg/png/gif/webp) can go through Claude's vision
-		// extractor exactly like a PDF — pre-fills the form from a
+		// Image files (jpg/png/gif/webp) can go through the AI backend's
+		// vision extractor exactly like a PDF — pre-fills the form from a
 		// screenshot. Fall back to a blank form on any failure.
-		if core.ImageMediaType(mainPath) != "" && a.settings.ProcessingMode == "claude" {
+		if core.ImageMediaType(mainPath) != "" && a.settings.UsesAI() {
 			ctx, cancel := context.WithTimeout(context.Background(), processingTimeout)
 			var done, canceled atomic.Bool
 			progress, setStatus, _, stopTimer := a.newProcessingDialog(mainPath)
@@ -337,6 +347,10 @@ func (a *App) processSubmission(mainPath string, attachments []string, onComplet
 		return
 	}
 
+	if a.offerPDFSplit(mainPath, attachments, onComplete) {
+		return
+	}
+
 	// Show loading indicator WITH a cancel button: extraction can stall (slow
 	// API, scanned-PDF rendering), so the user must be able to abort. The cancel
 	// button cancels the context (aborts the Claude request) and dismisses the
@@ -432,11 +446,61 @@ func (a *App) processSubmission(mainPath string, attachments []string, onComplet
 	}()
 }
 
+// processEInvoiceXML handles a standalone XRechnung/ZUGFeRD XML: the XML is
+// extracted structurally, a human-readable Sichtbeleg PDF is generated into a
+// temp folder, and the confirmation modal files that PDF as the main Beleg
+// with the XML as its first attachment — so the table, the preview and the
+// Belegpaket have a document to show while the original XML is archived next
+// to it. An XML that is not an e-invoice is reported as a processing error.
+func (a *App) processEInvoiceXML(xmlPath string, attachments []string, onComplete func()) {
+	meta, ok := a.extractEInvoice(xmlPath)
+	if !ok {
+		a.showProcessingError(xmlPath, attachments, onComplete,
+			a.bundle.T("error.processing.einvoice"))
+		return
+	}
+	format, _ := a.eInvoiceExtractor.DetectFormat(xmlPath)
+	data, err := core.BuildEInvoiceSichtbelegPDF(meta, format, filepath.Base(xmlPath))
+	if err != nil {
+		a.showProcessingError(xmlPath, attachments, onComplete,
+			a.bundle.T("error.processing.message", err.Error()))
+		return
+	}
+	tmpDir, err := os.MkdirTemp("", "buchisy-sichtbeleg-*")
+	if err != nil {
+		a.showProcessingError(xmlPath, attachments, onComplete,
+			a.bundle.T("error.processing.message", err.Error()))
+		return
+	}
+	pdfPath := filepath.Join(tmpDir, core.SichtbelegName(filepath.Base(xmlPath)))
+	if err := os.WriteFile(pdfPath, data, 0o644); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		a.showProcessingError(xmlPath, attachments, onComplete,
+			a.bundle.T("error.processing.message", err.Error()))
+		return
+	}
+	a.logger.Info("Sichtbeleg generated for %s", filepath.Base(xmlPath))
+
+	// The temp PDF is copied into the archive on save; drop it once the
+	// modal has closed either way.
+	done := func() {
+		_ = os.RemoveAll(tmpDir)
+		if onComplete != nil {
+			onComplete()
+		}
+	}
+	a.showConfirmationModal(pdfPath, append([]string{xmlPath}, attachments...), meta, done)
+}
+
 // enqueueSubmissions queues supported files for sequential entry. The first
 // opens immediately; closing each review modal (save or cancel) opens the next.
 func (a *App) enqueueSubmissions(paths []string) {
 	var files []string
 	for _, p := range paths {
+		if core.IsMailFile(p) {
+			files = append(files, a.unpackMailFile(p)...)
+			continue
+		}
 		if core.IsSupportedFile(filepath.Base(p)) {
 			files = append(files, p)
 		}
@@ -477,5 +541,9 @@ func (a *App) processNextPending() {
 	path := a.pendingFiles[0]
 	a.pendingFiles = a.pendingFiles[1:]
 	a.batchDone++
+	if imp, ok := a.mailImports[path]; ok {
+		a.processMailImport(imp, func() { a.processNextPending() })
+		return
+	}
 	a.processSubmission(path, nil, func() { a.processNextPending() })
 }
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/bergx2/buchisy/internal/core"
+	"github.com/bergx2/buchisy/internal/db"
+	"github.com/bergx2/buchisy/internal/logging"
+)
+
+// offerDuplicateCheck compares a new receipt's content with the archive
+// before extraction. It returns false when the file goes straight on;
+// otherwise it has taken over and either drops the file (the user declined
+// a duplicate) or resubmits it.
+func (a *App) offerDuplicateCheck(mainPath string, attachments []string, onComplete func()) bool {
+	if a.dbRepo == nil || a.dupChecked[mainPath] {
+		return false
+	}
+	if a.dupChecked == nil {
+		a.dupChecked = map[string]bool{}
+	}
+	a.dupChecked[mainPath] = true
+	resubmit := func() {
+		a.processSubmission(mainPath, attachments, func() {
+			delete(a.dupChecked, mainPath)
+			if onComplete != nil {
+				onComplete()
+			}
+		})
+	}
+	repo := a.dbRepo
+	go func() {
+		var matches []core.FingerprintMatch
+		fp, err := core.ComputeFingerprint(mainPath)
+		if err == nil {
+			matches, err = repo.FindSameContent(fp)
+		}
+		if err != nil {
+			a.logger.Warn("Duplicate check of %s failed: %v", mainPath, err)
+		}
+		fyne.Do(func() {
+			if len(matches) == 0 {
+				resubmit()
+				return
+			}
+			a.confirmDuplicate(mainPath, matches, resubmit, onComplete)
+		})
+	}()
+	return true
+}
+
+// confirmDuplicate asks whether to capture a file whose content is already
+// archived. Declining a scan-inbox file moves it to "<inbox>/duplikate".
+func (a *App) confirmDuplicate(mainPath string, matches []core.FingerprintMatch, resubmit, onComplete func()) {
+	const maxListed = 5
+	var labels []string
+	for i, m := range matches {
+		if i == maxListed {
+			labels = append(labels, "…")
+			break
+		}
+		labels = append(labels, fmt.Sprintf("%s (%s/%s)", m.Label(), m.Monat, m.Jahr))
+	}
+	title, msg := a.bundle.T("duplicate.near.title"), a.bundle.T("duplicate.near.message", filepath.Base(mainPath))
+	if matches[0].Exact {
+		title, msg = a.bundle.T("duplicate.exact.title"), a.bundle.T("duplicate.exact.message", filepath.Base(mainPath))
+	}
+	msg += "\n\n" + strings.Join(labels, "\n") + "\n\n" + a.bundle.T("duplicate.confirm")
+	dialog.ShowConfirm(title, msg, func(ok bool) {
+		if ok {
+			resubmit()
+			return
+		}
+		a.logger.Info("Duplicate not captured: %s (%s)", mainPath, matches[0].Label())
+		a.moveOutOfScanInbox(mainPath, "duplikate")
+		delete(a.dupChecked, mainPath)
+		if onComplete != nil {
+			onComplete()
+		}
+	}, a.window)
+}
+
+// recordFingerprints stores the content fingerprints of an invoice's
+// archived main file and its "_Anhang<n>" siblings in the background.
+func (a *App) recordFingerprints(row core.CSVRow, mainPath string) {
+	if a.dbRepo == nil {
+		return
+	}
+	go storeFingerprints(a.dbRepo, a.logger, row, mainPath)
+}
+
+// storeFingerprints fingerprints one invoice's files and saves them.
+func storeFingerprints(repo *db.Repository, logger *logging.Logger, row core.CSVRow, mainPath string) {
+	if !core.FileExists(mainPath) {
+		return
+	}
+	paths := append([]string{mainPath}, core.AttachmentPathsIn(filepath.Dir(mainPath), row.Dateiname)...)
+	fps := make([]core.Fingerprint, 0, len(paths))
+	for i, p := range paths {
+		fp, err := core.ComputeFingerprint(p)
+		if err != nil {
+			logger.Warn("Fingerprint of %s failed: %v", p, err)
+			continue
+		}
+		fp.Anhang = i
+		fps = append(fps, fp)
+	}
+	if err := repo.SetFingerprints(row.Jahr, row.Monat, row.Dateiname, fps); err != nil {
+		logger.Warn("Failed to store fingerprints of %s: %v", row.Dateiname, err)
+	}
+}
+
+// backfillFingerprints fingerprints invoices archived before fingerprints
+// existed, once per start, in the background.
+func (a *App) backfillFingerprints() {
+	repo := a.dbRepo
+	if repo == nil {
+		return
+	}
+	go func() {
+		rows, err := repo.InvoicesWithoutFingerprints()
+		if err != nil || len(rows) == 0 {
+			return
+		}
+		done := 0
+		for _, row := range rows {
+			var path string
+			fyne.DoAndWait(func() {
+				if a.dbRepo == repo {
+					path = a.resolveInvoicePath(row)
+				}
+			})
+			if path == "" {
+				return // profile switched
+			}
+			if core.FileExists(path) {
+				storeFingerprints(repo, a.logger, row, path)
+				done++
+			}
+		}
+		a.logger.Info("Fingerprinted %d of %d archived invoices", done, len(rows))
+	}()
+}
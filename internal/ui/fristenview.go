@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// showUStVAFristen lists the due dates of the current year's returns for the
+// profile's Voranmeldungszeitraum and Dauerfristverlängerung, each with its
+// Kz 83 (the December return net of the Sondervorauszahlung, Kz 39) and, for
+// a monthly filer with the extension, the Sondervorauszahlung itself (Kz 38).
+// A return marked as filed shows its date, or a warning when the current
+// values differ from the filing.
+func (a *App) showUStVAFristen() {
+	year := a.currentYear
+	now := time.Now()
+	heute := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	fmtAmt := func(v float64) string {
+		return formatMoney(v, "EUR", a.settings.DecimalSeparator)
+	}
+	bold := func(key string) *widget.Label {
+		return widget.NewLabelWithStyle(a.bundle.T(key), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+
+	body := container.NewVBox(
+		container.NewGridWithColumns(4,
+			bold("fristen.col.zeitraum"), bold("fristen.col.faellig"), bold("fristen.col.betrag"), bold("fristen.col.status")),
+		widget.NewSeparator(),
+	)
+	svz := a.sondervorauszahlung(year)
+	naechste := true
+	var meldungen []core.Meldung
+	if a.dbRepo != nil {
+		var err error
+		if meldungen, err = a.dbRepo.Meldungen(core.MeldungUStVA, fmt.Sprintf("%04d", year)); err != nil {
+			a.logger.Warn("UStVA-Fristen: Meldungen: %v", err)
+		}
+	}
+	for _, f := range core.UStVAFristen(year, a.settings.VoranmeldungQuartal, a.settings.Dauerfristverlaengerung) {
+		zeitraum, betrag := a.bundle.T("fristen.svz"), "Kz 38  "+fmtAmt(svz)
+		ende := time.Date(f.Jahr, time.January, 1, 0, 0, 0, 0, time.UTC)
+		var abweichung *core.MeldungAbweichung
+		if !f.Sondervorauszahlung() {
+			ende = time.Date(f.Jahr, time.Month(f.BisMonat)+1, 1, 0, 0, 0, 0, time.UTC)
+			zeitraum = f.Zeitraum()
+			rows := a.vatRows(year, f.VonMonat, year, f.BisMonat)
+			u := core.ComputeUStVAOfficial(rows, a.bookingRules)
+			if f.VonMonat == 12 && svz > 0 {
+				u = u.MitSondervorauszahlung(svz)
+				betrag = fmt.Sprintf("Kz 83  %s (Kz 39 −%s)", fmtAmt(u.Kz83), fmtAmt(u.Kz39))
+			} else {
+				betrag = "Kz 83  " + fmtAmt(u.Kz83)
+			}
+			if m, ok := core.LetzteMeldung(meldungen, core.MeldungUStVA, zeitraum); ok {
+				abw := core.VergleicheMeldung(m, core.NewUStVAMeldung(zeitraum, u, rows, a.bookingRules))
+				abweichung = &abw
+			}
+		}
+		status := widget.NewLabel("")
+		switch {
+		case abweichung != nil && abweichung.Abweichend():
+			status.SetText(a.bundle.T("fristen.berichtigen", abweichung.Gemeldet.Datum()))
+			status.Importance = widget.WarningImportance
+		case abweichung != nil:
+			status.SetText(a.bundle.T("fristen.gemeldet", abweichung.Gemeldet.Datum()))
+		case heute.Before(ende):
+			status.SetText(a.bundle.T("fristen.laufend"))
+		case naechste && !f.Faellig.Before(heute):
+			status.SetText(a.bundle.T("fristen.naechste"))
+			status.Importance = widget.WarningImportance
+			naechste = false
+		case f.Faellig.Before(heute):
+			status.SetText(a.bundle.T("fristen.abgelaufen"))
+		}
+		body.Add(container.NewGridWithColumns(4,
+			widget.NewLabel(zeitraum),
+			newCopyableLabel(a.bundle, f.Faellig.Format("02.01.2006")),
+			newCopyableLabel(a.bundle, betrag),
+			status,
+		))
+	}
+
+	basis := a.bundle.T("fristen.monatlich")
+	if a.settings.VoranmeldungQuartal {
+		basis = a.bundle.T("fristen.quartalsweise")
+	}
+	if a.settings.Dauerfristverlaengerung {
+		basis += " · " + a.bundle.T("fristen.dfv")
+	}
+	info := widget.NewLabel(basis + "\n" + a.bundle.T("fristen.info"))
+	info.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(info, nil, nil, nil, container.NewVScroll(body))
+	d := dialog.NewCustom(a.bundle.T("fristen.title", year), a.bundle.T("common.close"), content, a.window)
+	d.Resize(fyne.NewSize(720, 560))
+	d.Show()
+}
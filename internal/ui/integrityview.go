@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// showArchiveIntegrity compares the month folders with the database in the
+// background (every archived file is hashed) and then lists the findings
+// with their repair actions.
+func (a *App) showArchiveIntegrity() {
+	if a.dbRepo == nil {
+		a.showError(a.bundle.T("integrity.title"), "Datenbank nicht verfügbar.")
+		return
+	}
+	repo, sm, csvRepo := a.dbRepo, a.storageManager, a.csvRepo
+	progress := dialog.NewCustomWithoutButtons(a.bundle.T("integrity.title"),
+		container.NewVBox(widget.NewLabel(a.bundle.T("integrity.running")), widget.NewProgressBarInfinite()),
+		a.window)
+	progress.Show()
+	go func() {
+		months, err := repo.ArchiveMonths()
+		var rep core.IntegrityReport
+		if err == nil {
+			rep = sm.CheckArchive(months, csvRepo)
+		}
+		fyne.Do(func() {
+			progress.Hide()
+			if err != nil {
+				a.showError(a.bundle.T("integrity.title"), err.Error())
+				return
+			}
+			a.logger.Info("Archive check: %d folders, %d invoices, %d files, %d finding(s)",
+				rep.Monate, rep.Belege, rep.Dateien, len(rep.Issues))
+			a.showIntegrityReport(rep)
+		})
+	}()
+}
+
+// showIntegrityReport lists the findings of an archive check. Each line
+// offers the repair that fits: re-link a missing file to the unbooked file
+// found for it, run an unbooked file through the capture flow, or rewrite
+// invoices.csv from the database. Modified files and missing ones without a
+// candidate can only be shown; they need a human decision.
+func (a *App) showIntegrityReport(rep core.IntegrityReport) {
+	if rep.OK() {
+		a.showInfo(a.bundle.T("integrity.title"),
+			a.bundle.T("integrity.ok", rep.Monate, rep.Belege, rep.Dateien))
+		return
+	}
+	var d dialog.Dialog
+	rescan := func() {
+		d.Hide()
+		a.loadInvoices()
+		a.showArchiveIntegrity()
+	}
+	kindLabel := map[core.IntegrityKind]string{
+		core.IntegrityMissing:  a.bundle.T("integrity.missing"),
+		core.IntegrityOrphan:   a.bundle.T("integrity.orphan"),
+		core.IntegrityModified: a.bundle.T("integrity.modified"),
+		core.IntegrityCSV:      a.bundle.T("integrity.csv"),
+	}
+
+	list := widget.NewList(
+		func() int { return len(rep.Issues) },
+		func() fyne.CanvasObject {
+			lbl := widget.NewLabel("")
+			lbl.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, nil, widget.NewButton("", nil), lbl)
+		},
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			is := rep.Issues[id]
+			c := o.(*fyne.Container)
+			c.Objects[0].(*widget.Label).SetText(kindLabel[is.Art] + " · " + is.Describe())
+			btn := c.Objects[1].(*widget.Button)
+			switch {
+			case is.Art == core.IntegrityMissing && is.Kandidat != "":
+				btn.SetText(a.bundle.T("integrity.relink"))
+				btn.OnTapped = func() { a.relinkArchiveFile(is, rescan) }
+			case is.Art == core.IntegrityOrphan:
+				btn.SetText(a.bundle.T("integrity.reimport"))
+				btn.OnTapped = func() {
+					d.Hide()
+					a.enqueueSubmissions([]string{is.Pfad})
+				}
+			case is.Art == core.IntegrityCSV:
+				btn.SetText(a.bundle.T("integrity.regencsv"))
+				btn.OnTapped = func() {
+					a.regenerateCSV(is)
+					rescan()
+				}
+			case is.Art == core.IntegrityMissing:
+				btn.SetText(a.bundle.T("integrity.show"))
+				btn.OnTapped = func() { a.openFolder(filepath.Dir(is.Pfad)) }
+			default:
+				btn.SetText(a.bundle.T("integrity.show"))
+				btn.OnTapped = func() { a.openFile(is.Pfad) }
+			}
+		},
+	)
+
+	summary := widget.NewLabel(a.bundle.T("integrity.summary", rep.Monate, rep.Belege, rep.Dateien, len(rep.Issues)))
+	summary.Wrapping = fyne.TextWrapWord
+	var bottom fyne.CanvasObject = widget.NewLabel("")
+	if rep.Count(core.IntegrityCSV) > 1 {
+		bottom = container.NewHBox(widget.NewButton(a.bundle.T("integrity.regenall"), func() {
+			for _, is := range rep.Issues {
+				if is.Art == core.IntegrityCSV {
+					a.regenerateCSV(is)
+				}
+			}
+			rescan()
+		}))
+	}
+	d = dialog.NewCustom(a.bundle.T("integrity.title"), a.bundle.T("common.close"),
+		container.NewBorder(summary, bottom, nil, nil, list), a.window)
+	d.Resize(fyne.NewSize(860, 520))
+	d.Show()
+}
+
+// relinkArchiveFile moves the unbooked file found for a missing one to the
+// path the booking expects, after confirmation.
+func (a *App) relinkArchiveFile(is core.IntegrityIssue, onDone func()) {
+	msg := a.bundle.T("integrity.relink.confirm", filepath.Base(is.Kandidat), is.Pfad)
+	dialog.ShowConfirm(a.bundle.T("integrity.relink"), msg, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := a.storageManager.Relink(is); err != nil {
+			a.showError(a.bundle.T("error.processing.title"), err.Error())
+			return
+		}
+		a.logger.Info("Re-linked %s → %s", is.Kandidat, is.Pfad)
+		a.showToast(a.bundle.T("integrity.repaired", is.Dateiname))
+		onDone()
+	}, a.window)
+}
+
+// regenerateCSV rewrites one month's invoices.csv from the database.
+func (a *App) regenerateCSV(is core.IntegrityIssue) {
+	y, _ := strconv.Atoi(is.Jahr)
+	m, _ := strconv.Atoi(is.Monat)
+	csvPath := a.storageManager.GetCSVPath(y, time.Month(m))
+	if err := a.dbRepo.ExportToCSV(is.Jahr, is.Monat, csvPath, a.csvRepo); err != nil {
+		a.showError(a.bundle.T("error.processing.title"), err.Error())
+		return
+	}
+	a.logger.Info("Rewrote %s from the database", csvPath)
+}
@@ -137,6 +137,8 @@ func (a *App) invoiceSubfolder(bankAccount string, ausgangsrechnung bool) string
 
 // showConfirmationModal shows the invoice data confirmation modal.
 func (a *App) showConfirmationModal(originalPath string, attachments []string, meta core.Meta, onClose func()) {
+	meta = a.applyMailHint(originalPath, attachments, meta)
+
 	// Forward-declared so the calendar buttons can open the date picker
 	// on this window (assigned further down).
 	var confirmWin fyne.Window
@@ -447,7 +449,7 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 	// one. Falls back to the viewed month when the date is missing/unparsable.
 	filingYear, filingMonth := a.currentYear, a.currentMonth
 	if y, mo, ok := parseFilingYearMonth(meta.Rechnungsdatum); ok {
-		filingYear, filingMonth = y, mo
+		filingYear, filingMonth = a.openFilingPeriod(y, mo)
 	}
 
 	yearSelect := widget.NewSelect(generateYearOptions(), nil)
@@ -481,6 +483,7 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 		if !ok {
 			return
 		}
+		y, mo = a.openFilingPeriod(y, mo)
 		syncingFiling = true
 		yearSelect.SetSelected(fmt.Sprintf("%d", y))
 		monthSelect.SetSelected(fmt.Sprintf("%02d - %-12s", int(mo),
@@ -748,7 +751,7 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 					parseFloat(rabattEntry.Text, a.settings.DecimalSeparator))
 			}
 		}
-		a.showBookingEditor(seed, confirmWin, func(edited core.Booking) {
+		a.showBookingEditor(seed, meta.Positionen, confirmWin, func(edited core.Booking) {
 			manualBooking = &edited
 			recomputeBooking()
 		})
@@ -848,7 +851,7 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 	warningsLabel.Hide()
 
 	refreshWarnings := func() {
-		warnings := core.InvoiceWarnings(core.CSVRow{
+		warnings := a.invoiceWarnings(core.CSVRow{
 			BetragNetto:              core.SumNetto(ed.Lines()),
 			SteuersatzBetrag:         core.SumMwSt(ed.Lines()),
 			Bruttobetrag:             ed.Brutto(),
@@ -864,6 +867,9 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 			BewirtungTeilnehmer:      teilnehmerEntry.Text,
 			BewirtungAngabenAufBeleg: aufBelegCheck.Checked,
 		})
+		if meta.Pruefbericht != nil {
+			warnings = append(warnings, meta.Pruefbericht.Warnings()...)
+		}
 		if len(warnings) == 0 {
 			warningsLabel.Hide()
 		} else {
@@ -884,7 +890,31 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 
 	// #8: group the source badge, duplicate banner and live warnings into ONE
 	// calm info block at the top (each still shows/hides independently).
-	infoLine := container.NewVBox(quelleLabel, dupBanner, statementHint, warningsLabel)
+	// E-invoice validation report (EN 16931/XRechnung), exportable for the
+	// correspondence with the issuer when the invoice has to be rejected.
+	validationRow := container.NewHBox()
+	if meta.Pruefbericht != nil && len(meta.Pruefbericht.Findings) > 0 {
+		v := *meta.Pruefbericht
+		validationRow.Add(widget.NewButton(a.bundle.T("einvoice.validation.export"), func() {
+			current := meta
+			current.Auftraggeber = companyEntry.Text
+			current.Rechnungsnummer = invoiceNumEntry.Text
+			current.Rechnungsdatum = dateEntry.Text
+			current.Bruttobetrag = ed.Brutto()
+			data, err := core.BuildEInvoiceValidationPDF(current, v, a.profile)
+			if err != nil {
+				a.showError(a.bundle.T("error.processing.title"), err.Error())
+				return
+			}
+			a.savePDF(core.ValidationReportName(filepath.Base(originalPath)), data)
+		}))
+	} else {
+		validationRow.Hide()
+	}
+	// Fields the extractor was unsure about (per-field provenance); their
+	// inputs are outlined below.
+	provenanceLabel := a.provenanceHint(meta)
+	infoLine := container.NewVBox(quelleLabel, provenanceLabel, dupBanner, statementHint, warningsLabel, validationRow)
 	belegnrLabel := newCopyableLabel(a.bundle, belegnrText)
 	belegnrLabel.TextStyle = fyne.TextStyle{Bold: true}
 
@@ -908,27 +938,29 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 	formItems = append(formItems,
 		widget.NewSeparator(),
 		section("Identifikation", selectableForm(a.bundle,
-			fi(a.bundle.T("field.company"), companyEntry),
+			fi(a.bundle.T("field.company"), provenanceFrame(meta, companyEntry, core.FieldAuftraggeber)),
 			fi(a.bundle.T("field.shortdesc"), container.NewBorder(nil, nil, nil, shortDescLabel, shortDescEntry)),
 			fi(a.bundle.T("field.invoicenumber"),
 				container.NewGridWithColumns(2,
-					invoiceNumEntry,
+					provenanceFrame(meta, invoiceNumEntry, core.FieldRechnungsnummer),
 					container.NewBorder(nil, nil,
 						widget.NewLabelWithStyle(a.bundle.T("field.vatid"),
 							fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-						nil, vatIDEntry),
+						nil, provenanceFrame(meta, vatIDEntry, core.FieldVATID)),
 				)),
 		)),
 		section("Beträge und Datum", selectableForm(a.bundle,
 			fi(a.bundle.T("field.invoiceDate"),
 				container.NewGridWithColumns(2,
-					container.NewBorder(nil, nil, nil, dateCalendarBtn, dateEntry),
+					container.NewBorder(nil, nil, nil, dateCalendarBtn,
+						provenanceFrame(meta, dateEntry, core.FieldRechnungsdatum)),
 					container.NewBorder(nil, nil,
 						widget.NewLabelWithStyle(a.bundle.T("field.paymentDate"),
 							fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-						paymentDateCalendarBtn, paymentDateEntry),
+						paymentDateCalendarBtn, provenanceFrame(meta, paymentDateEntry, core.FieldBezahldatum)),
 				)),
-			fi("MwSt.-Zeilen", ed.Container()),
+			fi("MwSt.-Zeilen", provenanceFrame(meta, ed.Container(),
+				core.FieldBetragNetto, core.FieldSteuersatzBetrag, core.FieldBruttobetrag)),
 			fi(a.bundle.T("field.currency"),
 				container.NewBorder(nil, nil, nil, nil, currencySelect)),
 			fi(a.bundle.T("field.rabatt"), container.NewBorder(nil, nil, nil, paidActualLabel, rabattEntry)),
@@ -1161,6 +1193,7 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 				rememberCheck.Checked,
 				filenameEntry.Text,
 				ausgangsrechnungCheck.Checked,
+				false, // issueNumber
 				targetYear,
 				targetMonth,
 				finalBooking,
@@ -1179,6 +1212,28 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 					ExpenseKonto: selectedAccount,
 				})
 			}
+			// Remember the account for the e-mail sender as well, so the
+			// next mail from this address or domain is pre-assigned.
+			if imp, ok := a.mailImportFor(originalPath, attachments); ok && rememberCheck.Checked {
+				core.RememberSender(a.companyMap, imp.Hint, selectedAccount)
+				if err := a.companyMap.Save(); err != nil {
+					a.logger.Warn("Failed to save company mapping: %v", err)
+				}
+			}
+			// Learn where this supplier prints each (corrected) value, so
+			// the local extraction reads the next receipt correctly.
+			if meta.Textebene != "" && !ausgangsrechnungCheck.Checked {
+				a.learnExtractionTemplate(meta.Textebene, core.Meta{
+					Auftraggeber:     companyEntry.Text,
+					VATID:            vatIDEntry.Text,
+					Rechnungsnummer:  invoiceNumEntry.Text,
+					Rechnungsdatum:   dateEntry.Text,
+					Bezahldatum:      paymentDateEntry.Text,
+					BetragNetto:      core.SumNetto(ed.Lines()),
+					SteuersatzBetrag: core.SumMwSt(ed.Lines()),
+					Bruttobetrag:     ed.Brutto(),
+				})
+			}
 			a.loadInvoices()
 			confirmWin.Close()
 			// Offer to reconcile right away for a bank/credit-card expense, so the
@@ -1190,7 +1245,7 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 			}
 		}
 
-		warnings := core.InvoiceWarnings(core.CSVRow{
+		warnings := a.invoiceWarnings(core.CSVRow{
 			BetragNetto:              core.SumNetto(ed.Lines()),
 			SteuersatzBetrag:         core.SumMwSt(ed.Lines()),
 			Bruttobetrag:             ed.Brutto(),
@@ -1206,6 +1261,10 @@ func (a *App) showConfirmationModal(originalPath string, attachments []string, m
 			BewirtungTeilnehmer:      teilnehmerEntry.Text,
 			BewirtungAngabenAufBeleg: aufBelegCheck.Checked,
 		})
+		if meta.Pruefbericht != nil {
+			warnings = append(warnings, meta.Pruefbericht.Warnings()...)
+		}
+		warnings = append(warnings, a.gemeldetWarnungen(fmt.Sprintf("%04d-%02d", targetYear, int(targetMonth)))...)
 		if len(warnings) > 0 {
 			msg := a.bundle.T("warnings.intro") + "\n• " + strings.Join(warnings, "\n• ")
 			dialog.NewConfirm(a.bundle.T("warnings.title"), msg, func(ok bool) {
@@ -1310,6 +1369,7 @@ func (a *App) saveInvoice(
 	rememberMapping bool,
 	filenameInput string,
 	ausgangsrechnung bool,
+	issueNumber bool,
 	targetYear int,
 	targetMonth time.Month,
 	buchung core.Booking,
@@ -1430,11 +1490,17 @@ func (a *App) saveInvoice(
 		newRow.HatAnhaenge = seq > 0
 		newRow.AnzahlAnhaenge = seq
 
-		// Insert into SQLite database
-		_, err = a.dbRepo.Insert(newRow)
+		// Insert into SQLite database; an invoice written in BuchISY takes
+		// its number in the same transaction.
+		insert := a.dbRepo.Insert
+		if issueNumber {
+			insert = a.dbRepo.InsertRechnung
+		}
+		_, err = insert(newRow)
 		if err != nil {
 			return fmt.Errorf("failed to insert into database: %w", err)
 		}
+		a.recordFingerprints(newRow, filepath.Join(targetFolder, finalFilename))
 
 		// Export to CSV (database is source of truth)
 		err = a.dbRepo.ExportToCSV(meta.Jahr, meta.Monat, csvPath, a.csvRepo)
@@ -1589,6 +1655,7 @@ func (a *App) autoBookInvoice(mainPath string, attachments []string, meta core.M
 		false, // rememberMapping
 		filename,
 		false, // ausgangsrechnung
+		false, // issueNumber
 		targetYear,
 		targetMonth,
 		booking,
@@ -1661,6 +1728,21 @@ func parseFilingYearMonth(date string) (int, time.Month, bool) {
 	return y, time.Month(m), true
 }
 
+// openFilingPeriod returns y/mo, or the current month when that period is
+// locked: a receipt for a closed month (e.g. the corrected booking after a
+// Storno) is filed in the running period instead.
+func (a *App) openFilingPeriod(y int, mo time.Month) (int, time.Month) {
+	if a.dbRepo == nil {
+		return y, mo
+	}
+	locked, err := a.dbRepo.IsPeriodLocked(fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", int(mo)))
+	if err != nil || !locked {
+		return y, mo
+	}
+	now := time.Now()
+	return now.Year(), now.Month()
+}
+
 // parseFloat parses a user-entered amount, tolerating thousands separators.
 // The thousands separator is whichever of "." / "," is not decimalSep.
 func parseFloat(s string, decimalSep string) float64 {
@@ -1768,6 +1850,7 @@ func (a *App) addAttachmentToInvoice(row core.CSVRow, sourcePath string) (int, e
 	if err := a.dbRepo.Update(row.Jahr, row.Monat, row.Dateiname, row); err != nil {
 		return 0, fmt.Errorf("Datenbank-Aktualisierung fehlgeschlagen: %w", err)
 	}
+	a.recordFingerprints(row, invoicePath)
 	csvPath := filepath.Join(monthFolder, "invoices.csv")
 	if err := a.dbRepo.ExportToCSV(row.Jahr, row.Monat, csvPath, a.csvRepo); err != nil {
 		a.logger.Warn("CSV-Export nach Anhang fehlgeschlagen: %v", err)
@@ -1798,6 +1881,7 @@ func (a *App) removeAttachmentFromInvoice(row core.CSVRow, attPath string) error
 	if err := a.dbRepo.Update(row.Jahr, row.Monat, row.Dateiname, row); err != nil {
 		return fmt.Errorf("Datenbank-Aktualisierung fehlgeschlagen: %w", err)
 	}
+	a.recordFingerprints(row, invoicePath)
 	csvPath := filepath.Join(monthFolder, "invoices.csv")
 	if err := a.dbRepo.ExportToCSV(row.Jahr, row.Monat, csvPath, a.csvRepo); err != nil {
 		a.logger.Warn("CSV-Export nach Anhang-Löschung fehlgeschlagen: %v", err)
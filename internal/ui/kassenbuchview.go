@@ -79,8 +79,10 @@ func (a *App) cashInvoicesFor(account string) []core.CSVRow {
 // cashCarryIn returns the opening balance carried into (year, month) for a
 // cash account: it walks backwards to the most recent month that has a
 // stored cash book (the anchor), then rolls the balance forward — counting
-// each month's cash invoices — up to the month before (year, month). ok is
-// false when no stored cash book exists in the lookback window.
+// each month's cash invoices — up to the month before (year, month). A
+// manual EB-Wert of the cash account's booking account anchors the walk at
+// 1 January when no later cash book is stored. ok is false when neither
+// exists in the lookback window.
 func (a *App) cashCarryIn(account string, year int, month time.Month) (float64, bool) {
 	const maxLookback = 60 // months
 	type ym struct {
@@ -93,6 +95,18 @@ func (a *App) cashCarryIn(account string, year int, month time.Month) (float64,
 	found := false
 	y, m := year, month
 	for i := 0; i < maxLookback && !found; i++ {
+		if m == time.January {
+			if eb, ok := a.cashOpeningBalance(account, y); ok {
+				balance := eb
+				for j := len(chain) - 1; j >= 0; j-- {
+					mo := chain[j]
+					_, balance = core.ComputeCashReport(
+						core.CashBook{Konto: account, Anfangsbestand: balance},
+						a.cashInvoicesForMonth(account, mo.y, mo.m))
+				}
+				return balance, true
+			}
+		}
 		m--
 		if m < time.January {
 			m, y = time.December, y-1
@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// showKennzahlMapping lists the account → UStVA Kennzahl mapping in effect
+// (core.BookingRules.KennzahlKonten) and edits its explicit part, the
+// profile's UStVAKonten: entries can be added for accounts the defaults do not
+// cover (§ 15a corrections, accounts outside the chart's standard …) or to
+// override a default, and removed again. Kennzahlen still without an account
+// are named above the list. Every change is saved to the
+// profile's booking rules; onChange lets the caller recompute.
+func (a *App) showKennzahlMapping(onChange func()) {
+	win := a.app.NewWindow(a.bundle.T("kzmap.title"))
+	bold := func(key string) *widget.Label {
+		return widget.NewLabelWithStyle(a.bundle.T(key), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	kzLabel := func(kz string) string {
+		if kz == "" {
+			return "—"
+		}
+		return "Kz " + kz
+	}
+
+	save := func() {
+		if err := a.bookingRulesStore.Save(a.bookingRules); err != nil {
+			a.logger.Warn("Failed to save booking rules: %v", err)
+		}
+		if onChange != nil {
+			onChange()
+		}
+	}
+
+	ohneKonto := widget.NewLabel("")
+	ohneKonto.Importance = widget.WarningImportance
+	ohneKonto.Wrapping = fyne.TextWrapWord
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		list.Objects = nil
+		explicit := len(a.bookingRules.UStVAKonten)
+		for i, k := range a.bookingRules.KennzahlKonten() {
+			satz := ""
+			if k.BasisKz != "" {
+				satz = fmt.Sprintf("%g %%", k.Satz)
+			}
+			var action fyne.CanvasObject = widget.NewLabel(a.bundle.T("kzmap.default"))
+			if i < explicit {
+				idx := i
+				action = widget.NewButton(a.bundle.T("kzmap.remove"), func() {
+					r := a.bookingRules
+					r.UStVAKonten = append(r.UStVAKonten[:idx:idx], r.UStVAKonten[idx+1:]...)
+					save()
+					refresh()
+				})
+			}
+			list.Add(container.NewGridWithColumns(6,
+				newCopyableLabel(a.bundle, paymentSKR04Label(a, k.Konto)),
+				widget.NewLabel(k.Steuerschluessel),
+				widget.NewLabel(kzLabel(k.Kz)),
+				widget.NewLabel(kzLabel(k.BasisKz)),
+				widget.NewLabel(satz),
+				action,
+			))
+		}
+		list.Refresh()
+		if kz := a.bookingRules.KennzahlenOhneKonto(); len(kz) > 0 {
+			ohneKonto.SetText(a.bundle.T("ustva.ohnekonto", strings.Join(kz, ", ")))
+			ohneKonto.Show()
+		} else {
+			ohneKonto.Hide()
+		}
+	}
+	refresh()
+
+	// New explicit entry.
+	kzOptions := []string{"—"}
+	for _, kz := range core.UStVAKennzahlen {
+		kzOptions = append(kzOptions, "Kz "+kz)
+	}
+	kzValue := func(sel string) string {
+		if sel == "—" {
+			return ""
+		}
+		return strings.TrimPrefix(sel, "Kz ")
+	}
+
+	konto := 0
+	kontoLbl := widget.NewLabel(paymentSKR04Label(a, 0))
+	kontoBtn := widget.NewButton(a.bundle.T("settings.rules.pick"), func() {
+		a.showAccountSearch(konto, win, func(n int) { konto = n; kontoLbl.SetText(paymentSKR04Label(a, n)) })
+	})
+	schluesselEntry := widget.NewEntry()
+	schluesselEntry.SetPlaceHolder(a.bundle.T("kzmap.col.schluessel"))
+	kzSelect := widget.NewSelect(kzOptions, nil)
+	kzSelect.SetSelected("—")
+	basisSelect := widget.NewSelect(kzOptions, nil)
+	basisSelect.SetSelected("—")
+	satzEntry := widget.NewEntry()
+	satzEntry.SetPlaceHolder(a.bundle.T("kzmap.col.satz"))
+	errLbl := widget.NewLabel("")
+	errLbl.Importance = widget.DangerImportance
+
+	addBtn := widget.NewButton(a.bundle.T("kzmap.add"), func() {
+		k := core.KennzahlKonto{
+			Konto:            konto,
+			Steuerschluessel: strings.TrimSpace(schluesselEntry.Text),
+			Kz:               kzValue(kzSelect.Selected),
+			BasisKz:          kzValue(basisSelect.Selected),
+			Satz:             parseDecimal(satzEntry.Text),
+		}
+		switch {
+		case k.Konto == 0:
+			errLbl.SetText(a.bundle.T("kzmap.err.konto"))
+			return
+		case k.Kz == "" && k.BasisKz == "":
+			errLbl.SetText(a.bundle.T("kzmap.err.kz"))
+			return
+		case k.BasisKz != "" && k.Satz <= 0:
+			errLbl.SetText(a.bundle.T("kzmap.err.satz"))
+			return
+		}
+		errLbl.SetText("")
+		r := a.bookingRules
+		// An entry for the same account and tax key replaces the old one.
+		kept := r.UStVAKonten[:0:0]
+		for _, e := range r.UStVAKonten {
+			if e.Konto != k.Konto || e.Steuerschluessel != k.Steuerschluessel {
+				kept = append(kept, e)
+			}
+		}
+		r.UStVAKonten = append(kept, k)
+		save()
+		refresh()
+	})
+
+	info := widget.NewLabel(a.bundle.T("kzmap.info"))
+	info.Wrapping = fyne.TextWrapWord
+	header := container.NewGridWithColumns(6,
+		bold("kzmap.col.konto"), bold("kzmap.col.schluessel"), bold("kzmap.col.kz"),
+		bold("kzmap.col.basis"), bold("kzmap.col.satz"), widget.NewLabel(""))
+	form := container.NewVBox(
+		widget.NewSeparator(),
+		bold("kzmap.new"),
+		container.NewBorder(nil, nil, nil, kontoBtn, kontoLbl),
+		container.NewGridWithColumns(5, schluesselEntry, kzSelect, basisSelect, satzEntry, addBtn),
+		errLbl,
+	)
+	scroll := container.NewVScroll(list)
+	content := container.NewBorder(container.NewVBox(info, ohneKonto, header, widget.NewSeparator()), form, nil, nil, scroll)
+	win.SetContent(container.NewPadded(content))
+	win.Resize(fyne.NewSize(860, 560))
+	win.CenterOnScreen()
+	win.Show()
+}
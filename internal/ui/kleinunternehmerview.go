@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// showKleinunternehmerDialog replaces the UStVA for a Kleinunternehmer
+// profile: the Gesamtumsatz of the current year per month and of the
+// previous year, checked against the § 19 UStG limits — the figures the
+// annual return asks for. Revenue counts when paid, so the invoices of the
+// year before the previous one are read as well.
+func (a *App) showKleinunternehmerDialog() {
+	year := a.currentYear
+	s := core.ComputeKleinunternehmer(a.collectInvoiceRows(year-2, 1, year, 12), a.zahlungen(), a.bookingRules, fmt.Sprintf("%04d", year))
+	fmtAmt := func(v float64) string {
+		return formatMoney(v, "EUR", a.settings.DecimalSeparator)
+	}
+	bold := func(text string) *widget.Label {
+		return widget.NewLabelWithStyle(text, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+
+	body := container.NewVBox(
+		bold(a.bundle.T("ku.grenzen")),
+		newCopyableLabel(a.bundle, a.bundle.T("ku.vorjahr", year-1, fmtAmt(s.UmsatzVorjahr), fmtAmt(core.KleinunternehmerGrenzeVorjahr))),
+		newCopyableLabel(a.bundle, a.bundle.T("ku.jahr", year, fmtAmt(s.Umsatz), fmtAmt(core.KleinunternehmerGrenzeJahr))),
+	)
+	status := widget.NewLabel(a.bundle.T("ku.ok"))
+	status.Wrapping = fyne.TextWrapWord
+	switch {
+	case s.VorjahrUeberschritten():
+		status.SetText(a.bundle.T("ku.vorjahr.ueberschritten", year-1, year))
+		status.Importance = widget.DangerImportance
+	case s.GrenzeUeberschrittenMit != "":
+		status.SetText(a.bundle.T("ku.jahr.ueberschritten", s.GrenzeUeberschrittenMit))
+		status.Importance = widget.DangerImportance
+	case s.Umsatz > core.KleinunternehmerGrenzeJahr*0.9 || s.Umsatz > core.KleinunternehmerGrenzeVorjahr:
+		status.SetText(a.bundle.T("ku.hinweis", year+1))
+		status.Importance = widget.WarningImportance
+	}
+	body.Add(status)
+	body.Add(widget.NewSeparator())
+
+	body.Add(bold(a.bundle.T("ku.monate", year)))
+	var kumuliert float64
+	for m, v := range s.Monate {
+		kumuliert += v
+		body.Add(container.NewGridWithColumns(3,
+			widget.NewLabel(a.bundle.T(fmt.Sprintf("month.%02d", m+1))),
+			newCopyableLabel(a.bundle, fmtAmt(v)),
+			newCopyableLabel(a.bundle, fmtAmt(kumuliert)),
+		))
+	}
+	body.Add(widget.NewSeparator())
+	body.Add(bold(a.bundle.T("ku.summe", fmtAmt(s.Umsatz))))
+
+	info := widget.NewLabel(a.bundle.T("ku.info"))
+	info.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(info, nil, nil, nil, container.NewVScroll(body))
+	d := dialog.NewCustom(a.bundle.T("ku.title", year), a.bundle.T("common.close"), content, a.window)
+	d.Resize(fyne.NewSize(560, 620))
+	d.Show()
+}
+
+// hatSteuerschuldnerschaft reports whether the invoices of year owe tax as
+// recipient of a § 13b service (Kz 46/47 or 84/85) — for a Kleinunternehmer
+// the periods that still need a UStVA.
+func (a *App) hatSteuerschuldnerschaft(year int) bool {
+	u := core.ComputeUStVAOfficial(a.collectInvoiceRows(year, 1, year, 12), a.bookingRules)
+	return u.Kz46 != 0 || u.Kz47 != 0 || u.Kz84 != 0 || u.Kz85 != 0
+}
@@ -20,7 +20,6 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"github.com/zalando/go-keyring"
 
 	"github.com/bergx2/buchisy/internal/core"
 )
@@ -98,14 +97,14 @@ func (a *App) extractStatementMetadata(ctx context.Context, folder, rel string,
 		return fmt.Errorf("Dateiformat wird nicht unterstützt: %s", filepath.Ext(fullPath))
 	}
 
-	apiKey, err := keyring.Get("BuchISY", a.keyringAccount())
+	apiKey, model, err := a.aiCredentials()
 	if err != nil {
 		return fmt.Errorf("API-Key nicht verfügbar (in den Einstellungen hinterlegen): %w", err)
 	}
 
-	setStatus(fmt.Sprintf("An Claude senden (%d Seite(n)) – Metadaten werden erkannt …", len(images)))
+	setStatus(fmt.Sprintf("An %s senden (%d Seite(n)) – Metadaten werden erkannt …", a.anthropicExtractor.ProviderName(), len(images)))
 	extracted, err := a.anthropicExtractor.ExtractStatementFromImages(
-		ctx, apiKey, a.settings.AnthropicModel,
+		ctx, apiKey, model,
 		images, mediaType,
 	)
 	if err != nil {
@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// unpackMessage writes the receipts of one raw e-mail into a fresh temp
+// folder and registers the result under its main path. ok is false for a
+// mail without receipts or one that cannot be read.
+func (a *App) unpackMessage(raw []byte, origin string) (core.MailImport, bool) {
+	msg, err := core.ParseMail(bytes.NewReader(raw))
+	if err != nil {
+		a.logger.Warn("E-Mail %s: %v", origin, err)
+		return core.MailImport{}, false
+	}
+	dir, err := os.MkdirTemp("", "buchisy-mail-*")
+	if err != nil {
+		a.logger.Warn("E-Mail %s: %v", origin, err)
+		return core.MailImport{}, false
+	}
+	imp, err := core.WriteMailImport(msg, dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		if errors.Is(err, core.ErrNoMailReceipts) {
+			a.logger.Info("E-Mail %s (%q) has no receipt attachment — skipped", origin, msg.Subject)
+		} else {
+			a.logger.Warn("E-Mail %s: %v", origin, err)
+		}
+		return core.MailImport{}, false
+	}
+	if a.mailImports == nil {
+		a.mailImports = map[string]core.MailImport{}
+	}
+	a.mailImports[imp.Main] = imp
+	a.logger.Info("E-Mail %s: %s + %d attachment(s)", origin, filepath.Base(imp.Main), len(imp.Attachments))
+	return imp, true
+}
+
+// unpackMailFile unpacks every message of a dropped .eml or .mbox file and
+// returns the main receipt paths for the entry queue.
+func (a *App) unpackMailFile(path string) []string {
+	msgs, err := core.ReadMailFile(path)
+	if err != nil {
+		a.showError(a.bundle.T("error.processing.title"), err.Error())
+		return nil
+	}
+	var mains []string
+	for i, raw := range msgs {
+		origin := filepath.Base(path)
+		if len(msgs) > 1 {
+			origin = fmt.Sprintf("%s#%d", filepath.Base(path), i+1)
+		}
+		if imp, ok := a.unpackMessage(raw, origin); ok {
+			mains = append(mains, imp.Main)
+		}
+	}
+	if skipped := len(msgs) - len(mains); skipped > 0 {
+		a.showToast(a.bundle.T("mail.skipped", skipped))
+	}
+	return mains
+}
+
+// processMailImport runs an unpacked e-mail through the normal capture flow
+// and removes its temp folder once the review has closed.
+func (a *App) processMailImport(imp core.MailImport, onComplete func()) {
+	a.processSubmission(imp.Main, imp.Attachments, func() {
+		delete(a.mailImports, imp.Main)
+		_ = os.RemoveAll(filepath.Dir(imp.Main))
+		if onComplete != nil {
+			onComplete()
+		}
+	})
+}
+
+// mailImportFor finds the unpacked e-mail a review belongs to. An e-invoice
+// XML is reviewed through its generated Sichtbeleg, with the XML as first
+// attachment.
+func (a *App) mailImportFor(path string, attachments []string) (core.MailImport, bool) {
+	if imp, ok := a.mailImports[path]; ok {
+		return imp, true
+	}
+	if len(attachments) > 0 {
+		if imp, ok := a.mailImports[attachments[0]]; ok {
+			return imp, true
+		}
+	}
+	return core.MailImport{}, false
+}
+
+// applyMailHint fills in what the receipt of an imported e-mail did not
+// give away: the sender as supplier, the account remembered for the
+// sender's address or domain, and the sender/subject as comment.
+func (a *App) applyMailHint(path string, attachments []string, meta core.Meta) core.Meta {
+	imp, ok := a.mailImportFor(path, attachments)
+	if !ok {
+		return meta
+	}
+	if strings.TrimSpace(meta.Auftraggeber) == "" {
+		if name := imp.Hint.Supplier(); name != "" {
+			meta.Auftraggeber = name
+			if meta.Provenance == nil {
+				meta.Provenance = core.Provenance{}
+			}
+			meta.Provenance[core.FieldAuftraggeber] = core.FieldProvenance{
+				Source: core.SourceMail, Confidence: core.ConfidenceModel,
+			}
+		}
+	}
+	if a.settings.AutoSelectAccount {
+		if _, known := a.companyMap.Get(meta.Auftraggeber); !known || meta.Auftraggeber == "" {
+			if acc, ok := core.SuggestAccountForSender(a.companyMap, imp.Hint); ok {
+				meta.Gegenkonto = acc
+			}
+		}
+	}
+	if strings.TrimSpace(meta.Kommentar) == "" {
+		meta.Kommentar = imp.Kommentar
+	}
+	return meta
+}
+
+// mailWatcher polls the configured e-mail inbox (a Maildir, or a folder of
+// .eml/.mbox files) and feeds messages with receipt attachments into the
+// capture flow, one at a time. Imported messages are recorded in the
+// profile's MailImportLog so they are not offered again after a restart.
+type mailWatcher struct {
+	app     *App
+	mu      sync.Mutex
+	sizes   map[string]int64 // source file -> last observed size
+	profile string           // profile the log belongs to
+	log     *core.MailImportLog
+	busy    bool // an e-mail is currently being processed
+}
+
+// newMailWatcher creates a watcher bound to the given app.
+func newMailWatcher(app *App) *mailWatcher {
+	return &mailWatcher{app: app, sizes: make(map[string]int64)}
+}
+
+// start launches the polling loop in a background goroutine.
+func (w *mailWatcher) start() {
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			w.poll()
+		}
+	}()
+}
+
+// onMailDone clears the busy flag so the next message can be processed.
+func (w *mailWatcher) onMailDone() {
+	w.mu.Lock()
+	w.busy = false
+	w.mu.Unlock()
+}
+
+// poll dispatches at most one not yet imported message with receipts.
+func (w *mailWatcher) poll() {
+	var folder, profile string
+	fyne.DoAndWait(func() {
+		folder = strings.TrimSpace(w.app.settings.MailInboxFolder)
+		profile = w.app.profile
+	})
+	if folder == "" {
+		return
+	}
+	w.mu.Lock()
+	busy := w.busy
+	w.mu.Unlock()
+	if busy {
+		return
+	}
+	if w.log == nil || w.profile != profile {
+		configDir, err := core.GetProfileConfigDir(profile)
+		if err != nil {
+			return
+		}
+		w.log = core.NewMailImportLog(configDir)
+		if err := w.log.Load(); err != nil {
+			w.app.logger.Warn("Failed to load mail import log: %v", err)
+		}
+		w.profile = profile
+	}
+	sources, err := core.MailSources(folder)
+	if err != nil {
+		return
+	}
+	for _, src := range sources {
+		fi, err := os.Stat(src)
+		if err != nil {
+			continue
+		}
+		prev, seen := w.sizes[src]
+		w.sizes[src] = fi.Size()
+		if !scanFileReady(prev, seen, fi.Size(), false) {
+			continue // new or still being written
+		}
+		msgs, err := core.ReadMailFile(src)
+		if err != nil {
+			continue
+		}
+		for _, raw := range msgs {
+			msg, err := core.ParseMail(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			key := core.MailImportKey(raw, msg)
+			if w.log.Has(key) {
+				continue
+			}
+			// Recorded before the review opens: a cancelled review is not
+			// offered again (the mail can still be dropped by hand).
+			if err := w.log.Add(key); err != nil {
+				w.app.logger.Warn("%v", err)
+			}
+			var imp core.MailImport
+			var ok bool
+			fyne.DoAndWait(func() {
+				imp, ok = w.app.unpackMessage(raw, filepath.Base(src))
+			})
+			if !ok {
+				continue
+			}
+			w.mu.Lock()
+			w.busy = true
+			w.mu.Unlock()
+			fyne.Do(func() {
+				w.app.processMailImport(imp, w.onMailDone)
+			})
+			return
+		}
+	}
+}
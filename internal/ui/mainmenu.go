@@ -13,6 +13,7 @@ func (a *App) buildMainMenu() *fyne.MainMenu {
 
 	file := fyne.NewMenu(t("menu.file"),
 		fyne.NewMenuItem(t("menu.import"), a.importMultiple),
+		fyne.NewMenuItem(t("menu.writeinvoice"), a.showRechnungEditor),
 		fyne.NewMenuItem(t("menu.openTarget"), a.openTargetFolder),
 		fyne.NewMenuItemSeparator(),
 		a.profileMenuItem(),
@@ -24,6 +25,8 @@ func (a *App) buildMainMenu() *fyne.MainMenu {
 	edit := fyne.NewMenu(t("menu.edit"),
 		fyne.NewMenuItem(t("menu.renumber"), a.renumberBelegnummern),
 		fyne.NewMenuItem(t("menu.autorules"), a.showAutoRulesDialog),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem(t("menu.integrity"), a.showArchiveIntegrity),
 	)
 	export := fyne.NewMenu(t("menu.export"),
 		fyne.NewMenuItem(t("menu.csvexport"), a.showCSVExportDialog),
@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// letzteMeldung returns the last snapshot of art filed for zeitraum
+// ("YYYY-MM" / "YYYY-QN"); false without a database or filing.
+func (a *App) letzteMeldung(art, zeitraum string) (core.Meldung, bool) {
+	if a.dbRepo == nil {
+		return core.Meldung{}, false
+	}
+	ms, err := a.dbRepo.Meldungen(art, zeitraum[:4])
+	if err != nil {
+		a.logger.Warn("Meldungen %s %s: %v", art, zeitraum, err)
+		return core.Meldung{}, false
+	}
+	return core.LetzteMeldung(ms, art, zeitraum)
+}
+
+// gemeldetWarnungen returns a warning per return (UStVA, ZM) already marked
+// as filed for the month or quarter of a filing period ("YYYY-MM"): saving a
+// receipt there changes the filed values, so a berichtigte Meldung may be
+// due. Each filed return is named once, however many periods share it.
+func (a *App) gemeldetWarnungen(perioden ...string) []string {
+	var w []string
+	seen := map[string]bool{}
+	for _, p := range perioden {
+		var jahr, monat int
+		if _, err := fmt.Sscanf(p, "%d-%d", &jahr, &monat); err != nil || monat < 1 || monat > 12 {
+			continue
+		}
+		for _, zeitraum := range []string{p, fmt.Sprintf("%04d-Q%d", jahr, (monat-1)/3+1)} {
+			for _, art := range []string{core.MeldungUStVA, core.MeldungZM} {
+				if seen[art+zeitraum] {
+					continue
+				}
+				seen[art+zeitraum] = true
+				if m, ok := a.letzteMeldung(art, zeitraum); ok {
+					w = append(w, a.bundle.T("meldung.beleg.gemeldet", art, zeitraum, m.Datum()))
+				}
+			}
+		}
+	}
+	return w
+}
+
+// meldungArt is the label of a filing: "Erstmeldung" or "Berichtigung n".
+func (a *App) meldungArt(m core.Meldung) string {
+	if m.Berichtigung() {
+		return a.bundle.T("meldung.berichtigung", m.Nr-1)
+	}
+	return a.bundle.T("meldung.erstmeldung")
+}
+
+// meldungPanel shows the filing state of a return. update(aktuell) compares
+// the current snapshot with the last filing of its period (zeitraum "" =
+// not a filing period: the panel is hidden) and returns that comparison;
+// a divergence is shown as a warning with the changed values and a button
+// for the Abweichungsbericht. The "Als gemeldet markieren" button stores
+// the current snapshot; done runs after it was stored.
+func (a *App) meldungPanel(done func()) (panel fyne.CanvasObject, markBtn *widget.Button, update func(aktuell core.Meldung) (core.MeldungAbweichung, bool)) {
+	fmtAmt := func(v float64) string {
+		return formatMoney(v, "EUR", a.settings.DecimalSeparator)
+	}
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+	var abw core.MeldungAbweichung
+	var aktuell core.Meldung
+	gemeldet := false
+
+	berichtBtn := widget.NewButton(a.bundle.T("meldung.bericht"), func() {
+		g := abw.Gemeldet
+		data, err := core.BuildMeldungAbweichungPDF(abw, a.bundle.T("meldung.bericht.title", g.Art, g.Zeitraum), a.profile)
+		if err != nil {
+			a.showError(a.bundle.T("error.processing.title"), err.Error())
+			return
+		}
+		a.savePDF(fmt.Sprintf("%s_%s_Abweichung.pdf", g.Art, g.Zeitraum), data)
+	})
+	box := container.NewBorder(nil, nil, nil, berichtBtn, status)
+
+	markBtn = widget.NewButton(a.bundle.T("meldung.markieren"), func() {
+		title := a.bundle.T("meldung.markieren")
+		switch {
+		case a.dbRepo == nil:
+			a.showError(title, a.bundle.T("meldung.nodb"))
+			return
+		case aktuell.Zeitraum == "":
+			a.showError(title, a.bundle.T("meldung.jahr"))
+			return
+		case gemeldet && !abw.Abweichend():
+			a.showInfo(title, a.bundle.T("meldung.unveraendert", aktuell.Art, aktuell.Zeitraum))
+			return
+		}
+		msg := a.bundle.T("meldung.confirm", aktuell.Art, aktuell.Zeitraum)
+		if gemeldet {
+			msg = a.bundle.T("meldung.confirm.berichtigung", aktuell.Art, aktuell.Zeitraum)
+		}
+		dialog.ShowConfirm(title, msg, func(ok bool) {
+			if !ok {
+				return
+			}
+			if _, err := a.dbRepo.SaveMeldung(aktuell); err != nil {
+				a.showError(title, err.Error())
+				return
+			}
+			done()
+		}, a.window)
+	})
+
+	update = func(m core.Meldung) (core.MeldungAbweichung, bool) {
+		aktuell, abw, gemeldet = m, core.MeldungAbweichung{}, false
+		box.Hide()
+		if m.Zeitraum == "" {
+			return abw, false
+		}
+		g, ok := a.letzteMeldung(m.Art, m.Zeitraum)
+		if !ok {
+			return abw, false
+		}
+		gemeldet = true
+		abw = core.VergleicheMeldung(g, m)
+		if !abw.Abweichend() {
+			status.Importance = widget.MediumImportance
+			status.SetText(a.bundle.T("meldung.gemeldet", g.Datum(), a.meldungArt(g)))
+			berichtBtn.Hide()
+			box.Show()
+			return abw, true
+		}
+		var werte []string
+		for _, w := range abw.Werte {
+			kurz, _ := core.MeldungWert(m.Art, w.Schluessel)
+			werte = append(werte, fmt.Sprintf("%s %s → %s", kurz, fmtAmt(w.Gemeldet), fmtAmt(w.Aktuell)))
+		}
+		status.Importance = widget.WarningImportance
+		status.SetText(a.bundle.T("meldung.abweichung", g.Datum(), a.meldungArt(g), strings.Join(werte, "; "), len(abw.Belege)) +
+			" " + a.bundle.T("meldung.abweichung."+strings.ToLower(m.Art)))
+		berichtBtn.Show()
+		box.Show()
+		return abw, true
+	}
+	box.Hide()
+	return box, markBtn, update
+}
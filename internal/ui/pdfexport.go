@@ -9,6 +9,11 @@ import (
 // chosen location. Cancel is silently ignored; write errors are shown to the
 // user. The helper mirrors the saveExportCSV pattern in csvexport.go.
 func (a *App) savePDF(defaultName string, data []byte) {
+	a.saveFile(defaultName, data)
+}
+
+// saveFile is savePDF for any file type (e.g. an XRechnung XML).
+func (a *App) saveFile(defaultName string, data []byte) {
 	d := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
 		if w == nil {
 			return // user cancelled
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// offerPDFSplit checks a multi-page PDF for several receipts (a scanner
+// batch) before extraction. It returns false when the file goes straight on
+// to extraction; otherwise it has taken over and either splits the file and
+// reviews the parts one by one, or resubmits it unchanged.
+func (a *App) offerPDFSplit(mainPath string, attachments []string, onComplete func()) bool {
+	if len(attachments) > 0 || a.splitChecked[mainPath] {
+		return false
+	}
+	if n, err := core.PDFPageCount(mainPath); err != nil || n < 2 {
+		return false
+	}
+	if a.splitChecked == nil {
+		a.splitChecked = map[string]bool{}
+	}
+	a.splitChecked[mainPath] = true
+	resubmit := func() {
+		a.processSubmission(mainPath, attachments, func() {
+			delete(a.splitChecked, mainPath)
+			if onComplete != nil {
+				onComplete()
+			}
+		})
+	}
+	own := a.ownVATIDList()
+	go func() {
+		var ranges []core.PageRange
+		pages, err := core.AnalyzePDFPages(mainPath)
+		if err != nil {
+			a.logger.Warn("Page analysis of %s failed: %v", mainPath, err)
+		} else {
+			ranges = core.DetectReceiptBoundaries(pages, own)
+		}
+		fyne.Do(func() {
+			if len(ranges) < 2 {
+				resubmit()
+				return
+			}
+			a.confirmPDFSplit(mainPath, ranges, resubmit, onComplete)
+		})
+	}()
+	return true
+}
+
+// confirmPDFSplit asks whether to split mainPath along ranges.
+func (a *App) confirmPDFSplit(mainPath string, ranges []core.PageRange, resubmit, onComplete func()) {
+	labels := make([]string, len(ranges))
+	for i, r := range ranges {
+		labels[i] = a.bundle.T("split.part", i+1, r.String())
+	}
+	msg := a.bundle.T("split.message", filepath.Base(mainPath), len(ranges)) + "\n\n" + strings.Join(labels, "\n")
+	dialog.ShowConfirm(a.bundle.T("split.title"), msg, func(ok bool) {
+		if !ok {
+			resubmit()
+			return
+		}
+		dir, err := os.MkdirTemp("", "buchisy-split-*")
+		if err == nil {
+			var parts []string
+			if parts, err = core.SplitPDF(mainPath, ranges, dir); err == nil {
+				a.logger.Info("Split %s into %d receipts", mainPath, len(parts))
+				a.moveOutOfScanInbox(mainPath, "aufgeteilt")
+				a.processSplitParts(parts, func() {
+					_ = os.RemoveAll(dir)
+					delete(a.splitChecked, mainPath)
+					if onComplete != nil {
+						onComplete()
+					}
+				})
+				return
+			}
+			_ = os.RemoveAll(dir)
+		}
+		a.showError(a.bundle.T("error.processing.title"), err.Error())
+		resubmit()
+	}, a.window)
+}
+
+// processSplitParts reviews the parts of a split PDF one after another.
+// Parts live in a temp folder, so they are copied (not moved) on filing.
+func (a *App) processSplitParts(parts []string, onComplete func()) {
+	if len(parts) == 0 {
+		onComplete()
+		return
+	}
+	a.processSubmission(parts[0], nil, func() {
+		a.processSplitParts(parts[1:], onComplete)
+	})
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// provenanceFieldKeys maps a provenance field to the i18n key of its label
+// in the confirmation dialog.
+var provenanceFieldKeys = map[string]string{
+	core.FieldAuftraggeber:     "field.company",
+	core.FieldRechnungsnummer:  "field.invoicenumber",
+	core.FieldVATID:            "field.vatid",
+	core.FieldRechnungsdatum:   "field.invoiceDate",
+	core.FieldBezahldatum:      "field.paymentDate",
+	core.FieldBetragNetto:      "field.net",
+	core.FieldSteuersatzBetrag: "field.vatAmount",
+	core.FieldBruttobetrag:     "field.gross",
+}
+
+// provenanceFrame outlines obj in the warning colour when any of fields is
+// uncertain in meta (see Meta.UncertainFields), so the user checks it before
+// saving. Other objects are returned unchanged.
+func provenanceFrame(meta core.Meta, obj fyne.CanvasObject, fields ...string) fyne.CanvasObject {
+	uncertain := meta.UncertainFields()
+	for _, f := range fields {
+		for _, u := range uncertain {
+			if f == u {
+				r := canvas.NewRectangle(color.Transparent)
+				r.StrokeColor = theme.Color(theme.ColorNameWarning)
+				r.StrokeWidth = 2
+				r.CornerRadius = theme.InputRadiusSize()
+				return container.NewStack(obj, r)
+			}
+		}
+	}
+	return obj
+}
+
+// provenanceHint lists the uncertain fields with source and confidence, e.g.
+// "Rechnungsdatum (Vision, 40 %)". Hidden when every field is certain.
+func (a *App) provenanceHint(meta core.Meta) *widget.Label {
+	lbl := widget.NewLabel("")
+	lbl.Importance = widget.WarningImportance
+	lbl.Wrapping = fyne.TextWrapWord
+	uncertain := meta.UncertainFields()
+	if len(uncertain) == 0 {
+		lbl.Hide()
+		return lbl
+	}
+	parts := make([]string, 0, len(uncertain))
+	for _, f := range uncertain {
+		name := a.bundle.T(provenanceFieldKeys[f])
+		if p, ok := meta.Provenance[f]; ok {
+			parts = append(parts, fmt.Sprintf("%s (%s, %.0f %%)", name, p.Source, p.Confidence*100))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (%s)", name, a.bundle.T("provenance.missing")))
+		}
+	}
+	lbl.SetText(a.bundle.T("provenance.uncertain", strings.Join(parts, ", ")))
+	return lbl
+}
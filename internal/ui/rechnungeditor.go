@@ -0,0 +1,465 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// rechnungPositionRow holds the widgets of one invoice position:
+// [Bezeichnung | Menge | Einheit | Einzelpreis | USt % | Netto | ✕].
+type rechnungPositionRow struct {
+	bezeichnung *widget.Entry
+	menge       *widget.Entry
+	einheit     *widget.Select
+	preis       *widget.Entry
+	satz        *widget.Select
+	netto       *widget.Label
+	removeBtn   *widget.Button
+}
+
+// rechnungSaetze are the VAT rates offered per position.
+var rechnungSaetze = []string{"19", "7", "0"}
+
+// showRechnungEditor opens the invoice writer: customer, positions, payment
+// term. Saving assigns the next number of the outgoing range, renders the
+// PDF (optionally with embedded ZUGFeRD data) and files it as an
+// Ausgangsrechnung with its Forderung booking — see saveRechnung.
+func (a *App) showRechnungEditor() {
+	if strings.TrimSpace(a.settings.Firma.Name) == "" {
+		a.showError(a.bundle.T("rechnung.title"), a.bundle.T("rechnung.nocompany"))
+		return
+	}
+	win := a.app.NewWindow(a.bundle.T("rechnung.title"))
+	sep := a.settings.DecimalSeparator
+	layout := a.settings.Rechnungslayout
+
+	// Customer.
+	kundeEntry := widget.NewSelectEntry(a.kunden.Namen())
+	kundeEntry.SetPlaceHolder(a.bundle.T("rechnung.customer.placeholder"))
+	kontaktEntry := widget.NewEntry()
+	strasseEntry := widget.NewEntry()
+	plzEntry := widget.NewEntry()
+	ortEntry := widget.NewEntry()
+	landEntry := widget.NewEntry()
+	landEntry.SetPlaceHolder("DE")
+	ustIDEntry := widget.NewEntry()
+	ustIDEntry.SetPlaceHolder("z. B. ATU12345678")
+	emailEntry := widget.NewEntry()
+	referenceEntry := widget.NewEntry()
+	referenceEntry.SetPlaceHolder("z. B. 04011000-12345-67")
+
+	// Invoice data.
+	nummerLabel := widget.NewLabel("")
+	dateEntry := widget.NewEntry()
+	dateEntry.SetText(time.Now().Format("02.01.2006"))
+	dateCalendarBtn := widget.NewButton("📅", func() {
+		a.showDatePicker(win, dateEntry.Text, func(d string) { dateEntry.SetText(d) })
+	})
+	dateCalendarBtn.Importance = widget.LowImportance
+	leistungEntry := widget.NewEntry()
+	leistungEntry.SetPlaceHolder(a.bundle.T("rechnung.delivery.placeholder"))
+	leistungCalendarBtn := widget.NewButton("📅", func() {
+		a.showDatePicker(win, leistungEntry.Text, func(d string) { leistungEntry.SetText(d) })
+	})
+	leistungCalendarBtn.Importance = widget.LowImportance
+	betreffEntry := widget.NewEntry()
+	zielEntry := widget.NewEntry()
+	zielEntry.SetText(strconv.Itoa(layout.ZahlungszielOrDefault()))
+	faelligLabel := widget.NewLabel("")
+	embedCheck := widget.NewCheck(a.bundle.T("rechnung.zugferd"), nil)
+	embedCheck.SetChecked(true)
+
+	// Positions.
+	einheitLabels := make([]string, len(core.Einheiten))
+	einheitCode := map[string]string{}
+	for i, e := range core.Einheiten {
+		einheitLabels[i] = e.Label
+		einheitCode[e.Label] = e.Code
+	}
+	var rows []rechnungPositionRow
+	rowsBox := container.NewVBox()
+	summenLabel := widget.NewLabelWithStyle("", fyne.TextAlignTrailing, fyne.TextStyle{Bold: true})
+
+	// entwurf reads the form into a Rechnungsentwurf (number not yet set).
+	entwurf := func() core.Rechnungsentwurf {
+		r := core.Rechnungsentwurf{
+			Kunde: core.Firmendaten{
+				Name:            strings.TrimSpace(kundeEntry.Text),
+				Ansprechpartner: strings.TrimSpace(kontaktEntry.Text),
+				Strasse:         strings.TrimSpace(strasseEntry.Text),
+				PLZ:             strings.TrimSpace(plzEntry.Text),
+				Ort:             strings.TrimSpace(ortEntry.Text),
+				Land:            strings.ToUpper(strings.TrimSpace(landEntry.Text)),
+				Email:           strings.TrimSpace(emailEntry.Text),
+			},
+			KundeUStID:      strings.ToUpper(strings.ReplaceAll(ustIDEntry.Text, " ", "")),
+			Kaeuferreferenz: strings.TrimSpace(referenceEntry.Text),
+			Rechnungsdatum:  strings.TrimSpace(dateEntry.Text),
+			Leistungsdatum:  strings.TrimSpace(leistungEntry.Text),
+			Betreff:         strings.TrimSpace(betreffEntry.Text),
+			Waehrung:        "EUR",
+		}
+		r.ZahlungszielTage, _ = strconv.Atoi(strings.TrimSpace(zielEntry.Text))
+		if r.ZahlungszielTage <= 0 {
+			r.ZahlungszielTage = layout.ZahlungszielOrDefault()
+		}
+		for _, row := range rows {
+			menge := parseFloat(row.menge.Text, sep)
+			preis := parseFloat(row.preis.Text, sep)
+			satz, _ := strconv.ParseFloat(row.satz.Selected, 64)
+			if strings.TrimSpace(row.bezeichnung.Text) == "" && menge == 0 && preis == 0 {
+				continue // untouched empty row
+			}
+			r.Positionen = append(r.Positionen, core.InvoicePosition{
+				Bezeichnung: strings.TrimSpace(row.bezeichnung.Text),
+				Menge:       menge,
+				Einheit:     einheitCode[row.einheit.Selected],
+				Einzelpreis: preis,
+				Netto:       round2(menge * preis),
+				SatzProzent: satz,
+			})
+		}
+		return r
+	}
+
+	refresh := func() {
+		r := entwurf()
+		for _, row := range rows {
+			row.netto.SetText(formatDecimal(round2(parseFloat(row.menge.Text, sep)*parseFloat(row.preis.Text, sep)), sep))
+		}
+		m := r.Meta()
+		summenLabel.SetText(a.bundle.T("rechnung.totals",
+			formatMoney(m.BetragNetto, m.Waehrung, sep),
+			formatMoney(m.SteuersatzBetrag, m.Waehrung, sep),
+			formatMoney(m.Bruttobetrag, m.Waehrung, sep)))
+		faelligLabel.SetText(r.Faelligkeit())
+		if y, ok := rechnungYear(r.Rechnungsdatum); ok {
+			if nr, err := a.nextRechnungsnummer(y); err != nil {
+				nummerLabel.SetText(a.bundle.T("rechnung.number.error", err.Error()))
+			} else {
+				nummerLabel.SetText(a.bundle.T("rechnung.number.preview", nr))
+			}
+		}
+	}
+
+	var addRow func(core.InvoicePosition)
+	rebuild := func() {
+		rowsBox.RemoveAll()
+		for _, row := range rows {
+			rowsBox.Add(container.NewBorder(nil, nil, nil, row.removeBtn,
+				container.NewGridWithColumns(6, row.bezeichnung, row.menge, row.einheit, row.preis, row.satz, row.netto)))
+		}
+		rowsBox.Refresh()
+	}
+	addRow = func(p core.InvoicePosition) {
+		row := rechnungPositionRow{
+			bezeichnung: widget.NewEntry(),
+			menge:       widget.NewEntry(),
+			einheit:     widget.NewSelect(einheitLabels, nil),
+			preis:       widget.NewEntry(),
+			satz:        widget.NewSelect(rechnungSaetze, nil),
+			netto:       widget.NewLabelWithStyle("", fyne.TextAlignTrailing, fyne.TextStyle{}),
+		}
+		row.bezeichnung.SetPlaceHolder(a.bundle.T("rechnung.col.description"))
+		row.menge.SetText(formatDecimal(p.Menge, sep))
+		row.einheit.SetSelected(core.EinheitLabel(p.Einheit))
+		if p.Einzelpreis != 0 {
+			row.preis.SetText(formatDecimal(p.Einzelpreis, sep))
+		}
+		row.satz.SetSelected(strconv.FormatFloat(p.SatzProzent, 'f', -1, 64))
+		onChange := func(string) { refresh() }
+		row.menge.OnChanged = onChange
+		row.preis.OnChanged = onChange
+		row.satz.OnChanged = onChange
+		row.removeBtn = widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+		row.removeBtn.Importance = widget.DangerImportance
+		bez := row.bezeichnung
+		row.removeBtn.OnTapped = func() {
+			for i, r := range rows {
+				if r.bezeichnung == bez {
+					rows = append(rows[:i], rows[i+1:]...)
+					break
+				}
+			}
+			rebuild()
+			refresh()
+		}
+		rows = append(rows, row)
+		rebuild()
+	}
+	addRow(core.InvoicePosition{Menge: 1, Einheit: "H87", SatzProzent: 19})
+	addBtn := widget.NewButtonWithIcon(a.bundle.T("rechnung.position.add"), theme.ContentAddIcon(), func() {
+		addRow(core.InvoicePosition{Menge: 1, Einheit: "H87", SatzProzent: 19})
+		refresh()
+	})
+	addBtn.Importance = widget.LowImportance
+
+	// Choosing a known customer fills the address and the terms.
+	kundeEntry.OnChanged = func(name string) {
+		k, ok := a.kunden.Get(name)
+		if !ok {
+			return
+		}
+		kontaktEntry.SetText(k.Ansprechpartner)
+		strasseEntry.SetText(k.Strasse)
+		plzEntry.SetText(k.PLZ)
+		ortEntry.SetText(k.Ort)
+		landEntry.SetText(k.Land)
+		ustIDEntry.SetText(k.UStID)
+		emailEntry.SetText(k.Email)
+		referenceEntry.SetText(k.Kaeuferreferenz)
+		if k.ZahlungszielTage > 0 {
+			zielEntry.SetText(strconv.Itoa(k.ZahlungszielTage))
+		}
+	}
+	dateEntry.OnChanged = func(string) { refresh() }
+	zielEntry.OnChanged = func(string) { refresh() }
+	refresh()
+
+	posHeader := container.NewBorder(nil, nil, nil, rowRightSpacer(),
+		container.NewGridWithColumns(6,
+			columnHeader(a.bundle.T("rechnung.col.description")), columnHeader(a.bundle.T("rechnung.col.quantity")),
+			columnHeader(a.bundle.T("rechnung.col.unit")), columnHeader(a.bundle.T("rechnung.col.price")),
+			columnHeader(a.bundle.T("rechnung.col.vat")), columnHeader(a.bundle.T("rechnung.col.net")),
+		))
+
+	form := container.NewVBox(
+		widget.NewLabelWithStyle(a.bundle.T("rechnung.section.customer"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		selectableForm(a.bundle,
+			fi(a.bundle.T("rechnung.customer"), kundeEntry),
+			fi(a.bundle.T("company.contact"), kontaktEntry),
+			fi(a.bundle.T("company.street"), strasseEntry),
+			fi(a.bundle.T("company.zip"), plzEntry),
+			fi(a.bundle.T("company.city"), ortEntry),
+			fi(a.bundle.T("company.country"), landEntry),
+			fi(a.bundle.T("field.vatid"), ustIDEntry),
+			fi(a.bundle.T("company.email"), emailEntry),
+			fi(a.bundle.T("einvoice.out.reference"), referenceEntry),
+		),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle(a.bundle.T("rechnung.section.invoice"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		selectableForm(a.bundle,
+			fi(a.bundle.T("rechnung.number"), nummerLabel),
+			fi(a.bundle.T("field.invoiceDate"), container.NewBorder(nil, nil, nil, dateCalendarBtn, dateEntry)),
+			fi(a.bundle.T("einvoice.out.delivery"), container.NewBorder(nil, nil, nil, leistungCalendarBtn, leistungEntry)),
+			fi(a.bundle.T("rechnung.subject"), betreffEntry),
+			fi(a.bundle.T("rechnung.term"), zielEntry),
+			fi(a.bundle.T("einvoice.out.due"), faelligLabel),
+		),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle(a.bundle.T("rechnung.section.positions"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		posHeader,
+		rowsBox,
+		addBtn,
+		widget.NewSeparator(),
+		summenLabel,
+		embedCheck,
+	)
+
+	previewBtn := widget.NewButton(a.bundle.T("rechnung.preview"), func() {
+		r := entwurf()
+		if err := r.Validate(); err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		if y, ok := rechnungYear(r.Rechnungsdatum); ok {
+			nr, err := a.nextRechnungsnummer(y)
+			if err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			r.Rechnungsnummer = nr
+		}
+		data, err := core.BuildRechnungPDF(r, a.settings.Firma, a.ownUStID(), layout)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		path := filepath.Join(os.TempDir(), "BuchISY_Rechnungsvorschau.pdf")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		a.openFile(path)
+	})
+	saveBtn := widget.NewButton(a.bundle.T("rechnung.save"), func() {
+		r := entwurf()
+		if err := r.Validate(); err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		nr, err := a.saveRechnung(r, embedCheck.Checked)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		win.Close()
+		a.loadInvoices()
+		a.showToast(a.bundle.T("rechnung.saved", nr))
+	})
+	saveBtn.Importance = widget.HighImportance
+	cancelBtn := widget.NewButton(a.bundle.T("btn.cancel"), func() { win.Close() })
+	cancelBtn.Importance = widget.LowImportance
+
+	win.SetContent(container.NewBorder(nil,
+		container.NewPadded(container.NewHBox(saveBtn, previewBtn, widget.NewSeparator(), cancelBtn)),
+		nil, nil,
+		container.NewVScroll(container.NewPadded(form)),
+	))
+	win.Resize(fyne.NewSize(900, 760))
+	win.CenterOnScreen()
+	win.Show()
+}
+
+// rechnungYear returns the year of a DD.MM.YYYY invoice date.
+func rechnungYear(datum string) (int, bool) {
+	t, err := time.Parse("02.01.2006", strings.TrimSpace(datum))
+	if err != nil {
+		return 0, false
+	}
+	return t.Year(), true
+}
+
+// ownUStID returns the first own VAT-ID, printed on written invoices.
+func (a *App) ownUStID() string {
+	if ids := a.ownVATIDList(); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// nextRechnungsnummer peeks the next number of the outgoing invoice range
+// for year; the number is only taken when the invoice is saved. Without the
+// issued numbers there is no safe next one: the error is returned.
+func (a *App) nextRechnungsnummer(year int) (string, error) {
+	pattern := a.settings.Rechnungslayout.NummernkreisOrDefault()
+	existing, err := a.dbRepo.AusgangsRechnungsnummern(core.RechnungsnummerPrefix(pattern, year))
+	if err != nil {
+		return "", fmt.Errorf("%s", a.bundle.T("rechnung.number.error", err.Error()))
+	}
+	return core.NextRechnungsnummer(pattern, year, existing), nil
+}
+
+// saveRechnung files a written invoice in one step: it assigns the next
+// number of the outgoing range, renders the PDF (with the ZUGFeRD XML
+// embedded when embed is set), builds the revenue booking (Forderung an
+// Erlös + Umsatzsteuer) and saves the Ausgangsrechnung row and the archived
+// file through saveInvoice. The number is issued in the transaction that
+// inserts the row (InsertRechnung), so a failed save leaves no gap; it stays
+// taken even if the invoice is deleted later. The customer is remembered for the next invoice. Returns
+// the assigned invoice number.
+func (a *App) saveRechnung(r core.Rechnungsentwurf, embed bool) (string, error) {
+	datum, err := time.Parse("02.01.2006", r.Rechnungsdatum)
+	if err != nil {
+		return "", fmt.Errorf("Ungültiges Rechnungsdatum: %q", r.Rechnungsdatum)
+	}
+	year, month := datum.Year(), datum.Month()
+	if r.Rechnungsnummer, err = a.nextRechnungsnummer(year); err != nil {
+		return "", err
+	}
+	meta := r.Meta()
+
+	revenueAccount, ok := a.bookingRules.ErloesKonto(meta.VATID, meta.SteuersatzBetrag)
+	if !ok {
+		return "", fmt.Errorf("%s", a.bundle.T("rechnung.norevenue"))
+	}
+	bankAccount := a.settings.DefaultBankAccount
+	booking, bookable, reason := a.computeRevenueBooking(meta.TaxLines, revenueAccount, bankAccount)
+	if !bookable {
+		return "", fmt.Errorf("%s", reason)
+	}
+
+	data, err := core.BuildRechnungPDF(r, a.settings.Firma, a.ownUStID(), a.settings.Rechnungslayout)
+	if err != nil {
+		return "", fmt.Errorf("PDF: %w", err)
+	}
+	if embed {
+		cii, err := core.BuildCIIInvoiceXML(r.EInvoice(a.settings.Firma, a.ownUStID()), core.GuidelineEN16931)
+		if err == nil {
+			data, err = core.EmbedFacturX(data, cii, "Rechnung "+r.Rechnungsnummer, time.Now())
+		}
+		if err != nil {
+			return "", fmt.Errorf("ZUGFeRD: %w", err)
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "buchisy-rechnung-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpPath := filepath.Join(tmpDir, core.SanitizeFilename(r.Rechnungsnummer)+".pdf")
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return "", err
+	}
+
+	belegnr, err := a.dbRepo.NextBelegnummer(fmt.Sprintf("%04d", year))
+	if err != nil {
+		a.logger.Warn("saveRechnung: NextBelegnummer: %v", err)
+		belegnr = ""
+	}
+	tplMeta := meta
+	tplMeta.Belegnummer = belegnr
+	filename, err := core.ApplyTemplate(a.settings.NamingTemplate, tplMeta,
+		core.TemplateOpts{DecimalSeparator: a.settings.DecimalSeparator})
+	if err != nil || strings.TrimSpace(filename) == "" {
+		filename = core.SanitizeFilename(r.Rechnungsnummer) + ".pdf"
+	}
+
+	if err := a.saveInvoice(
+		tmpPath,
+		nil,
+		meta.Auftraggeber,
+		meta.Verwendungszweck,
+		meta.Rechnungsnummer,
+		meta.VATID,
+		meta.Rechnungsdatum,
+		"", // paymentDate: open Forderung
+		meta.TaxLines,
+		0, // trinkgeld
+		meta.Waehrung,
+		revenueAccount,
+		bankAccount,
+		false,         // partialPayment
+		"",            // comment
+		"", "", false, // Bewirtung
+		0,     // netEUR
+		0,     // fee
+		0,     // rabatt
+		0,     // wechselkurs
+		0,     // gebuehrProzent
+		false, // rememberMapping
+		filename,
+		true, // ausgangsrechnung
+		true, // issueNumber
+		year,
+		month,
+		booking,
+		belegnr,
+	); err != nil {
+		return "", err
+	}
+
+	zahlungsziel := r.ZahlungszielTage
+	if zahlungsziel == a.settings.Rechnungslayout.ZahlungszielOrDefault() {
+		zahlungsziel = 0
+	}
+	a.kunden.Set(core.Kunde{Firmendaten: r.Kunde, UStID: r.KundeUStID, Kaeuferreferenz: r.Kaeuferreferenz, ZahlungszielTage: zahlungsziel})
+	if err := a.kunden.Save(); err != nil {
+		a.logger.Warn("Failed to save customers: %v", err)
+	}
+	a.logger.Info("Wrote outgoing invoice %s (%s)", r.Rechnungsnummer, filename)
+	return r.Rechnungsnummer, nil
+}
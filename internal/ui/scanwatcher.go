@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,6 +9,8 @@ import (
 	"time"
 
 	"fyne.io/fyne/v2"
+
+	"github.com/bergx2/buchisy/internal/core"
 )
 
 // scanFileReady reports whether a watched file should be processed now: it
@@ -17,8 +20,15 @@ func scanFileReady(prevSize int64, seenBefore bool, curSize int64, handled bool)
 	return seenBefore && !handled && prevSize == curSize
 }
 
+// isScanInboxFile reports whether a file in the scan inbox is picked up: a
+// scanned PDF or a standalone e-invoice XML dropped there by a mail rule.
+func isScanInboxFile(name string) bool {
+	return core.IsPDF(name) || core.IsXML(name)
+}
+
 // scanWatcher polls the configured scan-inbox folder and feeds new, fully
-// written PDFs into the normal processing flow, one at a time.
+// written PDFs and e-invoice XMLs into the normal processing flow, one at a
+// time.
 type scanWatcher struct {
 	app     *App
 	mu      sync.Mutex
@@ -54,7 +64,7 @@ func (w *scanWatcher) onScanDone() {
 	w.mu.Unlock()
 }
 
-// poll checks the inbox folder once and dispatches at most one ready PDF.
+// poll checks the inbox folder once and dispatches at most one ready file.
 func (w *scanWatcher) poll() {
 	// Read the setting on the Fyne main thread — a.settings is otherwise
 	// only ever touched there (incl. the settings-save), so this avoids a
@@ -78,7 +88,7 @@ func (w *scanWatcher) poll() {
 	w.mu.Lock()
 	var candidate string
 	for _, e := range entries {
-		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".pdf") {
+		if e.IsDir() || !isScanInboxFile(e.Name()) {
 			continue
 		}
 		path := filepath.Join(folder, e.Name())
@@ -106,3 +116,26 @@ func (w *scanWatcher) poll() {
 		})
 	}
 }
+
+// moveOutOfScanInbox moves a scan-inbox file that was not filed as a
+// receipt into the inbox subfolder sub ("aufgeteilt", "duplikate"): the
+// watcher no longer offers it and the scanner original is kept. Files from
+// elsewhere stay where they are.
+func (a *App) moveOutOfScanInbox(path, sub string) {
+	if !a.isFromScanInbox(path) {
+		return
+	}
+	dir := filepath.Join(filepath.Dir(path), sub)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		a.logger.Warn("Failed to move %s to %s: %v", path, sub, err)
+		return
+	}
+	target := filepath.Join(dir, filepath.Base(path))
+	if core.FileExists(target) {
+		ext := filepath.Ext(path)
+		target = filepath.Join(dir, fmt.Sprintf("%s_%s%s", strings.TrimSuffix(filepath.Base(path), ext), time.Now().Format("20060102-150405"), ext))
+	}
+	if err := os.Rename(path, target); err != nil {
+		a.logger.Warn("Failed to move %s to %s: %v", path, sub, err)
+	}
+}
@@ -23,3 +23,18 @@ func TestScanFileReady(t *testing.T) {
 		}
 	}
 }
+
+func TestIsScanInboxFile(t *testing.T) {
+	cases := map[string]bool{
+		"scan_001.pdf":  true,
+		"SCAN.PDF":      true,
+		"xrechnung.xml": true,
+		"foto.jpg":      false,
+		"notiz.txt":     false,
+	}
+	for name, want := range cases {
+		if got := isScanInboxFile(name); got != want {
+			t.Errorf("isScanInboxFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/bergx2/buchisy/internal/core"
 	"github.com/bergx2/buchisy/internal/logging"
+	"github.com/bergx2/buchisy/internal/openai"
 )
 
 // persistBankAccounts writes the given bank account list to disk and
@@ -61,6 +62,19 @@ func (a *App) showSettingsView() {
 		}, a.window)
 	})
 
+	mailInboxEntry := widget.NewEntry()
+	mailInboxEntry.SetText(a.settings.MailInboxFolder)
+	mailInboxEntry.SetPlaceHolder("leer = aus")
+
+	browseMailInboxBtn := widget.NewButton("...", func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			mailInboxEntry.SetText(uri.Path())
+		}, a.window)
+	})
+
 	useMonthFoldersCheck := widget.NewCheck(
 		a.bundle.T("settings.useMonthFolders"),
 		nil,
@@ -102,14 +116,82 @@ func (a *App) showSettingsView() {
 	ownVATIDEntry.SetPlaceHolder("z. B. DE287472874, DE319686097")
 	ownVATIDEntry.SetText(a.settings.OwnVATID)
 
+	istCheck := widget.NewCheck(a.bundle.T("settings.vat.ist"), nil)
+	istCheck.SetChecked(a.settings.IstVersteuerung)
+	istHint := newCopyableLabel(a.bundle, a.bundle.T("settings.vat.ist.hint"))
+	istHint.Wrapping = fyne.TextWrapWord
+	zeitraumSelect := widget.NewSelect([]string{a.bundle.T("settings.vat.monat"), a.bundle.T("settings.vat.quartal")}, nil)
+	zeitraumSelect.SetSelected(a.bundle.T("settings.vat.monat"))
+	if a.settings.VoranmeldungQuartal {
+		zeitraumSelect.SetSelected(a.bundle.T("settings.vat.quartal"))
+	}
+	dfvCheck := widget.NewCheck(a.bundle.T("settings.vat.dfv"), nil)
+	dfvCheck.SetChecked(a.settings.Dauerfristverlaengerung)
+	dfvHint := newCopyableLabel(a.bundle, a.bundle.T("settings.vat.dfv.hint"))
+	dfvHint.Wrapping = fyne.TextWrapWord
+	kuCheck := widget.NewCheck(a.bundle.T("settings.vat.ku"), nil)
+	kuCheck.SetChecked(a.settings.Kleinunternehmer)
+	kuHint := newCopyableLabel(a.bundle, a.bundle.T("settings.vat.ku.hint"))
+	kuHint.Wrapping = fyne.TextWrapWord
+	herstellerEntry := widget.NewEntry()
+	herstellerEntry.SetPlaceHolder("z. B. 40123")
+	herstellerEntry.SetText(a.settings.ElsterHerstellerID)
+	herstellerHint := newCopyableLabel(a.bundle, a.bundle.T("settings.vat.hersteller.hint", core.ElsterTestHerstellerID))
+	herstellerHint.Wrapping = fyne.TextWrapWord
+
+	// Own company data: the seller on generated e-invoices.
+	firma := a.settings.Firma
+	newFirmaEntry := func(value, placeholder string) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetText(value)
+		e.SetPlaceHolder(placeholder)
+		return e
+	}
+	firmaNameEntry := newFirmaEntry(firma.Name, "")
+	firmaStrasseEntry := newFirmaEntry(firma.Strasse, "")
+	firmaPLZEntry := newFirmaEntry(firma.PLZ, "")
+	firmaOrtEntry := newFirmaEntry(firma.Ort, "")
+	firmaLandEntry := newFirmaEntry(firma.Land, "DE")
+	firmaSteuernrEntry := newFirmaEntry(firma.Steuernummer, "z. B. 30/123/45678")
+	firmaFinanzamtEntry := newFirmaEntry(firma.Finanzamt, "z. B. 1130")
+	firmaKontaktEntry := newFirmaEntry(firma.Ansprechpartner, "")
+	firmaTelefonEntry := newFirmaEntry(firma.Telefon, "")
+	firmaEmailEntry := newFirmaEntry(firma.Email, "")
+	firmaIBANEntry := newFirmaEntry(firma.IBAN, "")
+	firmaBICEntry := newFirmaEntry(firma.BIC, "")
+
+	// Invoice writer: number range, payment term and PDF layout.
+	rl := a.settings.Rechnungslayout
+	rlNummernkreisEntry := newFirmaEntry(rl.Nummernkreis, core.DefaultNummernkreis)
+	rlZielEntry := newFirmaEntry("", strconv.Itoa(core.DefaultZahlungszielTage))
+	if rl.ZahlungszielTage > 0 {
+		rlZielEntry.SetText(strconv.Itoa(rl.ZahlungszielTage))
+	}
+	rlEinleitungEntry := widget.NewMultiLineEntry()
+	rlEinleitungEntry.SetText(rl.Einleitung)
+	rlEinleitungEntry.SetMinRowsVisible(2)
+	rlSchlussEntry := widget.NewMultiLineEntry()
+	rlSchlussEntry.SetText(rl.Schlusstext)
+	rlSchlussEntry.SetMinRowsVisible(2)
+	rlFusszeileEntry := widget.NewMultiLineEntry()
+	rlFusszeileEntry.SetText(rl.Fusszeile)
+	rlFusszeileEntry.SetPlaceHolder(a.bundle.T("invoicelayout.footer.placeholder"))
+	rlFusszeileEntry.SetMinRowsVisible(3)
+	rlLogoEntry := newFirmaEntry(rl.LogoPfad, "logo.png")
+	rlAkzentEntry := newFirmaEntry(rl.Akzentfarbe, "#1F4E79")
+
 	// Processing mode
 	modeSelect := widget.NewRadioGroup([]string{
 		a.bundle.T("settings.mode.claude"),
+		a.bundle.T("settings.mode.openai"),
 		a.bundle.T("settings.mode.local"),
 	}, nil)
-	if a.settings.ProcessingMode == "claude" {
+	switch a.settings.ProcessingMode {
+	case core.ProcessingModeClaude:
 		modeSelect.SetSelected(a.bundle.T("settings.mode.claude"))
-	} else {
+	case core.ProcessingModeOpenAI:
+		modeSelect.SetSelected(a.bundle.T("settings.mode.openai"))
+	default:
 		modeSelect.SetSelected(a.bundle.T("settings.mode.local"))
 	}
 
@@ -125,17 +207,40 @@ func (a *App) showSettingsView() {
 		apiKeyEntry.SetText(existingKey)
 	}
 
+	// OpenAI-compatible backend: base URL, model and an optional key
+	openAIURLEntry := widget.NewEntry()
+	openAIURLEntry.SetText(a.settings.OpenAIBaseURL)
+	openAIURLEntry.SetPlaceHolder(openai.DefaultBaseURL)
+	openAIModelEntry := widget.NewEntry()
+	openAIModelEntry.SetText(a.settings.OpenAIModel)
+	openAIModelEntry.SetPlaceHolder("qwen2.5vl:7b")
+	openAIKeyEntry := widget.NewPasswordEntry()
+	openAIKeyEntry.SetPlaceHolder(a.bundle.T("settings.openai.apiKey.placeholder"))
+	if k, err := keyring.Get("BuchISY", a.openAIKeyringAccount()); err == nil {
+		openAIKeyEntry.SetText(k)
+	}
+
 	// Show/hide API key fields based on mode
 	apiKeyContainer := container.NewVBox()
 	updateAPIKeyVisibility := func() {
-		if modeSelect.Selected == a.bundle.T("settings.mode.claude") {
+		switch modeSelect.Selected {
+		case a.bundle.T("settings.mode.claude"):
 			apiKeyContainer.Objects = []fyne.CanvasObject{
 				selectableForm(a.bundle,
 					fi(a.bundle.T("settings.model"), modelEntry),
 					fi(a.bundle.T("settings.apiKey"), apiKeyEntry),
 				),
 			}
-		} else {
+		case a.bundle.T("settings.mode.openai"):
+			apiKeyContainer.Objects = []fyne.CanvasObject{
+				selectableForm(a.bundle,
+					fi(a.bundle.T("settings.openai.baseURL"), openAIURLEntry),
+					fi(a.bundle.T("settings.model"), openAIModelEntry),
+					fi(a.bundle.T("settings.openai.apiKey"), openAIKeyEntry),
+				),
+				widget.NewLabel(a.bundle.T("settings.openai.hint")),
+			}
+		default:
 			apiKeyContainer.Objects = []fyne.CanvasObject{}
 		}
 		apiKeyContainer.Refresh()
@@ -745,6 +850,8 @@ func (a *App) showSettingsView() {
 				container.NewBorder(nil, nil, nil, browseFolderBtn, storageRootEntry)),
 			fi("Scan-Eingang-Ordner",
 				container.NewBorder(nil, nil, nil, browseScanInboxBtn, scanInboxEntry)),
+			fi("E-Mail-Eingang (Maildir/mbox)",
+				container.NewBorder(nil, nil, nil, browseMailInboxBtn, mailInboxEntry)),
 		),
 		useMonthFoldersCheck,
 		widget.NewSeparator(),
@@ -767,6 +874,52 @@ func (a *App) showSettingsView() {
 		),
 		widget.NewSeparator(),
 
+		widget.NewLabel(a.bundle.T("settings.vat")),
+		istCheck,
+		istHint,
+		container.NewBorder(nil, nil, widget.NewLabel(a.bundle.T("settings.vat.zeitraum")), nil, zeitraumSelect),
+		dfvCheck,
+		dfvHint,
+		kuCheck,
+		kuHint,
+		container.NewBorder(nil, nil, widget.NewLabel(a.bundle.T("settings.vat.hersteller")), nil, herstellerEntry),
+		herstellerHint,
+		widget.NewSeparator(),
+
+		widget.NewLabel(a.bundle.T("company.section")),
+		selectableForm(a.bundle,
+			fi(a.bundle.T("company.name"), firmaNameEntry),
+			fi(a.bundle.T("company.street"), firmaStrasseEntry),
+			fi(a.bundle.T("company.zip"), firmaPLZEntry),
+			fi(a.bundle.T("company.city"), firmaOrtEntry),
+			fi(a.bundle.T("company.country"), firmaLandEntry),
+			fi(a.bundle.T("company.taxnumber"), firmaSteuernrEntry),
+			fi(a.bundle.T("company.taxoffice"), firmaFinanzamtEntry),
+			fi(a.bundle.T("company.contact"), firmaKontaktEntry),
+			fi(a.bundle.T("company.phone"), firmaTelefonEntry),
+			fi(a.bundle.T("company.email"), firmaEmailEntry),
+			fi(a.bundle.T("company.iban"), firmaIBANEntry),
+			fi(a.bundle.T("company.bic"), firmaBICEntry),
+		),
+		widget.NewSeparator(),
+
+		widget.NewLabel(a.bundle.T("invoicelayout.section")),
+		selectableForm(a.bundle,
+			fi(a.bundle.T("invoicelayout.numberrange"), rlNummernkreisEntry),
+			fi(a.bundle.T("rechnung.term"), rlZielEntry),
+			fi(a.bundle.T("invoicelayout.intro"), rlEinleitungEntry),
+			fi(a.bundle.T("invoicelayout.closing"), rlSchlussEntry),
+			fi(a.bundle.T("invoicelayout.footer"), rlFusszeileEntry),
+			fi(a.bundle.T("invoicelayout.logo"), rlLogoEntry),
+			fi(a.bundle.T("invoicelayout.accent"), rlAkzentEntry),
+		),
+		widget.NewLabelWithStyle(
+			a.bundle.T("invoicelayout.hint"),
+			fyne.TextAlignLeading,
+			fyne.TextStyle{Italic: true},
+		),
+		widget.NewSeparator(),
+
 		widget.NewLabel(a.bundle.T("settings.csv")),
 		selectableForm(a.bundle,
 			fi(a.bundle.T("settings.csvSeparator"), csvSeparatorSelect),
@@ -1060,19 +1213,54 @@ func (a *App) showSettingsView() {
 
 		newSettings.StorageRoot = storageRootEntry.Text
 		newSettings.ScanInboxFolder = scanInboxEntry.Text
+		newSettings.MailInboxFolder = strings.TrimSpace(mailInboxEntry.Text)
 		newSettings.UseMonthSubfolders = useMonthFoldersCheck.Checked
 		newSettings.NamingTemplate = templateEntry.Text
 		newSettings.DecimalSeparator = decimalSelect.Selected
 		newSettings.CurrencyDefault = currencyEntry.Text
 		newSettings.OwnVATID = strings.TrimSpace(ownVATIDEntry.Text)
+		newSettings.IstVersteuerung = istCheck.Checked
+		newSettings.VoranmeldungQuartal = zeitraumSelect.Selected == a.bundle.T("settings.vat.quartal")
+		newSettings.Dauerfristverlaengerung = dfvCheck.Checked
+		newSettings.Kleinunternehmer = kuCheck.Checked
+		newSettings.ElsterHerstellerID = strings.TrimSpace(herstellerEntry.Text)
+		newSettings.Firma = core.Firmendaten{
+			Name:            strings.TrimSpace(firmaNameEntry.Text),
+			Strasse:         strings.TrimSpace(firmaStrasseEntry.Text),
+			PLZ:             strings.TrimSpace(firmaPLZEntry.Text),
+			Ort:             strings.TrimSpace(firmaOrtEntry.Text),
+			Land:            strings.ToUpper(strings.TrimSpace(firmaLandEntry.Text)),
+			Steuernummer:    strings.TrimSpace(firmaSteuernrEntry.Text),
+			Finanzamt:       strings.TrimSpace(firmaFinanzamtEntry.Text),
+			Ansprechpartner: strings.TrimSpace(firmaKontaktEntry.Text),
+			Telefon:         strings.TrimSpace(firmaTelefonEntry.Text),
+			Email:           strings.TrimSpace(firmaEmailEntry.Text),
+			IBAN:            strings.TrimSpace(firmaIBANEntry.Text),
+			BIC:             strings.TrimSpace(firmaBICEntry.Text),
+		}
+		rlZiel, _ := strconv.Atoi(strings.TrimSpace(rlZielEntry.Text))
+		newSettings.Rechnungslayout = core.Rechnungslayout{
+			Nummernkreis:     strings.TrimSpace(rlNummernkreisEntry.Text),
+			ZahlungszielTage: rlZiel,
+			Einleitung:       strings.TrimSpace(rlEinleitungEntry.Text),
+			Schlusstext:      strings.TrimSpace(rlSchlussEntry.Text),
+			Fusszeile:        strings.TrimSpace(rlFusszeileEntry.Text),
+			LogoPfad:         strings.TrimSpace(rlLogoEntry.Text),
+			Akzentfarbe:      strings.TrimSpace(rlAkzentEntry.Text),
+		}
 
-		if modeSelect.Selected == a.bundle.T("settings.mode.claude") {
-			newSettings.ProcessingMode = "claude"
-		} else {
-			newSettings.ProcessingMode = "local"
+		switch modeSelect.Selected {
+		case a.bundle.T("settings.mode.claude"):
+			newSettings.ProcessingMode = core.ProcessingModeClaude
+		case a.bundle.T("settings.mode.openai"):
+			newSettings.ProcessingMode = core.ProcessingModeOpenAI
+		default:
+			newSettings.ProcessingMode = core.ProcessingModeLocal
 		}
 
 		newSettings.AnthropicModel = modelEntry.Text
+		newSettings.OpenAIBaseURL = strings.TrimSpace(openAIURLEntry.Text)
+		newSettings.OpenAIModel = strings.TrimSpace(openAIModelEntry.Text)
 
 		// CSV settings
 		if csvSeparatorSelect.Selected == "\\t" {
@@ -1093,6 +1281,14 @@ func (a *App) showSettingsView() {
 				)
 			}
 		}
+		// The OpenAI-compatible key is optional: clearing the field removes it.
+		if k := strings.TrimSpace(openAIKeyEntry.Text); k != "" {
+			if err := keyring.Set("BuchISY", a.openAIKeyringAccount(), k); err != nil {
+				a.logger.Warn("Failed to save OpenAI-compatible API key: %v", err)
+			}
+		} else {
+			_ = keyring.Delete("BuchISY", a.openAIKeyringAccount())
+		}
 
 		defaultAccount, _ := strconv.Atoi(defaultAccountEntry.Text)
 		newSettings.DefaultAccount = defaultAccount
@@ -1164,6 +1360,7 @@ func (a *App) showSettingsView() {
 
 		// Update extractor debug flag
 		a.anthropicExtractor.SetDebug(newSettings.DebugMode)
+		a.applyAIProvider()
 
 		// Update CSV column order
 		a.csvRepo.SetColumnOrder(newSettings.ColumnOrder)
@@ -1199,7 +1396,7 @@ func (a *App) showSettingsView() {
 				if p := parseDecimal(bewProzentEntry.Text); p > 0 {
 					rules.Regeln[i].AbziehbarProzent = p
 				}
-			case "reverse_charge":
+			case "reverse_charge", "reverse_charge_eu":
 				rules.Regeln[i].KontoVStRC = vstRC
 				rules.Regeln[i].KontoUStRC = ustRC
 			case "geschenke":
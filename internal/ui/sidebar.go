@@ -100,6 +100,27 @@ func (a *App) activeNavKey() string {
 	}
 }
 
+// finanzamtNavItems returns the FINANZAMT entries: UStVA, its Verprobung and
+// due dates, or for a Kleinunternehmer the § 19 revenue figures — plus the
+// UStVA while the current year has § 13b services, whose tax a
+// Kleinunternehmer still files (§ 18 Abs. 4a UStG); the ZM in both cases.
+func (a *App) finanzamtNavItems() []navItem {
+	if a.settings.Kleinunternehmer {
+		items := []navItem{{"nav.kleinunternehmer", a.showKleinunternehmerDialog}}
+		if a.hatSteuerschuldnerschaft(a.currentYear) {
+			items = append(items, navItem{"nav.ustva", a.showUStVADialog})
+		}
+		return append(items, navItem{"nav.zm", a.showZMDialog})
+	}
+	return []navItem{
+		{"nav.ustva", a.showUStVADialog},
+		{"nav.verprobung", a.showUStVerprobungDialog},
+		{"nav.fristen", a.showUStVAFristen},
+		{"nav.ustjahr", a.showUStJahrDialog},
+		{"nav.zm", a.showZMDialog},
+	}
+}
+
 // buildSidebar returns the persistent workflow navigation column (fixed width).
 // It groups every screen by workflow phase (ERFASSEN, BUCHEN, AUSWERTEN,
 // FINANZAMT, ABSCHLUSS) and renders each group as a bold header followed by
@@ -115,6 +136,7 @@ func (a *App) buildSidebar() fyne.CanvasObject {
 		}},
 		{"nav.group.buchen", []navItem{
 			{"nav.konten", a.openKontenPicker},
+			{"nav.umbuchungen", a.showUmbuchungen},
 			{"nav.belegabgleich", a.showBelegabgleich},
 			{"nav.erloesabgleich", a.showErloesAbgleich},
 			{"nav.anlagen", a.showAnlagen},
@@ -126,12 +148,10 @@ func (a *App) buildSidebar() fyne.CanvasObject {
 			{"nav.controlling", a.showControllingDialog},
 			{"nav.yearoverview", a.showYearOverviewDialog},
 		}},
-		{"nav.group.finanzamt", []navItem{
-			{"nav.ustva", a.showUStVADialog},
-			{"nav.zm", a.showZMDialog},
-		}},
+		{"nav.group.finanzamt", a.finanzamtNavItems()},
 		{"nav.group.abschluss", []navItem{
 			a.lockToggleNavItem(), // lock OR unlock depending on a.currentMonthLocked
+			{"nav.eroeffnung", a.showOpeningBalances},
 			{"nav.audit", a.showAuditLog},
 			{"nav.verfahrensdoku", a.showVerfahrensdokuPDF},
 			{"nav.gobdexport", a.showExportPackage},
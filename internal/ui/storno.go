@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// offerStorno answers an edit or delete attempt in a locked period: the
+// booking stays as it is, but it can be reversed in the running period.
+func (a *App) offerStorno(row core.CSVRow) {
+	if row.IsStorno() || a.dbRepo == nil {
+		a.showInfo(a.bundle.T("period.locked.title"), a.bundle.T("period.locked.msg"))
+		return
+	}
+	dialog.ShowConfirm(a.bundle.T("period.locked.title"), a.bundle.T("storno.offer"), func(ok bool) {
+		if ok {
+			a.showStornoDialog(row)
+		}
+	}, a.window)
+}
+
+// showStornoDialog asks for the Storno date and reason, files the reversing
+// booking with its Eigenbeleg and, if requested, opens the capture dialog
+// with the original receipt for the corrected booking.
+func (a *App) showStornoDialog(row core.CSVRow) {
+	if a.dbRepo == nil {
+		a.showError(a.bundle.T("storno.title"), "Datenbank nicht verfügbar.")
+		return
+	}
+	if row.IsStorno() {
+		a.showInfo(a.bundle.T("storno.title"), a.bundle.T("storno.isStorno", row.StornoZu))
+		return
+	}
+	if existing, err := a.dbRepo.StornoFor(row.StornoKey()); err != nil {
+		a.showError(a.bundle.T("error.processing.title"), err.Error())
+		return
+	} else if existing != "" {
+		a.showInfo(a.bundle.T("storno.title"), a.bundle.T("storno.already", row.StornoKey(), existing))
+		return
+	}
+
+	dateEntry := widget.NewEntry()
+	dateEntry.SetText(time.Now().Format("02.01.2006"))
+	reasonEntry := widget.NewMultiLineEntry()
+	reasonEntry.SetPlaceHolder(a.bundle.T("storno.reason.placeholder"))
+	reasonEntry.SetMinRowsVisible(3)
+	correctCheck := widget.NewCheck(a.bundle.T("storno.correct"), nil)
+	correctCheck.SetChecked(true)
+
+	info := widget.NewLabel(a.bundle.T("storno.intro", row.StornoKey(), row.Auftraggeber,
+		formatMoney(row.Bruttobetrag, row.Waehrung, a.settings.DecimalSeparator), row.Jahr, row.Monat))
+	info.Wrapping = fyne.TextWrapWord
+	form := widget.NewForm(
+		widget.NewFormItem(a.bundle.T("storno.date"), dateEntry),
+		widget.NewFormItem(a.bundle.T("storno.reason"), reasonEntry),
+	)
+	d := dialog.NewCustomConfirm(a.bundle.T("storno.title"), a.bundle.T("storno.confirm"), a.bundle.T("btn.cancel"),
+		container.NewVBox(info, form, correctCheck), func(ok bool) {
+			if !ok {
+				return
+			}
+			datum, err := time.Parse("02.01.2006", strings.TrimSpace(dateEntry.Text))
+			if err != nil {
+				a.showError(a.bundle.T("storno.title"), a.bundle.T("storno.baddate"))
+				return
+			}
+			s, err := a.fileStorno(row, datum, reasonEntry.Text)
+			if err != nil {
+				a.showError(a.bundle.T("error.processing.title"), err.Error())
+				return
+			}
+			a.loadInvoices()
+			a.showToast(a.bundle.T("storno.done", s.Belegnummer, row.StornoKey()))
+			if correctCheck.Checked {
+				a.captureCorrection(row)
+			}
+		}, a.window)
+	d.Resize(fyne.NewSize(560, 380))
+	d.Show()
+}
+
+// fileStorno builds the Storno of row dated datum, writes its Eigenbeleg
+// into the target month and books it.
+func (a *App) fileStorno(row core.CSVRow, datum time.Time, grund string) (core.CSVRow, error) {
+	y, m := datum.Year(), datum.Month()
+	jahr, monat := fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", int(m))
+	if locked, err := a.dbRepo.IsPeriodLocked(jahr, monat); err != nil {
+		return core.CSVRow{}, err
+	} else if locked {
+		return core.CSVRow{}, fmt.Errorf("%s", a.bundle.T("storno.locked", jahr, monat))
+	}
+
+	s := core.BuildStorno(row, datum, grund)
+	belegnr, err := a.dbRepo.NextBelegnummer(jahr)
+	if err != nil {
+		return core.CSVRow{}, fmt.Errorf("Belegnummer: %w", err)
+	}
+	s.Belegnummer = belegnr
+	s.Unterordner = a.invoiceSubfolder(s.Bankkonto, s.Ausgangsrechnung)
+
+	company := a.settings.Firma.Name
+	if strings.TrimSpace(company) == "" {
+		company = a.profile
+	}
+	data, err := core.BuildStornoBelegPDF(s, row, a.chart, company)
+	if err != nil {
+		return core.CSVRow{}, fmt.Errorf("Stornobeleg: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp("", "buchisy-storno-")
+	if err != nil {
+		return core.CSVRow{}, err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpPath := filepath.Join(tmpDir, "storno.pdf")
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return core.CSVRow{}, err
+	}
+
+	folder := a.storageManager.GetMonthFolder(y, m)
+	if s.Unterordner != "" {
+		folder = filepath.Join(folder, s.Unterordner)
+	}
+	name := core.SanitizeFilename(fmt.Sprintf("Storno_%s_zu_%s.pdf", belegnr, strings.TrimSuffix(row.StornoKey(), filepath.Ext(row.StornoKey()))))
+	finalName, err := a.storageManager.MoveAndRename(tmpPath, folder, name)
+	if err != nil {
+		return core.CSVRow{}, fmt.Errorf("Stornobeleg ablegen: %w", err)
+	}
+	s.Dateiname = finalName
+	if _, err := a.dbRepo.Storno(row, s); err != nil {
+		_ = os.Remove(filepath.Join(folder, finalName))
+		return core.CSVRow{}, err
+	}
+	a.recordFingerprints(s, filepath.Join(folder, finalName))
+	if err := a.dbRepo.ExportToCSV(jahr, monat, a.storageManager.GetCSVPath(y, m), a.csvRepo); err != nil {
+		a.logger.Warn("Failed to export to CSV: %v", err)
+	}
+	a.logger.Info("Storno %s for %s filed as %s", s.Belegnummer, s.StornoZu, finalName)
+	return s, nil
+}
+
+// captureCorrection opens the capture dialog for the corrected booking,
+// prefilled from the reversed one, with copies of its receipt files (the
+// archived originals stay where they are).
+func (a *App) captureCorrection(row core.CSVRow) {
+	mainPath := a.resolveInvoicePath(row)
+	if !core.FileExists(mainPath) {
+		a.showError(a.bundle.T("storno.title"), a.bundle.T("storno.nofile", row.Dateiname))
+		return
+	}
+	tmpDir, err := os.MkdirTemp("", "buchisy-korrektur-")
+	if err != nil {
+		a.showError(a.bundle.T("error.processing.title"), err.Error())
+		return
+	}
+	copyTo := func(p string) (string, error) {
+		name, err := a.storageManager.CopyAndRename(p, tmpDir, filepath.Base(p))
+		return filepath.Join(tmpDir, name), err
+	}
+	tmpMain, err := copyTo(mainPath)
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+		a.showError(a.bundle.T("error.processing.title"), err.Error())
+		return
+	}
+	var atts []string
+	for _, p := range a.invoiceAttachmentPaths(row) {
+		if c, err := copyTo(p); err == nil {
+			atts = append(atts, c)
+		}
+	}
+	// The copy is the archived receipt itself: no duplicate warning.
+	a.dupChecked[tmpMain] = true
+
+	meta := row.ToMeta()
+	meta.Belegnummer = ""
+	meta.Dateiname = ""
+	meta.BuchungRef = ""
+	meta.Exportiert = false
+	meta.Kommentar = strings.TrimSpace("Korrektur zu Beleg " + row.StornoKey() + ". " + row.Kommentar)
+	a.showConfirmationModal(tmpMain, atts, meta, func() { _ = os.RemoveAll(tmpDir) })
+}
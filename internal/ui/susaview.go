@@ -14,7 +14,7 @@ import (
 // showSuSa displays the Summen-/Saldenliste for the current year.
 func (a *App) showSuSa() {
 	year := a.currentYear
-	rows := a.collectInvoiceRows(year, 1, year, 12)
+	rows := a.collectBookingRows(year, 1, year, 12)
 	bals := core.ComputeSuSa(rows, a.chart)
 
 	fmtAmt := func(v float64) string {
@@ -24,14 +24,16 @@ func (a *App) showSuSa() {
 	headers := []string{
 		a.bundle.T("susa.col.konto"),
 		a.bundle.T("susa.col.name"),
+		a.bundle.T("susa.col.eb"),
 		a.bundle.T("susa.col.soll"),
 		a.bundle.T("susa.col.haben"),
 		a.bundle.T("susa.col.saldo"),
 	}
 
 	// Compute totals
-	var totalSoll, totalHaben, totalSaldo float64
+	var totalEB, totalSoll, totalHaben, totalSaldo float64
 	for _, b := range bals {
+		totalEB += b.EBWert
 		totalSoll += b.SollSumme
 		totalHaben += b.HabenSumme
 		totalSaldo += b.Saldo
@@ -41,7 +43,7 @@ func (a *App) showSuSa() {
 	totalRows := numDataRows + 1  // +1 for header
 
 	tbl := widget.NewTable(
-		func() (int, int) { return totalRows, 5 },
+		func() (int, int) { return totalRows, 6 },
 		func() fyne.CanvasObject { return newHoverLabel(nil, nil) },
 		func(id widget.TableCellID, o fyne.CanvasObject) {
 			hl := o.(*hoverLabel)
@@ -71,11 +73,14 @@ func (a *App) showSuSa() {
 					hl.SetText("")
 				case 2:
 					hl.Alignment = fyne.TextAlignTrailing
-					hl.SetText(fmtAmt(totalSoll))
+					hl.SetText(fmtAmt(totalEB))
 				case 3:
 					hl.Alignment = fyne.TextAlignTrailing
-					hl.SetText(fmtAmt(totalHaben))
+					hl.SetText(fmtAmt(totalSoll))
 				case 4:
+					hl.Alignment = fyne.TextAlignTrailing
+					hl.SetText(fmtAmt(totalHaben))
+				case 5:
 					hl.Alignment = fyne.TextAlignTrailing
 					hl.SetText(fmtAmt(totalSaldo))
 				}
@@ -90,11 +95,14 @@ func (a *App) showSuSa() {
 				hl.SetText(b.Name)
 			case 2:
 				hl.Alignment = fyne.TextAlignTrailing
-				hl.SetText(fmtAmt(b.SollSumme))
+				hl.SetText(fmtAmt(b.EBWert))
 			case 3:
 				hl.Alignment = fyne.TextAlignTrailing
-				hl.SetText(fmtAmt(b.HabenSumme))
+				hl.SetText(fmtAmt(b.SollSumme))
 			case 4:
+				hl.Alignment = fyne.TextAlignTrailing
+				hl.SetText(fmtAmt(b.HabenSumme))
+			case 5:
 				hl.Alignment = fyne.TextAlignTrailing
 				hl.SetText(fmtAmt(b.Saldo))
 			}
@@ -105,9 +113,10 @@ func (a *App) showSuSa() {
 	tbl.SetColumnWidth(2, 100)
 	tbl.SetColumnWidth(3, 100)
 	tbl.SetColumnWidth(4, 100)
+	tbl.SetColumnWidth(5, 100)
 
 	scroll := container.NewVScroll(tbl)
-	scroll.SetMinSize(fyne.NewSize(640, 380))
+	scroll.SetMinSize(fyne.NewSize(740, 380))
 
 	pdfBtn := widget.NewButton(a.bundle.T("report.pdf"), func() {
 		title := fmt.Sprintf("%s %d", a.bundle.T("susa.title"), year)
@@ -134,14 +143,14 @@ func (a *App) showSuSa() {
 		content,
 		a.window,
 	)
-	d.Resize(fyne.NewSize(700, 520))
+	d.Resize(fyne.NewSize(800, 520))
 	d.Show()
 }
 
 // showGuV displays the Gewinn- und Verlustrechnung for the current year.
 func (a *App) showGuV() {
 	year := a.currentYear
-	rows := a.collectInvoiceRows(year, 1, year, 12)
+	rows := a.collectBookingRows(year, 1, year, 12)
 	bals := core.ComputeSuSa(rows, a.chart)
 	g := core.ComputeGuV(bals, a.chart)
 
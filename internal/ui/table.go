@@ -951,6 +951,23 @@ func (r *rightClickTable) TappedSecondary(e *fyne.PointEvent) {
 			}
 		}
 
+		// "E-Rechnung erzeugen" — ZUGFeRD/XRechnung for outgoing invoices.
+		if row.Ausgangsrechnung {
+			items = append(items, fyne.NewMenuItemSeparator(),
+				fyne.NewMenuItem(it.bundle.T("einvoice.out.menu"), func() {
+					it.app.showEInvoiceExport(row)
+				}))
+		}
+
+		// "Stornieren …" — reverses the booking in the running period; the
+		// way to correct a booking in a locked (festgeschrieben) month.
+		if !row.IsStorno() {
+			items = append(items, fyne.NewMenuItemSeparator(),
+				fyne.NewMenuItem(it.bundle.T("table.storno"), func() {
+					it.app.showStornoDialog(row)
+				}))
+		}
+
 		// "Verknüpfung entfernen" — only shown when the invoice is linked to
 		// a statement line. Clears BuchungRef after confirmation.
 		if row.BuchungRef != "" {
@@ -1263,12 +1280,12 @@ func (it *InvoiceTable) valueForColumn(row core.CSVRow, colID string) string {
 	case "Kommentar":
 		return row.Kommentar
 	case "BetragNetto_EUR":
-		if row.BetragNetto_EUR > 0 {
+		if row.BetragNetto_EUR != 0 {
 			return it.formatMoneyCell(row.BetragNetto_EUR, "EUR")
 		}
 		return ""
 	case "Gebuehr":
-		if row.Gebuehr > 0 {
+		if row.Gebuehr != 0 {
 			return it.formatAmount(row.Gebuehr)
 		}
 		return ""
@@ -1301,6 +1318,8 @@ func (it *InvoiceTable) valueForColumn(row core.CSVRow, colID string) string {
 			return "✓ " + it.bundle.T("status.cashCovered")
 		}
 		return "○" // unlinked
+	case "StornoZu":
+		return row.StornoZu
 	default:
 		return ""
 	}
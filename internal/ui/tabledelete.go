@@ -34,7 +34,7 @@ func (a *App) unlinkInvoice(row core.CSVRow) {
 // showDeleteConfirmation shows a confirmation dialog before deleting an invoice.
 func (a *App) showDeleteConfirmation(row core.CSVRow) {
 	if a.currentMonthLocked {
-		a.showInfo(a.bundle.T("period.locked.title"), a.bundle.T("period.locked.msg"))
+		a.offerStorno(row)
 		return
 	}
 	message := a.bundle.T(
@@ -173,6 +173,7 @@ func (a *App) undoDelete(row core.CSVRow, jahr, monat, filePath string, data []b
 		return
 	}
 	a.logger.Info("Undo: re-inserted invoice %s", row.Dateiname)
+	a.recordFingerprints(row, filePath)
 
 	// Regenerate the CSV for the month the invoice BELONGED to (captured at
 	// delete time) — the user may have navigated to another month during the
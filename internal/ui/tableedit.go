@@ -66,7 +66,7 @@ func (a *App) resolveInvoicePath(row core.CSVRow) string {
 // with a document preview on the right.
 func (a *App) showEditDialog(row core.CSVRow, onClose func()) {
 	if a.currentMonthLocked {
-		a.showInfo(a.bundle.T("period.locked.title"), a.bundle.T("period.locked.msg"))
+		a.offerStorno(row)
 		return
 	}
 
@@ -537,7 +537,7 @@ func (a *App) showEditDialog(row core.CSVRow, onClose func()) {
 					parseFloat(rabattEntry.Text, a.settings.DecimalSeparator))
 			}
 		}
-		a.showBookingEditor(seed, editWin, func(edited core.Booking) {
+		a.showBookingEditor(seed, nil, editWin, func(edited core.Booking) {
 			manualBooking = &edited
 			recomputeBooking()
 		})
@@ -557,7 +557,7 @@ func (a *App) showEditDialog(row core.CSVRow, onClose func()) {
 	warningsLabel.Hide()
 
 	refreshWarnings = func() {
-		warnings := core.InvoiceWarnings(core.CSVRow{
+		warnings := a.invoiceWarnings(core.CSVRow{
 			BetragNetto:              core.SumNetto(ed.Lines()),
 			SteuersatzBetrag:         core.SumMwSt(ed.Lines()),
 			Bruttobetrag:             ed.Brutto(),
@@ -1065,51 +1065,67 @@ func (a *App) showEditDialog(row core.CSVRow, onClose func()) {
 				finalBooking = b
 			}
 		}
-		err := a.updateInvoice(
-			row,
-			originalPath,
-			companyEntry.Text,
-			shortDescEntry.Text,
-			invoiceNumEntry.Text,
-			vatIDEntry.Text,
-			dateEntry.Text,
-			paymentDateEntry.Text,
-			ed.Lines(),
-			ed.Trinkgeld(),
-			core.CurrencyCodeFromOption(currencySelect.Selected),
-			selectedAccount,
-			bankAccountSelect.Selected,
-			partialPaymentCheck.Checked,
-			commentEntry.Text,
-			strings.TrimSpace(anlassEntry.Text),
-			strings.TrimSpace(teilnehmerEntry.Text),
-			aufBelegCheck.Checked,
-			parseFloat(netEUREntry.Text, a.settings.DecimalSeparator),
-			parseFloat(feeEntry.Text, a.settings.DecimalSeparator),
-			parseFloat(rabattEntry.Text, a.settings.DecimalSeparator),
-			parseDecimal(kursEntry.Text),
-			parseDecimal(feePctEntry.Text),
-			filenameEntry.Text,
-			targetYear,
-			targetMonth,
-			ausgangsrechnungCheck.Checked,
-			finalBooking,
-			belegnrEntry.Text,
-		)
-		if err != nil {
-			dialog.ShowInformation(a.bundle.T("error.processing.title"), err.Error(), editWin)
-			return
+
+		doSave := func() {
+			err := a.updateInvoice(
+				row,
+				originalPath,
+				companyEntry.Text,
+				shortDescEntry.Text,
+				invoiceNumEntry.Text,
+				vatIDEntry.Text,
+				dateEntry.Text,
+				paymentDateEntry.Text,
+				ed.Lines(),
+				ed.Trinkgeld(),
+				core.CurrencyCodeFromOption(currencySelect.Selected),
+				selectedAccount,
+				bankAccountSelect.Selected,
+				partialPaymentCheck.Checked,
+				commentEntry.Text,
+				strings.TrimSpace(anlassEntry.Text),
+				strings.TrimSpace(teilnehmerEntry.Text),
+				aufBelegCheck.Checked,
+				parseFloat(netEUREntry.Text, a.settings.DecimalSeparator),
+				parseFloat(feeEntry.Text, a.settings.DecimalSeparator),
+				parseFloat(rabattEntry.Text, a.settings.DecimalSeparator),
+				parseDecimal(kursEntry.Text),
+				parseDecimal(feePctEntry.Text),
+				filenameEntry.Text,
+				targetYear,
+				targetMonth,
+				ausgangsrechnungCheck.Checked,
+				finalBooking,
+				belegnrEntry.Text,
+			)
+			if err != nil {
+				dialog.ShowInformation(a.bundle.T("error.processing.title"), err.Error(), editWin)
+				return
+			}
+			// Learn the booking template for this company on successful update
+			// (only when using the auto path — skip when a manual booking was set).
+			if learn && companyEntry.Text != "" {
+				_ = a.bookingTemplates.Set(companyEntry.Text, core.BookingTemplate{
+					Kategorie:    catKeyByLabel[categorySelect.Selected],
+					ExpenseKonto: selectedAccount,
+				})
+			}
+			a.loadInvoices()
+			editWin.Close()
 		}
-		// Learn the booking template for this company on successful update
-		// (only when using the auto path — skip when a manual booking was set).
-		if learn && companyEntry.Text != "" {
-			_ = a.bookingTemplates.Set(companyEntry.Text, core.BookingTemplate{
-				Kategorie:    catKeyByLabel[categorySelect.Selected],
-				ExpenseKonto: selectedAccount,
-			})
+
+		// Saving into (or moving out of) a filed period changes a filed return.
+		warnings := a.gemeldetWarnungen(fmt.Sprintf("%04d-%02d", targetYear, int(targetMonth)), row.Jahr+"-"+row.Monat)
+		if len(warnings) > 0 {
+			msg := a.bundle.T("warnings.intro") + "\n• " + strings.Join(warnings, "\n• ")
+			dialog.NewConfirm(a.bundle.T("warnings.title"), msg, func(ok bool) {
+				if ok {
+					doSave()
+				}
+			}, editWin).Show()
+			return
 		}
-		a.loadInvoices()
-		editWin.Close()
+		doSave()
 	}
 
 	deleteBtn := widget.NewButton("Löschen", func() {
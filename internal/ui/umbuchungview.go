@@ -0,0 +1,203 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+	"github.com/bergx2/buchisy/internal/db"
+)
+
+// showUmbuchungen opens the journal of manual entries (Umbuchungen) of the
+// current year.
+func (a *App) showUmbuchungen() {
+	if a.dbRepo == nil {
+		a.showError(a.bundle.T("umbuchung.title"), "Datenbank nicht verfügbar.")
+		return
+	}
+	win := a.app.NewWindow(fmt.Sprintf("%s %d", a.bundle.T("umbuchung.title"), a.currentYear))
+	listBox := container.NewVBox()
+	var refresh func()
+
+	bold := func(key string, align fyne.TextAlign) *widget.Label {
+		return widget.NewLabelWithStyle(a.bundle.T(key), align, fyne.TextStyle{Bold: true})
+	}
+	refresh = func() {
+		us, err := a.dbRepo.Umbuchungen(fmt.Sprintf("%04d-01", a.currentYear), fmt.Sprintf("%04d-12", a.currentYear))
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		objs := []fyne.CanvasObject{
+			container.NewGridWithColumns(5,
+				bold("umbuchung.col.beleg", fyne.TextAlignLeading),
+				bold("umbuchung.col.datum", fyne.TextAlignLeading),
+				bold("umbuchung.col.text", fyne.TextAlignLeading),
+				bold("umbuchung.col.betrag", fyne.TextAlignTrailing),
+				bold("umbuchung.col.konten", fyne.TextAlignLeading),
+			),
+			widget.NewSeparator(),
+		}
+		if len(us) == 0 {
+			objs = append(objs, widget.NewLabel(a.bundle.T("umbuchung.empty")))
+		}
+		for _, u := range us {
+			u := u
+			beleg := u.Belegnummer
+			if u.Exportiert {
+				beleg += " ✓"
+			}
+			text := newCopyableLabel(a.bundle, u.Text)
+			text.Wrapping = fyne.TextWrapWord
+			betrag := newCopyableLabel(a.bundle, formatMoney(u.Buchung.SollSum(), "EUR", a.settings.DecimalSeparator))
+			betrag.Alignment = fyne.TextAlignTrailing
+			editBtn := widget.NewButton(a.bundle.T("umbuchung.edit"), func() { a.showUmbuchungForm(win, u, refresh) })
+			editBtn.Importance = widget.LowImportance
+			delBtn := widget.NewButton(a.bundle.T("umbuchung.delete"), func() { a.deleteUmbuchung(win, u, refresh) })
+			delBtn.Importance = widget.LowImportance
+			objs = append(objs,
+				container.NewGridWithColumns(5,
+					newCopyableLabel(a.bundle, beleg), newCopyableLabel(a.bundle, u.Datum), text, betrag,
+					widget.NewLabel(umbuchungKonten(u.Buchung))),
+				container.NewHBox(editBtn, delBtn))
+		}
+		listBox.Objects = objs
+		listBox.Refresh()
+	}
+	refresh()
+
+	scroll := container.NewVScroll(listBox)
+	scroll.SetMinSize(fyne.NewSize(760, 320))
+	neuBtn := widget.NewButton(a.bundle.T("umbuchung.new"), func() {
+		a.showUmbuchungForm(win, core.Umbuchung{Datum: time.Now().Format("02.01.2006")}, refresh)
+	})
+	neuBtn.Importance = widget.HighImportance
+	closeBtn := widget.NewButton(a.bundle.T("common.close"), func() { win.Close() })
+	closeBtn.Importance = widget.LowImportance
+
+	win.SetContent(container.NewPadded(container.NewBorder(nil,
+		container.NewPadded(container.NewHBox(neuBtn, widget.NewSeparator(), closeBtn)), nil, nil, scroll)))
+	win.Resize(fyne.NewSize(820, 480))
+	win.CenterOnScreen()
+	win.Show()
+}
+
+// umbuchungKonten summarises the entry lines as "Soll an Haben" accounts.
+func umbuchungKonten(b core.Booking) string {
+	var soll, haben []string
+	for _, e := range b.Entries {
+		if e.Soll {
+			soll = append(soll, fmt.Sprintf("%d", e.Konto))
+		} else {
+			haben = append(haben, fmt.Sprintf("%d", e.Konto))
+		}
+	}
+	return strings.Join(soll, ", ") + " an " + strings.Join(haben, ", ")
+}
+
+// showUmbuchungForm edits date, text and lines of u (ID 0 = new entry). The
+// lines are edited with the booking editor; saving validates the entry
+// (balanced, one single-account side) and books it.
+func (a *App) showUmbuchungForm(parent fyne.Window, u core.Umbuchung, onSaved func()) {
+	isNew := u.ID == 0
+	title := a.bundle.T("umbuchung.form.new")
+	if !isNew {
+		title = a.bundle.T("umbuchung.form.edit", u.Belegnummer)
+	}
+	dateEntry := widget.NewEntry()
+	dateEntry.SetText(u.Datum)
+	dateEntry.SetPlaceHolder("TT.MM.JJJJ")
+	textEntry := widget.NewEntry()
+	textEntry.SetText(u.Text)
+	textEntry.SetPlaceHolder(a.bundle.T("umbuchung.form.text.placeholder"))
+
+	booking := u.Buchung
+	linesLabel := widget.NewLabel("")
+	linesLabel.Wrapping = fyne.TextWrapWord
+	showLines := func() {
+		if len(booking.Entries) == 0 {
+			linesLabel.SetText(a.bundle.T("umbuchung.form.nolines"))
+			return
+		}
+		var sb strings.Builder
+		for _, e := range booking.Entries {
+			side := a.bundle.T("booking.haben")
+			if e.Soll {
+				side = a.bundle.T("booking.soll")
+			}
+			fmt.Fprintf(&sb, "%s  %s  %s\n", side, a.bookingKontoLabel(e.Konto),
+				formatMoney(e.Betrag, "EUR", a.settings.DecimalSeparator))
+		}
+		if booking.Balanced() {
+			sb.WriteString(a.bundle.T("booking.balanced"))
+		} else {
+			sb.WriteString(a.bundle.T("booking.editor.diff",
+				strings.Replace(fmt.Sprintf("%.2f", booking.SollSum()-booking.HabenSum()), ".", ",", 1)))
+		}
+		linesLabel.SetText(sb.String())
+	}
+	showLines()
+	editLines := widget.NewButton(a.bundle.T("umbuchung.form.lines"), func() {
+		a.showBookingEditor(booking, nil, parent, func(b core.Booking) {
+			booking = b
+			showLines()
+		})
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem(a.bundle.T("umbuchung.col.datum"), dateEntry),
+		widget.NewFormItem(a.bundle.T("umbuchung.col.text"), textEntry),
+	)
+	content := container.NewVBox(form, linesLabel, editLines)
+	d := dialog.NewCustomConfirm(title, a.bundle.T("btn.save"), a.bundle.T("btn.cancel"), content, func(ok bool) {
+		if !ok {
+			return
+		}
+		u.Datum = strings.TrimSpace(dateEntry.Text)
+		u.Text = strings.TrimSpace(textEntry.Text)
+		u.Buchung = booking
+		var err error
+		if isNew {
+			u, err = a.dbRepo.InsertUmbuchung(u)
+		} else {
+			err = a.dbRepo.UpdateUmbuchung(u)
+		}
+		if errors.Is(err, db.ErrPeriodLocked) {
+			jahr, monat, _ := u.Period()
+			err = fmt.Errorf("%s", a.bundle.T("umbuchung.locked", jahr, monat))
+		}
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+		a.showToast(a.bundle.T("umbuchung.saved", u.Belegnummer))
+		onSaved()
+	}, parent)
+	d.Resize(fyne.NewSize(560, 420))
+	d.Show()
+}
+
+// deleteUmbuchung asks for confirmation and deletes u.
+func (a *App) deleteUmbuchung(parent fyne.Window, u core.Umbuchung, onDeleted func()) {
+	dialog.ShowConfirm(a.bundle.T("umbuchung.delete"), a.bundle.T("umbuchung.delete.confirm", u.Belegnummer), func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := a.dbRepo.DeleteUmbuchung(u.ID); err != nil {
+			if errors.Is(err, db.ErrPeriodLocked) {
+				jahr, monat, _ := u.Period()
+				err = fmt.Errorf("%s", a.bundle.T("umbuchung.locked", jahr, monat))
+			}
+			dialog.ShowError(err, parent)
+			return
+		}
+		onDeleted()
+	}, parent)
+}
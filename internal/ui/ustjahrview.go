@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// showUStJahrDialog shows the annual VAT return of the current year next to
+// the sum of its monthly or quarterly Voranmeldungen. Differences and
+// receipts dated in a locked period but filed later are highlighted.
+func (a *App) showUStJahrDialog() {
+	year := a.currentYear
+	fmtAmt := func(v float64) string {
+		return formatMoney(v, "EUR", a.settings.DecimalSeparator)
+	}
+
+	locked := map[string]bool{}
+	if a.dbRepo != nil {
+		perioden, err := a.dbRepo.LockedPeriods()
+		if err != nil {
+			a.logger.Warn("USt-Jahreserklärung: festgeschriebene Perioden: %v", err)
+		}
+		for _, p := range perioden {
+			locked[p] = true
+		}
+	}
+	j := core.ComputeUStJahreserklaerung(a.vatRows(year, 1, year, 12), a.bookingRules, year,
+		a.settings.VoranmeldungQuartal, a.settings.IstVersteuerung, func(p string) bool { return locked[p] })
+
+	body := container.NewVBox()
+
+	bold := func(s string) *widget.Label {
+		return widget.NewLabelWithStyle(s, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	warn := func(s string) *copyableLabel {
+		l := newCopyableLabel(a.bundle, s)
+		l.Importance = widget.WarningImportance
+		return l
+	}
+
+	// Reconciliation: Kz | annual Kz | label | year | Σ Voranmeldungen | difference
+	grid := container.NewGridWithColumns(6,
+		bold(a.bundle.T("ustjahr.col.kz")), bold(a.bundle.T("ustjahr.col.jahrkz")), bold(a.bundle.T("ustjahr.col.bezeichnung")),
+		bold(a.bundle.T("ustjahr.col.jahr")), bold(a.bundle.T("ustjahr.col.summe")), bold(a.bundle.T("ustjahr.col.differenz")))
+	differenzen := 0
+	for _, z := range core.UStJahrZeilen {
+		wert, summe, diff := j.Erklaerung.Wert(z.Kz), j.Summe.Wert(z.Kz), j.Differenz(z.Kz)
+		if wert == 0 && summe == 0 && z.Kz != "83" {
+			continue
+		}
+		jahrKz := "—"
+		if z.JahrKz != "" {
+			jahrKz = "Kz " + z.JahrKz
+		}
+		diffLbl := newCopyableLabel(a.bundle, fmtAmt(diff))
+		if diff != 0 {
+			diffLbl.Importance = widget.WarningImportance
+			differenzen++
+		}
+		grid.Add(newCopyableLabel(a.bundle, "Kz "+z.Kz))
+		grid.Add(newCopyableLabel(a.bundle, jahrKz))
+		grid.Add(newCopyableLabel(a.bundle, z.Bezeichnung))
+		grid.Add(newCopyableLabel(a.bundle, fmtAmt(wert)))
+		grid.Add(newCopyableLabel(a.bundle, fmtAmt(summe)))
+		grid.Add(diffLbl)
+	}
+	body.Add(grid)
+	if differenzen > 0 {
+		hint := warn(a.bundle.T("ustjahr.differenzen", differenzen))
+		hint.Wrapping = fyne.TextWrapWord
+		body.Add(hint)
+	}
+	body.Add(widget.NewSeparator())
+
+	body.Add(newCopyableLabel(a.bundle, a.bundle.T("ustjahr.vorauszahlungssoll", fmtAmt(j.Vorauszahlungssoll))))
+	if j.Abschlusszahlung < 0 {
+		body.Add(bold(a.bundle.T("ustjahr.erstattung", fmtAmt(-j.Abschlusszahlung))))
+	} else {
+		body.Add(bold(a.bundle.T("ustjahr.abschlusszahlung", fmtAmt(j.Abschlusszahlung))))
+	}
+	body.Add(widget.NewSeparator())
+
+	body.Add(bold(a.bundle.T("ustjahr.voranmeldungen")))
+	for _, p := range j.Voranmeldungen {
+		line := fmt.Sprintf("    %04d-%s    Kz 83 %s", year, p.Zeitraum(), fmtAmt(p.UStVA.Kz83))
+		nach := j.NachbuchungenIn(p)
+		if len(nach) == 0 {
+			body.Add(newCopyableLabel(a.bundle, line))
+			continue
+		}
+		var kz83 float64
+		for _, n := range nach {
+			kz83 += n.UStVA().Kz83
+		}
+		body.Add(warn(line + "    " + a.bundle.T("ustjahr.nachbuchungen.count", len(nach), fmtAmt(kz83))))
+	}
+
+	if len(j.Nachbuchungen) > 0 {
+		body.Add(widget.NewSeparator())
+		body.Add(bold(a.bundle.T("ustjahr.nachbuchungen")))
+		for _, n := range j.Nachbuchungen {
+			var kz []string
+			for _, b := range n.Beitraege() {
+				kz = append(kz, fmt.Sprintf("Kz %s %s", b.Kz, fmtAmt(b.Betrag)))
+			}
+			text := a.bundle.T("ustjahr.nachbuchung", n.Belegnummer, n.Rechnungsdatum, n.Auftraggeber,
+				n.Belegperiode, n.Buchungsperiode, strings.Join(kz, "; "))
+			if n.Geschaetzt {
+				text += " " + a.bundle.T("ustjahr.geschaetzt")
+			}
+			l := warn("    " + text)
+			l.Wrapping = fyne.TextWrapWord
+			body.Add(l)
+		}
+	}
+
+	pdfBtn := widget.NewButton(a.bundle.T("report.pdf"), func() {
+		data, err := core.BuildUStJahrPDF(j, a.bundle.T("ustjahr.title", year), a.profile)
+		if err != nil {
+			a.showError(a.bundle.T("error.processing.title"), err.Error())
+			return
+		}
+		a.savePDF(fmt.Sprintf("USt-Jahreserklaerung_%04d.pdf", year), data)
+	})
+	xmlBtn := widget.NewButton(a.bundle.T("report.xml"), func() {
+		data, err := core.BuildUStJahrXML(j, a.settings.OwnVATID)
+		if err != nil {
+			a.showError(a.bundle.T("error.processing.title"), err.Error())
+			return
+		}
+		a.saveFile(fmt.Sprintf("USt-Jahreserklaerung_%04d.xml", year), data)
+	})
+
+	basis := a.bundle.T("ustva.soll")
+	if a.settings.IstVersteuerung {
+		basis = a.bundle.T("ustva.ist")
+	}
+	header := container.NewVBox(
+		bold(a.bundle.T("ustjahr.heading")),
+		container.NewBorder(nil, nil, nil, container.NewHBox(xmlBtn, pdfBtn), widget.NewLabel(basis)),
+	)
+	content := container.NewBorder(header, nil, nil, nil, container.NewVScroll(body))
+	d := dialog.NewCustom(a.bundle.T("ustjahr.title", year), a.bundle.T("common.close"), content, a.window)
+	d.Resize(fyne.NewSize(900, 600))
+	d.Show()
+}
@@ -2,6 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -15,7 +18,10 @@ import (
 // year using the official ELSTER Kennzahlen (net bases + derived VAT + Zahllast).
 func (a *App) showUStVADialog() {
 	// period: 0 = month, 1 = quarter, 2 = year
-	period := 0 // default: month (UStVA is filed monthly or quarterly)
+	period := 0 // default: the profile's Voranmeldungszeitraum
+	if a.settings.VoranmeldungQuartal {
+		period = 1
+	}
 
 	body := container.NewVBox()
 	scroll := container.NewVScroll(body)
@@ -50,9 +56,21 @@ func (a *App) showUStVADialog() {
 		body.Add(widget.NewSeparator())
 	}
 
-	var u core.UStVAOfficial // current period's result, for the PDF export
+	var u core.UStVAOfficial   // current period's result, for the PDF export
+	var vonMonat, bisMonat int // current period's months, for the ELSTER export
+	var berichtigt bool        // the ELSTER export is a Berichtigung (Kz 10)
+
+	var reload func()
+	meldung, markBtn, updateMeldung := a.meldungPanel(func() { reload() })
+
+	geschaetztLbl := widget.NewLabel("")
+	geschaetztLbl.Importance = widget.WarningImportance
+	geschaetztLbl.Wrapping = fyne.TextWrapWord
+	ohneKontoLbl := widget.NewLabel("")
+	ohneKontoLbl.Importance = widget.WarningImportance
+	ohneKontoLbl.Wrapping = fyne.TextWrapWord
 
-	reload := func() {
+	reload = func() {
 		fromY, fromM, toY, toM := a.currentYear, int(a.currentMonth), a.currentYear, int(a.currentMonth)
 		switch period {
 		case 1: // quarter: calendar quarter containing currentMonth
@@ -62,11 +80,45 @@ func (a *App) showUStVADialog() {
 		case 2: // year
 			fromM, toM = 1, 12
 		}
-		rows := a.collectInvoiceRows(fromY, fromM, toY, toM)
+		vonMonat, bisMonat = fromM, toM
+		rows := a.vatRows(fromY, fromM, toY, toM)
 		u = core.ComputeUStVAOfficial(rows, a.bookingRules)
+		u.Ist = a.settings.IstVersteuerung
+		if period == 0 && toM == 12 {
+			if svz := a.sondervorauszahlung(fromY); svz > 0 {
+				u = u.MitSondervorauszahlung(svz)
+			}
+		}
+		zeitraum := ""
+		switch period {
+		case 0:
+			zeitraum = fmt.Sprintf("%04d-%02d", fromY, fromM)
+		case 1:
+			zeitraum = fmt.Sprintf("%04d-Q%d", fromY, (fromM-1)/3+1)
+		}
+		// A filed period is berichtigt only when its values changed, or when the
+		// last filing already was a Berichtigung; an unchanged re-export
+		// repeats that filing.
+		abw, gemeldet := updateMeldung(core.NewUStVAMeldung(zeitraum, u, rows, a.bookingRules))
+		berichtigt = abw.Abweichend() || (gemeldet && abw.Gemeldet.Berichtigung())
 
 		body.Objects = nil
 
+		// Rows without a mapped booking entry were classified from the
+		// invoice data; name them so the booking can be checked.
+		if g := core.UStVAGeschaetzt(rows, a.bookingRules); len(g) > 0 {
+			geschaetztLbl.SetText(a.bundle.T("ustva.geschaetzt", len(g), strings.Join(g, ", ")))
+			body.Add(geschaetztLbl)
+			body.Add(widget.NewSeparator())
+		}
+		// Kennzahlen no account reports to stay empty whatever is booked;
+		// say so instead of filing them as 0.
+		if kz := a.bookingRules.KennzahlenOhneKonto(); len(kz) > 0 {
+			ohneKontoLbl.SetText(a.bundle.T("ustva.ohnekonto", strings.Join(kz, ", ")))
+			body.Add(ohneKontoLbl)
+			body.Add(widget.NewSeparator())
+		}
+
 		// A. Umsätze
 		addSection("ustva.sectionA", []struct {
 			kzKey  string
@@ -77,8 +129,39 @@ func (a *App) showUStVADialog() {
 		}{
 			{"ustva.kz81", "Kz 81", u.Kz81, "ustva.ust", u.USt81},
 			{"ustva.kz86", "Kz 86", u.Kz86, "ustva.ust", u.USt86},
+			{"ustva.kz35", "Kz 35", u.Kz35, "", 0},
+			{"ustva.kz36", "Kz 36", u.Kz36, "", 0},
 		})
 
+		// B. Steuerfreie Umsätze mit Vorsteuerabzug
+		if u.Kz41 != 0 || u.Kz44 != 0 || u.Kz43 != 0 {
+			addSection("ustva.sectionB", []struct {
+				kzKey  string
+				kzNum  string
+				val    float64
+				ustKey string
+				ustVal float64
+			}{
+				{"ustva.kz41", "Kz 41", u.Kz41, "", 0},
+				{"ustva.kz44", "Kz 44", u.Kz44, "", 0},
+				{"ustva.kz43", "Kz 43", u.Kz43, "", 0},
+			})
+		}
+
+		// C. Innergemeinschaftliche Erwerbe
+		if u.Kz89 != 0 || u.Kz93 != 0 {
+			addSection("ustva.sectionC", []struct {
+				kzKey  string
+				kzNum  string
+				val    float64
+				ustKey string
+				ustVal float64
+			}{
+				{"ustva.kz89", "Kz 89", u.Kz89, "ustva.ust", u.USt89},
+				{"ustva.kz93", "Kz 93", u.Kz93, "ustva.ust", u.USt93},
+			})
+		}
+
 		// E. Nicht steuerbare Umsätze
 		addSection("ustva.sectionE", []struct {
 			kzKey  string
@@ -99,6 +182,8 @@ func (a *App) showUStVADialog() {
 			ustKey string
 			ustVal float64
 		}{
+			{"ustva.kz46", "Kz 46", u.Kz46, "", 0},
+			{"ustva.kz47", "Kz 47", u.Kz47, "", 0},
 			{"ustva.kz84", "Kz 84", u.Kz84, "", 0},
 			{"ustva.kz85", "Kz 85", u.Kz85, "", 0},
 		})
@@ -112,9 +197,16 @@ func (a *App) showUStVADialog() {
 			ustVal float64
 		}{
 			{"ustva.kz66", "Kz 66", u.Kz66, "", 0},
+			{"ustva.kz61", "Kz 61", u.Kz61, "", 0},
+			{"ustva.kz62", "Kz 62", u.Kz62, "", 0},
 			{"ustva.kz67", "Kz 67", u.Kz67, "", 0},
+			{"ustva.kz64", "Kz 64", u.Kz64, "", 0},
 		})
 
+		if u.Kz39 != 0 {
+			body.Add(newCopyableLabel(a.bundle, fmt.Sprintf("Kz 39   %s   %s", a.bundle.T("ustva.kz39"), fmtAmt(u.Kz39))))
+		}
+
 		// Kz83: Zahllast or Überschuss — always shown
 		zKey, zVal := "ustva.zahllast", u.Kz83
 		if u.Kz83 < 0 {
@@ -143,7 +235,7 @@ func (a *App) showUStVADialog() {
 		reload()
 	})
 	toggle.Horizontal = true
-	toggle.SetSelected(a.bundle.T("export.month"))
+	toggle.SetSelected(toggleLabels[period])
 	reload()
 
 	pdfBtn := widget.NewButton(a.bundle.T("report.pdf"), func() {
@@ -162,7 +254,9 @@ func (a *App) showUStVADialog() {
 		a.savePDF("UStVA_"+periodStr+".pdf", data)
 	})
 
-	xmlBtn := widget.NewButton(a.bundle.T("report.xml"), func() {
+	// The structured XML for the tax advisor, labelled so it is not taken
+	// for the ELSTER file next to it.
+	xmlBtn := widget.NewButton(a.bundle.T("ustva.xml"), func() {
 		periodStr := fmt.Sprintf("%04d", a.currentYear)
 		switch period {
 		case 0:
@@ -178,10 +272,122 @@ func (a *App) showUStVADialog() {
 		a.savePDF("UStVA_"+periodStr+".xml", data)
 	})
 
+	// The ELSTER file needs the HerstellerID from the settings; without it
+	// only a test case is offered, never a production file with the test ID.
+	elsterBtn := widget.NewButton(a.bundle.T("ustva.elster"), func() {
+		zeitraum, err := core.ElsterZeitraum(vonMonat, bisMonat)
+		if err != nil {
+			a.showError(a.bundle.T("ustva.elster.title"), a.bundle.T("ustva.elster.year"))
+			return
+		}
+		export := func(testfall bool) {
+			data, err := core.BuildUStVAElster(u, core.ElsterUStVAKopf{
+				Jahr:         a.currentYear,
+				Zeitraum:     zeitraum,
+				Firma:        a.settings.Firma,
+				Berichtigt:   berichtigt,
+				Erstellt:     time.Now(),
+				HerstellerID: a.settings.ElsterHerstellerID,
+				Testfall:     testfall,
+			})
+			if err != nil {
+				a.showError(a.bundle.T("ustva.elster.title"), err.Error())
+				return
+			}
+			name := fmt.Sprintf("UStVA_%04d-%s_ELSTER", a.currentYear, zeitraum)
+			if berichtigt {
+				name += "_Berichtigung"
+			}
+			if testfall {
+				name += "_Testfall"
+			}
+			a.saveFile(name+".xml", data)
+		}
+		if core.ElsterHerstellerIDProduktiv(a.settings.ElsterHerstellerID) {
+			export(false)
+			return
+		}
+		dialog.NewConfirm(a.bundle.T("ustva.elster.title"),
+			a.bundle.T("ustva.elster.testfall", core.ElsterTestHerstellerID),
+			func(ok bool) {
+				if ok {
+					export(true)
+				}
+			}, a.window).Show()
+	})
+
 	header := widget.NewLabelWithStyle(a.bundle.T("ustva.heading"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-	topBar := container.NewBorder(nil, nil, nil, container.NewHBox(xmlBtn, pdfBtn), toggle)
-	content := container.NewBorder(container.NewVBox(header, topBar), nil, nil, nil, scroll)
+	basis := a.bundle.T("ustva.soll")
+	if a.settings.IstVersteuerung {
+		basis = a.bundle.T("ustva.ist")
+	}
+	mappingBtn := widget.NewButton(a.bundle.T("kzmap.button"), func() {
+		a.showKennzahlMapping(reload)
+	})
+	topBar := container.NewBorder(nil, nil, nil, container.NewHBox(mappingBtn, markBtn, elsterBtn, xmlBtn, pdfBtn), toggle)
+	// The ELSTER export is checked by rules, not against the schema.
+	elsterHint := widget.NewLabel(a.bundle.T("ustva.elster.hinweis"))
+	elsterHint.Importance = widget.LowImportance
+	elsterHint.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(container.NewVBox(header, widget.NewLabel(basis), topBar, meldung), elsterHint, nil, nil, scroll)
 	d := dialog.NewCustom(a.bundle.T("ustva.title"), a.bundle.T("common.close"), content, a.window)
-	d.Resize(fyne.NewSize(520, 520))
+	d.Resize(fyne.NewSize(720, 560))
 	d.Show()
 }
+
+// vatRows returns the invoice rows of a VAT return for fromY/fromM..toY/toM.
+// Under Ist-Versteuerung the outgoing invoices of the two preceding years are
+// read as well and filed by payment (core.IstRows); the payments come from
+// the statement lines linked in BuchungRef, else the Bezahldatum.
+func (a *App) vatRows(fromY, fromM, toY, toM int) []core.CSVRow {
+	if !a.settings.IstVersteuerung {
+		return a.collectInvoiceRows(fromY, fromM, toY, toM)
+	}
+	rows := a.collectInvoiceRows(fromY-2, fromM, toY, toM)
+	return core.IstRows(rows, a.zahlungen(),
+		fmt.Sprintf("%04d-%02d", fromY, fromM), fmt.Sprintf("%04d-%02d", toY, toM))
+}
+
+// zahlungen returns a core.Zahlungen lookup that resolves a row's linked
+// statement lines from the Kontoauszug files; each file is parsed once per
+// lookup returned.
+func (a *App) zahlungen() func(core.CSVRow) []core.Zahlung {
+	parsed := map[string][]core.StatementBooking{}
+	return func(r core.CSVRow) []core.Zahlung {
+		var linked []core.StatementBooking
+		if r.BuchungRef != core.CashConfirmedRef {
+			for _, ref := range core.ParseBuchungRefs(r.BuchungRef) {
+				path := filepath.Join(a.statementFolder(r.Bankkonto), ref.StatementFilename)
+				lines, ok := parsed[path]
+				if !ok {
+					var err error
+					if lines, err = core.ParseStatementBookings(path); err != nil {
+						a.logger.Warn("Zahlungen: Kontoauszug %s: %v", ref.StatementFilename, err)
+					}
+					parsed[path] = lines
+				}
+				for _, l := range lines {
+					if l.Page == ref.Page && l.LineIdx == ref.LineIdx {
+						linked = append(linked, l)
+						break
+					}
+				}
+			}
+		}
+		return core.Zahlungen(r, linked)
+	}
+}
+
+// sondervorauszahlung returns the Sondervorauszahlung of year, computed from
+// the previous year's monthly returns (core.Sondervorauszahlung); 0 unless the
+// profile files monthly with Dauerfristverlängerung.
+func (a *App) sondervorauszahlung(year int) float64 {
+	if !a.settings.Dauerfristverlaengerung || a.settings.VoranmeldungQuartal || a.settings.Kleinunternehmer {
+		return 0
+	}
+	vorjahr := make([]core.UStVAOfficial, 0, 12)
+	for m := 1; m <= 12; m++ {
+		vorjahr = append(vorjahr, core.ComputeUStVAOfficial(a.vatRows(year-1, m, year-1, m), a.bookingRules))
+	}
+	return core.Sondervorauszahlung(vorjahr)
+}
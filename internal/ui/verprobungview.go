@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bergx2/buchisy/internal/core"
+)
+
+// showUStVerprobungDialog reconciles the booked Umsatzsteuer, Vorsteuer and
+// revenue accounts — receipts and Umbuchungen — with the UStVA Kennzahlen for
+// a selectable period (month, quarter or year) and lists the receipts that
+// cause a difference.
+func (a *App) showUStVerprobungDialog() {
+	// period: 0 = month, 1 = quarter, 2 = year
+	period := 0 // default: the profile's Voranmeldungszeitraum
+	if a.settings.VoranmeldungQuartal {
+		period = 1
+	}
+
+	body := container.NewVBox()
+	scroll := container.NewVScroll(body)
+
+	fmtAmt := func(v float64) string {
+		return formatMoney(v, "EUR", a.settings.DecimalSeparator)
+	}
+	bold := func(s string) *widget.Label {
+		return widget.NewLabelWithStyle(s, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	warn := func(s string, ok bool) *copyableLabel {
+		l := newCopyableLabel(a.bundle, s)
+		if !ok {
+			l.Importance = widget.WarningImportance
+		}
+		return l
+	}
+
+	var v core.UStVerprobung // current period's result, for the PDF export
+	var zeitraum string      // "YYYY-MM", "YYYY-QN" or "YYYY"
+
+	reload := func() {
+		fromY, fromM, toY, toM := a.currentYear, int(a.currentMonth), a.currentYear, int(a.currentMonth)
+		switch period {
+		case 1: // quarter: calendar quarter containing currentMonth
+			q := (int(a.currentMonth) - 1) / 3
+			fromM = q*3 + 1
+			toM = q*3 + 3
+		case 2: // year
+			fromM, toM = 1, 12
+		}
+		switch period {
+		case 0:
+			zeitraum = fmt.Sprintf("%04d-%02d", fromY, fromM)
+		case 1:
+			zeitraum = fmt.Sprintf("%04d-Q%d", fromY, (fromM-1)/3+1)
+		default:
+			zeitraum = fmt.Sprintf("%04d", fromY)
+		}
+		// The accounts carry the Umbuchungen of the period as well; they are
+		// no part of the return (ComputeUStVerprobung keeps them on the booked
+		// side), so a manual correction shows up as a difference. The EB-Werte
+		// are balances brought forward, no turnover of the period, and stay out.
+		rows := append(a.vatRows(fromY, fromM, toY, toM), a.umbuchungRows(fromY, fromM, toY, toM)...)
+		v = core.ComputeUStVerprobung(rows, a.bookingRules)
+		v.Ist = a.settings.IstVersteuerung
+
+		body.Objects = nil
+		abweichend := 0
+		for _, b := range v.Bereiche {
+			if b.Differenz() != 0 {
+				abweichend++
+			}
+		}
+		if v.Abweichend() {
+			body.Add(warn(a.bundle.T("verprobung.abweichend", abweichend), false))
+		} else {
+			body.Add(newCopyableLabel(a.bundle, a.bundle.T("verprobung.ok")))
+		}
+		body.Add(widget.NewSeparator())
+
+		// Bereich | accounts | booked | Kennzahlen | difference
+		grid := container.NewGridWithColumns(5,
+			bold(a.bundle.T("verprobung.col.bereich")), bold(a.bundle.T("verprobung.col.konten")),
+			bold(a.bundle.T("verprobung.col.gebucht")), bold(a.bundle.T("verprobung.col.kennzahlen")),
+			bold(a.bundle.T("verprobung.col.differenz")))
+		for _, b := range v.Bereiche {
+			var konten []string
+			for _, k := range b.Konten {
+				konten = append(konten, fmt.Sprintf("%d: %s", k.Konto, fmtAmt(k.Saldo)))
+			}
+			grid.Add(newCopyableLabel(a.bundle, a.bundle.T("verprobung."+b.Bereich)))
+			grid.Add(newCopyableLabel(a.bundle, strings.Join(konten, "; ")))
+			grid.Add(newCopyableLabel(a.bundle, fmtAmt(b.Gebucht)))
+			grid.Add(newCopyableLabel(a.bundle, fmtAmt(b.Kennzahlen)))
+			grid.Add(warn(fmtAmt(b.Differenz()), b.Differenz() == 0))
+		}
+		grid.Add(bold(a.bundle.T("verprobung.zahllast")))
+		grid.Add(widget.NewLabel(""))
+		grid.Add(bold(fmtAmt(v.Zahllast())))
+		grid.Add(bold(fmtAmt(v.UStVA.Kz83)))
+		grid.Add(warn(fmtAmt(v.ZahllastDifferenz()), v.ZahllastDifferenz() == 0))
+		body.Add(grid)
+
+		// Drill-down: the receipts behind each difference.
+		for _, b := range v.Bereiche {
+			if len(b.Belege) == 0 && b.Rundung() == 0 {
+				continue
+			}
+			body.Add(widget.NewSeparator())
+			body.Add(bold(a.bundle.T("verprobung.belege", a.bundle.T("verprobung."+b.Bereich))))
+			for _, r := range b.Belege {
+				line := a.bundle.T("verprobung.beleg", r.Belegnummer, r.Rechnungsdatum, r.Auftraggeber,
+					fmtAmt(r.Gebucht), fmtAmt(r.Kennzahlen), fmtAmt(r.Differenz()))
+				if r.Geschaetzt {
+					line += " " + a.bundle.T("verprobung.geschaetzt")
+				}
+				l := warn(line, false)
+				l.Wrapping = fyne.TextWrapWord
+				body.Add(l)
+			}
+			if rd := b.Rundung(); rd != 0 {
+				body.Add(newCopyableLabel(a.bundle, a.bundle.T("verprobung.rundung", fmtAmt(rd))))
+			}
+		}
+		body.Refresh()
+	}
+
+	toggleLabels := []string{
+		a.bundle.T("export.month"),
+		a.bundle.T("zm.quarter"),
+		a.bundle.T("export.year"),
+	}
+	toggle := widget.NewRadioGroup(toggleLabels, func(sel string) {
+		switch sel {
+		case a.bundle.T("export.month"):
+			period = 0
+		case a.bundle.T("zm.quarter"):
+			period = 1
+		default:
+			period = 2
+		}
+		reload()
+	})
+	toggle.Horizontal = true
+	toggle.SetSelected(toggleLabels[period])
+	reload()
+
+	pdfBtn := widget.NewButton(a.bundle.T("report.pdf"), func() {
+		data, err := core.BuildUStVerprobungPDF(v, a.bundle.T("verprobung.pdf.title", zeitraum), a.profile)
+		if err != nil {
+			a.showError(a.bundle.T("error.processing.title"), err.Error())
+			return
+		}
+		a.savePDF("UStVA-Verprobung_"+zeitraum+".pdf", data)
+	})
+
+	basis := a.bundle.T("ustva.soll")
+	if a.settings.IstVersteuerung {
+		basis = a.bundle.T("ustva.ist")
+	}
+	heading := widget.NewLabel(a.bundle.T("verprobung.heading"))
+	heading.Wrapping = fyne.TextWrapWord
+	header := container.NewVBox(
+		heading,
+		container.NewBorder(nil, nil, nil, pdfBtn, toggle),
+		widget.NewLabel(basis),
+	)
+	content := container.NewBorder(header, nil, nil, nil, scroll)
+	d := dialog.NewCustom(a.bundle.T("verprobung.title"), a.bundle.T("common.close"), content, a.window)
+	d.Resize(fyne.NewSize(900, 600))
+	d.Show()
+}
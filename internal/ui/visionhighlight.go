@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"fyne.io/fyne/v2"
-	"github.com/zalando/go-keyring"
 
 	"github.com/bergx2/buchisy/internal/core"
 )
@@ -18,10 +17,10 @@ import (
 // Call after building the strip; skips silently when:
 //   - strip is nil
 //   - value is empty
-//   - no API key is configured
+//   - no AI backend is configured (see aiReady)
 //   - the text layer already provided ≥1 matching rect (HighlightRects hit)
 func (a *App) visionHighlight(strip *pdfPreviewStrip, path, value string, hl previewHighlight) {
-	if strip == nil || value == "" || !a.hasAPIKey() {
+	if strip == nil || value == "" || !a.aiReady() {
 		return
 	}
 	if !core.IsPDF(path) {
@@ -75,14 +74,11 @@ func (a *App) visionHighlight(strip *pdfPreviewStrip, path, value string, hl pre
 			return
 		}
 
-		// Fetch the API key from the keyring.
-		apiKey, err := keyring.Get("BuchISY", a.keyringAccount())
-		if err != nil || apiKey == "" {
+		apiKey, model, err := a.aiCredentials()
+		if err != nil {
 			return
 		}
-
-		model := a.settings.AnthropicModel
-		if model == "" {
+		if model == "" && a.settings.ProcessingMode == core.ProcessingModeClaude {
 			model = "claude-opus-4-5"
 		}
 
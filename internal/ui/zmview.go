@@ -26,7 +26,10 @@ func (a *App) showZMDialog() {
 
 	var zm core.ZM // current period's result, for the PDF export
 
-	reload := func() {
+	var reload func()
+	meldung, markBtn, updateMeldung := a.meldungPanel(func() { reload() })
+
+	reload = func() {
 		fromY, fromM, toY, toM := a.currentYear, int(a.currentMonth), a.currentYear, int(a.currentMonth)
 		switch period {
 		case 1: // quarter: calendar quarter containing currentMonth
@@ -36,17 +39,31 @@ func (a *App) showZMDialog() {
 		case 2: // year
 			fromM, toM = 1, 12
 		}
-		rows := a.collectInvoiceRows(fromY, fromM, toY, toM)
-		zm = core.ComputeZM(rows)
+		rows := a.vatRows(fromY, fromM, toY, toM)
+		zm = core.ComputeZMMitRegeln(rows, a.bookingRules)
+		zm.Ist = a.settings.IstVersteuerung
+		zeitraum := ""
+		switch period {
+		case 0:
+			zeitraum = fmt.Sprintf("%04d-%02d", fromY, fromM)
+		case 1:
+			zeitraum = fmt.Sprintf("%04d-Q%d", fromY, (fromM-1)/3+1)
+		}
+		updateMeldung(core.NewZMMeldung(zeitraum, zm, rows, a.bookingRules))
 
 		body.Objects = nil
 
 		if len(zm.Zeilen) == 0 {
 			body.Add(newCopyableLabel(a.bundle, a.bundle.T("zm.empty")))
 		} else {
-			sonstige := a.bundle.T("zm.art.sonstige")
+			artKeys := map[string]string{
+				core.ZMArtLieferung: "zm.art.lieferung",
+				core.ZMArtDreieck:   "zm.art.dreieck",
+				core.ZMArtSonstige:  "zm.art.sonstige",
+			}
 			for _, z := range zm.Zeilen {
-				body.Add(newCopyableLabel(a.bundle, fmt.Sprintf("    %s    %s    %s", z.UStIdNr, fmtAmt(z.Netto), sonstige)))
+				art := a.bundle.T(artKeys[z.Leistungsart()])
+				body.Add(newCopyableLabel(a.bundle, fmt.Sprintf("    %s    %s    %s", z.UStIdNr, fmtAmt(z.Netto), art)))
 			}
 			body.Add(widget.NewSeparator())
 			body.Add(widget.NewLabelWithStyle(
@@ -110,16 +127,42 @@ func (a *App) showZMDialog() {
 		a.savePDF("ZM_"+periodStr+".xml", data)
 	})
 
+	csvBtn := widget.NewButton(a.bundle.T("zm.bzst"), func() {
+		if period == 2 {
+			a.showError(a.bundle.T("zm.bzst.title"), a.bundle.T("zm.bzst.year"))
+			return
+		}
+		data, err := core.BuildZMCSV(zm)
+		if err != nil {
+			a.showError(a.bundle.T("zm.bzst.title"), err.Error())
+			return
+		}
+		periodStr := fmt.Sprintf("%04d-%02d", a.currentYear, int(a.currentMonth))
+		if period == 1 {
+			periodStr = fmt.Sprintf("%04d-Q%d", a.currentYear, (int(a.currentMonth)-1)/3+1)
+		}
+		a.saveFile("ZM_"+periodStr+"_BZSt.csv", data)
+	})
+
 	headingLabel := widget.NewLabelWithStyle(a.bundle.T("zm.heading"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 	headerItems := []fyne.CanvasObject{headingLabel}
 	if a.settings.OwnVATID != "" {
 		headerItems = append(headerItems, newCopyableLabel(a.bundle, "USt-IdNr: "+a.settings.OwnVATID))
 	}
-	headerItems = append(headerItems, container.NewBorder(nil, nil, nil, container.NewHBox(xmlBtn, pdfBtn), toggle))
+	if a.settings.IstVersteuerung {
+		istHint := newCopyableLabel(a.bundle, a.bundle.T("zm.ist"))
+		istHint.Wrapping = fyne.TextWrapWord
+		headerItems = append(headerItems, istHint)
+	}
+	headerItems = append(headerItems, container.NewBorder(nil, nil, nil, container.NewHBox(markBtn, csvBtn, xmlBtn, pdfBtn), toggle), meldung)
 	header := container.NewVBox(headerItems...)
 
-	content := container.NewBorder(header, nil, nil, nil, scroll)
+	// The BZSt export is checked by rules, not against the BZSt's specification.
+	bzstHint := widget.NewLabel(a.bundle.T("zm.bzst.hinweis"))
+	bzstHint.Importance = widget.LowImportance
+	bzstHint.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(header, bzstHint, nil, nil, scroll)
 	d := dialog.NewCustom(a.bundle.T("zm.title"), a.bundle.T("common.close"), content, a.window)
-	d.Resize(fyne.NewSize(520, 400))
+	d.Resize(fyne.NewSize(600, 400))
 	d.Show()
 }